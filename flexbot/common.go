@@ -10,10 +10,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
-	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ucsm"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/server"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/util/crypt"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
+	log "github.com/sirupsen/logrus"
 )
 
 // Default timeouts
@@ -101,8 +105,8 @@ func waitForSSH(nodeConfig *config.NodeConfig, waitForSSHTimeout int, sshUser st
 		}
 		time.Sleep(5 * time.Second)
 		if time.Now().After(restartTime) {
-			ucsm.StopServer(nodeConfig)
-			ucsm.StartServer(nodeConfig)
+			server.StopServer(nodeConfig)
+			server.StartServer(nodeConfig)
 			restartTime = time.Now().Add(time.Second * NodeRestartTimeout)
 		}
 	}
@@ -165,7 +169,7 @@ func shutdownServer(nodeConfig *config.NodeConfig, sshUser string, sshPrivateKey
 	if _, err = runSSHCommand(nodeConfig.Network.Node[0].Ip, sshUser, sshPrivateKey, "sudo shutdown -h 0"); err == nil {
 	        waitForShutdown := time.Now().Add(time.Second * time.Duration(NodeGraceShutdownTimeout))
 	        for time.Now().Before(waitForShutdown) {
-	        	if operState, err = ucsm.GetServerOperationalState(nodeConfig); err != nil {
+	        	if operState, err = server.GetServerOperationalState(nodeConfig); err != nil {
 			        return
 		        }
 		        if operState == "power-off" {
@@ -174,7 +178,7 @@ func shutdownServer(nodeConfig *config.NodeConfig, sshUser string, sshPrivateKey
 		        time.Sleep(5 * time.Second)
 	        }
 	}
-	if err = ucsm.StopServer(nodeConfig); err != nil {
+	if err = server.StopServer(nodeConfig); err != nil {
 		err = fmt.Errorf("shutdownServer(ip=%s): %s", nodeConfig.Network.Node[0].Ip, err)
 	}
         return
@@ -184,7 +188,7 @@ func waitForOperationalState(nodeConfig *config.NodeConfig, state string, waitTi
 	var currentState string
 	giveupTime := time.Now().Add(time.Second * time.Duration(waitTimeout))
 	for time.Now().Before(giveupTime) {
-		if currentState, err = ucsm.GetServerOperationalState(nodeConfig); err != nil {
+		if currentState, err = server.GetServerOperationalState(nodeConfig); err != nil {
 			return
 		}
 		if currentState == state {
@@ -200,7 +204,7 @@ func waitForPowerState(nodeConfig *config.NodeConfig, state string, waitTimeout
 	var currentState string
 	giveupTime := time.Now().Add(time.Second * time.Duration(waitTimeout))
 	for time.Now().Before(giveupTime) {
-		if currentState, err = ucsm.GetServerPowerState(nodeConfig); err != nil {
+		if currentState, err = server.GetServerPowerState(nodeConfig); err != nil {
 			return
 		}
 		if currentState == state {
@@ -316,6 +320,99 @@ func createSnapshot(nodeConfig *config.NodeConfig, sshUser string, sshPrivateKey
 	return
 }
 
+// storageApiDiagnostics reports ONTAP API capabilities negotiated by "auto" API method,
+// warning is issued if any feature is served by ZAPI
+func storageApiDiagnostics(nodeConfig *config.NodeConfig) (diags diag.Diagnostics) {
+	for _, credentials := range []config.CdotCredentials{nodeConfig.Storage.CdotCredentials, nodeConfig.Storage.Replication.CdotCredentials} {
+		if credentials.ApiMethod != "auto" {
+			continue
+		}
+		caps, err := client.NegotiateCapabilities(credentials)
+		if err != nil {
+			continue
+		}
+		log.Infof("Negotiated ONTAP API capabilities: %s", caps)
+		if caps.ApiMethod() != "rest" {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Warning,
+				Summary:  fmt.Sprintf("ONTAP API negotiated for %s: %s", credentials.Host, caps.ApiMethod()),
+				Detail:   caps.String() + ". ZAPI is being retired, upgrade ONTAP to serve all features with REST API.",
+			})
+		}
+	}
+	return
+}
+
+// setSignaturesInput sets images and templates signatures verification from provider configuration
+func setSignaturesInput(p *schema.ResourceData, nodeConfig *config.NodeConfig) {
+	nodeConfig.Storage.Signatures.Required = p.Get("require_signed_uploads").(bool)
+	nodeConfig.Storage.Signatures.TrustedKeys = []string{}
+	for _, key := range p.Get("trusted_keys").([]interface{}) {
+		nodeConfig.Storage.Signatures.TrustedKeys = append(nodeConfig.Storage.Signatures.TrustedKeys, key.(string))
+	}
+}
+
+// setUploadInput sets chunked upload settings from provider configuration
+func setUploadInput(p *schema.ResourceData, nodeConfig *config.NodeConfig) {
+	nodeConfig.Storage.Upload.ChunkSize = p.Get("upload_chunk_size").(int)
+	nodeConfig.Storage.Upload.Parallelism = p.Get("upload_parallelism").(int)
+	nodeConfig.Storage.Upload.Retries = p.Get("upload_retries").(int)
+}
+
+// setIntersightTLSInput sets Intersight endpoint certificate verification from provider compute configuration
+func setIntersightTLSInput(p *schema.ResourceData, nodeConfig *config.NodeConfig) {
+	pCompute := p.Get("compute").([]interface{})[0].(map[string]interface{})
+	nodeConfig.Compute.IntersightTLS.Insecure = pCompute["insecure"].(bool)
+	nodeConfig.Compute.IntersightTLS.CaCert = pCompute["ca_cert"].(string)
+}
+
+// setArtifactSourcesInput sets S3 and OCI registry artifact sources from provider configuration
+func setArtifactSourcesInput(p *schema.ResourceData, nodeConfig *config.NodeConfig) {
+	nodeConfig.Storage.ArtifactSources = config.ArtifactSources{}
+	if len(p.Get("artifact_sources").([]interface{})) == 0 || p.Get("artifact_sources").([]interface{})[0] == nil {
+		return
+	}
+	sources := p.Get("artifact_sources").([]interface{})[0].(map[string]interface{})
+	if len(sources["s3"].([]interface{})) > 0 && sources["s3"].([]interface{})[0] != nil {
+		s3 := sources["s3"].([]interface{})[0].(map[string]interface{})
+		nodeConfig.Storage.ArtifactSources.S3.Endpoint = s3["endpoint"].(string)
+		nodeConfig.Storage.ArtifactSources.S3.Region = s3["region"].(string)
+		nodeConfig.Storage.ArtifactSources.S3.AccessKey = s3["access_key"].(string)
+		nodeConfig.Storage.ArtifactSources.S3.SecretKey = s3["secret_key"].(string)
+		nodeConfig.Storage.ArtifactSources.S3.SessionToken = s3["session_token"].(string)
+	}
+	if len(sources["oci"].([]interface{})) > 0 && sources["oci"].([]interface{})[0] != nil {
+		oci := sources["oci"].([]interface{})[0].(map[string]interface{})
+		nodeConfig.Storage.ArtifactSources.Oci.User = oci["user"].(string)
+		nodeConfig.Storage.ArtifactSources.Oci.Password = oci["password"].(string)
+		nodeConfig.Storage.ArtifactSources.Oci.PlainHttp = oci["plain_http"].(bool)
+	}
+}
+
+// uploadProgressLogger makes upload progress callback which logs every 10% (or every GB if size is unknown)
+func uploadProgressLogger(name string) client.UploadProgress {
+	startTime := time.Now()
+	var reported int64
+	return func(bytesUploaded int64, bytesTotal int64) {
+		var step int64
+		if bytesTotal > 0 {
+			step = bytesUploaded * 10 / bytesTotal
+		} else {
+			step = bytesUploaded >> 30
+		}
+		if step <= reported {
+			return
+		}
+		reported = step
+		throughput := float64(bytesUploaded) / 1048576 / time.Since(startTime).Seconds()
+		if bytesTotal > 0 {
+			log.Infof("Uploading %s: %d%% (%d of %d bytes, %.1f MB/s)", name, bytesUploaded*100/bytesTotal, bytesUploaded, bytesTotal, throughput)
+		} else {
+			log.Infof("Uploading %s: %d bytes (%.1f MB/s)", name, bytesUploaded, throughput)
+		}
+	}
+}
+
 func stringSliceIntersection(src1, src2 []string) (dst []string) {
 	hash := make(map[string]bool)
 	for _, e := range src1 {
@@ -0,0 +1,146 @@
+package flexbot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap"
+)
+
+func dataSourceFlexbotSnapshots() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceFlexbotSnapshotsRead,
+		Schema: map[string]*schema.Schema{
+			"hostname": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "Server host name",
+			},
+			"volume_name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "Server volume name, default is derived from host name",
+			},
+			"svm_name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "SVM name, required for cluster scope provider credentials",
+			},
+			"snapshots": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"create_time": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"flexbot": {
+							Type:        schema.TypeBool,
+							Computed:    true,
+							Description: "Snapshot is created by flexbot",
+						},
+						"version": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"os_image": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"seed_template": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"blade_model": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"boot_lun_size": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"data_lun_size": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"labels": {
+							Type:     schema.TypeMap,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"comment": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceFlexbotSnapshotsRead(ctx context.Context, d *schema.ResourceData, meta interface{}) (diags diag.Diagnostics) {
+	var err error
+	nodeConfig := &config.NodeConfig{}
+	p := meta.(*config.FlexbotConfig).FlexbotProvider
+	if len(p.Get("storage").([]interface{})) == 0 {
+		diags = diag.FromErr(fmt.Errorf("dataSourceFlexbotSnapshotsRead(): expected storage in provider configuration"))
+		return
+	}
+	pStorage := p.Get("storage").([]interface{})[0].(map[string]interface{})
+	cdotCredentials := pStorage["credentials"].([]interface{})[0].(map[string]interface{})
+	nodeConfig.Storage.CdotCredentials.Host = cdotCredentials["host"].(string)
+	nodeConfig.Storage.CdotCredentials.User = cdotCredentials["user"].(string)
+	nodeConfig.Storage.CdotCredentials.Password = cdotCredentials["password"].(string)
+	nodeConfig.Storage.CdotCredentials.ApiMethod = cdotCredentials["api_method"].(string)
+	nodeConfig.Storage.CdotCredentials.ZapiVersion = cdotCredentials["zapi_version"].(string)
+	nodeConfig.Storage.SvmName = d.Get("svm_name").(string)
+	nodeConfig.Compute.HostName = d.Get("hostname").(string)
+	nodeConfig.Storage.VolumeName = d.Get("volume_name").(string)
+	if nodeConfig.Storage.VolumeName == "" {
+		if nodeConfig.Storage.VolumeName, err = config.GetVolumeName(nodeConfig.Compute.HostName); err != nil {
+			diags = diag.FromErr(fmt.Errorf("dataSourceFlexbotSnapshotsRead(): %s", err))
+			return
+		}
+	}
+	if err = config.SetDefaults(nodeConfig, "", "", "", p.Get("pass_phrase").(string)); err != nil {
+		diags = diag.FromErr(fmt.Errorf("dataSourceFlexbotSnapshotsRead(): SetDefaults() failure: %s", err))
+		return
+	}
+	var snapshotDetails []ontap.SnapshotDetails
+	if snapshotDetails, err = ontap.GetSnapshotsDetails(nodeConfig); err != nil {
+		diags = diag.FromErr(fmt.Errorf("dataSourceFlexbotSnapshotsRead(): %s", err))
+		return
+	}
+	snapshots := []interface{}{}
+	for _, snapshotDetail := range snapshotDetails {
+		snapshot := make(map[string]interface{})
+		snapshot["name"] = snapshotDetail.Name
+		snapshot["create_time"] = snapshotDetail.CreateTime
+		snapshot["flexbot"] = snapshotDetail.Flexbot
+		if snapshotDetail.Metadata != nil {
+			snapshot["version"] = snapshotDetail.Metadata.Version
+			snapshot["os_image"] = snapshotDetail.Metadata.OsImage
+			snapshot["seed_template"] = snapshotDetail.Metadata.SeedTemplate
+			snapshot["blade_model"] = snapshotDetail.Metadata.BladeModel
+			snapshot["boot_lun_size"] = snapshotDetail.Metadata.BootLunSize
+			snapshot["data_lun_size"] = snapshotDetail.Metadata.DataLunSize
+			snapshot["labels"] = snapshotDetail.Metadata.Labels
+			snapshot["comment"] = snapshotDetail.Metadata.Comment
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	d.Set("volume_name", nodeConfig.Storage.VolumeName)
+	d.Set("snapshots", snapshots)
+	d.SetId(nodeConfig.Compute.HostName)
+	return
+}
@@ -0,0 +1,115 @@
+package flexbot
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ipam"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/server"
+	log "github.com/sirupsen/logrus"
+)
+
+// isDryRun checks if provider runs server create and delete in dry-run mode
+func isDryRun(meta interface{}) bool {
+	return meta.(*config.FlexbotConfig).FlexbotProvider.Get("dry_run").(bool)
+}
+
+// planCreateServer runs server creation steps with mutations recorded in the plan
+func planCreateServer(d *schema.ResourceData, nodeConfig *config.NodeConfig) (diags diag.Diagnostics) {
+	var err error
+	var ipamProvider ipam.IpamProvider
+	log.Infof("Planning Server %s creation (dry run)", nodeConfig.Compute.HostName)
+	if ipamProvider, err = ipam.NewProvider(&nodeConfig.Ipam); err == nil {
+		err = ipamProvider.Allocate(nodeConfig)
+	}
+	if err == nil {
+		err = ontap.CreateBootStorage(nodeConfig)
+	}
+	if err == nil {
+		err = server.CreateServer(nodeConfig)
+	}
+	if err == nil && len(nodeConfig.Network.FcInitiator) > 0 {
+		err = ontap.SetFcInitiators(nodeConfig)
+	}
+	if err == nil {
+		err = ontap.CreateNvmeStorage(nodeConfig)
+	}
+	if err == nil {
+		err = ontap.CreateSeedStorage(nodeConfig)
+	}
+	if err == nil {
+		err = server.StartServer(nodeConfig)
+	}
+	if err == nil {
+		for _, snapshot := range d.Get("snapshot").([]interface{}) {
+			if err = ontap.CreateSnapshot(nodeConfig, snapshot.(map[string]interface{})["name"].(string), ""); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = ontap.CreateReplication(nodeConfig)
+	}
+	diags = planDiagnostics("resourceCreateServer()", nodeConfig, err)
+	return
+}
+
+// planDeleteServer runs server deletion steps with mutations recorded in the plan
+func planDeleteServer(meta interface{}, nodeConfig *config.NodeConfig, powerState string) (diags diag.Diagnostics) {
+	var err error
+	log.Infof("Planning Server %s deletion (dry run)", nodeConfig.Compute.HostName)
+	if meta.(*config.FlexbotConfig).RancherApiEnabled {
+		if powerState == "up" {
+			nodeConfig.Plan.Record("rancher", "", "RancherAPINodeCordonDrain", "cordon and drain node %s", nodeConfig.Compute.HostName)
+		}
+		nodeConfig.Plan.Record("rancher", "", "RancherAPINodeDelete", "delete node %s", nodeConfig.Compute.HostName)
+	}
+	if powerState == "up" {
+		err = server.StopServer(nodeConfig)
+	}
+	if err == nil {
+		err = server.DeleteServer(nodeConfig)
+	}
+	if err == nil {
+		err = ontap.DeleteReplication(nodeConfig)
+	}
+	if err == nil {
+		err = ontap.DeleteBootStorage(nodeConfig)
+	}
+	if err == nil {
+		if primaryErr := ontap.DeletePrimaryStorage(nodeConfig); primaryErr != nil {
+			log.Warnf("Server %s storage leftover: %s", nodeConfig.Compute.HostName, primaryErr)
+		}
+	}
+	if err == nil {
+		var ipamProvider ipam.IpamProvider
+		if ipamProvider, err = ipam.NewProvider(&nodeConfig.Ipam); err == nil {
+			err = ipamProvider.Release(nodeConfig)
+		}
+	}
+	diags = planDiagnostics("resourceDeleteServer()", nodeConfig, err)
+	return
+}
+
+// planDiagnostics reports planned operations as error to keep Terraform state unchanged
+func planDiagnostics(summary string, nodeConfig *config.NodeConfig, err error) (diags diag.Diagnostics) {
+	for _, op := range nodeConfig.Plan.Operations() {
+		log.Infof("Planned %s operation on %s: %s", op.Backend, nodeConfig.Compute.HostName, op.Description)
+	}
+	if err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  summary,
+			Detail:   err.Error(),
+		})
+	}
+	diags = append(diags, diag.Diagnostic{
+		Severity: diag.Error,
+		Summary:  fmt.Sprintf("%s: dry run for server %s, no changes were applied", summary, nodeConfig.Compute.HostName),
+		Detail:   "Planned operations:\n" + nodeConfig.Plan.String(),
+	})
+	return
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"sync"
 	"os"
+	"time"
 	"encoding/base64"
 
 	"github.com/denisbrodbeck/machineid"
@@ -14,7 +15,10 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	nodeConfig "github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ucsm"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/util/crypt"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/util/signature"
 )
 
 var (
@@ -54,6 +58,138 @@ func Provider() *schema.Provider {
 				Optional: true,
 				Default:  false,
 			},
+			"require_signed_uploads": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"trusted_keys": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+					ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+						if _, err := signature.ParsePublicKey(val.(string)); err != nil {
+							errs = append(errs, fmt.Errorf("invalid %q: %s", key, err))
+						}
+						return
+					},
+				},
+			},
+			"upload_chunk_size": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      16,
+				ValidateFunc: validation.IntBetween(1, 1024),
+			},
+			"upload_parallelism": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      4,
+				ValidateFunc: validation.IntBetween(1, 64),
+			},
+			"upload_retries": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      5,
+				ValidateFunc: validation.IntBetween(0, 100),
+			},
+			"storage_session_cache": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"storage_max_concurrency": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      8,
+				ValidateFunc: validation.IntBetween(1, 64),
+			},
+			"storage_health_check_interval": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      300,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"compute_session_cache": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"compute_keepalive_interval": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      300,
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"dry_run": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"artifact_sources": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"s3": {
+							Type:     schema.TypeList,
+							Optional: true,
+							MaxItems: 1,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"endpoint": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+									"region": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+									"access_key": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+									"secret_key": {
+										Type:      schema.TypeString,
+										Optional:  true,
+										Sensitive: true,
+									},
+									"session_token": {
+										Type:      schema.TypeString,
+										Optional:  true,
+										Sensitive: true,
+									},
+								},
+							},
+						},
+						"oci": {
+							Type:     schema.TypeList,
+							Optional: true,
+							MaxItems: 1,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"user": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+									"password": {
+										Type:      schema.TypeString,
+										Optional:  true,
+										Sensitive: true,
+									},
+									"plain_http": {
+										Type:     schema.TypeBool,
+										Optional: true,
+										Default:  false,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
 			"ipam": {
 				Type:     schema.TypeList,
 				Optional: true,
@@ -124,6 +260,22 @@ func Provider() *schema.Provider {
 				MaxItems: 1,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
+						"provider": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      "ucsm",
+							ValidateFunc: validation.StringInSlice([]string{"ucsm", "intersight", "redfish"}, false),
+						},
+						"insecure": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  false,
+						},
+						"ca_cert": {
+							Type:     schema.TypeString,
+							Optional: true,
+							Default:  "",
+						},
 						"credentials": {
 							Type:     schema.TypeList,
 							Required: true,
@@ -180,8 +332,46 @@ func Provider() *schema.Provider {
 										Default:  "rest",
 										ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
 											v := val.(string)
-											if !(v == "zapi" || v == "rest") {
-												errs = append(errs, fmt.Errorf("unsupported %q=%s, allowed values are \"zapi\" and \"rest\"", key, v))
+											if !(v == "zapi" || v == "rest" || v == "auto") {
+												errs = append(errs, fmt.Errorf("unsupported %q=%s, allowed values are \"zapi\", \"rest\" and \"auto\"", key, v))
+											}
+											return
+										},
+									},
+									"zapi_version": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+								},
+							},
+						},
+						"replication_credentials": {
+							Type:     schema.TypeList,
+							Optional: true,
+							MaxItems: 1,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"host": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"user": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"password": {
+										Type:      schema.TypeString,
+										Required:  true,
+										Sensitive: true,
+									},
+									"api_method": {
+										Type:     schema.TypeString,
+										Optional: true,
+										Default:  "rest",
+										ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+											v := val.(string)
+											if !(v == "zapi" || v == "rest" || v == "auto") {
+												errs = append(errs, fmt.Errorf("unsupported %q=%s, allowed values are \"zapi\", \"rest\" and \"auto\"", key, v))
 											}
 											return
 										},
@@ -383,6 +573,7 @@ func Provider() *schema.Provider {
 		},
 		DataSourcesMap: map[string]*schema.Resource{
 			"flexbot_crypt": dataSourceFelxbotCrypt(),
+			"flexbot_snapshots": dataSourceFlexbotSnapshots(),
 		},
 		ConfigureContextFunc: providerConfigure,
 	}
@@ -524,6 +715,26 @@ func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}
 			NodeConfig:        make(map[string]*nodeConfig.NodeConfig),
 		}
 	}
+	if d.Get("storage_session_cache").(bool) {
+		sessionCache := client.NewSessionCache(d.Get("storage_max_concurrency").(int), time.Duration(d.Get("storage_health_check_interval").(int))*time.Second)
+		client.SetSessionCache(sessionCache)
+		if stopCtx, ok := schema.StopContext(ctx); ok {
+			go func() {
+				<-stopCtx.Done()
+				sessionCache.Close()
+			}()
+		}
+	}
+	if d.Get("compute_session_cache").(bool) {
+		ucsmSessionCache := ucsm.NewSessionCache(time.Duration(d.Get("compute_keepalive_interval").(int))*time.Second)
+		ucsm.SetSessionCache(ucsmSessionCache)
+		if stopCtx, ok := schema.StopContext(ctx); ok {
+			go func() {
+				<-stopCtx.Done()
+				ucsmSessionCache.Close()
+			}()
+		}
+	}
 	if len(d.Get("vmware_api").([]interface{})) > 0 {
 		vmwareAPI := d.Get("vmware_api").([]interface{})[0].(map[string]interface{})
 		var apiUserPassword, hostSdkUserPassword string
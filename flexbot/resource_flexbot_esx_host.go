@@ -14,7 +14,7 @@ import (
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ipam"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap"
-	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ucsm"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/server"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/vmware"
 )
 
@@ -52,7 +52,7 @@ func resourceCreateEsxHost(ctx context.Context, d *schema.ResourceData, meta int
 	}
 	log.Infof("Creating ESX host %s", nodeConfig.Compute.HostName)
 	var nodeExists bool
-	if nodeExists, err = ucsm.DiscoverServer(nodeConfig); err != nil {
+	if nodeExists, err = server.DiscoverServer(nodeConfig); err != nil {
 		diags = diag.FromErr(err)
 		return
 	}
@@ -79,10 +79,10 @@ func resourceCreateEsxHost(ctx context.Context, d *schema.ResourceData, meta int
 			Detail:   err.Error(),
 		})
 	}
-	if err = ucsm.CreateServerPreflight(nodeConfig); err != nil {
+	if err = server.CreateServerPreflight(nodeConfig); err != nil {
 		diags = append(diags, diag.Diagnostic{
 			Severity: diag.Error,
-			Summary:  "ucsm.CreateServerPreflight()",
+			Summary:  "server.CreateServerPreflight()",
 			Detail:   err.Error(),
 		})
 	}
@@ -94,19 +94,19 @@ func resourceCreateEsxHost(ctx context.Context, d *schema.ResourceData, meta int
 		return
 	}
 	if err = ontap.CreateEsxStorage(nodeConfig); err == nil {
-		_, err = ucsm.CreateServer(nodeConfig)
+		err = server.CreateServer(nodeConfig)
 	}
 	if err == nil {
 		// confirm host in state file if all components created successfully
 		meta.(*config.FlexbotConfig).Sync.Lock()
 		d.SetId(nodeConfig.Compute.HostName)
 		meta.(*config.FlexbotConfig).Sync.Unlock()
-		err = ucsm.StartServer(nodeConfig)
+		err = server.StartServer(nodeConfig)
 	} else {
 		errs = append(errs, err)
 		// do cleanup if any of the components fail
 		ontap.DeleteEsxStorage(nodeConfig)
-		ucsm.DeleteServer(nodeConfig)
+		server.DeleteServer(nodeConfig)
 		ipamProvider.Release(nodeConfig)
 	}
 	if err == nil {
@@ -136,7 +136,7 @@ func resourceReadEsxHost(ctx context.Context, d *schema.ResourceData, meta inter
 	}
 	log.Infof("Refreshing ESX host %s", nodeConfig.Compute.HostName)
 	var nodeExists bool
-	if nodeExists, err = ucsm.DiscoverServer(nodeConfig); err != nil {
+	if nodeExists, err = server.DiscoverServer(nodeConfig); err != nil {
 		diags = diag.FromErr(err)
 		return
 	}
@@ -254,12 +254,12 @@ func resourceUpdateEsxHostCompute(d *schema.ResourceData, meta interface{}, node
 	if (nodeConfig.ChangeStatus & ChangeBladeSpec) > 0 {
 		nodeConfig.Compute.BladeSpec.Dn = newBladeSpec["dn"].(string)
 	}
-	if powerState, err = ucsm.GetServerPowerState(nodeConfig); err != nil {
-		err = fmt.Errorf("resourceUpdateEsxHost(compute): ucsm.GetServerPowerState(%s): %s", nodeConfig.Compute.HostName, err)
+	if powerState, err = server.GetServerPowerState(nodeConfig); err != nil {
+		err = fmt.Errorf("resourceUpdateEsxHost(compute): server.GetServerPowerState(%s): %s", nodeConfig.Compute.HostName, err)
 		return
 	}
-	if operState, err = ucsm.GetServerOperationalState(nodeConfig); err != nil {
-		err = fmt.Errorf("resourceUpdateEsxHost(compute): ucsm.GetServerOperationalState(%s): %s", nodeConfig.Compute.HostName, err)
+	if operState, err = server.GetServerOperationalState(nodeConfig); err != nil {
+		err = fmt.Errorf("resourceUpdateEsxHost(compute): server.GetServerOperationalState(%s): %s", nodeConfig.Compute.HostName, err)
 		return
 	}
 	nodeConfig.Compute.Powerstate = powerState
@@ -277,8 +277,8 @@ func resourceUpdateEsxHostCompute(d *schema.ResourceData, meta interface{}, node
 		log.Infof("Updating ESX host %s", nodeConfig.Compute.HostName)
 	        if (nodeConfig.ChangeStatus & ChangeBladeSpec) > 0 {
 	        	log.Infof("Running compute  preflight check")
-	                if err = ucsm.UpdateServerPreflight(nodeConfig); err != nil {
-			        err = fmt.Errorf("resourceUpdateEsxHost(compute): ucsm.UpdateServerPreflight(%s): %s", nodeConfig.Compute.HostName, err)
+	                if err = server.UpdateServerPreflight(nodeConfig); err != nil {
+			        err = fmt.Errorf("resourceUpdateEsxHost(compute): server.UpdateServerPreflight(%s): %s", nodeConfig.Compute.HostName, err)
 			        meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 			        return
 	                }
@@ -293,23 +293,23 @@ func resourceUpdateEsxHostCompute(d *schema.ResourceData, meta interface{}, node
 				}
 			}
 			log.Infof("Power off host %s", nodeConfig.Compute.HostName)
-			if err = ucsm.StopServer(nodeConfig); err != nil {
-			        err = fmt.Errorf("resourceUpdateEsxHost(compute): ucsm.StopServer(%s): %s", nodeConfig.Compute.HostName, err)
+			if err = server.StopServer(nodeConfig); err != nil {
+			        err = fmt.Errorf("resourceUpdateEsxHost(compute): server.StopServer(%s): %s", nodeConfig.Compute.HostName, err)
 				meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 				return
 			}
 		}
 		if (nodeConfig.ChangeStatus & ChangeBladeSpec) > 0 {
 			log.Infof("Changing blade specification for host %s", nodeConfig.Compute.HostName)
-			if err = ucsm.UpdateServer(nodeConfig); err != nil {
-			        err = fmt.Errorf("resourceUpdateEsxHost(compute): ucsm.UpdateServer(%s): %s", nodeConfig.Compute.HostName, err)
+			if err = server.UpdateServer(nodeConfig); err != nil {
+			        err = fmt.Errorf("resourceUpdateEsxHost(compute): server.UpdateServer(%s): %s", nodeConfig.Compute.HostName, err)
 				meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 				return
 			}
 		}
 		if newPowerState == "up" {
 			log.Infof("Power on ESX host %s", nodeConfig.Compute.HostName)
-			if err = ucsm.StartServer(nodeConfig); err == nil {
+			if err = server.StartServer(nodeConfig); err == nil {
 				var vmwareAPI vmware.VMwareAPI
 				if vmwareAPI, err = waitForEsxHost(d, meta, nodeConfig, meta.(*config.FlexbotConfig).VMwareConfig.WaitForHostBootTimeout); err == nil && vmwareAPI != nil {
 					err = vmwareAPI.VMwareAPIExitMaintenanceMode(EsxExitMaintModeTimeout)
@@ -324,8 +324,8 @@ func resourceUpdateEsxHostCompute(d *schema.ResourceData, meta interface{}, node
 	}
 	if (oldCompute.([]interface{})[0].(map[string]interface{}))["description"].(string) != (newCompute.([]interface{})[0].(map[string]interface{}))["description"].(string) ||
 		(oldCompute.([]interface{})[0].(map[string]interface{}))["label"].(string) != (newCompute.([]interface{})[0].(map[string]interface{}))["label"].(string) {
-		if err = ucsm.UpdateServerAttributes(nodeConfig); err != nil {
-			err = fmt.Errorf("resourceUpdateEsxHost(compute): ucsm.UpdateServerAttributes(%s): %s", nodeConfig.Compute.HostName, err)
+		if err = server.UpdateServerAttributes(nodeConfig); err != nil {
+			err = fmt.Errorf("resourceUpdateEsxHost(compute): server.UpdateServerAttributes(%s): %s", nodeConfig.Compute.HostName, err)
 		}
 	}
 	return
@@ -363,13 +363,13 @@ func resourceUpdateEsxHostStorage(d *schema.ResourceData, meta interface{}, node
 			meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 			return
 		}
-		if powerState, err = ucsm.GetServerPowerState(nodeConfig); err != nil {
-			err = fmt.Errorf("resourceUpdateEsxHost(storage): ucsm.GetServerPowerState(%s): %s", nodeConfig.Compute.HostName, err)
+		if powerState, err = server.GetServerPowerState(nodeConfig); err != nil {
+			err = fmt.Errorf("resourceUpdateEsxHost(storage): server.GetServerPowerState(%s): %s", nodeConfig.Compute.HostName, err)
 			meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 			return
 		}
-		if operState, err = ucsm.GetServerOperationalState(nodeConfig); err != nil {
-			err = fmt.Errorf("resourceUpdateEsxHost(storage): ucsm.GetServerOperationalState(%s): %s", nodeConfig.Compute.HostName, err)
+		if operState, err = server.GetServerOperationalState(nodeConfig); err != nil {
+			err = fmt.Errorf("resourceUpdateEsxHost(storage): server.GetServerOperationalState(%s): %s", nodeConfig.Compute.HostName, err)
 			meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 			return
 		}
@@ -390,8 +390,8 @@ func resourceUpdateEsxHostStorage(d *schema.ResourceData, meta interface{}, node
 				}
 			}
 			log.Infof("Power off ESX host %s", nodeConfig.Compute.HostName)
-			if err = ucsm.StopServer(nodeConfig); err != nil {
-				err = fmt.Errorf("resourceUpdateEsxHost(storage): ucsm.StopServer(%s): %s", nodeConfig.Compute.HostName, err)
+			if err = server.StopServer(nodeConfig); err != nil {
+				err = fmt.Errorf("resourceUpdateEsxHost(storage): server.StopServer(%s): %s", nodeConfig.Compute.HostName, err)
 				meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 				return
 			}
@@ -409,7 +409,7 @@ func resourceUpdateEsxHostStorage(d *schema.ResourceData, meta interface{}, node
 		}
 		if err == nil {
 			log.Infof("Power on ESX host %s", nodeConfig.Compute.HostName)
-			if err = ucsm.StartServer(nodeConfig); err == nil {
+			if err = server.StartServer(nodeConfig); err == nil {
 				_, err = waitForEsxHost(d, meta, nodeConfig, meta.(*config.FlexbotConfig).VMwareConfig.WaitForHostInstallerTimeout)
 			}
 		}
@@ -433,13 +433,13 @@ func resourceDeleteEsxHost(ctx context.Context, d *schema.ResourceData, meta int
         meta.(*config.FlexbotConfig).Sync.Lock()
 	compute := d.Get("compute").([]interface{})[0].(map[string]interface{})
         meta.(*config.FlexbotConfig).Sync.Unlock()
-	if powerState, err = ucsm.GetServerPowerState(nodeConfig); err != nil {
-		err = fmt.Errorf("resourceDeleteEsxHost(): ucsm.GetServerPowerState(%s): %s", nodeConfig.Compute.HostName, err)
+	if powerState, err = server.GetServerPowerState(nodeConfig); err != nil {
+		err = fmt.Errorf("resourceDeleteEsxHost(): server.GetServerPowerState(%s): %s", nodeConfig.Compute.HostName, err)
 		diags = diag.FromErr(err)
 		return
 	}
-	if operState, err = ucsm.GetServerOperationalState(nodeConfig); err != nil {
-		err = fmt.Errorf("resourceDeleteEsxHost(): ucsm.GetServerOperationalState(%s): %s", nodeConfig.Compute.HostName, err)
+	if operState, err = server.GetServerOperationalState(nodeConfig); err != nil {
+		err = fmt.Errorf("resourceDeleteEsxHost(): server.GetServerOperationalState(%s): %s", nodeConfig.Compute.HostName, err)
 		diags = diag.FromErr(err)
 		return
 	}
@@ -458,16 +458,16 @@ func resourceDeleteEsxHost(ctx context.Context, d *schema.ResourceData, meta int
 			}
 		}
 		log.Infof("Power off ESX host %s", nodeConfig.Compute.HostName)
-		if err = ucsm.StopServer(nodeConfig); err != nil {
-			err = fmt.Errorf("resourceDeleteEsxHost(): ucsm.StopServer(%s): %s", nodeConfig.Compute.HostName, err)
+		if err = server.StopServer(nodeConfig); err != nil {
+			err = fmt.Errorf("resourceDeleteEsxHost(): server.StopServer(%s): %s", nodeConfig.Compute.HostName, err)
 			diags = diag.FromErr(err)
 			return
 		}
         }
-	if err = ucsm.DeleteServer(nodeConfig); err != nil {
+	if err = server.DeleteServer(nodeConfig); err != nil {
 		diags = append(diags, diag.Diagnostic{
 			Severity: diag.Error,
-			Summary:  "ucsm.DeleteServer()",
+			Summary:  "server.DeleteServer()",
 			Detail:   err.Error(),
 		})
 	}
@@ -533,6 +533,7 @@ func setFlexbotEsxHostInput(d *schema.ResourceData, meta interface{}) (nodeConfi
 	nodeConfig.Compute.UcsmCredentials.Host = ucsmCredentials["host"].(string)
 	nodeConfig.Compute.UcsmCredentials.User = ucsmCredentials["user"].(string)
 	nodeConfig.Compute.UcsmCredentials.Password = ucsmCredentials["password"].(string)
+	nodeConfig.Compute.Provider = pCompute["provider"].(string)
 	pStorage := p.Get("storage").([]interface{})[0].(map[string]interface{})
 	cdotCredentials := pStorage["credentials"].([]interface{})[0].(map[string]interface{})
 	nodeConfig.Storage.CdotCredentials.Host = cdotCredentials["host"].(string)
@@ -540,8 +541,20 @@ func setFlexbotEsxHostInput(d *schema.ResourceData, meta interface{}) (nodeConfi
 	nodeConfig.Storage.CdotCredentials.Password = cdotCredentials["password"].(string)
 	nodeConfig.Storage.CdotCredentials.ApiMethod = cdotCredentials["api_method"].(string)
 	nodeConfig.Storage.CdotCredentials.ZapiVersion = cdotCredentials["zapi_version"].(string)
+	setSignaturesInput(p, nodeConfig)
+	setUploadInput(p, nodeConfig)
+	setArtifactSourcesInput(p, nodeConfig)
 	nodeConfig.Compute.SpOrg = compute["sp_org"].(string)
 	nodeConfig.Compute.SpTemplate = compute["sp_template"].(string)
+	nodeConfig.Compute.SystemId = compute["system_id"].(string)
+	nodeConfig.Compute.RedfishCredentials.Host = compute["bmc_host"].(string)
+	if nodeConfig.Compute.RedfishCredentials.Host == "" {
+		nodeConfig.Compute.RedfishCredentials.Host = nodeConfig.Compute.UcsmCredentials.Host
+	}
+	nodeConfig.Compute.RedfishCredentials.User = nodeConfig.Compute.UcsmCredentials.User
+	nodeConfig.Compute.RedfishCredentials.Password = nodeConfig.Compute.UcsmCredentials.Password
+	nodeConfig.Compute.IntersightCredentials = nodeConfig.Compute.UcsmCredentials
+	setIntersightTLSInput(p, nodeConfig)
 	nodeConfig.Compute.Description = compute["description"].(string)
 	nodeConfig.Compute.Label = compute["label"].(string)
 	nodeConfig.Compute.Firmware = compute["firmware"].(string)
@@ -554,6 +567,11 @@ func setFlexbotEsxHostInput(d *schema.ResourceData, meta interface{}) (nodeConfi
 		nodeConfig.Compute.BladeSpec.NumOfCores = bladeSpec["num_of_cores"].(string)
 		nodeConfig.Compute.BladeSpec.NumOfThreads = bladeSpec["num_of_threads"].(string)
 		nodeConfig.Compute.BladeSpec.TotalMemory = bladeSpec["total_memory"].(string)
+		nodeConfig.Compute.BladePlacement.Policy = bladeSpec["placement"].(string)
+		nodeConfig.Compute.BladePlacement.Firmware = bladeSpec["firmware_version"].(string)
+	}
+	if len(compute["blade_assigned"].([]interface{})) > 0 && compute["blade_assigned"].([]interface{})[0] != nil {
+		nodeConfig.Compute.Placement = compute["blade_assigned"].([]interface{})[0].(map[string]interface{})["placement"].(string)
 	}
 	if len(compute["chassis_id"].(string)) > 0 {
 		nodeConfig.Compute.ChassisId = compute["chassis_id"].(string)
@@ -638,6 +656,7 @@ func setFlexbotEsxHostOutput(d *schema.ResourceData, meta interface{}, nodeConfi
 	bladeAssigned["num_of_cores"] = nodeConfig.Compute.BladeAssigned.NumOfCores
 	bladeAssigned["num_of_threads"] = nodeConfig.Compute.BladeAssigned.NumOfThreads
 	bladeAssigned["total_memory"] = nodeConfig.Compute.BladeAssigned.TotalMemory
+	bladeAssigned["placement"] = nodeConfig.Compute.Placement
 	if len(compute["blade_assigned"].([]interface{})) > 0 {
 		compute["blade_assigned"].([]interface{})[0] = bladeAssigned
 	} else {
@@ -702,7 +721,7 @@ func shutdownEsxHost(d *schema.ResourceData, meta interface{}, nodeConfig *confi
 			if err = vmwareAPI.VMwareAPIShutdownHost(EsxShutdownTimeout); err == nil {
 				giveupTime := time.Now().Add(time.Second * time.Duration(EsxShutdownTimeout))
 				for time.Now().Before(giveupTime) {
-					if operState, err = ucsm.GetServerOperationalState(nodeConfig); err != nil {
+					if operState, err = server.GetServerOperationalState(nodeConfig); err != nil {
 						return
 					}
 					if operState == "power-off" {
@@ -13,8 +13,8 @@ import (
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ipam"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap"
-	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ucsm"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/rancher"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/server"
 )
 
 const (
@@ -59,7 +59,7 @@ func resourceCreateHarvesterNode(ctx context.Context, d *schema.ResourceData, me
 	}
 	log.Infof("Creating Harvester Node %s", nodeConfig.Compute.HostName)
 	var nodeExists bool
-	if nodeExists, err = ucsm.DiscoverServer(nodeConfig); err != nil {
+	if nodeExists, err = server.DiscoverServer(nodeConfig); err != nil {
 		diags = diag.FromErr(err)
 		return
 	}
@@ -86,10 +86,10 @@ func resourceCreateHarvesterNode(ctx context.Context, d *schema.ResourceData, me
 			Detail:   err.Error(),
 		})
 	}
-	if err = ucsm.CreateServerPreflight(nodeConfig); err != nil {
+	if err = server.CreateServerPreflight(nodeConfig); err != nil {
 		diags = append(diags, diag.Diagnostic{
 			Severity: diag.Error,
-			Summary:  "ucsm.CreateServerPreflight()",
+			Summary:  "server.CreateServerPreflight()",
 			Detail:   err.Error(),
 		})
 	}
@@ -120,7 +120,7 @@ func resourceCreateHarvesterNode(ctx context.Context, d *schema.ResourceData, me
 		time.Sleep(time.Duration(StorageRetryTimeout * (i + 1)) * time.Second)
 	}
 	if err == nil {
-		_, err = ucsm.CreateServer(nodeConfig)
+		err = server.CreateServer(nodeConfig)
 	}
 	if err == nil {
 		for i := 0; i < StorageRetryAttempts; i++ {
@@ -132,7 +132,7 @@ func resourceCreateHarvesterNode(ctx context.Context, d *schema.ResourceData, me
 		}
 	}
 	if err == nil {
-		if err = ucsm.StartServer(nodeConfig); err == nil {
+		if err = server.StartServer(nodeConfig); err == nil {
 			err = waitForHostNetwork(nodeConfig, ServerBootTimeout)
 		}
 	} else {
@@ -143,10 +143,10 @@ func resourceCreateHarvesterNode(ctx context.Context, d *schema.ResourceData, me
 		d.SetConnInfo(map[string]string{"type": "ssh", "host": nodeConfig.Network.Node[0].Ip})
 		meta.(*config.FlexbotConfig).Sync.Unlock()
 		if err = waitForOperationalState(nodeConfig, "power-off", HarvesterInstallerStage1Timeout); err == nil {
-			ucsm.StopServer(nodeConfig)
+			server.StopServer(nodeConfig)
 			if err = waitForPowerState(nodeConfig, "down", ServerPowerStateTimeout); err == nil {
 				if err = ontap.RemapHarvesterStorage(nodeConfig); err == nil {
-					err = ucsm.StartServer(nodeConfig)
+					err = server.StartServer(nodeConfig)
 					if err == nil && len(sshUser) > 0 && len(sshPrivateKey) > 0 {
 						waitForSshTimeout := HarvesterInstallerStage2Timeout
 						if compute["wait_for_ssh_timeout"].(int) > 0 {
@@ -195,7 +195,7 @@ func resourceReadHarvesterNode(ctx context.Context, d *schema.ResourceData, meta
 	}
 	log.Infof("Refreshing Harvester Node %s", nodeConfig.Compute.HostName)
 	var nodeExists bool
-	if nodeExists, err = ucsm.DiscoverServer(nodeConfig); err != nil {
+	if nodeExists, err = server.DiscoverServer(nodeConfig); err != nil {
 		diags = diag.FromErr(err)
 		return
 	}
@@ -318,10 +318,10 @@ func resourceUpdateHarvesterNodeCompute(d *schema.ResourceData, meta interface{}
 	if (nodeConfig.ChangeStatus & ChangeBladeSpec) > 0 {
 		nodeConfig.Compute.BladeSpec.Dn = newBladeSpec["dn"].(string)
 	}
-	if powerState, err = ucsm.GetServerPowerState(nodeConfig); err != nil {
+	if powerState, err = server.GetServerPowerState(nodeConfig); err != nil {
 		return
 	}
-	if operState, err = ucsm.GetServerOperationalState(nodeConfig); err != nil {
+	if operState, err = server.GetServerOperationalState(nodeConfig); err != nil {
 		return
 	}
 	nodeConfig.Compute.Powerstate = powerState
@@ -339,7 +339,7 @@ func resourceUpdateHarvesterNodeCompute(d *schema.ResourceData, meta interface{}
 		log.Infof("Updating Harvester node %s", nodeConfig.Compute.HostName)
 	        if (nodeConfig.ChangeStatus & ChangeBladeSpec) > 0 {
 	        	log.Infof("Running compute  preflight check")
-	                if err = ucsm.UpdateServerPreflight(nodeConfig); err != nil {
+	                if err = server.UpdateServerPreflight(nodeConfig); err != nil {
 			        err = fmt.Errorf("resourceUpdateHarvesterNode(compute): error: %s", err)
 			        meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 			        return
@@ -367,21 +367,21 @@ func resourceUpdateHarvesterNodeCompute(d *schema.ResourceData, meta interface{}
 				}
 			}
 			log.Infof("Power off node %s", nodeConfig.Compute.HostName)
-			if err = ucsm.StopServer(nodeConfig); err != nil {
+			if err = server.StopServer(nodeConfig); err != nil {
 				meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 				return
 			}
 		}
 		if (nodeConfig.ChangeStatus & ChangeBladeSpec) > 0 {
 			log.Infof("Changing blade specification for node %s", nodeConfig.Compute.HostName)
-			if err = ucsm.UpdateServer(nodeConfig); err != nil {
+			if err = server.UpdateServer(nodeConfig); err != nil {
 				meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 				return
 			}
 		}
 		if newPowerState == "up" {
 			log.Infof("Power on node %s", nodeConfig.Compute.HostName)
-			if err = ucsm.StartServer(nodeConfig); err != nil {
+			if err = server.StartServer(nodeConfig); err != nil {
 				meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 				return
 			}
@@ -419,7 +419,7 @@ func resourceUpdateHarvesterNodeCompute(d *schema.ResourceData, meta interface{}
 	}
 	if (oldCompute.([]interface{})[0].(map[string]interface{}))["description"].(string) != (newCompute.([]interface{})[0].(map[string]interface{}))["description"].(string) ||
 		(oldCompute.([]interface{})[0].(map[string]interface{}))["label"].(string) != (newCompute.([]interface{})[0].(map[string]interface{}))["label"].(string) {
-		err = ucsm.UpdateServerAttributes(nodeConfig)
+		err = server.UpdateServerAttributes(nodeConfig)
 	}
 	return
 }
@@ -457,11 +457,11 @@ func resourceUpdateHarvesterNodeStorage(d *schema.ResourceData, meta interface{}
 			err = fmt.Errorf("resourceUpdateHarvesterNode(storage): last resource instance update returned error: %s", err)
 			return
 		}
-		if powerState, err = ucsm.GetServerPowerState(nodeConfig); err != nil {
+		if powerState, err = server.GetServerPowerState(nodeConfig); err != nil {
 			meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 			return
 		}
-		if operState, err = ucsm.GetServerOperationalState(nodeConfig); err != nil {
+		if operState, err = server.GetServerOperationalState(nodeConfig); err != nil {
 			meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 			return
 		}
@@ -508,7 +508,7 @@ func resourceUpdateHarvesterNodeStorage(d *schema.ResourceData, meta interface{}
 		}
 		if powerState == "up" {
 			log.Infof("Power off harvester node %s", nodeConfig.Compute.HostName)
-			if err = ucsm.StopServer(nodeConfig); err != nil {
+			if err = server.StopServer(nodeConfig); err != nil {
 				meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 				return
 			}
@@ -531,7 +531,7 @@ func resourceUpdateHarvesterNodeStorage(d *schema.ResourceData, meta interface{}
 			return
 		}
 		log.Infof("Power on harvester node %s", nodeConfig.Compute.HostName)
-		if err = ucsm.StartServer(nodeConfig); err != nil {
+		if err = server.StartServer(nodeConfig); err != nil {
 			meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 			return
 		}
@@ -540,10 +540,10 @@ func resourceUpdateHarvesterNodeStorage(d *schema.ResourceData, meta interface{}
 			return
 		}
 		if err = waitForOperationalState(nodeConfig, "power-off", HarvesterInstallerStage1Timeout); err == nil {
-			ucsm.StopServer(nodeConfig)
+			server.StopServer(nodeConfig)
 			if err = waitForPowerState(nodeConfig, "down", ServerPowerStateTimeout); err == nil {
 				if err = ontap.RemapHarvesterStorage(nodeConfig); err == nil {
-					err = ucsm.StartServer(nodeConfig)
+					err = server.StartServer(nodeConfig)
 					if err == nil && len(sshUser) > 0 && len(sshPrivateKey) > 0 {
 						waitForSshTimeout := HarvesterInstallerStage2Timeout
 						if compute["wait_for_ssh_timeout"].(int) > 0 {
@@ -592,11 +592,11 @@ func resourceDeleteHarvesterNode(ctx context.Context, d *schema.ResourceData, me
         meta.(*config.FlexbotConfig).Sync.Lock()
 	compute := d.Get("compute").([]interface{})[0].(map[string]interface{})
         meta.(*config.FlexbotConfig).Sync.Unlock()
-	if powerState, err = ucsm.GetServerPowerState(nodeConfig); err != nil {
+	if powerState, err = server.GetServerPowerState(nodeConfig); err != nil {
 		diags = diag.FromErr(err)
 		return
 	}
-	if operState, err = ucsm.GetServerOperationalState(nodeConfig); err != nil {
+	if operState, err = server.GetServerOperationalState(nodeConfig); err != nil {
 		diags = diag.FromErr(err)
 		return
 	}
@@ -627,15 +627,15 @@ func resourceDeleteHarvesterNode(ctx context.Context, d *schema.ResourceData, me
 		return
 	}
 	if powerState == "up" {
-		if err = ucsm.StopServer(nodeConfig); err != nil {
+		if err = server.StopServer(nodeConfig); err != nil {
 			diags = diag.FromErr(err)
 			return
 		}
         }
-	if err = ucsm.DeleteServer(nodeConfig); err != nil {
+	if err = server.DeleteServer(nodeConfig); err != nil {
 		diags = append(diags, diag.Diagnostic{
 			Severity: diag.Error,
-			Summary:  "ucsm.DeleteServer()",
+			Summary:  "server.DeleteServer()",
 			Detail:   err.Error(),
 		})
 	}
@@ -701,6 +701,7 @@ func setFlexbotHarvesterNodeInput(d *schema.ResourceData, meta interface{}) (nod
 	nodeConfig.Compute.UcsmCredentials.Host = ucsmCredentials["host"].(string)
 	nodeConfig.Compute.UcsmCredentials.User = ucsmCredentials["user"].(string)
 	nodeConfig.Compute.UcsmCredentials.Password = ucsmCredentials["password"].(string)
+	nodeConfig.Compute.Provider = pCompute["provider"].(string)
 	pStorage := p.Get("storage").([]interface{})[0].(map[string]interface{})
 	cdotCredentials := pStorage["credentials"].([]interface{})[0].(map[string]interface{})
 	nodeConfig.Storage.CdotCredentials.Host = cdotCredentials["host"].(string)
@@ -708,8 +709,20 @@ func setFlexbotHarvesterNodeInput(d *schema.ResourceData, meta interface{}) (nod
 	nodeConfig.Storage.CdotCredentials.Password = cdotCredentials["password"].(string)
 	nodeConfig.Storage.CdotCredentials.ApiMethod = cdotCredentials["api_method"].(string)
 	nodeConfig.Storage.CdotCredentials.ZapiVersion = cdotCredentials["zapi_version"].(string)
+	setSignaturesInput(p, nodeConfig)
+	setUploadInput(p, nodeConfig)
+	setArtifactSourcesInput(p, nodeConfig)
 	nodeConfig.Compute.SpOrg = compute["sp_org"].(string)
 	nodeConfig.Compute.SpTemplate = compute["sp_template"].(string)
+	nodeConfig.Compute.SystemId = compute["system_id"].(string)
+	nodeConfig.Compute.RedfishCredentials.Host = compute["bmc_host"].(string)
+	if nodeConfig.Compute.RedfishCredentials.Host == "" {
+		nodeConfig.Compute.RedfishCredentials.Host = nodeConfig.Compute.UcsmCredentials.Host
+	}
+	nodeConfig.Compute.RedfishCredentials.User = nodeConfig.Compute.UcsmCredentials.User
+	nodeConfig.Compute.RedfishCredentials.Password = nodeConfig.Compute.UcsmCredentials.Password
+	nodeConfig.Compute.IntersightCredentials = nodeConfig.Compute.UcsmCredentials
+	setIntersightTLSInput(p, nodeConfig)
 	nodeConfig.Compute.Description = compute["description"].(string)
 	nodeConfig.Compute.Label = compute["label"].(string)
 	if len(compute["blade_spec"].([]interface{})) > 0 {
@@ -720,6 +733,11 @@ func setFlexbotHarvesterNodeInput(d *schema.ResourceData, meta interface{}) (nod
 		nodeConfig.Compute.BladeSpec.NumOfCores = bladeSpec["num_of_cores"].(string)
 		nodeConfig.Compute.BladeSpec.NumOfThreads = bladeSpec["num_of_threads"].(string)
 		nodeConfig.Compute.BladeSpec.TotalMemory = bladeSpec["total_memory"].(string)
+		nodeConfig.Compute.BladePlacement.Policy = bladeSpec["placement"].(string)
+		nodeConfig.Compute.BladePlacement.Firmware = bladeSpec["firmware_version"].(string)
+	}
+	if len(compute["blade_assigned"].([]interface{})) > 0 && compute["blade_assigned"].([]interface{})[0] != nil {
+		nodeConfig.Compute.Placement = compute["blade_assigned"].([]interface{})[0].(map[string]interface{})["placement"].(string)
 	}
 	if len(compute["chassis_id"].(string)) > 0 {
 		nodeConfig.Compute.ChassisId = compute["chassis_id"].(string)
@@ -811,6 +829,7 @@ func setFlexbotHarvesterNodeOutput(d *schema.ResourceData, meta interface{}, nod
 	bladeAssigned["num_of_cores"] = nodeConfig.Compute.BladeAssigned.NumOfCores
 	bladeAssigned["num_of_threads"] = nodeConfig.Compute.BladeAssigned.NumOfThreads
 	bladeAssigned["total_memory"] = nodeConfig.Compute.BladeAssigned.TotalMemory
+	bladeAssigned["placement"] = nodeConfig.Compute.Placement
 	if len(compute["blade_assigned"].([]interface{})) > 0 {
 		compute["blade_assigned"].([]interface{})[0] = bladeAssigned
 	} else {
@@ -3,6 +3,7 @@ package flexbot
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
@@ -46,10 +47,19 @@ func resourceCreateRepo(ctx context.Context, d *schema.ResourceData, meta interf
 		diags = diag.FromErr(err)
 		return
 	}
+	if err = syncRepoManifest(d, nodeConfig); err != nil {
+		diags = diag.FromErr(err)
+		return
+	}
+	if err = gcRepoImages(d, nodeConfig); err != nil {
+		diags = diag.FromErr(err)
+		return
+	}
 	if diags = resourceReadRepo(ctx, d, meta); diags != nil && len(diags) > 0 {
 		return
 	}
 	d.SetId(nodeConfig.Storage.CdotCredentials.Host + ":/repo")
+	diags = append(diags, storageApiDiagnostics(nodeConfig)...)
 	return
 }
 
@@ -69,7 +79,7 @@ func createRepo(d *schema.ResourceData, nodeConfig *config.NodeConfig, repo stri
 					time.Sleep(5 * time.Second)
 				}
 				if err == nil {
-					err = ontap.CreateRepoImage(nodeConfig, repoItem.(map[string]interface{})["name"].(string), repoItem.(map[string]interface{})["location"].(string))
+					err = ontap.CreateRepoImage(nodeConfig, repoItem.(map[string]interface{})["name"].(string), repoItem.(map[string]interface{})["location"].(string), repoItem.(map[string]interface{})["checksum"].(string), repoItem.(map[string]interface{})["signature"].(string), uploadProgressLogger(repoItem.(map[string]interface{})["name"].(string)))
 				}
 			}
 			if repo == "template_repo" {
@@ -78,7 +88,7 @@ func createRepo(d *schema.ResourceData, nodeConfig *config.NodeConfig, repo stri
 					time.Sleep(5 * time.Second)
 				}
 				if err == nil {
-					err = ontap.CreateRepoTemplate(nodeConfig, repoItem.(map[string]interface{})["name"].(string), repoItem.(map[string]interface{})["location"].(string))
+					err = ontap.CreateRepoTemplate(nodeConfig, repoItem.(map[string]interface{})["name"].(string), repoItem.(map[string]interface{})["location"].(string), repoItem.(map[string]interface{})["signature"].(string))
 				}
 			}
 		}
@@ -111,6 +121,17 @@ func resourceUpdateRepo(ctx context.Context, d *schema.ResourceData, meta interf
 	if err == nil && d.HasChange("template_repo") && !d.IsNewResource() {
 		err = updateRepo(d, meta, "template_repo")
 	}
+	if err == nil && !d.IsNewResource() {
+		var nodeConfig *config.NodeConfig
+		if nodeConfig, err = setRepoInput(d, meta); err == nil {
+			if d.HasChange("manifest") {
+				err = syncRepoManifest(d, nodeConfig)
+			}
+			if err == nil {
+				err = gcRepoImages(d, nodeConfig)
+			}
+		}
+	}
 	if err == nil {
 		diags = resourceReadRepo(ctx, d, meta)
 	} else {
@@ -139,6 +160,15 @@ func updateRepo(d *schema.ResourceData, meta interface{}, repo string) (err erro
 	if repo == "template_repo" {
 		repoStorage, err = ontap.GetRepoTemplates(nodeConfig)
 	}
+	if err == nil && repo == "image_repo" && repoImageGcProtectInUse(d) {
+		var removed []string
+		for _, name := range oldRepoState {
+			if stringSliceElementExists(repoStorage, name) && !stringSliceElementExists(repoStateInter, name) {
+				removed = append(removed, name)
+			}
+		}
+		err = ontap.CheckRepoImagesNotInUse(nodeConfig, removed)
+	}
 	for _, name := range oldRepoState {
 		if err == nil {
 			if stringSliceElementExists(repoStorage, name) && !stringSliceElementExists(repoStateInter, name) {
@@ -166,15 +196,22 @@ func updateRepo(d *schema.ResourceData, meta interface{}, repo string) (err erro
 				for _, oldRepoItem := range oldRepo.([]interface{}) {
 					if newRepoItem.(map[string]interface{})["name"].(string) == oldRepoItem.(map[string]interface{})["name"].(string) && newRepoItem.(map[string]interface{})["location"].(string) == oldRepoItem.(map[string]interface{})["location"].(string) {
 						locationChanged = false
+						// changed image checksum (or checksum drift detected in repository) triggers image update
+						if repo == "image_repo" && newRepoItem.(map[string]interface{})["checksum"].(string) != oldRepoItem.(map[string]interface{})["checksum"].(string) {
+							locationChanged = true
+						}
+						if newRepoItem.(map[string]interface{})["signature"].(string) != oldRepoItem.(map[string]interface{})["signature"].(string) {
+							locationChanged = true
+						}
 					}
 				}
 				if repo == "image_repo" {
-					if stringSliceElementExists(repoStorage, newRepoItem.(map[string]interface{})["name"].(string)) && locationChanged {
-						err = ontap.DeleteRepoImage(nodeConfig, newRepoItem.(map[string]interface{})["name"].(string))
-						time.Sleep(5 * time.Second)
+					// updated image replaces existing image only once uploaded and verified, image in use is not replaced
+					if stringSliceElementExists(repoStorage, newRepoItem.(map[string]interface{})["name"].(string)) && locationChanged && repoImageGcProtectInUse(d) {
+						err = ontap.CheckRepoImagesNotInUse(nodeConfig, []string{newRepoItem.(map[string]interface{})["name"].(string)})
 					}
 					if err == nil && (!stringSliceElementExists(repoStateInter, newRepoItem.(map[string]interface{})["name"].(string)) || locationChanged) {
-						err = ontap.CreateRepoImage(nodeConfig, newRepoItem.(map[string]interface{})["name"].(string), newRepoItem.(map[string]interface{})["location"].(string))
+						err = ontap.CreateRepoImage(nodeConfig, newRepoItem.(map[string]interface{})["name"].(string), newRepoItem.(map[string]interface{})["location"].(string), newRepoItem.(map[string]interface{})["checksum"].(string), newRepoItem.(map[string]interface{})["signature"].(string), uploadProgressLogger(newRepoItem.(map[string]interface{})["name"].(string)))
 					}
 				}
 				if repo == "template_repo" {
@@ -183,7 +220,7 @@ func updateRepo(d *schema.ResourceData, meta interface{}, repo string) (err erro
 						time.Sleep(5 * time.Second)
 					}
 					if err == nil && (!stringSliceElementExists(repoStateInter, newRepoItem.(map[string]interface{})["name"].(string)) || locationChanged) {
-						err = ontap.CreateRepoTemplate(nodeConfig, newRepoItem.(map[string]interface{})["name"].(string), newRepoItem.(map[string]interface{})["location"].(string))
+						err = ontap.CreateRepoTemplate(nodeConfig, newRepoItem.(map[string]interface{})["name"].(string), newRepoItem.(map[string]interface{})["location"].(string), newRepoItem.(map[string]interface{})["signature"].(string))
 					}
 				}
 			}
@@ -200,6 +237,17 @@ func resourceDeleteRepo(ctx context.Context, d *schema.ResourceData, meta interf
 	var nodeConfig *config.NodeConfig
 	log.Infof("Deleting Image Repository")
 	nodeConfig, err = setRepoInput(d, meta)
+	if err == nil && repoImageGcProtectInUse(d) {
+		var images, removed []string
+		if images, err = ontap.GetRepoImages(nodeConfig); err == nil {
+			for _, repoItem := range d.Get("image_repo").([]interface{}) {
+				if stringSliceElementExists(images, repoItem.(map[string]interface{})["name"].(string)) {
+					removed = append(removed, repoItem.(map[string]interface{})["name"].(string))
+				}
+			}
+			err = ontap.CheckRepoImagesNotInUse(nodeConfig, removed)
+		}
+	}
 	for _, repoItem := range d.Get("image_repo").([]interface{}) {
 		if err == nil {
 			err = ontap.DeleteRepoImage(nodeConfig, repoItem.(map[string]interface{})["name"].(string))
@@ -237,6 +285,9 @@ func setRepoInput(d *schema.ResourceData, meta interface{}) (nodeConfig *config.
 	nodeConfig.Storage.CdotCredentials.Password = cdotCredentials["password"].(string)
 	nodeConfig.Storage.CdotCredentials.ApiMethod = cdotCredentials["api_method"].(string)
 	nodeConfig.Storage.CdotCredentials.ZapiVersion = cdotCredentials["zapi_version"].(string)
+	setSignaturesInput(p, nodeConfig)
+	setUploadInput(p, nodeConfig)
+	setArtifactSourcesInput(p, nodeConfig)
 	if err = config.SetDefaults(nodeConfig, "", "", "", p.Get("pass_phrase").(string)); err != nil {
 		err = fmt.Errorf("SetDefaults(): failure: %s", err)
 	}
@@ -245,13 +296,169 @@ func setRepoInput(d *schema.ResourceData, meta interface{}) (nodeConfig *config.
 
 func setRepoOutput(d *schema.ResourceData, meta interface{}, nodeConfig *config.NodeConfig) (err error) {
 	var images, templates []string
+	var imagesInfo []ontap.RepoImageInfo
 	meta.(*config.FlexbotConfig).Sync.Lock()
 	defer meta.(*config.FlexbotConfig).Sync.Unlock()
-	if images, err = ontap.GetRepoImages(nodeConfig); err == nil {
+	if imagesInfo, err = ontap.GetRepoImagesInfo(nodeConfig); err == nil {
+		imageChecksums := make(map[string]interface{})
+		for _, imageInfo := range imagesInfo {
+			images = append(images, imageInfo.Name)
+			if imageInfo.Sha256 != "" {
+				imageChecksums[imageInfo.Name] = "sha256:" + imageInfo.Sha256
+			}
+		}
 		d.Set("images", images)
+		d.Set("image_checksums", imageChecksums)
+		setRepoImageChecksumDrift(d, imagesInfo)
 		if templates, err = ontap.GetRepoTemplates(nodeConfig); err == nil {
 			d.Set("templates", templates)
 		}
 	}
+	if err == nil {
+		err = setRepoManifestPlan(d, nodeConfig)
+	}
+	if err == nil {
+		var imagesUsage []ontap.RepoImageUsage
+		if imagesUsage, err = ontap.GetRepoImagesUsage(nodeConfig); err == nil {
+			imageConsumers := make(map[string]interface{})
+			for _, imageUsage := range imagesUsage {
+				imageConsumers[imageUsage.Name] = strings.Join(imageUsage.Consumers, ",")
+			}
+			d.Set("image_consumers", imageConsumers)
+		}
+	}
+	return
+}
+
+// repoImageGcProtectInUse checks if deletion of images in use must be refused
+func repoImageGcProtectInUse(d *schema.ResourceData) bool {
+	if imageGc := d.Get("image_gc").([]interface{}); len(imageGc) > 0 && imageGc[0] != nil {
+		return imageGc[0].(map[string]interface{})["protect_in_use"].(bool)
+	}
+	return false
+}
+
+// gcRepoImages deletes images which are not declared in image_repo, not in use and older than image_gc max_age
+func gcRepoImages(d *schema.ResourceData, nodeConfig *config.NodeConfig) (err error) {
+	imageGc := d.Get("image_gc").([]interface{})
+	if len(imageGc) == 0 || imageGc[0] == nil || imageGc[0].(map[string]interface{})["max_age"].(string) == "" {
+		return
+	}
+	var keepImages, pruned []string
+	for _, repoItem := range d.Get("image_repo").([]interface{}) {
+		keepImages = append(keepImages, repoItem.(map[string]interface{})["name"].(string))
+	}
+	if manifest := d.Get("manifest").([]interface{}); len(manifest) > 0 && manifest[0] != nil {
+		var repoManifest *ontap.RepoManifest
+		if repoManifest, err = ontap.ReadRepoManifest(nodeConfig, manifest[0].(map[string]interface{})["location"].(string)); err != nil {
+			err = fmt.Errorf("gcRepoImages(): %s", err)
+			return
+		}
+		for _, image := range repoManifest.Images {
+			keepImages = append(keepImages, image.Name)
+		}
+	}
+	if _, pruned, err = ontap.GcRepoImages(nodeConfig, imageGc[0].(map[string]interface{})["max_age"].(string), keepImages); err != nil {
+		err = fmt.Errorf("gcRepoImages(): %s", err)
+		return
+	}
+	for _, imageName := range pruned {
+		log.Infof("Deleted unused image \"%s\" from image repository", imageName)
+	}
+	return
+}
+
+// setRepoImageChecksumDrift sets in state digest of repository image if it differs from declared image checksum,
+// checksum URLs are not resolved and images without digest are ignored
+func setRepoImageChecksumDrift(d *schema.ResourceData, imagesInfo []ontap.RepoImageInfo) {
+	var drift bool
+	imageRepo := d.Get("image_repo").([]interface{})
+	for _, repoItem := range imageRepo {
+		checksum := repoItem.(map[string]interface{})["checksum"].(string)
+		if checksum == "" || strings.Contains(checksum, "/") {
+			continue
+		}
+		for _, imageInfo := range imagesInfo {
+			if imageInfo.Name == repoItem.(map[string]interface{})["name"].(string) && imageInfo.Sha256 != "" {
+				if strings.ToLower(strings.TrimPrefix(checksum, "sha256:")) != imageInfo.Sha256 {
+					log.Warnf("image \"%s\" checksum drift: declared %s, repository sha256:%s", imageInfo.Name, checksum, imageInfo.Sha256)
+					repoItem.(map[string]interface{})["checksum"] = "sha256:" + imageInfo.Sha256
+					drift = true
+				}
+			}
+		}
+	}
+	if drift {
+		d.Set("image_repo", imageRepo)
+	}
+}
+
+// repoInlineItems gets names of images and templates declared in image_repo and template_repo
+func repoInlineItems(d *schema.ResourceData) (items []string) {
+	for _, repo := range []string{"image_repo", "template_repo"} {
+		for _, repoItem := range d.Get(repo).([]interface{}) {
+			items = append(items, repoItem.(map[string]interface{})["name"].(string))
+		}
+	}
+	return
+}
+
+// planRepoManifest reads manifest and compares repository with it, images and templates declared inline are never pruned
+func planRepoManifest(d *schema.ResourceData, nodeConfig *config.NodeConfig) (repoManifest *ontap.RepoManifest, changes []ontap.RepoSyncChange, err error) {
+	manifest := d.Get("manifest").([]interface{})
+	if len(manifest) == 0 || manifest[0] == nil {
+		return
+	}
+	if repoManifest, err = ontap.ReadRepoManifest(nodeConfig, manifest[0].(map[string]interface{})["location"].(string)); err != nil {
+		return
+	}
+	changes, err = ontap.PlanRepoSync(nodeConfig, repoManifest, manifest[0].(map[string]interface{})["prune"].(bool), repoInlineItems(d))
+	return
+}
+
+// syncRepoManifest reconciles repository with manifest
+func syncRepoManifest(d *schema.ResourceData, nodeConfig *config.NodeConfig) (err error) {
+	var repoManifest *ontap.RepoManifest
+	var changes []ontap.RepoSyncChange
+	if repoManifest, changes, err = planRepoManifest(d, nodeConfig); err != nil {
+		err = fmt.Errorf("syncRepoManifest(): %s", err)
+		return
+	}
+	if repoManifest == nil || !ontap.RepoSyncPending(changes) {
+		return
+	}
+	log.Infof("Repository manifest sync: %s", ontap.RepoSyncSummary(changes))
+	if err = ontap.ApplyRepoSync(nodeConfig, repoManifest, changes, d.Get("manifest").([]interface{})[0].(map[string]interface{})["parallelism"].(int), uploadProgressLogger); err != nil {
+		err = fmt.Errorf("syncRepoManifest(): %s", err)
+	}
+	return
+}
+
+// setRepoManifestPlan sets in state pending manifest changes,
+// manifest location is reset in state if repository is out of sync so that next apply reconciles it
+func setRepoManifestPlan(d *schema.ResourceData, nodeConfig *config.NodeConfig) (err error) {
+	var changes []ontap.RepoSyncChange
+	if _, changes, err = planRepoManifest(d, nodeConfig); err != nil {
+		err = fmt.Errorf("setRepoManifestPlan(): %s", err)
+		return
+	}
+	var plan []string
+	for _, change := range changes {
+		if change.Action == ontap.RepoSyncUnchanged {
+			continue
+		}
+		item := change.Action + " " + change.Kind + " " + change.Name
+		if change.Reason != "" {
+			item += " (" + change.Reason + ")"
+		}
+		plan = append(plan, item)
+	}
+	d.Set("manifest_plan", plan)
+	if ontap.RepoSyncPending(changes) {
+		log.Warnf("image repository is out of sync with manifest: %s", ontap.RepoSyncSummary(changes))
+		manifest := d.Get("manifest").([]interface{})
+		manifest[0].(map[string]interface{})["location"] = ""
+		d.Set("manifest", manifest)
+	}
 	return
 }
@@ -3,8 +3,10 @@ package flexbot
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
@@ -14,8 +16,9 @@ import (
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ipam"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap"
-	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ucsm"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/rancher"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/server"
 )
 
 func resourceFlexbotServer() *schema.Resource {
@@ -53,6 +56,9 @@ func resourceCreateServer(ctx context.Context, d *schema.ResourceData, meta inte
 		diags = diag.FromErr(err)
 		return
 	}
+	if isDryRun(meta) {
+		nodeConfig.Plan = config.NewPlan()
+	}
 	for _, snapshot := range d.Get("snapshot").([]interface{}) {
 		name := snapshot.(map[string]interface{})["name"].(string)
 		if snapshot.(map[string]interface{})["fsfreeze"].(bool) {
@@ -64,7 +70,7 @@ func resourceCreateServer(ctx context.Context, d *schema.ResourceData, meta inte
 	}
 	log.Infof("Creating Server %s", nodeConfig.Compute.HostName)
 	var serverExists bool
-	if serverExists, err = ucsm.DiscoverServer(nodeConfig); err != nil {
+	if serverExists, err = server.DiscoverServer(nodeConfig); err != nil {
 		diags = diag.FromErr(err)
 		return
 	}
@@ -91,10 +97,10 @@ func resourceCreateServer(ctx context.Context, d *schema.ResourceData, meta inte
 			Detail:   err.Error(),
 		})
 	}
-	if err = ucsm.CreateServerPreflight(nodeConfig); err != nil {
+	if err = server.CreateServerPreflight(nodeConfig); err != nil {
 		diags = append(diags, diag.Diagnostic{
 			Severity: diag.Error,
-			Summary:  "ucsm.CreateServerPreflight()",
+			Summary:  "server.CreateServerPreflight()",
 			Detail:   err.Error(),
 		})
 	}
@@ -108,6 +114,10 @@ func resourceCreateServer(ctx context.Context, d *schema.ResourceData, meta inte
 	if len(diags) > 0 {
 		return
 	}
+	if nodeConfig.Plan != nil {
+		diags = planCreateServer(d, nodeConfig)
+		return
+	}
 	if err = ipamProvider.Allocate(nodeConfig); err != nil {
 		diags = diag.FromErr(fmt.Errorf("resourceCreateServer(): %s", err))
 		return
@@ -125,7 +135,10 @@ func resourceCreateServer(ctx context.Context, d *schema.ResourceData, meta inte
 		time.Sleep(time.Duration(StorageRetryTimeout * (i + 1)) * time.Second)
 	}
 	if err == nil {
-		_, err = ucsm.CreateServer(nodeConfig)
+		err = server.CreateServer(nodeConfig)
+	}
+	if err == nil && len(nodeConfig.Network.FcInitiator) > 0 {
+		err = ontap.SetFcInitiators(nodeConfig)
 	}
 	if err == nil {
 		for i := 0; i < StorageRetryAttempts; i++ {
@@ -141,7 +154,7 @@ func resourceCreateServer(ctx context.Context, d *schema.ResourceData, meta inte
 		}
 	}
 	if err == nil {
-		err = ucsm.StartServer(nodeConfig)
+		err = server.StartServer(nodeConfig)
 	} else {
 		ontap.DeleteBootStorage(nodeConfig)
 	}
@@ -177,6 +190,12 @@ func resourceCreateServer(ctx context.Context, d *schema.ResourceData, meta inte
 			}
 		}
 	}
+	if err == nil {
+		err = pruneServerSnapshots(d, meta, nodeConfig)
+	}
+	if err == nil {
+		err = ontap.CreateReplication(nodeConfig)
+	}
 	setFlexbotOutput(d, meta, nodeConfig)
 	if err == nil {
 		var rancherNode rancher.RancherNode
@@ -194,6 +213,7 @@ func resourceCreateServer(ctx context.Context, d *schema.ResourceData, meta inte
 			})
 		}
 	}
+	diags = append(diags, storageApiDiagnostics(nodeConfig)...)
 	return
 }
 
@@ -206,10 +226,17 @@ func resourceReadServer(ctx context.Context, d *schema.ResourceData, meta interf
 	}
 	log.Infof("Refreshing Server %s", nodeConfig.Compute.HostName)
 	var serverExists bool
-	if serverExists, err = ucsm.DiscoverServer(nodeConfig); err != nil {
+	if serverExists, err = server.DiscoverServer(nodeConfig); err != nil {
 		diags = diag.FromErr(err)
 		return
 	}
+	var storageDrift []string
+	if serverExists && d.Get("storage.0.verify").(bool) {
+		if storageDrift, err = verifyServerStorage(nodeConfig); err != nil {
+			diags = diag.FromErr(err)
+			return
+		}
+	}
 	var storageExists bool
 	if storageExists, err = ontap.DiscoverBootStorage(nodeConfig); err != nil {
 		diags = diag.FromErr(err)
@@ -230,6 +257,10 @@ func resourceReadServer(ctx context.Context, d *schema.ResourceData, meta interf
 			return
 		}
 		setFlexbotOutput(d, meta, nodeConfig)
+		if len(storageDrift) > 0 {
+			setServerStorageDrift(d, meta, storageDrift)
+			diags = append(diags, storageDriftDiagnostics(nodeConfig, storageDrift)...)
+		}
 	} else {
                 meta.(*config.FlexbotConfig).Sync.Lock()
 		d.SetId("")
@@ -238,12 +269,47 @@ func resourceReadServer(ctx context.Context, d *schema.ResourceData, meta interf
 	return
 }
 
+// verifyServerStorage deeply verifies server storage against state, discrepancies are reported in "storage.drift"
+func verifyServerStorage(nodeConfig *config.NodeConfig) (storageDrift []string, err error) {
+	var discrepancies []ontap.StorageDiscrepancy
+	if discrepancies, err = ontap.VerifyBootStorage(nodeConfig); err != nil {
+		err = fmt.Errorf("verifyServerStorage(): %s", err)
+		return
+	}
+	for _, discrepancy := range discrepancies {
+		log.Warnf("Server %s storage drift: %s", nodeConfig.Compute.HostName, discrepancy.String())
+		storageDrift = append(storageDrift, discrepancy.String())
+	}
+	return
+}
+
+// storageDriftDiagnostics reports storage discrepancies as warnings, drift does not trigger update
+func storageDriftDiagnostics(nodeConfig *config.NodeConfig, storageDrift []string) (diags diag.Diagnostics) {
+	for _, discrepancy := range storageDrift {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("Server %s storage drift", nodeConfig.Compute.HostName),
+			Detail:   discrepancy,
+		})
+	}
+	return
+}
+
+// setServerStorageDrift sets storage discrepancies in computed "storage.drift"
+func setServerStorageDrift(d *schema.ResourceData, meta interface{}, storageDrift []string) {
+        meta.(*config.FlexbotConfig).Sync.Lock()
+	defer meta.(*config.FlexbotConfig).Sync.Unlock()
+	storage := d.Get("storage").([]interface{})[0].(map[string]interface{})
+	storage["drift"] = storageDrift
+	d.Set("storage", []interface{}{storage})
+}
+
 func resourceUpdateServer(ctx context.Context, d *schema.ResourceData, meta interface{}) (diags diag.Diagnostics) {
 	var err error
 	var nodeConfig *config.NodeConfig
 	var nodeLabels map[string]string
 	var nodeTaints []v1.Taint
-	var isNew, isSnapshot, isCompute, isStorage, isLabels, isTaints, isRestore, isMaintenance bool
+	var isNew, isSnapshot, isSnapshotRetention, isCompute, isStorage, isReplication, isLabels, isTaints, isRestore, isFailover, isMaintenance bool
 	if nodeConfig, err = setFlexbotServerInput(d, meta); err != nil {
 		diags = diag.FromErr(err)
 		return
@@ -251,13 +317,16 @@ func resourceUpdateServer(ctx context.Context, d *schema.ResourceData, meta inte
         meta.(*config.FlexbotConfig).Sync.Lock()
 	isNew = d.IsNewResource()
 	isSnapshot = d.HasChange("snapshot")
+	isSnapshotRetention = d.HasChange("snapshot_retention")
         isCompute = d.HasChange("compute")
         isStorage = d.HasChange("storage")
+        isReplication = d.HasChange("storage.0.replication")
         isLabels = d.HasChange("labels")
         isTaints = d.HasChange("taints")
         isRestore = d.HasChange("restore")
+        isFailover = d.HasChange("failover")
         isMaintenance = d.HasChange("maintenance")
-        if isCompute || isStorage || isSnapshot || isRestore || isMaintenance {
+        if isCompute || isStorage || isSnapshot || isRestore || isFailover || isMaintenance {
 		if isCompute || isStorage || isRestore {
 			nodeLabels = make(map[string]string)
 			for labelKey, labelValue := range d.Get("labels").(map[string]interface{}) {
@@ -283,6 +352,12 @@ func resourceUpdateServer(ctx context.Context, d *schema.ResourceData, meta inte
 			return
 		}
 	}
+	if isSnapshotRetention && !isNew {
+		if err = pruneServerSnapshots(d, meta, nodeConfig); err != nil {
+			diags = diag.FromErr(fmt.Errorf("resourceUpdateServer(snapshot_retention): %s", err))
+			return
+		}
+	}
 	if isCompute && !isNew {
 		if err = resourceUpdateServerCompute(d, meta, nodeConfig); err != nil {
 			resourceReadServer(ctx, d, meta)
@@ -297,6 +372,12 @@ func resourceUpdateServer(ctx context.Context, d *schema.ResourceData, meta inte
 			return
 		}
 	}
+	if (isReplication || (nodeConfig.ChangeStatus & ChangeSnapshotCreate) > 0) && !isNew {
+		if err = resourceUpdateServerReplication(d, meta, nodeConfig); err != nil {
+			diags = diag.FromErr(err)
+			return
+		}
+	}
 	if isMaintenance {
 		if err = resourceUpdateServerMaintenance(d, meta, nodeConfig); err != nil {
 			diags = diag.FromErr(err)
@@ -309,9 +390,15 @@ func resourceUpdateServer(ctx context.Context, d *schema.ResourceData, meta inte
 			return
 		}
 	}
+	if isFailover {
+		if err = resourceUpdateServerFailover(d, meta, nodeConfig); err != nil {
+			diags = diag.FromErr(err)
+			return
+		}
+	}
 	if (nodeConfig.ChangeStatus & (ChangeBladeSpec | ChangeOsImage | ChangeSeedTemplate | ChangeDataDisk | ChangeSnapshotRestore)) > 0 {
 		log.Infof("Set annotations, labels, and taints for node %s", nodeConfig.Compute.HostName)
-		if _, err = ucsm.DiscoverServer(nodeConfig); err == nil {
+		if _, err = server.DiscoverServer(nodeConfig); err == nil {
 			var rancherNode rancher.RancherNode
 			nodeConfig.Labels = nodeLabels
 			nodeConfig.Taints = nodeTaints
@@ -401,7 +488,7 @@ func resourceUpdateServerCompute(d *schema.ResourceData, meta interface{}, nodeC
 	if (nodeConfig.ChangeStatus & ChangeBladeSpec) > 0 {
 		nodeConfig.Compute.BladeSpec.Dn = newBladeSpec["dn"].(string)
 	}
-	if powerState, err = ucsm.GetServerPowerState(nodeConfig); err != nil {
+	if powerState, err = server.GetServerPowerState(nodeConfig); err != nil {
 		return
 	}
 	nodeConfig.Compute.Powerstate = powerState
@@ -419,7 +506,7 @@ func resourceUpdateServerCompute(d *schema.ResourceData, meta interface{}, nodeC
 		log.Infof("Updating Server Compute for node %s", nodeConfig.Compute.HostName)
 	        if (nodeConfig.ChangeStatus & ChangeBladeSpec) > 0 {
 	        	log.Infof("Running compute  preflight check")
-	                if err = ucsm.UpdateServerPreflight(nodeConfig); err != nil {
+	                if err = server.UpdateServerPreflight(nodeConfig); err != nil {
 			        err = fmt.Errorf("resourceUpdateServer(compute): error: %s", err)
 			        meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 			        return
@@ -458,7 +545,7 @@ func resourceUpdateServerCompute(d *schema.ResourceData, meta interface{}, nodeC
                                 }
 			} else {
 				log.Infof("Power off node %s", nodeConfig.Compute.HostName)
-				if err = ucsm.StopServer(nodeConfig); err != nil {
+				if err = server.StopServer(nodeConfig); err != nil {
 					meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 					return
 				}
@@ -466,14 +553,14 @@ func resourceUpdateServerCompute(d *schema.ResourceData, meta interface{}, nodeC
 		}
 		if (nodeConfig.ChangeStatus & ChangeBladeSpec) > 0 {
 			log.Infof("Changing blade specification for node %s", nodeConfig.Compute.HostName)
-			if err = ucsm.UpdateServer(nodeConfig); err != nil {
+			if err = server.UpdateServer(nodeConfig); err != nil {
 				meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 				return
 			}
 		}
 		if newPowerState == "up" {
 			log.Infof("Power on node %s", nodeConfig.Compute.HostName)
-			if err = ucsm.StartServer(nodeConfig); err != nil {
+			if err = server.StartServer(nodeConfig); err != nil {
 				meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 				return
 			}
@@ -511,7 +598,7 @@ func resourceUpdateServerCompute(d *schema.ResourceData, meta interface{}, nodeC
 	}
 	if (oldCompute.([]interface{})[0].(map[string]interface{}))["description"].(string) != (newCompute.([]interface{})[0].(map[string]interface{}))["description"].(string) ||
 		(oldCompute.([]interface{})[0].(map[string]interface{}))["label"].(string) != (newCompute.([]interface{})[0].(map[string]interface{}))["label"].(string) {
-		err = ucsm.UpdateServerAttributes(nodeConfig)
+		err = server.UpdateServerAttributes(nodeConfig)
 	}
 	return
 }
@@ -544,6 +631,16 @@ func resourceUpdateServerStorage(d *schema.ResourceData, meta interface{}, nodeC
 	if len((newStorage.([]interface{})[0].(map[string]interface{}))["data_nvme"].([]interface{})) > 0 {
 		newDataNvme = (newStorage.([]interface{})[0].(map[string]interface{}))["data_nvme"].([]interface{})[0].(map[string]interface{})
 	}
+	oldVolumeMove := (oldStorage.([]interface{})[0].(map[string]interface{}))["volume_move"].([]interface{})
+	newVolumeMove := (newStorage.([]interface{})[0].(map[string]interface{}))["volume_move"].([]interface{})
+	if len(newVolumeMove) > 0 && (len(oldVolumeMove) == 0 ||
+		oldVolumeMove[0].(map[string]interface{})["aggregate"].(string) != newVolumeMove[0].(map[string]interface{})["aggregate"].(string) ||
+		oldVolumeMove[0].(map[string]interface{})["policy"].(string) != newVolumeMove[0].(map[string]interface{})["policy"].(string)) {
+		if err = moveServerVolume(d, nodeConfig, newVolumeMove[0].(map[string]interface{})); err != nil {
+			err = fmt.Errorf("resourceUpdateServer(storage): error: %s", err)
+			return
+		}
+	}
 	if oldBootLun["os_image"].(string) != newBootLun["os_image"].(string) ||
 		oldSeedLun["seed_template"].(string) != newSeedLun["seed_template"].(string) ||
 		(oldDataLun != nil && oldDataLun["size"].(int) > 0 && (newDataLun == nil || newDataLun["size"].(int) == 0)) ||
@@ -574,7 +671,7 @@ func resourceUpdateServerStorage(d *schema.ResourceData, meta interface{}, nodeC
 			err = fmt.Errorf("resourceUpdateServer(storage): last resource instance update returned error: %s", err)
 			return
 		}
-		if powerState, err = ucsm.GetServerPowerState(nodeConfig); err != nil {
+		if powerState, err = server.GetServerPowerState(nodeConfig); err != nil {
 			meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 			return
 		}
@@ -627,7 +724,7 @@ func resourceUpdateServerStorage(d *schema.ResourceData, meta interface{}, nodeC
 		}
 		if powerState == "up" {
 			log.Infof("Power off node %s", nodeConfig.Compute.HostName)
-			if err = ucsm.StopServer(nodeConfig); err != nil {
+			if err = server.StopServer(nodeConfig); err != nil {
 				meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 				return
 			}
@@ -635,7 +732,21 @@ func resourceUpdateServerStorage(d *schema.ResourceData, meta interface{}, nodeC
 		if (newStorage.([]interface{})[0].(map[string]interface{}))["auto_snapshot_on_update"].(bool) {
 			t := time.Now()
 			snapshotName := fmt.Sprintf("terraform:%s:%s-%s", oldBootLun["os_image"].(string), oldSeedLun["seed_template"].(string), t.Format(time.RFC3339))
-			if err = ontap.CreateSnapshot(nodeConfig, snapshotName, ""); err != nil {
+			// snapshot metadata should describe storage before update
+			snapshotConfig := *nodeConfig
+			snapshotConfig.Storage.BootLun.OsImage.Name = filepath.Base(oldBootLun["os_image"].(string))
+			snapshotConfig.Storage.SeedLun.SeedTemplate.Name = filepath.Base(oldSeedLun["seed_template"].(string))
+			snapshotConfig.Storage.BootLun.Size = oldBootLun["size"].(int)
+			snapshotConfig.Storage.DataLun.Size = 0
+			if oldDataLun != nil {
+				snapshotConfig.Storage.DataLun.Size = oldDataLun["size"].(int)
+			}
+			if err = ontap.CreateSnapshot(&snapshotConfig, snapshotName, ""); err != nil {
+				err = fmt.Errorf("resourceUpdateServer(storage): error: %s", err)
+				meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
+				return
+			}
+			if err = pruneServerSnapshots(d, meta, nodeConfig); err != nil {
 				err = fmt.Errorf("resourceUpdateServer(storage): error: %s", err)
 				meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 				return
@@ -669,7 +780,7 @@ func resourceUpdateServerStorage(d *schema.ResourceData, meta interface{}, nodeC
 			}
 		}
 		log.Infof("Power on node %s", nodeConfig.Compute.HostName)
-		if err = ucsm.StartServer(nodeConfig); err != nil {
+		if err = server.StartServer(nodeConfig); err != nil {
 			meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 			return
 		}
@@ -787,6 +898,19 @@ func resourceUpdateServerStorage(d *schema.ResourceData, meta interface{}, nodeC
 	return
 }
 
+// moveServerVolume moves server volume online to aggregate picked explicitly or by policy
+func moveServerVolume(d *schema.ResourceData, nodeConfig *config.NodeConfig, volumeMove map[string]interface{}) (err error) {
+	var aggregateName string
+	if aggregateName, err = ontap.GetVolumeMoveAggregate(nodeConfig, volumeMove["aggregate"].(string), volumeMove["policy"].(string)); err != nil {
+		return
+	}
+	log.Infof("Moving volume %s of node %s to aggregate %s", nodeConfig.Storage.VolumeName, nodeConfig.Compute.HostName, aggregateName)
+	err = ontap.MoveBootStorage(nodeConfig, aggregateName, d.Timeout(schema.TimeoutUpdate), func(moveInfo client.VolumeMoveInfo) {
+		log.Infof("Volume %s move to aggregate %s: %s, %d%% complete", nodeConfig.Storage.VolumeName, aggregateName, moveInfo.State, moveInfo.PercentComplete)
+	})
+	return
+}
+
 func resourceUpdateServerSnapshot(d *schema.ResourceData, meta interface{}, nodeConfig *config.NodeConfig) (err error) {
 	var oldSnapState, newSnapState, snapStateInter, snapStorage []string
 	var sshPrivateKey string
@@ -842,6 +966,32 @@ func resourceUpdateServerSnapshot(d *schema.ResourceData, meta interface{}, node
 			}
 		}
 	}
+	if (nodeConfig.ChangeStatus & ChangeSnapshotCreate) > 0 {
+		if err = pruneServerSnapshots(d, meta, nodeConfig); err != nil {
+			err = fmt.Errorf("resourceUpdateServer(snapshot): %s", err)
+		}
+	}
+	return
+}
+
+// pruneServerSnapshots applies snapshot retention rules, snapshots defined in "snapshot" blocks are preserved
+func pruneServerSnapshots(d *schema.ResourceData, meta interface{}, nodeConfig *config.NodeConfig) (err error) {
+	var keepSnapshots, pruned []string
+	if len(nodeConfig.Storage.SnapshotRetention) == 0 {
+		return
+	}
+        meta.(*config.FlexbotConfig).Sync.Lock()
+	for _, snapshot := range d.Get("snapshot").([]interface{}) {
+		keepSnapshots = append(keepSnapshots, snapshot.(map[string]interface{})["name"].(string))
+	}
+        meta.(*config.FlexbotConfig).Sync.Unlock()
+	if pruned, err = ontap.PruneSnapshots(nodeConfig, keepSnapshots); err != nil {
+		return
+	}
+	if len(pruned) > 0 {
+		log.Infof("Pruned storage snapshots for node %s: %s", nodeConfig.Compute.HostName, strings.Join(pruned, ", "))
+		nodeConfig.ChangeStatus = nodeConfig.ChangeStatus | ChangeSnapshotDelete
+	}
 	return
 }
 
@@ -859,7 +1009,7 @@ func resourceUpdateServerMaintenance(d *schema.ResourceData, meta interface{}, n
 		return
 	}
 	log.Infof("Running Server Maintenance Tasks")
-	if powerState, err = ucsm.GetServerPowerState(nodeConfig); err != nil || powerState == "down" {
+	if powerState, err = server.GetServerPowerState(nodeConfig); err != nil || powerState == "down" {
 		return
 	}
 	var rancherNode rancher.RancherNode
@@ -920,7 +1070,7 @@ func resourceUpdateServerMaintenance(d *schema.ResourceData, meta interface{}, n
 		                        meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 		                        return
                                 }
-	                        if err = ucsm.StartServer(nodeConfig); err != nil {
+	                        if err = server.StartServer(nodeConfig); err != nil {
 		                        meta.(*config.FlexbotConfig).UpdateManagerSetError(err)
 		                        return
 	                        }
@@ -929,9 +1079,9 @@ func resourceUpdateServerMaintenance(d *schema.ResourceData, meta interface{}, n
 			                return
 		                }
 			} else {
-				if err = ucsm.StopServer(nodeConfig); err == nil {
+				if err = server.StopServer(nodeConfig); err == nil {
 					time.Sleep(NodeGraceShutdownTimeout * time.Second)
-					err = ucsm.StartServer(nodeConfig)
+					err = server.StartServer(nodeConfig)
 				}
 				if err != nil {
 					err = fmt.Errorf("resourceUpdateServer(maintenance): restart error: %s", err)
@@ -1031,7 +1181,20 @@ func resourceUpdateServerRestore(d *schema.ResourceData, meta interface{}, nodeC
 		err = fmt.Errorf("resourceUpdateServer(restore): snapshot not found, expected snapshot_name")
 		return
 	}
-	if powerState, err = ucsm.GetServerPowerState(nodeConfig); err != nil {
+	var snapshotMetadata *ontap.SnapshotMetadata
+	if snapshotMetadata, err = ontap.GetSnapshotMetadata(nodeConfig, restore["snapshot_name"].(string)); err != nil {
+		err = fmt.Errorf("resourceUpdateServer(restore): error: %s", err)
+		return
+	}
+	if snapshotMetadata != nil {
+		if snapshotMetadata.OsImage != "" && snapshotMetadata.OsImage != nodeConfig.Storage.BootLun.OsImage.Name {
+			log.Warnf("Snapshot %s of node %s has OS image %s, declared OS image is %s", restore["snapshot_name"].(string), nodeConfig.Compute.HostName, snapshotMetadata.OsImage, nodeConfig.Storage.BootLun.OsImage.Name)
+		}
+		if snapshotMetadata.SeedTemplate != "" && snapshotMetadata.SeedTemplate != nodeConfig.Storage.SeedLun.SeedTemplate.Name {
+			log.Warnf("Snapshot %s of node %s has seed template %s, declared seed template is %s", restore["snapshot_name"].(string), nodeConfig.Compute.HostName, snapshotMetadata.SeedTemplate, nodeConfig.Storage.SeedLun.SeedTemplate.Name)
+		}
+	}
+	if powerState, err = server.GetServerPowerState(nodeConfig); err != nil {
 		return
 	}
 	if powerState == "up" && compute["safe_removal"].(bool) {
@@ -1039,7 +1202,7 @@ func resourceUpdateServerRestore(d *schema.ResourceData, meta interface{}, nodeC
 		return
 	}
 	if powerState == "up" {
-		if err = ucsm.StopServer(nodeConfig); err != nil {
+		if err = server.StopServer(nodeConfig); err != nil {
 			return
 		}
 		time.Sleep(NodeGracePowerOffTimeout * time.Second)
@@ -1052,7 +1215,7 @@ func resourceUpdateServerRestore(d *schema.ResourceData, meta interface{}, nodeC
 		err = fmt.Errorf("resourceUpdateServer(restore): error: %s", err)
 		return
 	}
-	if err = ucsm.StartServer(nodeConfig); err != nil {
+	if err = server.StartServer(nodeConfig); err != nil {
 		return
 	}
 	if compute["wait_for_ssh_timeout"].(int) > 0 && len(sshUser) > 0 && len(sshPrivateKey) > 0 {
@@ -1067,6 +1230,95 @@ func resourceUpdateServerRestore(d *schema.ResourceData, meta interface{}, nodeC
 	return
 }
 
+func resourceUpdateServerReplication(d *schema.ResourceData, meta interface{}, nodeConfig *config.NodeConfig) (err error) {
+        meta.(*config.FlexbotConfig).Sync.Lock()
+	oldStorage, newStorage := d.GetChange("storage")
+        meta.(*config.FlexbotConfig).Sync.Unlock()
+	oldReplication := oldStorage.([]interface{})[0].(map[string]interface{})["replication"].([]interface{})
+	newReplication := newStorage.([]interface{})[0].(map[string]interface{})["replication"].([]interface{})
+	if len(oldReplication) > 0 {
+		oldSvmName := oldReplication[0].(map[string]interface{})["svm_name"].(string)
+		if len(newReplication) == 0 || newReplication[0].(map[string]interface{})["svm_name"].(string) != oldSvmName {
+			if oldReplication[0].(map[string]interface{})["failed_over"].(bool) {
+				err = fmt.Errorf("resourceUpdateServer(replication): server %s is failed over to SVM %s, replication can not be changed", nodeConfig.Compute.HostName, oldSvmName)
+				return
+			}
+			log.Infof("Deleting replication of Server Storage to SVM %s", oldSvmName)
+			oldConfig := *nodeConfig
+			oldConfig.Storage.Replication.SvmName = oldSvmName
+			if err = ontap.DeleteReplication(&oldConfig); err != nil {
+				err = fmt.Errorf("resourceUpdateServer(replication): error: %s", err)
+				return
+			}
+		}
+	}
+	if len(newReplication) > 0 {
+		if err = ontap.UpdateReplication(nodeConfig); err != nil {
+			err = fmt.Errorf("resourceUpdateServer(replication): error: %s", err)
+		}
+	}
+	return
+}
+
+func resourceUpdateServerFailover(d *schema.ResourceData, meta interface{}, nodeConfig *config.NodeConfig) (err error) {
+	var powerState, sshPrivateKey string
+        meta.(*config.FlexbotConfig).Sync.Lock()
+	compute := d.Get("compute").([]interface{})[0].(map[string]interface{})
+	oldFailover, newFailover := d.GetChange("failover")
+        meta.(*config.FlexbotConfig).Sync.Unlock()
+	sshUser := compute["ssh_user"].(string)
+	if sshPrivateKey, err = decryptAttribute(meta, compute["ssh_private_key"].(string)); err != nil {
+		err = fmt.Errorf("resourceUpdateServer(failover): failure: %s", err)
+		return
+	}
+	if len(newFailover.([]interface{})) == 0 {
+		return
+	}
+	failover := newFailover.([]interface{})[0].(map[string]interface{})
+	if !failover["failover"].(bool) {
+		return
+	}
+	if nodeConfig.Storage.Replication.SvmName == "" {
+		err = fmt.Errorf("resourceUpdateServer(failover): storage replication is not configured for server %s", nodeConfig.Compute.HostName)
+		return
+	}
+	log.Infof("Failing over Server Storage to SVM %s", nodeConfig.Storage.Replication.SvmName)
+	if powerState, err = server.GetServerPowerState(nodeConfig); err != nil {
+		return
+	}
+	if powerState == "up" && compute["safe_removal"].(bool) {
+		err = fmt.Errorf("resourceUpdateServer(failover): server %s has power state up", nodeConfig.Compute.HostName)
+		return
+	}
+	if powerState == "up" {
+		if err = server.StopServer(nodeConfig); err != nil {
+			return
+		}
+		time.Sleep(NodeGracePowerOffTimeout * time.Second)
+	}
+	err = ontap.FailoverReplication(nodeConfig)
+	setFlexbotOutput(d, meta, nodeConfig)
+	if err != nil {
+		err = fmt.Errorf("resourceUpdateServer(failover): error: %s", err)
+		return
+	}
+	if err = server.SetServerBootTargets(nodeConfig); err != nil {
+		return
+	}
+	if err = server.StartServer(nodeConfig); err != nil {
+		return
+	}
+	if compute["wait_for_ssh_timeout"].(int) > 0 && len(sshUser) > 0 && len(sshPrivateKey) > 0 {
+		if err = waitForSSH(nodeConfig, compute["wait_for_ssh_timeout"].(int), sshUser, sshPrivateKey); err != nil {
+			return
+		}
+	}
+        meta.(*config.FlexbotConfig).Sync.Lock()
+	d.Set("failover", oldFailover)
+        meta.(*config.FlexbotConfig).Sync.Unlock()
+	return
+}
+
 func resourceUpdateServerLabels(d *schema.ResourceData, meta interface{}, nodeConfig *config.NodeConfig) (err error) {
 	var rancherNode rancher.RancherNode
         meta.(*config.FlexbotConfig).Sync.Lock()
@@ -1103,7 +1355,7 @@ func resourceDeleteServer(ctx context.Context, d *schema.ResourceData, meta inte
         meta.(*config.FlexbotConfig).Sync.Lock()
 	compute := d.Get("compute").([]interface{})[0].(map[string]interface{})
         meta.(*config.FlexbotConfig).Sync.Unlock()
-	if powerState, err = ucsm.GetServerPowerState(nodeConfig); err != nil {
+	if powerState, err = server.GetServerPowerState(nodeConfig); err != nil {
 		diags = diag.FromErr(err)
 		return
 	}
@@ -1111,6 +1363,11 @@ func resourceDeleteServer(ctx context.Context, d *schema.ResourceData, meta inte
 		diags = diag.FromErr(fmt.Errorf("resourceDeleteServer(): server %s has power state up", nodeConfig.Compute.HostName))
 		return
 	}
+	if isDryRun(meta) {
+		nodeConfig.Plan = config.NewPlan()
+		diags = planDeleteServer(meta, nodeConfig, powerState)
+		return
+	}
 	var rancherNode rancher.RancherNode
 	if rancherNode, err = rancher.RancherAPIInitialize(d, meta, nodeConfig, false); err != nil {
 		diags = diag.FromErr(fmt.Errorf("resourceDeleteServer(): error: %s", err))
@@ -1140,7 +1397,7 @@ func resourceDeleteServer(ctx context.Context, d *schema.ResourceData, meta inte
 		}
         }
 	if powerState == "up" {
-		if err = ucsm.StopServer(nodeConfig); err != nil {
+		if err = server.StopServer(nodeConfig); err != nil {
 			diags = diag.FromErr(err)
 			return
 		}
@@ -1148,10 +1405,17 @@ func resourceDeleteServer(ctx context.Context, d *schema.ResourceData, meta inte
 	if err = rancherNode.RancherAPINodeForceDelete(); err != nil {
 		diags = diag.FromErr(fmt.Errorf("resourceDeleteServer(): error: %s", err))
 	}
-	if err = ucsm.DeleteServer(nodeConfig); err != nil {
+	if err = server.DeleteServer(nodeConfig); err != nil {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  "server.DeleteServer()",
+			Detail:   err.Error(),
+		})
+	}
+	if err = ontap.DeleteReplication(nodeConfig); err != nil {
 		diags = append(diags, diag.Diagnostic{
 			Severity: diag.Error,
-			Summary:  "ucsm.DeleteServer()",
+			Summary:  "ontap.DeleteReplication()",
 			Detail:   err.Error(),
 		})
 	}
@@ -1162,6 +1426,14 @@ func resourceDeleteServer(ctx context.Context, d *schema.ResourceData, meta inte
 			Detail:   err.Error(),
 		})
 	}
+	if err = ontap.DeletePrimaryStorage(nodeConfig); err != nil {
+		log.Warnf("Server %s storage leftover: %s", nodeConfig.Compute.HostName, err)
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("Server %s storage is left on original primary cluster", nodeConfig.Compute.HostName),
+			Detail:   err.Error() + ". Delete the volume, iGroup and SnapMirror source info manually once the cluster is available.",
+		})
+	}
 	var ipamProvider ipam.IpamProvider
 	if ipamProvider, err = ipam.NewProvider(&nodeConfig.Ipam); err != nil {
 		diags = append(diags, diag.Diagnostic{
@@ -1217,6 +1489,7 @@ func setFlexbotServerInput(d *schema.ResourceData, meta interface{}) (nodeConfig
 	nodeConfig.Compute.UcsmCredentials.Host = ucsmCredentials["host"].(string)
 	nodeConfig.Compute.UcsmCredentials.User = ucsmCredentials["user"].(string)
 	nodeConfig.Compute.UcsmCredentials.Password = ucsmCredentials["password"].(string)
+	nodeConfig.Compute.Provider = pCompute["provider"].(string)
 	pStorage := p.Get("storage").([]interface{})[0].(map[string]interface{})
 	cdotCredentials := pStorage["credentials"].([]interface{})[0].(map[string]interface{})
 	nodeConfig.Storage.CdotCredentials.Host = cdotCredentials["host"].(string)
@@ -1224,8 +1497,20 @@ func setFlexbotServerInput(d *schema.ResourceData, meta interface{}) (nodeConfig
 	nodeConfig.Storage.CdotCredentials.Password = cdotCredentials["password"].(string)
 	nodeConfig.Storage.CdotCredentials.ApiMethod = cdotCredentials["api_method"].(string)
 	nodeConfig.Storage.CdotCredentials.ZapiVersion = cdotCredentials["zapi_version"].(string)
+	setSignaturesInput(p, nodeConfig)
+	setUploadInput(p, nodeConfig)
+	setArtifactSourcesInput(p, nodeConfig)
 	nodeConfig.Compute.SpOrg = compute["sp_org"].(string)
 	nodeConfig.Compute.SpTemplate = compute["sp_template"].(string)
+	nodeConfig.Compute.SystemId = compute["system_id"].(string)
+	nodeConfig.Compute.RedfishCredentials.Host = compute["bmc_host"].(string)
+	if nodeConfig.Compute.RedfishCredentials.Host == "" {
+		nodeConfig.Compute.RedfishCredentials.Host = nodeConfig.Compute.UcsmCredentials.Host
+	}
+	nodeConfig.Compute.RedfishCredentials.User = nodeConfig.Compute.UcsmCredentials.User
+	nodeConfig.Compute.RedfishCredentials.Password = nodeConfig.Compute.UcsmCredentials.Password
+	nodeConfig.Compute.IntersightCredentials = nodeConfig.Compute.UcsmCredentials
+	setIntersightTLSInput(p, nodeConfig)
 	nodeConfig.Compute.Description = compute["description"].(string)
 	nodeConfig.Compute.Label = compute["label"].(string)
 	if len(compute["blade_spec"].([]interface{})) > 0 {
@@ -1236,6 +1521,11 @@ func setFlexbotServerInput(d *schema.ResourceData, meta interface{}) (nodeConfig
 		nodeConfig.Compute.BladeSpec.NumOfCores = bladeSpec["num_of_cores"].(string)
 		nodeConfig.Compute.BladeSpec.NumOfThreads = bladeSpec["num_of_threads"].(string)
 		nodeConfig.Compute.BladeSpec.TotalMemory = bladeSpec["total_memory"].(string)
+		nodeConfig.Compute.BladePlacement.Policy = bladeSpec["placement"].(string)
+		nodeConfig.Compute.BladePlacement.Firmware = bladeSpec["firmware_version"].(string)
+	}
+	if len(compute["blade_assigned"].([]interface{})) > 0 && compute["blade_assigned"].([]interface{})[0] != nil {
+		nodeConfig.Compute.Placement = compute["blade_assigned"].([]interface{})[0].(map[string]interface{})["placement"].(string)
 	}
 	storage := d.Get("storage").([]interface{})[0].(map[string]interface{})
 	nodeConfig.Storage.SvmName = storage["svm_name"].(string)
@@ -1261,6 +1551,35 @@ func setFlexbotServerInput(d *schema.ResourceData, meta interface{}) (nodeConfig
 		nodeConfig.Storage.DataNvme.Subsystem = dataNvme["subsystem"].(string)
 		nodeConfig.Storage.DataNvme.Size = dataNvme["size"].(int)
 	}
+	if len(pStorage["replication_credentials"].([]interface{})) > 0 {
+		replicationCredentials := pStorage["replication_credentials"].([]interface{})[0].(map[string]interface{})
+		nodeConfig.Storage.Replication.CdotCredentials.Host = replicationCredentials["host"].(string)
+		nodeConfig.Storage.Replication.CdotCredentials.User = replicationCredentials["user"].(string)
+		nodeConfig.Storage.Replication.CdotCredentials.Password = replicationCredentials["password"].(string)
+		nodeConfig.Storage.Replication.CdotCredentials.ApiMethod = replicationCredentials["api_method"].(string)
+		nodeConfig.Storage.Replication.CdotCredentials.ZapiVersion = replicationCredentials["zapi_version"].(string)
+	}
+	if len(storage["replication"].([]interface{})) > 0 {
+		if nodeConfig.Storage.Replication.CdotCredentials.Host == "" {
+			err = fmt.Errorf("setFlexbotServerInput(): storage replication requires provider storage replication_credentials")
+			return
+		}
+		replication := storage["replication"].([]interface{})[0].(map[string]interface{})
+		nodeConfig.Storage.Replication.SvmName = replication["svm_name"].(string)
+		nodeConfig.Storage.Replication.Policy = replication["policy"].(string)
+		nodeConfig.Storage.Replication.Schedule = replication["schedule"].(string)
+		nodeConfig.Storage.Replication.State = replication["state"].(string)
+		nodeConfig.Storage.Replication.FailedOver = replication["failed_over"].(bool)
+	}
+	for _, retention := range d.Get("snapshot_retention").([]interface{}) {
+		nodeConfig.Storage.SnapshotRetention = append(
+			nodeConfig.Storage.SnapshotRetention,
+			config.SnapshotRetention{
+				Prefix: retention.(map[string]interface{})["prefix"].(string),
+				KeepLast: retention.(map[string]interface{})["keep_last"].(int),
+				MaxAge: retention.(map[string]interface{})["max_age"].(string),
+			})
+	}
 	network := d.Get("network").([]interface{})[0].(map[string]interface{})
 	for i := range network["node"].([]interface{}) {
 		node := network["node"].([]interface{})[i].(map[string]interface{})
@@ -1306,6 +1625,31 @@ func setFlexbotServerInput(d *schema.ResourceData, meta interface{}) (nodeConfig
 			}
 		}
 	}
+	if len(network["iscsi_chap"].([]interface{})) > 0 {
+		iscsiChap := network["iscsi_chap"].([]interface{})[0].(map[string]interface{})
+		nodeConfig.Network.IscsiChap.User = iscsiChap["user"].(string)
+		nodeConfig.Network.IscsiChap.Password = iscsiChap["password"].(string)
+		nodeConfig.Network.IscsiChap.TargetUser = iscsiChap["target_user"].(string)
+		nodeConfig.Network.IscsiChap.TargetPassword = iscsiChap["target_password"].(string)
+	}
+	for i := range network["fc_initiator"].([]interface{}) {
+		initiator := network["fc_initiator"].([]interface{})[i].(map[string]interface{})
+		nodeConfig.Network.FcInitiator = append(nodeConfig.Network.FcInitiator, config.FcInitiator{})
+		nodeConfig.Network.FcInitiator[i].Name = initiator["name"].(string)
+		nodeConfig.Network.FcInitiator[i].Wwpn = initiator["wwpn"].(string)
+		nodeConfig.Network.FcInitiator[i].Wwnn = initiator["wwnn"].(string)
+		nodeConfig.Network.FcInitiator[i].Fabric = initiator["fabric"].(string)
+		for _, targetLif := range initiator["target_lifs"].([]interface{}) {
+			nodeConfig.Network.FcInitiator[i].TargetLifs = append(nodeConfig.Network.FcInitiator[i].TargetLifs, targetLif.(string))
+		}
+		nodeConfig.Network.FcInitiator[i].FcTarget = &config.FcTarget{}
+		if len(initiator["fc_target"].([]interface{})) > 0 {
+			nodeConfig.Network.FcInitiator[i].FcTarget.NodeName = initiator["fc_target"].([]interface{})[0].(map[string]interface{})["node_name"].(string)
+			for _, targetAddr := range initiator["fc_target"].([]interface{})[0].(map[string]interface{})["interfaces"].([]interface{}) {
+				nodeConfig.Network.FcInitiator[i].FcTarget.Interfaces = append(nodeConfig.Network.FcInitiator[i].FcTarget.Interfaces, targetAddr.(string))
+			}
+		}
+	}
 	for i := range network["nvme_host"].([]interface{}) {
 		nvmeHost := network["nvme_host"].([]interface{})[i].(map[string]interface{})
 		nodeConfig.Network.NvmeHost = append(nodeConfig.Network.NvmeHost, config.NvmeHost{})
@@ -1362,6 +1706,7 @@ func setFlexbotOutput(d *schema.ResourceData, meta interface{}, nodeConfig *conf
 	bladeAssigned["num_of_cores"] = nodeConfig.Compute.BladeAssigned.NumOfCores
 	bladeAssigned["num_of_threads"] = nodeConfig.Compute.BladeAssigned.NumOfThreads
 	bladeAssigned["total_memory"] = nodeConfig.Compute.BladeAssigned.TotalMemory
+	bladeAssigned["placement"] = nodeConfig.Compute.Placement
 	if len(compute["blade_assigned"].([]interface{})) > 0 {
 		compute["blade_assigned"].([]interface{})[0] = bladeAssigned
 	} else {
@@ -1375,6 +1720,7 @@ func setFlexbotOutput(d *schema.ResourceData, meta interface{}, nodeConfig *conf
 	storage["image_repo_name"] = nodeConfig.Storage.ImageRepoName
 	storage["volume_name"] = nodeConfig.Storage.VolumeName
 	storage["igroup_name"] = nodeConfig.Storage.IgroupName
+	storage["aggregate"] = nodeConfig.Storage.Aggregate
 	bootLun := storage["boot_lun"].([]interface{})[0].(map[string]interface{})
 	bootLun["name"] = nodeConfig.Storage.BootLun.Name
 	bootLun["id"] = nodeConfig.Storage.BootLun.Id
@@ -1415,6 +1761,13 @@ func setFlexbotOutput(d *schema.ResourceData, meta interface{}, nodeConfig *conf
 		storage["snapshots"] = append(storage["snapshots"].([]string), snapshot)
 	}
 	storage["force_update"] = false
+	storage["drift"] = []string{}
+	if len(storage["replication"].([]interface{})) > 0 {
+		replication := storage["replication"].([]interface{})[0].(map[string]interface{})
+		replication["state"] = nodeConfig.Storage.Replication.State
+		replication["failed_over"] = nodeConfig.Storage.Replication.FailedOver
+		storage["replication"].([]interface{})[0] = replication
+	}
 	for i := range network["node"].([]interface{}) {
 		node := network["node"].([]interface{})[i].(map[string]interface{})
 		node["macaddr"] = nodeConfig.Network.Node[i].Macaddr
@@ -1445,6 +1798,23 @@ func setFlexbotOutput(d *schema.ResourceData, meta interface{}, nodeConfig *conf
 		}
 		network["iscsi_initiator"].([]interface{})[i] = initiator
 	}
+	for i := range network["fc_initiator"].([]interface{}) {
+		initiator := network["fc_initiator"].([]interface{})[i].(map[string]interface{})
+		initiator["wwpn"] = nodeConfig.Network.FcInitiator[i].Wwpn
+		initiator["wwnn"] = nodeConfig.Network.FcInitiator[i].Wwnn
+		if len(initiator["fc_target"].([]interface{})) == 0 {
+			if nodeConfig.Network.FcInitiator[i].FcTarget != nil {
+				fcTarget := make(map[string]interface{})
+				fcTarget["node_name"] = nodeConfig.Network.FcInitiator[i].FcTarget.NodeName
+				fcTarget["interfaces"] = []string{}
+				for _, iface := range nodeConfig.Network.FcInitiator[i].FcTarget.Interfaces {
+					fcTarget["interfaces"] = append(fcTarget["interfaces"].([]string), iface)
+				}
+				initiator["fc_target"] = append(initiator["fc_target"].([]interface{}), fcTarget)
+			}
+		}
+		network["fc_initiator"].([]interface{})[i] = initiator
+	}
 	for i := range network["nvme_host"].([]interface{}) {
 		nvmeHost := network["nvme_host"].([]interface{})[i].(map[string]interface{})
 		nvmeHost["host_interface"] = nodeConfig.Network.NvmeHost[i].HostInterface
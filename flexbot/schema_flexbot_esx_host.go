@@ -23,12 +23,22 @@ func schemaEsxHost() map[string]*schema.Schema {
 					},
 					"sp_org": {
 						Type:     schema.TypeString,
-						Required: true,
+						Optional: true,
 						ForceNew: true,
 					},
 					"sp_template": {
 						Type:     schema.TypeString,
-						Required: true,
+						Optional: true,
+						ForceNew: true,
+					},
+					"bmc_host": {
+						Type:     schema.TypeString,
+						Optional: true,
+						ForceNew: true,
+					},
+					"system_id": {
+						Type:     schema.TypeString,
+						Optional: true,
 						ForceNew: true,
 					},
 					"sp_dn": {
@@ -104,6 +114,21 @@ func schemaEsxHost() map[string]*schema.Schema {
 										return
 									},
 								},
+								"placement": {
+									Type:     schema.TypeString,
+									Optional: true,
+									ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+										v := val.(string)
+										if !(v == "random" || v == "spread" || v == "pack" || v == "lowest-slot" || v == "firmware") {
+											errs = append(errs, fmt.Errorf("value %q=%s must be either \"random\", \"spread\", \"pack\", \"lowest-slot\" or \"firmware\"", key, v))
+										}
+										return
+									},
+								},
+								"firmware_version": {
+									Type:     schema.TypeString,
+									Optional: true,
+								},
 							},
 						},
 					},
@@ -149,6 +174,11 @@ func schemaEsxHost() map[string]*schema.Schema {
 									Optional: true,
 									Computed: true,
 								},
+								"placement": {
+									Type:     schema.TypeString,
+									Optional: true,
+									Computed: true,
+								},
 							},
 						},
 					},
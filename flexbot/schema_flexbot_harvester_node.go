@@ -23,12 +23,22 @@ func schemaHarvesterNode() map[string]*schema.Schema {
 					},
 					"sp_org": {
 						Type:     schema.TypeString,
-						Required: true,
+						Optional: true,
 						ForceNew: true,
 					},
 					"sp_template": {
 						Type:     schema.TypeString,
-						Required: true,
+						Optional: true,
+						ForceNew: true,
+					},
+					"bmc_host": {
+						Type:     schema.TypeString,
+						Optional: true,
+						ForceNew: true,
+					},
+					"system_id": {
+						Type:     schema.TypeString,
+						Optional: true,
 						ForceNew: true,
 					},
 					"sp_dn": {
@@ -125,6 +135,21 @@ func schemaHarvesterNode() map[string]*schema.Schema {
 										return
 									},
 								},
+								"placement": {
+									Type:     schema.TypeString,
+									Optional: true,
+									ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+										v := val.(string)
+										if !(v == "random" || v == "spread" || v == "pack" || v == "lowest-slot" || v == "firmware") {
+											errs = append(errs, fmt.Errorf("value %q=%s must be either \"random\", \"spread\", \"pack\", \"lowest-slot\" or \"firmware\"", key, v))
+										}
+										return
+									},
+								},
+								"firmware_version": {
+									Type:     schema.TypeString,
+									Optional: true,
+								},
 							},
 						},
 					},
@@ -170,6 +195,11 @@ func schemaHarvesterNode() map[string]*schema.Schema {
 									Optional: true,
 									Computed: true,
 								},
+								"placement": {
+									Type:     schema.TypeString,
+									Optional: true,
+									Computed: true,
+								},
 							},
 						},
 					},
@@ -1,7 +1,11 @@
 package flexbot
 
 import (
+	"fmt"
+
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap"
 )
 
 func schemaFlexbotRepo() map[string]*schema.Schema {
@@ -12,6 +16,41 @@ func schemaFlexbotRepo() map[string]*schema.Schema {
 			Computed: true,
 			Elem:     &schema.Schema{Type: schema.TypeString},
 		},
+		"image_checksums": {
+			Type:     schema.TypeMap,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"image_consumers": {
+			Type:     schema.TypeMap,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"image_gc": {
+			Type:     schema.TypeList,
+			Optional: true,
+			MaxItems: 1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"protect_in_use": {
+						Type:     schema.TypeBool,
+						Optional: true,
+						Default:  true,
+					},
+					"max_age": {
+						Type:     schema.TypeString,
+						Optional: true,
+						Default:  "",
+						ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+							if _, err := ontap.ParseSnapshotMaxAge(val.(string)); err != nil {
+								errs = append(errs, fmt.Errorf("%q: %s", key, err))
+							}
+							return
+						},
+					},
+				},
+			},
+		},
 		"templates": {
 			Type:     schema.TypeList,
 			Optional: true,
@@ -31,9 +70,46 @@ func schemaFlexbotRepo() map[string]*schema.Schema {
 						Type:     schema.TypeString,
 						Optional: true,
 					},
+					"checksum": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+					"signature": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
+				},
+			},
+		},
+		"manifest": {
+			Type:     schema.TypeList,
+			Optional: true,
+			MaxItems: 1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"location": {
+						Type:     schema.TypeString,
+						Required: true,
+					},
+					"prune": {
+						Type:     schema.TypeBool,
+						Optional: true,
+						Default:  false,
+					},
+					"parallelism": {
+						Type:         schema.TypeInt,
+						Optional:     true,
+						Default:      2,
+						ValidateFunc: validation.IntBetween(1, 16),
+					},
 				},
 			},
 		},
+		"manifest_plan": {
+			Type:     schema.TypeList,
+			Computed: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
 		"template_repo": {
 			Type:     schema.TypeList,
 			Optional: true,
@@ -47,6 +123,10 @@ func schemaFlexbotRepo() map[string]*schema.Schema {
 						Type:     schema.TypeString,
 						Optional: true,
 					},
+					"signature": {
+						Type:     schema.TypeString,
+						Optional: true,
+					},
 				},
 			},
 		},
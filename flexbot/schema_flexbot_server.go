@@ -6,6 +6,7 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap"
 )
 
 var (
@@ -31,12 +32,22 @@ func schemaFlexbotServer() map[string]*schema.Schema {
 					},
 					"sp_org": {
 						Type:     schema.TypeString,
-						Required: true,
+						Optional: true,
 						ForceNew: true,
 					},
 					"sp_template": {
 						Type:     schema.TypeString,
-						Required: true,
+						Optional: true,
+						ForceNew: true,
+					},
+					"bmc_host": {
+						Type:     schema.TypeString,
+						Optional: true,
+						ForceNew: true,
+					},
+					"system_id": {
+						Type:     schema.TypeString,
+						Optional: true,
 						ForceNew: true,
 					},
 					"sp_dn": {
@@ -148,6 +159,21 @@ func schemaFlexbotServer() map[string]*schema.Schema {
 										return
 									},
 								},
+								"placement": {
+									Type:     schema.TypeString,
+									Optional: true,
+									ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+										v := val.(string)
+										if !(v == "random" || v == "spread" || v == "pack" || v == "lowest-slot" || v == "firmware") {
+											errs = append(errs, fmt.Errorf("value %q=%s must be either \"random\", \"spread\", \"pack\", \"lowest-slot\" or \"firmware\"", key, v))
+										}
+										return
+									},
+								},
+								"firmware_version": {
+									Type:     schema.TypeString,
+									Optional: true,
+								},
 							},
 						},
 					},
@@ -193,6 +219,11 @@ func schemaFlexbotServer() map[string]*schema.Schema {
 									Optional: true,
 									Computed: true,
 								},
+								"placement": {
+									Type:     schema.TypeString,
+									Optional: true,
+									Computed: true,
+								},
 							},
 						},
 					},
@@ -253,6 +284,10 @@ func schemaFlexbotServer() map[string]*schema.Schema {
 						Optional: true,
 						Computed: true,
 					},
+					"aggregate": {
+						Type:     schema.TypeString,
+						Computed: true,
+					},
 					"boot_lun": {
 						Type:     schema.TypeList,
 						Required: true,
@@ -386,6 +421,67 @@ func schemaFlexbotServer() map[string]*schema.Schema {
 						Computed: true,
 						Elem:     &schema.Schema{Type: schema.TypeString},
 					},
+					"verify": {
+						Type:     schema.TypeBool,
+						Optional: true,
+						Default:  false,
+					},
+					"drift": {
+						Type:     schema.TypeList,
+						Computed: true,
+						Elem:     &schema.Schema{Type: schema.TypeString},
+					},
+					"volume_move": {
+						Type:     schema.TypeList,
+						Optional: true,
+						MaxItems: 1,
+						Elem: &schema.Resource{
+							Schema: map[string]*schema.Schema{
+								"aggregate": {
+									Type:     schema.TypeString,
+									Optional: true,
+									Default:  "",
+								},
+								"policy": {
+									Type:         schema.TypeString,
+									Optional:     true,
+									Default:      ontap.VolumeMovePolicyMaxAvailable,
+									ValidateFunc: validation.StringInSlice([]string{ontap.VolumeMovePolicyMaxAvailable}, false),
+								},
+							},
+						},
+					},
+					"replication": {
+						Type:     schema.TypeList,
+						Optional: true,
+						MaxItems: 1,
+						Elem: &schema.Resource{
+							Schema: map[string]*schema.Schema{
+								"svm_name": {
+									Type:     schema.TypeString,
+									Required: true,
+								},
+								"policy": {
+									Type:     schema.TypeString,
+									Optional: true,
+									Default:  "MirrorAllSnapshots",
+								},
+								"schedule": {
+									Type:     schema.TypeString,
+									Optional: true,
+									Default:  "",
+								},
+								"state": {
+									Type:     schema.TypeString,
+									Computed: true,
+								},
+								"failed_over": {
+									Type:     schema.TypeBool,
+									Computed: true,
+								},
+							},
+						},
+					},
 				},
 			},
 		},
@@ -527,7 +623,7 @@ func schemaFlexbotServer() map[string]*schema.Schema {
 					},
 					"iscsi_initiator": {
 						Type:     schema.TypeList,
-						Required: true,
+						Optional: true,
 						MaxItems: 2,
 						Elem: &schema.Resource{
 							Schema: map[string]*schema.Schema{
@@ -657,6 +753,86 @@ func schemaFlexbotServer() map[string]*schema.Schema {
 							},
 						},
 					},
+					"iscsi_chap": {
+						Type:     schema.TypeList,
+						Optional: true,
+						MaxItems: 1,
+						Elem: &schema.Resource{
+							Schema: map[string]*schema.Schema{
+								"user": {
+									Type:     schema.TypeString,
+									Required: true,
+								},
+								"password": {
+									Type:      schema.TypeString,
+									Required:  true,
+									Sensitive: true,
+								},
+								"target_user": {
+									Type:     schema.TypeString,
+									Optional: true,
+								},
+								"target_password": {
+									Type:      schema.TypeString,
+									Optional:  true,
+									Sensitive: true,
+								},
+							},
+						},
+					},
+					"fc_initiator": {
+						Type:     schema.TypeList,
+						Optional: true,
+						MaxItems: 2,
+						Elem: &schema.Resource{
+							Schema: map[string]*schema.Schema{
+								"name": {
+									Type:     schema.TypeString,
+									Required: true,
+								},
+								"wwpn": {
+									Type:     schema.TypeString,
+									Optional: true,
+									Computed: true,
+								},
+								"wwnn": {
+									Type:     schema.TypeString,
+									Optional: true,
+									Computed: true,
+								},
+								"fabric": {
+									Type:     schema.TypeString,
+									Optional: true,
+								},
+								"target_lifs": {
+									Type:     schema.TypeList,
+									Optional: true,
+									Elem:     &schema.Schema{Type: schema.TypeString},
+								},
+								"fc_target": {
+									Type:     schema.TypeList,
+									Optional: true,
+									Computed: true,
+									MaxItems: 1,
+									Elem: &schema.Resource{
+										Schema: map[string]*schema.Schema{
+											"node_name": {
+												Type:     schema.TypeString,
+												Optional: true,
+												Computed: true,
+											},
+											"interfaces": {
+												Type:     schema.TypeList,
+												Optional: true,
+												Computed: true,
+												Elem:     &schema.Schema{Type: schema.TypeString},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
 					"nvme_host": {
 						Type:     schema.TypeList,
 						Optional: true,
@@ -757,6 +933,36 @@ func schemaFlexbotServer() map[string]*schema.Schema {
 				},
 			},
 		},
+		"snapshot_retention": {
+			Type:     schema.TypeList,
+			Optional: true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"prefix": {
+						Type:     schema.TypeString,
+						Optional: true,
+						Default:  "",
+					},
+					"keep_last": {
+						Type:     schema.TypeInt,
+						Optional: true,
+						Default:  0,
+						ValidateFunc: validation.IntAtLeast(0),
+					},
+					"max_age": {
+						Type:     schema.TypeString,
+						Optional: true,
+						Default:  "",
+						ValidateFunc: func(val interface{}, key string) (warns []string, errs []error) {
+							if _, err := ontap.ParseSnapshotMaxAge(val.(string)); err != nil {
+								errs = append(errs, fmt.Errorf("%q: %s", key, err))
+							}
+							return
+						},
+					},
+				},
+			},
+		},
 		"restore": {
 			Type:     schema.TypeList,
 			Optional: true,
@@ -776,6 +982,20 @@ func schemaFlexbotServer() map[string]*schema.Schema {
 				},
 			},
 		},
+		"failover": {
+			Type:     schema.TypeList,
+			Optional: true,
+			MaxItems: 1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"failover": {
+						Type:     schema.TypeBool,
+						Optional: true,
+						Default:  false,
+					},
+				},
+			},
+		},
 		"maintenance": {
 			Type:     schema.TypeList,
 			Optional: true,
@@ -13,6 +13,7 @@ require (
 	github.com/rancher/norman v0.5.0
 	github.com/rancher/rancher/pkg/client v0.0.0-20240716141526-e0d2afd007d8
 	github.com/sirupsen/logrus v1.9.3
+	github.com/vmware/go-nfs-client v0.0.0-20190605212624-d43b92724c1b
 	github.com/vmware/govmomi v0.53.1
 	golang.org/x/crypto v0.26.0
 	gopkg.in/yaml.v3 v3.0.1
@@ -87,7 +88,6 @@ require (
 	github.com/spf13/cobra v1.8.1 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/vmihailenco/msgpack v4.0.1+incompatible // indirect
-	github.com/vmware/go-nfs-client v0.0.0-20190605212624-d43b92724c1b // indirect
 	github.com/x448/float16 v0.8.4 // indirect
 	github.com/xlab/treeprint v1.2.0 // indirect
 	github.com/zclconf/go-cty v1.2.1 // indirect
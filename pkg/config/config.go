@@ -25,6 +25,7 @@ const (
 	templateRepoVolName = "template_repo"
 	zapiVersion         = "1.160"
 	apiMethod           = "zapi"
+	replicationPolicy   = "MirrorAllSnapshots"
 )
 
 // Name convention for cDOT storage objects (can be overriden via config.yaml)
@@ -47,9 +48,10 @@ const (
 
 // ComputeAnnotations is node annotations for compute
 type ComputeAnnotations struct {
-	UcsmHost string         `yaml:"ucsmHost" json:"ucsmHost"`
-	SpDn     string         `yaml:"spDn" json:"spDn"`
-	Blade    util.BladeSpec `yaml:"blade" json:"blade"`
+	UcsmHost  string         `yaml:"ucsmHost" json:"ucsmHost"`
+	SpDn      string         `yaml:"spDn" json:"spDn"`
+	Blade     util.BladeSpec `yaml:"blade" json:"blade"`
+	Placement string         `yaml:"placement,omitempty" json:"placement,omitempty"`
 }
 
 // StorageAnnotations is node annotations for storage
@@ -77,6 +79,13 @@ type Credentials struct {
 	Password string `yaml:"password,omitempty" json:"password,omitempty"`
 }
 
+// TLSOptions is API endpoint certificate verification, Insecure skips verification,
+// CaCert is PEM encoded CA certificate(s) trusted in addition to system CA's (i.e. for on-prem appliance)
+type TLSOptions struct {
+	Insecure bool   `yaml:"insecure,omitempty" json:"insecure,omitempty"`
+	CaCert   string `yaml:"caCert,omitempty" json:"caCert,omitempty"`
+}
+
 // InfobloxCredentials is Infoblox specific credentials
 type InfobloxCredentials struct {
 	Credentials                          `yaml:",inline" json:",inline"`
@@ -123,6 +132,31 @@ type IscsiInitiator struct {
 	IscsiTarget      *IscsiTarget `yaml:"iscsiTarget,omitempty" json:"iscsiTarget,omitempty"`
 }
 
+// IscsiChap is iSCSI CHAP authentication, mutual CHAP is enabled with target credentials
+type IscsiChap struct {
+	User           string `yaml:"user,omitempty" json:"user,omitempty"`
+	Password       string `yaml:"password,omitempty" json:"password,omitempty"`
+	TargetUser     string `yaml:"targetUser,omitempty" json:"targetUser,omitempty"`
+	TargetPassword string `yaml:"targetPassword,omitempty" json:"targetPassword,omitempty"`
+}
+
+// FcTarget is FC target
+type FcTarget struct {
+	NodeName   string   `yaml:"nodeName,omitempty" json:"nodeName,omitempty"`
+	Interfaces []string `yaml:"interfaces,omitempty" json:"interfaces,omitempty"`
+}
+
+// FcInitiator is FC initiator (vHBA), targets are FC LIF's on initiator fabric
+// selected by LIF names or WWPN's in TargetLifs or by fabric name reported by ONTAP
+type FcInitiator struct {
+	Name       string    `yaml:"name" json:"name"`
+	Wwpn       string    `yaml:"wwpn,omitempty" json:"wwpn,omitempty"`
+	Wwnn       string    `yaml:"wwnn,omitempty" json:"wwnn,omitempty"`
+	Fabric     string    `yaml:"fabric,omitempty" json:"fabric,omitempty"`
+	TargetLifs []string  `yaml:"targetLifs,omitempty" json:"targetLifs,omitempty"`
+	FcTarget   *FcTarget `yaml:"fcTarget,omitempty" json:"fcTarget,omitempty"`
+}
+
 // NvmeTarget is NVME Subsystem target
 type NvmeTarget struct {
 	TargetNqn  string   `yaml:"targetNqn,omitempty" json:"targetNqn,omitempty"`
@@ -145,15 +179,30 @@ type Ipam struct {
 	DnsZone       string              `yaml:"dnsZone,omitempty" json:"dnsZone,omitempty"`
 }
 
-// Compute is UCS compute
+// BladePlacement is blade placement policy, Policy is either "random" (default), "spread", "pack", "lowest-slot" or "firmware",
+// spread and pack group nodes by compute label, Firmware is preferred host firmware package version for "firmware" policy
+type BladePlacement struct {
+	Policy   string `yaml:"policy,omitempty" json:"policy,omitempty"`
+	Firmware string `yaml:"firmware,omitempty" json:"firmware,omitempty"`
+}
+
+// Compute is UCS compute, Intersight managed server or Redfish managed rack server,
+// Provider is either "ucsm" (default), "intersight" or "redfish"
 type Compute struct {
-	UcsmCredentials Credentials    `yaml:"ucsmCredentials,omitempty" json:"ucsmCredentials,omitempty"`
+	Provider              string      `yaml:"provider,omitempty" json:"provider,omitempty"`
+	UcsmCredentials       Credentials `yaml:"ucsmCredentials,omitempty" json:"ucsmCredentials,omitempty"`
+	RedfishCredentials    Credentials `yaml:"redfishCredentials,omitempty" json:"redfishCredentials,omitempty"`
+	IntersightCredentials Credentials `yaml:"intersightCredentials,omitempty" json:"intersightCredentials,omitempty"`
+	IntersightTLS         TLSOptions  `yaml:"intersightTls,omitempty" json:"intersightTls,omitempty"`
+	SystemId              string      `yaml:"systemId,omitempty" json:"systemId,omitempty"`
 	HostName        string         `yaml:"hostName,omitempty" json:"hostName,omitempty"`
 	SpOrg           string         `yaml:"spOrg" json:"spOrg"`
 	SpTemplate      string         `yaml:"spTemplate" json:"spTemplate"`
 	SpDn            string         `yaml:"spDn,omitempty" json:"spDn,omitempty"`
 	BladeSpec       util.BladeSpec `yaml:"bladeSpec,omitempty" json:"bladeSpec,omitempty"`
 	BladeAssigned   util.BladeSpec `yaml:"bladeAssigned,omitempty" json:"bladeAssigned,omitempty"`
+	BladePlacement  BladePlacement `yaml:"bladePlacement,omitempty" json:"bladePlacement,omitempty"`
+	Placement       string         `yaml:"placement,omitempty" json:"placement,omitempty"`
 	ChassisId       string         `yaml:"chassisId,omitempty" json:"chassisId,omitempty"`
 	Firmware        string         `yaml:"firmware,omitempty" json:"firmware,omitempty"`
 	KernelOpt       string         `yaml:"kernelopt,omitempty" json:"kernelopt,omitempty"`
@@ -200,6 +249,61 @@ type DataNvme struct {
 	Size int         `yaml:"size,omitempty" json:"size,omitempty"`
 }
 
+// Replication is SnapMirror replication of node volume to DR cluster
+type Replication struct {
+	CdotCredentials CdotCredentials `yaml:"cdotCredentials,omitempty" json:"cdotCredentials,omitempty"`
+	SvmName         string          `yaml:"svmName,omitempty" json:"svmName,omitempty"`
+	Policy          string          `yaml:"policy,omitempty" json:"policy,omitempty"`
+	Schedule        string          `yaml:"schedule,omitempty" json:"schedule,omitempty"`
+	State           string          `yaml:"state,omitempty" json:"state,omitempty"`
+	FailedOver      bool            `yaml:"failedOver,omitempty" json:"failedOver,omitempty"`
+}
+
+// Signatures is verification of images and templates with ed25519 signatures,
+// image data is verified at upload, afterwards image digest recorded at upload is verified
+type Signatures struct {
+	Required    bool     `yaml:"required,omitempty" json:"required,omitempty"`
+	TrustedKeys []string `yaml:"trustedKeys,omitempty" json:"trustedKeys,omitempty"`
+}
+
+// ArtifactSources is access to images and templates in S3-compatible object storage and OCI registries
+type ArtifactSources struct {
+	S3  S3Source  `yaml:"s3,omitempty" json:"s3,omitempty"`
+	Oci OciSource `yaml:"oci,omitempty" json:"oci,omitempty"`
+}
+
+// S3Source is S3-compatible object storage endpoint and credentials,
+// credentials default to AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables
+type S3Source struct {
+	Endpoint     string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	Region       string `yaml:"region,omitempty" json:"region,omitempty"`
+	AccessKey    string `yaml:"accessKey,omitempty" json:"accessKey,omitempty"`
+	SecretKey    string `yaml:"secretKey,omitempty" json:"secretKey,omitempty"`
+	SessionToken string `yaml:"sessionToken,omitempty" json:"sessionToken,omitempty"`
+}
+
+// OciSource is OCI registry credentials,
+// credentials default to OCI_USERNAME and OCI_PASSWORD environment variables
+type OciSource struct {
+	User      string `yaml:"user,omitempty" json:"user,omitempty"`
+	Password  string `yaml:"password,omitempty" json:"password,omitempty"`
+	PlainHttp bool   `yaml:"plainHttp,omitempty" json:"plainHttp,omitempty"`
+}
+
+// Upload is chunked upload settings for images and seed ISO
+type Upload struct {
+	ChunkSize   int `yaml:"chunkSize,omitempty" json:"chunkSize,omitempty"`
+	Parallelism int `yaml:"parallelism,omitempty" json:"parallelism,omitempty"`
+	Retries     int `yaml:"retries,omitempty" json:"retries,omitempty"`
+}
+
+// SnapshotRetention is retention rule for snapshots created by flexbot
+type SnapshotRetention struct {
+	Prefix   string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+	KeepLast int    `yaml:"keepLast,omitempty" json:"keepLast,omitempty"`
+	MaxAge   string `yaml:"maxAge,omitempty" json:"maxAge,omitempty"`
+}
+
 // Storage is cDOT storage
 type Storage struct {
 	CdotCredentials  CdotCredentials `yaml:"cdotCredentials,omitempty" json:"cdotCredentials,omitempty"`
@@ -207,6 +311,7 @@ type Storage struct {
 	ImageRepoName    string          `yaml:"imageRepoName,omitempty" json:"imageRepoName,omitempty"`
 	TemplateRepoName string          `yaml:"templateRepoName,omitempty" json:"templateRepoName,omitempty"`
 	VolumeName       string          `yaml:"volumeName,omitempty" json:"volumeName,omitempty"`
+	Aggregate        string          `yaml:"aggregate,omitempty" json:"aggregate,omitempty"`
 	IgroupName       string          `yaml:"igroupName,omitempty" json:"igroupName,omitempty"`
 	BootstrapLun     BootstrapLun    `yaml:"bootstrapLun,omitempty" json:"bootstrapLun,omitempty"`
 	BootLun          BootLun         `yaml:"bootLun,omitempty" json:"bootLun,omitempty"`
@@ -214,12 +319,19 @@ type Storage struct {
 	SeedLun          SeedLun         `yaml:"seedLun,omitempty" json:"seedLun,omitempty"`
 	DataNvme         DataNvme        `yaml:"dataNvme,omitempty" json:"dataNvme,omitempty"`
 	Snapshots        []string        `yaml:"snapshots,omitempty" json:"snapshots,omitempty"`
+	SnapshotRetention []SnapshotRetention `yaml:"snapshotRetention,omitempty" json:"snapshotRetention,omitempty"`
+	Replication      Replication     `yaml:"replication,omitempty" json:"replication,omitempty"`
+	Signatures       Signatures      `yaml:"signatures,omitempty" json:"signatures,omitempty"`
+	Upload           Upload          `yaml:"upload,omitempty" json:"upload,omitempty"`
+	ArtifactSources  ArtifactSources `yaml:"artifactSources,omitempty" json:"artifactSources,omitempty"`
 }
 
 // Network is compute network
 type Network struct {
 	Node           []NetworkInterface `yaml:"node" json:"node"`
 	IscsiInitiator []IscsiInitiator   `yaml:"iscsiInitiator" json:"iscsiInitiator"`
+	IscsiChap      IscsiChap          `yaml:"iscsiChap,omitempty" json:"iscsiChap,omitempty"`
+	FcInitiator    []FcInitiator      `yaml:"fcInitiator,omitempty" json:"fcInitiator,omitempty"`
 	NvmeHost       []NvmeHost         `yaml:"nvmeHost" json:"nvmeHost"`
 }
 
@@ -233,6 +345,7 @@ type NodeConfig struct {
 	Labels       map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
 	Taints       []v1.Taint        `yaml:"taints,omitempty" json:"taints,omitempty"`
 	ChangeStatus uint32            `yaml:"changeStatus,omitempty" json:"changeStatus,omitempty"`
+	Plan         *Plan             `yaml:"-" json:"-"`
 }
 
 // SetDefaults sets initial configuration with default values
@@ -241,9 +354,20 @@ func SetDefaults(nodeConfig *NodeConfig, hostName string, image string, template
 	if nodeConfig.Storage.CdotCredentials.ApiMethod == "" {
 		nodeConfig.Storage.CdotCredentials.ApiMethod = apiMethod
 	}
-	if nodeConfig.Storage.CdotCredentials.ZapiVersion == "" {
+	if nodeConfig.Storage.CdotCredentials.ZapiVersion == "" && nodeConfig.Storage.CdotCredentials.ApiMethod != "auto" {
 		nodeConfig.Storage.CdotCredentials.ZapiVersion = zapiVersion
 	}
+	if nodeConfig.Storage.Replication.CdotCredentials.Host != "" {
+		if nodeConfig.Storage.Replication.CdotCredentials.ApiMethod == "" {
+			nodeConfig.Storage.Replication.CdotCredentials.ApiMethod = apiMethod
+		}
+		if nodeConfig.Storage.Replication.CdotCredentials.ZapiVersion == "" && nodeConfig.Storage.Replication.CdotCredentials.ApiMethod != "auto" {
+			nodeConfig.Storage.Replication.CdotCredentials.ZapiVersion = zapiVersion
+		}
+	}
+	if nodeConfig.Storage.Replication.SvmName != "" && nodeConfig.Storage.Replication.Policy == "" {
+		nodeConfig.Storage.Replication.Policy = replicationPolicy
+	}
 	if nodeConfig.Storage.ImageRepoName == "" {
 		nodeConfig.Storage.ImageRepoName = imageRepoVolName
 	}
@@ -264,8 +388,8 @@ func SetDefaults(nodeConfig *NodeConfig, hostName string, image string, template
 		nodeConfig.Storage.SeedLun.SeedTemplate.Location = templatePath
 	}
 	if nodeConfig.Compute.HostName != "" {
-		if len(nodeConfig.Network.IscsiInitiator) < 1 {
-			err = fmt.Errorf("expected at least one iSCSI initiator")
+		if len(nodeConfig.Network.IscsiInitiator) < 1 && len(nodeConfig.Network.FcInitiator) < 1 {
+			err = fmt.Errorf("expected at least one iSCSI or FC initiator")
 			return
 		}
 		for i := range nodeConfig.Network.Node {
@@ -294,6 +418,10 @@ func SetDefaults(nodeConfig *NodeConfig, hostName string, image string, template
 				nodeConfig.Network.IscsiInitiator[i].DnsServer2 = "0.0.0.0"
 			}
 		}
+		if nodeConfig.Network.IscsiChap.TargetUser != "" && nodeConfig.Network.IscsiChap.User == "" {
+			err = fmt.Errorf("expected iSCSI CHAP user with mutual CHAP target user")
+			return
+		}
 		for i := range nodeConfig.Network.NvmeHost {
 	                for j := range nodeConfig.Network.Node {
 		                if nodeConfig.Network.NvmeHost[i].HostInterface == nodeConfig.Network.Node[j].Name {
@@ -398,6 +526,18 @@ func SetDefaults(nodeConfig *NodeConfig, hostName string, image string, template
 	return
 }
 
+// GetVolumeName gets default node volume name for host name
+func GetVolumeName(hostName string) (volumeName string, err error) {
+	var tWriter bytes.Buffer
+	nodeConfig := &NodeConfig{Compute: Compute{HostName: hostName}}
+	t := template.Must(template.New("VolumeName").Parse(volumeNameTemplate))
+	if err = t.Execute(&tWriter, nodeConfig); err != nil {
+		return
+	}
+	volumeName = strings.Replace(tWriter.String(), "-", "_", -1)
+	return
+}
+
 // ParseNodeConfig parses node configuration
 func ParseNodeConfig(nodeConfigArg string, nodeConfig *NodeConfig) (err error) {
 	var b []byte
@@ -449,6 +589,66 @@ func EncryptNodeConfig(nodeConfig *NodeConfig, passPhrase string) (err error) {
 	}
 	if nodeConfig.Compute.UcsmCredentials.Password, err = crypt.EncryptString(nodeConfig.Compute.UcsmCredentials.Password, passPhrase); err != nil {
 		err = fmt.Errorf("EncryptNodeConfig(nodeConfig.Compute.UcsmCredentials.Password): failure: %s", err)
+		return
+	}
+	if nodeConfig.Compute.RedfishCredentials.User != "" {
+		if nodeConfig.Compute.RedfishCredentials.User, err = crypt.EncryptString(nodeConfig.Compute.RedfishCredentials.User, passPhrase); err != nil {
+			err = fmt.Errorf("EncryptNodeConfig(nodeConfig.Compute.RedfishCredentials.User): failure: %s", err)
+			return
+		}
+	}
+	if nodeConfig.Compute.IntersightCredentials.User != "" {
+		if nodeConfig.Compute.IntersightCredentials.User, err = crypt.EncryptString(nodeConfig.Compute.IntersightCredentials.User, passPhrase); err != nil {
+			err = fmt.Errorf("EncryptNodeConfig(nodeConfig.Compute.IntersightCredentials.User): failure: %s", err)
+			return
+		}
+	}
+	if nodeConfig.Compute.RedfishCredentials.Password != "" {
+		if nodeConfig.Compute.RedfishCredentials.Password, err = crypt.EncryptString(nodeConfig.Compute.RedfishCredentials.Password, passPhrase); err != nil {
+			err = fmt.Errorf("EncryptNodeConfig(nodeConfig.Compute.RedfishCredentials.Password): failure: %s", err)
+			return
+		}
+	}
+	if nodeConfig.Compute.IntersightCredentials.Password != "" {
+		if nodeConfig.Compute.IntersightCredentials.Password, err = crypt.EncryptString(nodeConfig.Compute.IntersightCredentials.Password, passPhrase); err != nil {
+			err = fmt.Errorf("EncryptNodeConfig(nodeConfig.Compute.IntersightCredentials.Password): failure: %s", err)
+			return
+		}
+	}
+	if nodeConfig.Storage.Replication.CdotCredentials.User != "" {
+		if nodeConfig.Storage.Replication.CdotCredentials.User, err = crypt.EncryptString(nodeConfig.Storage.Replication.CdotCredentials.User, passPhrase); err != nil {
+			err = fmt.Errorf("EncryptNodeConfig(nodeConfig.Storage.Replication.CdotCredentials.User): failure: %s", err)
+			return
+		}
+	}
+	if nodeConfig.Storage.Replication.CdotCredentials.Password != "" {
+		if nodeConfig.Storage.Replication.CdotCredentials.Password, err = crypt.EncryptString(nodeConfig.Storage.Replication.CdotCredentials.Password, passPhrase); err != nil {
+			err = fmt.Errorf("EncryptNodeConfig(nodeConfig.Storage.Replication.CdotCredentials.Password): failure: %s", err)
+			return
+		}
+	}
+	if nodeConfig.Network.IscsiChap.Password != "" {
+		if nodeConfig.Network.IscsiChap.Password, err = crypt.EncryptString(nodeConfig.Network.IscsiChap.Password, passPhrase); err != nil {
+			err = fmt.Errorf("EncryptNodeConfig(nodeConfig.Network.IscsiChap.Password): failure: %s", err)
+			return
+		}
+	}
+	if nodeConfig.Network.IscsiChap.TargetPassword != "" {
+		if nodeConfig.Network.IscsiChap.TargetPassword, err = crypt.EncryptString(nodeConfig.Network.IscsiChap.TargetPassword, passPhrase); err != nil {
+			err = fmt.Errorf("EncryptNodeConfig(nodeConfig.Network.IscsiChap.TargetPassword): failure: %s", err)
+			return
+		}
+	}
+	if nodeConfig.Storage.ArtifactSources.S3.SecretKey != "" {
+		if nodeConfig.Storage.ArtifactSources.S3.SecretKey, err = crypt.EncryptString(nodeConfig.Storage.ArtifactSources.S3.SecretKey, passPhrase); err != nil {
+			err = fmt.Errorf("EncryptNodeConfig(nodeConfig.Storage.ArtifactSources.S3.SecretKey): failure: %s", err)
+			return
+		}
+	}
+	if nodeConfig.Storage.ArtifactSources.Oci.Password != "" {
+		if nodeConfig.Storage.ArtifactSources.Oci.Password, err = crypt.EncryptString(nodeConfig.Storage.ArtifactSources.Oci.Password, passPhrase); err != nil {
+			err = fmt.Errorf("EncryptNodeConfig(nodeConfig.Storage.ArtifactSources.Oci.Password): failure: %s", err)
+		}
 	}
 	return
 }
@@ -477,6 +677,55 @@ func DecryptNodeConfig(nodeConfig *NodeConfig, passPhrase string) (err error) {
 	}
 	if nodeConfig.Compute.UcsmCredentials.Password, err = crypt.DecryptString(nodeConfig.Compute.UcsmCredentials.Password, passPhrase); err != nil {
 		err = fmt.Errorf("DecryptNodeConfig(nodeConfig.Compute.UcsmCredentials.Password): failure: %s", err)
+		return
+	}
+	if nodeConfig.Compute.RedfishCredentials.User != "" {
+		if nodeConfig.Compute.RedfishCredentials.User, err = crypt.DecryptString(nodeConfig.Compute.RedfishCredentials.User, passPhrase); err != nil {
+			err = fmt.Errorf("DecryptNodeConfig(nodeConfig.Compute.RedfishCredentials.User): failure: %s", err)
+			return
+		}
+	}
+	if nodeConfig.Compute.IntersightCredentials.User != "" {
+		if nodeConfig.Compute.IntersightCredentials.User, err = crypt.DecryptString(nodeConfig.Compute.IntersightCredentials.User, passPhrase); err != nil {
+			err = fmt.Errorf("DecryptNodeConfig(nodeConfig.Compute.IntersightCredentials.User): failure: %s", err)
+			return
+		}
+	}
+	if nodeConfig.Compute.RedfishCredentials.Password != "" {
+		if nodeConfig.Compute.RedfishCredentials.Password, err = crypt.DecryptString(nodeConfig.Compute.RedfishCredentials.Password, passPhrase); err != nil {
+			err = fmt.Errorf("DecryptNodeConfig(nodeConfig.Compute.RedfishCredentials.Password): failure: %s", err)
+			return
+		}
+	}
+	if nodeConfig.Compute.IntersightCredentials.Password != "" {
+		if nodeConfig.Compute.IntersightCredentials.Password, err = crypt.DecryptString(nodeConfig.Compute.IntersightCredentials.Password, passPhrase); err != nil {
+			err = fmt.Errorf("DecryptNodeConfig(nodeConfig.Compute.IntersightCredentials.Password): failure: %s", err)
+			return
+		}
+	}
+	if nodeConfig.Storage.Replication.CdotCredentials.User, err = crypt.DecryptString(nodeConfig.Storage.Replication.CdotCredentials.User, passPhrase); err != nil {
+		err = fmt.Errorf("DecryptNodeConfig(nodeConfig.Storage.Replication.CdotCredentials.User): failure: %s", err)
+		return
+	}
+	if nodeConfig.Storage.Replication.CdotCredentials.Password, err = crypt.DecryptString(nodeConfig.Storage.Replication.CdotCredentials.Password, passPhrase); err != nil {
+		err = fmt.Errorf("DecryptNodeConfig(nodeConfig.Storage.Replication.CdotCredentials.Password): failure: %s", err)
+		return
+	}
+	if nodeConfig.Network.IscsiChap.Password, err = crypt.DecryptString(nodeConfig.Network.IscsiChap.Password, passPhrase); err != nil {
+		err = fmt.Errorf("DecryptNodeConfig(nodeConfig.Network.IscsiChap.Password): failure: %s", err)
+		return
+	}
+	if nodeConfig.Network.IscsiChap.TargetPassword, err = crypt.DecryptString(nodeConfig.Network.IscsiChap.TargetPassword, passPhrase); err != nil {
+		err = fmt.Errorf("DecryptNodeConfig(nodeConfig.Network.IscsiChap.TargetPassword): failure: %s", err)
+		return
+	}
+	if nodeConfig.Storage.ArtifactSources.S3.SecretKey, err = crypt.DecryptString(nodeConfig.Storage.ArtifactSources.S3.SecretKey, passPhrase); err != nil {
+		err = fmt.Errorf("DecryptNodeConfig(nodeConfig.Storage.ArtifactSources.S3.SecretKey): failure: %s", err)
+		return
+	}
+	if nodeConfig.Storage.ArtifactSources.Oci.Password, err = crypt.DecryptString(nodeConfig.Storage.ArtifactSources.Oci.Password, passPhrase); err != nil {
+		err = fmt.Errorf("DecryptNodeConfig(nodeConfig.Storage.ArtifactSources.Oci.Password): failure: %s", err)
+		return
 	}
         for argKey, argValue := range nodeConfig.CloudArgs {
 		if nodeConfig.CloudArgs[argKey], err = crypt.DecryptString(argValue, passPhrase); err != nil {
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// PlannedOperation is backend mutation recorded in dry-run mode instead of the call
+type PlannedOperation struct {
+	Backend     string `yaml:"backend" json:"backend"`
+	Host        string `yaml:"host,omitempty" json:"host,omitempty"`
+	Operation   string `yaml:"operation" json:"operation"`
+	Description string `yaml:"description" json:"description"`
+}
+
+// Plan is ordered list of planned operations, set NodeConfig.Plan to run
+// provisioning in dry-run mode: read calls go to backends and mutations are recorded
+type Plan struct {
+	mu         sync.Mutex
+	operations []PlannedOperation
+	state      map[string]interface{}
+}
+
+// NewPlan creates empty plan
+func NewPlan() *Plan {
+	return &Plan{}
+}
+
+// Record appends planned operation
+func (p *Plan) Record(backend string, host string, operation string, format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.operations = append(p.operations, PlannedOperation{
+		Backend:     backend,
+		Host:        host,
+		Operation:   operation,
+		Description: fmt.Sprintf(format, args...),
+	})
+}
+
+// Operations gets planned operations in the order of recording
+func (p *Plan) Operations() []PlannedOperation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PlannedOperation{}, p.operations...)
+}
+
+// State gets backend state kept by key for the life of the plan (i.e. objects planned to be created),
+// newState initializes the state on first use
+func (p *Plan) State(key string, newState func() interface{}) interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state == nil {
+		p.state = make(map[string]interface{})
+	}
+	if _, ok := p.state[key]; !ok {
+		p.state[key] = newState()
+	}
+	return p.state[key]
+}
+
+// String formats plan as numbered list of operations
+func (p *Plan) String() string {
+	var b strings.Builder
+	for i, op := range p.Operations() {
+		fmt.Fprintf(&b, "%d. [%s] %s\n", i+1, op.Backend, op.Description)
+	}
+	return b.String()
+}
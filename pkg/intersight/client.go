@@ -0,0 +1,345 @@
+package intersight
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+const (
+	apiPath     = "/api/v1"
+	httpTimeout = 120
+)
+
+// Client is Intersight API client, requests are signed by API key (HTTP signature)
+type Client struct {
+	endPoint   string
+	keyId      string
+	key        crypto.Signer
+	httpClient *http.Client
+}
+
+// MoRef is Intersight managed object reference
+type MoRef struct {
+	ObjectType string `json:"ObjectType"`
+	Moid       string `json:"Moid"`
+}
+
+// Tag is Intersight managed object tag
+type Tag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+// Organization is organization.Organization
+type Organization struct {
+	Moid string `json:"Moid"`
+	Name string `json:"Name"`
+}
+
+// ProfileTemplate is server.ProfileTemplate
+type ProfileTemplate struct {
+	Moid         string `json:"Moid"`
+	Name         string `json:"Name"`
+	Organization *MoRef `json:"Organization,omitempty"`
+}
+
+// ConfigContext is server profile configuration state
+type ConfigContext struct {
+	ConfigState        string `json:"ConfigState"`
+	ConfigStateSummary string `json:"ConfigStateSummary"`
+	OperState          string `json:"OperState"`
+}
+
+// Profile is server.Profile
+type Profile struct {
+	Moid           string        `json:"Moid"`
+	Name           string        `json:"Name"`
+	Description    string        `json:"Description"`
+	Uuid           string        `json:"Uuid"`
+	Tags           []Tag         `json:"Tags"`
+	Organization   *MoRef        `json:"Organization,omitempty"`
+	SrcTemplate    *MoRef        `json:"SrcTemplate,omitempty"`
+	AssignedServer *MoRef        `json:"AssignedServer,omitempty"`
+	PolicyBucket   []MoRef       `json:"PolicyBucket"`
+	ConfigContext  ConfigContext `json:"ConfigContext"`
+}
+
+// PhysicalSummary is compute.PhysicalSummary, summary of blade or rack server
+type PhysicalSummary struct {
+	Moid             string `json:"Moid"`
+	Dn               string `json:"Dn"`
+	Name             string `json:"Name"`
+	Model            string `json:"Model"`
+	Serial           string `json:"Serial"`
+	NumCpus          int    `json:"NumCpus"`
+	NumCpuCores      int    `json:"NumCpuCores"`
+	NumThreads       int    `json:"NumThreads"`
+	TotalMemory      int    `json:"TotalMemory"`
+	ChassisId        string `json:"ChassisId"`
+	SlotId           int    `json:"SlotId"`
+	Firmware         string `json:"Firmware"`
+	OperPowerState   string `json:"OperPowerState"`
+	OperState        string `json:"OperState"`
+	ServiceProfile   string `json:"ServiceProfile"`
+	SourceObjectType string `json:"SourceObjectType"`
+	ManagementMode   string `json:"ManagementMode"`
+}
+
+// ServerSettings is compute.ServerSetting
+type ServerSettings struct {
+	Moid            string `json:"Moid"`
+	AdminPowerState string `json:"AdminPowerState"`
+}
+
+// EthIf is vnic.EthIf
+type EthIf struct {
+	Moid                  string `json:"Moid"`
+	Name                  string `json:"Name"`
+	MacAddress            string `json:"MacAddress"`
+	LanConnectivityPolicy *MoRef `json:"LanConnectivityPolicy,omitempty"`
+	IscsiBootPolicy       *MoRef `json:"IscsiBootPolicy,omitempty"`
+}
+
+// LanConnectivityPolicy is vnic.LanConnectivityPolicy
+type LanConnectivityPolicy struct {
+	Moid              string `json:"Moid"`
+	Name              string `json:"Name"`
+	IqnAllocationType string `json:"IqnAllocationType"`
+	StaticIqnName     string `json:"StaticIqnName"`
+}
+
+// IscsiLun is iSCSI target LUN
+type IscsiLun struct {
+	LunId    int  `json:"LunId"`
+	Bootable bool `json:"Bootable"`
+}
+
+// IscsiStaticTargetPolicy is vnic.IscsiStaticTargetPolicy
+type IscsiStaticTargetPolicy struct {
+	Moid         string   `json:"Moid,omitempty"`
+	Name         string   `json:"Name"`
+	TargetName   string   `json:"TargetName"`
+	IpAddress    string   `json:"IpAddress"`
+	Port         int      `json:"Port"`
+	Lun          IscsiLun `json:"Lun"`
+	Organization *MoRef   `json:"Organization,omitempty"`
+}
+
+// IscsiAuthProfile is iSCSI CHAP credentials
+type IscsiAuthProfile struct {
+	UserId   string `json:"UserId"`
+	Password string `json:"Password,omitempty"`
+}
+
+// IscsiInitiatorIpConfig is iSCSI initiator static IP configuration
+type IscsiInitiatorIpConfig struct {
+	DefaultGateway string `json:"DefaultGateway"`
+	Netmask        string `json:"Netmask"`
+	PrimaryDns     string `json:"PrimaryDns"`
+	SecondaryDns   string `json:"SecondaryDns"`
+}
+
+// IscsiBootPolicy is vnic.IscsiBootPolicy
+type IscsiBootPolicy struct {
+	Moid                       string                 `json:"Moid,omitempty"`
+	Name                       string                 `json:"Name"`
+	TargetSourceType           string                 `json:"TargetSourceType"`
+	InitiatorIpSource          string                 `json:"InitiatorIpSource"`
+	InitiatorStaticIpV4Address string                 `json:"InitiatorStaticIpV4Address"`
+	InitiatorStaticIpV4Config  IscsiInitiatorIpConfig `json:"InitiatorStaticIpV4Config"`
+	PrimaryTargetPolicy        *MoRef                 `json:"PrimaryTargetPolicy,omitempty"`
+	SecondaryTargetPolicy      *MoRef                 `json:"SecondaryTargetPolicy,omitempty"`
+	AuthenticationType         string                 `json:"AuthenticationType"`
+	Chap                       *IscsiAuthProfile      `json:"Chap,omitempty"`
+	MutualChap                 *IscsiAuthProfile      `json:"MutualChap,omitempty"`
+	Organization               *MoRef                 `json:"Organization,omitempty"`
+}
+
+// MoCloner is bulk.MoCloner and bulk.MoDeepCloner request
+type MoCloner struct {
+	Sources []MoRef                  `json:"Sources"`
+	Targets []map[string]interface{} `json:"Targets"`
+}
+
+// NewClient creates Intersight client, credentials user is API key Id and password is PEM encoded secret key,
+// host may include scheme (defaults to https), endpoint certificate is verified unless tlsOptions.Insecure is set
+func NewClient(credentials config.Credentials, tlsOptions config.TLSOptions) (client *Client, err error) {
+	var key interface{}
+	block, _ := pem.Decode([]byte(credentials.Password))
+	if block == nil {
+		err = fmt.Errorf("NewClient(): failed to decode PEM secret key for API key %s", credentials.User)
+		return
+	}
+	if key, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			if key, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+				err = fmt.Errorf("NewClient(): failed to parse secret key for API key %s: %s", credentials.User, err)
+				return
+			}
+		}
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		err = fmt.Errorf("NewClient(): unsupported secret key type for API key %s", credentials.User)
+		return
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: tlsOptions.Insecure}
+	if tlsOptions.CaCert != "" {
+		if tlsConfig.RootCAs, _ = x509.SystemCertPool(); tlsConfig.RootCAs == nil {
+			tlsConfig.RootCAs = x509.NewCertPool()
+		}
+		if !tlsConfig.RootCAs.AppendCertsFromPEM([]byte(tlsOptions.CaCert)) {
+			err = fmt.Errorf("NewClient(): failed to parse PEM CA certificate for endpoint %s", credentials.Host)
+			return
+		}
+	}
+	endPoint := strings.TrimRight(credentials.Host, "/")
+	if !strings.HasPrefix(endPoint, "http://") && !strings.HasPrefix(endPoint, "https://") {
+		endPoint = "https://" + endPoint
+	}
+	client = &Client{
+		endPoint: endPoint,
+		keyId:    credentials.User,
+		key:      signer,
+		httpClient: &http.Client{
+			Timeout: time.Duration(httpTimeout) * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}
+	return
+}
+
+// SigningString builds HTTP signature signing string of request target, date, digest and host headers
+func SigningString(method string, requestURI string, date string, digest string, host string) string {
+	return "(request-target): " + strings.ToLower(method) + " " + requestURI + "\n" +
+		"date: " + date + "\n" +
+		"digest: " + digest + "\n" +
+		"host: " + host
+}
+
+// sign adds Date, Digest and HTTP signature Authorization headers to request
+func (c *Client) sign(req *http.Request, body []byte) (err error) {
+	var signature []byte
+	var algorithm string
+	date := time.Now().UTC().Format(http.TimeFormat)
+	bodyDigest := sha256.Sum256(body)
+	digest := "SHA-256=" + base64.StdEncoding.EncodeToString(bodyDigest[:])
+	req.Header.Set("Date", date)
+	req.Header.Set("Digest", digest)
+	hashed := sha256.Sum256([]byte(SigningString(req.Method, req.URL.RequestURI(), date, digest, req.URL.Host)))
+	switch c.key.(type) {
+	case *rsa.PrivateKey:
+		algorithm = "rsa-sha256"
+	case *ecdsa.PrivateKey:
+		algorithm = "hs2019"
+	default:
+		err = fmt.Errorf("sign(): unsupported secret key type")
+		return
+	}
+	if signature, err = c.key.Sign(rand.Reader, hashed[:], crypto.SHA256); err != nil {
+		return
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Signature keyId=\"%s\",algorithm=\"%s\",headers=\"(request-target) date digest host\",signature=\"%s\"", c.keyId, algorithm, base64.StdEncoding.EncodeToString(signature)))
+	return
+}
+
+// do sends signed request to Intersight API and decodes response into out if not nil
+func (c *Client) do(method string, path string, in interface{}, out interface{}) (err error) {
+	var body []byte
+	var req *http.Request
+	var resp *http.Response
+	if in != nil {
+		if body, err = json.Marshal(in); err != nil {
+			return
+		}
+	}
+	if req, err = http.NewRequest(method, c.endPoint+apiPath+path, bytes.NewReader(body)); err != nil {
+		return
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if err = c.sign(req, body); err != nil {
+		return
+	}
+	if resp, err = c.httpClient.Do(req); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if body, err = ioutil.ReadAll(resp.Body); err != nil {
+		return
+	}
+	if resp.StatusCode >= 300 {
+		var apiError struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &apiError) == nil && apiError.Message != "" {
+			err = fmt.Errorf("%s %s: HTTP status %d: %s: %s", method, path, resp.StatusCode, apiError.Code, apiError.Message)
+		} else {
+			err = fmt.Errorf("%s %s: HTTP status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+		return
+	}
+	if out != nil && len(body) > 0 {
+		err = json.Unmarshal(body, out)
+	}
+	return
+}
+
+// Get retrieves managed object by path, i.e. "/server/Profiles/<moid>"
+func (c *Client) Get(path string, out interface{}) (err error) {
+	return c.do("GET", path, nil, out)
+}
+
+// Query retrieves managed objects matching OData filter into out slice pointer
+func (c *Client) Query(path string, filter string, out interface{}) (err error) {
+	var results struct {
+		Results json.RawMessage `json:"Results"`
+	}
+	if filter != "" {
+		path = path + "?" + strings.Replace(url.Values{"$filter": []string{filter}}.Encode(), "+", "%20", -1)
+	}
+	if err = c.do("GET", path, nil, &results); err != nil {
+		return
+	}
+	if len(results.Results) > 0 && string(results.Results) != "null" {
+		err = json.Unmarshal(results.Results, out)
+	}
+	return
+}
+
+// Post creates managed object
+func (c *Client) Post(path string, in interface{}, out interface{}) (err error) {
+	return c.do("POST", path, in, out)
+}
+
+// Patch updates managed object
+func (c *Client) Patch(path string, in interface{}, out interface{}) (err error) {
+	return c.do("PATCH", path, in, out)
+}
+
+// Delete deletes managed object
+func (c *Client) Delete(path string) (err error) {
+	return c.do("DELETE", path, nil, nil)
+}
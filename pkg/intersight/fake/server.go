@@ -0,0 +1,470 @@
+// Package fake is in-memory Intersight API stand-in for offline testing of pkg/intersight
+package fake
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/intersight"
+)
+
+// Server is Intersight API stand-in backed by in-memory managed objects,
+// it serves endpoints used by pkg/intersight over plain HTTP,
+// use URL as intersightCredentials.host
+type Server struct {
+	*httptest.Server
+	// KeyId and PublicKey enable HTTP signature verification if set
+	KeyId     string
+	PublicKey crypto.PublicKey
+	mu        sync.Mutex
+	objects   map[string]map[string]record
+	seq       int
+	macSeq    int
+	calls     []string
+}
+
+type record map[string]interface{}
+
+var (
+	pathRegexp   = regexp.MustCompile(`^/api/v1/([a-z]+)/([A-Za-z]+)(?:/([^/]+))?$`)
+	filterRegexp = regexp.MustCompile(`^\s*([A-Za-z.]+)\s+eq\s+'([^']*)'\s*$`)
+	authRegexp   = regexp.MustCompile(`(\w+)="([^"]*)"`)
+)
+
+// NewServer starts Intersight API stand-in
+func NewServer() (s *Server) {
+	s = &Server{
+		objects: make(map[string]map[string]record),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return
+}
+
+// Calls gets log of mutating calls in "<method> <path>" format
+func (s *Server) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.calls...)
+}
+
+// objectType derives managed object type from collection path, i.e. "server/Profiles" is "server.Profile"
+func objectType(group string, collection string) string {
+	switch {
+	case strings.HasSuffix(collection, "ies"):
+		collection = strings.TrimSuffix(collection, "ies") + "y"
+	case strings.HasSuffix(collection, "s"):
+		collection = strings.TrimSuffix(collection, "s")
+	}
+	return group + "." + collection
+}
+
+// add creates managed object in collection, i.e. "server/Profiles"
+func (s *Server) add(collection string, obj record) record {
+	s.seq++
+	moid := fmt.Sprintf("%024x", s.seq)
+	obj["Moid"] = moid
+	parts := strings.Split(collection, "/")
+	obj["ObjectType"] = objectType(parts[0], parts[1])
+	obj["_seq"] = s.seq
+	if s.objects[collection] == nil {
+		s.objects[collection] = make(map[string]record)
+	}
+	s.objects[collection][moid] = obj
+	return obj
+}
+
+// ref makes managed object reference
+func ref(obj record) map[string]interface{} {
+	return map[string]interface{}{"ObjectType": obj["ObjectType"], "Moid": obj["Moid"]}
+}
+
+// refMoid gets Moid of managed object reference property
+func refMoid(obj record, property string) string {
+	if r, ok := obj[property].(map[string]interface{}); ok {
+		moid, _ := r["Moid"].(string)
+		return moid
+	}
+	return ""
+}
+
+// nextMac generates vNIC MAC address from UCS MAC address range
+func (s *Server) nextMac() string {
+	s.macSeq++
+	return fmt.Sprintf("00:25:B5:00:%02X:%02X", s.macSeq/256, s.macSeq%256)
+}
+
+// AddOrganization adds organization and returns its Moid
+func (s *Server) AddOrganization(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.add("organization/Organizations", record{"Name": name})["Moid"].(string)
+}
+
+// AddProfileTemplate adds server profile template with LAN connectivity policy of named vNICs
+func (s *Server) AddProfileTemplate(orgMoid string, name string, vnics []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	org := map[string]interface{}{"ObjectType": "organization.Organization", "Moid": orgMoid}
+	lanPolicy := s.add("vnic/LanConnectivityPolicies", record{"Name": name + "-lan", "Organization": org, "IqnAllocationType": "Pool"})
+	for _, vnic := range vnics {
+		s.add("vnic/EthIfs", record{"Name": vnic, "LanConnectivityPolicy": ref(lanPolicy)})
+	}
+	template := s.add("server/ProfileTemplates", record{"Name": name, "Organization": org, "PolicyBucket": []interface{}{ref(lanPolicy)}})
+	return template["Moid"].(string)
+}
+
+// AddServer adds Intersight managed server with settings, Moid and ManagementMode are set by stand-in
+func (s *Server) AddServer(summary intersight.PhysicalSummary) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var obj record
+	b, _ := json.Marshal(summary)
+	json.Unmarshal(b, &obj)
+	obj["ManagementMode"] = "Intersight"
+	if summary.SourceObjectType == "" {
+		obj["SourceObjectType"] = "compute.Blade"
+	}
+	if summary.OperPowerState == "" {
+		obj["OperPowerState"] = "off"
+	}
+	server := s.add("compute/PhysicalSummaries", obj)
+	s.add("compute/ServerSettings", record{"Server": ref(server), "AdminPowerState": "Policy"})
+	return server["Moid"].(string)
+}
+
+// Summary gets server summary by Moid
+func (s *Server) Summary(moid string) (summary intersight.PhysicalSummary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if obj, ok := s.objects["compute/PhysicalSummaries"][moid]; ok {
+		b, _ := json.Marshal(obj)
+		json.Unmarshal(b, &summary)
+	}
+	return
+}
+
+// Count gets number of managed objects in collection, i.e. "server/Profiles"
+func (s *Server) Count(collection string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.objects[collection])
+}
+
+// property gets managed object property by dotted path, i.e. "Organization.Moid"
+func property(obj record, path string) string {
+	var value interface{} = map[string]interface{}(obj)
+	for _, name := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = m[name]
+	}
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// match matches managed object to OData filter of "eq" expressions joined by "and"
+func match(obj record, filter string) (matched bool, err error) {
+	if filter == "" {
+		return true, nil
+	}
+	for _, expr := range strings.Split(filter, " and ") {
+		m := filterRegexp.FindStringSubmatch(expr)
+		if m == nil {
+			return false, fmt.Errorf("unsupported filter expression \"%s\"", expr)
+		}
+		if property(obj, m[1]) != m[2] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// public strips stand-in internal properties
+func public(obj record) record {
+	out := record{}
+	for k, v := range obj {
+		if !strings.HasPrefix(k, "_") {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// verify verifies HTTP signature of request
+func (s *Server) verify(r *http.Request, body []byte) (err error) {
+	params := make(map[string]string)
+	for _, m := range authRegexp.FindAllStringSubmatch(r.Header.Get("Authorization"), -1) {
+		params[m[1]] = m[2]
+	}
+	if params["keyId"] != s.KeyId {
+		return fmt.Errorf("unknown API key \"%s\"", params["keyId"])
+	}
+	bodyDigest := sha256.Sum256(body)
+	digest := "SHA-256=" + base64.StdEncoding.EncodeToString(bodyDigest[:])
+	if r.Header.Get("Digest") != digest {
+		return fmt.Errorf("digest mismatch")
+	}
+	var signature []byte
+	if signature, err = base64.StdEncoding.DecodeString(params["signature"]); err != nil {
+		return
+	}
+	hashed := sha256.Sum256([]byte(intersight.SigningString(r.Method, r.URL.RequestURI(), r.Header.Get("Date"), digest, r.Host)))
+	switch key := s.PublicKey.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, hashed[:], signature) {
+			err = fmt.Errorf("ECDSA verification failure")
+		}
+	default:
+		err = fmt.Errorf("unsupported public key type")
+	}
+	return
+}
+
+// reply writes JSON response
+func reply(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if body != nil {
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+// replyError writes Intersight API error
+func replyError(w http.ResponseWriter, status int, code string, format string, args ...interface{}) {
+	reply(w, status, map[string]string{"code": code, "message": fmt.Sprintf(format, args...)})
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.PublicKey != nil {
+		if err := s.verify(r, body); err != nil {
+			replyError(w, http.StatusUnauthorized, "InvalidRequest", "signature verification failure: %s", err)
+			return
+		}
+	}
+	m := pathRegexp.FindStringSubmatch(r.URL.Path)
+	if m == nil {
+		replyError(w, http.StatusNotFound, "NotFound", "unknown path %s", r.URL.Path)
+		return
+	}
+	collection, moid := m[1]+"/"+m[2], m[3]
+	var in record
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &in); err != nil {
+			replyError(w, http.StatusBadRequest, "InvalidRequest", "invalid JSON body: %s", err)
+			return
+		}
+	}
+	if r.Method != "GET" {
+		s.calls = append(s.calls, r.Method+" "+strings.TrimPrefix(r.URL.Path, "/api/v1"))
+	}
+	switch {
+	case r.Method == "GET" && moid == "":
+		var results []record
+		for _, obj := range s.objects[collection] {
+			matched, err := match(obj, r.URL.Query().Get("$filter"))
+			if err != nil {
+				replyError(w, http.StatusBadRequest, "InvalidRequest", "%s", err)
+				return
+			}
+			if matched {
+				results = append(results, obj)
+			}
+		}
+		sort.Slice(results, func(i, j int) bool { return results[i]["_seq"].(int) < results[j]["_seq"].(int) })
+		var out []record
+		for _, obj := range results {
+			out = append(out, public(obj))
+		}
+		reply(w, http.StatusOK, map[string]interface{}{"ObjectType": objectType(m[1], m[2]) + ".List", "Results": out})
+	case r.Method == "GET":
+		obj, ok := s.objects[collection][moid]
+		if !ok {
+			replyError(w, http.StatusNotFound, "NotFound", "object %s not found", moid)
+			return
+		}
+		reply(w, http.StatusOK, public(obj))
+	case r.Method == "POST" && collection == "bulk/MoCloners":
+		s.cloneProfile(w, in)
+	case r.Method == "POST" && collection == "bulk/MoDeepCloners":
+		s.deepClone(w, in)
+	case r.Method == "POST" && moid == "":
+		for name := range in {
+			if name == "Moid" {
+				delete(in, name)
+			}
+		}
+		reply(w, http.StatusOK, public(s.add(collection, in)))
+	case r.Method == "PATCH":
+		obj, ok := s.objects[collection][moid]
+		if !ok {
+			replyError(w, http.StatusNotFound, "NotFound", "object %s not found", moid)
+			return
+		}
+		if err := s.patch(collection, obj, in); err != nil {
+			replyError(w, http.StatusBadRequest, "InvalidRequest", "%s", err)
+			return
+		}
+		reply(w, http.StatusOK, public(obj))
+	case r.Method == "DELETE":
+		obj, ok := s.objects[collection][moid]
+		if !ok {
+			replyError(w, http.StatusNotFound, "NotFound", "object %s not found", moid)
+			return
+		}
+		if collection == "server/Profiles" && refMoid(obj, "AssignedServer") != "" {
+			replyError(w, http.StatusBadRequest, "InvalidRequest", "profile %s is assigned to server", obj["Name"])
+			return
+		}
+		delete(s.objects[collection], moid)
+		reply(w, http.StatusOK, nil)
+	default:
+		replyError(w, http.StatusMethodNotAllowed, "InvalidRequest", "method %s is not supported", r.Method)
+	}
+}
+
+// cloneProfile derives server profiles from template
+func (s *Server) cloneProfile(w http.ResponseWriter, in record) {
+	var cloner intersight.MoCloner
+	b, _ := json.Marshal(in)
+	json.Unmarshal(b, &cloner)
+	if len(cloner.Sources) != 1 || cloner.Sources[0].ObjectType != "server.ProfileTemplate" {
+		replyError(w, http.StatusBadRequest, "InvalidRequest", "MoCloner source should be one server.ProfileTemplate")
+		return
+	}
+	template, ok := s.objects["server/ProfileTemplates"][cloner.Sources[0].Moid]
+	if !ok {
+		replyError(w, http.StatusNotFound, "NotFound", "template %s not found", cloner.Sources[0].Moid)
+		return
+	}
+	for _, target := range cloner.Targets {
+		for _, profile := range s.objects["server/Profiles"] {
+			if profile["Name"] == target["Name"] {
+				replyError(w, http.StatusConflict, "InvalidRequest", "profile %s already exists", target["Name"])
+				return
+			}
+		}
+		s.seq++
+		s.add("server/Profiles", record{
+			"Name":          target["Name"],
+			"Organization":  target["Organization"],
+			"SrcTemplate":   ref(template),
+			"PolicyBucket":  append([]interface{}{}, template["PolicyBucket"].([]interface{})...),
+			"Uuid":          fmt.Sprintf("1B4E28BA-2FA1-11D2-883F-%012X", s.seq),
+			"Description":   "",
+			"Tags":          []interface{}{},
+			"ConfigContext": map[string]interface{}{"ConfigState": "Not-assigned", "OperState": ""},
+		})
+	}
+	reply(w, http.StatusOK, map[string]interface{}{"ObjectType": "bulk.MoCloner"})
+}
+
+// deepClone clones LAN connectivity policies with vNICs, cloned vNICs get MAC addresses
+func (s *Server) deepClone(w http.ResponseWriter, in record) {
+	var cloner intersight.MoCloner
+	b, _ := json.Marshal(in)
+	json.Unmarshal(b, &cloner)
+	if len(cloner.Sources) != 1 || cloner.Sources[0].ObjectType != "vnic.LanConnectivityPolicy" {
+		replyError(w, http.StatusBadRequest, "InvalidRequest", "MoDeepCloner source should be one vnic.LanConnectivityPolicy")
+		return
+	}
+	source, ok := s.objects["vnic/LanConnectivityPolicies"][cloner.Sources[0].Moid]
+	if !ok {
+		replyError(w, http.StatusNotFound, "NotFound", "policy %s not found", cloner.Sources[0].Moid)
+		return
+	}
+	for _, target := range cloner.Targets {
+		policy := s.add("vnic/LanConnectivityPolicies", record{"Name": target["Name"], "Organization": target["Organization"], "IqnAllocationType": source["IqnAllocationType"]})
+		var ethIfs []record
+		for _, ethIf := range s.objects["vnic/EthIfs"] {
+			if refMoid(ethIf, "LanConnectivityPolicy") == source["Moid"] {
+				ethIfs = append(ethIfs, ethIf)
+			}
+		}
+		sort.Slice(ethIfs, func(i, j int) bool { return ethIfs[i]["_seq"].(int) < ethIfs[j]["_seq"].(int) })
+		for _, ethIf := range ethIfs {
+			s.add("vnic/EthIfs", record{"Name": ethIf["Name"], "LanConnectivityPolicy": ref(policy), "MacAddress": s.nextMac()})
+		}
+	}
+	reply(w, http.StatusOK, map[string]interface{}{"ObjectType": "bulk.MoDeepCloner"})
+}
+
+// patch updates managed object, profile actions and server power state changes are applied immediately
+func (s *Server) patch(collection string, obj record, in record) (err error) {
+	action, _ := in["Action"].(string)
+	delete(in, "Action")
+	if templateActions, ok := in["TemplateActions"].([]interface{}); ok {
+		for _, templateAction := range templateActions {
+			if t, _ := templateAction.(map[string]interface{}); t != nil && t["Type"] == "Detach" {
+				delete(obj, "SrcTemplate")
+			}
+		}
+		delete(in, "TemplateActions")
+	}
+	for name, value := range in {
+		if name != "Moid" && name != "ObjectType" {
+			obj[name] = value
+		}
+	}
+	switch collection {
+	case "server/Profiles":
+		configContext := obj["ConfigContext"].(map[string]interface{})
+		switch action {
+		case "Deploy":
+			serverMoid := refMoid(obj, "AssignedServer")
+			server, ok := s.objects["compute/PhysicalSummaries"][serverMoid]
+			if !ok {
+				return fmt.Errorf("profile %s has no assigned server", obj["Name"])
+			}
+			if server["ServiceProfile"] != "" && server["ServiceProfile"] != obj["Name"] {
+				return fmt.Errorf("server %s is associated with profile %s", server["Dn"], server["ServiceProfile"])
+			}
+			server["ServiceProfile"] = obj["Name"]
+			configContext["ConfigState"] = "Associated"
+			configContext["OperState"] = "Ok"
+		case "Unassign":
+			if server, ok := s.objects["compute/PhysicalSummaries"][refMoid(obj, "AssignedServer")]; ok {
+				server["ServiceProfile"] = ""
+			}
+			delete(obj, "AssignedServer")
+			configContext["ConfigState"] = "Not-assigned"
+			configContext["OperState"] = ""
+		case "":
+			if refMoid(obj, "AssignedServer") != "" && configContext["ConfigState"] == "Not-assigned" {
+				configContext["ConfigState"] = "Assigned"
+			}
+		default:
+			return fmt.Errorf("unsupported profile action \"%s\"", action)
+		}
+	case "compute/ServerSettings":
+		if server, ok := s.objects["compute/PhysicalSummaries"][refMoid(obj, "Server")]; ok {
+			switch in["AdminPowerState"] {
+			case "PowerOn":
+				server["OperPowerState"] = "on"
+			case "PowerOff":
+				server["OperPowerState"] = "off"
+			}
+		}
+	}
+	return
+}
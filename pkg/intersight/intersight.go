@@ -0,0 +1,1029 @@
+package intersight
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/igor-feoktistov/go-ucsm-sdk/util"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+const (
+	assignTryMax  = 5
+	assignWaitMax = 3600
+	powerWaitMax  = 600
+	defaultOrg    = "default"
+	lanPolicyType = "vnic.LanConnectivityPolicy"
+	wcardChars    = `[\^\*\+\.\(\)\[\]\|\?\$]`
+)
+
+var (
+	// stateWaitInterval is interval between profile and power state polls
+	stateWaitInterval = 10 * time.Second
+)
+
+// SetStateWaitInterval changes interval between profile and power state polls
+// (i.e. no wait with Intersight API stand-in), returns the previous interval
+func SetStateWaitInterval(interval time.Duration) (previous time.Duration) {
+	previous = stateWaitInterval
+	stateWaitInterval = interval
+	return
+}
+
+// lanPolicyName is per-server LAN connectivity policy name
+func lanPolicyName(hostName string) string {
+	return hostName + "-lan"
+}
+
+// iscsiBootPolicyName is per-server iSCSI boot policy name
+func iscsiBootPolicyName(hostName string, vnicName string) string {
+	return hostName + "-" + vnicName
+}
+
+// iscsiTargetPolicyName is per-server iSCSI static target policy name
+func iscsiTargetPolicyName(hostName string, vnicName string, n int) string {
+	return hostName + "-" + vnicName + "-target-" + strconv.Itoa(n)
+}
+
+// getOrganization finds organization by name, spOrg is organization name ("default" if not set)
+func getOrganization(client *Client, name string) (org *MoRef, err error) {
+	var orgs []Organization
+	if name == "" {
+		name = defaultOrg
+	}
+	if err = client.Query("/organization/Organizations", "Name eq '"+name+"'", &orgs); err != nil {
+		err = fmt.Errorf("getOrganization(): %s", err)
+		return
+	}
+	if len(orgs) == 0 {
+		err = fmt.Errorf("getOrganization(): organization \"%s\" not found", name)
+		return
+	}
+	org = &MoRef{ObjectType: "organization.Organization", Moid: orgs[0].Moid}
+	return
+}
+
+// getProfileTemplate finds server profile template by name in organization
+func getProfileTemplate(client *Client, org *MoRef, name string) (template *ProfileTemplate, err error) {
+	var templates []ProfileTemplate
+	if err = client.Query("/server/ProfileTemplates", "Name eq '"+name+"' and Organization.Moid eq '"+org.Moid+"'", &templates); err != nil {
+		err = fmt.Errorf("getProfileTemplate(): %s", err)
+		return
+	}
+	if len(templates) == 0 {
+		err = fmt.Errorf("getProfileTemplate(): server profile template \"%s\" not found", name)
+		return
+	}
+	template = &templates[0]
+	return
+}
+
+// getProfile finds server profile by name in organization, profile is nil if not found
+func getProfile(client *Client, org *MoRef, name string) (profile *Profile, err error) {
+	var profiles []Profile
+	if err = client.Query("/server/Profiles", "Name eq '"+name+"' and Organization.Moid eq '"+org.Moid+"'", &profiles); err != nil {
+		err = fmt.Errorf("getProfile(): %s", err)
+		return
+	}
+	if len(profiles) > 0 {
+		profile = &profiles[0]
+	}
+	return
+}
+
+// getServer retrieves physical server summary by Moid
+func getServer(client *Client, moid string) (server *PhysicalSummary, err error) {
+	var servers []PhysicalSummary
+	if err = client.Query("/compute/PhysicalSummaries", "Moid eq '"+moid+"'", &servers); err != nil {
+		err = fmt.Errorf("getServer(): %s", err)
+		return
+	}
+	if len(servers) == 0 {
+		err = fmt.Errorf("getServer(): server \"%s\" not found", moid)
+		return
+	}
+	server = &servers[0]
+	return
+}
+
+// matchSpec matches value to spec, spec is either regexp, range "<min>-<max>" for numbers, or exact value
+func matchSpec(spec string, value string, numeric bool) bool {
+	if spec == "" {
+		return true
+	}
+	if numeric {
+		rangeRegexp := regexp.MustCompile(`([0-9]+)\s*-\s*([0-9]+)`)
+		if specRange := rangeRegexp.FindStringSubmatch(spec); len(specRange) == 3 {
+			min, _ := strconv.Atoi(specRange[1])
+			max, _ := strconv.Atoi(specRange[2])
+			n, _ := strconv.Atoi(value)
+			return n >= min && n <= max
+		}
+		return strings.TrimSpace(spec) == value
+	}
+	if matched, _ := regexp.MatchString(wcardChars, spec); matched {
+		matched, _ = regexp.MatchString("^"+spec+"$", value)
+		return matched
+	}
+	return spec == value
+}
+
+// matchBladeSpec matches server summary to BladeSpec
+func matchBladeSpec(server *PhysicalSummary, bladeSpec *util.BladeSpec) bool {
+	return matchSpec(bladeSpec.Dn, server.Dn, false) &&
+		matchSpec(bladeSpec.Model, server.Model, false) &&
+		matchSpec(bladeSpec.NumOfCpus, strconv.Itoa(server.NumCpus), true) &&
+		matchSpec(bladeSpec.NumOfCores, strconv.Itoa(server.NumCpuCores), true) &&
+		matchSpec(bladeSpec.NumOfThreads, strconv.Itoa(server.NumThreads), true) &&
+		matchSpec(bladeSpec.TotalMemory, strconv.Itoa(server.TotalMemory), true)
+}
+
+// GetAvailableServers finds Intersight managed servers matching BladeSpec which are not assigned to any profile
+func GetAvailableServers(client *Client, bladeSpec *util.BladeSpec) (servers []PhysicalSummary, err error) {
+	var summaries []PhysicalSummary
+	var profiles []Profile
+	if err = client.Query("/compute/PhysicalSummaries", "ManagementMode eq 'Intersight'", &summaries); err != nil {
+		err = fmt.Errorf("GetAvailableServers(): %s", err)
+		return
+	}
+	if err = client.Query("/server/Profiles", "", &profiles); err != nil {
+		err = fmt.Errorf("GetAvailableServers(): %s", err)
+		return
+	}
+	assigned := make(map[string]bool)
+	for _, profile := range profiles {
+		if profile.AssignedServer != nil {
+			assigned[profile.AssignedServer.Moid] = true
+		}
+	}
+	for i := range summaries {
+		if summaries[i].ServiceProfile == "" && !assigned[summaries[i].Moid] && matchBladeSpec(&summaries[i], bladeSpec) {
+			servers = append(servers, summaries[i])
+		}
+	}
+	return
+}
+
+// waitForConfigState waits for profile configuration state, "Failed" state fails the wait
+func waitForConfigState(client *Client, profileMoid string, state string) (profile *Profile, err error) {
+	giveupTime := time.Now().Add(time.Second * time.Duration(assignWaitMax))
+	for time.Now().Before(giveupTime) {
+		profile = &Profile{}
+		if err = client.Get("/server/Profiles/"+profileMoid, profile); err != nil {
+			err = fmt.Errorf("waitForConfigState(): %s", err)
+			return
+		}
+		if profile.ConfigContext.ConfigState == state {
+			return
+		}
+		if profile.ConfigContext.ConfigState == "Failed" {
+			err = fmt.Errorf("waitForConfigState(): profile \"%s\" config state is \"Failed\": %s", profile.Name, profile.ConfigContext.ConfigStateSummary)
+			return
+		}
+		time.Sleep(stateWaitInterval)
+	}
+	err = fmt.Errorf("waitForConfigState(): timeout waiting for profile \"%s\" config state \"%s\", current state is \"%s\"", profileMoid, state, profile.ConfigContext.ConfigState)
+	return
+}
+
+// deployProfile deploys profile and waits for association
+func deployProfile(client *Client, profileMoid string) (profile *Profile, err error) {
+	if err = client.Patch("/server/Profiles/"+profileMoid, map[string]interface{}{"Action": "Deploy"}, nil); err != nil {
+		err = fmt.Errorf("deployProfile(): %s", err)
+		return
+	}
+	profile, err = waitForConfigState(client, profileMoid, "Associated")
+	return
+}
+
+// setInventory sets assigned server attributes from server summary
+func setInventory(server *PhysicalSummary, profile *Profile, nodeConfig *config.NodeConfig) {
+	nodeConfig.Compute.SpDn = profile.Moid
+	nodeConfig.Compute.BladeSpec.Dn = server.Dn
+	nodeConfig.Compute.BladeAssigned = util.BladeSpec{
+		Dn:           server.Dn,
+		Model:        server.Model,
+		Serial:       server.Serial,
+		NumOfCpus:    strconv.Itoa(server.NumCpus),
+		NumOfCores:   strconv.Itoa(server.NumCpuCores),
+		NumOfThreads: strconv.Itoa(server.NumThreads),
+		TotalMemory:  strconv.Itoa(server.TotalMemory),
+	}
+	nodeConfig.Compute.ChassisId = server.ChassisId
+	nodeConfig.Compute.Powerstate = powerState(server.OperPowerState)
+	for i := range nodeConfig.Network.NvmeHost {
+		nodeConfig.Network.NvmeHost[i].HostNqn = "nqn.2014-08.org.nvmexpress:uuid:" + strings.ToLower(profile.Uuid)
+	}
+}
+
+// getEthIfs retrieves vNICs of profile LAN connectivity policy
+func getEthIfs(client *Client, profile *Profile) (ethIfs []EthIf, err error) {
+	for _, policy := range profile.PolicyBucket {
+		if policy.ObjectType == lanPolicyType {
+			if err = client.Query("/vnic/EthIfs", "LanConnectivityPolicy.Moid eq '"+policy.Moid+"'", &ethIfs); err != nil {
+				err = fmt.Errorf("getEthIfs(): %s", err)
+			}
+			return
+		}
+	}
+	err = fmt.Errorf("getEthIfs(): profile \"%s\" has no LAN connectivity policy", profile.Name)
+	return
+}
+
+// setMacAddresses sets MAC addresses of node and iSCSI interfaces from profile vNICs
+func setMacAddresses(client *Client, profile *Profile, nodeConfig *config.NodeConfig) (ethIfs []EthIf, err error) {
+	if ethIfs, err = getEthIfs(client, profile); err != nil {
+		return
+	}
+	for i := range nodeConfig.Network.Node {
+		var found int = 0
+		for _, ethIf := range ethIfs {
+			if ethIf.Name == nodeConfig.Network.Node[i].Name {
+				nodeConfig.Network.Node[i].Macaddr = ethIf.MacAddress
+				found++
+			}
+		}
+		if found == 0 {
+			err = fmt.Errorf("setMacAddresses(): no vNICs found in profile \"%s\" that match node interface \"%s\"", profile.Name, nodeConfig.Network.Node[i].Name)
+			return
+		}
+	}
+	for i := range nodeConfig.Network.IscsiInitiator {
+		if i > 1 {
+			break
+		}
+		var found int = 0
+		for _, ethIf := range ethIfs {
+			if ethIf.Name == nodeConfig.Network.IscsiInitiator[i].Name {
+				nodeConfig.Network.IscsiInitiator[i].Macaddr = ethIf.MacAddress
+				found++
+			}
+		}
+		if found == 0 {
+			err = fmt.Errorf("setMacAddresses(): no vNICs found in profile \"%s\" that match iSCSI interface \"%s\"", profile.Name, nodeConfig.Network.IscsiInitiator[i].Name)
+			return
+		}
+	}
+	return
+}
+
+// AssignServer assigns physical server per BladeSpec to profile and deploys profile
+func AssignServer(client *Client, profile *Profile, nodeConfig *config.NodeConfig) (err error) {
+	var servers []PhysicalSummary
+	var assignErr error
+	if nodeConfig.Plan != nil {
+		return planAssignServer(client, nodeConfig)
+	}
+	for i := 0; i < assignTryMax; i++ {
+		bladeSpec := nodeConfig.Compute.BladeSpec
+		if servers, err = GetAvailableServers(client, &bladeSpec); err != nil {
+			err = fmt.Errorf("AssignServer: %s", err)
+			return
+		}
+		if len(servers) == 0 {
+			err = fmt.Errorf("AssignServer: GetAvailableServers(): no servers found per BladeSpec")
+			return
+		}
+		rand.Seed(time.Now().UnixNano())
+		server := servers[rand.Intn(len(servers))]
+		assignedServer := MoRef{ObjectType: server.SourceObjectType, Moid: server.Moid}
+		if assignErr = client.Patch("/server/Profiles/"+profile.Moid, map[string]interface{}{"AssignedServer": assignedServer}, nil); assignErr == nil {
+			if profile, err = deployProfile(client, profile.Moid); err != nil {
+				err = fmt.Errorf("AssignServer: %s", err)
+				return
+			}
+			setInventory(&server, profile, nodeConfig)
+			if _, err = setMacAddresses(client, profile, nodeConfig); err != nil {
+				err = fmt.Errorf("AssignServer: %s", err)
+			}
+			return
+		}
+		assignErr = fmt.Errorf("AssignServer: failed to assign server \"%s\": %s", server.Dn, assignErr)
+		time.Sleep(2 * time.Second)
+	}
+	err = assignErr
+	return
+}
+
+// setLanPolicy replaces template LAN connectivity policy of profile with per-server deep copy,
+// iSCSI boot policies and static IQN are server specific
+func setLanPolicy(client *Client, org *MoRef, profile *Profile, nodeConfig *config.NodeConfig) (err error) {
+	var policies []LanConnectivityPolicy
+	name := lanPolicyName(nodeConfig.Compute.HostName)
+	for i, policy := range profile.PolicyBucket {
+		if policy.ObjectType != lanPolicyType {
+			continue
+		}
+		if err = client.Query("/vnic/LanConnectivityPolicies", "Moid eq '"+policy.Moid+"'", &policies); err != nil {
+			err = fmt.Errorf("setLanPolicy(): %s", err)
+			return
+		}
+		if len(policies) > 0 && policies[0].Name == name {
+			return
+		}
+		cloner := MoCloner{
+			Sources: []MoRef{policy},
+			Targets: []map[string]interface{}{{"ObjectType": lanPolicyType, "Name": name, "Organization": org}},
+		}
+		if err = client.Post("/bulk/MoDeepCloners", cloner, nil); err != nil {
+			err = fmt.Errorf("setLanPolicy(): MoDeepCloner failure: %s", err)
+			return
+		}
+		if err = client.Query("/vnic/LanConnectivityPolicies", "Name eq '"+name+"' and Organization.Moid eq '"+org.Moid+"'", &policies); err != nil {
+			err = fmt.Errorf("setLanPolicy(): %s", err)
+			return
+		}
+		if len(policies) == 0 {
+			err = fmt.Errorf("setLanPolicy(): LAN connectivity policy \"%s\" not found after clone", name)
+			return
+		}
+		if len(nodeConfig.Network.IscsiInitiator) > 0 {
+			if err = client.Patch("/vnic/LanConnectivityPolicies/"+policies[0].Moid, map[string]interface{}{"IqnAllocationType": "Static", "StaticIqnName": nodeConfig.Network.IscsiInitiator[0].InitiatorName}, nil); err != nil {
+				err = fmt.Errorf("setLanPolicy(): %s", err)
+				return
+			}
+		}
+		profile.PolicyBucket[i] = MoRef{ObjectType: lanPolicyType, Moid: policies[0].Moid}
+		if err = client.Patch("/server/Profiles/"+profile.Moid, map[string]interface{}{"PolicyBucket": profile.PolicyBucket}, nil); err != nil {
+			err = fmt.Errorf("setLanPolicy(): %s", err)
+		}
+		return
+	}
+	err = fmt.Errorf("setLanPolicy(): profile \"%s\" has no LAN connectivity policy", profile.Name)
+	return
+}
+
+// setTargetPolicy creates or updates iSCSI static target policy
+func setTargetPolicy(client *Client, org *MoRef, policy *IscsiStaticTargetPolicy) (ref *MoRef, err error) {
+	var policies []IscsiStaticTargetPolicy
+	if err = client.Query("/vnic/IscsiStaticTargetPolicies", "Name eq '"+policy.Name+"' and Organization.Moid eq '"+org.Moid+"'", &policies); err != nil {
+		return
+	}
+	policy.Organization = org
+	if len(policies) > 0 {
+		policy.Moid = ""
+		err = client.Patch("/vnic/IscsiStaticTargetPolicies/"+policies[0].Moid, policy, nil)
+		ref = &MoRef{ObjectType: "vnic.IscsiStaticTargetPolicy", Moid: policies[0].Moid}
+		return
+	}
+	var created IscsiStaticTargetPolicy
+	if err = client.Post("/vnic/IscsiStaticTargetPolicies", policy, &created); err != nil {
+		return
+	}
+	ref = &MoRef{ObjectType: "vnic.IscsiStaticTargetPolicy", Moid: created.Moid}
+	return
+}
+
+// setIscsiBoot programs per-server iSCSI boot policies with boot targets on profile iSCSI vNICs
+func setIscsiBoot(client *Client, org *MoRef, profile *Profile, nodeConfig *config.NodeConfig) (err error) {
+	var ethIfs []EthIf
+	var ipv4Net *net.IPNet
+	if len(nodeConfig.Network.IscsiInitiator) == 0 {
+		return
+	}
+	if ethIfs, err = getEthIfs(client, profile); err != nil {
+		return
+	}
+	for i := range nodeConfig.Network.IscsiInitiator {
+		if i > 1 {
+			break
+		}
+		initiator := nodeConfig.Network.IscsiInitiator[i]
+		var ethIf *EthIf
+		for j := range ethIfs {
+			if ethIfs[j].Name == initiator.Name {
+				ethIf = &ethIfs[j]
+			}
+		}
+		if ethIf == nil {
+			err = fmt.Errorf("setIscsiBoot(): no vNICs found in profile \"%s\" that match iSCSI interface \"%s\"", profile.Name, initiator.Name)
+			return
+		}
+		if _, ipv4Net, err = net.ParseCIDR(initiator.Subnet); err != nil {
+			err = fmt.Errorf("setIscsiBoot(): ParseCIDR() failure for subnet %s: %s", initiator.Subnet, err)
+			return
+		}
+		bootPolicy := IscsiBootPolicy{
+			Name:                       iscsiBootPolicyName(nodeConfig.Compute.HostName, initiator.Name),
+			TargetSourceType:           "Static",
+			InitiatorIpSource:          "Static",
+			InitiatorStaticIpV4Address: initiator.Ip,
+			InitiatorStaticIpV4Config: IscsiInitiatorIpConfig{
+				DefaultGateway: initiator.Gateway,
+				Netmask:        net.IPv4(ipv4Net.Mask[0], ipv4Net.Mask[1], ipv4Net.Mask[2], ipv4Net.Mask[3]).String(),
+				PrimaryDns:     initiator.DnsServer1,
+				SecondaryDns:   initiator.DnsServer2,
+			},
+			AuthenticationType: "None",
+			Organization:       org,
+		}
+		if initiator.IscsiTarget != nil {
+			for j, targetInterface := range initiator.IscsiTarget.Interfaces {
+				if j > 1 {
+					break
+				}
+				var ref *MoRef
+				if ref, err = setTargetPolicy(client, org, &IscsiStaticTargetPolicy{
+					Name:       iscsiTargetPolicyName(nodeConfig.Compute.HostName, initiator.Name, j+1),
+					TargetName: initiator.IscsiTarget.NodeName,
+					IpAddress:  targetInterface,
+					Port:       3260,
+					Lun:        IscsiLun{LunId: 0, Bootable: true},
+				}); err != nil {
+					err = fmt.Errorf("setIscsiBoot(): failure for iSCSI interface %s: %s", initiator.Name, err)
+					return
+				}
+				if j == 0 {
+					bootPolicy.PrimaryTargetPolicy = ref
+				} else {
+					bootPolicy.SecondaryTargetPolicy = ref
+				}
+			}
+		}
+		if nodeConfig.Network.IscsiChap.User != "" {
+			bootPolicy.AuthenticationType = "chap"
+			bootPolicy.Chap = &IscsiAuthProfile{UserId: nodeConfig.Network.IscsiChap.User, Password: nodeConfig.Network.IscsiChap.Password}
+			if nodeConfig.Network.IscsiChap.TargetUser != "" {
+				bootPolicy.AuthenticationType = "mutual_chap"
+				bootPolicy.MutualChap = &IscsiAuthProfile{UserId: nodeConfig.Network.IscsiChap.TargetUser, Password: nodeConfig.Network.IscsiChap.TargetPassword}
+			}
+		}
+		var bootPolicies []IscsiBootPolicy
+		if err = client.Query("/vnic/IscsiBootPolicies", "Name eq '"+bootPolicy.Name+"' and Organization.Moid eq '"+org.Moid+"'", &bootPolicies); err != nil {
+			err = fmt.Errorf("setIscsiBoot(): %s", err)
+			return
+		}
+		var bootPolicyMoid string
+		if len(bootPolicies) > 0 {
+			bootPolicyMoid = bootPolicies[0].Moid
+			err = client.Patch("/vnic/IscsiBootPolicies/"+bootPolicyMoid, bootPolicy, nil)
+		} else {
+			var created IscsiBootPolicy
+			err = client.Post("/vnic/IscsiBootPolicies", bootPolicy, &created)
+			bootPolicyMoid = created.Moid
+		}
+		if err != nil {
+			err = fmt.Errorf("setIscsiBoot(): failure for iSCSI interface %s: %s", initiator.Name, err)
+			return
+		}
+		if ethIf.IscsiBootPolicy == nil || ethIf.IscsiBootPolicy.Moid != bootPolicyMoid {
+			if err = client.Patch("/vnic/EthIfs/"+ethIf.Moid, map[string]interface{}{"IscsiBootPolicy": MoRef{ObjectType: "vnic.IscsiBootPolicy", Moid: bootPolicyMoid}}, nil); err != nil {
+				err = fmt.Errorf("setIscsiBoot(): failure for iSCSI interface %s: %s", initiator.Name, err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// deleteServerPolicies deletes per-server LAN connectivity, iSCSI boot and iSCSI target policies
+func deleteServerPolicies(client *Client, org *MoRef, nodeConfig *config.NodeConfig) (err error) {
+	for _, initiator := range nodeConfig.Network.IscsiInitiator {
+		var bootPolicies []IscsiBootPolicy
+		if err = client.Query("/vnic/IscsiBootPolicies", "Name eq '"+iscsiBootPolicyName(nodeConfig.Compute.HostName, initiator.Name)+"' and Organization.Moid eq '"+org.Moid+"'", &bootPolicies); err != nil {
+			return
+		}
+		for _, bootPolicy := range bootPolicies {
+			if err = client.Delete("/vnic/IscsiBootPolicies/" + bootPolicy.Moid); err != nil {
+				return
+			}
+		}
+		for n := 1; n <= 2; n++ {
+			var targetPolicies []IscsiStaticTargetPolicy
+			if err = client.Query("/vnic/IscsiStaticTargetPolicies", "Name eq '"+iscsiTargetPolicyName(nodeConfig.Compute.HostName, initiator.Name, n)+"' and Organization.Moid eq '"+org.Moid+"'", &targetPolicies); err != nil {
+				return
+			}
+			for _, targetPolicy := range targetPolicies {
+				if err = client.Delete("/vnic/IscsiStaticTargetPolicies/" + targetPolicy.Moid); err != nil {
+					return
+				}
+			}
+		}
+	}
+	var lanPolicies []LanConnectivityPolicy
+	if err = client.Query("/vnic/LanConnectivityPolicies", "Name eq '"+lanPolicyName(nodeConfig.Compute.HostName)+"' and Organization.Moid eq '"+org.Moid+"'", &lanPolicies); err != nil {
+		return
+	}
+	for _, lanPolicy := range lanPolicies {
+		if err = client.Delete("/vnic/LanConnectivityPolicies/" + lanPolicy.Moid); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// setPowerState sets server admin power state to "PowerOn" or "PowerOff" and waits for the power state
+func setPowerState(client *Client, profile *Profile, nodeConfig *config.NodeConfig, adminPowerState string) (err error) {
+	var settings []ServerSettings
+	var server *PhysicalSummary
+	state := "off"
+	if adminPowerState == "PowerOn" {
+		state = "on"
+	}
+	if profile.AssignedServer == nil {
+		err = fmt.Errorf("setPowerState(): profile \"%s\" has no assigned server", profile.Name)
+		return
+	}
+	if nodeConfig.Plan != nil {
+		planRecord(nodeConfig, "SetAdminPowerState", "set server profile %s power state \"%s\"", profile.Name, adminPowerState)
+		nodeConfig.Compute.Powerstate = powerState(state)
+		return
+	}
+	if err = client.Query("/compute/ServerSettings", "Server.Moid eq '"+profile.AssignedServer.Moid+"'", &settings); err != nil {
+		err = fmt.Errorf("setPowerState(): %s", err)
+		return
+	}
+	if len(settings) == 0 {
+		err = fmt.Errorf("setPowerState(): server settings not found for server \"%s\"", profile.AssignedServer.Moid)
+		return
+	}
+	if err = client.Patch("/compute/ServerSettings/"+settings[0].Moid, map[string]interface{}{"AdminPowerState": adminPowerState}, nil); err != nil {
+		err = fmt.Errorf("setPowerState(): %s", err)
+		return
+	}
+	giveupTime := time.Now().Add(time.Second * time.Duration(powerWaitMax))
+	for time.Now().Before(giveupTime) {
+		if server, err = getServer(client, profile.AssignedServer.Moid); err != nil {
+			err = fmt.Errorf("setPowerState(): %s", err)
+			return
+		}
+		if server.OperPowerState == state {
+			nodeConfig.Compute.Powerstate = powerState(state)
+			return
+		}
+		time.Sleep(stateWaitInterval)
+	}
+	err = fmt.Errorf("setPowerState(): timeout waiting for server \"%s\" power state \"%s\"", server.Dn, state)
+	return
+}
+
+// powerState translates Intersight server power state to compute power state
+func powerState(state string) string {
+	switch strings.ToLower(state) {
+	case "on":
+		return "up"
+	case "off":
+		return "down"
+	}
+	return strings.ToLower(state)
+}
+
+// login creates client and finds node organization
+func login(nodeConfig *config.NodeConfig) (client *Client, org *MoRef, err error) {
+	if client, err = NewClient(nodeConfig.Compute.IntersightCredentials, nodeConfig.Compute.IntersightTLS); err != nil {
+		return
+	}
+	org, err = getOrganization(client, nodeConfig.Compute.SpOrg)
+	return
+}
+
+// CreateServerPreflight does sanity check before profile creation
+func CreateServerPreflight(nodeConfig *config.NodeConfig) (err error) {
+	var client *Client
+	var org *MoRef
+	var servers []PhysicalSummary
+	if len(nodeConfig.Network.FcInitiator) > 0 {
+		err = fmt.Errorf("CreateServerPreflight: FC boot is not supported by Intersight compute")
+		return
+	}
+	if client, org, err = login(nodeConfig); err != nil {
+		err = fmt.Errorf("CreateServerPreflight: %s", err)
+		return
+	}
+	if _, err = getProfileTemplate(client, org, nodeConfig.Compute.SpTemplate); err != nil {
+		err = fmt.Errorf("CreateServerPreflight: %s", err)
+		return
+	}
+	if servers, err = GetAvailableServers(client, &nodeConfig.Compute.BladeSpec); err != nil {
+		err = fmt.Errorf("CreateServerPreflight: %s", err)
+		return
+	}
+	if len(servers) == 0 {
+		err = fmt.Errorf("CreateServerPreflight: GetAvailableServers(): no servers found per BladeSpec")
+	}
+	return
+}
+
+// CreateServer derives server profile from template, programs iSCSI boot and assigns server
+func CreateServer(nodeConfig *config.NodeConfig) (err error) {
+	var client *Client
+	var org *MoRef
+	var template *ProfileTemplate
+	var profile *Profile
+	if client, org, err = login(nodeConfig); err != nil {
+		err = fmt.Errorf("CreateServer: %s", err)
+		return
+	}
+	if template, err = getProfileTemplate(client, org, nodeConfig.Compute.SpTemplate); err != nil {
+		err = fmt.Errorf("CreateServer: %s", err)
+		return
+	}
+	if nodeConfig.Plan != nil {
+		return planCreateServer(client, template, nodeConfig)
+	}
+	cloner := MoCloner{
+		Sources: []MoRef{{ObjectType: "server.ProfileTemplate", Moid: template.Moid}},
+		Targets: []map[string]interface{}{{"ObjectType": "server.Profile", "Name": nodeConfig.Compute.HostName, "Organization": org}},
+	}
+	if err = client.Post("/bulk/MoCloners", cloner, nil); err != nil {
+		err = fmt.Errorf("CreateServer: MoCloner failure: %s", err)
+		return
+	}
+	if profile, err = getProfile(client, org, nodeConfig.Compute.HostName); err != nil {
+		err = fmt.Errorf("CreateServer: %s", err)
+		return
+	}
+	if profile == nil {
+		err = fmt.Errorf("CreateServer: server profile \"%s\" not found after derive from template \"%s\"", nodeConfig.Compute.HostName, template.Name)
+		return
+	}
+	nodeConfig.Compute.SpDn = profile.Moid
+	attributes := map[string]interface{}{"TemplateActions": []map[string]string{{"Type": "Detach"}}}
+	if len(nodeConfig.Compute.Description) > 0 || len(nodeConfig.Compute.Label) > 0 {
+		attributes["Description"] = nodeConfig.Compute.Description
+		attributes["Tags"] = []Tag{{Key: "label", Value: nodeConfig.Compute.Label}}
+	}
+	if err = client.Patch("/server/Profiles/"+profile.Moid, attributes, nil); err != nil {
+		err = fmt.Errorf("CreateServer: failed to detach profile from template: %s", err)
+		return
+	}
+	if err = setLanPolicy(client, org, profile, nodeConfig); err != nil {
+		err = fmt.Errorf("CreateServer: %s", err)
+		return
+	}
+	if err = setIscsiBoot(client, org, profile, nodeConfig); err != nil {
+		err = fmt.Errorf("CreateServer: %s", err)
+		return
+	}
+	err = AssignServer(client, profile, nodeConfig)
+	return
+}
+
+// SetServerBootTargets re-programs iSCSI boot targets (usually after storage failover) and re-deploys profile
+func SetServerBootTargets(nodeConfig *config.NodeConfig) (err error) {
+	var client *Client
+	var org *MoRef
+	var profile *Profile
+	if client, org, err = login(nodeConfig); err != nil {
+		err = fmt.Errorf("SetServerBootTargets: %s", err)
+		return
+	}
+	if nodeConfig.Plan != nil {
+		planServerBoot(nodeConfig)
+		return
+	}
+	if profile, err = getProfile(client, org, nodeConfig.Compute.HostName); err != nil || profile == nil {
+		err = fmt.Errorf("SetServerBootTargets: server profile \"%s\" not found: %v", nodeConfig.Compute.HostName, err)
+		return
+	}
+	if err = setIscsiBoot(client, org, profile, nodeConfig); err != nil {
+		err = fmt.Errorf("SetServerBootTargets: %s", err)
+		return
+	}
+	if profile.AssignedServer != nil {
+		if _, err = deployProfile(client, profile.Moid); err != nil {
+			err = fmt.Errorf("SetServerBootTargets: %s", err)
+		}
+	}
+	return
+}
+
+// DiscoverServer finds server profile by name and retrives it's attributes
+func DiscoverServer(nodeConfig *config.NodeConfig) (serverExists bool, err error) {
+	var client *Client
+	var org *MoRef
+	var profile *Profile
+	var server *PhysicalSummary
+	var ethIfs []EthIf
+	if client, org, err = login(nodeConfig); err != nil {
+		err = fmt.Errorf("DiscoverServer: %s", err)
+		return
+	}
+	if profile, err = getProfile(client, org, nodeConfig.Compute.HostName); err != nil {
+		err = fmt.Errorf("DiscoverServer: %s", err)
+		return
+	}
+	if profile == nil {
+		serverExists = false
+		return
+	}
+	serverExists = true
+	nodeConfig.Compute.SpDn = profile.Moid
+	nodeConfig.Compute.Description = profile.Description
+	for _, tag := range profile.Tags {
+		if tag.Key == "label" {
+			nodeConfig.Compute.Label = tag.Value
+		}
+	}
+	if profile.AssignedServer == nil {
+		if err = AssignServer(client, profile, nodeConfig); err != nil {
+			return
+		}
+	} else {
+		if server, err = getServer(client, profile.AssignedServer.Moid); err != nil {
+			err = fmt.Errorf("DiscoverServer: %s", err)
+			return
+		}
+		setInventory(server, profile, nodeConfig)
+	}
+	if ethIfs, err = setMacAddresses(client, profile, nodeConfig); err != nil {
+		err = fmt.Errorf("DiscoverServer: %s", err)
+		return
+	}
+	for i := range nodeConfig.Network.IscsiInitiator {
+		if i > 1 {
+			break
+		}
+		var bootPolicy IscsiBootPolicy
+		for _, ethIf := range ethIfs {
+			if ethIf.Name == nodeConfig.Network.IscsiInitiator[i].Name && ethIf.IscsiBootPolicy != nil {
+				if err = client.Get("/vnic/IscsiBootPolicies/"+ethIf.IscsiBootPolicy.Moid, &bootPolicy); err != nil {
+					err = fmt.Errorf("DiscoverServer: %s", err)
+					return
+				}
+			}
+		}
+		if bootPolicy.PrimaryTargetPolicy == nil {
+			err = fmt.Errorf("DiscoverServer: iSCSI targets are not configured for interface \"%s\" in profile \"%s\"", nodeConfig.Network.IscsiInitiator[i].Name, profile.Name)
+			return
+		}
+		nodeConfig.Network.IscsiInitiator[i].IscsiTarget = &config.IscsiTarget{}
+		for _, targetRef := range []*MoRef{bootPolicy.PrimaryTargetPolicy, bootPolicy.SecondaryTargetPolicy} {
+			if targetRef == nil {
+				continue
+			}
+			var targetPolicy IscsiStaticTargetPolicy
+			if err = client.Get("/vnic/IscsiStaticTargetPolicies/"+targetRef.Moid, &targetPolicy); err != nil {
+				err = fmt.Errorf("DiscoverServer: %s", err)
+				return
+			}
+			nodeConfig.Network.IscsiInitiator[i].IscsiTarget.NodeName = targetPolicy.TargetName
+			nodeConfig.Network.IscsiInitiator[i].IscsiTarget.Interfaces = append(nodeConfig.Network.IscsiInitiator[i].IscsiTarget.Interfaces, targetPolicy.IpAddress)
+		}
+		nodeConfig.Network.IscsiInitiator[i].Ip = bootPolicy.InitiatorStaticIpV4Address
+		nodeConfig.Network.IscsiInitiator[i].Gateway = bootPolicy.InitiatorStaticIpV4Config.DefaultGateway
+		nodeConfig.Network.IscsiInitiator[i].DnsServer1 = bootPolicy.InitiatorStaticIpV4Config.PrimaryDns
+		nodeConfig.Network.IscsiInitiator[i].DnsServer2 = bootPolicy.InitiatorStaticIpV4Config.SecondaryDns
+	}
+	return
+}
+
+// UpdateServerPreflight check server availability before re-assigning
+func UpdateServerPreflight(nodeConfig *config.NodeConfig) (err error) {
+	var client *Client
+	var servers []PhysicalSummary
+	if client, err = NewClient(nodeConfig.Compute.IntersightCredentials, nodeConfig.Compute.IntersightTLS); err != nil {
+		err = fmt.Errorf("UpdateServerPreflight: %s", err)
+		return
+	}
+	if servers, err = GetAvailableServers(client, &nodeConfig.Compute.BladeSpec); err != nil {
+		err = fmt.Errorf("UpdateServerPreflight: %s", err)
+		return
+	}
+	if len(servers) == 0 {
+		err = fmt.Errorf("UpdateServerPreflight: GetAvailableServers(): no servers found per BladeSpec")
+	}
+	return
+}
+
+// UpdateServer re-assigns physical server
+func UpdateServer(nodeConfig *config.NodeConfig) (err error) {
+	var client *Client
+	var org *MoRef
+	var profile *Profile
+	if client, org, err = login(nodeConfig); err != nil {
+		err = fmt.Errorf("UpdateServer: %s", err)
+		return
+	}
+	if profile, err = getProfile(client, org, nodeConfig.Compute.HostName); err != nil {
+		err = fmt.Errorf("UpdateServer: %s", err)
+		return
+	}
+	if profile == nil {
+		err = fmt.Errorf("UpdateServer: server profile does not exist")
+		return
+	}
+	if profile.AssignedServer != nil {
+		var server *PhysicalSummary
+		if server, err = getServer(client, profile.AssignedServer.Moid); err != nil {
+			err = fmt.Errorf("UpdateServer: %s", err)
+			return
+		}
+		if powerState(server.OperPowerState) == "up" {
+			err = fmt.Errorf("UpdateServer: power state is \"up\", cannot re-assign server")
+			return
+		}
+		if nodeConfig.Plan != nil {
+			planRecord(nodeConfig, "Unassign", "unassign server %s from server profile %s", server.Dn, profile.Name)
+		} else {
+			if err = client.Patch("/server/Profiles/"+profile.Moid, map[string]interface{}{"Action": "Unassign"}, nil); err != nil {
+				err = fmt.Errorf("UpdateServer: %s", err)
+				return
+			}
+			if profile, err = waitForConfigState(client, profile.Moid, "Not-assigned"); err != nil {
+				err = fmt.Errorf("UpdateServer: %s", err)
+				return
+			}
+		}
+	}
+	err = AssignServer(client, profile, nodeConfig)
+	return
+}
+
+// UpdateServerAttributes updates server profile description and label
+func UpdateServerAttributes(nodeConfig *config.NodeConfig) (err error) {
+	var client *Client
+	var org *MoRef
+	var profile *Profile
+	if client, org, err = login(nodeConfig); err != nil {
+		err = fmt.Errorf("UpdateServerAttributes: %s", err)
+		return
+	}
+	if profile, err = getProfile(client, org, nodeConfig.Compute.HostName); err != nil {
+		err = fmt.Errorf("UpdateServerAttributes: %s", err)
+		return
+	}
+	if profile == nil {
+		err = fmt.Errorf("UpdateServerAttributes: server profile does not exist")
+		return
+	}
+	var label string
+	for _, tag := range profile.Tags {
+		if tag.Key == "label" {
+			label = tag.Value
+		}
+	}
+	if profile.Description != nodeConfig.Compute.Description || label != nodeConfig.Compute.Label {
+		if nodeConfig.Plan != nil {
+			planRecord(nodeConfig, "SetAttributes", "set server profile %s description \"%s\", label \"%s\"", profile.Name, nodeConfig.Compute.Description, nodeConfig.Compute.Label)
+			return
+		}
+		var tags []Tag
+		for _, tag := range profile.Tags {
+			if tag.Key != "label" {
+				tags = append(tags, tag)
+			}
+		}
+		tags = append(tags, Tag{Key: "label", Value: nodeConfig.Compute.Label})
+		if err = client.Patch("/server/Profiles/"+profile.Moid, map[string]interface{}{"Description": nodeConfig.Compute.Description, "Tags": tags}, nil); err != nil {
+			err = fmt.Errorf("UpdateServerAttributes: %s", err)
+		}
+	}
+	return
+}
+
+// DeleteServer unassigns server and deletes server profile with per-server policies
+func DeleteServer(nodeConfig *config.NodeConfig) (err error) {
+	var client *Client
+	var org *MoRef
+	var profile *Profile
+	if client, org, err = login(nodeConfig); err != nil {
+		err = fmt.Errorf("DeleteServer: %s", err)
+		return
+	}
+	if profile, err = getProfile(client, org, nodeConfig.Compute.HostName); err != nil {
+		err = fmt.Errorf("DeleteServer: %s", err)
+		return
+	}
+	if profile == nil {
+		return
+	}
+	if profile.AssignedServer != nil {
+		var server *PhysicalSummary
+		if server, err = getServer(client, profile.AssignedServer.Moid); err != nil {
+			err = fmt.Errorf("DeleteServer: %s", err)
+			return
+		}
+		if powerState(server.OperPowerState) != "down" {
+			err = fmt.Errorf("DeleteServer: server \"%s\" has power state \"%s\"", server.Dn, powerState(server.OperPowerState))
+			return
+		}
+	}
+	if nodeConfig.Plan != nil {
+		planDeleteServer(nodeConfig, profile)
+		return
+	}
+	if profile.AssignedServer != nil {
+		if err = client.Patch("/server/Profiles/"+profile.Moid, map[string]interface{}{"Action": "Unassign"}, nil); err != nil {
+			err = fmt.Errorf("DeleteServer: %s", err)
+			return
+		}
+		if _, err = waitForConfigState(client, profile.Moid, "Not-assigned"); err != nil {
+			err = fmt.Errorf("DeleteServer: %s", err)
+			return
+		}
+	}
+	if err = client.Delete("/server/Profiles/" + profile.Moid); err != nil {
+		err = fmt.Errorf("DeleteServer: %s", err)
+		return
+	}
+	if err = deleteServerPolicies(client, org, nodeConfig); err != nil {
+		err = fmt.Errorf("DeleteServer: %s", err)
+	}
+	return
+}
+
+// StartServer sets server power state to "up"
+func StartServer(nodeConfig *config.NodeConfig) (err error) {
+	var client *Client
+	var org *MoRef
+	var profile *Profile
+	if client, org, err = login(nodeConfig); err != nil {
+		err = fmt.Errorf("StartServer: %s", err)
+		return
+	}
+	if nodeConfig.Plan != nil {
+		planRecord(nodeConfig, "SetAdminPowerState", "set server profile %s power state \"PowerOn\"", nodeConfig.Compute.HostName)
+		nodeConfig.Compute.Powerstate = "up"
+		return
+	}
+	if profile, err = getProfile(client, org, nodeConfig.Compute.HostName); err != nil || profile == nil {
+		err = fmt.Errorf("StartServer: server profile \"%s\" not found: %v", nodeConfig.Compute.HostName, err)
+		return
+	}
+	if err = setPowerState(client, profile, nodeConfig, "PowerOn"); err != nil {
+		err = fmt.Errorf("StartServer: %s", err)
+	}
+	return
+}
+
+// StopServer sets server power state to "down"
+func StopServer(nodeConfig *config.NodeConfig) (err error) {
+	var client *Client
+	var org *MoRef
+	var profile *Profile
+	if client, org, err = login(nodeConfig); err != nil {
+		err = fmt.Errorf("StopServer: %s", err)
+		return
+	}
+	if nodeConfig.Plan != nil {
+		planRecord(nodeConfig, "SetAdminPowerState", "set server profile %s power state \"PowerOff\"", nodeConfig.Compute.HostName)
+		nodeConfig.Compute.Powerstate = "down"
+		return
+	}
+	if profile, err = getProfile(client, org, nodeConfig.Compute.HostName); err != nil || profile == nil {
+		err = fmt.Errorf("StopServer: server profile \"%s\" not found: %v", nodeConfig.Compute.HostName, err)
+		return
+	}
+	if err = setPowerState(client, profile, nodeConfig, "PowerOff"); err != nil {
+		err = fmt.Errorf("StopServer: %s", err)
+	}
+	return
+}
+
+// getAssignedServer finds server assigned to node profile, server is nil if profile does not exist or not assigned
+func getAssignedServer(nodeConfig *config.NodeConfig) (server *PhysicalSummary, err error) {
+	var client *Client
+	var org *MoRef
+	var profile *Profile
+	if client, org, err = login(nodeConfig); err != nil {
+		return
+	}
+	if profile, err = getProfile(client, org, nodeConfig.Compute.HostName); err != nil {
+		return
+	}
+	if profile != nil && profile.AssignedServer != nil {
+		server, err = getServer(client, profile.AssignedServer.Moid)
+	}
+	return
+}
+
+// GetServerPowerState retrievs server powerstate
+func GetServerPowerState(nodeConfig *config.NodeConfig) (state string, err error) {
+	var server *PhysicalSummary
+	if server, err = getAssignedServer(nodeConfig); err != nil {
+		err = fmt.Errorf("GetServerPowerState: %s", err)
+		return
+	}
+	if server != nil {
+		state = powerState(server.OperPowerState)
+	}
+	return
+}
+
+// GetServerOperationalState retrievs server operational state
+func GetServerOperationalState(nodeConfig *config.NodeConfig) (operationalState string, err error) {
+	var server *PhysicalSummary
+	if server, err = getAssignedServer(nodeConfig); err != nil {
+		err = fmt.Errorf("GetServerOperationalState: %s", err)
+		return
+	}
+	if server == nil {
+		err = fmt.Errorf("GetServerOperationalState: server profile \"%s\" not found or not assigned", nodeConfig.Compute.HostName)
+		return
+	}
+	if powerState(server.OperPowerState) == "down" {
+		operationalState = "power-off"
+	} else {
+		operationalState = strings.ToLower(server.OperState)
+		if operationalState == "" {
+			operationalState = "ok"
+		}
+	}
+	return
+}
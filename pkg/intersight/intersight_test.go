@@ -0,0 +1,572 @@
+package intersight_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/igor-feoktistov/go-ucsm-sdk/util"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/intersight"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/intersight/fake"
+)
+
+const (
+	testKeyId           = "5f8a6b3e7564612d33a5b0e1/5f8a6b3e7564612d33a5b0e5/5f8a6b4a7564612d33a5b1a2"
+	testOrg             = "flexbot"
+	testTemplate        = "k8s-node"
+	testIscsiTargetName = "iqn.1992-08.com.netapp:sn.0123456789"
+)
+
+// testKeyPEM encodes secret key as PKCS8 PEM
+func testKeyPEM(t *testing.T, key crypto.Signer) string {
+	t.Helper()
+	b, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: b}))
+}
+
+// useFakeServer starts Intersight API stand-in with organization, profile template and two blade servers,
+// requests are verified by HTTP signature of generated ECDSA key, state polling is immediate
+func useFakeServer(t *testing.T) (server *fake.Server, credentials config.Credentials) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server = fake.NewServer()
+	server.KeyId = testKeyId
+	server.PublicKey = key.Public()
+	orgMoid := server.AddOrganization(testOrg)
+	server.AddOrganization("default")
+	server.AddProfileTemplate(orgMoid, testTemplate, []string{"eth0", "iscsi0", "iscsi1"})
+	server.AddServer(intersight.PhysicalSummary{Dn: "sys/chassis-1/blade-1", Name: "blade-1", Model: "UCSB-B200-M5", Serial: "FCH2201J0AB", NumCpus: 2, NumCpuCores: 36, NumThreads: 72, TotalMemory: 393216, ChassisId: "1", SlotId: 1, OperState: "Ok"})
+	server.AddServer(intersight.PhysicalSummary{Dn: "sys/chassis-1/blade-2", Name: "blade-2", Model: "UCSB-B200-M4", Serial: "FCH2201J0AC", NumCpus: 2, NumCpuCores: 24, NumThreads: 48, TotalMemory: 262144, ChassisId: "1", SlotId: 2, OperState: "Ok"})
+	waitInterval := intersight.SetStateWaitInterval(0)
+	t.Cleanup(func() {
+		server.Close()
+		intersight.SetStateWaitInterval(waitInterval)
+	})
+	credentials = config.Credentials{Host: server.URL, User: testKeyId, Password: testKeyPEM(t, key)}
+	return
+}
+
+// testNodeConfig makes node configuration with node vNIC and two iSCSI vNICs
+func testNodeConfig(credentials config.Credentials, hostName string) (nodeConfig *config.NodeConfig) {
+	nodeConfig = &config.NodeConfig{}
+	nodeConfig.Compute.Provider = "intersight"
+	nodeConfig.Compute.IntersightCredentials = credentials
+	nodeConfig.Compute.HostName = hostName
+	nodeConfig.Compute.SpOrg = testOrg
+	nodeConfig.Compute.SpTemplate = testTemplate
+	nodeConfig.Compute.BladeSpec.Model = "UCSB-B200-M5"
+	nodeConfig.Compute.BladeSpec.TotalMemory = "262144-524288"
+	nodeConfig.Network.Node = []config.NetworkInterface{{Name: "eth0"}}
+	for i, name := range []string{"iscsi0", "iscsi1"} {
+		nodeConfig.Network.IscsiInitiator = append(nodeConfig.Network.IscsiInitiator, config.IscsiInitiator{
+			NetworkInterface: config.NetworkInterface{
+				Name:       name,
+				Ip:         []string{"192.168.10.101", "192.168.20.101"}[i],
+				Subnet:     []string{"192.168.10.0/24", "192.168.20.0/23"}[i],
+				Gateway:    []string{"192.168.10.1", "192.168.20.1"}[i],
+				DnsServer1: "10.0.0.53",
+			},
+			InitiatorName: "iqn.2005-02.com.open-iscsi:" + hostName,
+			IscsiTarget: &config.IscsiTarget{
+				NodeName:   testIscsiTargetName,
+				Interfaces: []string{[]string{"192.168.10.11", "192.168.20.11"}[i], []string{"192.168.10.12", "192.168.20.12"}[i]},
+			},
+		})
+	}
+	return
+}
+
+// testSignedRequest makes request signed as signedBody is sent, body is sent instead
+func testSignedRequest(t *testing.T, key *rsa.PrivateKey, method string, uri string, signedBody []byte, body []byte) (req *http.Request) {
+	t.Helper()
+	var err error
+	if req, err = http.NewRequest(method, uri, bytes.NewReader(body)); err != nil {
+		t.Fatal(err)
+	}
+	date := time.Now().UTC().Format(http.TimeFormat)
+	bodyDigest := sha256.Sum256(signedBody)
+	digest := "SHA-256=" + base64.StdEncoding.EncodeToString(bodyDigest[:])
+	hashed := sha256.Sum256([]byte(intersight.SigningString(method, req.URL.RequestURI(), date, digest, req.URL.Host)))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Date", date)
+	req.Header.Set("Digest", digest)
+	req.Header.Set("Authorization", "Signature keyId=\""+testKeyId+"\",algorithm=\"rsa-sha256\",headers=\"(request-target) date digest host\",signature=\""+base64.StdEncoding.EncodeToString(signature)+"\"")
+	return
+}
+
+// testClient creates client to inspect managed objects of the stand-in
+func testClient(t *testing.T, credentials config.Credentials) (client *intersight.Client) {
+	t.Helper()
+	var err error
+	if client, err = intersight.NewClient(credentials, config.TLSOptions{}); err != nil {
+		t.Fatalf("NewClient() failure: %s", err)
+	}
+	return
+}
+
+// testProfile gets server profile by name
+func testProfile(t *testing.T, client *intersight.Client, name string) (profile *intersight.Profile) {
+	t.Helper()
+	var profiles []intersight.Profile
+	if err := client.Query("/server/Profiles", "Name eq '"+name+"'", &profiles); err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) > 0 {
+		profile = &profiles[0]
+	}
+	return
+}
+
+func TestSigning(t *testing.T) {
+	server, credentials := useFakeServer(t)
+	nodeConfig := testNodeConfig(credentials, "node1")
+	if err := intersight.CreateServerPreflight(nodeConfig); err != nil {
+		t.Fatalf("CreateServerPreflight() failure with ECDSA key: %s", err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.PublicKey = rsaKey.Public()
+	if err = intersight.CreateServerPreflight(nodeConfig); err == nil || !strings.Contains(err.Error(), "HTTP status 401: InvalidRequest: signature verification failure") {
+		t.Fatalf("expected signature verification failure, got %v", err)
+	}
+	nodeConfig.Compute.IntersightCredentials.Password = testKeyPEM(t, rsaKey)
+	if err = intersight.CreateServerPreflight(nodeConfig); err != nil {
+		t.Fatalf("CreateServerPreflight() failure with RSA key: %s", err)
+	}
+	// PKCS1 encoded RSA key is accepted as well
+	nodeConfig.Compute.IntersightCredentials.Password = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}))
+	if err = intersight.CreateServerPreflight(nodeConfig); err != nil {
+		t.Fatalf("CreateServerPreflight() failure with PKCS1 RSA key: %s", err)
+	}
+
+	nodeConfig.Compute.IntersightCredentials.User = "unknown"
+	if err = intersight.CreateServerPreflight(nodeConfig); err == nil || !strings.Contains(err.Error(), "unknown API key \"unknown\"") {
+		t.Fatalf("expected unknown API key failure, got %v", err)
+	}
+	nodeConfig.Compute.IntersightCredentials.Password = "not a key"
+	if err = intersight.CreateServerPreflight(nodeConfig); err == nil || !strings.Contains(err.Error(), "NewClient(): failed to decode PEM secret key for API key unknown") {
+		t.Fatalf("expected PEM decode failure, got %v", err)
+	}
+
+	// request body is covered by signature digest
+	req := testSignedRequest(t, rsaKey, "GET", server.URL+"/api/v1/organization/Organizations", nil, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("signed request is rejected, HTTP status %d", resp.StatusCode)
+	}
+	req = testSignedRequest(t, rsaKey, "PATCH", server.URL+"/api/v1/server/Profiles/0", []byte(`{"Action":"Deploy"}`), []byte(`{"Action":"Unassign"}`))
+	if resp, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("request with altered body is accepted, HTTP status %d", resp.StatusCode)
+	}
+	if calls := server.Calls(); len(calls) != 0 {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+}
+
+func TestTLSVerification(t *testing.T) {
+	server, credentials := useFakeServer(t)
+	appliance := httptest.NewTLSServer(server.Config.Handler)
+	defer appliance.Close()
+	nodeConfig := testNodeConfig(credentials, "node1")
+	nodeConfig.Compute.IntersightCredentials.Host = appliance.URL
+	if err := intersight.CreateServerPreflight(nodeConfig); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("expected certificate verification failure, got %v", err)
+	}
+	nodeConfig.Compute.IntersightTLS.CaCert = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: appliance.Certificate().Raw}))
+	if err := intersight.CreateServerPreflight(nodeConfig); err != nil {
+		t.Fatalf("CreateServerPreflight() failure with appliance CA certificate: %s", err)
+	}
+	nodeConfig.Compute.IntersightTLS.CaCert = "not a certificate"
+	if err := intersight.CreateServerPreflight(nodeConfig); err == nil || !strings.Contains(err.Error(), "NewClient(): failed to parse PEM CA certificate") {
+		t.Fatalf("expected CA certificate parse failure, got %v", err)
+	}
+	nodeConfig.Compute.IntersightTLS = config.TLSOptions{Insecure: true}
+	if err := intersight.CreateServerPreflight(nodeConfig); err != nil {
+		t.Fatalf("CreateServerPreflight() failure with insecure endpoint: %s", err)
+	}
+}
+
+func TestCreateServer(t *testing.T) {
+	server, credentials := useFakeServer(t)
+	nodeConfig := testNodeConfig(credentials, "node1")
+	nodeConfig.Compute.Description = "k8s worker"
+	nodeConfig.Compute.Label = "pool-a"
+	nodeConfig.Network.IscsiChap = config.IscsiChap{User: "chap", Password: "chapsecret", TargetUser: "target", TargetPassword: "targetsecret"}
+	if err := intersight.CreateServerPreflight(nodeConfig); err != nil {
+		t.Fatalf("CreateServerPreflight() failure: %s", err)
+	}
+	if err := intersight.CreateServer(nodeConfig); err != nil {
+		t.Fatalf("CreateServer() failure: %s", err)
+	}
+	client := testClient(t, credentials)
+	profile := testProfile(t, client, "node1")
+	if profile == nil || profile.SrcTemplate != nil || profile.ConfigContext.ConfigState != "Associated" || profile.AssignedServer == nil {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+	if profile.Description != "k8s worker" || len(profile.Tags) != 1 || profile.Tags[0] != (intersight.Tag{Key: "label", Value: "pool-a"}) {
+		t.Fatalf("unexpected profile attributes %+v", profile)
+	}
+	assigned := server.Summary(profile.AssignedServer.Moid)
+	if assigned.Dn != "sys/chassis-1/blade-1" || assigned.ServiceProfile != "node1" {
+		t.Fatalf("server is not assigned per BladeSpec: %+v", assigned)
+	}
+	if nodeConfig.Compute.SpDn != profile.Moid || nodeConfig.Compute.BladeAssigned.Dn != assigned.Dn || nodeConfig.Compute.BladeAssigned.NumOfCores != "36" || nodeConfig.Compute.Powerstate != "down" {
+		t.Fatalf("unexpected compute %+v", nodeConfig.Compute)
+	}
+
+	// profile gets per-server copy of template LAN connectivity policy with static IQN
+	var lanPolicies []intersight.LanConnectivityPolicy
+	if err := client.Query("/vnic/LanConnectivityPolicies", "Moid eq '"+profile.PolicyBucket[0].Moid+"'", &lanPolicies); err != nil || len(lanPolicies) != 1 {
+		t.Fatalf("profile LAN connectivity policy not found: %v", err)
+	}
+	if lanPolicies[0].Name != "node1-lan" || lanPolicies[0].IqnAllocationType != "Static" || lanPolicies[0].StaticIqnName != "iqn.2005-02.com.open-iscsi:node1" {
+		t.Fatalf("unexpected LAN connectivity policy %+v", lanPolicies[0])
+	}
+	var ethIfs []intersight.EthIf
+	err := client.Query("/vnic/EthIfs", "LanConnectivityPolicy.Moid eq '"+profile.PolicyBucket[0].Moid+"'", &ethIfs)
+	if err != nil || len(ethIfs) != 3 {
+		t.Fatalf("unexpected vNICs %+v, error %v", ethIfs, err)
+	}
+	macs := map[string]string{}
+	for _, ethIf := range ethIfs {
+		macs[ethIf.Name] = ethIf.MacAddress
+	}
+	if nodeConfig.Network.Node[0].Macaddr == "" || nodeConfig.Network.Node[0].Macaddr != macs["eth0"] || nodeConfig.Network.IscsiInitiator[1].Macaddr != macs["iscsi1"] {
+		t.Fatalf("unexpected MAC addresses %+v, vNICs %v", nodeConfig.Network, macs)
+	}
+	for i, initiator := range nodeConfig.Network.IscsiInitiator {
+		var bootPolicy intersight.IscsiBootPolicy
+		for _, ethIf := range ethIfs {
+			if ethIf.Name == initiator.Name && ethIf.IscsiBootPolicy != nil {
+				if err = client.Get("/vnic/IscsiBootPolicies/"+ethIf.IscsiBootPolicy.Moid, &bootPolicy); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if bootPolicy.Name != "node1-"+initiator.Name || bootPolicy.InitiatorStaticIpV4Address != initiator.Ip || bootPolicy.InitiatorStaticIpV4Config.DefaultGateway != initiator.Gateway {
+			t.Fatalf("unexpected iSCSI boot policy %+v", bootPolicy)
+		}
+		if netmask := []string{"255.255.255.0", "255.255.254.0"}[i]; bootPolicy.InitiatorStaticIpV4Config.Netmask != netmask {
+			t.Errorf("expected netmask %s, got %s", netmask, bootPolicy.InitiatorStaticIpV4Config.Netmask)
+		}
+		if bootPolicy.AuthenticationType != "mutual_chap" || bootPolicy.Chap == nil || bootPolicy.Chap.UserId != "chap" || bootPolicy.MutualChap == nil || bootPolicy.MutualChap.UserId != "target" {
+			t.Errorf("unexpected iSCSI authentication %+v", bootPolicy)
+		}
+		for j, targetRef := range []*intersight.MoRef{bootPolicy.PrimaryTargetPolicy, bootPolicy.SecondaryTargetPolicy} {
+			var targetPolicy intersight.IscsiStaticTargetPolicy
+			if targetRef == nil {
+				t.Fatalf("target policy %d is not set in %+v", j+1, bootPolicy)
+			}
+			if err = client.Get("/vnic/IscsiStaticTargetPolicies/"+targetRef.Moid, &targetPolicy); err != nil {
+				t.Fatal(err)
+			}
+			if targetPolicy.TargetName != testIscsiTargetName || targetPolicy.IpAddress != initiator.IscsiTarget.Interfaces[j] || targetPolicy.Port != 3260 || !targetPolicy.Lun.Bootable {
+				t.Errorf("unexpected iSCSI target policy %+v", targetPolicy)
+			}
+		}
+	}
+	// template LAN connectivity policy is not changed
+	if n := server.Count("vnic/LanConnectivityPolicies"); n != 2 {
+		t.Fatalf("expected 2 LAN connectivity policies, got %d", n)
+	}
+	var templateEthIfs []intersight.EthIf
+	if err = client.Query("/vnic/EthIfs", "Name eq 'iscsi0'", &templateEthIfs); err != nil || len(templateEthIfs) != 2 || templateEthIfs[0].IscsiBootPolicy != nil || templateEthIfs[0].MacAddress != "" {
+		t.Fatalf("template vNIC is changed: %+v, error %v", templateEthIfs, err)
+	}
+
+	if err = intersight.CreateServer(nodeConfig); err == nil || !strings.Contains(err.Error(), "MoCloner failure: POST /bulk/MoCloners: HTTP status 409: InvalidRequest: profile node1 already exists") {
+		t.Fatalf("expected existing profile failure, got %v", err)
+	}
+}
+
+func TestCreateServerPreflight(t *testing.T) {
+	_, credentials := useFakeServer(t)
+	tests := []struct {
+		name   string
+		update func(nodeConfig *config.NodeConfig)
+		err    string
+	}{
+		{name: "FC boot", update: func(nodeConfig *config.NodeConfig) {
+			nodeConfig.Network.FcInitiator = []config.FcInitiator{{Name: "fc0"}}
+		}, err: "FC boot is not supported by Intersight compute"},
+		{name: "organization", update: func(nodeConfig *config.NodeConfig) { nodeConfig.Compute.SpOrg = "other" }, err: "getOrganization(): organization \"other\" not found"},
+		{name: "default organization", update: func(nodeConfig *config.NodeConfig) { nodeConfig.Compute.SpOrg = "" }, err: "getProfileTemplate(): server profile template \"" + testTemplate + "\" not found"},
+		{name: "template", update: func(nodeConfig *config.NodeConfig) { nodeConfig.Compute.SpTemplate = "other" }, err: "getProfileTemplate(): server profile template \"other\" not found"},
+		{name: "blade spec", update: func(nodeConfig *config.NodeConfig) { nodeConfig.Compute.BladeSpec.NumOfCores = "40-64" }, err: "no servers found per BladeSpec"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nodeConfig := testNodeConfig(credentials, "node1")
+			test.update(nodeConfig)
+			if err := intersight.CreateServerPreflight(nodeConfig); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+	// servers of Dn regexp and threads range
+	nodeConfig := testNodeConfig(credentials, "node1")
+	nodeConfig.Compute.BladeSpec = util.BladeSpec{Dn: "sys/chassis-1/blade-[0-9]+", NumOfThreads: "40-50"}
+	servers, err := intersight.GetAvailableServers(testClient(t, credentials), &nodeConfig.Compute.BladeSpec)
+	if err != nil || len(servers) != 1 || servers[0].Dn != "sys/chassis-1/blade-2" {
+		t.Fatalf("unexpected available servers %+v, error %v", servers, err)
+	}
+}
+
+func TestServerAssignment(t *testing.T) {
+	server, credentials := useFakeServer(t)
+	node1 := testNodeConfig(credentials, "node1")
+	node1.Compute.BladeSpec.Model = "UCSB-B200-M[45]"
+	node1.Compute.BladeSpec.TotalMemory = ""
+	if err := intersight.CreateServer(node1); err != nil {
+		t.Fatalf("CreateServer() failure: %s", err)
+	}
+	// the other server is the only one available
+	node2 := testNodeConfig(credentials, "node2")
+	node2.Compute.BladeSpec = node1.Compute.BladeSpec
+	node2.Compute.BladeSpec.Dn = ""
+	if err := intersight.CreateServer(node2); err != nil {
+		t.Fatalf("CreateServer() failure: %s", err)
+	}
+	if node1.Compute.BladeAssigned.Dn == node2.Compute.BladeAssigned.Dn {
+		t.Fatalf("server %s is assigned twice", node1.Compute.BladeAssigned.Dn)
+	}
+	node3 := testNodeConfig(credentials, "node3")
+	node3.Compute.BladeSpec = node2.Compute.BladeSpec
+	if err := intersight.CreateServerPreflight(node3); err == nil || !strings.Contains(err.Error(), "no servers found per BladeSpec") {
+		t.Fatalf("expected no available servers failure, got %v", err)
+	}
+
+	// re-assigning is rejected while server is powered on
+	if err := intersight.StartServer(node1); err != nil {
+		t.Fatalf("StartServer() failure: %s", err)
+	}
+	if err := intersight.UpdateServer(node1); err == nil || !strings.Contains(err.Error(), "power state is \"up\", cannot re-assign server") {
+		t.Fatalf("expected powered on server failure, got %v", err)
+	}
+	if err := intersight.StopServer(node1); err != nil {
+		t.Fatalf("StopServer() failure: %s", err)
+	}
+	if err := intersight.DeleteServer(node2); err != nil {
+		t.Fatalf("DeleteServer() failure: %s", err)
+	}
+	previous := node1.Compute.BladeAssigned.Dn
+	node1.Compute.BladeSpec.Dn = node2.Compute.BladeAssigned.Dn
+	if err := intersight.UpdateServerPreflight(node1); err != nil {
+		t.Fatalf("UpdateServerPreflight() failure: %s", err)
+	}
+	if err := intersight.UpdateServer(node1); err != nil {
+		t.Fatalf("UpdateServer() failure: %s", err)
+	}
+	if node1.Compute.BladeAssigned.Dn == previous {
+		t.Fatalf("server %s is not re-assigned", previous)
+	}
+	profile := testProfile(t, testClient(t, credentials), "node1")
+	if summary := server.Summary(profile.AssignedServer.Moid); profile.ConfigContext.ConfigState != "Associated" || summary.ServiceProfile != "node1" || summary.Dn != node1.Compute.BladeAssigned.Dn {
+		t.Fatalf("re-assigned profile is not deployed: %+v", profile)
+	}
+}
+
+func TestDiscoverServer(t *testing.T) {
+	_, credentials := useFakeServer(t)
+	if err := intersight.CreateServer(testNodeConfig(credentials, "node1")); err != nil {
+		t.Fatalf("CreateServer() failure: %s", err)
+	}
+	if err := intersight.UpdateServerAttributes(testNodeConfig(credentials, "node1")); err != nil {
+		t.Fatalf("UpdateServerAttributes() failure: %s", err)
+	}
+	nodeConfig := testNodeConfig(credentials, "node1")
+	nodeConfig.Compute.Label = "pool-b"
+	if err := intersight.UpdateServerAttributes(nodeConfig); err != nil {
+		t.Fatalf("UpdateServerAttributes() failure: %s", err)
+	}
+	nodeConfig = testNodeConfig(credentials, "node1")
+	for i := range nodeConfig.Network.IscsiInitiator {
+		nodeConfig.Network.IscsiInitiator[i].IscsiTarget = nil
+		nodeConfig.Network.IscsiInitiator[i].Ip = ""
+		nodeConfig.Network.IscsiInitiator[i].Gateway = ""
+	}
+	serverExists, err := intersight.DiscoverServer(nodeConfig)
+	if err != nil || !serverExists {
+		t.Fatalf("DiscoverServer() failure: %v, exists %v", err, serverExists)
+	}
+	initiator := nodeConfig.Network.IscsiInitiator[1]
+	if initiator.IscsiTarget == nil || initiator.IscsiTarget.NodeName != testIscsiTargetName || strings.Join(initiator.IscsiTarget.Interfaces, ",") != "192.168.20.11,192.168.20.12" {
+		t.Fatalf("unexpected iSCSI target %+v", initiator.IscsiTarget)
+	}
+	if initiator.Ip != "192.168.20.101" || initiator.Gateway != "192.168.20.1" || initiator.DnsServer1 != "10.0.0.53" || initiator.Macaddr == "" {
+		t.Fatalf("unexpected iSCSI initiator %+v", initiator)
+	}
+	if nodeConfig.Compute.Label != "pool-b" || nodeConfig.Compute.BladeAssigned.Dn != "sys/chassis-1/blade-1" {
+		t.Fatalf("unexpected compute %+v", nodeConfig.Compute)
+	}
+	if serverExists, err = intersight.DiscoverServer(testNodeConfig(credentials, "node2")); err != nil || serverExists {
+		t.Fatalf("missing profile is discovered: %v", err)
+	}
+}
+
+func TestSetServerBootTargets(t *testing.T) {
+	server, credentials := useFakeServer(t)
+	nodeConfig := testNodeConfig(credentials, "node1")
+	if err := intersight.CreateServer(nodeConfig); err != nil {
+		t.Fatalf("CreateServer() failure: %s", err)
+	}
+	policies := server.Count("vnic/IscsiStaticTargetPolicies")
+	nodeConfig.Network.IscsiInitiator[0].IscsiTarget.Interfaces = []string{"192.168.10.13", "192.168.10.14"}
+	if err := intersight.SetServerBootTargets(nodeConfig); err != nil {
+		t.Fatalf("SetServerBootTargets() failure: %s", err)
+	}
+	if n := server.Count("vnic/IscsiStaticTargetPolicies"); n != policies {
+		t.Fatalf("target policies are re-created, %d policies instead of %d", n, policies)
+	}
+	discovered := testNodeConfig(credentials, "node1")
+	if _, err := intersight.DiscoverServer(discovered); err != nil {
+		t.Fatalf("DiscoverServer() failure: %s", err)
+	}
+	if targets := strings.Join(discovered.Network.IscsiInitiator[0].IscsiTarget.Interfaces, ","); targets != "192.168.10.13,192.168.10.14" {
+		t.Fatalf("unexpected iSCSI targets %s", targets)
+	}
+	// profile is re-deployed with new targets
+	calls := server.Calls()
+	if calls[len(calls)-1] != "PATCH /server/Profiles/"+nodeConfig.Compute.SpDn {
+		t.Fatalf("profile is not re-deployed, calls %v", calls)
+	}
+}
+
+func TestPowerState(t *testing.T) {
+	server, credentials := useFakeServer(t)
+	nodeConfig := testNodeConfig(credentials, "node1")
+	if _, err := intersight.GetServerOperationalState(nodeConfig); err == nil || !strings.Contains(err.Error(), "server profile \"node1\" not found or not assigned") {
+		t.Fatalf("expected missing profile failure, got %v", err)
+	}
+	if err := intersight.StartServer(nodeConfig); err == nil || !strings.Contains(err.Error(), "server profile \"node1\" not found") {
+		t.Fatalf("expected missing profile failure, got %v", err)
+	}
+	if err := intersight.CreateServer(nodeConfig); err != nil {
+		t.Fatalf("CreateServer() failure: %s", err)
+	}
+	if state, err := intersight.GetServerOperationalState(nodeConfig); err != nil || state != "power-off" {
+		t.Fatalf("unexpected operational state %q, error %v", state, err)
+	}
+	if err := intersight.StartServer(nodeConfig); err != nil {
+		t.Fatalf("StartServer() failure: %s", err)
+	}
+	if state, err := intersight.GetServerPowerState(nodeConfig); err != nil || state != "up" || nodeConfig.Compute.Powerstate != "up" {
+		t.Fatalf("unexpected power state %q, error %v", state, err)
+	}
+	if state, err := intersight.GetServerOperationalState(nodeConfig); err != nil || state != "ok" {
+		t.Fatalf("unexpected operational state %q, error %v", state, err)
+	}
+	if err := intersight.DeleteServer(nodeConfig); err == nil || !strings.Contains(err.Error(), "server \"sys/chassis-1/blade-1\" has power state \"up\"") {
+		t.Fatalf("expected powered on server failure, got %v", err)
+	}
+	if err := intersight.StopServer(nodeConfig); err != nil {
+		t.Fatalf("StopServer() failure: %s", err)
+	}
+	if state, err := intersight.GetServerPowerState(nodeConfig); err != nil || state != "down" || nodeConfig.Compute.Powerstate != "down" {
+		t.Fatalf("unexpected power state %q, error %v", state, err)
+	}
+	var calls []string
+	for _, call := range server.Calls() {
+		if strings.HasPrefix(call, "PATCH /compute/ServerSettings/") {
+			calls = append(calls, call)
+		}
+	}
+	if len(calls) != 2 {
+		t.Fatalf("unexpected server settings calls %v", calls)
+	}
+}
+
+func TestDeleteServer(t *testing.T) {
+	server, credentials := useFakeServer(t)
+	nodeConfig := testNodeConfig(credentials, "node1")
+	if err := intersight.CreateServer(nodeConfig); err != nil {
+		t.Fatalf("CreateServer() failure: %s", err)
+	}
+	moid := testProfile(t, testClient(t, credentials), "node1").AssignedServer.Moid
+	if err := intersight.DeleteServer(nodeConfig); err != nil {
+		t.Fatalf("DeleteServer() failure: %s", err)
+	}
+	if summary := server.Summary(moid); summary.ServiceProfile != "" {
+		t.Fatalf("server is not unassigned: %+v", summary)
+	}
+	for collection, expected := range map[string]int{
+		"server/Profiles":                0,
+		"server/ProfileTemplates":        1,
+		"vnic/LanConnectivityPolicies":   1,
+		"vnic/IscsiBootPolicies":         0,
+		"vnic/IscsiStaticTargetPolicies": 0,
+		"compute/PhysicalSummaries":      2,
+		"organization/Organizations":     2,
+	} {
+		if n := server.Count(collection); n != expected {
+			t.Errorf("expected %d objects in %s, got %d", expected, collection, n)
+		}
+	}
+	if serverExists, err := intersight.DiscoverServer(nodeConfig); err != nil || serverExists {
+		t.Fatalf("deleted profile is discovered: %v", err)
+	}
+	// missing profile is not an error
+	if err := intersight.DeleteServer(nodeConfig); err != nil {
+		t.Fatalf("DeleteServer() failure: %s", err)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	server, credentials := useFakeServer(t)
+	nodeConfig := testNodeConfig(credentials, "node1")
+	nodeConfig.Plan = config.NewPlan()
+	if err := intersight.CreateServer(nodeConfig); err != nil {
+		t.Fatalf("CreateServer() failure: %s", err)
+	}
+	if err := intersight.StartServer(nodeConfig); err != nil {
+		t.Fatalf("StartServer() failure: %s", err)
+	}
+	if calls := server.Calls(); len(calls) != 0 {
+		t.Fatalf("unexpected calls %v in plan mode", calls)
+	}
+	var operations []string
+	for _, op := range nodeConfig.Plan.Operations() {
+		if op.Backend != "intersight" || op.Host != server.URL {
+			t.Errorf("unexpected planned operation %+v", op)
+		}
+		operations = append(operations, op.Operation)
+	}
+	if strings.Join(operations, ",") != "MoCloner,Detach,MoDeepCloner,SetIscsiBootPolicy,SetIscsiBootPolicy,Deploy,SetAdminPowerState" {
+		t.Fatalf("unexpected planned operations %v", operations)
+	}
+	if ops := nodeConfig.Plan.Operations(); !strings.Contains(ops[5].Description, "one of 1 available servers (sys/chassis-1/blade-1)") {
+		t.Fatalf("unexpected planned assignment %q", ops[5].Description)
+	}
+}
@@ -0,0 +1,80 @@
+package intersight
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+const (
+	planBackend = "intersight"
+)
+
+// planRecord records Intersight mutation in node configuration plan
+func planRecord(nodeConfig *config.NodeConfig, operation string, format string, args ...interface{}) {
+	nodeConfig.Plan.Record(planBackend, nodeConfig.Compute.IntersightCredentials.Host, operation, format, args...)
+}
+
+// planCreateServer records profile derive from template, server assignment is planned per available servers
+func planCreateServer(client *Client, template *ProfileTemplate, nodeConfig *config.NodeConfig) (err error) {
+	hostName := nodeConfig.Compute.HostName
+	planRecord(nodeConfig, "MoCloner", "derive server profile %s from template %s", hostName, template.Name)
+	planRecord(nodeConfig, "Detach", "detach server profile %s from template", hostName)
+	if len(nodeConfig.Compute.Description) > 0 || len(nodeConfig.Compute.Label) > 0 {
+		planRecord(nodeConfig, "SetAttributes", "set server profile %s description \"%s\", label \"%s\"", hostName, nodeConfig.Compute.Description, nodeConfig.Compute.Label)
+	}
+	planRecord(nodeConfig, "MoDeepCloner", "clone LAN connectivity policy of server profile %s to %s", hostName, lanPolicyName(hostName))
+	planServerBoot(nodeConfig)
+	err = planAssignServer(client, nodeConfig)
+	return
+}
+
+// planServerBoot records programming of per-server iSCSI boot policies
+func planServerBoot(nodeConfig *config.NodeConfig) {
+	for i := range nodeConfig.Network.IscsiInitiator {
+		if i > 1 {
+			break
+		}
+		initiator := nodeConfig.Network.IscsiInitiator[i]
+		var targetName string
+		var targets []string
+		if initiator.IscsiTarget != nil {
+			targetName = initiator.IscsiTarget.NodeName
+			targets = initiator.IscsiTarget.Interfaces
+			if len(targets) > 2 {
+				targets = targets[:2]
+			}
+		}
+		planRecord(nodeConfig, "SetIscsiBootPolicy", "set iSCSI boot policy %s on vNIC %s, initiator %s, IP %s, target %s via %s", iscsiBootPolicyName(nodeConfig.Compute.HostName, initiator.Name), initiator.Name, initiator.InitiatorName, initiator.Ip, targetName, strings.Join(targets, ","))
+	}
+}
+
+// planAssignServer records server assignment, available servers are queried per BladeSpec
+func planAssignServer(client *Client, nodeConfig *config.NodeConfig) (err error) {
+	var servers []PhysicalSummary
+	bladeSpec := nodeConfig.Compute.BladeSpec
+	if servers, err = GetAvailableServers(client, &bladeSpec); err != nil {
+		err = fmt.Errorf("AssignServer: %s", err)
+		return
+	}
+	if len(servers) == 0 {
+		err = fmt.Errorf("AssignServer: GetAvailableServers(): no servers found per BladeSpec")
+		return
+	}
+	var dns []string
+	for _, server := range servers {
+		dns = append(dns, server.Dn)
+	}
+	planRecord(nodeConfig, "Deploy", "assign server profile %s to one of %d available servers (%s) and deploy", nodeConfig.Compute.HostName, len(dns), strings.Join(dns, ","))
+	return
+}
+
+// planDeleteServer records profile unassign and deletion
+func planDeleteServer(nodeConfig *config.NodeConfig, profile *Profile) {
+	if profile.AssignedServer != nil {
+		planRecord(nodeConfig, "Unassign", "unassign server from server profile %s", profile.Name)
+	}
+	planRecord(nodeConfig, "Delete", "delete server profile %s", profile.Name)
+	planRecord(nodeConfig, "Delete", "delete per-server LAN connectivity and iSCSI boot policies of %s", profile.Name)
+}
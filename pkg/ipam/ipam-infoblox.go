@@ -381,12 +381,12 @@ func (p *InfobloxProvider) Allocate(nodeConfig *config.NodeConfig) (err error) {
 	for i := range nodeConfig.Network.Node {
 		if len(nodeConfig.Network.Node[i].Ip) > 0 {
 			ipaddr = nodeConfig.Network.Node[i].Ip
-			err = p.AssignIp(ipaddr, nodeConfig.Compute.HostName+hostSuffix+"."+p.DnsZone)
+			err = p.planAssignIp(nodeConfig, ipaddr, nodeConfig.Compute.HostName+hostSuffix+"."+p.DnsZone)
 		} else {
 			if len(nodeConfig.Network.Node[i].IpRange) > 0 {
-				ipaddr, err = p.AllocateIp(nodeConfig.Network.Node[i].IpRange, nodeConfig.Compute.HostName+hostSuffix+"."+p.DnsZone)
+				ipaddr, err = p.planAllocateIp(nodeConfig, nodeConfig.Network.Node[i].IpRange, nodeConfig.Compute.HostName+hostSuffix+"."+p.DnsZone)
 			} else {
-				ipaddr, err = p.AllocateIp(nodeConfig.Network.Node[i].Subnet, nodeConfig.Compute.HostName+hostSuffix+"."+p.DnsZone)
+				ipaddr, err = p.planAllocateIp(nodeConfig, nodeConfig.Network.Node[i].Subnet, nodeConfig.Compute.HostName+hostSuffix+"."+p.DnsZone)
 			}
 		}
 		if err != nil {
@@ -400,12 +400,12 @@ func (p *InfobloxProvider) Allocate(nodeConfig *config.NodeConfig) (err error) {
 		hostSuffix = "-i" + strconv.Itoa(i+1)
 		if len(nodeConfig.Network.IscsiInitiator[i].Ip) > 0 {
 			ipaddr = nodeConfig.Network.IscsiInitiator[i].Ip
-			err = p.AssignIp(ipaddr, nodeConfig.Compute.HostName+hostSuffix+"."+p.DnsZone)
+			err = p.planAssignIp(nodeConfig, ipaddr, nodeConfig.Compute.HostName+hostSuffix+"."+p.DnsZone)
 		} else {
 			if len(nodeConfig.Network.IscsiInitiator[i].IpRange) > 0 {
-				ipaddr, err = p.AllocateIp(nodeConfig.Network.IscsiInitiator[i].IpRange, nodeConfig.Compute.HostName+hostSuffix+"."+p.DnsZone)
+				ipaddr, err = p.planAllocateIp(nodeConfig, nodeConfig.Network.IscsiInitiator[i].IpRange, nodeConfig.Compute.HostName+hostSuffix+"."+p.DnsZone)
 			} else {
-				ipaddr, err = p.AllocateIp(nodeConfig.Network.IscsiInitiator[i].Subnet, nodeConfig.Compute.HostName+hostSuffix+"."+p.DnsZone)
+				ipaddr, err = p.planAllocateIp(nodeConfig, nodeConfig.Network.IscsiInitiator[i].Subnet, nodeConfig.Compute.HostName+hostSuffix+"."+p.DnsZone)
 			}
 		}
 		if err != nil {
@@ -522,7 +522,7 @@ func (p *InfobloxProvider) Release(nodeConfig *config.NodeConfig) (err error) {
 	var ipaddr string
 	var hostSuffix string = ""
 	for i := range nodeConfig.Network.Node {
-		if ipaddr, err = p.ReleaseIp(nodeConfig.Compute.HostName + hostSuffix + "." + p.DnsZone); err != nil {
+		if ipaddr, err = p.planReleaseIp(nodeConfig, nodeConfig.Compute.HostName + hostSuffix + "." + p.DnsZone); err != nil {
 			return
 		}
 		nodeConfig.Network.Node[i].Ip = ipaddr
@@ -530,10 +530,54 @@ func (p *InfobloxProvider) Release(nodeConfig *config.NodeConfig) (err error) {
 	}
 	for i := range nodeConfig.Network.IscsiInitiator {
 		hostSuffix = "-i" + strconv.Itoa(i+1)
-		if ipaddr, err = p.ReleaseIp(nodeConfig.Compute.HostName + hostSuffix + "." + p.DnsZone); err != nil {
+		if ipaddr, err = p.planReleaseIp(nodeConfig, nodeConfig.Compute.HostName + hostSuffix + "." + p.DnsZone); err != nil {
 			return
 		}
 		nodeConfig.Network.IscsiInitiator[i].Ip = ipaddr
 	}
 	return
 }
+
+// planAllocateIp allocates IP, in dry-run mode next available IP is looked up and allocation is recorded in the plan
+func (p *InfobloxProvider) planAllocateIp(nodeConfig *config.NodeConfig, cidr string, fqdn string) (ipaddr string, err error) {
+	if nodeConfig.Plan == nil {
+		return p.AllocateIp(cidr, fqdn)
+	}
+	if ipaddr, err = p.AllocateIp(cidr, ""); err == nil {
+		nodeConfig.Plan.Record("ipam", p.HostConfig.Host, "AllocateIp", "allocate next available IP in %s (currently %s) to %s", cidr, ipaddr, fqdn)
+	}
+	return
+}
+
+// planAssignIp assigns IP, in dry-run mode the assignment is recorded in the plan
+func (p *InfobloxProvider) planAssignIp(nodeConfig *config.NodeConfig, ipaddr string, fqdn string) (err error) {
+	if nodeConfig.Plan == nil {
+		return p.AssignIp(ipaddr, fqdn)
+	}
+	nodeConfig.Plan.Record("ipam", p.HostConfig.Host, "AssignIp", "assign IP %s to %s", ipaddr, fqdn)
+	return
+}
+
+// planReleaseIp releases IP, in dry-run mode the release is recorded in the plan
+func (p *InfobloxProvider) planReleaseIp(nodeConfig *config.NodeConfig, fqdn string) (ipaddr string, err error) {
+	if nodeConfig.Plan == nil {
+		return p.ReleaseIp(fqdn)
+	}
+	transportConfig := ibclient.NewTransportConfig("false", 20, 10)
+	requestBuilder := &ibclient.WapiRequestBuilder{}
+	requestor := &ibclient.WapiHttpRequestor{}
+	var conn *ibclient.Connector
+	if conn, err = ibclient.NewConnector(p.HostConfig, transportConfig, requestBuilder, requestor); err != nil {
+		err = fmt.Errorf("ReleaseIP(): NewConnector(): %s", err)
+		return
+	}
+	defer conn.Logout()
+	if ipaddr, err = getIpByHost(conn, p.NetworkView, fqdn); err != nil {
+		err = fmt.Errorf("ReleaseIP(): getIpByHost(): %s", err)
+		return
+	}
+	if ipaddr != "" {
+		nodeConfig.Plan.Record("ipam", p.HostConfig.Host, "ReleaseIp", "release IP %s of %s", ipaddr, fqdn)
+	}
+	return
+}
@@ -3,6 +3,7 @@ package ontap
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
@@ -35,22 +36,13 @@ func CreateBootStorage(nodeConfig *config.NodeConfig) (err error) {
 			return
 		}
 	}
-	var igroupExists bool
-	if igroupExists, err = c.IgroupExists(nodeConfig.Storage.IgroupName); err != nil {
+	if err = createIgroup(c, nodeConfig); err != nil {
 		err = fmt.Errorf(errorFormat, err)
 		return
 	}
-	if !igroupExists {
-		if c.IgroupCreate(nodeConfig.Storage.IgroupName, "linux"); err != nil {
-			err = fmt.Errorf(errorFormat, err)
-			return
-		}
-		for i := range nodeConfig.Network.IscsiInitiator {
-			if c.IgroupAddInitiator(nodeConfig.Storage.IgroupName, nodeConfig.Network.IscsiInitiator[i].InitiatorName); err != nil {
-				err = fmt.Errorf(errorFormat, err)
-				return
-			}
-		}
+	if err = setIscsiInitiatorsAuth(c, nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
 	}
 	var lunExists bool
 	if lunExists, err = c.LunExists(bootLunPath); err != nil {
@@ -58,6 +50,10 @@ func CreateBootStorage(nodeConfig *config.NodeConfig) (err error) {
 		return
 	}
 	if !lunExists {
+		if err = verifyRepoImageSigned(c, nodeConfig, imageLunPath); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
 		if err = c.LunCopy(imageLunPath, bootLunPath); err != nil {
 			err = fmt.Errorf(errorFormat, err)
 			return
@@ -100,15 +96,78 @@ func CreateBootStorage(nodeConfig *config.NodeConfig) (err error) {
 			}
 		}
 	}
+	if err = discoverIscsiTargets(c, nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	if err = discoverFcTargets(c, nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	return
+}
+
+// createIgroup creates node iGroup with iSCSI initiators and known FC initiators, iGroup protocol is "mixed" for both
+func createIgroup(c client.OntapClient, nodeConfig *config.NodeConfig) (err error) {
+	var igroupExists bool
+	if igroupExists, err = c.IgroupExists(nodeConfig.Storage.IgroupName); err != nil {
+		return
+	}
+	if igroupExists {
+		return
+	}
+	igroupProtocol := "iscsi"
+	if len(nodeConfig.Network.FcInitiator) > 0 {
+		if len(nodeConfig.Network.IscsiInitiator) > 0 {
+			igroupProtocol = "mixed"
+		} else {
+			igroupProtocol = "fcp"
+		}
+	}
+	if err = c.IgroupCreate(nodeConfig.Storage.IgroupName, igroupProtocol, "linux"); err != nil {
+		return
+	}
+	for i := range nodeConfig.Network.IscsiInitiator {
+		if err = c.IgroupAddInitiator(nodeConfig.Storage.IgroupName, nodeConfig.Network.IscsiInitiator[i].InitiatorName); err != nil {
+			return
+		}
+	}
+	for i := range nodeConfig.Network.FcInitiator {
+		if nodeConfig.Network.FcInitiator[i].Wwpn != "" {
+			if err = c.IgroupAddInitiator(nodeConfig.Storage.IgroupName, strings.ToLower(nodeConfig.Network.FcInitiator[i].Wwpn)); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// setIscsiInitiatorsAuth sets CHAP authentication for node iSCSI initiators
+func setIscsiInitiatorsAuth(c client.OntapClient, nodeConfig *config.NodeConfig) (err error) {
+	if nodeConfig.Network.IscsiChap.User == "" {
+		return
+	}
+	for i := range nodeConfig.Network.IscsiInitiator {
+		if err = c.IscsiInitiatorSetAuth(nodeConfig.Network.IscsiInitiator[i].InitiatorName, nodeConfig.Network.IscsiChap.User, nodeConfig.Network.IscsiChap.Password, nodeConfig.Network.IscsiChap.TargetUser, nodeConfig.Network.IscsiChap.TargetPassword); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// discoverIscsiTargets sets iSCSI target node name and LIF's for every iSCSI initiator
+func discoverIscsiTargets(c client.OntapClient, nodeConfig *config.NodeConfig) (err error) {
+	if len(nodeConfig.Network.IscsiInitiator) == 0 {
+		return
+	}
+	bootLunPath := "/vol/" + nodeConfig.Storage.VolumeName + "/" + nodeConfig.Storage.BootLun.Name
 	var iscsiNodeName string
 	if iscsiNodeName, err = c.IscsiTargetGetName(); err != nil {
-		err = fmt.Errorf(errorFormat, err)
 		return
 	}
 	for i := range nodeConfig.Network.IscsiInitiator {
 		var lifs []string
 		if lifs, err = c.DiscoverIscsiLIFs(bootLunPath, nodeConfig.Network.IscsiInitiator[i].Subnet); err != nil {
-			err = fmt.Errorf(errorFormat, err)
 			return
 		}
 		nodeConfig.Network.IscsiInitiator[i].IscsiTarget = &config.IscsiTarget{}
@@ -118,6 +177,109 @@ func CreateBootStorage(nodeConfig *config.NodeConfig) (err error) {
 	return
 }
 
+// SetFcInitiators adds SP vHBA's WWPN's to node iGroup
+func SetFcInitiators(nodeConfig *config.NodeConfig) (err error) {
+	var c client.OntapClient
+	errorFormat := "SetFcInitiators(): %s"
+	if c, err = client.NewOntapClient(nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	var initiators []string
+	if initiators, err = c.IgroupGetInitiators(nodeConfig.Storage.IgroupName); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	for _, fcInitiator := range nodeConfig.Network.FcInitiator {
+		if fcInitiator.Wwpn == "" {
+			err = fmt.Errorf("SetFcInitiators(): WWPN is not assigned to fcInitiator \"%s\"", fcInitiator.Name)
+			return
+		}
+		var found bool
+		for _, initiator := range initiators {
+			if strings.EqualFold(initiator, fcInitiator.Wwpn) {
+				found = true
+			}
+		}
+		if !found {
+			if err = c.IgroupAddInitiator(nodeConfig.Storage.IgroupName, strings.ToLower(fcInitiator.Wwpn)); err != nil {
+				err = fmt.Errorf(errorFormat, err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// discoverFcTargets sets FC target WWPN's on initiator fabric for every FC initiator
+func discoverFcTargets(c client.OntapClient, nodeConfig *config.NodeConfig) (err error) {
+	if len(nodeConfig.Network.FcInitiator) == 0 {
+		return
+	}
+	var fcpNodeName string
+	if fcpNodeName, err = c.FcpTargetGetName(); err != nil {
+		return
+	}
+	var lifs []client.FcInterface
+	if lifs, err = c.GetFcpLIFs(); err != nil {
+		return
+	}
+	for i := range nodeConfig.Network.FcInitiator {
+		var targets []string
+		if targets, err = selectFcTargets(&nodeConfig.Network.FcInitiator[i], lifs); err != nil {
+			return
+		}
+		nodeConfig.Network.FcInitiator[i].FcTarget = &config.FcTarget{}
+		nodeConfig.Network.FcInitiator[i].FcTarget.NodeName = fcpNodeName
+		nodeConfig.Network.FcInitiator[i].FcTarget.Interfaces = targets
+	}
+	return
+}
+
+// selectFcTargets selects WWPN's of FC LIF's on initiator fabric: LIF's named in initiator target LIF's,
+// LIF's logged in to initiator fabric, or all LIF's if ONTAP reports a single fabric only
+func selectFcTargets(initiator *config.FcInitiator, lifs []client.FcInterface) (targets []string, err error) {
+	if len(initiator.TargetLifs) > 0 {
+		for _, lif := range lifs {
+			for _, targetLif := range initiator.TargetLifs {
+				if lif.Name == targetLif || strings.EqualFold(lif.Wwpn, targetLif) {
+					targets = append(targets, lif.Wwpn)
+					break
+				}
+			}
+		}
+		if len(targets) == 0 {
+			err = fmt.Errorf("selectFcTargets(): none of target LIFs %v of fcInitiator \"%s\" is FC LIF in up state", initiator.TargetLifs, initiator.Name)
+		}
+		return
+	}
+	if initiator.Fabric != "" {
+		for _, lif := range lifs {
+			if strings.EqualFold(lif.Fabric, initiator.Fabric) {
+				targets = append(targets, lif.Wwpn)
+			}
+		}
+		if len(targets) == 0 {
+			err = fmt.Errorf("selectFcTargets(): no FC LIFs found on fabric \"%s\" of fcInitiator \"%s\", ZAPI does not report LIF fabric, use targetLifs with ZAPI", initiator.Fabric, initiator.Name)
+		}
+		return
+	}
+	var fabrics []string
+	seen := make(map[string]bool)
+	for _, lif := range lifs {
+		if lif.Fabric != "" && !seen[lif.Fabric] {
+			seen[lif.Fabric] = true
+			fabrics = append(fabrics, lif.Fabric)
+		}
+		targets = append(targets, lif.Wwpn)
+	}
+	if len(fabrics) > 1 {
+		targets = nil
+		err = fmt.Errorf("selectFcTargets(): FC LIFs are logged in to fabrics %v, set fabric or targetLifs for fcInitiator \"%s\"", fabrics, initiator.Name)
+	}
+	return
+}
+
 // CreateBootStoragePreflight is sanity check before actual storage provisioning
 func CreateBootStoragePreflight(nodeConfig *config.NodeConfig) (err error) {
 	var c client.OntapClient
@@ -145,20 +307,26 @@ func CreateBootStoragePreflight(nodeConfig *config.NodeConfig) (err error) {
 		err = fmt.Errorf("CreateBootStoragePreflight(): image \"%s\" not found in image repository volume \"%s\"", nodeConfig.Storage.BootLun.OsImage.Name, nodeConfig.Storage.ImageRepoName)
 		return
 	}
-	var iscsiNodeName string
-	if iscsiNodeName, err = c.IscsiTargetGetName(); err != nil {
-		err = fmt.Errorf(errorFormat, err)
-		return
-	}
-	for i := range nodeConfig.Network.IscsiInitiator {
-		var lifs []string
-		if lifs, err = c.DiscoverIscsiLIFs(repoLunPath, nodeConfig.Network.IscsiInitiator[i].Subnet); err != nil {
+	if len(nodeConfig.Network.IscsiInitiator) > 0 {
+		var iscsiNodeName string
+		if iscsiNodeName, err = c.IscsiTargetGetName(); err != nil {
 			err = fmt.Errorf(errorFormat, err)
 			return
 		}
-		nodeConfig.Network.IscsiInitiator[i].IscsiTarget = &config.IscsiTarget{}
-		nodeConfig.Network.IscsiInitiator[i].IscsiTarget.NodeName = iscsiNodeName
-		nodeConfig.Network.IscsiInitiator[i].IscsiTarget.Interfaces = append(nodeConfig.Network.IscsiInitiator[i].IscsiTarget.Interfaces, lifs...)
+		for i := range nodeConfig.Network.IscsiInitiator {
+			var lifs []string
+			if lifs, err = c.DiscoverIscsiLIFs(repoLunPath, nodeConfig.Network.IscsiInitiator[i].Subnet); err != nil {
+				err = fmt.Errorf(errorFormat, err)
+				return
+			}
+			nodeConfig.Network.IscsiInitiator[i].IscsiTarget = &config.IscsiTarget{}
+			nodeConfig.Network.IscsiInitiator[i].IscsiTarget.NodeName = iscsiNodeName
+			nodeConfig.Network.IscsiInitiator[i].IscsiTarget.Interfaces = append(nodeConfig.Network.IscsiInitiator[i].IscsiTarget.Interfaces, lifs...)
+		}
+	}
+	if err = discoverFcTargets(c, nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
 	}
 	err = CreateNvmeStoragePreflight(nodeConfig)
 	return
@@ -203,6 +371,14 @@ func DeleteBootStorage(nodeConfig *config.NodeConfig) (err error) {
 			return
 		}
 	}
+	if nodeConfig.Network.IscsiChap.User != "" {
+		for i := range nodeConfig.Network.IscsiInitiator {
+			if err = c.IscsiInitiatorDeleteAuth(nodeConfig.Network.IscsiInitiator[i].InitiatorName); err != nil {
+				err = fmt.Errorf(errorFormat, err)
+				return
+			}
+		}
+	}
 	var volumeExists bool
 	if volumeExists, err = c.VolumeExists(nodeConfig.Storage.VolumeName); err != nil {
 		err = fmt.Errorf(errorFormat, err)
@@ -282,6 +458,10 @@ func DiscoverBootStorage(nodeConfig *config.NodeConfig) (storageExists bool, err
 	if !storageExists {
 		return
 	}
+	if nodeConfig.Storage.Aggregate, err = c.VolumeGetAggregate(nodeConfig.Storage.VolumeName); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
 	if storageExists, err = c.LunExists(bootLunPath); err != nil {
 		err = fmt.Errorf(errorFormat, err)
 		return
@@ -302,7 +482,7 @@ func DiscoverBootStorage(nodeConfig *config.NodeConfig) (storageExists bool, err
 		return
 	}
 	if lunInfo.Comment != "" {
-		nodeConfig.Storage.BootLun.OsImage.Name = lunInfo.Comment
+		nodeConfig.Storage.BootLun.OsImage.Name = ParseRepoImageComment(lunInfo.Comment).Name
 	}
 	nodeConfig.Storage.BootLun.Size = lunInfo.Size
 	if lunInfo, err = c.LunGetInfo(dataLunPath); err == nil {
@@ -316,20 +496,26 @@ func DiscoverBootStorage(nodeConfig *config.NodeConfig) (storageExists bool, err
 		nodeConfig.Storage.SeedLun.SeedTemplate.Location = lunInfo.Comment
 		nodeConfig.Storage.SeedLun.SeedTemplate.Name = filepath.Base(lunInfo.Comment)
 	}
-	var iscsiNodeName string
-	if iscsiNodeName, err = c.IscsiTargetGetName(); err != nil {
-		err = fmt.Errorf(errorFormat, err)
-		return
-	}
-	for i := range nodeConfig.Network.IscsiInitiator {
-		var lifs []string
-		if lifs, err = c.DiscoverIscsiLIFs(bootLunPath, nodeConfig.Network.IscsiInitiator[i].Subnet); err != nil {
+	if len(nodeConfig.Network.IscsiInitiator) > 0 {
+		var iscsiNodeName string
+		if iscsiNodeName, err = c.IscsiTargetGetName(); err != nil {
 			err = fmt.Errorf(errorFormat, err)
 			return
 		}
-		nodeConfig.Network.IscsiInitiator[i].IscsiTarget = &config.IscsiTarget{}
-		nodeConfig.Network.IscsiInitiator[i].IscsiTarget.NodeName = iscsiNodeName
-		nodeConfig.Network.IscsiInitiator[i].IscsiTarget.Interfaces = append(nodeConfig.Network.IscsiInitiator[i].IscsiTarget.Interfaces, lifs...)
+		for i := range nodeConfig.Network.IscsiInitiator {
+			var lifs []string
+			if lifs, err = c.DiscoverIscsiLIFs(bootLunPath, nodeConfig.Network.IscsiInitiator[i].Subnet); err != nil {
+				err = fmt.Errorf(errorFormat, err)
+				return
+			}
+			nodeConfig.Network.IscsiInitiator[i].IscsiTarget = &config.IscsiTarget{}
+			nodeConfig.Network.IscsiInitiator[i].IscsiTarget.NodeName = iscsiNodeName
+			nodeConfig.Network.IscsiInitiator[i].IscsiTarget.Interfaces = append(nodeConfig.Network.IscsiInitiator[i].IscsiTarget.Interfaces, lifs...)
+		}
+	}
+	if err = discoverFcTargets(c, nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
 	}
         if err = DiscoverNvmeStorage(nodeConfig) ; err != nil {
 		err = fmt.Errorf(errorFormat, err)
@@ -0,0 +1,195 @@
+package ontap
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
+)
+
+// testCreateNodeStorage uploads repository image and creates node boot, seed and data storage
+func testCreateNodeStorage(t *testing.T, nodeConfig *config.NodeConfig, content []byte) {
+	t.Helper()
+	imagePath, checksumPath := testImageFile(t, content)
+	if err := CreateRepoImage(nodeConfig, testImageName, imagePath, checksumPath, "", nil); err != nil {
+		t.Fatalf("CreateRepoImage() failure: %s", err)
+	}
+	if err := CreateBootStorage(nodeConfig); err != nil {
+		t.Fatalf("CreateBootStorage() failure: %s", err)
+	}
+	if err := CreateSeedStorage(nodeConfig); err != nil {
+		t.Fatalf("CreateSeedStorage() failure: %s", err)
+	}
+}
+
+func TestCreateBootStorage(t *testing.T) {
+	f := useFakeFactory(t)
+	cluster := f.Cluster(testCdotHost, testSvm)
+	nodeConfig := testNodeConfig(t, "node1")
+	content := testImageContent(1, 64*1024)
+	testCreateNodeStorage(t, nodeConfig, content)
+	testVerifyClean(t, nodeConfig)
+	data, err := cluster.LunData("/vol/node1_iboot/node1_iboot")
+	if err != nil || string(data) != string(content) {
+		t.Fatalf("boot LUN is not cloned from image: %v", err)
+	}
+	target := nodeConfig.Network.IscsiInitiator[0].IscsiTarget
+	if target == nil || target.NodeName != cluster.IscsiTargetName || strings.Join(target.Interfaces, ",") != "192.168.10.11,192.168.10.12" {
+		t.Fatalf("unexpected iSCSI target %+v", target)
+	}
+
+	// repeated call does not re-create existing storage
+	if err = CreateBootStorage(nodeConfig); err != nil {
+		t.Fatalf("CreateBootStorage() failure: %s", err)
+	}
+	if testCalls(cluster, "LunCopy") != 1 || testCalls(cluster, "LunCreate") != 1 || testCalls(cluster, "LunMap") != 3 {
+		t.Fatalf("unexpected calls on repeated CreateBootStorage(): %v", cluster.Calls())
+	}
+	testVerifyClean(t, nodeConfig)
+
+	// configuration drift is reported
+	nodeConfig.Storage.DataLun.Size = 8
+	nodeConfig.Network.IscsiInitiator[0].InitiatorName = "iqn.2005-02.com.open-iscsi:other"
+	if !testHasDiscrepancy(t, nodeConfig, "lun /vol/node1_iboot/node1_data", "size") {
+		t.Errorf("data LUN size discrepancy is not reported")
+	}
+	if !testHasDiscrepancy(t, nodeConfig, "igroup node1_iboot", "initiators") {
+		t.Errorf("igroup initiators discrepancy is not reported")
+	}
+}
+
+func TestCreateBootStorageIgroupProtocol(t *testing.T) {
+	tests := []struct {
+		name       string
+		iscsi      bool
+		fc         bool
+		protocol   string
+		initiators string
+	}{
+		{name: "iSCSI", iscsi: true, protocol: "iscsi", initiators: "iqn.2005-02.com.open-iscsi:node1"},
+		{name: "FC", fc: true, protocol: "fcp", initiators: "20:00:00:25:b5:01:00:0a"},
+		{name: "iSCSI and FC", iscsi: true, fc: true, protocol: "mixed", initiators: "iqn.2005-02.com.open-iscsi:node1,20:00:00:25:b5:01:00:0a"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := useFakeFactory(t)
+			cluster := f.Cluster(testCdotHost, testSvm)
+			cluster.FcpLIFs = []client.FcInterface{{Name: "fc_lif1", Wwpn: "20:01:00:a0:98:00:00:01"}}
+			nodeConfig := testNodeConfig(t, "node1")
+			if !test.iscsi {
+				nodeConfig.Network.IscsiInitiator = nil
+			}
+			if test.fc {
+				nodeConfig.Network.FcInitiator = []config.FcInitiator{{Name: "fc0", Wwpn: "20:00:00:25:B5:01:00:0A"}}
+			}
+			testCreateNodeStorage(t, nodeConfig, testImageContent(1, 16*1024))
+			if protocol, err := cluster.IgroupProtocol("node1_iboot"); err != nil || protocol != test.protocol {
+				t.Fatalf("unexpected iGroup protocol %q, error %v", protocol, err)
+			}
+			c, _ := f.NewClient(nodeConfig)
+			if initiators, err := c.IgroupGetInitiators("node1_iboot"); err != nil || strings.Join(initiators, ",") != test.initiators {
+				t.Fatalf("unexpected iGroup initiators %v, error %v", initiators, err)
+			}
+			testVerifyClean(t, nodeConfig)
+		})
+	}
+}
+
+func TestCreateBootStorageFault(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		match    string
+		object   string
+		property string
+	}{
+		{name: "data LUN create", method: "LunCreate", match: "_data", object: "lun /vol/node1_iboot/node1_data", property: "exists"},
+		{name: "boot LUN map", method: "LunMap", match: "_iboot/node1_iboot", object: "lun /vol/node1_iboot/node1_iboot", property: "mapping"},
+		{name: "boot LUN resize", method: "LunResize", object: "lun /vol/node1_iboot/node1_iboot", property: "size"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := useFakeFactory(t)
+			cluster := f.Cluster(testCdotHost, testSvm)
+			nodeConfig := testNodeConfig(t, "node1")
+			imagePath, checksumPath := testImageFile(t, testImageContent(1, 64*1024))
+			if err := CreateRepoImage(nodeConfig, testImageName, imagePath, checksumPath, "", nil); err != nil {
+				t.Fatalf("CreateRepoImage() failure: %s", err)
+			}
+			cluster.InjectFault(test.method, errors.New("connection reset"), 1, test.match)
+			err := CreateBootStorage(nodeConfig)
+			if err == nil || !strings.Contains(err.Error(), test.method+"() failure: connection reset") {
+				t.Fatalf("expected %s() failure, got %v", test.method, err)
+			}
+			if !testHasDiscrepancy(t, nodeConfig, test.object, test.property) {
+				t.Errorf("discrepancy of %s %s is not reported", test.object, test.property)
+			}
+			// retry completes partially created storage
+			if err = CreateBootStorage(nodeConfig); err != nil && test.method != "LunResize" {
+				t.Fatalf("CreateBootStorage() retry failure: %s", err)
+			}
+			if test.method == "LunResize" {
+				// cloned boot LUN exists, retry does not resize it again
+				if !testHasDiscrepancy(t, nodeConfig, test.object, test.property) {
+					t.Errorf("discrepancy of %s %s is not reported after retry", test.object, test.property)
+				}
+				if err = ResizeBootStorage(nodeConfig); err != nil {
+					t.Fatalf("ResizeBootStorage() failure: %s", err)
+				}
+			}
+			if err = CreateSeedStorage(nodeConfig); err != nil {
+				t.Fatalf("CreateSeedStorage() failure: %s", err)
+			}
+			testVerifyClean(t, nodeConfig)
+			if testCalls(cluster, "LunCopy") != 1 {
+				t.Errorf("boot LUN is cloned more than once")
+			}
+		})
+	}
+}
+
+func TestVerifyBootStorageMissing(t *testing.T) {
+	f := useFakeFactory(t)
+	nodeConfig := testNodeConfig(t, "node1")
+	discrepancies, err := VerifyBootStorage(nodeConfig)
+	if err != nil {
+		t.Fatalf("VerifyBootStorage() failure: %s", err)
+	}
+	if len(discrepancies) != 1 || discrepancies[0].Object != "volume node1_iboot" || discrepancies[0].Property != "exists" {
+		t.Fatalf("unexpected discrepancies %v", discrepancies)
+	}
+	f.Cluster(testCdotHost, testSvm).InjectFault("VolumeExists", errors.New("timeout"), 0, "")
+	if _, err = VerifyBootStorage(nodeConfig); err == nil || !strings.Contains(err.Error(), "VolumeExists() failure: timeout") {
+		t.Fatalf("expected VolumeExists() failure, got %v", err)
+	}
+}
+
+func TestLunRestoreMapping(t *testing.T) {
+	f := useFakeFactory(t)
+	cluster := f.Cluster(testCdotHost, testSvm)
+	nodeConfig := testNodeConfig(t, "node1")
+	testCreateNodeStorage(t, nodeConfig, testImageContent(1, 16*1024))
+	c, _ := f.NewClient(nodeConfig)
+	for _, lunName := range []string{"node1_iboot", "node1_seed"} {
+		if err := c.LunUnmap("/vol/node1_iboot/"+lunName, "node1_iboot"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !testHasDiscrepancy(t, nodeConfig, "lun /vol/node1_iboot/node1_seed", "mapping") {
+		t.Fatalf("unmapped seed LUN is not reported")
+	}
+	cluster.InjectFault("LunMap", errors.New("connection reset"), 1, "_seed")
+	if err := LunRestoreMapping(nodeConfig); err == nil || !strings.Contains(err.Error(), "LunMap() failure") {
+		t.Fatalf("expected LunMap() failure, got %v", err)
+	}
+	if err := LunRestoreMapping(nodeConfig); err != nil {
+		t.Fatalf("LunRestoreMapping() failure: %s", err)
+	}
+	testVerifyClean(t, nodeConfig)
+	nodeConfig.Storage.IgroupName = "missing"
+	if err := LunRestoreMapping(nodeConfig); err == nil || !strings.Contains(err.Error(), "igroup \"missing\" not found") {
+		t.Fatalf("expected missing igroup failure, got %v", err)
+	}
+}
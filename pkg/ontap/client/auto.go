@@ -0,0 +1,918 @@
+package client
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/igor-feoktistov/go-ontap-sdk/ontap"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+// API features negotiated by "auto" API method
+const (
+	FeatureCore       = "core"
+	FeatureSnapmirror = "snapmirror"
+	FeatureFiles      = "files"
+	FeatureVolumeMove = "volumeMove"
+	FeatureLunCopy    = "lunCopy"
+	FeatureLunIO      = "lunIO"
+	FeatureNvme       = "nvme"
+)
+
+// restFeatures is minimum ONTAP version (generation, major) of REST endpoints used by OntapRestAPI per feature
+var restFeatures = []struct {
+	feature    string
+	generation int
+	major      int
+}{
+	{FeatureCore, 9, 6},
+	{FeatureSnapmirror, 9, 6},
+	{FeatureFiles, 9, 8},
+	{FeatureVolumeMove, 9, 8},
+	{FeatureLunCopy, 9, 10},
+	{FeatureLunIO, 9, 10},
+	{FeatureNvme, 9, 10},
+}
+
+// Capabilities is ONTAP API capabilities negotiated by "auto" API method,
+// features not available in REST API are served by ZAPI
+type Capabilities struct {
+	Host         string
+	Version      string
+	Generation   int
+	Major        int
+	Minor        int
+	ZapiVersion  string
+	Rest         bool
+	Zapi         bool
+	RestFeatures []string
+	ZapiFeatures []string
+}
+
+var (
+	capabilitiesMu sync.Mutex
+	capabilities   = make(map[string]*Capabilities)
+)
+
+// autoClient is "auto" API method client, it runs each feature with REST or ZAPI client as negotiated
+type autoClient struct {
+	caps       *Capabilities
+	rest       OntapClient
+	zapi       OntapClient
+	zapiConfig config.NodeConfig
+	mu         sync.Mutex
+}
+
+// ontapVersion is version part of /api/cluster
+type ontapVersion struct {
+	Version struct {
+		Full       string `json:"full"`
+		Generation int    `json:"generation"`
+		Major      int    `json:"major"`
+		Minor      int    `json:"minor"`
+	} `json:"version"`
+}
+
+// systemGetOntapiVersionParams is system-get-ontapi-version API parameters
+type systemGetOntapiVersionParams struct {
+	XMLName xml.Name `xml:"system-get-ontapi-version"`
+}
+
+// systemGetOntapiVersionResponse is system-get-ontapi-version API response
+type systemGetOntapiVersionResponse struct {
+	XMLName xml.Name `xml:"netapp"`
+	Results struct {
+		MajorVersion int `xml:"major-version"`
+		MinorVersion int `xml:"minor-version"`
+	} `xml:"results"`
+}
+
+// String formats negotiated capabilities for diagnostics
+func (caps *Capabilities) String() string {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	version := caps.Version
+	if version == "" {
+		version = "unknown version"
+	}
+	s := fmt.Sprintf("ONTAP %s at %s:", version, caps.Host)
+	if len(caps.RestFeatures) > 0 {
+		s += fmt.Sprintf(" REST API for %s;", strings.Join(caps.RestFeatures, ", "))
+	}
+	if len(caps.ZapiFeatures) > 0 {
+		if caps.Zapi {
+			s += fmt.Sprintf(" ZAPI %s for %s;", caps.ZapiVersion, strings.Join(caps.ZapiFeatures, ", "))
+		} else {
+			s += fmt.Sprintf(" %s not available (no REST endpoints and no ZAPI);", strings.Join(caps.ZapiFeatures, ", "))
+		}
+	}
+	return strings.TrimSuffix(s, ";")
+}
+
+// ApiMethod gets negotiated API method, either "rest", "zapi" or "mixed"
+func (caps *Capabilities) ApiMethod() string {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	switch {
+	case len(caps.ZapiFeatures) == 0:
+		return "rest"
+	case len(caps.RestFeatures) == 0:
+		return "zapi"
+	}
+	return "mixed"
+}
+
+// RestFeature checks if feature is served by REST API
+func (caps *Capabilities) RestFeature(feature string) bool {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	for _, f := range caps.RestFeatures {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// disableRestFeature moves feature to ZAPI after REST endpoint was found missing
+func (caps *Capabilities) disableRestFeature(feature string) {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	for i, f := range caps.RestFeatures {
+		if f == feature {
+			caps.RestFeatures = append(caps.RestFeatures[:i], caps.RestFeatures[i+1:]...)
+			caps.ZapiFeatures = append(caps.ZapiFeatures, feature)
+			sort.Strings(caps.ZapiFeatures)
+			return
+		}
+	}
+}
+
+// RestFeature checks if feature is served by REST API for node storage, "auto" API method is negotiated if not yet
+func RestFeature(nodeConfig *config.NodeConfig, feature string) bool {
+	switch nodeConfig.Storage.CdotCredentials.ApiMethod {
+	case "rest":
+		return true
+	case "auto":
+		if caps, err := NegotiateCapabilities(nodeConfig.Storage.CdotCredentials); err == nil {
+			return caps.RestFeature(feature)
+		}
+	}
+	return false
+}
+
+// NegotiateCapabilities probes cluster version and REST and ZAPI availability, the result is cached per host and user
+func NegotiateCapabilities(credentials config.CdotCredentials) (caps *Capabilities, err error) {
+	key := credentials.Host + "/" + credentials.User
+	capabilitiesMu.Lock()
+	caps = capabilities[key]
+	capabilitiesMu.Unlock()
+	if caps != nil {
+		return
+	}
+	caps = &Capabilities{Host: credentials.Host}
+	if err = caps.probeRest(credentials); err != nil {
+		err = fmt.Errorf("NegotiateCapabilities(): %s", err)
+		return
+	}
+	caps.probeZapi(credentials)
+	for _, f := range restFeatures {
+		if caps.Rest && (caps.Version == "" || caps.Generation > f.generation || (caps.Generation == f.generation && caps.Major >= f.major)) {
+			caps.RestFeatures = append(caps.RestFeatures, f.feature)
+		} else {
+			caps.ZapiFeatures = append(caps.ZapiFeatures, f.feature)
+		}
+	}
+	sort.Strings(caps.ZapiFeatures)
+	if len(caps.RestFeatures) == 0 && !caps.Zapi {
+		err = fmt.Errorf("NegotiateCapabilities(): neither REST nor ZAPI is available at %s", credentials.Host)
+		return
+	}
+	capabilitiesMu.Lock()
+	if cached := capabilities[key]; cached != nil {
+		caps = cached
+	} else {
+		capabilities[key] = caps
+	}
+	capabilitiesMu.Unlock()
+	return
+}
+
+// probeRest gets cluster version via REST API, REST is not available if /api returns anything but JSON,
+// SVM scoped accounts may not read cluster version in which case version is left unknown
+func (caps *Capabilities) probeRest(credentials config.CdotCredentials) (err error) {
+	httpClient := &http.Client{
+		Timeout: 60 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	var req *http.Request
+	if req, err = http.NewRequest("GET", "https://"+credentials.Host+"/api/cluster?fields=version", nil); err != nil {
+		return
+	}
+	req.SetBasicAuth(credentials.User, credentials.Password)
+	req.Header.Set("Accept", "application/json")
+	var res *http.Response
+	if res, err = httpClient.Do(req); err != nil {
+		err = fmt.Errorf("probeRest() failure: %s", err)
+		return
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		v := ontapVersion{}
+		if json.NewDecoder(res.Body).Decode(&v) == nil {
+			caps.Rest = true
+			caps.Version = v.Version.Full
+			caps.Generation, caps.Major, caps.Minor = v.Version.Generation, v.Version.Major, v.Version.Minor
+			if caps.Generation > 0 {
+				caps.Version = fmt.Sprintf("%d.%d.%d", caps.Generation, caps.Major, caps.Minor)
+			}
+		}
+	case http.StatusUnauthorized:
+		err = fmt.Errorf("probeRest() failure: authentication failed for user \"%s\"", credentials.User)
+	case http.StatusForbidden:
+		caps.Rest = strings.Contains(res.Header.Get("Content-Type"), "json")
+	}
+	return
+}
+
+// probeZapi gets ONTAPI version, ZAPI is not available if the call fails
+func (caps *Capabilities) probeZapi(credentials config.CdotCredentials) {
+	c := &OntapZAPI{
+		ZapiVersion: "1.0",
+		Client: ontap.NewClient(
+			"https://"+credentials.Host,
+			&ontap.ClientOptions{
+				BasicAuthUser:     credentials.User,
+				BasicAuthPassword: credentials.Password,
+				SSLVerify:         false,
+				Timeout:           60 * time.Second,
+				Version:           "1.0",
+			},
+		),
+	}
+	r := systemGetOntapiVersionResponse{}
+	if c.zapiCall(&systemGetOntapiVersionParams{}, &r) != nil {
+		return
+	}
+	caps.Zapi = true
+	caps.ZapiVersion = fmt.Sprintf("%d.%d", r.Results.MajorVersion, r.Results.MinorVersion)
+	if credentials.ZapiVersion != "" {
+		caps.ZapiVersion = credentials.ZapiVersion
+	}
+}
+
+// NewOntapAutoClient creates client for "auto" API method, plain ZAPI client is created if REST API is not available
+func NewOntapAutoClient(nodeConfig *config.NodeConfig) (ontap OntapClient, err error) {
+	var caps *Capabilities
+	if caps, err = NegotiateCapabilities(nodeConfig.Storage.CdotCredentials); err != nil {
+		return
+	}
+	zapiConfig := *nodeConfig
+	zapiConfig.Storage.CdotCredentials.ApiMethod = "zapi"
+	zapiConfig.Storage.CdotCredentials.ZapiVersion = caps.ZapiVersion
+	if caps.ApiMethod() == "zapi" {
+		if ontap, err = NewOntapZAPI(&zapiConfig); err == nil {
+			nodeConfig.Storage.SvmName = zapiConfig.Storage.SvmName
+		}
+		return
+	}
+	var rest *OntapRestAPI
+	if rest, err = NewOntapRestAPI(nodeConfig); err != nil {
+		return
+	}
+	zapiConfig.Storage.SvmName = nodeConfig.Storage.SvmName
+	ontap = &autoClient{caps: caps, rest: rest, zapiConfig: zapiConfig}
+	return
+}
+
+// isRestEndpointMissing checks if REST call failed for endpoint not implemented by cluster
+func isRestEndpointMissing(err error) bool {
+	return strings.Contains(err.Error(), "HTTP code=404") && strings.Contains(err.Error(), "REST code=\"3\"")
+}
+
+// zapiClient gets ZAPI client, the client is created on first use
+func (c *autoClient) zapiClient() (zapi OntapClient, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.zapi == nil {
+		if !c.caps.Zapi {
+			err = fmt.Errorf("NewOntapAutoClient(): ZAPI is not available at %s", c.caps.Host)
+			return
+		}
+		if c.zapi, err = NewOntapZAPI(&c.zapiConfig); err != nil {
+			return
+		}
+	}
+	zapi = c.zapi
+	return
+}
+
+// call runs feature call with REST client if REST serves the feature, otherwise or if REST endpoint
+// turns out to be missing the call runs with ZAPI client
+func (c *autoClient) call(feature string, fn func(api OntapClient) error) (err error) {
+	if c.caps.RestFeature(feature) {
+		if err = fn(c.rest); err == nil || !isRestEndpointMissing(err) || !c.caps.Zapi {
+			return
+		}
+		c.caps.disableRestFeature(feature)
+	}
+	var zapi OntapClient
+	if zapi, err = c.zapiClient(); err != nil {
+		return
+	}
+	return fn(zapi)
+}
+
+// Ping verifies REST session
+func (c *autoClient) Ping() error {
+	return c.rest.(pinger).Ping()
+}
+
+// GetAggregateMax runs GetAggregateMax with API negotiated for core feature
+func (c *autoClient) GetAggregateMax(nodeConfig *config.NodeConfig) (result string, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.GetAggregateMax(nodeConfig)
+		return
+	})
+	return
+}
+
+// VolumeExists runs VolumeExists with API negotiated for core feature
+func (c *autoClient) VolumeExists(volumeName string) (result bool, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.VolumeExists(volumeName)
+		return
+	})
+	return
+}
+
+// VolumeCreateSAN runs VolumeCreateSAN with API negotiated for core feature
+func (c *autoClient) VolumeCreateSAN(volumeName string, aggregateName string, volumeSize int) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.VolumeCreateSAN(volumeName, aggregateName, volumeSize)
+	})
+}
+
+// VolumeCreateNAS runs VolumeCreateNAS with API negotiated for core feature
+func (c *autoClient) VolumeCreateNAS(volumeName string, aggregateName string, exportPolicyName string, volumeSize int) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.VolumeCreateNAS(volumeName, aggregateName, exportPolicyName, volumeSize)
+	})
+}
+
+// VolumeCreateDP runs VolumeCreateDP with API negotiated for core feature
+func (c *autoClient) VolumeCreateDP(volumeName string, aggregateName string, volumeSize int) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.VolumeCreateDP(volumeName, aggregateName, volumeSize)
+	})
+}
+
+// VolumeDestroy runs VolumeDestroy with API negotiated for core feature
+func (c *autoClient) VolumeDestroy(volumeName string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.VolumeDestroy(volumeName)
+	})
+}
+
+// VolumeResize runs VolumeResize with API negotiated for core feature
+func (c *autoClient) VolumeResize(volumeName string, volumeSize int) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.VolumeResize(volumeName, volumeSize)
+	})
+}
+
+// VolumeGetAggregate runs VolumeGetAggregate with API negotiated for core feature
+func (c *autoClient) VolumeGetAggregate(volumeName string) (result string, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.VolumeGetAggregate(volumeName)
+		return
+	})
+	return
+}
+
+// VolumeMoveStart runs VolumeMoveStart with API negotiated for volumeMove feature
+func (c *autoClient) VolumeMoveStart(volumeName string, aggregateName string) error {
+	return c.call(FeatureVolumeMove, func(api OntapClient) error {
+		return api.VolumeMoveStart(volumeName, aggregateName)
+	})
+}
+
+// VolumeMoveGetStatus runs VolumeMoveGetStatus with API negotiated for volumeMove feature
+func (c *autoClient) VolumeMoveGetStatus(volumeName string) (result *VolumeMoveInfo, err error) {
+	err = c.call(FeatureVolumeMove, func(api OntapClient) (err error) {
+		result, err = api.VolumeMoveGetStatus(volumeName)
+		return
+	})
+	return
+}
+
+// ExportPolicyCreate runs ExportPolicyCreate with API negotiated for core feature
+func (c *autoClient) ExportPolicyCreate(exportPolicyName string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.ExportPolicyCreate(exportPolicyName)
+	})
+}
+
+// IgroupExists runs IgroupExists with API negotiated for core feature
+func (c *autoClient) IgroupExists(volumeName string) (result bool, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.IgroupExists(volumeName)
+		return
+	})
+	return
+}
+
+// IgroupCreate runs IgroupCreate with API negotiated for core feature
+func (c *autoClient) IgroupCreate(igroupName string, protocol string, osType string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.IgroupCreate(igroupName, protocol, osType)
+	})
+}
+
+// IgroupAddInitiator runs IgroupAddInitiator with API negotiated for core feature
+func (c *autoClient) IgroupAddInitiator(igroupName string, initiatorName string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.IgroupAddInitiator(igroupName, initiatorName)
+	})
+}
+
+// IgroupGetInitiators runs IgroupGetInitiators with API negotiated for core feature
+func (c *autoClient) IgroupGetInitiators(igroupName string) (result []string, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.IgroupGetInitiators(igroupName)
+		return
+	})
+	return
+}
+
+// IgroupDestroy runs IgroupDestroy with API negotiated for core feature
+func (c *autoClient) IgroupDestroy(igroupName string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.IgroupDestroy(igroupName)
+	})
+}
+
+// LunExists runs LunExists with API negotiated for core feature
+func (c *autoClient) LunExists(lunPath string) (result bool, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.LunExists(lunPath)
+		return
+	})
+	return
+}
+
+// IsLunMapped runs IsLunMapped with API negotiated for core feature
+func (c *autoClient) IsLunMapped(lunPath string, igroupName string) (result bool, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.IsLunMapped(lunPath, igroupName)
+		return
+	})
+	return
+}
+
+// LunGetMaps runs LunGetMaps with API negotiated for core feature
+func (c *autoClient) LunGetMaps(lunPath string) (result []LunMapInfo, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.LunGetMaps(lunPath)
+		return
+	})
+	return
+}
+
+// LunGetInfo runs LunGetInfo with API negotiated for core feature
+func (c *autoClient) LunGetInfo(lunPath string) (result *LunInfo, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.LunGetInfo(lunPath)
+		return
+	})
+	return
+}
+
+// LunGetList runs LunGetList with API negotiated for core feature
+func (c *autoClient) LunGetList(volumeName string) (result []string, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.LunGetList(volumeName)
+		return
+	})
+	return
+}
+
+// LunGetInfoList runs LunGetInfoList with API negotiated for core feature
+func (c *autoClient) LunGetInfoList(volumeName string) (result []LunInfo, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.LunGetInfoList(volumeName)
+		return
+	})
+	return
+}
+
+// LunSetComment runs LunSetComment with API negotiated for core feature
+func (c *autoClient) LunSetComment(lunPath string, lunComment string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.LunSetComment(lunPath, lunComment)
+	})
+}
+
+// LunRename runs LunRename with API negotiated for core feature
+func (c *autoClient) LunRename(lunPath string, newLunPath string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.LunRename(lunPath, newLunPath)
+	})
+}
+
+// LunCopy runs LunCopy with API negotiated for lunCopy feature
+func (c *autoClient) LunCopy(imagePath string, lunPath string) error {
+	return c.call(FeatureLunCopy, func(api OntapClient) error {
+		return api.LunCopy(imagePath, lunPath)
+	})
+}
+
+// LunResize runs LunResize with API negotiated for core feature
+func (c *autoClient) LunResize(lunPath string, lunSize int) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.LunResize(lunPath, lunSize)
+	})
+}
+
+// LunMap runs LunMap with API negotiated for core feature
+func (c *autoClient) LunMap(lunPath string, lunID int, igroupName string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.LunMap(lunPath, lunID, igroupName)
+	})
+}
+
+// LunUnmap runs LunUnmap with API negotiated for core feature
+func (c *autoClient) LunUnmap(lunPath string, igroupName string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.LunUnmap(lunPath, igroupName)
+	})
+}
+
+// LunCreate runs LunCreate with API negotiated for core feature
+func (c *autoClient) LunCreate(lunPath string, lunSize int, osType string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.LunCreate(lunPath, lunSize, osType)
+	})
+}
+
+// LunCreateFromFile runs LunCreateFromFile with API negotiated for core feature
+func (c *autoClient) LunCreateFromFile(volumeName string, filePath string, lunPath string, lunComment string, osType string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.LunCreateFromFile(volumeName, filePath, lunPath, lunComment, osType)
+	})
+}
+
+// LunCreateAndUpload runs LunCreateAndUpload with API negotiated for lunIO feature
+func (c *autoClient) LunCreateAndUpload(volumeName string, filePath string, fileSize int64, fileReader io.Reader, lunPath string, lunComment string, osType string, progress UploadProgress) error {
+	return c.call(FeatureLunIO, func(api OntapClient) error {
+		return api.LunCreateAndUpload(volumeName, filePath, fileSize, fileReader, lunPath, lunComment, osType, progress)
+	})
+}
+
+// LunUpload runs LunUpload with API negotiated for lunIO feature
+func (c *autoClient) LunUpload(lunPath string, fileReader io.Reader, fileSize int64, progress UploadProgress) error {
+	return c.call(FeatureLunIO, func(api OntapClient) error {
+		return api.LunUpload(lunPath, fileReader, fileSize, progress)
+	})
+}
+
+// LunDestroy runs LunDestroy with API negotiated for core feature
+func (c *autoClient) LunDestroy(lunPath string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.LunDestroy(lunPath)
+	})
+}
+
+// IscsiTargetGetName runs IscsiTargetGetName with API negotiated for core feature
+func (c *autoClient) IscsiTargetGetName() (result string, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.IscsiTargetGetName()
+		return
+	})
+	return
+}
+
+// DiscoverIscsiLIFs runs DiscoverIscsiLIFs with API negotiated for core feature
+func (c *autoClient) DiscoverIscsiLIFs(lunPath string, initiatorSubnet string) (result []string, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.DiscoverIscsiLIFs(lunPath, initiatorSubnet)
+		return
+	})
+	return
+}
+
+// IscsiInitiatorSetAuth runs IscsiInitiatorSetAuth with API negotiated for core feature
+func (c *autoClient) IscsiInitiatorSetAuth(initiatorName string, chapUser string, chapPassword string, outboundUser string, outboundPassword string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.IscsiInitiatorSetAuth(initiatorName, chapUser, chapPassword, outboundUser, outboundPassword)
+	})
+}
+
+// IscsiInitiatorDeleteAuth runs IscsiInitiatorDeleteAuth with API negotiated for core feature
+func (c *autoClient) IscsiInitiatorDeleteAuth(initiatorName string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.IscsiInitiatorDeleteAuth(initiatorName)
+	})
+}
+
+// FcpTargetGetName runs FcpTargetGetName with API negotiated for core feature
+func (c *autoClient) FcpTargetGetName() (result string, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.FcpTargetGetName()
+		return
+	})
+	return
+}
+
+// GetFcpLIFs runs GetFcpLIFs with API negotiated for core feature
+func (c *autoClient) GetFcpLIFs() (result []FcInterface, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.GetFcpLIFs()
+		return
+	})
+	return
+}
+
+// FileExists runs FileExists with API negotiated for files feature
+func (c *autoClient) FileExists(volumeName string, filePath string) (result bool, err error) {
+	err = c.call(FeatureFiles, func(api OntapClient) (err error) {
+		result, err = api.FileExists(volumeName, filePath)
+		return
+	})
+	return
+}
+
+// FileGetList runs FileGetList with API negotiated for files feature
+func (c *autoClient) FileGetList(volumeName string, dirPath string) (result []string, err error) {
+	err = c.call(FeatureFiles, func(api OntapClient) (err error) {
+		result, err = api.FileGetList(volumeName, dirPath)
+		return
+	})
+	return
+}
+
+// FileDelete runs FileDelete with API negotiated for files feature
+func (c *autoClient) FileDelete(volumName string, filePath string) error {
+	return c.call(FeatureFiles, func(api OntapClient) error {
+		return api.FileDelete(volumName, filePath)
+	})
+}
+
+// FileDownload runs FileDownload with API negotiated for files feature
+func (c *autoClient) FileDownload(volumeName string, filePath string) (result []byte, err error) {
+	err = c.call(FeatureFiles, func(api OntapClient) (err error) {
+		result, err = api.FileDownload(volumeName, filePath)
+		return
+	})
+	return
+}
+
+// FileUploadAPI runs FileUploadAPI with API negotiated for files feature
+func (c *autoClient) FileUploadAPI(volumeName string, filePath string, reader io.Reader) error {
+	return c.call(FeatureFiles, func(api OntapClient) error {
+		return api.FileUploadAPI(volumeName, filePath, reader)
+	})
+}
+
+// FileUploadNFS runs FileUploadNFS with API negotiated for core feature
+func (c *autoClient) FileUploadNFS(volumeName string, filePath string, reader io.Reader) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.FileUploadNFS(volumeName, filePath, reader)
+	})
+}
+
+// SnapshotGetList runs SnapshotGetList with API negotiated for core feature
+func (c *autoClient) SnapshotGetList(volumeName string) (result []string, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.SnapshotGetList(volumeName)
+		return
+	})
+	return
+}
+
+// SnapshotGetInfoList runs SnapshotGetInfoList with API negotiated for core feature
+func (c *autoClient) SnapshotGetInfoList(volumeName string) (result []SnapshotInfo, err error) {
+	err = c.call(FeatureCore, func(api OntapClient) (err error) {
+		result, err = api.SnapshotGetInfoList(volumeName)
+		return
+	})
+	return
+}
+
+// SnapshotCreate runs SnapshotCreate with API negotiated for core feature
+func (c *autoClient) SnapshotCreate(volumeName string, snapshotName string, snapshotComment string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.SnapshotCreate(volumeName, snapshotName, snapshotComment)
+	})
+}
+
+// SnapshotCreateGroup runs SnapshotCreateGroup with API negotiated for core feature
+func (c *autoClient) SnapshotCreateGroup(volumeNames []string, snapshotName string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.SnapshotCreateGroup(volumeNames, snapshotName)
+	})
+}
+
+// SnapshotSetComment runs SnapshotSetComment with API negotiated for core feature
+func (c *autoClient) SnapshotSetComment(volumeName string, snapshotName string, snapshotComment string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.SnapshotSetComment(volumeName, snapshotName, snapshotComment)
+	})
+}
+
+// SnapshotDelete runs SnapshotDelete with API negotiated for core feature
+func (c *autoClient) SnapshotDelete(volumeName string, snapshotName string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.SnapshotDelete(volumeName, snapshotName)
+	})
+}
+
+// SnapshotRestore runs SnapshotRestore with API negotiated for core feature
+func (c *autoClient) SnapshotRestore(volumeName string, snapshotName string) error {
+	return c.call(FeatureCore, func(api OntapClient) error {
+		return api.SnapshotRestore(volumeName, snapshotName)
+	})
+}
+
+// SnapmirrorGet runs SnapmirrorGet with API negotiated for snapmirror feature
+func (c *autoClient) SnapmirrorGet(destinationPath string) (result *SnapmirrorInfo, err error) {
+	err = c.call(FeatureSnapmirror, func(api OntapClient) (err error) {
+		result, err = api.SnapmirrorGet(destinationPath)
+		return
+	})
+	return
+}
+
+// SnapmirrorCreate runs SnapmirrorCreate with API negotiated for snapmirror feature
+func (c *autoClient) SnapmirrorCreate(sourcePath string, destinationPath string, policy string, schedule string) error {
+	return c.call(FeatureSnapmirror, func(api OntapClient) error {
+		return api.SnapmirrorCreate(sourcePath, destinationPath, policy, schedule)
+	})
+}
+
+// SnapmirrorModify runs SnapmirrorModify with API negotiated for snapmirror feature
+func (c *autoClient) SnapmirrorModify(destinationPath string, policy string, schedule string) error {
+	return c.call(FeatureSnapmirror, func(api OntapClient) error {
+		return api.SnapmirrorModify(destinationPath, policy, schedule)
+	})
+}
+
+// SnapmirrorUpdate runs SnapmirrorUpdate with API negotiated for snapmirror feature
+func (c *autoClient) SnapmirrorUpdate(destinationPath string) error {
+	return c.call(FeatureSnapmirror, func(api OntapClient) error {
+		return api.SnapmirrorUpdate(destinationPath)
+	})
+}
+
+// SnapmirrorBreak runs SnapmirrorBreak with API negotiated for snapmirror feature
+func (c *autoClient) SnapmirrorBreak(destinationPath string) error {
+	return c.call(FeatureSnapmirror, func(api OntapClient) error {
+		return api.SnapmirrorBreak(destinationPath)
+	})
+}
+
+// SnapmirrorDelete runs SnapmirrorDelete with API negotiated for snapmirror feature
+func (c *autoClient) SnapmirrorDelete(destinationPath string) error {
+	return c.call(FeatureSnapmirror, func(api OntapClient) error {
+		return api.SnapmirrorDelete(destinationPath)
+	})
+}
+
+// NvmeTargetGetNqn runs NvmeTargetGetNqn with API negotiated for nvme feature
+func (c *autoClient) NvmeTargetGetNqn(subsystemName string) (result string, err error) {
+	err = c.call(FeatureNvme, func(api OntapClient) (err error) {
+		result, err = api.NvmeTargetGetNqn(subsystemName)
+		return
+	})
+	return
+}
+
+// NvmeSubsystemExists runs NvmeSubsystemExists with API negotiated for nvme feature
+func (c *autoClient) NvmeSubsystemExists(subsystemName string) (result bool, err error) {
+	err = c.call(FeatureNvme, func(api OntapClient) (err error) {
+		result, err = api.NvmeSubsystemExists(subsystemName)
+		return
+	})
+	return
+}
+
+// NvmeSubsystemCreate runs NvmeSubsystemCreate with API negotiated for nvme feature
+func (c *autoClient) NvmeSubsystemCreate(subsystemName string, osType string) error {
+	return c.call(FeatureNvme, func(api OntapClient) error {
+		return api.NvmeSubsystemCreate(subsystemName, osType)
+	})
+}
+
+// NvmeSubsystemDestroy runs NvmeSubsystemDestroy with API negotiated for nvme feature
+func (c *autoClient) NvmeSubsystemDestroy(subsystemName string) error {
+	return c.call(FeatureNvme, func(api OntapClient) error {
+		return api.NvmeSubsystemDestroy(subsystemName)
+	})
+}
+
+// NvmeSubsystemAddHost runs NvmeSubsystemAddHost with API negotiated for nvme feature
+func (c *autoClient) NvmeSubsystemAddHost(subsystemName string, hostNqn string) error {
+	return c.call(FeatureNvme, func(api OntapClient) error {
+		return api.NvmeSubsystemAddHost(subsystemName, hostNqn)
+	})
+}
+
+// NvmeSubsystemGetHosts runs NvmeSubsystemGetHosts with API negotiated for nvme feature
+func (c *autoClient) NvmeSubsystemGetHosts(subsystemName string) (result []string, err error) {
+	err = c.call(FeatureNvme, func(api OntapClient) (err error) {
+		result, err = api.NvmeSubsystemGetHosts(subsystemName)
+		return
+	})
+	return
+}
+
+// NvmeNamespaceExists runs NvmeNamespaceExists with API negotiated for nvme feature
+func (c *autoClient) NvmeNamespaceExists(namespacePath string) (result bool, err error) {
+	err = c.call(FeatureNvme, func(api OntapClient) (err error) {
+		result, err = api.NvmeNamespaceExists(namespacePath)
+		return
+	})
+	return
+}
+
+// NvmeNamespaceGetInfo runs NvmeNamespaceGetInfo with API negotiated for nvme feature
+func (c *autoClient) NvmeNamespaceGetInfo(namespacePath string) (result *NvmeNamespaceInfo, err error) {
+	err = c.call(FeatureNvme, func(api OntapClient) (err error) {
+		result, err = api.NvmeNamespaceGetInfo(namespacePath)
+		return
+	})
+	return
+}
+
+// IsNvmeNamespaceMapped runs IsNvmeNamespaceMapped with API negotiated for nvme feature
+func (c *autoClient) IsNvmeNamespaceMapped(namespacePath string) (result bool, err error) {
+	err = c.call(FeatureNvme, func(api OntapClient) (err error) {
+		result, err = api.IsNvmeNamespaceMapped(namespacePath)
+		return
+	})
+	return
+}
+
+// NvmeNamespaceResize runs NvmeNamespaceResize with API negotiated for nvme feature
+func (c *autoClient) NvmeNamespaceResize(namespacePath string, namespaceSize int) error {
+	return c.call(FeatureNvme, func(api OntapClient) error {
+		return api.NvmeNamespaceResize(namespacePath, namespaceSize)
+	})
+}
+
+// NvmeNamespaceMap runs NvmeNamespaceMap with API negotiated for nvme feature
+func (c *autoClient) NvmeNamespaceMap(namespacePath string, subsystemName string) error {
+	return c.call(FeatureNvme, func(api OntapClient) error {
+		return api.NvmeNamespaceMap(namespacePath, subsystemName)
+	})
+}
+
+// NvmeNamespaceUnmap runs NvmeNamespaceUnmap with API negotiated for nvme feature
+func (c *autoClient) NvmeNamespaceUnmap(namespacePath string) error {
+	return c.call(FeatureNvme, func(api OntapClient) error {
+		return api.NvmeNamespaceUnmap(namespacePath)
+	})
+}
+
+// NvmeNamespaceCreate runs NvmeNamespaceCreate with API negotiated for nvme feature
+func (c *autoClient) NvmeNamespaceCreate(namespacePath string, namespaceSize int, osType string) error {
+	return c.call(FeatureNvme, func(api OntapClient) error {
+		return api.NvmeNamespaceCreate(namespacePath, namespaceSize, osType)
+	})
+}
+
+// NvmeNamespaceDestroy runs NvmeNamespaceDestroy with API negotiated for nvme feature
+func (c *autoClient) NvmeNamespaceDestroy(namespacePath string) error {
+	return c.call(FeatureNvme, func(api OntapClient) error {
+		return api.NvmeNamespaceDestroy(namespacePath)
+	})
+}
+
+// GetNvmeLIFs runs GetNvmeLIFs with API negotiated for nvme feature
+func (c *autoClient) GetNvmeLIFs() (result []string, err error) {
+	err = c.call(FeatureNvme, func(api OntapClient) (err error) {
+		result, err = api.GetNvmeLIFs()
+		return
+	})
+	return
+}
+
+// DiscoverNvmeLIFs runs DiscoverNvmeLIFs with API negotiated for nvme feature
+func (c *autoClient) DiscoverNvmeLIFs(namespacePath string, hostSubnet string) (result []string, err error) {
+	err = c.call(FeatureNvme, func(api OntapClient) (err error) {
+		result, err = api.DiscoverNvmeLIFs(namespacePath, hostSubnet)
+		return
+	})
+	return
+}
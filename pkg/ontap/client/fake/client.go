@@ -0,0 +1,1489 @@
+package fake
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
+)
+
+const (
+	// LUN size overhead for LunCreateAndUpload, matches REST client (base plus overhead)
+	lunSizeOverhead = 2 * 1024 * 1024
+	// volume move progress per VolumeMoveGetStatus call
+	volumeMoveStep = 50
+)
+
+// Client is in-memory cDOT client
+type Client struct {
+	cluster *Cluster
+}
+
+// Cluster gets in-memory cluster of the client
+func (c *Client) Cluster() *Cluster {
+	return c.cluster
+}
+
+// GetAggregateMax finds aggregate with maximum space available
+func (c *Client) GetAggregateMax(nodeConfig *config.NodeConfig) (aggregateName string, err error) {
+	if err = c.cluster.fault("GetAggregateMax", ""); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var names []string
+	for name := range c.cluster.Aggregates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	available := -1
+	for _, name := range names {
+		if free := c.cluster.Aggregates[name] - c.cluster.aggregateUsed(name); free > available {
+			aggregateName, available = name, free
+		}
+	}
+	if aggregateName == "" || (nodeConfig.Storage.BootLun.Size+nodeConfig.Storage.DataLun.Size)*2 > available {
+		err = fmt.Errorf("GetAggregateMax(): no aggregates found for requested storage size %dGB", (nodeConfig.Storage.BootLun.Size+nodeConfig.Storage.DataLun.Size)*2)
+	}
+	return
+}
+
+// VolumeExists checks if volume exists
+func (c *Client) VolumeExists(volumeName string) (exists bool, err error) {
+	if err = c.cluster.fault("VolumeExists", volumeName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	_, exists = c.cluster.volumes[volumeName]
+	return
+}
+
+// volumeCreate creates volume of given type
+func (c *Client) volumeCreate(method string, volumeName string, aggregateName string, volumeSize int, volumeType string) (err error) {
+	if err = c.cluster.fault(method, volumeName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	if _, exists := c.cluster.volumes[volumeName]; exists {
+		err = fmt.Errorf("%s() failure: volume \"%s\" already exists", method, volumeName)
+		return
+	}
+	aggregateSize, exists := c.cluster.Aggregates[aggregateName]
+	if !exists {
+		err = fmt.Errorf("%s() failure: aggregate \"%s\" not found", method, aggregateName)
+		return
+	}
+	if aggregateSize-c.cluster.aggregateUsed(aggregateName) < volumeSize {
+		err = fmt.Errorf("%s() failure: not enough space in aggregate \"%s\"", method, aggregateName)
+		return
+	}
+	c.cluster.volumes[volumeName] = &volume{
+		name:       volumeName,
+		aggregate:  aggregateName,
+		size:       volumeSize,
+		volumeType: volumeType,
+		luns:       make(map[string]*lun),
+		files:      make(map[string][]byte),
+		dirs:       make(map[string]bool),
+		namespaces: make(map[string]*namespace),
+	}
+	return
+}
+
+// VolumeCreateSAN creates volume for SAN
+func (c *Client) VolumeCreateSAN(volumeName string, aggregateName string, volumeSize int) (err error) {
+	return c.volumeCreate("VolumeCreateSAN", volumeName, aggregateName, volumeSize, "rw")
+}
+
+// VolumeCreateNAS creates volume for NAS
+func (c *Client) VolumeCreateNAS(volumeName string, aggregateName string, exportPolicyName string, volumeSize int) (err error) {
+	return c.volumeCreate("VolumeCreateNAS", volumeName, aggregateName, volumeSize, "rw")
+}
+
+// VolumeCreateDP creates data protection volume for SnapMirror destination
+func (c *Client) VolumeCreateDP(volumeName string, aggregateName string, volumeSize int) (err error) {
+	return c.volumeCreate("VolumeCreateDP", volumeName, aggregateName, volumeSize, "dp")
+}
+
+// VolumeDestroy deletes volume
+func (c *Client) VolumeDestroy(volumeName string) (err error) {
+	if err = c.cluster.fault("VolumeDestroy", volumeName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	delete(c.cluster.volumes, volumeName)
+	return
+}
+
+// VolumeResize sets volume new size
+func (c *Client) VolumeResize(volumeName string, volumeSize int) (err error) {
+	if err = c.cluster.fault("VolumeResize", volumeName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = c.cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("VolumeResize() failure: %s", err)
+		return
+	}
+	vol.size = volumeSize
+	return
+}
+
+// VolumeGetAggregate gets name of aggregate hosting volume
+func (c *Client) VolumeGetAggregate(volumeName string) (aggregateName string, err error) {
+	if err = c.cluster.fault("VolumeGetAggregate", volumeName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = c.cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("VolumeGetAggregate() failure: %s", err)
+		return
+	}
+	aggregateName = vol.aggregate
+	return
+}
+
+// VolumeMoveStart starts volume move, the move progresses on every VolumeMoveGetStatus call
+func (c *Client) VolumeMoveStart(volumeName string, aggregateName string) (err error) {
+	if err = c.cluster.fault("VolumeMoveStart", volumeName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = c.cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("VolumeMoveStart() failure: %s", err)
+		return
+	}
+	if _, exists := c.cluster.Aggregates[aggregateName]; !exists {
+		err = fmt.Errorf("VolumeMoveStart() failure: aggregate \"%s\" not found", aggregateName)
+		return
+	}
+	if vol.aggregate == aggregateName {
+		err = fmt.Errorf("VolumeMoveStart() failure: volume \"%s\" is already in aggregate \"%s\"", volumeName, aggregateName)
+		return
+	}
+	if vol.move != nil && vol.move.State == "replicating" {
+		err = fmt.Errorf("VolumeMoveStart() failure: volume \"%s\" move is in progress", volumeName)
+		return
+	}
+	vol.move = &client.VolumeMoveInfo{DestinationAggregate: aggregateName, State: "replicating"}
+	return
+}
+
+// VolumeMoveGetStatus gets volume move status
+func (c *Client) VolumeMoveGetStatus(volumeName string) (moveInfo *client.VolumeMoveInfo, err error) {
+	if err = c.cluster.fault("VolumeMoveGetStatus", volumeName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = c.cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("VolumeMoveGetStatus() failure: %s", err)
+		return
+	}
+	if vol.move == nil {
+		return
+	}
+	vol.advanceMove()
+	info := *vol.move
+	moveInfo = &info
+	return
+}
+
+// ExportPolicyCreate creates export-policy
+func (c *Client) ExportPolicyCreate(exportPolicyName string) (err error) {
+	if err = c.cluster.fault("ExportPolicyCreate", exportPolicyName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	c.cluster.exportPolicies[exportPolicyName] = true
+	return
+}
+
+// IgroupExists checks if iGroup exists
+func (c *Client) IgroupExists(igroupName string) (exists bool, err error) {
+	if err = c.cluster.fault("IgroupExists", igroupName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	_, exists = c.cluster.igroups[igroupName]
+	return
+}
+
+// IgroupCreate creates iGroup
+func (c *Client) IgroupCreate(igroupName string, protocol string, osType string) (err error) {
+	if err = c.cluster.fault("IgroupCreate", igroupName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	if _, exists := c.cluster.igroups[igroupName]; exists {
+		err = fmt.Errorf("IgroupCreate() failure: iGroup \"%s\" already exists", igroupName)
+		return
+	}
+	c.cluster.igroups[igroupName] = &igroup{protocol: protocol, osType: osType}
+	return
+}
+
+// IgroupAddInitiator adds initiator to iGroup, iSCSI initiators are rejected by FCP iGroup and WWPN's by iSCSI iGroup
+func (c *Client) IgroupAddInitiator(igroupName string, initiatorName string) (err error) {
+	if err = c.cluster.fault("IgroupAddInitiator", igroupName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	ig, exists := c.cluster.igroups[igroupName]
+	if !exists {
+		err = fmt.Errorf("IgroupAddInitiator() failure: iGroup \"%s\" not found", igroupName)
+		return
+	}
+	for _, initiator := range ig.initiators {
+		if initiator == initiatorName {
+			err = fmt.Errorf("IgroupAddInitiator() failure: initiator \"%s\" is already in iGroup \"%s\"", initiatorName, igroupName)
+			return
+		}
+	}
+	if iscsi := strings.HasPrefix(initiatorName, "iqn.") || strings.HasPrefix(initiatorName, "eui."); (iscsi && ig.protocol == "fcp") || (!iscsi && ig.protocol == "iscsi") {
+		err = fmt.Errorf("IgroupAddInitiator() failure: initiator \"%s\" does not match protocol \"%s\" of iGroup \"%s\"", initiatorName, ig.protocol, igroupName)
+		return
+	}
+	ig.initiators = append(ig.initiators, initiatorName)
+	return
+}
+
+// IgroupGetInitiators gets list of iGroup initiators
+func (c *Client) IgroupGetInitiators(igroupName string) (initiators []string, err error) {
+	initiators = []string{}
+	if err = c.cluster.fault("IgroupGetInitiators", igroupName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	ig, exists := c.cluster.igroups[igroupName]
+	if !exists {
+		err = fmt.Errorf("IgroupGetInitiators() failure: iGroup \"%s\" not found", igroupName)
+		return
+	}
+	initiators = append(initiators, ig.initiators...)
+	return
+}
+
+// IgroupDestroy deletes iGroup, iGroup with mapped LUN's can't be deleted
+func (c *Client) IgroupDestroy(igroupName string) (err error) {
+	if err = c.cluster.fault("IgroupDestroy", igroupName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	for _, vol := range c.cluster.volumes {
+		for _, l := range vol.luns {
+			if _, mapped := l.maps[igroupName]; mapped {
+				err = fmt.Errorf("IgroupDestroy() failure: iGroup \"%s\" has mapped LUN's", igroupName)
+				return
+			}
+		}
+	}
+	delete(c.cluster.igroups, igroupName)
+	return
+}
+
+// LunExists checks if LUN exists
+func (c *Client) LunExists(lunPath string) (exists bool, err error) {
+	if err = c.cluster.fault("LunExists", lunPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	_, _, lunErr := c.cluster.getLun(lunPath)
+	exists = (lunErr == nil)
+	return
+}
+
+// IsLunMapped checks if LUN is mapped to iGroup
+func (c *Client) IsLunMapped(lunPath string, igroupName string) (mapped bool, err error) {
+	if err = c.cluster.fault("IsLunMapped", lunPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var l *lun
+	if _, l, err = c.cluster.getLun(lunPath); err != nil {
+		err = fmt.Errorf("IsLunMapped() failure: %s", err)
+		return
+	}
+	_, mapped = l.maps[igroupName]
+	return
+}
+
+// LunGetMaps gets LUN mappings to iGroups with LUN ID's
+func (c *Client) LunGetMaps(lunPath string) (lunMaps []client.LunMapInfo, err error) {
+	lunMaps = []client.LunMapInfo{}
+	if err = c.cluster.fault("LunGetMaps", lunPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var l *lun
+	if _, l, err = c.cluster.getLun(lunPath); err != nil {
+		err = fmt.Errorf("LunGetMaps() failure: %s", err)
+		return
+	}
+	for igroupName, lunID := range l.maps {
+		lunMaps = append(lunMaps, client.LunMapInfo{IgroupName: igroupName, LunId: lunID})
+	}
+	sort.Slice(lunMaps, func(i, j int) bool {
+		return lunMaps[i].IgroupName < lunMaps[j].IgroupName
+	})
+	return
+}
+
+// lunInfo makes generic LUN info
+func lunInfo(lunPath string, l *lun) client.LunInfo {
+	return client.LunInfo{
+		Path:       lunPath,
+		Comment:    l.comment,
+		Size:       int(math.Round(float64(l.size) / gb)),
+		CreateTime: l.createTime,
+		Source:     l.source,
+	}
+}
+
+// LunGetInfo gets generic LUN attributes
+func (c *Client) LunGetInfo(lunPath string) (info *client.LunInfo, err error) {
+	if err = c.cluster.fault("LunGetInfo", lunPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var l *lun
+	if _, l, err = c.cluster.getLun(lunPath); err != nil {
+		err = fmt.Errorf("LunGetInfo() failure: %s", err)
+		return
+	}
+	lunInfo := lunInfo(lunPath, l)
+	info = &lunInfo
+	return
+}
+
+// LunGetList gets sorted list of LUN names in volume
+func (c *Client) LunGetList(volumeName string) (lunList []string, err error) {
+	lunList = []string{}
+	if err = c.cluster.fault("LunGetList", volumeName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = c.cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("LunGetList() failure: %s", err)
+		return
+	}
+	for name := range vol.luns {
+		lunList = append(lunList, name)
+	}
+	sort.Strings(lunList)
+	return
+}
+
+// LunGetInfoList gets info of LUN's in volume or in the whole SVM if volume name is empty
+func (c *Client) LunGetInfoList(volumeName string) (luns []client.LunInfo, err error) {
+	luns = []client.LunInfo{}
+	if err = c.cluster.fault("LunGetInfoList", volumeName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	for _, vol := range c.cluster.volumes {
+		if volumeName != "" && vol.name != volumeName {
+			continue
+		}
+		for name, l := range vol.luns {
+			luns = append(luns, lunInfo("/vol/"+vol.name+"/"+name, l))
+		}
+	}
+	sort.Slice(luns, func(i, j int) bool {
+		return luns[i].Path < luns[j].Path
+	})
+	return
+}
+
+// LunSetComment sets LUN comment
+func (c *Client) LunSetComment(lunPath string, lunComment string) (err error) {
+	if err = c.cluster.fault("LunSetComment", lunPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var l *lun
+	if _, l, err = c.cluster.getLun(lunPath); err != nil {
+		err = fmt.Errorf("LunSetComment() failure: %s", err)
+		return
+	}
+	l.comment = lunComment
+	return
+}
+
+// LunRename renames LUN, LUN keeps its data, comment, maps and creation time
+func (c *Client) LunRename(lunPath string, newLunPath string) (err error) {
+	if err = c.cluster.fault("LunRename", lunPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	var l *lun
+	if vol, l, err = c.cluster.getLun(lunPath); err != nil {
+		err = fmt.Errorf("LunRename() failure: %s", err)
+		return
+	}
+	createTime := l.createTime
+	if err = c.cluster.addLun("LunRename", newLunPath, l); err != nil {
+		return
+	}
+	l.createTime = createTime
+	delete(vol.luns, filepath.Base(lunPath))
+	return
+}
+
+// addLun adds LUN to volume, the caller must hold the lock
+func (cluster *Cluster) addLun(method string, lunPath string, l *lun) (err error) {
+	var volumeName, lunName string
+	var vol *volume
+	if volumeName, lunName, err = splitPath(lunPath); err != nil {
+		err = fmt.Errorf("%s() failure: %s", method, err)
+		return
+	}
+	if vol, err = cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("%s() failure: %s", method, err)
+		return
+	}
+	if _, exists := vol.luns[lunName]; exists {
+		err = fmt.Errorf("%s() failure: LUN \"%s\" already exists", method, lunPath)
+		return
+	}
+	if l.maps == nil {
+		l.maps = make(map[string]int)
+	}
+	l.createTime = time.Now()
+	vol.luns[lunName] = l
+	return
+}
+
+// LunCopy copies LUN, destination LUN inherits source LUN comment
+func (c *Client) LunCopy(imagePath string, lunPath string) (err error) {
+	if err = c.cluster.fault("LunCopy", lunPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var src *lun
+	if _, src, err = c.cluster.getLun(imagePath); err != nil {
+		err = fmt.Errorf("LunCopy() failure: %s", err)
+		return
+	}
+	dst := copyLun(src)
+	dst.source = imagePath
+	err = c.cluster.addLun("LunCopy", lunPath, dst)
+	return
+}
+
+// LunResize sets LUN new size
+func (c *Client) LunResize(lunPath string, lunSize int) (err error) {
+	if err = c.cluster.fault("LunResize", lunPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var l *lun
+	if _, l, err = c.cluster.getLun(lunPath); err != nil {
+		err = fmt.Errorf("LunResize() failure: %s", err)
+		return
+	}
+	if int64(lunSize)*gb < l.size {
+		err = fmt.Errorf("LunResize() failure: LUN \"%s\" can't be shrunk", lunPath)
+		return
+	}
+	l.size = int64(lunSize) * gb
+	return
+}
+
+// LunMap maps LUN to iGroup with LUN ID
+func (c *Client) LunMap(lunPath string, lunID int, igroupName string) (err error) {
+	if err = c.cluster.fault("LunMap", lunPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var l *lun
+	if _, l, err = c.cluster.getLun(lunPath); err != nil {
+		err = fmt.Errorf("LunMap() failure: %s", err)
+		return
+	}
+	if _, exists := c.cluster.igroups[igroupName]; !exists {
+		err = fmt.Errorf("LunMap() failure: iGroup \"%s\" not found", igroupName)
+		return
+	}
+	if _, mapped := l.maps[igroupName]; mapped {
+		err = fmt.Errorf("LunMap() failure: LUN \"%s\" is already mapped to iGroup \"%s\"", lunPath, igroupName)
+		return
+	}
+	for _, vol := range c.cluster.volumes {
+		for _, other := range vol.luns {
+			if id, mapped := other.maps[igroupName]; mapped && id == lunID {
+				err = fmt.Errorf("LunMap() failure: LUN ID %d is already used in iGroup \"%s\"", lunID, igroupName)
+				return
+			}
+		}
+	}
+	l.maps[igroupName] = lunID
+	return
+}
+
+// LunUnmap unmaps LUN from iGroup
+func (c *Client) LunUnmap(lunPath string, igroupName string) (err error) {
+	if err = c.cluster.fault("LunUnmap", lunPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var l *lun
+	if _, l, err = c.cluster.getLun(lunPath); err != nil {
+		err = fmt.Errorf("LunUnmap() failure: %s", err)
+		return
+	}
+	delete(l.maps, igroupName)
+	return
+}
+
+// LunCreate creates LUN
+func (c *Client) LunCreate(lunPath string, lunSize int, osType string) (err error) {
+	if err = c.cluster.fault("LunCreate", lunPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	err = c.cluster.addLun("LunCreate", lunPath, &lun{size: int64(lunSize) * gb, osType: osType})
+	return
+}
+
+// LunCreateFromFile creates LUN from file in volume
+func (c *Client) LunCreateFromFile(volumeName string, filePath string, lunPath string, lunComment string, osType string) (err error) {
+	if err = c.cluster.fault("LunCreateFromFile", lunPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = c.cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("LunCreateFromFile() failure: %s", err)
+		return
+	}
+	data, exists := vol.files[filePath]
+	if !exists {
+		err = fmt.Errorf("LunCreateFromFile() failure: file \"%s\" not found in volume \"%s\"", filePath, volumeName)
+		return
+	}
+	err = c.cluster.addLun("LunCreateFromFile", lunPath, &lun{comment: lunComment, size: int64(len(data)), osType: osType, data: append([]byte{}, data...)})
+	return
+}
+
+// readAll reads upload stream reporting progress
+func readAll(reader io.Reader, size int64, progress client.UploadProgress) (data []byte, err error) {
+	if data, err = ioutil.ReadAll(reader); err != nil {
+		return
+	}
+	if int64(len(data)) < size {
+		err = fmt.Errorf("short read: expected \"%d\" bytes, read \"%d\" bytes", size, len(data))
+		return
+	}
+	if progress != nil {
+		progress(int64(len(data)), size)
+	}
+	return
+}
+
+// LunCreateAndUpload creates LUN and uploads data to the LUN
+func (c *Client) LunCreateAndUpload(volumeName string, filePath string, fileSize int64, fileReader io.Reader, lunPath string, lunComment string, osType string, progress client.UploadProgress) (err error) {
+	if err = c.cluster.fault("LunCreateAndUpload", lunPath); err != nil {
+		return
+	}
+	var data []byte
+	if data, err = readAll(fileReader, fileSize, progress); err != nil {
+		err = fmt.Errorf("LunCreateAndUpload() failure: %s", err)
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	err = c.cluster.addLun("LunCreateAndUpload", lunPath, &lun{comment: lunComment, size: fileSize + lunSizeOverhead, osType: osType, data: data})
+	return
+}
+
+// LunUpload uploads data to existent LUN
+func (c *Client) LunUpload(lunPath string, fileReader io.Reader, fileSize int64, progress client.UploadProgress) (err error) {
+	if err = c.cluster.fault("LunUpload", lunPath); err != nil {
+		return
+	}
+	var data []byte
+	if data, err = readAll(fileReader, fileSize, progress); err != nil {
+		err = fmt.Errorf("LunUpload() failure: %s", err)
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var l *lun
+	if _, l, err = c.cluster.getLun(lunPath); err != nil {
+		err = fmt.Errorf("LunUpload() failure: %s", err)
+		return
+	}
+	if int64(len(data)) > l.size {
+		err = fmt.Errorf("LunUpload() failure: data size \"%d\" exceeds LUN size \"%d\"", len(data), l.size)
+		return
+	}
+	l.data = data
+	return
+}
+
+// LunDestroy deletes LUN, missing LUN is not an error
+func (c *Client) LunDestroy(lunPath string) (err error) {
+	if err = c.cluster.fault("LunDestroy", lunPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, _, err = c.cluster.getLun(lunPath); err != nil {
+		err = nil
+		return
+	}
+	delete(vol.luns, filepath.Base(lunPath))
+	return
+}
+
+// IscsiTargetGetName gets iSCSI target name
+func (c *Client) IscsiTargetGetName() (targetName string, err error) {
+	if err = c.cluster.fault("IscsiTargetGetName", ""); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	if c.cluster.IscsiTargetName == "" {
+		err = fmt.Errorf("IscsiTargetGetName() failure: iSCSI service is not running")
+		return
+	}
+	targetName = c.cluster.IscsiTargetName
+	return
+}
+
+// parseLif parses LIF address in "<ip>" or "<ip>/<netlen>" format, default network length is 24
+func parseLif(lif string) (ip net.IP, ipNet *net.IPNet) {
+	if !strings.Contains(lif, "/") {
+		lif = lif + "/24"
+	}
+	ip, ipNet, _ = net.ParseCIDR(lif)
+	return
+}
+
+// lifsInSubnet filters LIF's by subnet in CIDR format, all LIF's are returned for empty subnet
+func lifsInSubnet(lifs []string, subnet string) (subnetLifs []string, err error) {
+	subnetLifs = []string{}
+	var ipNet *net.IPNet
+	if subnet != "" {
+		if _, ipNet, err = net.ParseCIDR(subnet); err != nil {
+			return
+		}
+	}
+	for _, lif := range lifs {
+		if ip, _ := parseLif(lif); ip != nil && (ipNet == nil || ipNet.Contains(ip)) {
+			subnetLifs = append(subnetLifs, ip.String())
+		}
+	}
+	return
+}
+
+// DiscoverIscsiLIFs gets list of iSCSI interfaces in initiator subnet
+func (c *Client) DiscoverIscsiLIFs(lunPath string, initiatorSubnet string) (lifs []string, err error) {
+	if err = c.cluster.fault("DiscoverIscsiLIFs", lunPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	if lifs, err = lifsInSubnet(c.cluster.IscsiLIFs, initiatorSubnet); err != nil {
+		err = fmt.Errorf("DiscoverIscsiLIFs() failure: %s", err)
+	}
+	return
+}
+
+// IscsiInitiatorSetAuth sets CHAP authentication for iSCSI initiator
+func (c *Client) IscsiInitiatorSetAuth(initiatorName string, chapUser string, chapPassword string, outboundUser string, outboundPassword string) (err error) {
+	if err = c.cluster.fault("IscsiInitiatorSetAuth", initiatorName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	c.cluster.iscsiAuth[initiatorName] = chapUser
+	return
+}
+
+// IscsiInitiatorDeleteAuth deletes iSCSI initiator security record
+func (c *Client) IscsiInitiatorDeleteAuth(initiatorName string) (err error) {
+	if err = c.cluster.fault("IscsiInitiatorDeleteAuth", initiatorName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	delete(c.cluster.iscsiAuth, initiatorName)
+	return
+}
+
+// FcpTargetGetName gets FCP target node name
+func (c *Client) FcpTargetGetName() (targetName string, err error) {
+	if err = c.cluster.fault("FcpTargetGetName", ""); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	targetName = c.cluster.FcpTargetName
+	return
+}
+
+// GetFcpLIFs gets list of FCP interfaces
+func (c *Client) GetFcpLIFs() (lifs []client.FcInterface, err error) {
+	if err = c.cluster.fault("GetFcpLIFs", ""); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	lifs = append([]client.FcInterface{}, c.cluster.FcpLIFs...)
+	return
+}
+
+// FileExists checks if file exists in volume
+func (c *Client) FileExists(volumeName string, filePath string) (exists bool, err error) {
+	if err = c.cluster.fault("FileExists", volumeName+filePath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = c.cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("FileExists() failure: %s", err)
+		return
+	}
+	_, exists = vol.files[filePath]
+	return
+}
+
+// FileGetList gets sorted list of file names in volume directory
+func (c *Client) FileGetList(volumeName string, dirPath string) (fileList []string, err error) {
+	fileList = []string{}
+	if err = c.cluster.fault("FileGetList", volumeName+dirPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = c.cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("FileGetList() failure: %s", err)
+		return
+	}
+	for filePath := range vol.files {
+		if filepath.Dir(filePath) == filepath.Clean(dirPath) {
+			fileList = append(fileList, filepath.Base(filePath))
+		}
+	}
+	sort.Strings(fileList)
+	return
+}
+
+// FileDelete deletes file, missing file is not an error
+func (c *Client) FileDelete(volumeName string, filePath string) (err error) {
+	if err = c.cluster.fault("FileDelete", volumeName+filePath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = c.cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("FileDelete() failure: %s", err)
+		return
+	}
+	delete(vol.files, filePath)
+	return
+}
+
+// FileDownload gets file content
+func (c *Client) FileDownload(volumeName string, filePath string) (fileContent []byte, err error) {
+	if err = c.cluster.fault("FileDownload", volumeName+filePath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = c.cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("FileDownload() failure: %s", err)
+		return
+	}
+	data, exists := vol.files[filePath]
+	if !exists {
+		err = fmt.Errorf("FileDownload() failure: file \"%s\" not found in volume \"%s\"", filePath, volumeName)
+		return
+	}
+	fileContent = append([]byte{}, data...)
+	return
+}
+
+// fileUpload stores file content
+func (c *Client) fileUpload(method string, volumeName string, filePath string, reader io.Reader) (err error) {
+	if err = c.cluster.fault(method, volumeName+filePath); err != nil {
+		return
+	}
+	var data []byte
+	if data, err = ioutil.ReadAll(reader); err != nil {
+		err = fmt.Errorf("%s() failure: %s", method, err)
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = c.cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("%s() failure: %s", method, err)
+		return
+	}
+	vol.files[filePath] = data
+	return
+}
+
+// FileUploadAPI uploads file content
+func (c *Client) FileUploadAPI(volumeName string, filePath string, reader io.Reader) (err error) {
+	return c.fileUpload("FileUploadAPI", volumeName, filePath, reader)
+}
+
+// FileUploadNFS uploads file content
+func (c *Client) FileUploadNFS(volumeName string, filePath string, reader io.Reader) (err error) {
+	return c.fileUpload("FileUploadNFS", volumeName, filePath, reader)
+}
+
+// SnapshotGetList gets list of volume snapshot names in creation order
+func (c *Client) SnapshotGetList(volumeName string) (snapshots []string, err error) {
+	snapshots = []string{}
+	var infoList []client.SnapshotInfo
+	if infoList, err = c.SnapshotGetInfoList(volumeName); err != nil {
+		return
+	}
+	for _, info := range infoList {
+		snapshots = append(snapshots, info.Name)
+	}
+	return
+}
+
+// SnapshotGetInfoList gets list of volume snapshots with comment and create time
+func (c *Client) SnapshotGetInfoList(volumeName string) (snapshots []client.SnapshotInfo, err error) {
+	snapshots = []client.SnapshotInfo{}
+	if err = c.cluster.fault("SnapshotGetInfoList", volumeName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = c.cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("SnapshotGetInfoList() failure: %s", err)
+		return
+	}
+	for _, snap := range vol.snapshots {
+		snapshots = append(snapshots, snap.info)
+	}
+	return
+}
+
+// snapshotCreate takes volume snapshot, the caller must hold the lock
+func (cluster *Cluster) snapshotCreate(volumeName string, snapshotName string, snapshotComment string) (err error) {
+	var vol *volume
+	if vol, err = cluster.getVolume(volumeName); err != nil {
+		return
+	}
+	for _, snap := range vol.snapshots {
+		if snap.info.Name == snapshotName {
+			err = fmt.Errorf("snapshot \"%s\" already exists in volume \"%s\"", snapshotName, volumeName)
+			return
+		}
+	}
+	vol.snapshots = append(vol.snapshots, &snapshot{
+		info:       client.SnapshotInfo{Name: snapshotName, Comment: snapshotComment, CreateTime: time.Now()},
+		luns:       copyLuns(vol.luns),
+		files:      copyFiles(vol.files),
+		namespaces: copyNamespaces(vol.namespaces),
+	})
+	return
+}
+
+// SnapshotCreate takes volume snapshot
+func (c *Client) SnapshotCreate(volumeName string, snapshotName string, snapshotComment string) (err error) {
+	if err = c.cluster.fault("SnapshotCreate", volumeName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	if err = c.cluster.snapshotCreate(volumeName, snapshotName, snapshotComment); err != nil {
+		err = fmt.Errorf("SnapshotCreate() failure: %s", err)
+	}
+	return
+}
+
+// SnapshotCreateGroup takes consistency group snapshot of volumes
+func (c *Client) SnapshotCreateGroup(volumeNames []string, snapshotName string) (err error) {
+	if err = c.cluster.fault("SnapshotCreateGroup", strings.Join(volumeNames, ",")); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	for _, volumeName := range volumeNames {
+		if _, err = c.cluster.getVolume(volumeName); err != nil {
+			err = fmt.Errorf("SnapshotCreateGroup() failure: %s", err)
+			return
+		}
+	}
+	for _, volumeName := range volumeNames {
+		if err = c.cluster.snapshotCreate(volumeName, snapshotName, ""); err != nil {
+			err = fmt.Errorf("SnapshotCreateGroup() failure: %s", err)
+			return
+		}
+	}
+	return
+}
+
+// SnapshotSetComment sets snapshot comment
+func (c *Client) SnapshotSetComment(volumeName string, snapshotName string, snapshotComment string) (err error) {
+	if err = c.cluster.fault("SnapshotSetComment", volumeName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = c.cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("SnapshotSetComment() failure: %s", err)
+		return
+	}
+	for _, snap := range vol.snapshots {
+		if snap.info.Name == snapshotName {
+			snap.info.Comment = snapshotComment
+			return
+		}
+	}
+	err = fmt.Errorf("SnapshotSetComment() failure: snapshot \"%s\" not found in volume \"%s\"", snapshotName, volumeName)
+	return
+}
+
+// SnapshotDelete deletes volume snapshot
+func (c *Client) SnapshotDelete(volumeName string, snapshotName string) (err error) {
+	if err = c.cluster.fault("SnapshotDelete", volumeName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = c.cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("SnapshotDelete() failure: %s", err)
+		return
+	}
+	for i, snap := range vol.snapshots {
+		if snap.info.Name == snapshotName {
+			vol.snapshots = append(vol.snapshots[:i], vol.snapshots[i+1:]...)
+			return
+		}
+	}
+	err = fmt.Errorf("SnapshotDelete() failure: snapshot \"%s\" not found in volume \"%s\"", snapshotName, volumeName)
+	return
+}
+
+// SnapshotRestore restores volume from snapshot, newer snapshots are deleted and LUN's are restored unmapped
+func (c *Client) SnapshotRestore(volumeName string, snapshotName string) (err error) {
+	if err = c.cluster.fault("SnapshotRestore", volumeName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = c.cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("SnapshotRestore() failure: %s", err)
+		return
+	}
+	for i, snap := range vol.snapshots {
+		if snap.info.Name == snapshotName {
+			vol.luns = make(map[string]*lun)
+			for name, l := range snap.luns {
+				vol.luns[name] = copyLun(l)
+			}
+			vol.files = copyFiles(snap.files)
+			vol.namespaces = copyNamespaces(snap.namespaces)
+			for _, ns := range vol.namespaces {
+				ns.subsystem = ""
+			}
+			vol.snapshots = vol.snapshots[:i+1]
+			return
+		}
+	}
+	err = fmt.Errorf("SnapshotRestore() failure: snapshot \"%s\" not found in volume \"%s\"", snapshotName, volumeName)
+	return
+}
+
+// splitSnapmirrorPath splits "<svm>:<volume>" path
+func splitSnapmirrorPath(path string) (svm string, volumeName string, err error) {
+	parts := strings.SplitN(path, ":", 2)
+	if len(parts) != 2 {
+		err = fmt.Errorf("invalid SnapMirror path \"%s\"", path)
+		return
+	}
+	svm, volumeName = parts[0], parts[1]
+	return
+}
+
+// snapmirrorTransfer copies source volume content to destination volume if source cluster is known to the factory
+func (c *Client) snapmirrorTransfer(sourcePath string, destinationPath string) (err error) {
+	var sourceSvm, sourceVolumeName, destinationVolumeName string
+	if sourceSvm, sourceVolumeName, err = splitSnapmirrorPath(sourcePath); err != nil {
+		return
+	}
+	if _, destinationVolumeName, err = splitSnapmirrorPath(destinationPath); err != nil {
+		return
+	}
+	source := c.cluster.factory.svmCluster(sourceSvm)
+	if source == nil || source == c.cluster {
+		return
+	}
+	source.mu.Lock()
+	var luns map[string]*lun
+	var files map[string][]byte
+	var namespaces map[string]*namespace
+	var snapshots []*snapshot
+	if vol, volErr := source.getVolume(sourceVolumeName); volErr == nil {
+		luns, files, namespaces = copyLuns(vol.luns), copyFiles(vol.files), copyNamespaces(vol.namespaces)
+		for _, snap := range vol.snapshots {
+			snapshots = append(snapshots, &snapshot{info: snap.info, luns: copyLuns(snap.luns), files: copyFiles(snap.files), namespaces: copyNamespaces(snap.namespaces)})
+		}
+	} else {
+		err = volErr
+	}
+	source.mu.Unlock()
+	if err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = c.cluster.getVolume(destinationVolumeName); err != nil {
+		return
+	}
+	for _, l := range luns {
+		l.maps = make(map[string]int)
+	}
+	for _, ns := range namespaces {
+		ns.subsystem = ""
+	}
+	vol.luns, vol.files, vol.namespaces, vol.snapshots = luns, files, namespaces, snapshots
+	return
+}
+
+// SnapmirrorGet gets SnapMirror relationship info by destination path, returns nil if relationship does not exist
+func (c *Client) SnapmirrorGet(destinationPath string) (smInfo *client.SnapmirrorInfo, err error) {
+	if err = c.cluster.fault("SnapmirrorGet", destinationPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	if info, exists := c.cluster.snapmirrors[destinationPath]; exists {
+		infoCopy := *info
+		smInfo = &infoCopy
+	}
+	return
+}
+
+// SnapmirrorCreate creates and initializes SnapMirror relationship
+func (c *Client) SnapmirrorCreate(sourcePath string, destinationPath string, policy string, schedule string) (err error) {
+	if err = c.cluster.fault("SnapmirrorCreate", destinationPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	_, exists := c.cluster.snapmirrors[destinationPath]
+	c.cluster.mu.Unlock()
+	if exists {
+		err = fmt.Errorf("SnapmirrorCreate() failure: relationship \"%s\" already exists", destinationPath)
+		return
+	}
+	if err = c.snapmirrorTransfer(sourcePath, destinationPath); err != nil {
+		err = fmt.Errorf("SnapmirrorCreate() failure: %s", err)
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	c.cluster.snapmirrors[destinationPath] = &client.SnapmirrorInfo{
+		SourcePath:      sourcePath,
+		DestinationPath: destinationPath,
+		Policy:          policy,
+		Schedule:        schedule,
+		State:           "snapmirrored",
+		Healthy:         true,
+	}
+	return
+}
+
+// SnapmirrorModify modifies SnapMirror relationship policy and schedule
+func (c *Client) SnapmirrorModify(destinationPath string, policy string, schedule string) (err error) {
+	if err = c.cluster.fault("SnapmirrorModify", destinationPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	info, exists := c.cluster.snapmirrors[destinationPath]
+	if !exists {
+		err = fmt.Errorf("SnapmirrorModify() failure: relationship \"%s\" not found", destinationPath)
+		return
+	}
+	info.Policy, info.Schedule = policy, schedule
+	return
+}
+
+// SnapmirrorUpdate transfers source volume content to destination
+func (c *Client) SnapmirrorUpdate(destinationPath string) (err error) {
+	if err = c.cluster.fault("SnapmirrorUpdate", destinationPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	info, exists := c.cluster.snapmirrors[destinationPath]
+	var sourcePath, state string
+	if exists {
+		sourcePath, state = info.SourcePath, info.State
+	}
+	c.cluster.mu.Unlock()
+	if !exists {
+		err = fmt.Errorf("SnapmirrorUpdate() failure: relationship \"%s\" not found", destinationPath)
+		return
+	}
+	if state != "snapmirrored" {
+		err = fmt.Errorf("SnapmirrorUpdate() failure: relationship \"%s\" is in state \"%s\"", destinationPath, state)
+		return
+	}
+	if err = c.snapmirrorTransfer(sourcePath, destinationPath); err != nil {
+		err = fmt.Errorf("SnapmirrorUpdate() failure: %s", err)
+	}
+	return
+}
+
+// SnapmirrorBreak breaks SnapMirror relationship making destination volume writable
+func (c *Client) SnapmirrorBreak(destinationPath string) (err error) {
+	if err = c.cluster.fault("SnapmirrorBreak", destinationPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	info, exists := c.cluster.snapmirrors[destinationPath]
+	if !exists {
+		err = fmt.Errorf("SnapmirrorBreak() failure: relationship \"%s\" not found", destinationPath)
+		return
+	}
+	info.State = "broken_off"
+	if _, volumeName, pathErr := splitSnapmirrorPath(destinationPath); pathErr == nil {
+		if vol, volErr := c.cluster.getVolume(volumeName); volErr == nil {
+			vol.volumeType = "rw"
+		}
+	}
+	return
+}
+
+// SnapmirrorDelete deletes SnapMirror relationship
+func (c *Client) SnapmirrorDelete(destinationPath string) (err error) {
+	if err = c.cluster.fault("SnapmirrorDelete", destinationPath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	delete(c.cluster.snapmirrors, destinationPath)
+	return
+}
+
+// NvmeTargetGetNqn gets NVME subsystem target NQN
+func (c *Client) NvmeTargetGetNqn(subsystemName string) (targetNqn string, err error) {
+	if err = c.cluster.fault("NvmeTargetGetNqn", subsystemName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	if _, exists := c.cluster.subsystems[subsystemName]; !exists {
+		err = fmt.Errorf("NvmeTargetGetNqn() failure: NVME subsystem \"%s\" not found", subsystemName)
+		return
+	}
+	targetNqn = c.cluster.NvmeTargetNqn + "." + subsystemName
+	return
+}
+
+// NvmeSubsystemExists checks if NVME subsystem exists
+func (c *Client) NvmeSubsystemExists(subsystemName string) (exists bool, err error) {
+	if err = c.cluster.fault("NvmeSubsystemExists", subsystemName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	_, exists = c.cluster.subsystems[subsystemName]
+	return
+}
+
+// NvmeSubsystemCreate creates NVME subsystem
+func (c *Client) NvmeSubsystemCreate(subsystemName string, osType string) (err error) {
+	if err = c.cluster.fault("NvmeSubsystemCreate", subsystemName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	if _, exists := c.cluster.subsystems[subsystemName]; exists {
+		err = fmt.Errorf("NvmeSubsystemCreate() failure: NVME subsystem \"%s\" already exists", subsystemName)
+		return
+	}
+	c.cluster.subsystems[subsystemName] = &subsystem{osType: osType}
+	return
+}
+
+// NvmeSubsystemDestroy deletes NVME subsystem, subsystem with mapped namespaces can't be deleted
+func (c *Client) NvmeSubsystemDestroy(subsystemName string) (err error) {
+	if err = c.cluster.fault("NvmeSubsystemDestroy", subsystemName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	for _, vol := range c.cluster.volumes {
+		for _, ns := range vol.namespaces {
+			if ns.subsystem == subsystemName {
+				err = fmt.Errorf("NvmeSubsystemDestroy() failure: NVME subsystem \"%s\" has mapped namespaces", subsystemName)
+				return
+			}
+		}
+	}
+	delete(c.cluster.subsystems, subsystemName)
+	return
+}
+
+// NvmeSubsystemAddHost adds host NQN to NVME subsystem
+func (c *Client) NvmeSubsystemAddHost(subsystemName string, hostNqn string) (err error) {
+	if err = c.cluster.fault("NvmeSubsystemAddHost", subsystemName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	ss, exists := c.cluster.subsystems[subsystemName]
+	if !exists {
+		err = fmt.Errorf("NvmeSubsystemAddHost() failure: NVME subsystem \"%s\" not found", subsystemName)
+		return
+	}
+	for _, host := range ss.hosts {
+		if host == hostNqn {
+			return
+		}
+	}
+	ss.hosts = append(ss.hosts, hostNqn)
+	return
+}
+
+// NvmeSubsystemGetHosts gets NQN's of NVME subsystem hosts
+func (c *Client) NvmeSubsystemGetHosts(subsystemName string) (hostNqns []string, err error) {
+	hostNqns = []string{}
+	if err = c.cluster.fault("NvmeSubsystemGetHosts", subsystemName); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	ss, exists := c.cluster.subsystems[subsystemName]
+	if !exists {
+		err = fmt.Errorf("NvmeSubsystemGetHosts() failure: NVME subsystem \"%s\" not found", subsystemName)
+		return
+	}
+	hostNqns = append(hostNqns, ss.hosts...)
+	return
+}
+
+// NvmeNamespaceExists checks if NVME namespace exists
+func (c *Client) NvmeNamespaceExists(namespacePath string) (exists bool, err error) {
+	if err = c.cluster.fault("NvmeNamespaceExists", namespacePath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	_, _, nsErr := c.cluster.getNamespace(namespacePath)
+	exists = (nsErr == nil)
+	return
+}
+
+// NvmeNamespaceGetInfo gets generic NVME namespace attributes
+func (c *Client) NvmeNamespaceGetInfo(namespacePath string) (info *client.NvmeNamespaceInfo, err error) {
+	if err = c.cluster.fault("NvmeNamespaceGetInfo", namespacePath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var ns *namespace
+	if _, ns, err = c.cluster.getNamespace(namespacePath); err != nil {
+		err = fmt.Errorf("NvmeNamespaceGetInfo() failure: %s", err)
+		return
+	}
+	info = &client.NvmeNamespaceInfo{
+		Comment: ns.comment,
+		Size:    int(math.Round(float64(ns.size) / gb)),
+	}
+	return
+}
+
+// IsNvmeNamespaceMapped checks if NVME namespace is mapped to subsystem
+func (c *Client) IsNvmeNamespaceMapped(namespacePath string) (mapped bool, err error) {
+	if err = c.cluster.fault("IsNvmeNamespaceMapped", namespacePath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var ns *namespace
+	if _, ns, err = c.cluster.getNamespace(namespacePath); err != nil {
+		err = fmt.Errorf("IsNvmeNamespaceMapped() failure: %s", err)
+		return
+	}
+	mapped = (ns.subsystem != "")
+	return
+}
+
+// NvmeNamespaceResize sets NVME namespace new size
+func (c *Client) NvmeNamespaceResize(namespacePath string, namespaceSize int) (err error) {
+	if err = c.cluster.fault("NvmeNamespaceResize", namespacePath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var ns *namespace
+	if _, ns, err = c.cluster.getNamespace(namespacePath); err != nil {
+		err = fmt.Errorf("NvmeNamespaceResize() failure: %s", err)
+		return
+	}
+	ns.size = int64(namespaceSize) * gb
+	return
+}
+
+// NvmeNamespaceMap maps NVME namespace to subsystem
+func (c *Client) NvmeNamespaceMap(namespacePath string, subsystemName string) (err error) {
+	if err = c.cluster.fault("NvmeNamespaceMap", namespacePath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var ns *namespace
+	if _, ns, err = c.cluster.getNamespace(namespacePath); err != nil {
+		err = fmt.Errorf("NvmeNamespaceMap() failure: %s", err)
+		return
+	}
+	if _, exists := c.cluster.subsystems[subsystemName]; !exists {
+		err = fmt.Errorf("NvmeNamespaceMap() failure: NVME subsystem \"%s\" not found", subsystemName)
+		return
+	}
+	if ns.subsystem != "" {
+		err = fmt.Errorf("NvmeNamespaceMap() failure: NVME namespace \"%s\" is already mapped", namespacePath)
+		return
+	}
+	ns.subsystem = subsystemName
+	return
+}
+
+// NvmeNamespaceUnmap unmaps NVME namespace from subsystem
+func (c *Client) NvmeNamespaceUnmap(namespacePath string) (err error) {
+	if err = c.cluster.fault("NvmeNamespaceUnmap", namespacePath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var ns *namespace
+	if _, ns, err = c.cluster.getNamespace(namespacePath); err != nil {
+		err = fmt.Errorf("NvmeNamespaceUnmap() failure: %s", err)
+		return
+	}
+	ns.subsystem = ""
+	return
+}
+
+// NvmeNamespaceCreate creates NVME namespace
+func (c *Client) NvmeNamespaceCreate(namespacePath string, namespaceSize int, osType string) (err error) {
+	if err = c.cluster.fault("NvmeNamespaceCreate", namespacePath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var volumeName, namespaceName string
+	var vol *volume
+	if volumeName, namespaceName, err = splitPath(namespacePath); err != nil {
+		err = fmt.Errorf("NvmeNamespaceCreate() failure: %s", err)
+		return
+	}
+	if vol, err = c.cluster.getVolume(volumeName); err != nil {
+		err = fmt.Errorf("NvmeNamespaceCreate() failure: %s", err)
+		return
+	}
+	if _, exists := vol.namespaces[namespaceName]; exists {
+		err = fmt.Errorf("NvmeNamespaceCreate() failure: NVME namespace \"%s\" already exists", namespacePath)
+		return
+	}
+	vol.namespaces[namespaceName] = &namespace{size: int64(namespaceSize) * gb, osType: osType}
+	return
+}
+
+// NvmeNamespaceDestroy deletes NVME namespace, missing namespace is not an error
+func (c *Client) NvmeNamespaceDestroy(namespacePath string) (err error) {
+	if err = c.cluster.fault("NvmeNamespaceDestroy", namespacePath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	var vol *volume
+	if vol, _, err = c.cluster.getNamespace(namespacePath); err != nil {
+		err = nil
+		return
+	}
+	delete(vol.namespaces, filepath.Base(namespacePath))
+	return
+}
+
+// GetNvmeLIFs gets list of NVME interfaces
+func (c *Client) GetNvmeLIFs() (lifs []string, err error) {
+	if err = c.cluster.fault("GetNvmeLIFs", ""); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	lifs, err = lifsInSubnet(c.cluster.NvmeLIFs, "")
+	return
+}
+
+// DiscoverNvmeLIFs gets list of NVME interfaces in host subnet
+func (c *Client) DiscoverNvmeLIFs(namespacePath string, hostSubnet string) (lifs []string, err error) {
+	if err = c.cluster.fault("DiscoverNvmeLIFs", namespacePath); err != nil {
+		return
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	if lifs, err = lifsInSubnet(c.cluster.NvmeLIFs, hostSubnet); err != nil {
+		err = fmt.Errorf("DiscoverNvmeLIFs() failure: %s", err)
+	}
+	return
+}
+
+// compile time check of client.OntapClient implementation
+var _ client.OntapClient = (*Client)(nil)
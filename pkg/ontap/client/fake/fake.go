@@ -0,0 +1,367 @@
+// Package fake is stateful in-memory implementation of client.OntapClient for offline testing of pkg/ontap
+package fake
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
+)
+
+const (
+	gb = 1024 * 1024 * 1024
+	// DefaultAggregateSize is size of aggregate in GB created by NewCluster
+	DefaultAggregateSize = 10240
+)
+
+// Factory keeps in-memory clusters keyed by cDOT host and SVM name,
+// use client.SetClientFactory(factory.NewClient) to make pkg/ontap functions run against in-memory clusters
+type Factory struct {
+	mu       sync.Mutex
+	clusters map[string]*Cluster
+}
+
+// Cluster is in-memory SVM state shared by all clients connected to the same host and SVM,
+// iSCSI and NVME LIF's are in "<ip>" or "<ip>/<netlen>" format
+type Cluster struct {
+	mu              sync.Mutex
+	factory         *Factory
+	Host            string
+	Svm             string
+	Aggregates      map[string]int
+	IscsiTargetName string
+	IscsiLIFs       []string
+	FcpTargetName   string
+	FcpLIFs         []client.FcInterface
+	NvmeTargetNqn   string
+	NvmeLIFs        []string
+	volumes         map[string]*volume
+	igroups         map[string]*igroup
+	exportPolicies  map[string]bool
+	iscsiAuth       map[string]string
+	subsystems      map[string]*subsystem
+	snapmirrors     map[string]*client.SnapmirrorInfo
+	faults          map[string][]*Fault
+	calls           []string
+}
+
+// Fault is error returned by cluster method instead of the call, Count limits number of failed calls (zero is unlimited)
+type Fault struct {
+	Method string
+	Err    error
+	Count  int
+	Match  string
+}
+
+type volume struct {
+	name       string
+	aggregate  string
+	size       int
+	volumeType string
+	luns       map[string]*lun
+	files      map[string][]byte
+	dirs       map[string]bool
+	namespaces map[string]*namespace
+	snapshots  []*snapshot
+	move       *client.VolumeMoveInfo
+}
+
+type lun struct {
+	comment    string
+	size       int64
+	osType     string
+	data       []byte
+	createTime time.Time
+	source     string
+	maps       map[string]int
+}
+
+type namespace struct {
+	comment   string
+	size      int64
+	osType    string
+	subsystem string
+}
+
+type igroup struct {
+	protocol   string
+	osType     string
+	initiators []string
+}
+
+type subsystem struct {
+	osType string
+	hosts  []string
+}
+
+type snapshot struct {
+	info       client.SnapshotInfo
+	luns       map[string]*lun
+	files      map[string][]byte
+	namespaces map[string]*namespace
+}
+
+// NewFactory creates empty factory
+func NewFactory() *Factory {
+	return &Factory{clusters: make(map[string]*Cluster)}
+}
+
+// Cluster gets in-memory cluster by host and SVM name, cluster is created on first use
+func (f *Factory) Cluster(host string, svm string) *Cluster {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := host + "/" + svm
+	if cluster, ok := f.clusters[key]; ok {
+		return cluster
+	}
+	cluster := NewCluster(host, svm)
+	cluster.factory = f
+	f.clusters[key] = cluster
+	return cluster
+}
+
+// NewClient is client.ClientFactory backed by in-memory clusters
+func (f *Factory) NewClient(nodeConfig *config.NodeConfig) (client.OntapClient, error) {
+	if nodeConfig.Storage.CdotCredentials.Host == "" {
+		return nil, fmt.Errorf("NewClient(): expected cdotCredentials.host in storage configuration")
+	}
+	cluster := f.Cluster(nodeConfig.Storage.CdotCredentials.Host, nodeConfig.Storage.SvmName)
+	if err := cluster.fault("NewClient", nodeConfig.Storage.SvmName); err != nil {
+		return nil, err
+	}
+	return &Client{cluster: cluster}, nil
+}
+
+// svmCluster finds cluster by SVM name, used to resolve SnapMirror source paths
+func (f *Factory) svmCluster(svm string) *Cluster {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, cluster := range f.clusters {
+		if cluster.Svm == svm {
+			return cluster
+		}
+	}
+	return nil
+}
+
+// NewCluster creates standalone in-memory cluster with a single aggregate and iSCSI, FCP and NVME services
+func NewCluster(host string, svm string) *Cluster {
+	return &Cluster{
+		Host:            host,
+		Svm:             svm,
+		Aggregates:      map[string]int{"aggr1": DefaultAggregateSize},
+		IscsiTargetName: "iqn.1992-08.com.netapp:sn.fake:vs." + svm,
+		FcpTargetName:   "20:00:00:a0:98:00:00:01",
+		NvmeTargetNqn:   "nqn.1992-08.com.netapp:sn.fake:subsystem",
+		volumes:         make(map[string]*volume),
+		igroups:         make(map[string]*igroup),
+		exportPolicies:  make(map[string]bool),
+		iscsiAuth:       make(map[string]string),
+		subsystems:      make(map[string]*subsystem),
+		snapmirrors:     make(map[string]*client.SnapmirrorInfo),
+		faults:          make(map[string][]*Fault),
+	}
+}
+
+// InjectFault makes method calls fail with given error, match (if not empty) limits the fault
+// to calls with the first argument (i.e. volume name or LUN path) containing match,
+// REST simulator requests are faulted as "<METHOD> <route>" (i.e. "POST /api/storage/luns"),
+// *RestError sets HTTP status and REST error code of the failed request
+func (cluster *Cluster) InjectFault(method string, err error, count int, match string) {
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	cluster.faults[method] = append(cluster.faults[method], &Fault{Method: method, Err: err, Count: count, Match: match})
+}
+
+// ClearFaults removes all injected faults
+func (cluster *Cluster) ClearFaults() {
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	cluster.faults = make(map[string][]*Fault)
+}
+
+// Calls gets history of cluster method calls in "Method(arg)" format
+func (cluster *Cluster) Calls() []string {
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	return append([]string{}, cluster.calls...)
+}
+
+// Volumes gets sorted list of volume names
+func (cluster *Cluster) Volumes() (volumes []string) {
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	for name := range cluster.volumes {
+		volumes = append(volumes, name)
+	}
+	sort.Strings(volumes)
+	return
+}
+
+// LunData gets content of LUN
+func (cluster *Cluster) LunData(lunPath string) (data []byte, err error) {
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	var l *lun
+	if _, l, err = cluster.getLun(lunPath); err != nil {
+		return
+	}
+	data = append([]byte{}, l.data...)
+	return
+}
+
+// IgroupProtocol gets protocol of iGroup
+func (cluster *Cluster) IgroupProtocol(igroupName string) (protocol string, err error) {
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	ig, exists := cluster.igroups[igroupName]
+	if !exists {
+		err = fmt.Errorf("iGroup \"%s\" not found", igroupName)
+		return
+	}
+	protocol = ig.protocol
+	return
+}
+
+// fault records method call and returns injected fault if any, the caller must not hold the lock
+func (cluster *Cluster) fault(method string, arg string) error {
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	cluster.calls = append(cluster.calls, method+"("+arg+")")
+	for _, f := range cluster.faults[method] {
+		if f.Match != "" && !strings.Contains(arg, f.Match) {
+			continue
+		}
+		if f.Count < 0 {
+			continue
+		}
+		if f.Count > 0 {
+			f.Count--
+			if f.Count == 0 {
+				f.Count = -1
+			}
+		}
+		if _, ok := f.Err.(*RestError); ok {
+			return f.Err
+		}
+		return fmt.Errorf("%s() failure: %s", method, f.Err)
+	}
+	return nil
+}
+
+// advanceMove progresses volume move in flight, the move completes in two steps
+func (vol *volume) advanceMove() {
+	if vol.move == nil || vol.move.State != "replicating" {
+		return
+	}
+	vol.move.PercentComplete += volumeMoveStep
+	if vol.move.PercentComplete >= 100 {
+		vol.move.PercentComplete = 100
+		vol.move.State = "success"
+		vol.aggregate = vol.move.DestinationAggregate
+	}
+}
+
+// splitPath splits "/vol/<volume>/<name>" path into volume name and name
+func splitPath(path string) (volumeName string, name string, err error) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/vol/"), "/", 2)
+	if !strings.HasPrefix(path, "/vol/") || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		err = fmt.Errorf("invalid path \"%s\"", path)
+		return
+	}
+	volumeName, name = parts[0], parts[1]
+	return
+}
+
+// getVolume gets volume by name, the caller must hold the lock
+func (cluster *Cluster) getVolume(volumeName string) (vol *volume, err error) {
+	var ok bool
+	if vol, ok = cluster.volumes[volumeName]; !ok {
+		err = fmt.Errorf("volume \"%s\" not found", volumeName)
+	}
+	return
+}
+
+// getLun gets LUN by path, the caller must hold the lock
+func (cluster *Cluster) getLun(lunPath string) (vol *volume, l *lun, err error) {
+	var volumeName, lunName string
+	if volumeName, lunName, err = splitPath(lunPath); err != nil {
+		return
+	}
+	if vol, err = cluster.getVolume(volumeName); err != nil {
+		return
+	}
+	var ok bool
+	if l, ok = vol.luns[lunName]; !ok {
+		err = fmt.Errorf("LUN \"%s\" not found", lunPath)
+	}
+	return
+}
+
+// getNamespace gets NVME namespace by path, the caller must hold the lock
+func (cluster *Cluster) getNamespace(namespacePath string) (vol *volume, ns *namespace, err error) {
+	var volumeName, namespaceName string
+	if volumeName, namespaceName, err = splitPath(namespacePath); err != nil {
+		return
+	}
+	if vol, err = cluster.getVolume(volumeName); err != nil {
+		return
+	}
+	var ok bool
+	if ns, ok = vol.namespaces[namespaceName]; !ok {
+		err = fmt.Errorf("NVME namespace \"%s\" not found", namespacePath)
+	}
+	return
+}
+
+// aggregateUsed gets space in GB used by volumes in aggregate, the caller must hold the lock
+func (cluster *Cluster) aggregateUsed(aggregateName string) (used int) {
+	for _, vol := range cluster.volumes {
+		if vol.aggregate == aggregateName {
+			used += vol.size
+		}
+	}
+	return
+}
+
+func copyLun(l *lun) *lun {
+	c := *l
+	c.data = append([]byte{}, l.data...)
+	c.maps = make(map[string]int)
+	return &c
+}
+
+func copyLuns(luns map[string]*lun) map[string]*lun {
+	c := make(map[string]*lun)
+	for name, l := range luns {
+		c[name] = copyLun(l)
+		for igroupName, lunID := range l.maps {
+			c[name].maps[igroupName] = lunID
+		}
+	}
+	return c
+}
+
+func copyFiles(files map[string][]byte) map[string][]byte {
+	c := make(map[string][]byte)
+	for name, data := range files {
+		c[name] = append([]byte{}, data...)
+	}
+	return c
+}
+
+func copyNamespaces(namespaces map[string]*namespace) map[string]*namespace {
+	c := make(map[string]*namespace)
+	for name, ns := range namespaces {
+		nsCopy := *ns
+		c[name] = &nsCopy
+	}
+	return c
+}
@@ -0,0 +1,1521 @@
+package fake
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/igor-feoktistov/go-ontap-rest/ontap"
+)
+
+// nameRef is REST object reference in request body
+type nameRef struct {
+	Name string `json:"name"`
+	Uuid string `json:"uuid"`
+}
+
+// sizeGB converts size in bytes to GB rounding up
+func sizeGB(size int64) int {
+	return int((size + gb - 1) / gb)
+}
+
+// svmRef makes SVM reference record
+func (s *RestServer) svmRef() record {
+	return record{"name": s.cluster.Svm, "uuid": uuidFor("svm", s.cluster.Svm)}
+}
+
+// findRecord finds record by UUID
+func findRecord(records []record, uuid string, kind string) (rec record, err error) {
+	for _, r := range records {
+		if r["uuid"] == uuid {
+			rec = r
+			return
+		}
+	}
+	err = notFound("%s \"%s\" not found", kind, uuid)
+	return
+}
+
+// recordName gets record name
+func recordName(rec record) string {
+	name, _ := rec["name"].(string)
+	return name
+}
+
+// single makes single record response
+func single(status int, rec record) (int, interface{}, error) {
+	return status, record{"num_records": 1, "records": []record{rec}}, nil
+}
+
+// svmRecords makes SVM records with aggregates
+func (s *RestServer) svmRecords() (records []record) {
+	s.cluster.mu.Lock()
+	defer s.cluster.mu.Unlock()
+	var names []string
+	for name := range s.cluster.Aggregates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	aggregates := []record{}
+	for _, name := range names {
+		aggregates = append(aggregates, record{
+			"name":           name,
+			"uuid":           uuidFor("aggregate", name),
+			"state":          "online",
+			"type":           "hdd",
+			"available_size": int64(s.cluster.Aggregates[name]-s.cluster.aggregateUsed(name)) * gb,
+		})
+	}
+	rec := s.svmRef()
+	rec["state"] = "running"
+	rec["aggregates"] = aggregates
+	rec["_links"] = links("/api/svm/svms/" + uuidFor("svm", s.cluster.Svm))
+	records = append(records, rec)
+	return
+}
+
+// getCluster gets cluster name and version
+func (s *RestServer) getCluster(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var generation, major, minor int
+	fmt.Sscanf(s.Version, "%d.%d.%d", &generation, &major, &minor)
+	status, body = http.StatusOK, record{
+		"name": s.cluster.Host,
+		"uuid": uuidFor("cluster", s.cluster.Host),
+		"version": record{
+			"full":       "NetApp Release " + s.Version,
+			"generation": generation,
+			"major":      major,
+			"minor":      minor,
+		},
+	}
+	return
+}
+
+// getSvms gets SVM collection
+func (s *RestServer) getSvms(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	status, body = http.StatusOK, collection(s.svmRecords(), r)
+	return
+}
+
+// volumeRecords makes volume records, volume moves in flight are progressed if advance is set
+func (s *RestServer) volumeRecords(advance bool) (records []record) {
+	s.cluster.mu.Lock()
+	defer s.cluster.mu.Unlock()
+	for _, vol := range s.cluster.volumes {
+		uuid := uuidFor("volume", vol.name)
+		if advance {
+			vol.advanceMove()
+		}
+		rec := record{
+			"name":       vol.name,
+			"uuid":       uuid,
+			"svm":        s.svmRef(),
+			"aggregates": []record{{"name": vol.aggregate, "uuid": uuidFor("aggregate", vol.aggregate)}},
+			"size":       int64(vol.size) * gb,
+			"type":       vol.volumeType,
+			"state":      "online",
+			"_links":     links("/api/storage/volumes/" + uuid),
+		}
+		if vol.move != nil {
+			rec["movement"] = record{
+				"destination_aggregate": record{"name": vol.move.DestinationAggregate},
+				"percent_complete":      vol.move.PercentComplete,
+				"state":                 vol.move.State,
+			}
+		}
+		records = append(records, rec)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return recordName(records[i]) < recordName(records[j])
+	})
+	return
+}
+
+// volumeName gets volume name by UUID
+func (s *RestServer) volumeName(uuid string) (volumeName string, err error) {
+	var rec record
+	if rec, err = findRecord(s.volumeRecords(false), uuid, "volume"); err == nil {
+		volumeName = recordName(rec)
+	}
+	return
+}
+
+// getVolumes gets volume collection, move status is progressed on every request for movement field
+func (s *RestServer) getVolumes(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	advance := strings.Contains(r.URL.Query().Get("fields"), "movement")
+	status, body = http.StatusOK, collection(s.volumeRecords(advance), r)
+	return
+}
+
+// getVolume gets volume by UUID
+func (s *RestServer) getVolume(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var rec record
+	if rec, err = findRecord(s.volumeRecords(false), ids[0], "volume"); err != nil {
+		return
+	}
+	status, body = http.StatusOK, rec
+	return
+}
+
+// volumeBody is volume create and modify request
+type volumeBody struct {
+	Name       string    `json:"name"`
+	Aggregates []nameRef `json:"aggregates"`
+	Size       *int64    `json:"size"`
+	Type       string    `json:"type"`
+	Nas        *struct {
+		ExportPolicy *nameRef `json:"export_policy"`
+	} `json:"nas"`
+	Movement *struct {
+		DestinationAggregate nameRef `json:"destination_aggregate"`
+	} `json:"movement"`
+}
+
+// createVolume creates volume as async job
+func (s *RestServer) createVolume(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	req := volumeBody{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	if req.Name == "" || len(req.Aggregates) == 0 || req.Size == nil {
+		err = badRequest("volume name, aggregates and size are required")
+		return
+	}
+	aggregateName, volumeSize := req.Aggregates[0].Name, sizeGB(*req.Size)
+	return s.startJob("POST /api/storage/volumes", func() error {
+		switch {
+		case req.Type == "dp":
+			return s.client.VolumeCreateDP(req.Name, aggregateName, volumeSize)
+		case req.Nas != nil:
+			exportPolicyName := ""
+			if req.Nas.ExportPolicy != nil {
+				exportPolicyName = req.Nas.ExportPolicy.Name
+			}
+			return s.client.VolumeCreateNAS(req.Name, aggregateName, exportPolicyName, volumeSize)
+		}
+		return s.client.VolumeCreateSAN(req.Name, aggregateName, volumeSize)
+	})
+}
+
+// modifyVolume restores volume from snapshot, starts volume move or resizes volume as async job
+func (s *RestServer) modifyVolume(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var volumeName string
+	if volumeName, err = s.volumeName(ids[0]); err != nil {
+		return
+	}
+	req := volumeBody{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	var op func() error
+	if snapshotUuid := r.URL.Query().Get("restore_to.snapshot.uuid"); snapshotUuid != "" {
+		var rec record
+		if rec, err = findRecord(s.snapshotRecords(volumeName), snapshotUuid, "snapshot"); err != nil {
+			return
+		}
+		op = func() error {
+			return s.client.SnapshotRestore(volumeName, recordName(rec))
+		}
+	} else if req.Movement != nil {
+		op = func() error {
+			return s.client.VolumeMoveStart(volumeName, req.Movement.DestinationAggregate.Name)
+		}
+	} else if req.Size != nil {
+		op = func() error {
+			return s.client.VolumeResize(volumeName, sizeGB(*req.Size))
+		}
+	} else {
+		op = func() error {
+			return nil
+		}
+	}
+	return s.startJob("PATCH /api/storage/volumes/"+ids[0], op)
+}
+
+// deleteVolume deletes volume as async job
+func (s *RestServer) deleteVolume(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var volumeName string
+	if volumeName, err = s.volumeName(ids[0]); err != nil {
+		return
+	}
+	return s.startJob("DELETE /api/storage/volumes/"+ids[0], func() error {
+		return s.client.VolumeDestroy(volumeName)
+	})
+}
+
+// snapshotRecords makes volume snapshot records in creation order
+func (s *RestServer) snapshotRecords(volumeName string) (records []record) {
+	s.cluster.mu.Lock()
+	defer s.cluster.mu.Unlock()
+	vol, err := s.cluster.getVolume(volumeName)
+	if err != nil {
+		return
+	}
+	volumeUuid := uuidFor("volume", vol.name)
+	for _, snap := range vol.snapshots {
+		uuid := uuidFor("snapshot", vol.name+"/"+snap.info.Name)
+		records = append(records, record{
+			"name":        snap.info.Name,
+			"uuid":        uuid,
+			"comment":     snap.info.Comment,
+			"create_time": snap.info.CreateTime.Format(time.RFC3339),
+			"svm":         s.svmRef(),
+			"volume":      record{"name": vol.name, "uuid": volumeUuid},
+			"_links":      links("/api/storage/volumes/" + volumeUuid + "/snapshots/" + uuid),
+		})
+	}
+	return
+}
+
+// getSnapshots gets volume snapshot collection
+func (s *RestServer) getSnapshots(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var volumeName string
+	if volumeName, err = s.volumeName(ids[0]); err != nil {
+		return
+	}
+	status, body = http.StatusOK, collection(s.snapshotRecords(volumeName), r)
+	return
+}
+
+// getSnapshot gets volume snapshot by UUID
+func (s *RestServer) getSnapshot(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var volumeName string
+	if volumeName, err = s.volumeName(ids[0]); err != nil {
+		return
+	}
+	var rec record
+	if rec, err = findRecord(s.snapshotRecords(volumeName), ids[1], "snapshot"); err != nil {
+		return
+	}
+	status, body = http.StatusOK, rec
+	return
+}
+
+// snapshotBody is snapshot create and modify request
+type snapshotBody struct {
+	Name    string  `json:"name"`
+	Comment *string `json:"comment"`
+}
+
+// createSnapshot takes volume snapshot as async job
+func (s *RestServer) createSnapshot(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var volumeName string
+	if volumeName, err = s.volumeName(ids[0]); err != nil {
+		return
+	}
+	req := snapshotBody{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	comment := ""
+	if req.Comment != nil {
+		comment = *req.Comment
+	}
+	return s.startJob("POST /api/storage/volumes/"+ids[0]+"/snapshots", func() error {
+		return s.client.SnapshotCreate(volumeName, req.Name, comment)
+	})
+}
+
+// modifySnapshot sets snapshot comment as async job
+func (s *RestServer) modifySnapshot(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var volumeName string
+	if volumeName, err = s.volumeName(ids[0]); err != nil {
+		return
+	}
+	var rec record
+	if rec, err = findRecord(s.snapshotRecords(volumeName), ids[1], "snapshot"); err != nil {
+		return
+	}
+	req := snapshotBody{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	return s.startJob("PATCH /api/storage/volumes/"+ids[0]+"/snapshots/"+ids[1], func() error {
+		if req.Comment == nil {
+			return nil
+		}
+		return s.client.SnapshotSetComment(volumeName, recordName(rec), *req.Comment)
+	})
+}
+
+// deleteSnapshot deletes volume snapshot as async job
+func (s *RestServer) deleteSnapshot(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var volumeName string
+	if volumeName, err = s.volumeName(ids[0]); err != nil {
+		return
+	}
+	var rec record
+	if rec, err = findRecord(s.snapshotRecords(volumeName), ids[1], "snapshot"); err != nil {
+		return
+	}
+	return s.startJob("DELETE /api/storage/volumes/"+ids[0]+"/snapshots/"+ids[1], func() error {
+		return s.client.SnapshotDelete(volumeName, recordName(rec))
+	})
+}
+
+// noSuchFile makes REST "no such file or directory" error
+func noSuchFile(path string) *RestError {
+	return &RestError{Status: http.StatusNotFound, Code: ontap.ERROR_NO_SUCH_FILE_OR_DIR, Message: fmt.Sprintf("\"%s\" does not exist", path)}
+}
+
+// dirExists checks if directory exists in volume, directories with files are implied, the caller must hold the lock
+func (vol *volume) dirExists(dirPath string) bool {
+	if dirPath == "/" || vol.dirs[dirPath] {
+		return true
+	}
+	for filePath := range vol.files {
+		if strings.HasPrefix(filePath, dirPath+"/") {
+			return true
+		}
+	}
+	for dir := range vol.dirs {
+		if strings.HasPrefix(dir, dirPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// dirRecords makes records of directory entries, the caller must hold the lock
+func (vol *volume) dirRecords(dirPath string) (records []record) {
+	dirs := make(map[string]bool)
+	for dir := range vol.dirs {
+		dirs[dir] = true
+	}
+	for filePath := range vol.files {
+		for dir := filepath.Dir(filePath); dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	for dir := range dirs {
+		if filepath.Dir(dir) == dirPath {
+			records = append(records, record{"name": filepath.Base(dir), "path": dir, "type": "directory", "size": 4096})
+		}
+	}
+	for filePath, data := range vol.files {
+		if filepath.Dir(filePath) == dirPath {
+			records = append(records, record{"name": filepath.Base(filePath), "path": filePath, "type": "file", "size": len(data)})
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return recordName(records[i]) < recordName(records[j])
+	})
+	return
+}
+
+// getFiles reads file content with byte_offset and length, gets directory metadata with return_metadata or lists directory
+func (s *RestServer) getFiles(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var volumeName string
+	if volumeName, err = s.volumeName(ids[0]); err != nil {
+		return
+	}
+	filePath := filepath.Clean(ids[1])
+	query := r.URL.Query()
+	s.cluster.mu.Lock()
+	defer s.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = s.cluster.getVolume(volumeName); err != nil {
+		return
+	}
+	if query.Get("length") != "" {
+		data, exists := vol.files[filePath]
+		if !exists {
+			err = noSuchFile(filePath)
+			return
+		}
+		offset, _ := strconv.Atoi(query.Get("byte_offset"))
+		length, _ := strconv.Atoi(query.Get("length"))
+		if offset > len(data) {
+			offset = len(data)
+		}
+		if offset+length > len(data) {
+			length = len(data) - offset
+		}
+		status, body = http.StatusOK, &multipartBody{fileName: filepath.Base(filePath), data: append([]byte{}, data[offset:offset+length]...)}
+		return
+	}
+	if !vol.dirExists(filePath) {
+		err = noSuchFile(filePath)
+		return
+	}
+	if query.Get("return_metadata") == "true" {
+		return single(http.StatusOK, record{"name": filepath.Base(filePath), "path": filePath, "type": "directory", "size": 4096})
+	}
+	status, body = http.StatusOK, collection(vol.dirRecords(filePath), r)
+	return
+}
+
+// writeFile creates file or directory with POST and writes file content at byte_offset or appends it with PATCH
+func (s *RestServer) writeFile(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var volumeName string
+	if volumeName, err = s.volumeName(ids[0]); err != nil {
+		return
+	}
+	filePath := filepath.Clean(ids[1])
+	if !isMultipart(r) {
+		req := struct {
+			Type string `json:"type"`
+		}{}
+		if err = decodeBody(r, &req); err != nil {
+			return
+		}
+		s.cluster.mu.Lock()
+		defer s.cluster.mu.Unlock()
+		var vol *volume
+		if vol, err = s.cluster.getVolume(volumeName); err != nil {
+			return
+		}
+		if req.Type == "directory" {
+			vol.dirs[filePath] = true
+		} else if _, exists := vol.files[filePath]; !exists {
+			vol.files[filePath] = []byte{}
+		}
+		return single(http.StatusCreated, record{"name": filepath.Base(filePath), "path": filePath, "type": req.Type})
+	}
+	var data []byte
+	if data, err = readMultipart(r); err != nil {
+		return
+	}
+	if r.Method == "POST" {
+		if err = s.client.FileUploadAPI(volumeName, filePath, bytes.NewReader(data)); err != nil {
+			return
+		}
+		status, body = http.StatusCreated, record{"bytes_written": len(data)}
+		return
+	}
+	s.cluster.mu.Lock()
+	defer s.cluster.mu.Unlock()
+	var vol *volume
+	if vol, err = s.cluster.getVolume(volumeName); err != nil {
+		return
+	}
+	content, exists := vol.files[filePath]
+	if !exists {
+		err = noSuchFile(filePath)
+		return
+	}
+	offset := len(content)
+	if byteOffset := r.URL.Query().Get("byte_offset"); byteOffset != "" {
+		offset, _ = strconv.Atoi(byteOffset)
+	}
+	if end := offset + len(data); end > len(content) {
+		content = append(content, make([]byte, end-len(content))...)
+	}
+	copy(content[offset:], data)
+	vol.files[filePath] = content
+	status, body = http.StatusOK, record{"bytes_written": len(data)}
+	return
+}
+
+// deleteFile deletes file or directory as async job
+func (s *RestServer) deleteFile(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var volumeName string
+	if volumeName, err = s.volumeName(ids[0]); err != nil {
+		return
+	}
+	filePath := filepath.Clean(ids[1])
+	s.cluster.mu.Lock()
+	var vol *volume
+	var isFile, isDir bool
+	if vol, err = s.cluster.getVolume(volumeName); err == nil {
+		_, isFile = vol.files[filePath]
+		isDir = vol.dirs[filePath]
+	}
+	s.cluster.mu.Unlock()
+	if err != nil {
+		return
+	}
+	if !isFile && !isDir {
+		err = noSuchFile(filePath)
+		return
+	}
+	return s.startJob("DELETE /api/storage/volumes/"+ids[0]+"/files"+filePath, func() error {
+		if isDir {
+			s.cluster.mu.Lock()
+			defer s.cluster.mu.Unlock()
+			delete(vol.dirs, filePath)
+			return nil
+		}
+		return s.client.FileDelete(volumeName, filePath)
+	})
+}
+
+// lunRecords makes LUN records
+func (s *RestServer) lunRecords() (records []record) {
+	s.cluster.mu.Lock()
+	defer s.cluster.mu.Unlock()
+	for _, vol := range s.cluster.volumes {
+		for name, l := range vol.luns {
+			lunPath := "/vol/" + vol.name + "/" + name
+			uuid := uuidFor("lun", lunPath)
+			rec := record{
+				"name":    lunPath,
+				"uuid":    uuid,
+				"svm":     s.svmRef(),
+				"os_type": l.osType,
+				"comment": l.comment,
+				"location": record{
+					"logical_unit": name,
+					"volume":       record{"name": vol.name, "uuid": uuidFor("volume", vol.name)},
+					"node":         record{"name": s.Node},
+				},
+				"create_time": l.createTime.Format(time.RFC3339),
+				"space":       record{"size": l.size, "used": int64(len(l.data))},
+				"status":      record{"state": "online", "mapped": len(l.maps) > 0},
+				"_links":      links("/api/storage/luns/" + uuid),
+			}
+			if l.source != "" {
+				rec["copy"] = record{"source": record{"name": l.source}}
+			}
+			records = append(records, rec)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return recordName(records[i]) < recordName(records[j])
+	})
+	return
+}
+
+// lunPath gets LUN path by UUID
+func (s *RestServer) lunPath(uuid string) (lunPath string, err error) {
+	var rec record
+	if rec, err = findRecord(s.lunRecords(), uuid, "LUN"); err == nil {
+		lunPath = recordName(rec)
+	}
+	return
+}
+
+// getLuns gets LUN collection
+func (s *RestServer) getLuns(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	status, body = http.StatusOK, collection(s.lunRecords(), r)
+	return
+}
+
+// lunBody is LUN create and modify request
+type lunBody struct {
+	Name     string `json:"name"`
+	Location *struct {
+		LogicalUnit string  `json:"logical_unit"`
+		Volume      nameRef `json:"volume"`
+	} `json:"location"`
+	Copy *struct {
+		Source nameRef `json:"source"`
+	} `json:"copy"`
+	OsType  string  `json:"os_type"`
+	Comment *string `json:"comment"`
+	Space   *struct {
+		Size *int64 `json:"size"`
+	} `json:"space"`
+}
+
+// createLun creates LUN or copies LUN if copy source is set
+func (s *RestServer) createLun(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	req := lunBody{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	lunPath := req.Name
+	if lunPath == "" && req.Location != nil {
+		lunPath = "/vol/" + req.Location.Volume.Name + "/" + req.Location.LogicalUnit
+	}
+	if req.Copy != nil {
+		err = s.client.LunCopy(req.Copy.Source.Name, lunPath)
+	} else {
+		if req.Space == nil || req.Space.Size == nil {
+			err = badRequest("LUN size is required")
+			return
+		}
+		if err = s.cluster.fault("LunCreate", lunPath); err != nil {
+			return
+		}
+		l := &lun{size: *req.Space.Size, osType: req.OsType}
+		if req.Comment != nil {
+			l.comment = *req.Comment
+		}
+		s.cluster.mu.Lock()
+		err = s.cluster.addLun("LunCreate", lunPath, l)
+		s.cluster.mu.Unlock()
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			err = &RestError{Status: http.StatusConflict, Code: ontap.ERROR_LUN_EXIST, Message: err.Error()}
+		}
+		return
+	}
+	var rec record
+	if rec, err = findRecord(s.lunRecords(), uuidFor("lun", lunPath), "LUN"); err != nil {
+		return
+	}
+	return single(http.StatusCreated, rec)
+}
+
+// getLun reads LUN data with data.offset and data.size or gets LUN by UUID
+func (s *RestServer) getLun(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var rec record
+	if rec, err = findRecord(s.lunRecords(), ids[0], "LUN"); err != nil {
+		return
+	}
+	query := r.URL.Query()
+	if query.Get("data.offset") == "" {
+		status, body = http.StatusOK, rec
+		return
+	}
+	offset, _ := strconv.ParseInt(query.Get("data.offset"), 10, 64)
+	size, _ := strconv.ParseInt(query.Get("data.size"), 10, 64)
+	s.cluster.mu.Lock()
+	defer s.cluster.mu.Unlock()
+	var l *lun
+	if _, l, err = s.cluster.getLun(recordName(rec)); err != nil {
+		return
+	}
+	if offset+size > l.size {
+		err = badRequest("read beyond LUN size \"%d\"", l.size)
+		return
+	}
+	data := make([]byte, size)
+	if offset < int64(len(l.data)) {
+		copy(data, l.data[offset:])
+	}
+	status, body = http.StatusOK, &multipartBody{fileName: filepath.Base(recordName(rec)), data: data}
+	return
+}
+
+// modifyLun writes LUN data at data.offset, sets LUN comment, name or size
+func (s *RestServer) modifyLun(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var lunPath string
+	if lunPath, err = s.lunPath(ids[0]); err != nil {
+		return
+	}
+	status = http.StatusOK
+	if isMultipart(r) {
+		offset, _ := strconv.ParseInt(r.URL.Query().Get("data.offset"), 10, 64)
+		var data []byte
+		if data, err = readMultipart(r); err != nil {
+			return
+		}
+		if err = s.cluster.fault("LunUpload", lunPath); err != nil {
+			return
+		}
+		s.cluster.mu.Lock()
+		defer s.cluster.mu.Unlock()
+		var l *lun
+		if _, l, err = s.cluster.getLun(lunPath); err != nil {
+			return
+		}
+		end := offset + int64(len(data))
+		if end > l.size {
+			err = badRequest("write beyond LUN size \"%d\"", l.size)
+			return
+		}
+		if end > int64(len(l.data)) {
+			l.data = append(l.data, make([]byte, end-int64(len(l.data)))...)
+		}
+		copy(l.data[offset:], data)
+		return
+	}
+	req := lunBody{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	if req.Comment != nil {
+		if err = s.client.LunSetComment(lunPath, *req.Comment); err != nil {
+			return
+		}
+	}
+	if req.Name != "" && req.Name != lunPath {
+		if err = s.client.LunRename(lunPath, req.Name); err != nil {
+			return
+		}
+		lunPath = req.Name
+	}
+	if req.Space != nil && req.Space.Size != nil {
+		if err = s.cluster.fault("LunResize", lunPath); err != nil {
+			return
+		}
+		s.cluster.mu.Lock()
+		defer s.cluster.mu.Unlock()
+		var l *lun
+		if _, l, err = s.cluster.getLun(lunPath); err != nil {
+			return
+		}
+		if *req.Space.Size < l.size {
+			err = badRequest("LUN \"%s\" can't be shrunk", lunPath)
+			return
+		}
+		l.size = *req.Space.Size
+	}
+	return
+}
+
+// deleteLun deletes LUN
+func (s *RestServer) deleteLun(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var lunPath string
+	if lunPath, err = s.lunPath(ids[0]); err != nil {
+		return
+	}
+	err = s.client.LunDestroy(lunPath)
+	status = http.StatusOK
+	return
+}
+
+// lunMapRecords makes LUN map records
+func (s *RestServer) lunMapRecords() (records []record) {
+	s.cluster.mu.Lock()
+	defer s.cluster.mu.Unlock()
+	for _, vol := range s.cluster.volumes {
+		for name, l := range vol.luns {
+			lunPath := "/vol/" + vol.name + "/" + name
+			lunUuid := uuidFor("lun", lunPath)
+			for igroupName, lunID := range l.maps {
+				igroupUuid := uuidFor("igroup", igroupName)
+				records = append(records, record{
+					"svm":                 s.svmRef(),
+					"lun":                 record{"name": lunPath, "uuid": lunUuid, "node": record{"name": s.Node}},
+					"igroup":              record{"name": igroupName, "uuid": igroupUuid},
+					"logical_unit_number": lunID,
+					"_links":              links("/api/protocols/san/lun-maps/" + lunUuid + "/" + igroupUuid),
+				})
+			}
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return fmt.Sprint(records[i]["_links"]) < fmt.Sprint(records[j]["_links"])
+	})
+	return
+}
+
+// getLunMaps gets LUN map collection
+func (s *RestServer) getLunMaps(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	status, body = http.StatusOK, collection(s.lunMapRecords(), r)
+	return
+}
+
+// createLunMap maps LUN to iGroup
+func (s *RestServer) createLunMap(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	req := struct {
+		Igroup            nameRef `json:"igroup"`
+		Lun               nameRef `json:"lun"`
+		LogicalUnitNumber *int    `json:"logical_unit_number"`
+	}{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	lunID := 0
+	if req.LogicalUnitNumber != nil {
+		lunID = *req.LogicalUnitNumber
+	}
+	if err = s.client.LunMap(req.Lun.Name, lunID, req.Igroup.Name); err == nil {
+		status = http.StatusCreated
+	}
+	return
+}
+
+// deleteLunMap unmaps LUN from iGroup
+func (s *RestServer) deleteLunMap(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	for _, rec := range s.lunMapRecords() {
+		lunRef, igroupRef := rec["lun"].(record), rec["igroup"].(record)
+		if lunRef["uuid"] == ids[0] && igroupRef["uuid"] == ids[1] {
+			err = s.client.LunUnmap(recordName(lunRef), recordName(igroupRef))
+			status = http.StatusOK
+			return
+		}
+	}
+	err = notFound("LUN map \"%s/%s\" not found", ids[0], ids[1])
+	return
+}
+
+// igroupRecords makes iGroup records
+func (s *RestServer) igroupRecords() (records []record) {
+	s.cluster.mu.Lock()
+	defer s.cluster.mu.Unlock()
+	for name, ig := range s.cluster.igroups {
+		uuid := uuidFor("igroup", name)
+		initiators := []record{}
+		for _, initiator := range ig.initiators {
+			initiators = append(initiators, record{"name": initiator})
+		}
+		records = append(records, record{
+			"name":       name,
+			"uuid":       uuid,
+			"svm":        s.svmRef(),
+			"os_type":    ig.osType,
+			"protocol":   ig.protocol,
+			"initiators": initiators,
+			"_links":     links("/api/protocols/san/igroups/" + uuid),
+		})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return recordName(records[i]) < recordName(records[j])
+	})
+	return
+}
+
+// getIgroups gets iGroup collection
+func (s *RestServer) getIgroups(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	status, body = http.StatusOK, collection(s.igroupRecords(), r)
+	return
+}
+
+// getIgroup gets iGroup by UUID
+func (s *RestServer) getIgroup(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var rec record
+	if rec, err = findRecord(s.igroupRecords(), ids[0], "iGroup"); err != nil {
+		return
+	}
+	status, body = http.StatusOK, rec
+	return
+}
+
+// createIgroup creates iGroup with initiators
+func (s *RestServer) createIgroup(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	req := struct {
+		Name       string    `json:"name"`
+		OsType     string    `json:"os_type"`
+		Protocol   string    `json:"protocol"`
+		Initiators []nameRef `json:"initiators"`
+	}{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	if err = s.client.IgroupCreate(req.Name, req.Protocol, req.OsType); err != nil {
+		return
+	}
+	for _, initiator := range req.Initiators {
+		if err = s.client.IgroupAddInitiator(req.Name, initiator.Name); err != nil {
+			return
+		}
+	}
+	status = http.StatusCreated
+	return
+}
+
+// deleteIgroup deletes iGroup
+func (s *RestServer) deleteIgroup(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var rec record
+	if rec, err = findRecord(s.igroupRecords(), ids[0], "iGroup"); err != nil {
+		return
+	}
+	err = s.client.IgroupDestroy(recordName(rec))
+	status = http.StatusOK
+	return
+}
+
+// addIgroupInitiators adds single initiator or list of initiator records to iGroup
+func (s *RestServer) addIgroupInitiators(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var rec record
+	if rec, err = findRecord(s.igroupRecords(), ids[0], "iGroup"); err != nil {
+		return
+	}
+	req := struct {
+		Name    string    `json:"name"`
+		Records []nameRef `json:"records"`
+	}{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	if req.Name != "" {
+		req.Records = append(req.Records, nameRef{Name: req.Name})
+	}
+	for _, initiator := range req.Records {
+		if err = s.client.IgroupAddInitiator(recordName(rec), initiator.Name); err != nil {
+			return
+		}
+	}
+	status = http.StatusCreated
+	return
+}
+
+// getIscsiServices gets iSCSI service collection
+func (s *RestServer) getIscsiServices(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	s.cluster.mu.Lock()
+	records := []record{}
+	if s.cluster.IscsiTargetName != "" {
+		records = append(records, record{"svm": s.svmRef(), "enabled": true, "target": record{"name": s.cluster.IscsiTargetName}})
+	}
+	s.cluster.mu.Unlock()
+	status, body = http.StatusOK, collection(records, r)
+	return
+}
+
+// iscsiCredentialsRecords makes iSCSI initiator security records
+func (s *RestServer) iscsiCredentialsRecords() (records []record) {
+	s.cluster.mu.Lock()
+	defer s.cluster.mu.Unlock()
+	for initiator, user := range s.cluster.iscsiAuth {
+		records = append(records, record{
+			"svm":                 s.svmRef(),
+			"initiator":           initiator,
+			"authentication_type": "chap",
+			"chap":                record{"inbound": record{"user": user}},
+			"_links":              links("/api/protocols/san/iscsi/credentials/" + uuidFor("svm", s.cluster.Svm) + "/" + initiator),
+		})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return fmt.Sprint(records[i]["initiator"]) < fmt.Sprint(records[j]["initiator"])
+	})
+	return
+}
+
+// getIscsiCredentials gets iSCSI initiator security collection
+func (s *RestServer) getIscsiCredentials(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	status, body = http.StatusOK, collection(s.iscsiCredentialsRecords(), r)
+	return
+}
+
+// setIscsiCredentials creates or modifies iSCSI initiator security record
+func (s *RestServer) setIscsiCredentials(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	type chapCredentials struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}
+	req := struct {
+		Initiator string `json:"initiator"`
+		Chap      struct {
+			Inbound  chapCredentials `json:"inbound"`
+			Outbound chapCredentials `json:"outbound"`
+		} `json:"chap"`
+	}{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	initiator, status := req.Initiator, http.StatusCreated
+	if len(ids) > 1 {
+		initiator, status = ids[1], http.StatusOK
+	}
+	err = s.client.IscsiInitiatorSetAuth(initiator, req.Chap.Inbound.User, req.Chap.Inbound.Password, req.Chap.Outbound.User, req.Chap.Outbound.Password)
+	return
+}
+
+// deleteIscsiCredentials deletes iSCSI initiator security record
+func (s *RestServer) deleteIscsiCredentials(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	err = s.client.IscsiInitiatorDeleteAuth(ids[1])
+	status = http.StatusOK
+	return
+}
+
+// getFcpServices gets FCP service collection
+func (s *RestServer) getFcpServices(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	s.cluster.mu.Lock()
+	records := []record{}
+	if s.cluster.FcpTargetName != "" {
+		records = append(records, record{"svm": s.svmRef(), "enabled": true, "target": record{"name": s.cluster.FcpTargetName}})
+	}
+	s.cluster.mu.Unlock()
+	status, body = http.StatusOK, collection(records, r)
+	return
+}
+
+// getFcInterfaces gets FC interface collection
+func (s *RestServer) getFcInterfaces(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	s.cluster.mu.Lock()
+	records := []record{}
+	for i, lif := range s.cluster.FcpLIFs {
+		name := lif.Name
+		if name == "" {
+			name = fmt.Sprintf("fc_lif%d", i+1)
+		}
+		rec := record{
+			"name":          name,
+			"uuid":          uuidFor("fc-interface", name),
+			"svm":           s.svmRef(),
+			"wwpn":          lif.Wwpn,
+			"state":         "up",
+			"enabled":       true,
+			"data_protocol": "fcp",
+		}
+		if lif.Fabric != "" {
+			rec["fabric"] = record{"name": lif.Fabric}
+		}
+		records = append(records, rec)
+	}
+	s.cluster.mu.Unlock()
+	status, body = http.StatusOK, collection(records, r)
+	return
+}
+
+// ipInterfaceRecords makes IP interface records for LIF's
+func (s *RestServer) ipInterfaceRecords(lifs []string, prefix string, service string) (records []record) {
+	for i, lif := range lifs {
+		ip, ipNet := parseLif(lif)
+		if ip == nil {
+			continue
+		}
+		name := fmt.Sprintf("%s%d", prefix, i+1)
+		netlen, _ := ipNet.Mask.Size()
+		records = append(records, record{
+			"name":     name,
+			"uuid":     uuidFor("ip-interface", name),
+			"svm":      s.svmRef(),
+			"ip":       record{"address": ip.String(), "netmask": strconv.Itoa(netlen), "family": "ipv4"},
+			"enabled":  true,
+			"state":    "up",
+			"services": []string{service},
+			"location": record{"home_node": record{"name": s.Node}, "node": record{"name": s.Node}, "is_home": true},
+		})
+	}
+	return
+}
+
+// getIpInterfaces gets iSCSI and NVME IP interface collection
+func (s *RestServer) getIpInterfaces(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	s.cluster.mu.Lock()
+	records := append(s.ipInterfaceRecords(s.cluster.IscsiLIFs, "iscsi_lif", "data_iscsi"), s.ipInterfaceRecords(s.cluster.NvmeLIFs, "nvme_lif", "data_nvme_tcp")...)
+	s.cluster.mu.Unlock()
+	status, body = http.StatusOK, collection(records, r)
+	return
+}
+
+// createExportPolicy creates export-policy
+func (s *RestServer) createExportPolicy(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	req := nameRef{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	if err = s.client.ExportPolicyCreate(req.Name); err == nil {
+		status = http.StatusCreated
+	}
+	return
+}
+
+// getCliVolumes gets volume nodes via private CLI
+func (s *RestServer) getCliVolumes(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	records := []record{}
+	for _, rec := range s.volumeRecords(false) {
+		records = append(records, record{"vserver": s.cluster.Svm, "volume": recordName(rec), "node": s.Node})
+	}
+	status, body = http.StatusOK, collection(records, r)
+	return
+}
+
+// createCliLun creates LUN from file via private CLI
+func (s *RestServer) createCliLun(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	req := struct {
+		Path     string `json:"path"`
+		FilePath string `json:"file-path"`
+		OsType   string `json:"ostype"`
+	}{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	var volumeName, fileName string
+	if volumeName, fileName, err = splitPath(req.FilePath); err != nil {
+		err = badRequest("%s", err)
+		return
+	}
+	if err = s.client.LunCreateFromFile(volumeName, "/"+fileName, req.Path, "", req.OsType); err == nil {
+		status = http.StatusCreated
+	}
+	return
+}
+
+// subsystemMapRecord makes NVME subsystem map record, the caller must hold the lock
+func (s *RestServer) subsystemMapRecord(namespacePath string, subsystemName string) record {
+	namespaceUuid, subsystemUuid := uuidFor("namespace", namespacePath), uuidFor("subsystem", subsystemName)
+	return record{
+		"svm":       s.svmRef(),
+		"namespace": record{"name": namespacePath, "uuid": namespaceUuid},
+		"subsystem": record{"name": subsystemName, "uuid": subsystemUuid, "_links": links("/api/protocols/nvme/subsystems/" + subsystemUuid)},
+		"_links":    links("/api/protocols/nvme/subsystem-maps/" + namespaceUuid + "/" + subsystemUuid),
+	}
+}
+
+// namespaceRecords makes NVME namespace records
+func (s *RestServer) namespaceRecords() (records []record) {
+	s.cluster.mu.Lock()
+	defer s.cluster.mu.Unlock()
+	for _, vol := range s.cluster.volumes {
+		for name, ns := range vol.namespaces {
+			namespacePath := "/vol/" + vol.name + "/" + name
+			uuid := uuidFor("namespace", namespacePath)
+			rec := record{
+				"name":    namespacePath,
+				"uuid":    uuid,
+				"svm":     s.svmRef(),
+				"os_type": ns.osType,
+				"comment": ns.comment,
+				"location": record{
+					"namespace": name,
+					"volume":    record{"name": vol.name, "uuid": uuidFor("volume", vol.name)},
+					"node":      record{"name": s.Node},
+				},
+				"space":  record{"size": ns.size},
+				"status": record{"state": "online", "mapped": ns.subsystem != ""},
+				"_links": links("/api/storage/namespaces/" + uuid),
+			}
+			if ns.subsystem != "" {
+				rec["subsystem_map"] = s.subsystemMapRecord(namespacePath, ns.subsystem)
+			}
+			records = append(records, rec)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return recordName(records[i]) < recordName(records[j])
+	})
+	return
+}
+
+// namespacePath gets NVME namespace path by UUID
+func (s *RestServer) namespacePath(uuid string) (namespacePath string, err error) {
+	var rec record
+	if rec, err = findRecord(s.namespaceRecords(), uuid, "NVME namespace"); err == nil {
+		namespacePath = recordName(rec)
+	}
+	return
+}
+
+// getNamespaces gets NVME namespace collection
+func (s *RestServer) getNamespaces(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	status, body = http.StatusOK, collection(s.namespaceRecords(), r)
+	return
+}
+
+// getNamespace gets NVME namespace by UUID
+func (s *RestServer) getNamespace(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var rec record
+	if rec, err = findRecord(s.namespaceRecords(), ids[0], "NVME namespace"); err != nil {
+		return
+	}
+	status, body = http.StatusOK, rec
+	return
+}
+
+// namespaceBody is NVME namespace create and modify request
+type namespaceBody struct {
+	Name     string `json:"name"`
+	Location *struct {
+		Namespace string  `json:"namespace"`
+		Volume    nameRef `json:"volume"`
+	} `json:"location"`
+	OsType string `json:"os_type"`
+	Space  *struct {
+		Size *int64 `json:"size"`
+	} `json:"space"`
+}
+
+// createNamespace creates NVME namespace
+func (s *RestServer) createNamespace(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	req := namespaceBody{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	namespacePath := req.Name
+	if namespacePath == "" && req.Location != nil {
+		namespacePath = "/vol/" + req.Location.Volume.Name + "/" + req.Location.Namespace
+	}
+	if req.Space == nil || req.Space.Size == nil {
+		err = badRequest("NVME namespace size is required")
+		return
+	}
+	if err = s.client.NvmeNamespaceCreate(namespacePath, sizeGB(*req.Space.Size), req.OsType); err != nil {
+		return
+	}
+	var rec record
+	if rec, err = findRecord(s.namespaceRecords(), uuidFor("namespace", namespacePath), "NVME namespace"); err != nil {
+		return
+	}
+	return single(http.StatusCreated, rec)
+}
+
+// modifyNamespace resizes NVME namespace
+func (s *RestServer) modifyNamespace(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var namespacePath string
+	if namespacePath, err = s.namespacePath(ids[0]); err != nil {
+		return
+	}
+	req := namespaceBody{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	if req.Space != nil && req.Space.Size != nil {
+		err = s.client.NvmeNamespaceResize(namespacePath, sizeGB(*req.Space.Size))
+	}
+	status = http.StatusOK
+	return
+}
+
+// deleteNamespace deletes NVME namespace
+func (s *RestServer) deleteNamespace(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var namespacePath string
+	if namespacePath, err = s.namespacePath(ids[0]); err != nil {
+		return
+	}
+	err = s.client.NvmeNamespaceDestroy(namespacePath)
+	status = http.StatusOK
+	return
+}
+
+// subsystemRecords makes NVME subsystem records with hosts and namespace maps
+func (s *RestServer) subsystemRecords() (records []record) {
+	s.cluster.mu.Lock()
+	defer s.cluster.mu.Unlock()
+	for name, ss := range s.cluster.subsystems {
+		uuid := uuidFor("subsystem", name)
+		hosts := []record{}
+		for _, host := range ss.hosts {
+			hosts = append(hosts, record{"nqn": host, "subsystem": record{"name": name, "uuid": uuid}})
+		}
+		maps := []record{}
+		for _, vol := range s.cluster.volumes {
+			for namespaceName, ns := range vol.namespaces {
+				if ns.subsystem == name {
+					maps = append(maps, s.subsystemMapRecord("/vol/"+vol.name+"/"+namespaceName, name))
+				}
+			}
+		}
+		records = append(records, record{
+			"name":           name,
+			"uuid":           uuid,
+			"svm":            s.svmRef(),
+			"os_type":        ss.osType,
+			"target_nqn":     s.cluster.NvmeTargetNqn + "." + name,
+			"hosts":          hosts,
+			"subsystem_maps": maps,
+			"_links":         links("/api/protocols/nvme/subsystems/" + uuid),
+		})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return recordName(records[i]) < recordName(records[j])
+	})
+	return
+}
+
+// getSubsystems gets NVME subsystem collection
+func (s *RestServer) getSubsystems(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	status, body = http.StatusOK, collection(s.subsystemRecords(), r)
+	return
+}
+
+// getSubsystem gets NVME subsystem by UUID
+func (s *RestServer) getSubsystem(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var rec record
+	if rec, err = findRecord(s.subsystemRecords(), ids[0], "NVME subsystem"); err != nil {
+		return
+	}
+	status, body = http.StatusOK, rec
+	return
+}
+
+// createSubsystem creates NVME subsystem
+func (s *RestServer) createSubsystem(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	req := struct {
+		Name   string `json:"name"`
+		OsType string `json:"os_type"`
+	}{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	if err = s.client.NvmeSubsystemCreate(req.Name, req.OsType); err != nil {
+		return
+	}
+	var rec record
+	if rec, err = findRecord(s.subsystemRecords(), uuidFor("subsystem", req.Name), "NVME subsystem"); err != nil {
+		return
+	}
+	return single(http.StatusCreated, rec)
+}
+
+// deleteSubsystem deletes NVME subsystem
+func (s *RestServer) deleteSubsystem(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var rec record
+	if rec, err = findRecord(s.subsystemRecords(), ids[0], "NVME subsystem"); err != nil {
+		return
+	}
+	err = s.client.NvmeSubsystemDestroy(recordName(rec))
+	status = http.StatusOK
+	return
+}
+
+// getSubsystemHosts gets NVME subsystem host collection
+func (s *RestServer) getSubsystemHosts(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var rec record
+	if rec, err = findRecord(s.subsystemRecords(), ids[0], "NVME subsystem"); err != nil {
+		return
+	}
+	status, body = http.StatusOK, collection(rec["hosts"].([]record), r)
+	return
+}
+
+// addSubsystemHost adds host NQN to NVME subsystem
+func (s *RestServer) addSubsystemHost(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var rec record
+	if rec, err = findRecord(s.subsystemRecords(), ids[0], "NVME subsystem"); err != nil {
+		return
+	}
+	req := struct {
+		Nqn string `json:"nqn"`
+	}{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	if err = s.client.NvmeSubsystemAddHost(recordName(rec), req.Nqn); err != nil {
+		return
+	}
+	return single(http.StatusCreated, record{"nqn": req.Nqn})
+}
+
+// subsystemMapRecords makes NVME subsystem map records
+func (s *RestServer) subsystemMapRecords() (records []record) {
+	for _, rec := range s.subsystemRecords() {
+		records = append(records, rec["subsystem_maps"].([]record)...)
+	}
+	return
+}
+
+// getSubsystemMaps gets NVME subsystem map collection
+func (s *RestServer) getSubsystemMaps(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	status, body = http.StatusOK, collection(s.subsystemMapRecords(), r)
+	return
+}
+
+// createSubsystemMap maps NVME namespace to subsystem
+func (s *RestServer) createSubsystemMap(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	req := struct {
+		Namespace nameRef `json:"namespace"`
+		Subsystem nameRef `json:"subsystem"`
+	}{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	if err = s.client.NvmeNamespaceMap(req.Namespace.Name, req.Subsystem.Name); err != nil {
+		return
+	}
+	s.cluster.mu.Lock()
+	rec := s.subsystemMapRecord(req.Namespace.Name, req.Subsystem.Name)
+	s.cluster.mu.Unlock()
+	return single(http.StatusCreated, rec)
+}
+
+// deleteSubsystemMap unmaps NVME namespace from subsystem
+func (s *RestServer) deleteSubsystemMap(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var namespacePath string
+	if namespacePath, err = s.namespacePath(ids[0]); err != nil {
+		return
+	}
+	err = s.client.NvmeNamespaceUnmap(namespacePath)
+	status = http.StatusOK
+	return
+}
+
+// snapmirrorRecords makes SnapMirror relationship records
+func (s *RestServer) snapmirrorRecords() (records []record) {
+	s.cluster.mu.Lock()
+	defer s.cluster.mu.Unlock()
+	for destinationPath, info := range s.cluster.snapmirrors {
+		uuid := uuidFor("snapmirror", destinationPath)
+		records = append(records, record{
+			"uuid":              uuid,
+			"source":            record{"path": info.SourcePath},
+			"destination":       record{"path": destinationPath},
+			"policy":            record{"name": info.Policy},
+			"transfer_schedule": record{"name": info.Schedule},
+			"state":             info.State,
+			"healthy":           info.Healthy,
+			"_links":            links("/api/snapmirror/relationships/" + uuid),
+		})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return fmt.Sprint(records[i]["destination"]) < fmt.Sprint(records[j]["destination"])
+	})
+	return
+}
+
+// snapmirrorPath gets SnapMirror relationship destination path by UUID
+func (s *RestServer) snapmirrorPath(uuid string) (destinationPath string, err error) {
+	var rec record
+	if rec, err = findRecord(s.snapmirrorRecords(), uuid, "SnapMirror relationship"); err == nil {
+		destinationPath = rec["destination"].(record)["path"].(string)
+	}
+	return
+}
+
+// snapmirrorBody is SnapMirror relationship create and modify request
+type snapmirrorBody struct {
+	Source *struct {
+		Path string `json:"path"`
+	} `json:"source"`
+	Destination *struct {
+		Path string `json:"path"`
+	} `json:"destination"`
+	Policy           *nameRef `json:"policy"`
+	TransferSchedule *nameRef `json:"transfer_schedule"`
+	State            string   `json:"state"`
+}
+
+// names gets policy and schedule names of SnapMirror relationship request
+func (req *snapmirrorBody) names() (policy string, schedule string) {
+	if req.Policy != nil {
+		policy = req.Policy.Name
+	}
+	if req.TransferSchedule != nil {
+		schedule = req.TransferSchedule.Name
+	}
+	return
+}
+
+// getSnapmirrors gets SnapMirror relationship collection
+func (s *RestServer) getSnapmirrors(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	status, body = http.StatusOK, collection(s.snapmirrorRecords(), r)
+	return
+}
+
+// createSnapmirror creates and initializes SnapMirror relationship as async job
+func (s *RestServer) createSnapmirror(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	req := snapmirrorBody{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	if req.Source == nil || req.Destination == nil {
+		err = badRequest("source and destination paths are required")
+		return
+	}
+	policy, schedule := req.names()
+	return s.startJob("POST /api/snapmirror/relationships", func() error {
+		return s.client.SnapmirrorCreate(req.Source.Path, req.Destination.Path, policy, schedule)
+	})
+}
+
+// modifySnapmirror breaks SnapMirror relationship or modifies its policy and schedule as async job
+func (s *RestServer) modifySnapmirror(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var destinationPath string
+	if destinationPath, err = s.snapmirrorPath(ids[0]); err != nil {
+		return
+	}
+	req := snapmirrorBody{}
+	if err = decodeBody(r, &req); err != nil {
+		return
+	}
+	policy, schedule := req.names()
+	return s.startJob("PATCH /api/snapmirror/relationships/"+ids[0], func() error {
+		if req.State == "broken_off" {
+			return s.client.SnapmirrorBreak(destinationPath)
+		}
+		return s.client.SnapmirrorModify(destinationPath, policy, schedule)
+	})
+}
+
+// deleteSnapmirror deletes SnapMirror relationship as async job
+func (s *RestServer) deleteSnapmirror(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var destinationPath string
+	if destinationPath, err = s.snapmirrorPath(ids[0]); err != nil {
+		return
+	}
+	return s.startJob("DELETE /api/snapmirror/relationships/"+ids[0], func() error {
+		return s.client.SnapmirrorDelete(destinationPath)
+	})
+}
+
+// transferSnapmirror starts SnapMirror transfer as async job
+func (s *RestServer) transferSnapmirror(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	var destinationPath string
+	if destinationPath, err = s.snapmirrorPath(ids[0]); err != nil {
+		return
+	}
+	return s.startJob("POST /api/snapmirror/relationships/"+ids[0]+"/transfers", func() error {
+		return s.client.SnapmirrorUpdate(destinationPath)
+	})
+}
@@ -0,0 +1,481 @@
+package fake
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/igor-feoktistov/go-ontap-rest/ontap"
+)
+
+// RestServer is ONTAP REST API simulator backed by in-memory cluster,
+// it serves endpoints used by client.OntapRestAPI and go-ontap-rest over TLS,
+// use Host() as cdotCredentials.host with "rest" API method
+type RestServer struct {
+	*httptest.Server
+	cluster *Cluster
+	client  *Client
+	routes  []route
+	// Node is cluster node name reported in LUN, namespace and LIF locations
+	Node string
+	// Version is ONTAP version reported by /api/cluster in "<generation>.<major>.<minor>" format
+	Version string
+	// User and Password enable basic authentication if set
+	User     string
+	Password string
+	// JobDelay is time async job stays in "running" state before the job operation runs,
+	// with zero delay the operation completes before the job is returned
+	JobDelay time.Duration
+	mu       sync.Mutex
+	jobs     map[string]*job
+	jobSeq   int
+}
+
+// RestError is ONTAP REST API error, inject it as fault to fail requests with specific HTTP status and REST code
+type RestError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+// Error formats REST error
+func (e *RestError) Error() string {
+	return fmt.Sprintf("REST code=%s, REST message=\"%s\"", e.Code, e.Message)
+}
+
+type job struct {
+	uuid        string
+	description string
+	state       string
+	code        int
+	message     string
+	startTime   time.Time
+	endTime     time.Time
+}
+
+type record map[string]interface{}
+
+type handler func(r *http.Request, ids []string) (status int, body interface{}, err error)
+
+type route struct {
+	method  string
+	pattern string
+	handler handler
+}
+
+// parameters which are not record filters
+var ignoredParameters = map[string]bool{
+	"fields":                   true,
+	"return_records":           true,
+	"return_timeout":           true,
+	"return_metadata":          true,
+	"max_records":              true,
+	"order_by":                 true,
+	"allow_delete_with_hosts":  true,
+	"data.offset":              true,
+	"data.size":                true,
+	"byte_offset":              true,
+	"length":                   true,
+	"restore_to.snapshot.uuid": true,
+}
+
+// NewRestServer starts REST API simulator for cluster
+func NewRestServer(cluster *Cluster) *RestServer {
+	s := &RestServer{
+		cluster: cluster,
+		client:  &Client{cluster: cluster},
+		Node:    cluster.Svm + "-01",
+		Version: "9.13.1",
+		jobs:    make(map[string]*job),
+	}
+	s.routes = []route{
+		{"GET", "/api/cluster", s.getCluster},
+		{"GET", "/api/svm/svms", s.getSvms},
+		{"GET", "/api/cluster/jobs/*", s.getJob},
+		{"GET", "/api/storage/volumes", s.getVolumes},
+		{"POST", "/api/storage/volumes", s.createVolume},
+		{"GET", "/api/storage/volumes/*", s.getVolume},
+		{"PATCH", "/api/storage/volumes/*", s.modifyVolume},
+		{"DELETE", "/api/storage/volumes/*", s.deleteVolume},
+		{"GET", "/api/storage/volumes/*/snapshots", s.getSnapshots},
+		{"POST", "/api/storage/volumes/*/snapshots", s.createSnapshot},
+		{"GET", "/api/storage/volumes/*/snapshots/*", s.getSnapshot},
+		{"PATCH", "/api/storage/volumes/*/snapshots/*", s.modifySnapshot},
+		{"DELETE", "/api/storage/volumes/*/snapshots/*", s.deleteSnapshot},
+		{"GET", "/api/storage/volumes/*/files/**", s.getFiles},
+		{"POST", "/api/storage/volumes/*/files/**", s.writeFile},
+		{"PATCH", "/api/storage/volumes/*/files/**", s.writeFile},
+		{"DELETE", "/api/storage/volumes/*/files/**", s.deleteFile},
+		{"GET", "/api/storage/luns", s.getLuns},
+		{"POST", "/api/storage/luns", s.createLun},
+		{"GET", "/api/storage/luns/*", s.getLun},
+		{"PATCH", "/api/storage/luns/*", s.modifyLun},
+		{"DELETE", "/api/storage/luns/*", s.deleteLun},
+		{"GET", "/api/protocols/san/lun-maps", s.getLunMaps},
+		{"POST", "/api/protocols/san/lun-maps", s.createLunMap},
+		{"DELETE", "/api/protocols/san/lun-maps/*/*", s.deleteLunMap},
+		{"GET", "/api/protocols/san/igroups", s.getIgroups},
+		{"POST", "/api/protocols/san/igroups", s.createIgroup},
+		{"GET", "/api/protocols/san/igroups/*", s.getIgroup},
+		{"DELETE", "/api/protocols/san/igroups/*", s.deleteIgroup},
+		{"POST", "/api/protocols/san/igroups/*/initiators", s.addIgroupInitiators},
+		{"GET", "/api/protocols/san/iscsi/services", s.getIscsiServices},
+		{"GET", "/api/protocols/san/iscsi/credentials", s.getIscsiCredentials},
+		{"POST", "/api/protocols/san/iscsi/credentials", s.setIscsiCredentials},
+		{"PATCH", "/api/protocols/san/iscsi/credentials/*/*", s.setIscsiCredentials},
+		{"DELETE", "/api/protocols/san/iscsi/credentials/*/*", s.deleteIscsiCredentials},
+		{"GET", "/api/protocols/san/fcp/services", s.getFcpServices},
+		{"GET", "/api/network/fc/interfaces", s.getFcInterfaces},
+		{"GET", "/api/network/ip/interfaces", s.getIpInterfaces},
+		{"POST", "/api/protocols/nfs/export-policies", s.createExportPolicy},
+		{"GET", "/api/private/cli/volume", s.getCliVolumes},
+		{"POST", "/api/private/cli/lun", s.createCliLun},
+		{"GET", "/api/storage/namespaces", s.getNamespaces},
+		{"POST", "/api/storage/namespaces", s.createNamespace},
+		{"GET", "/api/storage/namespaces/*", s.getNamespace},
+		{"PATCH", "/api/storage/namespaces/*", s.modifyNamespace},
+		{"DELETE", "/api/storage/namespaces/*", s.deleteNamespace},
+		{"GET", "/api/protocols/nvme/subsystems", s.getSubsystems},
+		{"POST", "/api/protocols/nvme/subsystems", s.createSubsystem},
+		{"GET", "/api/protocols/nvme/subsystems/*", s.getSubsystem},
+		{"DELETE", "/api/protocols/nvme/subsystems/*", s.deleteSubsystem},
+		{"GET", "/api/protocols/nvme/subsystems/*/hosts", s.getSubsystemHosts},
+		{"POST", "/api/protocols/nvme/subsystems/*/hosts", s.addSubsystemHost},
+		{"GET", "/api/protocols/nvme/subsystem-maps", s.getSubsystemMaps},
+		{"POST", "/api/protocols/nvme/subsystem-maps", s.createSubsystemMap},
+		{"DELETE", "/api/protocols/nvme/subsystem-maps/*/*", s.deleteSubsystemMap},
+		{"GET", "/api/snapmirror/relationships", s.getSnapmirrors},
+		{"POST", "/api/snapmirror/relationships", s.createSnapmirror},
+		{"PATCH", "/api/snapmirror/relationships/*", s.modifySnapmirror},
+		{"DELETE", "/api/snapmirror/relationships/*", s.deleteSnapmirror},
+		{"POST", "/api/snapmirror/relationships/*/transfers", s.transferSnapmirror},
+	}
+	s.Server = httptest.NewTLSServer(s)
+	return s
+}
+
+// Host gets "<address>:<port>" of the simulator
+func (s *RestServer) Host() string {
+	return strings.TrimPrefix(s.URL, "https://")
+}
+
+// Cluster gets in-memory cluster of the simulator
+func (s *RestServer) Cluster() *Cluster {
+	return s.cluster
+}
+
+// ServeHTTP routes REST API request
+func (s *RestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.User != "" {
+		if user, password, ok := r.BasicAuth(); !ok || user != s.User || password != s.Password {
+			writeError(w, &RestError{Status: http.StatusUnauthorized, Code: "6691623", Message: "User is not authorized"})
+			return
+		}
+	}
+	methodAllowed := false
+	for _, rt := range s.routes {
+		ids, ok := matchRoute(rt.pattern, r.URL.Path)
+		if !ok {
+			continue
+		}
+		if rt.method != r.Method {
+			methodAllowed = true
+			continue
+		}
+		if err := s.cluster.fault(rt.method+" "+rt.pattern, r.URL.Path); err != nil {
+			writeError(w, err)
+			return
+		}
+		status, body, err := rt.handler(r, ids)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		switch b := body.(type) {
+		case *multipartBody:
+			writeMultipart(w, b)
+		default:
+			writeJSON(w, status, body)
+		}
+		return
+	}
+	if methodAllowed {
+		writeError(w, &RestError{Status: http.StatusMethodNotAllowed, Code: "3", Message: "Method not allowed"})
+	} else {
+		writeError(w, &RestError{Status: http.StatusNotFound, Code: "3", Message: "API not found"})
+	}
+}
+
+// matchRoute matches path to route pattern, "*" matches one path segment and "**" matches the rest of the path
+func matchRoute(pattern string, path string) (ids []string, ok bool) {
+	patternParts := strings.Split(pattern, "/")
+	pathParts := strings.Split(path, "/")
+	for i, part := range patternParts {
+		if part == "**" {
+			if i >= len(pathParts) {
+				return nil, false
+			}
+			ids = append(ids, "/"+strings.TrimLeft(strings.Join(pathParts[i:], "/"), "/"))
+			return ids, true
+		}
+		if i >= len(pathParts) {
+			return nil, false
+		}
+		if part == "*" {
+			if pathParts[i] == "" {
+				return nil, false
+			}
+			ids = append(ids, pathParts[i])
+		} else if part != pathParts[i] {
+			return nil, false
+		}
+	}
+	return ids, len(patternParts) == len(pathParts)
+}
+
+// startJob runs operation as async job, the job is reported as "running" for JobDelay
+func (s *RestServer) startJob(description string, op func() error) (status int, body interface{}, err error) {
+	s.mu.Lock()
+	s.jobSeq++
+	j := &job{
+		uuid:        fmt.Sprintf("00000000-0000-0000-0000-%012d", s.jobSeq),
+		description: description,
+		state:       "running",
+		startTime:   time.Now(),
+	}
+	s.jobs[j.uuid] = j
+	delay := s.JobDelay
+	s.mu.Unlock()
+	run := func() {
+		time.Sleep(delay)
+		opErr := op()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if opErr != nil {
+			restErr := toRestError(opErr)
+			j.state = "failure"
+			j.code, _ = strconv.Atoi(restErr.Code)
+			j.message = restErr.Message
+		} else {
+			j.state = "success"
+			j.message = "success"
+		}
+		j.endTime = time.Now()
+	}
+	if delay == 0 {
+		run()
+	} else {
+		go run()
+	}
+	status = http.StatusAccepted
+	body = record{"job": record{"uuid": j.uuid, "_links": links("/api/cluster/jobs/" + j.uuid)}}
+	return
+}
+
+// getJob gets async job status
+func (s *RestServer) getJob(r *http.Request, ids []string) (status int, body interface{}, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, exists := s.jobs[ids[0]]
+	if !exists {
+		err = notFound("job \"%s\" not found", ids[0])
+		return
+	}
+	rec := record{
+		"uuid":        j.uuid,
+		"description": j.description,
+		"state":       j.state,
+		"code":        j.code,
+		"message":     j.message,
+		"start_time":  j.startTime.Format(time.RFC3339),
+		"_links":      links("/api/cluster/jobs/" + j.uuid),
+	}
+	if !j.endTime.IsZero() {
+		rec["end_time"] = j.endTime.Format(time.RFC3339)
+	}
+	status, body = http.StatusOK, rec
+	return
+}
+
+// uuidFor makes stable UUID for object kind and name
+func uuidFor(kind string, name string) string {
+	sum := md5.Sum([]byte(kind + ":" + name))
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// links makes "_links" record
+func links(href string) record {
+	return record{"self": record{"href": href}}
+}
+
+// collection makes collection response of records matching query filters
+func collection(records []record, r *http.Request) record {
+	matched := []record{}
+	for _, rec := range records {
+		if matchRecord(rec, r) {
+			matched = append(matched, rec)
+		}
+	}
+	return record{"records": matched, "num_records": len(matched)}
+}
+
+// matchRecord checks if record matches query filters, "|" separates alternative values
+func matchRecord(rec record, r *http.Request) bool {
+	for key, values := range r.URL.Query() {
+		if ignoredParameters[key] || len(values) == 0 {
+			continue
+		}
+		if !matchValue(lookupValue(rec, strings.Split(key, ".")), strings.Split(values[0], "|")) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupValue gets record value by dotted path, values of record lists are collected
+func lookupValue(value interface{}, keys []string) interface{} {
+	if len(keys) == 0 {
+		return value
+	}
+	switch v := value.(type) {
+	case record:
+		return lookupValue(v[keys[0]], keys[1:])
+	case []record:
+		var values []interface{}
+		for _, item := range v {
+			values = append(values, lookupValue(item, keys))
+		}
+		return values
+	}
+	return nil
+}
+
+// matchValue checks if value or any value of the list equals to one of patterns
+func matchValue(value interface{}, patterns []string) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case []interface{}:
+		for _, item := range v {
+			if matchValue(item, patterns) {
+				return true
+			}
+		}
+		return false
+	case []string:
+		for _, item := range v {
+			if matchValue(item, patterns) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, pattern := range patterns {
+		if fmt.Sprint(value) == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// notFound makes REST "entry doesn't exist" error
+func notFound(format string, args ...interface{}) *RestError {
+	return &RestError{Status: http.StatusNotFound, Code: ontap.ERROR_ENTRY_DOES_NOT_EXIST, Message: fmt.Sprintf(format, args...)}
+}
+
+// badRequest makes REST invalid request error
+func badRequest(format string, args ...interface{}) *RestError {
+	return &RestError{Status: http.StatusBadRequest, Code: "262179", Message: fmt.Sprintf(format, args...)}
+}
+
+// toRestError converts in-memory client error to REST error
+func toRestError(err error) *RestError {
+	if restErr, ok := err.(*RestError); ok {
+		return restErr
+	}
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "not found"):
+		return &RestError{Status: http.StatusNotFound, Code: ontap.ERROR_ENTRY_DOES_NOT_EXIST, Message: message}
+	case strings.Contains(message, "already exists"):
+		return &RestError{Status: http.StatusConflict, Code: "1", Message: message}
+	}
+	return &RestError{Status: http.StatusBadRequest, Code: "1", Message: message}
+}
+
+// decodeBody decodes JSON request body
+func decodeBody(r *http.Request, v interface{}) (err error) {
+	var b []byte
+	if b, err = ioutil.ReadAll(r.Body); err != nil {
+		return
+	}
+	if len(b) == 0 {
+		return
+	}
+	if err = json.Unmarshal(b, v); err != nil {
+		err = badRequest("invalid JSON input: %s", err)
+	}
+	return
+}
+
+// isMultipart checks if request body is multipart form data
+func isMultipart(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/")
+}
+
+// readMultipart reads the first part of multipart request body
+func readMultipart(r *http.Request) (data []byte, err error) {
+	var reader *multipart.Reader
+	if reader, err = r.MultipartReader(); err != nil {
+		err = badRequest("invalid multipart input: %s", err)
+		return
+	}
+	var part *multipart.Part
+	if part, err = reader.NextPart(); err != nil {
+		err = badRequest("invalid multipart input: %s", err)
+		return
+	}
+	data, err = ioutil.ReadAll(part)
+	return
+}
+
+// multipartBody is data returned as multipart form file
+type multipartBody struct {
+	fileName string
+	data     []byte
+}
+
+func writeMultipart(w http.ResponseWriter, body *multipartBody) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, _ := writer.CreateFormFile("file", body.fileName)
+	part.Write(body.data)
+	writer.Close()
+	w.Header().Set("Content-Type", writer.FormDataContentType())
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	if body == nil {
+		body = record{}
+	}
+	b, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", "application/hal+json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	restErr := toRestError(err)
+	writeJSON(w, restErr.Status, record{"error": record{"code": restErr.Code, "message": restErr.Message}})
+}
@@ -3,6 +3,7 @@ package client
 import (
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
 )
@@ -13,28 +14,41 @@ type OntapClient interface {
 	VolumeExists(volumeName string) (bool, error)
 	VolumeCreateSAN(volumeName string, aggregateName string, volumeSize int) error
 	VolumeCreateNAS(volumeName string, aggregateName string, exportPolicyName string, volumeSize int) error
+	VolumeCreateDP(volumeName string, aggregateName string, volumeSize int) error
 	VolumeDestroy(volumeName string) error
 	VolumeResize(volumeName string, volumeSize int) error
+	VolumeGetAggregate(volumeName string) (string, error)
+	VolumeMoveStart(volumeName string, aggregateName string) error
+	VolumeMoveGetStatus(volumeName string) (*VolumeMoveInfo, error)
 	ExportPolicyCreate(exportPolicyName string) error
 	IgroupExists(volumeName string) (bool, error)
-	IgroupCreate(igroupName string, osType string) error
+	IgroupCreate(igroupName string, protocol string, osType string) error
 	IgroupAddInitiator(igroupName string, initiatorName string) error
+	IgroupGetInitiators(igroupName string) ([]string, error)
 	IgroupDestroy(igroupName string) error
 	LunExists(lunPath string) (bool, error)
 	IsLunMapped(lunPath string, igroupName string) (bool, error)
+	LunGetMaps(lunPath string) ([]LunMapInfo, error)
 	LunGetInfo(lunPath string) (*LunInfo, error)
 	LunGetList(volumeName string) ([]string, error)
+	LunGetInfoList(volumeName string) ([]LunInfo, error)
+	LunSetComment(lunPath string, lunComment string) error
+	LunRename(lunPath string, newLunPath string) error
 	LunCopy(imagePath string, lunPath string) error
 	LunResize(lunPath string, lunSize int) error
 	LunMap(lunPath string, lunID int, igroupName string) error
 	LunUnmap(lunPath string, igroupName string) error
 	LunCreate(lunPath string, lunSize int, osType string) error
 	LunCreateFromFile(volumeName string, filePath string, lunPath string, lunComment string, osType string) error
-	LunCreateAndUpload(volumeName string, filePath string, fileSize int64, fileReader io.Reader, lunPath string, lunComment string, osType string) error
-	LunUpload(lunPath string, fileReader io.Reader, fileSize int64) error
+	LunCreateAndUpload(volumeName string, filePath string, fileSize int64, fileReader io.Reader, lunPath string, lunComment string, osType string, progress UploadProgress) error
+	LunUpload(lunPath string, fileReader io.Reader, fileSize int64, progress UploadProgress) error
 	LunDestroy(lunPath string) error
 	IscsiTargetGetName() (string, error)
 	DiscoverIscsiLIFs(lunPath string, initiatorSubnet string) ([]string, error)
+	IscsiInitiatorSetAuth(initiatorName string, chapUser string, chapPassword string, outboundUser string, outboundPassword string) error
+	IscsiInitiatorDeleteAuth(initiatorName string) error
+	FcpTargetGetName() (string, error)
+	GetFcpLIFs() ([]FcInterface, error)
 	FileExists(volumeName string, filePath string) (bool, error)
 	FileGetList(volumeName string, dirPath string) ([]string, error)
 	FileDelete(volumName string, filePath string) error
@@ -42,14 +56,24 @@ type OntapClient interface {
 	FileUploadAPI(volumeName string, filePath string, reader io.Reader) error
 	FileUploadNFS(volumeName string, filePath string, reader io.Reader) error
 	SnapshotGetList(volumeName string) ([]string, error)
+	SnapshotGetInfoList(volumeName string) ([]SnapshotInfo, error)
 	SnapshotCreate(volumeName string, snapshotName string, snapshotComment string) error
+	SnapshotCreateGroup(volumeNames []string, snapshotName string) error
+	SnapshotSetComment(volumeName string, snapshotName string, snapshotComment string) error
 	SnapshotDelete(volumeName string, snapshotName string) (err error)
 	SnapshotRestore(volumeName string, snapshotName string) error
+	SnapmirrorGet(destinationPath string) (*SnapmirrorInfo, error)
+	SnapmirrorCreate(sourcePath string, destinationPath string, policy string, schedule string) error
+	SnapmirrorModify(destinationPath string, policy string, schedule string) error
+	SnapmirrorUpdate(destinationPath string) error
+	SnapmirrorBreak(destinationPath string) error
+	SnapmirrorDelete(destinationPath string) error
 	NvmeTargetGetNqn(subsystemName string) (string, error)
         NvmeSubsystemExists(subsystemName string) (bool, error)
         NvmeSubsystemCreate(subsystemName string, osType string) error
         NvmeSubsystemDestroy(subsystemName string) error
         NvmeSubsystemAddHost(subsystemName string, hostNqn string) error
+	NvmeSubsystemGetHosts(subsystemName string) ([]string, error)
 	NvmeNamespaceExists(namespacePath string) (bool, error)
 	NvmeNamespaceGetInfo(namespacePath string) (*NvmeNamespaceInfo, error)
 	IsNvmeNamespaceMapped(namespacePath string) (bool, error)
@@ -62,10 +86,20 @@ type OntapClient interface {
 	DiscoverNvmeLIFs(namespacePath string, hostSubnet string) ([]string, error)
 }
 
-// LunInfo is generic LUN info
+// LunInfo is generic LUN info,
+// Path, CreateTime and Source (LUN copy source path if known) are set by LunGetInfoList
 type LunInfo struct {
-	Comment string
-	Size    int
+	Path       string
+	Comment    string
+	Size       int
+	CreateTime time.Time
+	Source     string
+}
+
+// LunMapInfo is generic LUN mapping info
+type LunMapInfo struct {
+	IgroupName string
+	LunId      int
 }
 
 // NvmeNamespaceInfo is generic NVME Namespace info
@@ -74,13 +108,92 @@ type NvmeNamespaceInfo struct {
 	Size    int
 }
 
-// NewOntapClient creates cDOT client
+// FcInterface is FC LIF, Fabric is the name of SAN fabric LIF is logged in to (empty if not reported)
+type FcInterface struct {
+	Name   string
+	Wwpn   string
+	Fabric string
+}
+
+// SnapshotInfo is generic snapshot info
+type SnapshotInfo struct {
+	Name       string
+	Comment    string
+	CreateTime time.Time
+}
+
+// SnapmirrorInfo is generic SnapMirror relationship info
+type SnapmirrorInfo struct {
+	SourcePath      string
+	DestinationPath string
+	Policy          string
+	Schedule        string
+	State           string
+	Healthy         bool
+}
+
+// VolumeMoveInfo is generic volume move status info
+type VolumeMoveInfo struct {
+	DestinationAggregate string
+	State                string
+	PercentComplete      int
+	Details              string
+}
+
+// ClientFactory creates cDOT client for node storage configuration
+type ClientFactory func(nodeConfig *config.NodeConfig) (OntapClient, error)
+
+// clientFactory overrides REST/ZAPI clients if set
+var clientFactory ClientFactory
+
+// SetClientFactory replaces REST/ZAPI clients with clients made by factory (i.e. in-memory client for testing),
+// nil factory restores default clients
+func SetClientFactory(factory ClientFactory) {
+	clientFactory = factory
+}
+
+// NewOntapClient creates cDOT client, DR cluster client is created for failed over node,
+// the client is reused from session cache if set, mutations are recorded instead of the calls if nodeConfig.Plan is set
 func NewOntapClient(nodeConfig *config.NodeConfig) (ontap OntapClient, err error) {
+	if nodeConfig.Storage.Replication.FailedOver {
+		return NewOntapReplicaClient(nodeConfig)
+	}
+	return getOntapClient(nodeConfig)
+}
+
+// NewOntapReplicaClient creates cDOT client for SnapMirror destination (DR) cluster
+func NewOntapReplicaClient(nodeConfig *config.NodeConfig) (ontap OntapClient, err error) {
+	replicaConfig := *nodeConfig
+	replicaConfig.Storage.CdotCredentials = nodeConfig.Storage.Replication.CdotCredentials
+	replicaConfig.Storage.SvmName = nodeConfig.Storage.Replication.SvmName
+	return getOntapClient(&replicaConfig)
+}
+
+// getOntapClient gets cDOT client from session cache or creates new one, the client is wrapped with plan client in dry-run mode
+func getOntapClient(nodeConfig *config.NodeConfig) (ontap OntapClient, err error) {
+	if sessionCache != nil {
+		ontap, err = sessionCache.Get(nodeConfig, newOntapClient)
+	} else {
+		ontap, err = newOntapClient(nodeConfig)
+	}
+	if err == nil && nodeConfig.Plan != nil {
+		ontap = newPlanClient(ontap, nodeConfig)
+	}
+	return
+}
+
+// newOntapClient creates cDOT client for API method
+func newOntapClient(nodeConfig *config.NodeConfig) (ontap OntapClient, err error) {
+	if clientFactory != nil {
+		return clientFactory(nodeConfig)
+	}
 	switch nodeConfig.Storage.CdotCredentials.ApiMethod {
 	case "rest":
 		ontap, err = NewOntapRestAPI(nodeConfig)
 	case "zapi":
 		ontap, err = NewOntapZAPI(nodeConfig)
+	case "auto":
+		ontap, err = NewOntapAutoClient(nodeConfig)
 	default:
 		err = fmt.Errorf("NewOntapAPI(): API method \"%s\" is not implemented", nodeConfig.Storage.CdotCredentials.ApiMethod)
 	}
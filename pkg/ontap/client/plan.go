@@ -0,0 +1,945 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+const (
+	planBackend = "ontap"
+	// planLIF is placeholder for iSCSI LIF of LUN planned to be created
+	planLIF = "<LIF of planned LUN>"
+)
+
+// planState is cDOT objects planned to be created, modified or deleted,
+// the state is shared by all plan clients of the same cDOT host and SVM
+type planState struct {
+	mu          sync.Mutex
+	exists      map[string]bool
+	aggregates  map[string]string
+	luns        map[string]*LunInfo
+	lunMaps     map[string]map[string]int
+	initiators  map[string][]string
+	hosts       map[string][]string
+	namespaces  map[string]*NvmeNamespaceInfo
+	nsMaps      map[string]string
+	files       map[string][]byte
+	snapshots   map[string]*SnapshotInfo
+	snapmirrors map[string]*SnapmirrorInfo
+	moves       map[string]*VolumeMoveInfo
+}
+
+// planClient is dry-run cDOT client, read calls go to cDOT and mutations are recorded in the plan,
+// reads of planned objects are answered from plan state
+type planClient struct {
+	OntapClient
+	plan  *config.Plan
+	host  string
+	state *planState
+}
+
+func newPlanState() interface{} {
+	return &planState{
+		exists:      make(map[string]bool),
+		aggregates:  make(map[string]string),
+		luns:        make(map[string]*LunInfo),
+		lunMaps:     make(map[string]map[string]int),
+		initiators:  make(map[string][]string),
+		hosts:       make(map[string][]string),
+		namespaces:  make(map[string]*NvmeNamespaceInfo),
+		nsMaps:      make(map[string]string),
+		files:       make(map[string][]byte),
+		snapshots:   make(map[string]*SnapshotInfo),
+		snapmirrors: make(map[string]*SnapmirrorInfo),
+		moves:       make(map[string]*VolumeMoveInfo),
+	}
+}
+
+// newPlanClient wraps cDOT client to record mutations in node configuration plan
+func newPlanClient(c OntapClient, nodeConfig *config.NodeConfig) OntapClient {
+	host := nodeConfig.Storage.CdotCredentials.Host
+	if nodeConfig.Storage.SvmName != "" {
+		host += "/" + nodeConfig.Storage.SvmName
+	}
+	return &planClient{
+		OntapClient: c,
+		plan:        nodeConfig.Plan,
+		host:        host,
+		state:       nodeConfig.Plan.State(planBackend+":"+host, newPlanState).(*planState),
+	}
+}
+
+func (c *planClient) record(operation string, format string, args ...interface{}) {
+	c.plan.Record(planBackend, c.host, operation, format, args...)
+}
+
+// planned gets planned existence of object, ok is false if object is not in plan
+func (c *planClient) planned(kind string, name string) (exists bool, ok bool) {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	exists, ok = c.state.exists[kind+":"+name]
+	return
+}
+
+func (c *planClient) setPlanned(kind string, name string, exists bool) {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.exists[kind+":"+name] = exists
+}
+
+// volumePlanned checks if volume of LUN, namespace or file path is planned to be created or deleted,
+// objects in such volume exist only if they are planned to be created
+func (c *planClient) volumePlanned(volumeName string) bool {
+	_, ok := c.planned("volume", volumeName)
+	return ok
+}
+
+// pathVolume gets volume name from "/vol/<volume>/<name>" path
+func pathVolume(objectPath string) string {
+	return strings.SplitN(strings.TrimPrefix(objectPath, "/vol/"), "/", 2)[0]
+}
+
+// exists gets existence of object from plan or from cDOT
+func (c *planClient) exists(kind string, name string, volumeName string, get func(string) (bool, error)) (bool, error) {
+	if exists, ok := c.planned(kind, name); ok {
+		return exists, nil
+	}
+	if volumeName != "" && c.volumePlanned(volumeName) {
+		return false, nil
+	}
+	return get(name)
+}
+
+// mergeNames applies planned creates and deletes of "<kind>:<prefix><name>" objects to list of names
+func (c *planClient) mergeNames(kind string, prefix string, names []string) (merged []string) {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	merged = []string{}
+	known := make(map[string]bool)
+	for _, name := range names {
+		known[name] = true
+		if exists, ok := c.state.exists[kind+":"+prefix+name]; ok && !exists {
+			continue
+		}
+		merged = append(merged, name)
+	}
+	var added []string
+	for key, exists := range c.state.exists {
+		if !exists || !strings.HasPrefix(key, kind+":"+prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(key, kind+":"+prefix)
+		if !known[name] && (prefix == "" || !strings.Contains(name, "/")) {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+	merged = append(merged, added...)
+	return
+}
+
+// VolumeExists checks if volume exists or is planned to be created
+func (c *planClient) VolumeExists(volumeName string) (bool, error) {
+	return c.exists("volume", volumeName, "", c.OntapClient.VolumeExists)
+}
+
+// VolumeCreateSAN records SAN volume creation
+func (c *planClient) VolumeCreateSAN(volumeName string, aggregateName string, volumeSize int) error {
+	c.record("VolumeCreateSAN", "create SAN volume %s on aggr %s, size %dGB", volumeName, aggregateName, volumeSize)
+	c.volumeCreated(volumeName, aggregateName)
+	return nil
+}
+
+// VolumeCreateNAS records NAS volume creation
+func (c *planClient) VolumeCreateNAS(volumeName string, aggregateName string, exportPolicyName string, volumeSize int) error {
+	c.record("VolumeCreateNAS", "create NAS volume %s on aggr %s with export policy %s, size %dGB", volumeName, aggregateName, exportPolicyName, volumeSize)
+	c.volumeCreated(volumeName, aggregateName)
+	return nil
+}
+
+// VolumeCreateDP records data protection volume creation
+func (c *planClient) VolumeCreateDP(volumeName string, aggregateName string, volumeSize int) error {
+	c.record("VolumeCreateDP", "create DP volume %s on aggr %s, size %dGB", volumeName, aggregateName, volumeSize)
+	c.volumeCreated(volumeName, aggregateName)
+	return nil
+}
+
+func (c *planClient) volumeCreated(volumeName string, aggregateName string) {
+	c.setPlanned("volume", volumeName, true)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.aggregates[volumeName] = aggregateName
+}
+
+// VolumeDestroy records volume deletion
+func (c *planClient) VolumeDestroy(volumeName string) error {
+	c.record("VolumeDestroy", "destroy volume %s", volumeName)
+	c.setPlanned("volume", volumeName, false)
+	return nil
+}
+
+// VolumeResize records volume resize
+func (c *planClient) VolumeResize(volumeName string, volumeSize int) error {
+	c.record("VolumeResize", "resize volume %s to %dGB", volumeName, volumeSize)
+	return nil
+}
+
+// VolumeGetAggregate gets aggregate of volume, planned aggregate is returned for planned volumes
+func (c *planClient) VolumeGetAggregate(volumeName string) (string, error) {
+	c.state.mu.Lock()
+	aggregateName, ok := c.state.aggregates[volumeName]
+	c.state.mu.Unlock()
+	if ok {
+		return aggregateName, nil
+	}
+	return c.OntapClient.VolumeGetAggregate(volumeName)
+}
+
+// VolumeMoveStart records volume move, the planned move is reported as completed
+func (c *planClient) VolumeMoveStart(volumeName string, aggregateName string) error {
+	c.record("VolumeMoveStart", "move volume %s to aggr %s", volumeName, aggregateName)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.aggregates[volumeName] = aggregateName
+	c.state.moves[volumeName] = &VolumeMoveInfo{
+		DestinationAggregate: aggregateName,
+		State:                "success",
+		PercentComplete:      100,
+		Details:              "planned",
+	}
+	return nil
+}
+
+// VolumeMoveGetStatus gets volume move status
+func (c *planClient) VolumeMoveGetStatus(volumeName string) (*VolumeMoveInfo, error) {
+	c.state.mu.Lock()
+	move, ok := c.state.moves[volumeName]
+	c.state.mu.Unlock()
+	if ok {
+		info := *move
+		return &info, nil
+	}
+	return c.OntapClient.VolumeMoveGetStatus(volumeName)
+}
+
+// ExportPolicyCreate records export policy creation
+func (c *planClient) ExportPolicyCreate(exportPolicyName string) error {
+	c.record("ExportPolicyCreate", "create export policy %s", exportPolicyName)
+	return nil
+}
+
+// IgroupExists checks if iGroup exists or is planned to be created
+func (c *planClient) IgroupExists(igroupName string) (bool, error) {
+	return c.exists("igroup", igroupName, "", c.OntapClient.IgroupExists)
+}
+
+// IgroupCreate records iGroup creation
+func (c *planClient) IgroupCreate(igroupName string, protocol string, osType string) error {
+	c.record("IgroupCreate", "create igroup %s, protocol %s, OS type %s", igroupName, protocol, osType)
+	c.setPlanned("igroup", igroupName, true)
+	return nil
+}
+
+// IgroupAddInitiator records adding initiator to iGroup
+func (c *planClient) IgroupAddInitiator(igroupName string, initiatorName string) error {
+	c.record("IgroupAddInitiator", "add initiator %s to igroup %s", initiatorName, igroupName)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.initiators[igroupName] = append(c.state.initiators[igroupName], initiatorName)
+	return nil
+}
+
+// IgroupGetInitiators gets iGroup initiators including planned ones
+func (c *planClient) IgroupGetInitiators(igroupName string) (initiators []string, err error) {
+	initiators = []string{}
+	if _, ok := c.planned("igroup", igroupName); !ok {
+		if initiators, err = c.OntapClient.IgroupGetInitiators(igroupName); err != nil {
+			return
+		}
+	}
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	initiators = append(initiators, c.state.initiators[igroupName]...)
+	return
+}
+
+// IgroupDestroy records iGroup deletion
+func (c *planClient) IgroupDestroy(igroupName string) error {
+	c.record("IgroupDestroy", "destroy igroup %s", igroupName)
+	c.setPlanned("igroup", igroupName, false)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	delete(c.state.initiators, igroupName)
+	return nil
+}
+
+// LunExists checks if LUN exists or is planned to be created
+func (c *planClient) LunExists(lunPath string) (bool, error) {
+	return c.exists("lun", lunPath, pathVolume(lunPath), c.OntapClient.LunExists)
+}
+
+// IsLunMapped checks if LUN is mapped or is planned to be mapped to iGroup
+func (c *planClient) IsLunMapped(lunPath string, igroupName string) (bool, error) {
+	c.state.mu.Lock()
+	lunID, ok := c.state.lunMaps[lunPath][igroupName]
+	c.state.mu.Unlock()
+	if ok {
+		return lunID >= 0, nil
+	}
+	if _, ok = c.planned("lun", lunPath); ok || c.volumePlanned(pathVolume(lunPath)) {
+		return false, nil
+	}
+	return c.OntapClient.IsLunMapped(lunPath, igroupName)
+}
+
+// LunGetMaps gets LUN maps with planned maps and unmaps applied
+func (c *planClient) LunGetMaps(lunPath string) (lunMaps []LunMapInfo, err error) {
+	lunMaps = []LunMapInfo{}
+	if _, ok := c.planned("lun", lunPath); !ok && !c.volumePlanned(pathVolume(lunPath)) {
+		if lunMaps, err = c.OntapClient.LunGetMaps(lunPath); err != nil {
+			return
+		}
+	}
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	merged := []LunMapInfo{}
+	for _, lunMap := range lunMaps {
+		if _, ok := c.state.lunMaps[lunPath][lunMap.IgroupName]; !ok {
+			merged = append(merged, lunMap)
+		}
+	}
+	var igroups []string
+	for igroupName := range c.state.lunMaps[lunPath] {
+		igroups = append(igroups, igroupName)
+	}
+	sort.Strings(igroups)
+	for _, igroupName := range igroups {
+		if lunID := c.state.lunMaps[lunPath][igroupName]; lunID >= 0 {
+			merged = append(merged, LunMapInfo{IgroupName: igroupName, LunId: lunID})
+		}
+	}
+	lunMaps = merged
+	return
+}
+
+// lunInfo gets planned or cDOT LUN info
+func (c *planClient) lunInfo(lunPath string) (lunInfo *LunInfo, err error) {
+	c.state.mu.Lock()
+	planned, ok := c.state.luns[lunPath]
+	c.state.mu.Unlock()
+	if ok {
+		info := *planned
+		lunInfo = &info
+		return
+	}
+	if lunInfo, err = c.OntapClient.LunGetInfo(lunPath); err != nil {
+		return
+	}
+	lunInfo.Path = lunPath
+	return
+}
+
+func (c *planClient) setLunInfo(lunInfo *LunInfo) {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.luns[lunInfo.Path] = lunInfo
+}
+
+// LunGetInfo gets LUN info with planned changes applied
+func (c *planClient) LunGetInfo(lunPath string) (lunInfo *LunInfo, err error) {
+	if exists, ok := c.planned("lun", lunPath); ok && !exists {
+		err = fmt.Errorf("LunGetInfo(): LUN \"%s\" is planned to be destroyed", lunPath)
+		return
+	}
+	return c.lunInfo(lunPath)
+}
+
+// LunGetList gets list of LUN's in volume with planned changes applied
+func (c *planClient) LunGetList(volumeName string) (lunList []string, err error) {
+	if !c.volumePlanned(volumeName) {
+		if lunList, err = c.OntapClient.LunGetList(volumeName); err != nil {
+			return
+		}
+	}
+	lunList = c.mergeNames("lun", "/vol/"+volumeName+"/", lunList)
+	return
+}
+
+// LunGetInfoList gets info of LUN's in volume with planned changes applied
+func (c *planClient) LunGetInfoList(volumeName string) (lunInfoList []LunInfo, err error) {
+	lunInfoList = []LunInfo{}
+	if !c.volumePlanned(volumeName) {
+		if lunInfoList, err = c.OntapClient.LunGetInfoList(volumeName); err != nil {
+			return
+		}
+	}
+	var lunList []string
+	for _, lunInfo := range lunInfoList {
+		lunList = append(lunList, lunInfo.Path)
+	}
+	prefix := ""
+	if volumeName != "" {
+		prefix = "/vol/" + volumeName + "/"
+		for i := range lunList {
+			lunList[i] = strings.TrimPrefix(lunList[i], prefix)
+		}
+	}
+	c.state.mu.Lock()
+	planned := make(map[string]LunInfo)
+	for lunPath, lunInfo := range c.state.luns {
+		planned[lunPath] = *lunInfo
+	}
+	c.state.mu.Unlock()
+	merged := []LunInfo{}
+	for _, lunName := range c.mergeNames("lun", prefix, lunList) {
+		if lunInfo, ok := planned[prefix+lunName]; ok {
+			merged = append(merged, lunInfo)
+			continue
+		}
+		for _, lunInfo := range lunInfoList {
+			if lunInfo.Path == prefix+lunName {
+				merged = append(merged, lunInfo)
+			}
+		}
+	}
+	lunInfoList = merged
+	return
+}
+
+// LunSetComment records LUN comment update
+func (c *planClient) LunSetComment(lunPath string, lunComment string) (err error) {
+	c.record("LunSetComment", "set LUN %s comment \"%s\"", lunPath, lunComment)
+	var lunInfo *LunInfo
+	if lunInfo, err = c.lunInfo(lunPath); err == nil {
+		lunInfo.Comment = lunComment
+		c.setLunInfo(lunInfo)
+	}
+	err = nil
+	return
+}
+
+// LunRename records LUN rename
+func (c *planClient) LunRename(lunPath string, newLunPath string) error {
+	c.record("LunRename", "rename LUN %s to %s", lunPath, newLunPath)
+	lunInfo := &LunInfo{Path: newLunPath, CreateTime: time.Now()}
+	if sourceInfo, err := c.lunInfo(lunPath); err == nil {
+		*lunInfo = *sourceInfo
+		lunInfo.Path = newLunPath
+	}
+	c.lunCreated(lunInfo)
+	c.setPlanned("lun", lunPath, false)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	delete(c.state.luns, lunPath)
+	return nil
+}
+
+// LunCopy records LUN copy
+func (c *planClient) LunCopy(imagePath string, lunPath string) error {
+	c.record("LunCopy", "copy LUN %s to %s", imagePath, lunPath)
+	lunInfo := &LunInfo{Path: lunPath, CreateTime: time.Now(), Source: imagePath}
+	if sourceInfo, err := c.lunInfo(imagePath); err == nil {
+		lunInfo.Comment = sourceInfo.Comment
+		lunInfo.Size = sourceInfo.Size
+	}
+	c.lunCreated(lunInfo)
+	return nil
+}
+
+// LunResize records LUN resize
+func (c *planClient) LunResize(lunPath string, lunSize int) (err error) {
+	c.record("LunResize", "resize LUN %s to %dGB", lunPath, lunSize)
+	var lunInfo *LunInfo
+	if lunInfo, err = c.lunInfo(lunPath); err == nil {
+		lunInfo.Size = lunSize
+		c.setLunInfo(lunInfo)
+	}
+	err = nil
+	return
+}
+
+// LunMap records LUN mapping
+func (c *planClient) LunMap(lunPath string, lunID int, igroupName string) error {
+	c.record("LunMap", "map LUN %s to igroup %s, id %d", lunPath, igroupName, lunID)
+	c.setLunMap(lunPath, igroupName, lunID)
+	return nil
+}
+
+// LunUnmap records LUN unmapping
+func (c *planClient) LunUnmap(lunPath string, igroupName string) error {
+	c.record("LunUnmap", "unmap LUN %s from igroup %s", lunPath, igroupName)
+	c.setLunMap(lunPath, igroupName, -1)
+	return nil
+}
+
+func (c *planClient) setLunMap(lunPath string, igroupName string, lunID int) {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	if c.state.lunMaps[lunPath] == nil {
+		c.state.lunMaps[lunPath] = make(map[string]int)
+	}
+	c.state.lunMaps[lunPath][igroupName] = lunID
+}
+
+func (c *planClient) lunCreated(lunInfo *LunInfo) {
+	c.setPlanned("lun", lunInfo.Path, true)
+	c.setLunInfo(lunInfo)
+}
+
+// LunCreate records LUN creation
+func (c *planClient) LunCreate(lunPath string, lunSize int, osType string) error {
+	c.record("LunCreate", "create LUN %s, size %dGB, OS type %s", lunPath, lunSize, osType)
+	c.lunCreated(&LunInfo{Path: lunPath, Size: lunSize, CreateTime: time.Now()})
+	return nil
+}
+
+// LunCreateFromFile records LUN creation from file
+func (c *planClient) LunCreateFromFile(volumeName string, filePath string, lunPath string, lunComment string, osType string) error {
+	c.record("LunCreateFromFile", "create LUN %s from file %s in volume %s, OS type %s", lunPath, filePath, volumeName, osType)
+	c.lunCreated(&LunInfo{Path: lunPath, Comment: lunComment, CreateTime: time.Now()})
+	return nil
+}
+
+// LunCreateAndUpload records LUN creation with upload of content, the content is not read
+func (c *planClient) LunCreateAndUpload(volumeName string, filePath string, fileSize int64, fileReader io.Reader, lunPath string, lunComment string, osType string, progress UploadProgress) error {
+	c.record("LunCreateAndUpload", "create LUN %s in volume %s and upload %d bytes, OS type %s", lunPath, volumeName, fileSize, osType)
+	c.lunCreated(&LunInfo{Path: lunPath, Comment: lunComment, CreateTime: time.Now()})
+	return nil
+}
+
+// LunUpload records upload of LUN content, the content is not read
+func (c *planClient) LunUpload(lunPath string, fileReader io.Reader, fileSize int64, progress UploadProgress) error {
+	c.record("LunUpload", "upload %d bytes to LUN %s", fileSize, lunPath)
+	return nil
+}
+
+// LunDestroy records LUN deletion
+func (c *planClient) LunDestroy(lunPath string) error {
+	c.record("LunDestroy", "destroy LUN %s", lunPath)
+	c.setPlanned("lun", lunPath, false)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	delete(c.state.luns, lunPath)
+	delete(c.state.lunMaps, lunPath)
+	return nil
+}
+
+// DiscoverIscsiLIFs gets iSCSI LIF's for LUN, placeholder is returned for planned LUN's
+func (c *planClient) DiscoverIscsiLIFs(lunPath string, initiatorSubnet string) ([]string, error) {
+	if exists, ok := c.planned("lun", lunPath); ok && exists {
+		return []string{planLIF}, nil
+	}
+	return c.OntapClient.DiscoverIscsiLIFs(lunPath, initiatorSubnet)
+}
+
+// IscsiInitiatorSetAuth records iSCSI initiator CHAP configuration
+func (c *planClient) IscsiInitiatorSetAuth(initiatorName string, chapUser string, chapPassword string, outboundUser string, outboundPassword string) error {
+	c.record("IscsiInitiatorSetAuth", "set CHAP for iSCSI initiator %s, user %s", initiatorName, chapUser)
+	return nil
+}
+
+// IscsiInitiatorDeleteAuth records deletion of iSCSI initiator CHAP configuration
+func (c *planClient) IscsiInitiatorDeleteAuth(initiatorName string) error {
+	c.record("IscsiInitiatorDeleteAuth", "delete CHAP for iSCSI initiator %s", initiatorName)
+	return nil
+}
+
+// FileExists checks if file exists or is planned to be uploaded
+func (c *planClient) FileExists(volumeName string, filePath string) (bool, error) {
+	name := path.Join(volumeName, filePath)
+	if exists, ok := c.planned("file", name); ok {
+		return exists, nil
+	}
+	if c.volumePlanned(volumeName) {
+		return false, nil
+	}
+	return c.OntapClient.FileExists(volumeName, filePath)
+}
+
+// FileGetList gets list of files in directory with planned changes applied
+func (c *planClient) FileGetList(volumeName string, dirPath string) (fileList []string, err error) {
+	if !c.volumePlanned(volumeName) {
+		if fileList, err = c.OntapClient.FileGetList(volumeName, dirPath); err != nil {
+			return
+		}
+	}
+	fileList = c.mergeNames("file", path.Join(volumeName, dirPath)+"/", fileList)
+	return
+}
+
+// FileDelete records file deletion
+func (c *planClient) FileDelete(volumeName string, filePath string) error {
+	c.record("FileDelete", "delete file %s in volume %s", filePath, volumeName)
+	name := path.Join(volumeName, filePath)
+	c.setPlanned("file", name, false)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	delete(c.state.files, name)
+	return nil
+}
+
+// FileDownload downloads file, content of planned file is returned from plan state
+func (c *planClient) FileDownload(volumeName string, filePath string) ([]byte, error) {
+	name := path.Join(volumeName, filePath)
+	if exists, ok := c.planned("file", name); ok {
+		if !exists {
+			return nil, fmt.Errorf("FileDownload(): file \"%s\" is planned to be deleted", name)
+		}
+		c.state.mu.Lock()
+		defer c.state.mu.Unlock()
+		return append([]byte{}, c.state.files[name]...), nil
+	}
+	return c.OntapClient.FileDownload(volumeName, filePath)
+}
+
+// FileUploadAPI records file upload via API, the content is kept in plan state
+func (c *planClient) FileUploadAPI(volumeName string, filePath string, reader io.Reader) error {
+	return c.fileUpload("FileUploadAPI", volumeName, filePath, reader)
+}
+
+// FileUploadNFS records file upload via NFS, the content is kept in plan state
+func (c *planClient) FileUploadNFS(volumeName string, filePath string, reader io.Reader) error {
+	return c.fileUpload("FileUploadNFS", volumeName, filePath, reader)
+}
+
+func (c *planClient) fileUpload(operation string, volumeName string, filePath string, reader io.Reader) (err error) {
+	var b []byte
+	if b, err = ioutil.ReadAll(reader); err != nil {
+		err = fmt.Errorf("%s(): failure to read content: %s", operation, err)
+		return
+	}
+	c.record(operation, "upload file %s to volume %s, %d bytes", filePath, volumeName, len(b))
+	name := path.Join(volumeName, filePath)
+	c.setPlanned("file", name, true)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.files[name] = b
+	return
+}
+
+// SnapshotGetList gets list of volume snapshots with planned changes applied
+func (c *planClient) SnapshotGetList(volumeName string) (snapshots []string, err error) {
+	if !c.volumePlanned(volumeName) {
+		if snapshots, err = c.OntapClient.SnapshotGetList(volumeName); err != nil {
+			return
+		}
+	}
+	snapshots = c.mergeNames("snapshot", volumeName+"@", snapshots)
+	return
+}
+
+// SnapshotGetInfoList gets list of volume snapshots info with planned changes applied
+func (c *planClient) SnapshotGetInfoList(volumeName string) (snapshots []SnapshotInfo, err error) {
+	snapshots = []SnapshotInfo{}
+	if !c.volumePlanned(volumeName) {
+		if snapshots, err = c.OntapClient.SnapshotGetInfoList(volumeName); err != nil {
+			return
+		}
+	}
+	var names []string
+	for _, snapshot := range snapshots {
+		names = append(names, snapshot.Name)
+	}
+	c.state.mu.Lock()
+	planned := make(map[string]SnapshotInfo)
+	for key, snapshot := range c.state.snapshots {
+		planned[key] = *snapshot
+	}
+	c.state.mu.Unlock()
+	merged := []SnapshotInfo{}
+	for _, name := range c.mergeNames("snapshot", volumeName+"@", names) {
+		if snapshot, ok := planned[volumeName+"@"+name]; ok {
+			merged = append(merged, snapshot)
+			continue
+		}
+		for _, snapshot := range snapshots {
+			if snapshot.Name == name {
+				merged = append(merged, snapshot)
+			}
+		}
+	}
+	snapshots = merged
+	return
+}
+
+func (c *planClient) snapshotCreated(volumeName string, snapshotName string, snapshotComment string) {
+	c.setPlanned("snapshot", volumeName+"@"+snapshotName, true)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.snapshots[volumeName+"@"+snapshotName] = &SnapshotInfo{Name: snapshotName, Comment: snapshotComment, CreateTime: time.Now()}
+}
+
+// SnapshotCreate records snapshot creation
+func (c *planClient) SnapshotCreate(volumeName string, snapshotName string, snapshotComment string) error {
+	c.record("SnapshotCreate", "create snapshot %s of volume %s", snapshotName, volumeName)
+	c.snapshotCreated(volumeName, snapshotName, snapshotComment)
+	return nil
+}
+
+// SnapshotCreateGroup records consistency group snapshot creation
+func (c *planClient) SnapshotCreateGroup(volumeNames []string, snapshotName string) error {
+	c.record("SnapshotCreateGroup", "create group snapshot %s of volumes %s", snapshotName, strings.Join(volumeNames, ","))
+	for _, volumeName := range volumeNames {
+		c.snapshotCreated(volumeName, snapshotName, "")
+	}
+	return nil
+}
+
+// SnapshotSetComment records snapshot comment update
+func (c *planClient) SnapshotSetComment(volumeName string, snapshotName string, snapshotComment string) error {
+	c.record("SnapshotSetComment", "set snapshot %s of volume %s comment \"%s\"", snapshotName, volumeName, snapshotComment)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	if snapshot, ok := c.state.snapshots[volumeName+"@"+snapshotName]; ok {
+		snapshot.Comment = snapshotComment
+	}
+	return nil
+}
+
+// SnapshotDelete records snapshot deletion
+func (c *planClient) SnapshotDelete(volumeName string, snapshotName string) error {
+	c.record("SnapshotDelete", "delete snapshot %s of volume %s", snapshotName, volumeName)
+	c.setPlanned("snapshot", volumeName+"@"+snapshotName, false)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	delete(c.state.snapshots, volumeName+"@"+snapshotName)
+	return nil
+}
+
+// SnapshotRestore records volume restore from snapshot
+func (c *planClient) SnapshotRestore(volumeName string, snapshotName string) error {
+	c.record("SnapshotRestore", "restore volume %s from snapshot %s", volumeName, snapshotName)
+	return nil
+}
+
+// SnapmirrorGet gets SnapMirror relationship, planned relationship is returned from plan state
+func (c *planClient) SnapmirrorGet(destinationPath string) (*SnapmirrorInfo, error) {
+	c.state.mu.Lock()
+	snapmirror, ok := c.state.snapmirrors[destinationPath]
+	c.state.mu.Unlock()
+	if ok {
+		if snapmirror == nil {
+			return nil, nil
+		}
+		info := *snapmirror
+		return &info, nil
+	}
+	return c.OntapClient.SnapmirrorGet(destinationPath)
+}
+
+func (c *planClient) setSnapmirror(destinationPath string, update func(*SnapmirrorInfo) *SnapmirrorInfo) {
+	var snapmirror *SnapmirrorInfo
+	if info, err := c.SnapmirrorGet(destinationPath); err == nil {
+		snapmirror = info
+	}
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.snapmirrors[destinationPath] = update(snapmirror)
+}
+
+// SnapmirrorCreate records SnapMirror relationship creation and initialization
+func (c *planClient) SnapmirrorCreate(sourcePath string, destinationPath string, policy string, schedule string) error {
+	c.record("SnapmirrorCreate", "create and initialize SnapMirror %s -> %s, policy %s, schedule %s", sourcePath, destinationPath, policy, schedule)
+	c.setSnapmirror(destinationPath, func(*SnapmirrorInfo) *SnapmirrorInfo {
+		return &SnapmirrorInfo{
+			SourcePath:      sourcePath,
+			DestinationPath: destinationPath,
+			Policy:          policy,
+			Schedule:        schedule,
+			State:           "snapmirrored",
+			Healthy:         true,
+		}
+	})
+	return nil
+}
+
+// SnapmirrorModify records SnapMirror policy and schedule update
+func (c *planClient) SnapmirrorModify(destinationPath string, policy string, schedule string) error {
+	c.record("SnapmirrorModify", "set SnapMirror %s policy %s, schedule %s", destinationPath, policy, schedule)
+	c.setSnapmirror(destinationPath, func(snapmirror *SnapmirrorInfo) *SnapmirrorInfo {
+		if snapmirror != nil {
+			snapmirror.Policy = policy
+			snapmirror.Schedule = schedule
+		}
+		return snapmirror
+	})
+	return nil
+}
+
+// SnapmirrorUpdate records SnapMirror transfer
+func (c *planClient) SnapmirrorUpdate(destinationPath string) error {
+	c.record("SnapmirrorUpdate", "update SnapMirror %s", destinationPath)
+	return nil
+}
+
+// SnapmirrorBreak records SnapMirror break
+func (c *planClient) SnapmirrorBreak(destinationPath string) error {
+	c.record("SnapmirrorBreak", "break SnapMirror %s", destinationPath)
+	c.setSnapmirror(destinationPath, func(snapmirror *SnapmirrorInfo) *SnapmirrorInfo {
+		if snapmirror != nil {
+			snapmirror.State = "broken_off"
+		}
+		return snapmirror
+	})
+	return nil
+}
+
+// SnapmirrorDelete records SnapMirror relationship deletion
+func (c *planClient) SnapmirrorDelete(destinationPath string) error {
+	c.record("SnapmirrorDelete", "delete SnapMirror %s", destinationPath)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.snapmirrors[destinationPath] = nil
+	return nil
+}
+
+// NvmeTargetGetNqn gets NVME subsystem target NQN, placeholder is returned for planned subsystem
+func (c *planClient) NvmeTargetGetNqn(subsystemName string) (string, error) {
+	if exists, ok := c.planned("subsystem", subsystemName); ok && exists {
+		return "<NQN of planned subsystem " + subsystemName + ">", nil
+	}
+	return c.OntapClient.NvmeTargetGetNqn(subsystemName)
+}
+
+// NvmeSubsystemExists checks if NVME subsystem exists or is planned to be created
+func (c *planClient) NvmeSubsystemExists(subsystemName string) (bool, error) {
+	return c.exists("subsystem", subsystemName, "", c.OntapClient.NvmeSubsystemExists)
+}
+
+// NvmeSubsystemCreate records NVME subsystem creation
+func (c *planClient) NvmeSubsystemCreate(subsystemName string, osType string) error {
+	c.record("NvmeSubsystemCreate", "create NVME subsystem %s, OS type %s", subsystemName, osType)
+	c.setPlanned("subsystem", subsystemName, true)
+	return nil
+}
+
+// NvmeSubsystemDestroy records NVME subsystem deletion
+func (c *planClient) NvmeSubsystemDestroy(subsystemName string) error {
+	c.record("NvmeSubsystemDestroy", "destroy NVME subsystem %s", subsystemName)
+	c.setPlanned("subsystem", subsystemName, false)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	delete(c.state.hosts, subsystemName)
+	return nil
+}
+
+// NvmeSubsystemAddHost records adding host to NVME subsystem
+func (c *planClient) NvmeSubsystemAddHost(subsystemName string, hostNqn string) error {
+	c.record("NvmeSubsystemAddHost", "add host %s to NVME subsystem %s", hostNqn, subsystemName)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.hosts[subsystemName] = append(c.state.hosts[subsystemName], hostNqn)
+	return nil
+}
+
+// NvmeSubsystemGetHosts gets NVME subsystem hosts including planned ones
+func (c *planClient) NvmeSubsystemGetHosts(subsystemName string) (hosts []string, err error) {
+	hosts = []string{}
+	if _, ok := c.planned("subsystem", subsystemName); !ok {
+		if hosts, err = c.OntapClient.NvmeSubsystemGetHosts(subsystemName); err != nil {
+			return
+		}
+	}
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	hosts = append(hosts, c.state.hosts[subsystemName]...)
+	return
+}
+
+// NvmeNamespaceExists checks if NVME namespace exists or is planned to be created
+func (c *planClient) NvmeNamespaceExists(namespacePath string) (bool, error) {
+	return c.exists("namespace", namespacePath, pathVolume(namespacePath), c.OntapClient.NvmeNamespaceExists)
+}
+
+// NvmeNamespaceGetInfo gets NVME namespace info, planned namespace info is returned from plan state
+func (c *planClient) NvmeNamespaceGetInfo(namespacePath string) (*NvmeNamespaceInfo, error) {
+	c.state.mu.Lock()
+	namespace, ok := c.state.namespaces[namespacePath]
+	c.state.mu.Unlock()
+	if ok {
+		info := *namespace
+		return &info, nil
+	}
+	return c.OntapClient.NvmeNamespaceGetInfo(namespacePath)
+}
+
+// IsNvmeNamespaceMapped checks if NVME namespace is mapped or is planned to be mapped
+func (c *planClient) IsNvmeNamespaceMapped(namespacePath string) (bool, error) {
+	c.state.mu.Lock()
+	subsystemName, ok := c.state.nsMaps[namespacePath]
+	c.state.mu.Unlock()
+	if ok {
+		return subsystemName != "", nil
+	}
+	if _, ok = c.planned("namespace", namespacePath); ok || c.volumePlanned(pathVolume(namespacePath)) {
+		return false, nil
+	}
+	return c.OntapClient.IsNvmeNamespaceMapped(namespacePath)
+}
+
+// NvmeNamespaceResize records NVME namespace resize
+func (c *planClient) NvmeNamespaceResize(namespacePath string, namespaceSize int) error {
+	c.record("NvmeNamespaceResize", "resize NVME namespace %s to %dGB", namespacePath, namespaceSize)
+	if info, err := c.NvmeNamespaceGetInfo(namespacePath); err == nil {
+		info.Size = namespaceSize
+		c.state.mu.Lock()
+		c.state.namespaces[namespacePath] = info
+		c.state.mu.Unlock()
+	}
+	return nil
+}
+
+// NvmeNamespaceMap records NVME namespace mapping
+func (c *planClient) NvmeNamespaceMap(namespacePath string, subsystemName string) error {
+	c.record("NvmeNamespaceMap", "map NVME namespace %s to subsystem %s", namespacePath, subsystemName)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.nsMaps[namespacePath] = subsystemName
+	return nil
+}
+
+// NvmeNamespaceUnmap records NVME namespace unmapping
+func (c *planClient) NvmeNamespaceUnmap(namespacePath string) error {
+	c.record("NvmeNamespaceUnmap", "unmap NVME namespace %s", namespacePath)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.nsMaps[namespacePath] = ""
+	return nil
+}
+
+// NvmeNamespaceCreate records NVME namespace creation
+func (c *planClient) NvmeNamespaceCreate(namespacePath string, namespaceSize int, osType string) error {
+	c.record("NvmeNamespaceCreate", "create NVME namespace %s, size %dGB, OS type %s", namespacePath, namespaceSize, osType)
+	c.setPlanned("namespace", namespacePath, true)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.namespaces[namespacePath] = &NvmeNamespaceInfo{Size: namespaceSize}
+	return nil
+}
+
+// NvmeNamespaceDestroy records NVME namespace deletion
+func (c *planClient) NvmeNamespaceDestroy(namespacePath string) error {
+	c.record("NvmeNamespaceDestroy", "destroy NVME namespace %s", namespacePath)
+	c.setPlanned("namespace", namespacePath, false)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	delete(c.state.namespaces, namespacePath)
+	delete(c.state.nsMaps, namespacePath)
+	return nil
+}
+
+// DiscoverNvmeLIFs gets NVME LIF's for namespace, all SVM NVME LIF's are returned for planned namespace
+func (c *planClient) DiscoverNvmeLIFs(namespacePath string, hostSubnet string) ([]string, error) {
+	if exists, ok := c.planned("namespace", namespacePath); ok && exists {
+		return c.OntapClient.GetNvmeLIFs()
+	}
+	return c.OntapClient.DiscoverNvmeLIFs(namespacePath, hostSubnet)
+}
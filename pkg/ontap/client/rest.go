@@ -1,9 +1,11 @@
 package client
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"math"
+	"net/http"
 	"path/filepath"
 	"time"
 
@@ -18,17 +20,107 @@ const (
         LUN_SIZE_OVERHEAD = 1024 * 1024
 	REST_RETRY_ATTEMPTS = 5
 	REST_RETRY_TIMEOUT = 15
+	SNAPMIRROR_QUIESCE_TIMEOUT = 300
 )
 
+// lunCreateSettleTime is wait time after LUN is created and before data is written into it
+var lunCreateSettleTime = 10 * time.Second
+
+// SetLunCreateSettleTime changes wait time after LUN is created and before data is written into it
+// (i.e. no wait with REST API simulator), returns the previous wait time
+func SetLunCreateSettleTime(settleTime time.Duration) (previous time.Duration) {
+	previous = lunCreateSettleTime
+	lunCreateSettleTime = settleTime
+	return
+}
+
+// fcpService is FCP service record (not implemented in go-ontap-rest)
+type fcpService struct {
+	ontap.Resource
+	Target struct {
+		Name string `json:"name"`
+	}                  `json:"target,omitempty"`
+}
+
+type fcpServiceResponse struct {
+	ontap.BaseResponse
+	FcpServices []fcpService `json:"records,omitempty"`
+}
+
+// fcInterface is FC interface record (not implemented in go-ontap-rest)
+type fcInterface struct {
+	ontap.Resource
+	Wwpn   string    `json:"wwpn,omitempty"`
+	Wwnn   string    `json:"wwnn,omitempty"`
+	State  string    `json:"state,omitempty"`
+	Fabric *fcFabric `json:"fabric,omitempty"`
+}
+
+// fcFabric is SAN fabric FC interface is logged in to
+type fcFabric struct {
+	Name string `json:"name,omitempty"`
+}
+
+type fcInterfaceResponse struct {
+	ontap.BaseResponse
+	FcInterfaces []fcInterface `json:"records,omitempty"`
+}
+
+// iscsiCredentials is iSCSI initiator security record (not implemented in go-ontap-rest)
+type iscsiCredentials struct {
+	Svm                *ontap.Resource `json:"svm,omitempty"`
+	Initiator          string          `json:"initiator,omitempty"`
+	AuthenticationType string          `json:"authentication_type,omitempty"`
+	Chap               *iscsiChap      `json:"chap,omitempty"`
+}
+
+type iscsiChap struct {
+	Inbound  *iscsiChapCredentials `json:"inbound,omitempty"`
+	Outbound *iscsiChapCredentials `json:"outbound,omitempty"`
+}
+
+type iscsiChapCredentials struct {
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type iscsiCredentialsResponse struct {
+	ontap.BaseResponse
+	IscsiCredentials []iscsiCredentials `json:"records,omitempty"`
+}
+
+// snapmirrorRelationship is SnapMirror relationship record (not implemented in go-ontap-rest)
+type snapmirrorRelationship struct {
+	Uuid             string               `json:"uuid,omitempty"`
+	Source           *snapmirrorEndpoint  `json:"source,omitempty"`
+	Destination      *snapmirrorEndpoint  `json:"destination,omitempty"`
+	Policy           *ontap.NameReference `json:"policy,omitempty"`
+	TransferSchedule *ontap.NameReference `json:"transfer_schedule,omitempty"`
+	State            string               `json:"state,omitempty"`
+	Healthy          *bool                `json:"healthy,omitempty"`
+}
+
+type snapmirrorEndpoint struct {
+	Path string `json:"path,omitempty"`
+}
+
+type snapmirrorRelationshipResponse struct {
+	ontap.BaseResponse
+	SnapmirrorRelationships []snapmirrorRelationship `json:"records,omitempty"`
+}
+
 // OntapRestAPI is ontap REST API client
 type OntapRestAPI struct {
 	Client *ontap.Client
 	Svm    string
+	upload config.Upload
 }
 
 // NewOntapRestAPI creates REST API client
 func NewOntapRestAPI(nodeConfig *config.NodeConfig) (c *OntapRestAPI, err error) {
-	c = &OntapRestAPI{}
+	c = &OntapRestAPI{
+		upload: nodeConfig.Storage.Upload,
+	}
 	c.Client = ontap.NewClient(
 		"https://"+nodeConfig.Storage.CdotCredentials.Host,
 		&ontap.ClientOptions{
@@ -66,6 +158,17 @@ func NewOntapRestAPI(nodeConfig *config.NodeConfig) (c *OntapRestAPI, err error)
 	return
 }
 
+// Ping verifies the session by SVM lookup
+func (c *OntapRestAPI) Ping() (err error) {
+	var svms []ontap.Svm
+	if svms, _, err = c.Client.SvmGetIter([]string{"name=" + c.Svm, "fields=name"}); err != nil {
+		err = fmt.Errorf("Ping(): SvmGetIter() failure: %s", err)
+	} else if len(svms) == 0 {
+		err = fmt.Errorf("Ping(): svm \"%s\" not found in the cluster", c.Svm)
+	}
+	return
+}
+
 // GetAggregateMax finds aggregate with maximum size available
 func (c *OntapRestAPI) GetAggregateMax(nodeConfig *config.NodeConfig) (aggregateName string, err error) {
 	var spaceAvailable int64
@@ -145,6 +248,33 @@ func (c *OntapRestAPI) VolumeCreateSAN(volumeName string, aggregateName string,
 	return
 }
 
+// VolumeCreateDP creates data protection volume for SnapMirror destination
+func (c *OntapRestAPI) VolumeCreateDP(volumeName string, aggregateName string, volumeSize int) (err error) {
+	sizeBytes := volumeSize * 1024 * 1024 * 1024
+	volume := ontap.Volume{
+		Resource: ontap.Resource{
+			Name: volumeName,
+		},
+		Svm: &ontap.Resource{
+			Name: c.Svm,
+		},
+		Aggregates: []ontap.Resource{
+			ontap.Resource{
+				Name: aggregateName,
+			},
+		},
+		Guarantee: &ontap.VolumeSpaceGuarantee{
+			Type: "none",
+		},
+		Size: &sizeBytes,
+		Type: "dp",
+	}
+	if _, err = c.Client.VolumeCreate(&volume, []string{}); err != nil {
+		err = fmt.Errorf("VolumeCreate() failure: %s", err)
+	}
+	return
+}
+
 // VolumeCreateNAS creates volume for NAS
 func (c *OntapRestAPI) VolumeCreateNAS(volumeName string, aggregateName string, exportPolicyName string, volumeSize int) (err error) {
 	sizeBytes := volumeSize * 1024 * 1024 * 1024
@@ -213,6 +343,72 @@ func (c *OntapRestAPI) VolumeResize(volumeName string, volumeSize int) (err erro
 	return
 }
 
+// VolumeGetAggregate gets name of aggregate hosting volume
+func (c *OntapRestAPI) VolumeGetAggregate(volumeName string) (aggregateName string, err error) {
+	var volumes []ontap.Volume
+	if volumes, _, err = c.Client.VolumeGetIter([]string{"svm.name=" + c.Svm,"name=" + volumeName,"fields=aggregates"}); err != nil {
+		err = fmt.Errorf("VolumeGetAggregate().VolumeGetIter() failure: %s", err)
+		return
+	}
+	if len(volumes) == 0 {
+		err = fmt.Errorf("VolumeGetAggregate() failure: volume \"%s\" not found", volumeName)
+		return
+	}
+	if len(volumes[0].Aggregates) > 0 {
+		aggregateName = volumes[0].Aggregates[0].Name
+	}
+	return
+}
+
+// volumeMoveUpdate is volume PATCH body to start volume move
+type volumeMoveUpdate struct {
+	Movement struct {
+		DestinationAggregate struct {
+			Name string `json:"name"`
+		} `json:"destination_aggregate"`
+	} `json:"movement"`
+}
+
+// VolumeMoveStart starts non-disruptive volume move to another aggregate, use VolumeMoveGetStatus to track the move
+func (c *OntapRestAPI) VolumeMoveStart(volumeName string, aggregateName string) (err error) {
+	var volume *ontap.Volume
+	var req *http.Request
+	if volume, _, err = c.VolumeGet(volumeName); err != nil {
+		return
+	}
+	update := volumeMoveUpdate{}
+	update.Movement.DestinationAggregate.Name = aggregateName
+	if req, err = c.Client.NewRequest("PATCH", volume.GetRef(), []string{}, update); err != nil {
+		err = fmt.Errorf("VolumeMoveStart() failure: %s", err)
+		return
+	}
+	if _, err = c.Client.Do(req, nil); err != nil {
+		err = fmt.Errorf("VolumeMoveStart() failure: %s", err)
+	}
+	return
+}
+
+// VolumeMoveGetStatus gets volume move status, returns nil if volume was never moved
+func (c *OntapRestAPI) VolumeMoveGetStatus(volumeName string) (moveInfo *VolumeMoveInfo, err error) {
+	var volumes []ontap.Volume
+	if volumes, _, err = c.Client.VolumeGetIter([]string{"svm.name=" + c.Svm,"name=" + volumeName,"fields=movement"}); err != nil {
+		err = fmt.Errorf("VolumeMoveGetStatus().VolumeGetIter() failure: %s", err)
+		return
+	}
+	if len(volumes) == 0 {
+		err = fmt.Errorf("VolumeMoveGetStatus() failure: volume \"%s\" not found", volumeName)
+		return
+	}
+	if volumes[0].Movement != nil && volumes[0].Movement.State != "" {
+		moveInfo = &VolumeMoveInfo{
+			DestinationAggregate: volumes[0].Movement.DestinationAggregate.Name,
+			State:                volumes[0].Movement.State,
+			PercentComplete:      volumes[0].Movement.PercentComplete,
+		}
+	}
+	return
+}
+
 // ExportPolicyCreate creates export-policy
 func (c *OntapRestAPI) ExportPolicyCreate(exportPolicyName string) (err error) {
 	exportPolicy := ontap.ExportPolicy{
@@ -263,7 +459,7 @@ func (c *OntapRestAPI) IgroupGet(igroupName string) (igroup *ontap.Igroup, res *
 }
 
 // IgroupCreate creates iGroup
-func (c *OntapRestAPI) IgroupCreate(igroupName string, osType string) (err error) {
+func (c *OntapRestAPI) IgroupCreate(igroupName string, protocol string, osType string) (err error) {
 	igroup := ontap.Igroup{
 		Resource: ontap.Resource{
 			Name: igroupName,
@@ -272,7 +468,7 @@ func (c *OntapRestAPI) IgroupCreate(igroupName string, osType string) (err error
 			Name: c.Svm,
 		},
 		OsType:   osType,
-		Protocol: "iscsi",
+		Protocol: protocol,
 	}
 	if _, err = c.Client.IgroupCreate(&igroup, []string{}); err != nil {
 		err = fmt.Errorf("IgroupCreate() failure: %s", err)
@@ -280,7 +476,7 @@ func (c *OntapRestAPI) IgroupCreate(igroupName string, osType string) (err error
 	return
 }
 
-// IgroupAddInitiator adds iSCSI initiator to iGroup
+// IgroupAddInitiator adds iSCSI or FC initiator to iGroup
 func (c *OntapRestAPI) IgroupAddInitiator(igroupName string, initiatorName string) (err error) {
 	var igroup *ontap.Igroup
 	if igroup, _, err = c.IgroupGet(igroupName); err != nil {
@@ -299,6 +495,24 @@ func (c *OntapRestAPI) IgroupAddInitiator(igroupName string, initiatorName strin
 	return
 }
 
+// IgroupGetInitiators gets list of iGroup initiators
+func (c *OntapRestAPI) IgroupGetInitiators(igroupName string) (initiators []string, err error) {
+	var igroups []ontap.Igroup
+	initiators = []string{}
+	if igroups, _, err = c.Client.IgroupGetIter([]string{"svm.name=" + c.Svm,"name=" + igroupName,"fields=initiators"}); err != nil {
+		err = fmt.Errorf("IgroupGetIter() failure: %s", err)
+		return
+	}
+	if len(igroups) == 0 {
+		err = fmt.Errorf("IgroupGetInitiators() failure: igroup \"%s\" not found", igroupName)
+		return
+	}
+	for _, initiator := range igroups[0].Initiators {
+		initiators = append(initiators, initiator.Name)
+	}
+	return
+}
+
 // IgroupDestroy deletes iGroup
 func (c *OntapRestAPI) IgroupDestroy(igroupName string) (err error) {
 	var igroup *ontap.Igroup
@@ -348,6 +562,27 @@ func (c *OntapRestAPI) LunGet(lunPath string) (lun *ontap.Lun, res *ontap.RestRe
 	return
 }
 
+// LunGetMaps gets LUN mappings to iGroups with LUN ID's
+func (c *OntapRestAPI) LunGetMaps(lunPath string) (lunMaps []LunMapInfo, err error) {
+	lunMaps = []LunMapInfo{}
+	var maps []ontap.LunMap
+	if maps, _, err = c.Client.LunMapGetIter([]string{"svm.name=" + c.Svm,"lun.name=" + lunPath,"fields=igroup.name,logical_unit_number"}); err != nil {
+		err = fmt.Errorf("LunGetMaps().LunMapGetIter() failure: %s", err)
+		return
+	}
+	for _, lunMap := range maps {
+		lunMapInfo := LunMapInfo{}
+		if lunMap.Igroup != nil {
+			lunMapInfo.IgroupName = lunMap.Igroup.Name
+		}
+		if lunMap.LogicalUnitNumber != nil {
+			lunMapInfo.LunId = *lunMap.LogicalUnitNumber
+		}
+		lunMaps = append(lunMaps, lunMapInfo)
+	}
+	return
+}
+
 // IsLunMapped checks if LUN is mapped
 func (c *OntapRestAPI) IsLunMapped(lunPath string, igroupName string) (mapped bool, err error) {
 	var lunMaps []ontap.LunMap
@@ -432,6 +667,32 @@ func (c *OntapRestAPI) LunResize(lunPath string, lunSize int) (err error) {
 	return
 }
 
+// LunSetComment sets LUN comment
+func (c *OntapRestAPI) LunSetComment(lunPath string, lunComment string) (err error) {
+	var lun *ontap.Lun
+	if lun, _, err = c.LunGet(lunPath); err != nil {
+		err = fmt.Errorf("LunSetComment().LunGet() failure: %s", err)
+		return
+	}
+	if _, err = c.Client.LunModify(lun.GetRef(), &ontap.Lun{Comment: lunComment}); err != nil {
+		err = fmt.Errorf("LunSetComment().LunModify() failure: %s", err)
+	}
+	return
+}
+
+// LunRename renames LUN within volume
+func (c *OntapRestAPI) LunRename(lunPath string, newLunPath string) (err error) {
+	var lun *ontap.Lun
+	if lun, _, err = c.LunGet(lunPath); err != nil {
+		err = fmt.Errorf("LunRename().LunGet() failure: %s", err)
+		return
+	}
+	if _, err = c.Client.LunModify(lun.GetRef(), &ontap.Lun{Name: newLunPath}); err != nil {
+		err = fmt.Errorf("LunRename().LunModify() failure: %s", err)
+	}
+	return
+}
+
 // LunMap maps LUN to iGroup
 func (c *OntapRestAPI) LunMap(lunPath string, lunID int, igroupName string) (err error) {
 	lunMap := ontap.LunMap{
@@ -540,6 +801,40 @@ func (c *OntapRestAPI) LunGetInfo(lunPath string) (lunInfo *LunInfo, err error)
 	return
 }
 
+// LunGetInfoList gets info of LUN's in volume or in SVM if volume name is empty
+func (c *OntapRestAPI) LunGetInfoList(volumeName string) (lunInfoList []LunInfo, err error) {
+	lunInfoList = []LunInfo{}
+	parameters := []string{"svm.name=" + c.Svm, "fields=name,comment,create_time,space.size,copy"}
+	if volumeName != "" {
+		parameters = append(parameters, "location.volume.name=" + volumeName)
+	}
+	var luns []ontap.Lun
+	if luns, _, err = c.Client.LunGetIter(parameters); err != nil {
+		err = fmt.Errorf("LunGetInfoList().LunGetIter() failure: %s", err)
+		return
+	}
+	for _, lun := range luns {
+		lunInfo := LunInfo{
+			Path:    lun.Name,
+			Comment: lun.Comment,
+		}
+		if lun.Space != nil && lun.Space.Size != nil {
+			lunInfo.Size = int(math.Round(float64(*lun.Space.Size) / 1024 / 1024 / 1024))
+		}
+		if lun.CreateTime != "" {
+			if lunInfo.CreateTime, err = time.Parse(time.RFC3339, lun.CreateTime); err != nil {
+				err = fmt.Errorf("LunGetInfoList(): unexpected LUN create time format: %s", err)
+				return
+			}
+		}
+		if lun.Copy != nil {
+			lunInfo.Source = lun.Copy.Source.Name
+		}
+		lunInfoList = append(lunInfoList, lunInfo)
+	}
+	return
+}
+
 // LunGetList gets list of LUN's
 func (c *OntapRestAPI) LunGetList(volumeName string) (lunList []string, err error) {
 	lunList = []string{}
@@ -569,6 +864,87 @@ func (c *OntapRestAPI) IscsiTargetGetName() (targetName string, err error) {
 	return
 }
 
+// iscsiCredentialsGet gets iSCSI initiator security record
+func (c *OntapRestAPI) iscsiCredentialsGet(initiatorName string) (credentials *iscsiCredentials, err error) {
+	var req *http.Request
+	r := iscsiCredentialsResponse{}
+	if req, err = c.Client.NewRequest("GET", "/api/protocols/san/iscsi/credentials", []string{"svm.name=" + c.Svm,"initiator=" + initiatorName,"fields=svm,authentication_type"}, nil); err != nil {
+		err = fmt.Errorf("IscsiCredentialsGetIter() failure: %s", err)
+		return
+	}
+	if _, err = c.Client.Do(req, &r); err != nil {
+		err = fmt.Errorf("IscsiCredentialsGetIter() failure: %s", err)
+		return
+	}
+	if len(r.IscsiCredentials) > 0 {
+		credentials = &r.IscsiCredentials[0]
+	}
+	return
+}
+
+// IscsiInitiatorSetAuth sets CHAP authentication for iSCSI initiator, mutual CHAP is set with outbound credentials
+func (c *OntapRestAPI) IscsiInitiatorSetAuth(initiatorName string, chapUser string, chapPassword string, outboundUser string, outboundPassword string) (err error) {
+	var req *http.Request
+	var credentials *iscsiCredentials
+	if credentials, err = c.iscsiCredentialsGet(initiatorName); err != nil {
+		return
+	}
+	update := &iscsiCredentials{
+		AuthenticationType: "chap",
+		Chap: &iscsiChap{
+			Inbound: &iscsiChapCredentials{
+				User:     chapUser,
+				Password: chapPassword,
+			},
+		},
+	}
+	if outboundUser != "" {
+		update.Chap.Outbound = &iscsiChapCredentials{
+			User:     outboundUser,
+			Password: outboundPassword,
+		}
+	}
+	if credentials == nil {
+		update.Svm = &ontap.Resource{
+			Name: c.Svm,
+		}
+		update.Initiator = initiatorName
+		if req, err = c.Client.NewRequest("POST", "/api/protocols/san/iscsi/credentials", []string{}, update); err != nil {
+			err = fmt.Errorf("IscsiCredentialsCreate() failure: %s", err)
+			return
+		}
+		if _, err = c.Client.Do(req, nil); err != nil {
+			err = fmt.Errorf("IscsiCredentialsCreate() failure: %s", err)
+		}
+	} else {
+		if req, err = c.Client.NewRequest("PATCH", "/api/protocols/san/iscsi/credentials/" + credentials.Svm.Uuid + "/" + initiatorName, []string{}, update); err != nil {
+			err = fmt.Errorf("IscsiCredentialsModify() failure: %s", err)
+			return
+		}
+		if _, err = c.Client.Do(req, nil); err != nil {
+			err = fmt.Errorf("IscsiCredentialsModify() failure: %s", err)
+		}
+	}
+	return
+}
+
+// IscsiInitiatorDeleteAuth deletes iSCSI initiator security record
+func (c *OntapRestAPI) IscsiInitiatorDeleteAuth(initiatorName string) (err error) {
+	var req *http.Request
+	var credentials *iscsiCredentials
+	if credentials, err = c.iscsiCredentialsGet(initiatorName); err != nil || credentials == nil {
+		return
+	}
+	if req, err = c.Client.NewRequest("DELETE", "/api/protocols/san/iscsi/credentials/" + credentials.Svm.Uuid + "/" + initiatorName, []string{}, nil); err != nil {
+		err = fmt.Errorf("IscsiCredentialsDelete() failure: %s", err)
+		return
+	}
+	if _, err = c.Client.Do(req, nil); err != nil {
+		err = fmt.Errorf("IscsiCredentialsDelete() failure: %s", err)
+	}
+	return
+}
+
 // DiscoverIscsiLIFs get list of iSCSI interfaces for LUN
 func (c *OntapRestAPI) DiscoverIscsiLIFs(lunPath string, initiatorSubnet string) (lifs []string, err error) {
 	lifs = []string{}
@@ -587,6 +963,220 @@ func (c *OntapRestAPI) DiscoverIscsiLIFs(lunPath string, initiatorSubnet string)
 	return
 }
 
+// FcpTargetGetName gets FCP target node name (WWNN)
+func (c *OntapRestAPI) FcpTargetGetName() (targetName string, err error) {
+	var req *http.Request
+	r := fcpServiceResponse{}
+	if req, err = c.Client.NewRequest("GET", "/api/protocols/san/fcp/services", []string{"enabled=true","svm.name=" + c.Svm,"fields=target"}, nil); err != nil {
+		err = fmt.Errorf("FcpServiceGetIter() failure: %s", err)
+		return
+	}
+	if _, err = c.Client.Do(req, &r); err != nil {
+		err = fmt.Errorf("FcpServiceGetIter() failure: %s", err)
+		return
+	}
+	if len(r.FcpServices) > 0 {
+		targetName = r.FcpServices[0].Target.Name
+	} else {
+		err = fmt.Errorf("FcpServiceGetIter() failure: FCP service is not running")
+	}
+	return
+}
+
+// GetFcpLIFs gets list of FC interfaces with WWPN's and fabric names
+func (c *OntapRestAPI) GetFcpLIFs() (lifs []FcInterface, err error) {
+	var req *http.Request
+	lifs = []FcInterface{}
+	path := "/api/network/fc/interfaces"
+	parameters := []string{"svm.name=" + c.Svm,"fields=name,wwpn,fabric.name","enabled=true","state=up","data_protocol=fcp"}
+	for {
+		r := fcInterfaceResponse{}
+		if req, err = c.Client.NewRequest("GET", path, parameters, nil); err != nil {
+			err = fmt.Errorf("GetFcpLIFs() failure: %s", err)
+			return
+		}
+		if _, err = c.Client.Do(req, &r); err != nil {
+			err = fmt.Errorf("GetFcpLIFs() failure: %s", err)
+			return
+		}
+		for _, fcInterface := range r.FcInterfaces {
+			lif := FcInterface{Name: fcInterface.Name, Wwpn: fcInterface.Wwpn}
+			if fcInterface.Fabric != nil {
+				lif.Fabric = fcInterface.Fabric.Name
+			}
+			lifs = append(lifs, lif)
+		}
+		if r.IsPaginate() {
+			path = r.GetNextRef()
+			parameters = []string{}
+		} else {
+			break
+		}
+	}
+	if len(lifs) == 0 {
+		err = fmt.Errorf("GetFcpLIFs(): no FC interfaces found")
+	}
+	return
+}
+
+// jobRequest runs REST API request and waits for the job to complete
+func (c *OntapRestAPI) jobRequest(method string, path string, body interface{}) (err error) {
+	var req *http.Request
+	var job *ontap.Job
+	jobLink := ontap.JobLinkResponse{}
+	if req, err = c.Client.NewRequest(method, path, []string{}, body); err != nil {
+		return
+	}
+	if _, err = c.Client.Do(req, &jobLink); err != nil {
+		return
+	}
+	if job, err = c.Client.JobWaitUntilComplete(jobLink.JobLink.GetRef()); err == nil {
+		if job != nil && job.State == "failure" {
+			err = fmt.Errorf("Error: REST code=%d, REST message=\"%s\"", job.Code, job.Message)
+		}
+	}
+	return
+}
+
+// snapmirrorGet gets SnapMirror relationship record by destination path
+func (c *OntapRestAPI) snapmirrorGet(destinationPath string) (relationship *snapmirrorRelationship, err error) {
+	var req *http.Request
+	r := snapmirrorRelationshipResponse{}
+	if req, err = c.Client.NewRequest("GET", "/api/snapmirror/relationships", []string{"destination.path=" + destinationPath,"fields=uuid,source,destination,policy,transfer_schedule,state,healthy"}, nil); err != nil {
+		err = fmt.Errorf("SnapmirrorRelationshipGetIter() failure: %s", err)
+		return
+	}
+	if _, err = c.Client.Do(req, &r); err != nil {
+		err = fmt.Errorf("SnapmirrorRelationshipGetIter() failure: %s", err)
+		return
+	}
+	if len(r.SnapmirrorRelationships) > 0 {
+		relationship = &r.SnapmirrorRelationships[0]
+	}
+	return
+}
+
+// SnapmirrorGet gets SnapMirror relationship info by destination path
+func (c *OntapRestAPI) SnapmirrorGet(destinationPath string) (snapmirrorInfo *SnapmirrorInfo, err error) {
+	var relationship *snapmirrorRelationship
+	if relationship, err = c.snapmirrorGet(destinationPath); err != nil || relationship == nil {
+		return
+	}
+	snapmirrorInfo = &SnapmirrorInfo{
+		DestinationPath: destinationPath,
+		State:           relationship.State,
+	}
+	if relationship.Source != nil {
+		snapmirrorInfo.SourcePath = relationship.Source.Path
+	}
+	if relationship.Policy != nil {
+		snapmirrorInfo.Policy = relationship.Policy.Name
+	}
+	if relationship.TransferSchedule != nil {
+		snapmirrorInfo.Schedule = relationship.TransferSchedule.Name
+	}
+	if relationship.Healthy != nil {
+		snapmirrorInfo.Healthy = *relationship.Healthy
+	}
+	return
+}
+
+// SnapmirrorCreate creates and initializes SnapMirror relationship
+func (c *OntapRestAPI) SnapmirrorCreate(sourcePath string, destinationPath string, policy string, schedule string) (err error) {
+	relationship := &snapmirrorRelationship{
+		Source: &snapmirrorEndpoint{
+			Path: sourcePath,
+		},
+		Destination: &snapmirrorEndpoint{
+			Path: destinationPath,
+		},
+		Policy: &ontap.NameReference{
+			Name: policy,
+		},
+		State: "snapmirrored",
+	}
+	if schedule != "" {
+		relationship.TransferSchedule = &ontap.NameReference{
+			Name: schedule,
+		}
+	}
+	if err = c.jobRequest("POST", "/api/snapmirror/relationships", relationship); err != nil {
+		err = fmt.Errorf("SnapmirrorRelationshipCreate() failure: %s", err)
+	}
+	return
+}
+
+// SnapmirrorModify modifies SnapMirror relationship policy and schedule
+func (c *OntapRestAPI) SnapmirrorModify(destinationPath string, policy string, schedule string) (err error) {
+	var relationship *snapmirrorRelationship
+	if relationship, err = c.snapmirrorGet(destinationPath); err != nil {
+		return
+	}
+	if relationship == nil {
+		err = fmt.Errorf("SnapmirrorRelationshipModify() failure: relationship \"%s\" not found", destinationPath)
+		return
+	}
+	update := &snapmirrorRelationship{
+		Policy: &ontap.NameReference{
+			Name: policy,
+		},
+		TransferSchedule: &ontap.NameReference{
+			Name: schedule,
+		},
+	}
+	if err = c.jobRequest("PATCH", "/api/snapmirror/relationships/" + relationship.Uuid, update); err != nil {
+		err = fmt.Errorf("SnapmirrorRelationshipModify() failure: %s", err)
+	}
+	return
+}
+
+// SnapmirrorUpdate starts SnapMirror transfer
+func (c *OntapRestAPI) SnapmirrorUpdate(destinationPath string) (err error) {
+	var relationship *snapmirrorRelationship
+	if relationship, err = c.snapmirrorGet(destinationPath); err != nil {
+		return
+	}
+	if relationship == nil {
+		err = fmt.Errorf("SnapmirrorTransferCreate() failure: relationship \"%s\" not found", destinationPath)
+		return
+	}
+	if err = c.jobRequest("POST", "/api/snapmirror/relationships/" + relationship.Uuid + "/transfers", struct{}{}); err != nil {
+		err = fmt.Errorf("SnapmirrorTransferCreate() failure: %s", err)
+	}
+	return
+}
+
+// SnapmirrorBreak breaks SnapMirror relationship making destination volume writable
+func (c *OntapRestAPI) SnapmirrorBreak(destinationPath string) (err error) {
+	var relationship *snapmirrorRelationship
+	if relationship, err = c.snapmirrorGet(destinationPath); err != nil {
+		return
+	}
+	if relationship == nil {
+		err = fmt.Errorf("SnapmirrorRelationshipModify() failure: relationship \"%s\" not found", destinationPath)
+		return
+	}
+	if relationship.State == "broken_off" {
+		return
+	}
+	if err = c.jobRequest("PATCH", "/api/snapmirror/relationships/" + relationship.Uuid, &snapmirrorRelationship{State: "broken_off"}); err != nil {
+		err = fmt.Errorf("SnapmirrorRelationshipModify() failure: %s", err)
+	}
+	return
+}
+
+// SnapmirrorDelete deletes SnapMirror relationship
+func (c *OntapRestAPI) SnapmirrorDelete(destinationPath string) (err error) {
+	var relationship *snapmirrorRelationship
+	if relationship, err = c.snapmirrorGet(destinationPath); err != nil || relationship == nil {
+		return
+	}
+	if err = c.jobRequest("DELETE", "/api/snapmirror/relationships/" + relationship.Uuid, nil); err != nil {
+		err = fmt.Errorf("SnapmirrorRelationshipDelete() failure: %s", err)
+	}
+	return
+}
+
 // FileExists checks if file exists
 func (c *OntapRestAPI) FileExists(volumeName string, filePath string) (exists bool, err error) {
 	var volume *ontap.Volume
@@ -710,6 +1300,32 @@ func (c *OntapRestAPI) SnapshotGetList(volumeName string) (snapshots []string, e
 	return
 }
 
+// SnapshotGetInfoList gets list of snapshots with comment and create time
+func (c *OntapRestAPI) SnapshotGetInfoList(volumeName string) (snapshots []SnapshotInfo, err error) {
+	snapshots = []SnapshotInfo{}
+	var volume *ontap.Volume
+	if volume, _, err = c.VolumeGet(volumeName); err != nil {
+		return
+	}
+	var volumeSnapshots []ontap.Snapshot
+	if volumeSnapshots, _, err = c.Client.SnapshotGetIter(volume.Uuid, []string{"svm.name=" + c.Svm, "fields=comment,create_time"}); err != nil {
+		err = fmt.Errorf("SnapshotGetIter(): failure: %s", err)
+		return
+	}
+	for _, snapshot := range volumeSnapshots {
+		snapshotInfo := SnapshotInfo{
+			Name:    snapshot.Name,
+			Comment: snapshot.Comment,
+		}
+		if snapshotInfo.CreateTime, err = time.Parse(time.RFC3339, snapshot.CreateTime); err != nil {
+			err = fmt.Errorf("SnapshotGetIter(): failure to parse create time for snapshot \"%s\": %s", snapshot.Name, err)
+			return
+		}
+		snapshots = append(snapshots, snapshotInfo)
+	}
+	return
+}
+
 // SnapshotCreate creates snapshot
 func (c *OntapRestAPI) SnapshotCreate(volumeName string, snapshotName string, snapshotComment string) (err error) {
 	var volume *ontap.Volume
@@ -731,6 +1347,60 @@ func (c *OntapRestAPI) SnapshotCreate(volumeName string, snapshotName string, sn
 	return
 }
 
+// SnapshotCreateGroup creates snapshot with the same name in a group of volumes,
+// volumes are resolved upfront to keep snapshots as close in time as possible.
+// Unlike ZAPI cg-start/cg-commit snapshots are taken one volume at a time,
+// so the group is crash-consistent only if filesystems are frozen (fsfreeze) for the duration.
+// Snapshots already created are deleted if any volume snapshot fails
+func (c *OntapRestAPI) SnapshotCreateGroup(volumeNames []string, snapshotName string) (err error) {
+	var volumes []*ontap.Volume
+	var created []string
+	for _, volumeName := range volumeNames {
+		var volume *ontap.Volume
+		if volume, _, err = c.VolumeGet(volumeName); err != nil {
+			return
+		}
+		volumes = append(volumes, volume)
+	}
+	defer func() {
+		if err != nil {
+			for _, volumeName := range created {
+				if deleteErr := c.SnapshotDelete(volumeName, snapshotName); deleteErr != nil {
+					err = fmt.Errorf("%s, rollback of snapshot in volume \"%s\" failed: %s", err, volumeName, deleteErr)
+				}
+			}
+		}
+	}()
+	for _, volume := range volumes {
+		snapshot := ontap.Snapshot{
+			Resource: ontap.Resource{
+				Name: snapshotName,
+			},
+			Svm: &ontap.Resource{
+				Name: c.Svm,
+			},
+		}
+		if _, err = c.Client.SnapshotCreate(volume.Uuid, &snapshot); err != nil {
+			err = fmt.Errorf("SnapshotCreate(): failure: %s", err)
+			return
+		}
+		created = append(created, volume.Name)
+	}
+	return
+}
+
+// SnapshotSetComment sets snapshot comment
+func (c *OntapRestAPI) SnapshotSetComment(volumeName string, snapshotName string, snapshotComment string) (err error) {
+	var snapshot *ontap.Snapshot
+	if snapshot, _, err = c.SnapshotGet(volumeName, snapshotName); err != nil {
+		return
+	}
+	if _, err = c.Client.SnapshotModify(snapshot.GetRef(), &ontap.Snapshot{Comment: snapshotComment}); err != nil {
+		err = fmt.Errorf("SnapshotModify(): failure: %s", err)
+	}
+	return
+}
+
 // SnapshotDelete deletes snapshot
 func (c *OntapRestAPI) SnapshotDelete(volumeName string, snapshotName string) (err error) {
 	var snapshot *ontap.Snapshot
@@ -766,7 +1436,7 @@ func (c *OntapRestAPI) SnapshotRestore(volumeName string, snapshotName string) (
 }
 
 // Create LUN and upload data
-func (c *OntapRestAPI) LunCreateAndUpload(volumeName string, filePath string, fileSize int64, fileReader io.Reader, lunPath string, lunComment string, osType string) (err error) {
+func (c *OntapRestAPI) LunCreateAndUpload(volumeName string, filePath string, fileSize int64, fileReader io.Reader, lunPath string, lunComment string, osType string, progress UploadProgress) (err error) {
         var sizeBytes, bytesWritten int64
 	sizeBytes = fileSize + int64(LUN_SIZE_BASE + LUN_SIZE_OVERHEAD)
 	lunName := filepath.Base(lunPath)
@@ -794,9 +1464,9 @@ func (c *OntapRestAPI) LunCreateAndUpload(volumeName string, filePath string, fi
 		err = fmt.Errorf("LunCreateAndUpload(): LunCreate() failure: %s", err)
 		return
         }
-        time.Sleep(10 * time.Second)
-	if bytesWritten, _, err = c.Client.LunWrite(luns[0].GetRef(), 0, fileReader); err != nil {
-		err = fmt.Errorf("LunCreateAndUpload(): LunWrite() failure: %s", err)
+        time.Sleep(lunCreateSettleTime)
+	if bytesWritten, err = c.lunChunkedWrite(luns[0].GetRef(), sizeBytes, fileReader, fileSize, progress); err != nil {
+		err = fmt.Errorf("LunCreateAndUpload(): %s", err)
 		return
 	}
 	if bytesWritten < fileSize {
@@ -806,15 +1476,19 @@ func (c *OntapRestAPI) LunCreateAndUpload(volumeName string, filePath string, fi
 }
 
 // Upload data to existent LUN
-func (c *OntapRestAPI) LunUpload(lunPath string, fileReader io.Reader, fileSize int64) (err error) {
+func (c *OntapRestAPI) LunUpload(lunPath string, fileReader io.Reader, fileSize int64, progress UploadProgress) (err error) {
         var bytesWritten int64
 	var lun *ontap.Lun
 	if lun, _, err = c.LunGet(lunPath); err != nil {
 		err = fmt.Errorf("LunUpload().LunGet(): failure: %s", err)
 		return
 	}
-	if bytesWritten, _, err = c.Client.LunWrite(lun.GetRef(), 0, fileReader); err != nil {
-		err = fmt.Errorf("LunUpload(): LunWrite() failure: %s", err)
+	var lunSize int64
+	if lun.Space != nil && lun.Space.Size != nil {
+		lunSize = *lun.Space.Size
+	}
+	if bytesWritten, err = c.lunChunkedWrite(lun.GetRef(), lunSize, fileReader, fileSize, progress); err != nil {
+		err = fmt.Errorf("LunUpload(): %s", err)
 		return
 	}
 	if bytesWritten < fileSize {
@@ -823,6 +1497,51 @@ func (c *OntapRestAPI) LunUpload(lunPath string, fileReader io.Reader, fileSize
 	return
 }
 
+// restLunWriter writes upload chunks to LUN via REST API
+type restLunWriter struct {
+	c    *OntapRestAPI
+	href string
+}
+
+// WriteAt writes chunk to LUN at given offset
+func (w *restLunWriter) WriteAt(p []byte, offset int64) (n int, err error) {
+	var bytesWritten int64
+	if bytesWritten, _, err = w.c.Client.LunWrite(w.href, offset, bytes.NewReader(p)); err != nil {
+		err = fmt.Errorf("LunWrite() failure: %s", err)
+		return
+	}
+	if n = int(bytesWritten); n < len(p) {
+		err = fmt.Errorf("LunWrite() short write: expected to write \"%d\" bytes, written \"%d\" bytes", len(p), n)
+	}
+	return
+}
+
+// Close is no-op for REST API writes
+func (w *restLunWriter) Close() error {
+	return nil
+}
+
+// lunChunkedWrite uploads data to LUN in parallel chunks, LUN is resized ahead of chunks beyond LUN size
+func (c *OntapRestAPI) lunChunkedWrite(href string, lunSize int64, fileReader io.Reader, fileSize int64, progress UploadProgress) (bytesWritten int64, err error) {
+	newWriter := func() (chunkWriter, error) {
+		return &restLunWriter{c: c, href: href}, nil
+	}
+	prepare := func(end int64) (err error) {
+		if end <= lunSize-int64(LUN_SIZE_BASE+LUN_SIZE_OVERHEAD) {
+			return
+		}
+		lunSizeBytes := end + int64(LUN_SIZE_BASE+LUN_SIZE_OVERHEAD) + int64(ontap.LUN_RESIZE_STEP)
+		if _, err = c.Client.LunModify(href, &ontap.Lun{Space: &ontap.LunSpace{Size: &lunSizeBytes}}); err != nil {
+			err = fmt.Errorf("LunModify() failure: %s", err)
+			return
+		}
+		lunSize = lunSizeBytes
+		return
+	}
+	bytesWritten, err = chunkedUpload(c.upload, fileReader, fileSize, newWriter, prepare, progress)
+	return
+}
+
 // NvmeSubsystemGet gets NVME Subsystem attributes
 func (c *OntapRestAPI) NvmeSubsystemGet(subsystemName string) (subsystem *ontap.NvmeSubsystem, res *ontap.RestResponse, err error) {
 	var subsystems []ontap.NvmeSubsystem
@@ -890,6 +1609,25 @@ func (c *OntapRestAPI) NvmeSubsystemDestroy(subsystemName string) (err error) {
 	return
 }
 
+// NvmeSubsystemGetHosts gets NQN's of NVME Subsystem hosts
+func (c *OntapRestAPI) NvmeSubsystemGetHosts(subsystemName string) (hostNqns []string, err error) {
+	hostNqns = []string{}
+	var subsystem *ontap.NvmeSubsystem
+	if subsystem, _, err = c.NvmeSubsystemGet(subsystemName); err != nil {
+		err = fmt.Errorf("NvmeSubsystemGetHosts() failure: %s", err)
+		return
+	}
+	var hosts []ontap.NvmeHost
+	if hosts, _, err = c.Client.NvmeHostGetIter(subsystem.GetRef(), []string{"fields=nqn"}); err != nil {
+		err = fmt.Errorf("NvmeSubsystemGetHosts().NvmeHostGetIter() failure: %s", err)
+		return
+	}
+	for _, host := range hosts {
+		hostNqns = append(hostNqns, host.Nqn)
+	}
+	return
+}
+
 // Add Host to NVME Subsystem
 func (c *OntapRestAPI) NvmeSubsystemAddHost(subsystemName string, hostNqn string) (err error) {
 	var subsystem *ontap.NvmeSubsystem
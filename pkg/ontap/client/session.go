@@ -0,0 +1,635 @@
+package client
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+// SessionCache keeps cDOT clients keyed by host, SVM, API method and credentials,
+// so that API version and SVM discovery is done once per cluster rather than once per call,
+// requests in flight to a cluster are limited by maxConcurrency, data uploads are not counted
+// as they last for the whole transfer and are limited by upload parallelism instead
+type SessionCache struct {
+	mu                  sync.Mutex
+	sessions            map[string]*session
+	clusters            map[string]chan struct{}
+	maxConcurrency      int
+	healthCheckInterval time.Duration
+	inflight            sync.WaitGroup
+	closed              bool
+}
+
+type session struct {
+	client  OntapClient
+	svm     string
+	checked time.Time
+	err     error
+	ready   chan struct{}
+}
+
+// pinger is implemented by clients able to verify the session is still usable
+type pinger interface {
+	Ping() error
+}
+
+// sessionClient is cached client bound to cluster concurrency limit
+type sessionClient struct {
+	OntapClient
+	cache *SessionCache
+	slots chan struct{}
+}
+
+// sessionCache is used by NewOntapClient and NewOntapReplicaClient if set
+var sessionCache *SessionCache
+
+// SetSessionCache makes NewOntapClient and NewOntapReplicaClient reuse clients from cache,
+// nil cache restores a new client per call
+func SetSessionCache(cache *SessionCache) {
+	sessionCache = cache
+}
+
+// NewSessionCache creates session cache, sessions idle for healthCheckInterval are verified before reuse
+// (zero interval disables health checks)
+func NewSessionCache(maxConcurrency int, healthCheckInterval time.Duration) *SessionCache {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	return &SessionCache{
+		sessions:            make(map[string]*session),
+		clusters:            make(map[string]chan struct{}),
+		maxConcurrency:      maxConcurrency,
+		healthCheckInterval: healthCheckInterval,
+	}
+}
+
+// sessionKey makes cache key, password is hashed to keep it out of the key
+func sessionKey(nodeConfig *config.NodeConfig) string {
+	credentials := nodeConfig.Storage.CdotCredentials
+	return fmt.Sprintf("%s/%s/%s/%s/%x/%s/%v",
+		credentials.Host,
+		nodeConfig.Storage.SvmName,
+		credentials.ApiMethod,
+		credentials.User,
+		sha256.Sum256([]byte(credentials.Password)),
+		credentials.ZapiVersion,
+		nodeConfig.Storage.Upload)
+}
+
+// Get gets cached client for node storage configuration or creates it with newClient,
+// concurrent calls for the same key wait for a single client to be created,
+// SVM discovered by the cached client is set in nodeConfig like for a new client
+func (cache *SessionCache) Get(nodeConfig *config.NodeConfig, newClient func(*config.NodeConfig) (OntapClient, error)) (ontap OntapClient, err error) {
+	key := sessionKey(nodeConfig)
+	host := nodeConfig.Storage.CdotCredentials.Host
+	cache.mu.Lock()
+	if cache.closed {
+		cache.mu.Unlock()
+		return newClient(nodeConfig)
+	}
+	slots, ok := cache.clusters[host]
+	if !ok {
+		slots = make(chan struct{}, cache.maxConcurrency)
+		cache.clusters[host] = slots
+	}
+	s, ok := cache.sessions[key]
+	if !ok {
+		s = &session{ready: make(chan struct{})}
+		cache.sessions[key] = s
+	}
+	cache.mu.Unlock()
+	if !ok {
+		s.client, s.err = newClient(nodeConfig)
+		s.svm, s.checked = nodeConfig.Storage.SvmName, time.Now()
+		if s.err != nil {
+			cache.evict(key, s)
+		} else if svmKey := sessionKey(nodeConfig); svmKey != key {
+			cache.mu.Lock()
+			if _, exists := cache.sessions[svmKey]; !exists && !cache.closed {
+				cache.sessions[svmKey] = s
+			}
+			cache.mu.Unlock()
+		}
+		close(s.ready)
+	}
+	<-s.ready
+	if err = s.err; err != nil {
+		return
+	}
+	c := &sessionClient{OntapClient: s.client, cache: cache, slots: slots}
+	if ok && !cache.healthy(s, c) {
+		cache.evict(key, s)
+		return cache.Get(nodeConfig, newClient)
+	}
+	if nodeConfig.Storage.SvmName == "" {
+		nodeConfig.Storage.SvmName = s.svm
+	}
+	ontap = c
+	return
+}
+
+// healthy verifies session idle for health check interval
+func (cache *SessionCache) healthy(s *session, c *sessionClient) bool {
+	p, ok := s.client.(pinger)
+	if !ok || cache.healthCheckInterval == 0 {
+		return true
+	}
+	cache.mu.Lock()
+	stale := time.Since(s.checked) > cache.healthCheckInterval
+	cache.mu.Unlock()
+	if !stale {
+		return true
+	}
+	release := c.acquire()
+	err := p.Ping()
+	release()
+	if err != nil {
+		return false
+	}
+	cache.mu.Lock()
+	s.checked = time.Now()
+	cache.mu.Unlock()
+	return true
+}
+
+// evict removes session from cache unless it has been replaced
+func (cache *SessionCache) evict(key string, s *session) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.sessions[key] == s {
+		delete(cache.sessions, key)
+	}
+}
+
+// Close waits for requests in flight and drops cached sessions, clients are not cached after Close
+func (cache *SessionCache) Close() {
+	cache.mu.Lock()
+	cache.closed = true
+	cache.sessions = make(map[string]*session)
+	cache.mu.Unlock()
+	cache.inflight.Wait()
+}
+
+// acquire waits for free cluster slot, returns function to release the slot
+func (c *sessionClient) acquire() func() {
+	c.cache.inflight.Add(1)
+	c.slots <- struct{}{}
+	return func() {
+		<-c.slots
+		c.cache.inflight.Done()
+	}
+}
+
+// track counts request in flight for Close without taking cluster slot, returns function to complete the request
+func (c *sessionClient) track() func() {
+	c.cache.inflight.Add(1)
+	return c.cache.inflight.Done
+}
+
+// GetAggregateMax calls GetAggregateMax within cluster concurrency limit
+func (c *sessionClient) GetAggregateMax(nodeConfig *config.NodeConfig) (string, error) {
+	defer c.acquire()()
+	return c.OntapClient.GetAggregateMax(nodeConfig)
+}
+
+// VolumeExists calls VolumeExists within cluster concurrency limit
+func (c *sessionClient) VolumeExists(volumeName string) (bool, error) {
+	defer c.acquire()()
+	return c.OntapClient.VolumeExists(volumeName)
+}
+
+// VolumeCreateSAN calls VolumeCreateSAN within cluster concurrency limit
+func (c *sessionClient) VolumeCreateSAN(volumeName string, aggregateName string, volumeSize int) error {
+	defer c.acquire()()
+	return c.OntapClient.VolumeCreateSAN(volumeName, aggregateName, volumeSize)
+}
+
+// VolumeCreateNAS calls VolumeCreateNAS within cluster concurrency limit
+func (c *sessionClient) VolumeCreateNAS(volumeName string, aggregateName string, exportPolicyName string, volumeSize int) error {
+	defer c.acquire()()
+	return c.OntapClient.VolumeCreateNAS(volumeName, aggregateName, exportPolicyName, volumeSize)
+}
+
+// VolumeCreateDP calls VolumeCreateDP within cluster concurrency limit
+func (c *sessionClient) VolumeCreateDP(volumeName string, aggregateName string, volumeSize int) error {
+	defer c.acquire()()
+	return c.OntapClient.VolumeCreateDP(volumeName, aggregateName, volumeSize)
+}
+
+// VolumeDestroy calls VolumeDestroy within cluster concurrency limit
+func (c *sessionClient) VolumeDestroy(volumeName string) error {
+	defer c.acquire()()
+	return c.OntapClient.VolumeDestroy(volumeName)
+}
+
+// VolumeResize calls VolumeResize within cluster concurrency limit
+func (c *sessionClient) VolumeResize(volumeName string, volumeSize int) error {
+	defer c.acquire()()
+	return c.OntapClient.VolumeResize(volumeName, volumeSize)
+}
+
+// VolumeGetAggregate calls VolumeGetAggregate within cluster concurrency limit
+func (c *sessionClient) VolumeGetAggregate(volumeName string) (string, error) {
+	defer c.acquire()()
+	return c.OntapClient.VolumeGetAggregate(volumeName)
+}
+
+// VolumeMoveStart calls VolumeMoveStart within cluster concurrency limit
+func (c *sessionClient) VolumeMoveStart(volumeName string, aggregateName string) error {
+	defer c.acquire()()
+	return c.OntapClient.VolumeMoveStart(volumeName, aggregateName)
+}
+
+// VolumeMoveGetStatus calls VolumeMoveGetStatus within cluster concurrency limit
+func (c *sessionClient) VolumeMoveGetStatus(volumeName string) (*VolumeMoveInfo, error) {
+	defer c.acquire()()
+	return c.OntapClient.VolumeMoveGetStatus(volumeName)
+}
+
+// ExportPolicyCreate calls ExportPolicyCreate within cluster concurrency limit
+func (c *sessionClient) ExportPolicyCreate(exportPolicyName string) error {
+	defer c.acquire()()
+	return c.OntapClient.ExportPolicyCreate(exportPolicyName)
+}
+
+// IgroupExists calls IgroupExists within cluster concurrency limit
+func (c *sessionClient) IgroupExists(volumeName string) (bool, error) {
+	defer c.acquire()()
+	return c.OntapClient.IgroupExists(volumeName)
+}
+
+// IgroupCreate calls IgroupCreate within cluster concurrency limit
+func (c *sessionClient) IgroupCreate(igroupName string, protocol string, osType string) error {
+	defer c.acquire()()
+	return c.OntapClient.IgroupCreate(igroupName, protocol, osType)
+}
+
+// IgroupAddInitiator calls IgroupAddInitiator within cluster concurrency limit
+func (c *sessionClient) IgroupAddInitiator(igroupName string, initiatorName string) error {
+	defer c.acquire()()
+	return c.OntapClient.IgroupAddInitiator(igroupName, initiatorName)
+}
+
+// IgroupGetInitiators calls IgroupGetInitiators within cluster concurrency limit
+func (c *sessionClient) IgroupGetInitiators(igroupName string) ([]string, error) {
+	defer c.acquire()()
+	return c.OntapClient.IgroupGetInitiators(igroupName)
+}
+
+// IgroupDestroy calls IgroupDestroy within cluster concurrency limit
+func (c *sessionClient) IgroupDestroy(igroupName string) error {
+	defer c.acquire()()
+	return c.OntapClient.IgroupDestroy(igroupName)
+}
+
+// LunExists calls LunExists within cluster concurrency limit
+func (c *sessionClient) LunExists(lunPath string) (bool, error) {
+	defer c.acquire()()
+	return c.OntapClient.LunExists(lunPath)
+}
+
+// IsLunMapped calls IsLunMapped within cluster concurrency limit
+func (c *sessionClient) IsLunMapped(lunPath string, igroupName string) (bool, error) {
+	defer c.acquire()()
+	return c.OntapClient.IsLunMapped(lunPath, igroupName)
+}
+
+// LunGetMaps calls LunGetMaps within cluster concurrency limit
+func (c *sessionClient) LunGetMaps(lunPath string) ([]LunMapInfo, error) {
+	defer c.acquire()()
+	return c.OntapClient.LunGetMaps(lunPath)
+}
+
+// LunGetInfo calls LunGetInfo within cluster concurrency limit
+func (c *sessionClient) LunGetInfo(lunPath string) (*LunInfo, error) {
+	defer c.acquire()()
+	return c.OntapClient.LunGetInfo(lunPath)
+}
+
+// LunGetList calls LunGetList within cluster concurrency limit
+func (c *sessionClient) LunGetList(volumeName string) ([]string, error) {
+	defer c.acquire()()
+	return c.OntapClient.LunGetList(volumeName)
+}
+
+// LunGetInfoList calls LunGetInfoList within cluster concurrency limit
+func (c *sessionClient) LunGetInfoList(volumeName string) ([]LunInfo, error) {
+	defer c.acquire()()
+	return c.OntapClient.LunGetInfoList(volumeName)
+}
+
+// LunSetComment calls LunSetComment within cluster concurrency limit
+func (c *sessionClient) LunSetComment(lunPath string, lunComment string) error {
+	defer c.acquire()()
+	return c.OntapClient.LunSetComment(lunPath, lunComment)
+}
+
+// LunRename calls LunRename within cluster concurrency limit
+func (c *sessionClient) LunRename(lunPath string, newLunPath string) error {
+	defer c.acquire()()
+	return c.OntapClient.LunRename(lunPath, newLunPath)
+}
+
+// LunCopy calls LunCopy within cluster concurrency limit
+func (c *sessionClient) LunCopy(imagePath string, lunPath string) error {
+	defer c.acquire()()
+	return c.OntapClient.LunCopy(imagePath, lunPath)
+}
+
+// LunResize calls LunResize within cluster concurrency limit
+func (c *sessionClient) LunResize(lunPath string, lunSize int) error {
+	defer c.acquire()()
+	return c.OntapClient.LunResize(lunPath, lunSize)
+}
+
+// LunMap calls LunMap within cluster concurrency limit
+func (c *sessionClient) LunMap(lunPath string, lunID int, igroupName string) error {
+	defer c.acquire()()
+	return c.OntapClient.LunMap(lunPath, lunID, igroupName)
+}
+
+// LunUnmap calls LunUnmap within cluster concurrency limit
+func (c *sessionClient) LunUnmap(lunPath string, igroupName string) error {
+	defer c.acquire()()
+	return c.OntapClient.LunUnmap(lunPath, igroupName)
+}
+
+// LunCreate calls LunCreate within cluster concurrency limit
+func (c *sessionClient) LunCreate(lunPath string, lunSize int, osType string) error {
+	defer c.acquire()()
+	return c.OntapClient.LunCreate(lunPath, lunSize, osType)
+}
+
+// LunCreateFromFile calls LunCreateFromFile within cluster concurrency limit
+func (c *sessionClient) LunCreateFromFile(volumeName string, filePath string, lunPath string, lunComment string, osType string) error {
+	defer c.acquire()()
+	return c.OntapClient.LunCreateFromFile(volumeName, filePath, lunPath, lunComment, osType)
+}
+
+// LunCreateAndUpload calls LunCreateAndUpload outside of cluster concurrency limit
+func (c *sessionClient) LunCreateAndUpload(volumeName string, filePath string, fileSize int64, fileReader io.Reader, lunPath string, lunComment string, osType string, progress UploadProgress) error {
+	defer c.track()()
+	return c.OntapClient.LunCreateAndUpload(volumeName, filePath, fileSize, fileReader, lunPath, lunComment, osType, progress)
+}
+
+// LunUpload calls LunUpload outside of cluster concurrency limit
+func (c *sessionClient) LunUpload(lunPath string, fileReader io.Reader, fileSize int64, progress UploadProgress) error {
+	defer c.track()()
+	return c.OntapClient.LunUpload(lunPath, fileReader, fileSize, progress)
+}
+
+// LunDestroy calls LunDestroy within cluster concurrency limit
+func (c *sessionClient) LunDestroy(lunPath string) error {
+	defer c.acquire()()
+	return c.OntapClient.LunDestroy(lunPath)
+}
+
+// IscsiTargetGetName calls IscsiTargetGetName within cluster concurrency limit
+func (c *sessionClient) IscsiTargetGetName() (string, error) {
+	defer c.acquire()()
+	return c.OntapClient.IscsiTargetGetName()
+}
+
+// DiscoverIscsiLIFs calls DiscoverIscsiLIFs within cluster concurrency limit
+func (c *sessionClient) DiscoverIscsiLIFs(lunPath string, initiatorSubnet string) ([]string, error) {
+	defer c.acquire()()
+	return c.OntapClient.DiscoverIscsiLIFs(lunPath, initiatorSubnet)
+}
+
+// IscsiInitiatorSetAuth calls IscsiInitiatorSetAuth within cluster concurrency limit
+func (c *sessionClient) IscsiInitiatorSetAuth(initiatorName string, chapUser string, chapPassword string, outboundUser string, outboundPassword string) error {
+	defer c.acquire()()
+	return c.OntapClient.IscsiInitiatorSetAuth(initiatorName, chapUser, chapPassword, outboundUser, outboundPassword)
+}
+
+// IscsiInitiatorDeleteAuth calls IscsiInitiatorDeleteAuth within cluster concurrency limit
+func (c *sessionClient) IscsiInitiatorDeleteAuth(initiatorName string) error {
+	defer c.acquire()()
+	return c.OntapClient.IscsiInitiatorDeleteAuth(initiatorName)
+}
+
+// FcpTargetGetName calls FcpTargetGetName within cluster concurrency limit
+func (c *sessionClient) FcpTargetGetName() (string, error) {
+	defer c.acquire()()
+	return c.OntapClient.FcpTargetGetName()
+}
+
+// GetFcpLIFs calls GetFcpLIFs within cluster concurrency limit
+func (c *sessionClient) GetFcpLIFs() ([]FcInterface, error) {
+	defer c.acquire()()
+	return c.OntapClient.GetFcpLIFs()
+}
+
+// FileExists calls FileExists within cluster concurrency limit
+func (c *sessionClient) FileExists(volumeName string, filePath string) (bool, error) {
+	defer c.acquire()()
+	return c.OntapClient.FileExists(volumeName, filePath)
+}
+
+// FileGetList calls FileGetList within cluster concurrency limit
+func (c *sessionClient) FileGetList(volumeName string, dirPath string) ([]string, error) {
+	defer c.acquire()()
+	return c.OntapClient.FileGetList(volumeName, dirPath)
+}
+
+// FileDelete calls FileDelete within cluster concurrency limit
+func (c *sessionClient) FileDelete(volumName string, filePath string) error {
+	defer c.acquire()()
+	return c.OntapClient.FileDelete(volumName, filePath)
+}
+
+// FileDownload calls FileDownload within cluster concurrency limit
+func (c *sessionClient) FileDownload(volumeName string, filePath string) ([]byte, error) {
+	defer c.acquire()()
+	return c.OntapClient.FileDownload(volumeName, filePath)
+}
+
+// FileUploadAPI calls FileUploadAPI outside of cluster concurrency limit
+func (c *sessionClient) FileUploadAPI(volumeName string, filePath string, reader io.Reader) error {
+	defer c.track()()
+	return c.OntapClient.FileUploadAPI(volumeName, filePath, reader)
+}
+
+// FileUploadNFS calls FileUploadNFS outside of cluster concurrency limit
+func (c *sessionClient) FileUploadNFS(volumeName string, filePath string, reader io.Reader) error {
+	defer c.track()()
+	return c.OntapClient.FileUploadNFS(volumeName, filePath, reader)
+}
+
+// SnapshotGetList calls SnapshotGetList within cluster concurrency limit
+func (c *sessionClient) SnapshotGetList(volumeName string) ([]string, error) {
+	defer c.acquire()()
+	return c.OntapClient.SnapshotGetList(volumeName)
+}
+
+// SnapshotGetInfoList calls SnapshotGetInfoList within cluster concurrency limit
+func (c *sessionClient) SnapshotGetInfoList(volumeName string) ([]SnapshotInfo, error) {
+	defer c.acquire()()
+	return c.OntapClient.SnapshotGetInfoList(volumeName)
+}
+
+// SnapshotCreate calls SnapshotCreate within cluster concurrency limit
+func (c *sessionClient) SnapshotCreate(volumeName string, snapshotName string, snapshotComment string) error {
+	defer c.acquire()()
+	return c.OntapClient.SnapshotCreate(volumeName, snapshotName, snapshotComment)
+}
+
+// SnapshotCreateGroup calls SnapshotCreateGroup within cluster concurrency limit
+func (c *sessionClient) SnapshotCreateGroup(volumeNames []string, snapshotName string) error {
+	defer c.acquire()()
+	return c.OntapClient.SnapshotCreateGroup(volumeNames, snapshotName)
+}
+
+// SnapshotSetComment calls SnapshotSetComment within cluster concurrency limit
+func (c *sessionClient) SnapshotSetComment(volumeName string, snapshotName string, snapshotComment string) error {
+	defer c.acquire()()
+	return c.OntapClient.SnapshotSetComment(volumeName, snapshotName, snapshotComment)
+}
+
+// SnapshotDelete calls SnapshotDelete within cluster concurrency limit
+func (c *sessionClient) SnapshotDelete(volumeName string, snapshotName string) (err error) {
+	defer c.acquire()()
+	return c.OntapClient.SnapshotDelete(volumeName, snapshotName)
+}
+
+// SnapshotRestore calls SnapshotRestore within cluster concurrency limit
+func (c *sessionClient) SnapshotRestore(volumeName string, snapshotName string) error {
+	defer c.acquire()()
+	return c.OntapClient.SnapshotRestore(volumeName, snapshotName)
+}
+
+// SnapmirrorGet calls SnapmirrorGet within cluster concurrency limit
+func (c *sessionClient) SnapmirrorGet(destinationPath string) (*SnapmirrorInfo, error) {
+	defer c.acquire()()
+	return c.OntapClient.SnapmirrorGet(destinationPath)
+}
+
+// SnapmirrorCreate calls SnapmirrorCreate within cluster concurrency limit
+func (c *sessionClient) SnapmirrorCreate(sourcePath string, destinationPath string, policy string, schedule string) error {
+	defer c.acquire()()
+	return c.OntapClient.SnapmirrorCreate(sourcePath, destinationPath, policy, schedule)
+}
+
+// SnapmirrorModify calls SnapmirrorModify within cluster concurrency limit
+func (c *sessionClient) SnapmirrorModify(destinationPath string, policy string, schedule string) error {
+	defer c.acquire()()
+	return c.OntapClient.SnapmirrorModify(destinationPath, policy, schedule)
+}
+
+// SnapmirrorUpdate calls SnapmirrorUpdate within cluster concurrency limit
+func (c *sessionClient) SnapmirrorUpdate(destinationPath string) error {
+	defer c.acquire()()
+	return c.OntapClient.SnapmirrorUpdate(destinationPath)
+}
+
+// SnapmirrorBreak calls SnapmirrorBreak within cluster concurrency limit
+func (c *sessionClient) SnapmirrorBreak(destinationPath string) error {
+	defer c.acquire()()
+	return c.OntapClient.SnapmirrorBreak(destinationPath)
+}
+
+// SnapmirrorDelete calls SnapmirrorDelete within cluster concurrency limit
+func (c *sessionClient) SnapmirrorDelete(destinationPath string) error {
+	defer c.acquire()()
+	return c.OntapClient.SnapmirrorDelete(destinationPath)
+}
+
+// NvmeTargetGetNqn calls NvmeTargetGetNqn within cluster concurrency limit
+func (c *sessionClient) NvmeTargetGetNqn(subsystemName string) (string, error) {
+	defer c.acquire()()
+	return c.OntapClient.NvmeTargetGetNqn(subsystemName)
+}
+
+// NvmeSubsystemExists calls NvmeSubsystemExists within cluster concurrency limit
+func (c *sessionClient) NvmeSubsystemExists(subsystemName string) (bool, error) {
+	defer c.acquire()()
+	return c.OntapClient.NvmeSubsystemExists(subsystemName)
+}
+
+// NvmeSubsystemCreate calls NvmeSubsystemCreate within cluster concurrency limit
+func (c *sessionClient) NvmeSubsystemCreate(subsystemName string, osType string) error {
+	defer c.acquire()()
+	return c.OntapClient.NvmeSubsystemCreate(subsystemName, osType)
+}
+
+// NvmeSubsystemDestroy calls NvmeSubsystemDestroy within cluster concurrency limit
+func (c *sessionClient) NvmeSubsystemDestroy(subsystemName string) error {
+	defer c.acquire()()
+	return c.OntapClient.NvmeSubsystemDestroy(subsystemName)
+}
+
+// NvmeSubsystemAddHost calls NvmeSubsystemAddHost within cluster concurrency limit
+func (c *sessionClient) NvmeSubsystemAddHost(subsystemName string, hostNqn string) error {
+	defer c.acquire()()
+	return c.OntapClient.NvmeSubsystemAddHost(subsystemName, hostNqn)
+}
+
+// NvmeSubsystemGetHosts calls NvmeSubsystemGetHosts within cluster concurrency limit
+func (c *sessionClient) NvmeSubsystemGetHosts(subsystemName string) ([]string, error) {
+	defer c.acquire()()
+	return c.OntapClient.NvmeSubsystemGetHosts(subsystemName)
+}
+
+// NvmeNamespaceExists calls NvmeNamespaceExists within cluster concurrency limit
+func (c *sessionClient) NvmeNamespaceExists(namespacePath string) (bool, error) {
+	defer c.acquire()()
+	return c.OntapClient.NvmeNamespaceExists(namespacePath)
+}
+
+// NvmeNamespaceGetInfo calls NvmeNamespaceGetInfo within cluster concurrency limit
+func (c *sessionClient) NvmeNamespaceGetInfo(namespacePath string) (*NvmeNamespaceInfo, error) {
+	defer c.acquire()()
+	return c.OntapClient.NvmeNamespaceGetInfo(namespacePath)
+}
+
+// IsNvmeNamespaceMapped calls IsNvmeNamespaceMapped within cluster concurrency limit
+func (c *sessionClient) IsNvmeNamespaceMapped(namespacePath string) (bool, error) {
+	defer c.acquire()()
+	return c.OntapClient.IsNvmeNamespaceMapped(namespacePath)
+}
+
+// NvmeNamespaceResize calls NvmeNamespaceResize within cluster concurrency limit
+func (c *sessionClient) NvmeNamespaceResize(namespacePath string, namespaceSize int) error {
+	defer c.acquire()()
+	return c.OntapClient.NvmeNamespaceResize(namespacePath, namespaceSize)
+}
+
+// NvmeNamespaceMap calls NvmeNamespaceMap within cluster concurrency limit
+func (c *sessionClient) NvmeNamespaceMap(namespacePath string, subsystemName string) error {
+	defer c.acquire()()
+	return c.OntapClient.NvmeNamespaceMap(namespacePath, subsystemName)
+}
+
+// NvmeNamespaceUnmap calls NvmeNamespaceUnmap within cluster concurrency limit
+func (c *sessionClient) NvmeNamespaceUnmap(namespacePath string) error {
+	defer c.acquire()()
+	return c.OntapClient.NvmeNamespaceUnmap(namespacePath)
+}
+
+// NvmeNamespaceCreate calls NvmeNamespaceCreate within cluster concurrency limit
+func (c *sessionClient) NvmeNamespaceCreate(namespacePath string, namespaceSize int, osType string) error {
+	defer c.acquire()()
+	return c.OntapClient.NvmeNamespaceCreate(namespacePath, namespaceSize, osType)
+}
+
+// NvmeNamespaceDestroy calls NvmeNamespaceDestroy within cluster concurrency limit
+func (c *sessionClient) NvmeNamespaceDestroy(namespacePath string) error {
+	defer c.acquire()()
+	return c.OntapClient.NvmeNamespaceDestroy(namespacePath)
+}
+
+// GetNvmeLIFs calls GetNvmeLIFs within cluster concurrency limit
+func (c *sessionClient) GetNvmeLIFs() ([]string, error) {
+	defer c.acquire()()
+	return c.OntapClient.GetNvmeLIFs()
+}
+
+// DiscoverNvmeLIFs calls DiscoverNvmeLIFs within cluster concurrency limit
+func (c *sessionClient) DiscoverNvmeLIFs(namespacePath string, hostSubnet string) ([]string, error) {
+	defer c.acquire()()
+	return c.OntapClient.DiscoverNvmeLIFs(namespacePath, hostSubnet)
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+// blockingClient is cDOT client with uploads blocked until release is closed
+type blockingClient struct {
+	OntapClient
+	uploading chan struct{}
+	release   chan struct{}
+}
+
+func (c *blockingClient) LunUpload(lunPath string, fileReader io.Reader, fileSize int64, progress UploadProgress) error {
+	c.uploading <- struct{}{}
+	<-c.release
+	return nil
+}
+
+func (c *blockingClient) VolumeExists(volumeName string) (bool, error) {
+	return true, nil
+}
+
+func TestSessionCacheUploadConcurrency(t *testing.T) {
+	stub := &blockingClient{uploading: make(chan struct{}), release: make(chan struct{})}
+	cache := NewSessionCache(1, 0)
+	nodeConfig := &config.NodeConfig{}
+	nodeConfig.Storage.CdotCredentials.Host = "cdot.test"
+	nodeConfig.Storage.SvmName = "svm1"
+	c, err := cache.Get(nodeConfig, func(*config.NodeConfig) (OntapClient, error) { return stub, nil })
+	if err != nil {
+		t.Fatalf("Get() failure: %s", err)
+	}
+	uploaded := make(chan error)
+	for i := 0; i < 2; i++ {
+		go func() {
+			uploaded <- c.LunUpload("/vol/image_repo/ubuntu-22.04", bytes.NewReader(nil), 0, nil)
+		}()
+		select {
+		case <-stub.uploading:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("uploads are serialized by cluster concurrency limit")
+		}
+	}
+
+	// uploads in progress do not hold the only cluster slot
+	done := make(chan struct{})
+	go func() {
+		c.VolumeExists("node1_iboot")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("VolumeExists() is blocked by uploads in progress")
+	}
+
+	// Close waits for uploads in progress
+	closed := make(chan struct{})
+	go func() {
+		cache.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatalf("Close() does not wait for uploads in progress")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(stub.release)
+	for i := 0; i < 2; i++ {
+		if err = <-uploaded; err != nil {
+			t.Fatalf("LunUpload() failure: %s", err)
+		}
+	}
+	<-closed
+}
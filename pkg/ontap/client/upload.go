@@ -0,0 +1,152 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+const (
+	uploadChunkSize     = 16 * 1024 * 1024
+	uploadParallelism   = 4
+	uploadRetries       = 5
+	uploadRetryInterval = 5 * time.Second
+)
+
+// UploadProgress reports number of bytes uploaded so far and total number of bytes (zero if unknown)
+type UploadProgress func(bytesUploaded int64, bytesTotal int64)
+
+// chunkWriter writes upload chunks at given offsets, every upload worker has own writer
+type chunkWriter interface {
+	WriteAt(p []byte, offset int64) (n int, err error)
+	Close() error
+}
+
+// uploadChunk is part of upload stream at given offset
+type uploadChunk struct {
+	offset int64
+	data   []byte
+}
+
+// chunkedUpload uploads stream in chunks by parallel workers,
+// failed chunk is retried at the same offset with a new writer so that upload resumes rather than restarts,
+// prepare (if set) is called in stream order before chunk with given end offset is dispatched to workers
+func chunkedUpload(settings config.Upload, reader io.Reader, size int64, newWriter func() (chunkWriter, error), prepare func(end int64) error, progress UploadProgress) (bytesUploaded int64, err error) {
+	chunkSize := uploadChunkSize
+	if settings.ChunkSize > 0 {
+		chunkSize = settings.ChunkSize * 1024 * 1024
+	}
+	parallelism := uploadParallelism
+	if settings.Parallelism > 0 {
+		parallelism = settings.Parallelism
+	}
+	retries := uploadRetries
+	if settings.Retries > 0 {
+		retries = settings.Retries
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var abortOnce sync.Once
+	abort := make(chan struct{})
+	fail := func(failErr error) {
+		mu.Lock()
+		if err == nil {
+			err = failErr
+		}
+		mu.Unlock()
+		abortOnce.Do(func() { close(abort) })
+	}
+	aborted := func() bool {
+		select {
+		case <-abort:
+			return true
+		default:
+			return false
+		}
+	}
+	chunks := make(chan *uploadChunk)
+	buffers := make(chan []byte, parallelism+1)
+	for i := 0; i < parallelism+1; i++ {
+		buffers <- make([]byte, chunkSize)
+	}
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var w chunkWriter
+			for chunk := range chunks {
+				if !aborted() {
+					var chunkErr error
+					for attempt := 0; attempt <= retries; attempt++ {
+						if attempt > 0 {
+							time.Sleep(time.Duration(attempt) * uploadRetryInterval)
+						}
+						if w == nil {
+							if w, chunkErr = newWriter(); chunkErr != nil {
+								w = nil
+								continue
+							}
+						}
+						if _, chunkErr = w.WriteAt(chunk.data, chunk.offset); chunkErr == nil {
+							break
+						}
+						w.Close()
+						w = nil
+					}
+					if chunkErr != nil {
+						fail(fmt.Errorf("chunkedUpload(): failure to upload %d bytes at offset %d after %d retries: %s", len(chunk.data), chunk.offset, retries, chunkErr))
+					} else {
+						mu.Lock()
+						bytesUploaded += int64(len(chunk.data))
+						if progress != nil {
+							progress(bytesUploaded, size)
+						}
+						mu.Unlock()
+					}
+				}
+				buffers <- chunk.data[:cap(chunk.data)]
+			}
+			if w != nil {
+				if closeErr := w.Close(); closeErr != nil {
+					fail(fmt.Errorf("chunkedUpload(): failure to complete upload: %s", closeErr))
+				}
+			}
+		}()
+	}
+	var offset int64
+	for {
+		var buf []byte
+		select {
+		case buf = <-buffers:
+		case <-abort:
+		}
+		if buf == nil {
+			break
+		}
+		n, readErr := io.ReadFull(reader, buf)
+		if n > 0 {
+			if prepare != nil {
+				if prepareErr := prepare(offset + int64(n)); prepareErr != nil {
+					fail(fmt.Errorf("chunkedUpload(): %s", prepareErr))
+					break
+				}
+			}
+			chunks <- &uploadChunk{offset: offset, data: buf[:n]}
+			offset += int64(n)
+		} else {
+			buffers <- buf
+		}
+		if readErr != nil {
+			if readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+				fail(fmt.Errorf("chunkedUpload(): failure to read source: %s", readErr))
+			}
+			break
+		}
+	}
+	close(chunks)
+	wg.Wait()
+	return
+}
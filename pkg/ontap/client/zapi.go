@@ -2,9 +2,12 @@ package client
 
 import (
 	"encoding/hex"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"math"
+	"net"
+	"net/http"
 	"strconv"
 	"strings"
 	"time"
@@ -12,16 +15,193 @@ import (
 	"github.com/igor-feoktistov/go-ontap-sdk/ontap"
 	"github.com/igor-feoktistov/go-ontap-sdk/util"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/vmware/go-nfs-client/nfs"
+	"github.com/vmware/go-nfs-client/nfs/rpc"
 )
 
+const (
+	// Max data size in a single file-write-file call
+	zapiFileWriteMax = 512 * 1024
+)
+
+// zapiRequest is generic request for ZAPI's not implemented in go-ontap-sdk
+type zapiRequest struct {
+	XMLName xml.Name `xml:"netapp"`
+	Version string   `xml:"version,attr"`
+	XMLNs   string   `xml:"xmlns,attr"`
+	Vfiler  string   `xml:"vfiler,attr,omitempty"`
+	Params  interface{}
+}
+
+// iscsiInitiatorAuthParams is iscsi-initiator-*-auth API parameters
+type iscsiInitiatorAuthParams struct {
+	XMLName          xml.Name
+	Initiator        string `xml:"initiator"`
+	AuthType         string `xml:"auth-type,omitempty"`
+	UserName         string `xml:"user-name,omitempty"`
+	Password         string `xml:"password,omitempty"`
+	OutboundUserName string `xml:"outbound-user-name,omitempty"`
+	OutboundPassword string `xml:"outbound-password,omitempty"`
+}
+
+// lunSetCommentParams is lun-set-comment API parameters
+type lunSetCommentParams struct {
+	XMLName xml.Name `xml:"lun-set-comment"`
+	Path    string   `xml:"path"`
+	Comment string   `xml:"comment"`
+}
+
+// lunMoveParams is lun-move API parameters
+type lunMoveParams struct {
+	XMLName xml.Name `xml:"lun-move"`
+	Path    string   `xml:"path"`
+	NewPath string   `xml:"new-path"`
+}
+
+// snapmirrorParams is snapmirror-* API parameters
+type snapmirrorParams struct {
+	XMLName             xml.Name
+	SourceLocation      string `xml:"source-location,omitempty"`
+	DestinationLocation string `xml:"destination-location,omitempty"`
+	Policy              string `xml:"policy,omitempty"`
+	Schedule            string `xml:"schedule,omitempty"`
+	RelationshipType    string `xml:"relationship-type,omitempty"`
+}
+
+// snapmirrorInfo is snapmirror-info record of snapmirror-get-iter API
+type snapmirrorInfo struct {
+	SourceLocation      string `xml:"source-location,omitempty"`
+	DestinationLocation string `xml:"destination-location,omitempty"`
+	Policy              string `xml:"policy,omitempty"`
+	Schedule            string `xml:"schedule,omitempty"`
+	MirrorState         string `xml:"mirror-state,omitempty"`
+	RelationshipStatus  string `xml:"relationship-status,omitempty"`
+	IsHealthy           bool   `xml:"is-healthy,omitempty"`
+}
+
+type snapmirrorGetIterParams struct {
+	XMLName xml.Name `xml:"snapmirror-get-iter"`
+	Query   struct {
+		SnapmirrorInfo snapmirrorInfo `xml:"snapmirror-info"`
+	}                `xml:"query"`
+}
+
+type snapmirrorGetIterResponse struct {
+	XMLName xml.Name `xml:"netapp"`
+	Results struct {
+		AttributesList struct {
+			SnapmirrorInfo []snapmirrorInfo `xml:"snapmirror-info"`
+		}                               `xml:"attributes-list"`
+	}                `xml:"results"`
+}
+
+// fcpServiceGetIterParams is fcp-service-get-iter API parameters
+type fcpServiceGetIterParams struct {
+	XMLName xml.Name `xml:"fcp-service-get-iter"`
+	Query   struct {
+		FcpServiceInfo struct {
+			IsAvailable string `xml:"is-available,omitempty"`
+		} `xml:"fcp-service-info"`
+	} `xml:"query"`
+}
+
+type fcpServiceGetIterResponse struct {
+	XMLName xml.Name `xml:"netapp"`
+	Results struct {
+		AttributesList struct {
+			FcpServiceInfo []struct {
+				NodeName string `xml:"node-name"`
+			} `xml:"fcp-service-info"`
+		} `xml:"attributes-list"`
+	} `xml:"results"`
+}
+
+// volumeMoveStartParams is volume-move-start API parameters
+type volumeMoveStartParams struct {
+	XMLName              xml.Name `xml:"volume-move-start"`
+	Vserver              string   `xml:"vserver"`
+	SourceVolume         string   `xml:"source-volume"`
+	DestinationAggregate string   `xml:"dest-aggr"`
+}
+
+// volumeMoveInfo is volume-move-info record of volume-move-get-iter API
+type volumeMoveInfo struct {
+	Vserver              string `xml:"vserver,omitempty"`
+	Volume               string `xml:"volume,omitempty"`
+	DestinationAggregate string `xml:"destination-aggregate,omitempty"`
+	Phase                string `xml:"phase,omitempty"`
+	State                string `xml:"state,omitempty"`
+	PercentComplete      int    `xml:"percent-complete,omitempty"`
+	Details              string `xml:"details,omitempty"`
+}
+
+type volumeMoveGetIterParams struct {
+	XMLName xml.Name `xml:"volume-move-get-iter"`
+	Query   struct {
+		VolumeMoveInfo volumeMoveInfo `xml:"volume-move-info"`
+	}                `xml:"query"`
+}
+
+type volumeMoveGetIterResponse struct {
+	XMLName xml.Name `xml:"netapp"`
+	Results struct {
+		AttributesList struct {
+			VolumeMoveInfo []volumeMoveInfo `xml:"volume-move-info"`
+		}                               `xml:"attributes-list"`
+	}                `xml:"results"`
+}
+
+// cgStartParams is cg-start API parameters
+type cgStartParams struct {
+	XMLName  xml.Name `xml:"cg-start"`
+	Snapshot string   `xml:"snapshot"`
+	Timeout  string   `xml:"timeout"`
+	Volumes  []string `xml:"volumes>volume-name"`
+}
+
+type cgStartResponse struct {
+	XMLName xml.Name `xml:"netapp"`
+	Results struct {
+		CgId string `xml:"cg-id"`
+	}                `xml:"results"`
+}
+
+// cgCommitParams is cg-commit API parameters
+type cgCommitParams struct {
+	XMLName xml.Name `xml:"cg-commit"`
+	CgId    string   `xml:"cg-id"`
+}
+
+// snapshotModifyIterParams is snapshot-modify-iter API parameters
+type snapshotModifyIterParams struct {
+	XMLName xml.Name `xml:"snapshot-modify-iter"`
+	Query   struct {
+		SnapshotInfo struct {
+			Volume string `xml:"volume"`
+			Name   string `xml:"name"`
+		}              `xml:"snapshot-info"`
+	}                `xml:"query"`
+	Attributes struct {
+		SnapshotInfo struct {
+			Comment string `xml:"comment"`
+		}              `xml:"snapshot-info"`
+	}                `xml:"attributes"`
+}
+
 // OntapZAPI is ontap ZAPI client
 type OntapZAPI struct {
-	Client *ontap.Client
+	Client      *ontap.Client
+	Svm         string
+	ZapiVersion string
+	upload      config.Upload
 }
 
 // NewOntapZAPI creates ontap ZAPI client
 func NewOntapZAPI(nodeConfig *config.NodeConfig) (c *OntapZAPI, err error) {
-	c = &OntapZAPI{}
+	c = &OntapZAPI{
+		ZapiVersion: nodeConfig.Storage.CdotCredentials.ZapiVersion,
+		upload:      nodeConfig.Storage.Upload,
+	}
 	c.Client = ontap.NewClient(
 		"https://"+nodeConfig.Storage.CdotCredentials.Host,
 		&ontap.ClientOptions{
@@ -53,6 +233,7 @@ func NewOntapZAPI(nodeConfig *config.NodeConfig) (c *OntapZAPI, err error) {
 	if vserverResponse.Results.NumRecords == 1 {
 		nodeConfig.Storage.SvmName = vserverResponse.Results.VserverAttributes[0].VserverName
 		c.Client.SetVserver(nodeConfig.Storage.SvmName)
+		c.Svm = nodeConfig.Storage.SvmName
 	} else {
 		if nodeConfig.Storage.SvmName == "" {
 			err = fmt.Errorf("CreateCdotClient(): expected svmName in storage configuration")
@@ -63,11 +244,28 @@ func NewOntapZAPI(nodeConfig *config.NodeConfig) (c *OntapZAPI, err error) {
 	return
 }
 
-// GetAggregateMax finds aggregate with MAX space available
+// Ping verifies the session by vserver lookup
+func (c *OntapZAPI) Ping() (err error) {
+	vserverOptions := &ontap.VserverGetOptions{
+		MaxRecords: 1,
+		Query: &ontap.VserverInfo{
+			VserverName: c.Svm,
+		},
+	}
+	var vserverResponse *ontap.VserverGetResponse
+	if vserverResponse, _, err = c.Client.VserverGetAPI(vserverOptions); err != nil {
+		err = fmt.Errorf("Ping(): VserverGetAPI() failure: %s", err)
+	} else if vserverResponse.Results.NumRecords != 1 {
+		err = fmt.Errorf("Ping(): vserver \"%s\" not found", c.Svm)
+	}
+	return
+}
+
+// GetAggregateMax finds aggregate with MAX space available in client SVM (i.e. DR SVM with replica client)
 func (c *OntapZAPI) GetAggregateMax(nodeConfig *config.NodeConfig) (aggregateName string, err error) {
 	aggrOptions := &ontap.VserverShowAggrGetOptions{
 		MaxRecords: 1024,
-		Vserver:    nodeConfig.Storage.SvmName,
+		Vserver:    c.Svm,
 	}
 	var aggrResponse *ontap.VserverShowAggrGetResponse
 	if aggrResponse, _, err = c.Client.VserverShowAggrGetAPI(aggrOptions); err != nil {
@@ -86,7 +284,7 @@ func (c *OntapZAPI) GetAggregateMax(nodeConfig *config.NodeConfig) (aggregateNam
 			err = fmt.Errorf("VserverShowAggrGetAPI(): no aggregates found for requested storage size %dGB", (nodeConfig.Storage.BootLun.Size+nodeConfig.Storage.DataLun.Size)*2)
 		}
 	} else {
-		err = fmt.Errorf("VserverShowAggrGetAPI(): no aggregates found for vserver %s", nodeConfig.Storage.SvmName)
+		err = fmt.Errorf("VserverShowAggrGetAPI(): no aggregates found for vserver %s", c.Svm)
 	}
 	return
 }
@@ -114,6 +312,22 @@ func (c *OntapZAPI) VolumeCreateSAN(volumeName string, aggregateName string, vol
 	return
 }
 
+// VolumeCreateDP creates data protection volume for SnapMirror destination
+func (c *OntapZAPI) VolumeCreateDP(volumeName string, aggregateName string, volumeSize int) (err error) {
+	volOptions := &ontap.VolumeCreateOptions{
+		VolumeType:                "dp",
+		Volume:                    volumeName,
+		SpaceReserve:              "none",
+		PercentageSnapshotReserve: 0,
+		Size:                      strconv.Itoa(volumeSize) + "g",
+		ContainingAggregateName:   aggregateName,
+	}
+	if _, _, err = c.Client.VolumeCreateAPI(volOptions); err != nil {
+		err = fmt.Errorf("VolumeCreateAPI() failure: %s", err)
+	}
+	return
+}
+
 // VolumeCreateNAS creates volume for NAS
 func (c *OntapZAPI) VolumeCreateNAS(volumeName string, aggregateName string, exportPolicyName string, volumeSize int) (err error) {
 	volOptions := &ontap.VolumeCreateOptions{
@@ -157,6 +371,69 @@ func (c *OntapZAPI) VolumeResize(volumeName string, volumeSize int) (err error)
 	return
 }
 
+// VolumeGetAggregate gets name of aggregate hosting volume
+func (c *OntapZAPI) VolumeGetAggregate(volumeName string) (aggregateName string, err error) {
+	options := &ontap.VolumeGetOptions{
+		MaxRecords: 1,
+		Query: &ontap.VolumeQuery{
+			VolumeInfo: &ontap.VolumeInfo{
+				VolumeIDAttributes: &ontap.VolumeIDAttributes{
+					Name: volumeName,
+				},
+			},
+		},
+	}
+	var response *ontap.VolumeGetResponse
+	if response, _, err = c.Client.VolumeGetAPI(options); err != nil {
+		err = fmt.Errorf("VolumeGetAPI() failure: %s", err)
+		return
+	}
+	if response.Results.NumRecords == 0 || response.Results.AttributesList[0].VolumeIDAttributes == nil {
+		err = fmt.Errorf("VolumeGetAggregate() failure: volume \"%s\" not found", volumeName)
+		return
+	}
+	aggregateName = response.Results.AttributesList[0].VolumeIDAttributes.ContainingAggregateName
+	return
+}
+
+// VolumeMoveStart starts non-disruptive volume move to another aggregate, use VolumeMoveGetStatus to track the move
+func (c *OntapZAPI) VolumeMoveStart(volumeName string, aggregateName string) (err error) {
+	params := &volumeMoveStartParams{
+		Vserver:              c.Svm,
+		SourceVolume:         volumeName,
+		DestinationAggregate: aggregateName,
+	}
+	if err = c.zapiCall(params, nil); err != nil {
+		err = fmt.Errorf("VolumeMoveStartAPI() failure: %s", err)
+	}
+	return
+}
+
+// VolumeMoveGetStatus gets volume move status, returns nil if volume was never moved
+func (c *OntapZAPI) VolumeMoveGetStatus(volumeName string) (moveInfo *VolumeMoveInfo, err error) {
+	params := &volumeMoveGetIterParams{}
+	params.Query.VolumeMoveInfo.Vserver = c.Svm
+	params.Query.VolumeMoveInfo.Volume = volumeName
+	r := volumeMoveGetIterResponse{}
+	if err = c.zapiCall(params, &r); err != nil {
+		err = fmt.Errorf("VolumeMoveGetIterAPI() failure: %s", err)
+		return
+	}
+	if len(r.Results.AttributesList.VolumeMoveInfo) > 0 {
+		info := r.Results.AttributesList.VolumeMoveInfo[0]
+		moveInfo = &VolumeMoveInfo{
+			DestinationAggregate: info.DestinationAggregate,
+			State:                info.Phase,
+			PercentComplete:      info.PercentComplete,
+			Details:              info.Details,
+		}
+		if info.State == "failed" {
+			moveInfo.State = info.State
+		}
+	}
+	return
+}
+
 // ExportPolicyCreate creates export-policy
 func (c *OntapZAPI) ExportPolicyCreate(exportPolicyName string) (err error) {
 	if _, _, err = c.Client.ExportPolicyCreateAPI(exportPolicyName, false); err != nil {
@@ -172,14 +449,14 @@ func (c *OntapZAPI) IgroupExists(igroupName string) (exists bool, err error) {
 }
 
 // IgroupCreate creates iGroup
-func (c *OntapZAPI) IgroupCreate(igroupName string, osType string) (err error) {
-	if _, _, err = c.Client.IgroupCreateAPI(igroupName, "iscsi", osType, ""); err != nil {
+func (c *OntapZAPI) IgroupCreate(igroupName string, protocol string, osType string) (err error) {
+	if _, _, err = c.Client.IgroupCreateAPI(igroupName, protocol, osType, ""); err != nil {
 		err = fmt.Errorf("IgroupCreateAPI() failure: %s", err)
 	}
 	return
 }
 
-// IgroupAddInitiator adds iSCSI initiator to iGroup
+// IgroupAddInitiator adds iSCSI or FC initiator to iGroup
 func (c *OntapZAPI) IgroupAddInitiator(igroupName string, initiatorName string) (err error) {
 	if _, _, err = c.Client.IgroupAddAPI(igroupName, initiatorName, false); err != nil {
 		err = fmt.Errorf("IgroupAddAPI() failure: %s", err)
@@ -187,6 +464,32 @@ func (c *OntapZAPI) IgroupAddInitiator(igroupName string, initiatorName string)
 	return
 }
 
+// IgroupGetInitiators gets list of iGroup initiators
+func (c *OntapZAPI) IgroupGetInitiators(igroupName string) (initiators []string, err error) {
+	var response *ontap.IgroupGetResponse
+	initiators = []string{}
+	options := &ontap.IgroupGetOptions{
+		MaxRecords: 1,
+		Query: &ontap.IgroupQuery{
+			IgroupInfo: &ontap.IgroupInfo{
+				InitiatorGroupName: igroupName,
+			},
+		},
+	}
+	if response, _, err = c.Client.IgroupGetAPI(options); err != nil {
+		err = fmt.Errorf("IgroupGetAPI() failure: %s", err)
+		return
+	}
+	if response.Results.NumRecords == 0 {
+		err = fmt.Errorf("IgroupGetInitiators() failure: igroup \"%s\" not found", igroupName)
+		return
+	}
+	for _, initiator := range response.Results.AttributesList.IgroupAttributes[0].Initiators {
+		initiators = append(initiators, initiator.InitiatorName)
+	}
+	return
+}
+
 // IgroupDestroy deletes iGroup
 func (c *OntapZAPI) IgroupDestroy(igroupName string) (err error) {
 	if _, _, err = c.Client.IgroupDestroyAPI(igroupName, false); err != nil {
@@ -201,6 +504,23 @@ func (c *OntapZAPI) LunExists(lunPath string) (exists bool, err error) {
 	return
 }
 
+// LunGetMaps gets LUN mappings to iGroups with LUN ID's
+func (c *OntapZAPI) LunGetMaps(lunPath string) (lunMaps []LunMapInfo, err error) {
+	lunMaps = []LunMapInfo{}
+	var response *ontap.LunMapListInfoResponse
+	if response, _, err = c.Client.LunMapListInfoAPI(lunPath); err != nil {
+		err = fmt.Errorf("LunMapListInfoAPI() failure: %s", err)
+		return
+	}
+	for _, igroup := range response.Results.InitiatorGroups.IgroupAttributes {
+		lunMaps = append(lunMaps, LunMapInfo{
+			IgroupName: igroup.InitiatorGroupName,
+			LunId:      igroup.LunId,
+		})
+	}
+	return
+}
+
 // IsLunMapped checks if LUN is mapped to iGroup
 func (c *OntapZAPI) IsLunMapped(lunPath string, igroupName string) (mapped bool, err error) {
 	mapped, err = util.IsLunMapped(c.Client, lunPath, igroupName)
@@ -227,6 +547,30 @@ func (c *OntapZAPI) LunResize(lunPath string, lunSize int) (err error) {
 	return
 }
 
+// LunSetComment sets LUN comment
+func (c *OntapZAPI) LunSetComment(lunPath string, lunComment string) (err error) {
+	params := &lunSetCommentParams{
+		Path:    lunPath,
+		Comment: lunComment,
+	}
+	if err = c.zapiCall(params, nil); err != nil {
+		err = fmt.Errorf("LunSetCommentAPI() failure: %s", err)
+	}
+	return
+}
+
+// LunRename renames LUN within volume
+func (c *OntapZAPI) LunRename(lunPath string, newLunPath string) (err error) {
+	params := &lunMoveParams{
+		Path:    lunPath,
+		NewPath: newLunPath,
+	}
+	if err = c.zapiCall(params, nil); err != nil {
+		err = fmt.Errorf("LunMoveAPI() failure: %s", err)
+	}
+	return
+}
+
 // LunMap maps LUN to iGroup
 func (c *OntapZAPI) LunMap(lunPath string, lunID int, igroupName string) (err error) {
 	bootLunMapOptions := &ontap.LunMapOptions{
@@ -330,6 +674,37 @@ func (c *OntapZAPI) LunGetInfo(lunPath string) (lunInfo *LunInfo, err error) {
 	return
 }
 
+// LunGetInfoList gets info of LUN's in volume or in SVM if volume name is empty
+func (c *OntapZAPI) LunGetInfoList(volumeName string) (lunInfoList []LunInfo, err error) {
+	lunInfoList = []LunInfo{}
+	options := &ontap.LunGetOptions{
+		MaxRecords: 1024,
+	}
+	if volumeName != "" {
+		options.Query = &ontap.LunQuery{
+			LunInfo: &ontap.LunInfo{
+				Volume: volumeName,
+			},
+		}
+	}
+	var response []*ontap.LunGetResponse
+	if response, err = c.Client.LunGetIterAPI(options); err != nil {
+		err = fmt.Errorf("LunGetIterAPI() failure: %s", err)
+		return
+	}
+	for _, responseLun := range response {
+		for _, lun := range responseLun.Results.AttributesList.LunAttributes {
+			lunInfoList = append(lunInfoList, LunInfo{
+				Path:       lun.Path,
+				Comment:    lun.Comment,
+				Size:       int(math.Round(float64(lun.Size) / 1024 / 1024 / 1024)),
+				CreateTime: time.Unix(int64(lun.CreationTimestamp), 0),
+			})
+		}
+	}
+	return
+}
+
 // LunGetList gets list of LUN's
 func (c *OntapZAPI) LunGetList(volumeName string) (lunList []string, err error) {
 	lunList = []string{}
@@ -366,6 +741,237 @@ func (c *OntapZAPI) IscsiTargetGetName() (targetName string, err error) {
 	return
 }
 
+// zapiError is failed ZAPI result with error number
+type zapiError struct {
+	errno  int
+	reason string
+}
+
+func (e *zapiError) Error() string {
+	return e.reason
+}
+
+// zapiErrno gets ZAPI error number of zapiCall failure, zero for other errors
+func zapiErrno(err error) int {
+	if e, ok := err.(*zapiError); ok {
+		return e.errno
+	}
+	return 0
+}
+
+// zapiCall runs ZAPI not implemented in go-ontap-sdk, response is decoded into out
+func (c *OntapZAPI) zapiCall(params interface{}, out interface{}) (err error) {
+	var req *http.Request
+	var res *http.Response
+	var b []byte
+	request := &zapiRequest{
+		Version: c.ZapiVersion,
+		XMLNs:   ontap.XMLNs,
+		Vfiler:  c.Svm,
+		Params:  params,
+	}
+	if req, err = c.Client.NewRequest("POST", request); err != nil {
+		return
+	}
+	if res, err = c.Client.Do(req, nil); err != nil {
+		return
+	}
+	defer res.Body.Close()
+	if b, err = io.ReadAll(res.Body); err != nil {
+		return
+	}
+	r := ontap.SingleResultResponse{}
+	if err = xml.Unmarshal(b, &r); err != nil {
+		return
+	}
+	if !r.Results.Passed() {
+		err = &zapiError{errno: r.Results.ErrorNo, reason: r.Results.Reason}
+		return
+	}
+	if out != nil {
+		err = xml.Unmarshal(b, out)
+	}
+	return
+}
+
+// IscsiInitiatorSetAuth sets CHAP authentication for iSCSI initiator, mutual CHAP is set with outbound credentials
+func (c *OntapZAPI) IscsiInitiatorSetAuth(initiatorName string, chapUser string, chapPassword string, outboundUser string, outboundPassword string) (err error) {
+	if err = c.IscsiInitiatorDeleteAuth(initiatorName); err != nil {
+		return
+	}
+	params := &iscsiInitiatorAuthParams{
+		XMLName:          xml.Name{Local: "iscsi-initiator-add-auth"},
+		Initiator:        initiatorName,
+		AuthType:         "CHAP",
+		UserName:         chapUser,
+		Password:         chapPassword,
+		OutboundUserName: outboundUser,
+		OutboundPassword: outboundPassword,
+	}
+	if err = c.zapiCall(params, nil); err != nil {
+		err = fmt.Errorf("IscsiInitiatorAddAuthAPI() failure: %s", err)
+	}
+	return
+}
+
+// IscsiInitiatorDeleteAuth deletes iSCSI initiator security record
+func (c *OntapZAPI) IscsiInitiatorDeleteAuth(initiatorName string) (err error) {
+	params := &iscsiInitiatorAuthParams{
+		XMLName:   xml.Name{Local: "iscsi-initiator-get-auth"},
+		Initiator: initiatorName,
+	}
+	if err = c.zapiCall(params, nil); err != nil {
+		if errno := zapiErrno(err); errno == ontap.ENTRYDOESNOTEXIST || errno == ontap.EISCSISECINITNOTFOUNDERROR {
+			// No initiator specific security record
+			err = nil
+		} else {
+			err = fmt.Errorf("IscsiInitiatorGetAuthAPI() failure: %s", err)
+		}
+		return
+	}
+	params.XMLName = xml.Name{Local: "iscsi-initiator-delete-auth"}
+	if err = c.zapiCall(params, nil); err != nil {
+		err = fmt.Errorf("IscsiInitiatorDeleteAuthAPI() failure: %s", err)
+	}
+	return
+}
+
+// snapmirrorGet gets snapmirror-info record by destination path
+func (c *OntapZAPI) snapmirrorGet(destinationPath string) (info *snapmirrorInfo, err error) {
+	params := &snapmirrorGetIterParams{}
+	params.Query.SnapmirrorInfo.DestinationLocation = destinationPath
+	r := snapmirrorGetIterResponse{}
+	if err = c.zapiCall(params, &r); err != nil {
+		err = fmt.Errorf("SnapmirrorGetIterAPI() failure: %s", err)
+		return
+	}
+	if len(r.Results.AttributesList.SnapmirrorInfo) > 0 {
+		info = &r.Results.AttributesList.SnapmirrorInfo[0]
+	}
+	return
+}
+
+// SnapmirrorGet gets SnapMirror relationship info by destination path
+func (c *OntapZAPI) SnapmirrorGet(destinationPath string) (smInfo *SnapmirrorInfo, err error) {
+	var info *snapmirrorInfo
+	if info, err = c.snapmirrorGet(destinationPath); err != nil || info == nil {
+		return
+	}
+	smInfo = &SnapmirrorInfo{
+		SourcePath:      info.SourceLocation,
+		DestinationPath: info.DestinationLocation,
+		Policy:          info.Policy,
+		Schedule:        info.Schedule,
+		State:           strings.Replace(info.MirrorState, "-", "_", -1),
+		Healthy:         info.IsHealthy,
+	}
+	return
+}
+
+// SnapmirrorCreate creates and initializes SnapMirror relationship
+func (c *OntapZAPI) SnapmirrorCreate(sourcePath string, destinationPath string, policy string, schedule string) (err error) {
+	params := &snapmirrorParams{
+		XMLName:             xml.Name{Local: "snapmirror-create"},
+		SourceLocation:      sourcePath,
+		DestinationLocation: destinationPath,
+		Policy:              policy,
+		Schedule:            schedule,
+		RelationshipType:    "extended_data_protection",
+	}
+	if err = c.zapiCall(params, nil); err != nil {
+		err = fmt.Errorf("SnapmirrorCreateAPI() failure: %s", err)
+		return
+	}
+	params = &snapmirrorParams{
+		XMLName:             xml.Name{Local: "snapmirror-initialize"},
+		DestinationLocation: destinationPath,
+	}
+	if err = c.zapiCall(params, nil); err != nil {
+		err = fmt.Errorf("SnapmirrorInitializeAPI() failure: %s", err)
+	}
+	return
+}
+
+// SnapmirrorModify modifies SnapMirror relationship policy and schedule
+func (c *OntapZAPI) SnapmirrorModify(destinationPath string, policy string, schedule string) (err error) {
+	params := &snapmirrorParams{
+		XMLName:             xml.Name{Local: "snapmirror-modify"},
+		DestinationLocation: destinationPath,
+		Policy:              policy,
+		Schedule:            schedule,
+	}
+	if err = c.zapiCall(params, nil); err != nil {
+		err = fmt.Errorf("SnapmirrorModifyAPI() failure: %s", err)
+	}
+	return
+}
+
+// SnapmirrorUpdate starts SnapMirror transfer
+func (c *OntapZAPI) SnapmirrorUpdate(destinationPath string) (err error) {
+	params := &snapmirrorParams{
+		XMLName:             xml.Name{Local: "snapmirror-update"},
+		DestinationLocation: destinationPath,
+	}
+	if err = c.zapiCall(params, nil); err != nil {
+		err = fmt.Errorf("SnapmirrorUpdateAPI() failure: %s", err)
+	}
+	return
+}
+
+// SnapmirrorBreak quiesces and breaks SnapMirror relationship making destination volume writable
+func (c *OntapZAPI) SnapmirrorBreak(destinationPath string) (err error) {
+	var info *snapmirrorInfo
+	if info, err = c.snapmirrorGet(destinationPath); err != nil {
+		return
+	}
+	if info == nil {
+		err = fmt.Errorf("SnapmirrorBreakAPI() failure: relationship \"%s\" not found", destinationPath)
+		return
+	}
+	if info.MirrorState == "broken-off" {
+		return
+	}
+	params := &snapmirrorParams{
+		XMLName:             xml.Name{Local: "snapmirror-quiesce"},
+		DestinationLocation: destinationPath,
+	}
+	if err = c.zapiCall(params, nil); err != nil {
+		err = fmt.Errorf("SnapmirrorQuiesceAPI() failure: %s", err)
+		return
+	}
+	giveupTime := time.Now().Add(time.Second * SNAPMIRROR_QUIESCE_TIMEOUT)
+	for time.Now().Before(giveupTime) {
+		if info, err = c.snapmirrorGet(destinationPath); err != nil {
+			return
+		}
+		if info != nil && info.RelationshipStatus == "quiesced" {
+			break
+		}
+		time.Sleep(5 * time.Second)
+	}
+	params.XMLName = xml.Name{Local: "snapmirror-break"}
+	if err = c.zapiCall(params, nil); err != nil {
+		err = fmt.Errorf("SnapmirrorBreakAPI() failure: %s", err)
+	}
+	return
+}
+
+// SnapmirrorDelete deletes SnapMirror relationship
+func (c *OntapZAPI) SnapmirrorDelete(destinationPath string) (err error) {
+	var info *snapmirrorInfo
+	if info, err = c.snapmirrorGet(destinationPath); err != nil || info == nil {
+		return
+	}
+	params := &snapmirrorParams{
+		XMLName:             xml.Name{Local: "snapmirror-destroy"},
+		DestinationLocation: destinationPath,
+	}
+	if err = c.zapiCall(params, nil); err != nil {
+		err = fmt.Errorf("SnapmirrorDestroyAPI() failure: %s", err)
+	}
+	return
+}
+
 // DiscoverIscsiLIFs gets list of iSCSI interfaces for LUN
 func (c *OntapZAPI) DiscoverIscsiLIFs(lunPath string, initiatorSubnet string) (lifs []string, err error) {
 	var iscsiLifs []*ontap.NetInterfaceInfo
@@ -384,6 +990,55 @@ func (c *OntapZAPI) DiscoverIscsiLIFs(lunPath string, initiatorSubnet string) (l
 	return
 }
 
+// FcpTargetGetName gets FCP target node name (WWNN)
+func (c *OntapZAPI) FcpTargetGetName() (targetName string, err error) {
+	params := &fcpServiceGetIterParams{}
+	params.Query.FcpServiceInfo.IsAvailable = "true"
+	r := fcpServiceGetIterResponse{}
+	if err = c.zapiCall(params, &r); err != nil {
+		err = fmt.Errorf("FcpServiceGetIterAPI() failure: %s", err)
+		return
+	}
+	if len(r.Results.AttributesList.FcpServiceInfo) > 0 {
+		targetName = r.Results.AttributesList.FcpServiceInfo[0].NodeName
+	}
+	if targetName == "" {
+		err = fmt.Errorf("FcpServiceGetIterAPI() failure: FCP service is not running")
+	}
+	return
+}
+
+// GetFcpLIFs gets list of FC interfaces WWPN's,
+// ZAPI does not report fabric FC interface is logged in to
+func (c *OntapZAPI) GetFcpLIFs() (lifs []FcInterface, err error) {
+	var responses []*ontap.NetInterfaceGetResponse
+	lifs = []FcInterface{}
+	options := &ontap.NetInterfaceGetOptions{
+		MaxRecords: 1024,
+		Query: &ontap.NetInterfaceQuery{
+			NetInterfaceInfo: &ontap.NetInterfaceInfo{
+				DataProtocols:     &[]string{"fcp"},
+				OperationalStatus: "up",
+			},
+		},
+	}
+	if responses, err = c.Client.NetInterfaceGetIterAPI(options); err != nil {
+		err = fmt.Errorf("NetInterfaceGetIterAPI() failure: %s", err)
+		return
+	}
+	for _, response := range responses {
+		for _, lif := range response.Results.AttributesList.NetInterfaceAttributes {
+			if lif.Wwpn != "" {
+				lifs = append(lifs, FcInterface{Name: lif.InterfaceName, Wwpn: lif.Wwpn})
+			}
+		}
+	}
+	if len(lifs) == 0 {
+		err = fmt.Errorf("GetFcpLIFs(): no FC interfaces found")
+	}
+	return
+}
+
 // FileExists checks if file exists
 func (c *OntapZAPI) FileExists(volumeName string, filePath string) (exists bool, err error) {
 	if exists, err = util.FileExists(c.Client, "/vol/"+volumeName+filePath); err != nil {
@@ -482,6 +1137,27 @@ func (c *OntapZAPI) SnapshotGetList(volumeName string) (snapshots []string, err
 	return
 }
 
+// SnapshotGetInfoList gets list of snapshots with comment and create time
+func (c *OntapZAPI) SnapshotGetInfoList(volumeName string) (snapshots []SnapshotInfo, err error) {
+	snapshots = []SnapshotInfo{}
+	snapOptions := &ontap.SnapshotListInfoOptions{
+		Volume: volumeName,
+	}
+	var snapResponse *ontap.SnapshotListInfoResponse
+	if snapResponse, _, err = c.Client.SnapshotListInfoAPI(snapOptions); err != nil {
+		err = fmt.Errorf("SnapshotListInfoAPI() failure: %s", err)
+		return
+	}
+	for _, snapshot := range snapResponse.Results.Snapshots {
+		snapshots = append(snapshots, SnapshotInfo{
+			Name:       snapshot.Name,
+			Comment:    snapshot.Comment,
+			CreateTime: time.Unix(int64(snapshot.AccessTime), 0),
+		})
+	}
+	return
+}
+
 // SnapshotCreate creates snapshot
 func (c *OntapZAPI) SnapshotCreate(volumeName string, snapshotName string, snapshotComment string) (err error) {
 	options := &ontap.SnapshotCreateOptions{
@@ -495,6 +1171,36 @@ func (c *OntapZAPI) SnapshotCreate(volumeName string, snapshotName string, snaps
 	return
 }
 
+// SnapshotCreateGroup creates consistency group snapshot in a group of volumes
+func (c *OntapZAPI) SnapshotCreateGroup(volumeNames []string, snapshotName string) (err error) {
+	params := &cgStartParams{
+		Snapshot: snapshotName,
+		Timeout:  "relaxed",
+		Volumes:  volumeNames,
+	}
+	r := cgStartResponse{}
+	if err = c.zapiCall(params, &r); err != nil {
+		err = fmt.Errorf("CgStartAPI() failure: %s", err)
+		return
+	}
+	if err = c.zapiCall(&cgCommitParams{CgId: r.Results.CgId}, nil); err != nil {
+		err = fmt.Errorf("CgCommitAPI() failure: %s", err)
+	}
+	return
+}
+
+// SnapshotSetComment sets snapshot comment
+func (c *OntapZAPI) SnapshotSetComment(volumeName string, snapshotName string, snapshotComment string) (err error) {
+	params := &snapshotModifyIterParams{}
+	params.Query.SnapshotInfo.Volume = volumeName
+	params.Query.SnapshotInfo.Name = snapshotName
+	params.Attributes.SnapshotInfo.Comment = snapshotComment
+	if err = c.zapiCall(params, nil); err != nil {
+		err = fmt.Errorf("SnapshotModifyIterAPI() failure: %s", err)
+	}
+	return
+}
+
 // SnapshotDelete deletes snapshot
 func (c *OntapZAPI) SnapshotDelete(volumeName string, snapshotName string) (err error) {
 	options := &ontap.SnapshotDeleteOptions{
@@ -526,11 +1232,11 @@ func (c *OntapZAPI) SnapshotRestore(volumeName string, snapshotName string) (err
 }
 
 // Create LUN and upload data
-func (c *OntapZAPI) LunCreateAndUpload(volumeName string, filePath string, fileSize int64, fileReader io.Reader, lunPath string, lunComment string, osType string) (err error) {
+func (c *OntapZAPI) LunCreateAndUpload(volumeName string, filePath string, fileSize int64, fileReader io.Reader, lunPath string, lunComment string, osType string, progress UploadProgress) (err error) {
         if filePath == "/seed" {
-	        err = c.FileUploadAPI(volumeName, filePath, fileReader)
+	        err = c.fileChunkedUploadAPI(volumeName, filePath, fileSize, fileReader, progress)
 	} else {
-	        err = c.FileUploadNFS(volumeName, filePath, fileReader)
+	        err = c.fileChunkedUploadNFS(volumeName, filePath, fileSize, fileReader, progress)
 	}
 	if err == nil {
 		time.Sleep(10 * time.Second)
@@ -542,8 +1248,206 @@ func (c *OntapZAPI) LunCreateAndUpload(volumeName string, filePath string, fileS
         return
 }
 
-// Upload data to existent LUN (not implemented in ZAPI)
-func (c *OntapZAPI) LunUpload(lunPath string, fileReader io.Reader, fileSize int64) (err error) {
+// Upload data to existent LUN, ZAPI has no LUN write API, file writes do not apply to LUN's
+func (c *OntapZAPI) LunUpload(lunPath string, fileReader io.Reader, fileSize int64, progress UploadProgress) (err error) {
+	err = fmt.Errorf("LunUpload(): upload to existing LUN %s is not supported by ZAPI, use REST API (api_method \"rest\" or \"auto\")", lunPath)
+	return
+}
+
+// zapiFileWriter writes upload chunks to file via ZAPI
+type zapiFileWriter struct {
+	c    *OntapZAPI
+	path string
+}
+
+// WriteAt writes chunk to file at given offset
+func (w *zapiFileWriter) WriteAt(p []byte, offset int64) (n int, err error) {
+	for n < len(p) {
+		end := n + zapiFileWriteMax
+		if end > len(p) {
+			end = len(p)
+		}
+		options := &ontap.FileWriteFileOptions{
+			Path:   w.path,
+			Offset: int(offset) + n,
+			Data:   hex.EncodeToString(p[n:end]),
+		}
+		if _, _, err = w.c.Client.FileWriteFileAPI(options); err != nil {
+			err = fmt.Errorf("FileWriteFileAPI() failure: %s", err)
+			return
+		}
+		n = end
+	}
+	return
+}
+
+// Close is no-op for ZAPI writes
+func (w *zapiFileWriter) Close() error {
+	return nil
+}
+
+// fileChunkedUploadAPI uploads file content via ZAPI in chunks,
+// writes are sequential since file-write-file creates the file on the first write
+func (c *OntapZAPI) fileChunkedUploadAPI(volumeName string, filePath string, fileSize int64, reader io.Reader, progress UploadProgress) (err error) {
+	settings := c.upload
+	settings.Parallelism = 1
+	newWriter := func() (chunkWriter, error) {
+		return &zapiFileWriter{c: c, path: "/vol/" + volumeName + filePath}, nil
+	}
+	if _, err = chunkedUpload(settings, reader, fileSize, newWriter, nil, progress); err != nil {
+		err = fmt.Errorf("fileChunkedUploadAPI(): %s", err)
+	}
+	return
+}
+
+// nfsFileWriter writes upload chunks to file via NFS, every writer has own NFS mount
+type nfsFileWriter struct {
+	mount  *nfs.Mount
+	target *nfs.Target
+	file   *nfs.File
+}
+
+// newNfsFileWriter mounts volume junction path and opens file for writing
+func newNfsFileWriter(serverIP string, junctionPath string, filePath string) (w *nfsFileWriter, err error) {
+	w = &nfsFileWriter{}
+	if w.mount, err = nfs.DialMount(serverIP); err != nil {
+		err = fmt.Errorf("DialMount() failure: %s", err)
+		return
+	}
+	auth := rpc.NewAuthUnix("root", 0, 0)
+	if w.target, err = w.mount.Mount(junctionPath, auth.Auth()); err != nil {
+		w.mount.Close()
+		err = fmt.Errorf("Mount() failure: %s", err)
+		return
+	}
+	if w.file, err = w.target.OpenFile(filePath, 0644); err != nil {
+		w.target.Close()
+		w.mount.Close()
+		err = fmt.Errorf("OpenFile() failure: %s", err)
+	}
+	return
+}
+
+// WriteAt writes chunk to file at given offset
+func (w *nfsFileWriter) WriteAt(p []byte, offset int64) (n int, err error) {
+	if _, err = w.file.Seek(offset, io.SeekStart); err != nil {
+		return
+	}
+	n, err = w.file.Write(p)
+	return
+}
+
+// Close commits the file and unmounts volume
+func (w *nfsFileWriter) Close() (err error) {
+	err = w.file.Close()
+	w.target.Close()
+	w.mount.Close()
+	return
+}
+
+// fileChunkedUploadNFS uploads file content via NFS in parallel chunks
+func (c *OntapZAPI) fileChunkedUploadNFS(volumeName string, filePath string, fileSize int64, reader io.Reader, progress UploadProgress) (err error) {
+	errorFormat := "fileChunkedUploadNFS(): %s"
+	var clientIP net.IP
+	if clientIP, err = util.GetOutboundIP(); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	options := &ontap.VolumeGetOptions{
+		MaxRecords: 1,
+		Query: &ontap.VolumeQuery{
+			VolumeInfo: &ontap.VolumeInfo{
+				VolumeIDAttributes: &ontap.VolumeIDAttributes{
+					Name: volumeName,
+				},
+			},
+		},
+	}
+	var response *ontap.VolumeGetResponse
+	if response, _, err = c.Client.VolumeGetAPI(options); err != nil {
+		err = fmt.Errorf("fileChunkedUploadNFS(): VolumeGetAPI() failure: %s", err)
+		return
+	}
+	if response.Results.NumRecords != 1 {
+		err = fmt.Errorf("fileChunkedUploadNFS(): volume %s not found", volumeName)
+		return
+	}
+	exportPolicy := response.Results.AttributesList[0].VolumeExportAttributes.Policy
+	junctionPath := response.Results.AttributesList[0].VolumeIDAttributes.JunctionPath
+	var lifs []*ontap.NetInterfaceInfo
+	if lifs, err = util.DiscoverNfsLIFs(c.Client, volumeName); err != nil {
+		err = fmt.Errorf("fileChunkedUploadNFS(): DiscoverNfsLIFs() failure: %s", err)
+		return
+	}
+	if len(lifs) == 0 {
+		err = fmt.Errorf("fileChunkedUploadNFS(): no NFS LIFs found for volume %s", volumeName)
+		return
+	}
+	serverIP := lifs[0].Address
+	if err = c.exportRuleCreate(exportPolicy, clientIP.String()); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	defer c.exportRuleDelete(exportPolicy, clientIP.String())
+	// file is created before parallel writers open it
+	var w *nfsFileWriter
+	if w, err = newNfsFileWriter(serverIP, junctionPath, filePath); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	w.Close()
+	newWriter := func() (chunkWriter, error) {
+		return newNfsFileWriter(serverIP, junctionPath, filePath)
+	}
+	if _, err = chunkedUpload(c.upload, reader, fileSize, newWriter, nil, progress); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+	}
+	return
+}
+
+// exportRuleCreate creates export rule for NFS client
+func (c *OntapZAPI) exportRuleCreate(policyName string, clientIP string) (err error) {
+	options := &ontap.ExportRuleCreateOptions{
+		PolicyName:           policyName,
+		AnonymousUserId:      "0",
+		SuperUserSecurity:    &[]string{"any"},
+		Protocol:             &[]string{"nfs"},
+		IsAllowDevIsEnabled:  true,
+		IsAllowSetUidEnabled: true,
+		ClientMatch:          clientIP,
+		RwRule:               &[]string{"any"},
+		RoRule:               &[]string{"any"},
+	}
+	if _, _, err = c.Client.ExportRuleCreateAPI(options); err != nil {
+		err = fmt.Errorf("ExportRuleCreateAPI() failure: %s", err)
+	}
+	return
+}
+
+// exportRuleDelete deletes export rules for NFS client
+func (c *OntapZAPI) exportRuleDelete(policyName string, clientIP string) (err error) {
+	options := &ontap.ExportRuleGetOptions{
+		MaxRecords: 1024,
+		Query: &ontap.ExportRuleQuery{
+			ExportRuleInfo: &ontap.ExportRuleInfo{
+				PolicyName:  policyName,
+				ClientMatch: clientIP,
+			},
+		},
+	}
+	var response *ontap.ExportRuleGetResponse
+	if response, _, err = c.Client.ExportRuleGetAPI(options); err != nil {
+		err = fmt.Errorf("ExportRuleGetAPI() failure: %s", err)
+		return
+	}
+	if response.Results.NumRecords > 0 {
+		for _, rule := range response.Results.AttributesList.ExportRuleAttributes {
+			if _, _, err = c.Client.ExportRuleDestroyAPI(policyName, rule.RuleIndex); err != nil {
+				err = fmt.Errorf("ExportRuleDestroyAPI() failure: %s", err)
+				return
+			}
+		}
+	}
 	return
 }
 
@@ -562,6 +1466,11 @@ func (c *OntapZAPI) NvmeSubsystemDestroy(subsystemName string) (err error) {
         return
 }
 
+// NvmeSubsystemGetHosts gets NQN's of NVME Subsystem hosts
+func (c *OntapZAPI) NvmeSubsystemGetHosts(subsystemName string) (hostNqns []string, err error) {
+        return
+}
+
 // Add Host to NVME Subsystem
 func (c *OntapZAPI) NvmeSubsystemAddHost(subsystemName string, hostNqn string) (err error) {
         return
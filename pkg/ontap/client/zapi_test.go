@@ -0,0 +1,159 @@
+package client
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+// zapiStub is ZAPI endpoint of cluster with SVM's and their aggregates (available size in bytes)
+type zapiStub struct {
+	*httptest.Server
+	aggregates map[string]map[string]int
+	mu         sync.Mutex
+	calls      []string
+}
+
+// newZapiStub starts ZAPI endpoint, it serves vserver-get-iter and vserver-show-aggr-get-iter
+func newZapiStub(aggregates map[string]map[string]int) (s *zapiStub) {
+	s = &zapiStub{aggregates: aggregates}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serveHTTP))
+	return
+}
+
+// host gets stub host for cdotCredentials.host
+func (s *zapiStub) host() string {
+	return strings.TrimPrefix(s.URL, "https://")
+}
+
+// parseZapiRequest parses API name and text of API parameters
+func parseZapiRequest(body io.Reader) (api string, params map[string]string, err error) {
+	params = make(map[string]string)
+	decoder := xml.NewDecoder(body)
+	var element string
+	for {
+		var token xml.Token
+		if token, err = decoder.Token(); err == io.EOF {
+			err = nil
+			return
+		} else if err != nil {
+			return
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "netapp" && api == "" {
+				api = t.Name.Local
+			}
+			element = t.Name.Local
+		case xml.CharData:
+			if text := strings.TrimSpace(string(t)); text != "" {
+				params[element] = text
+			}
+		}
+	}
+}
+
+func (s *zapiStub) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	api, params, err := parseZapiRequest(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, api+"("+params["vserver-name"]+params["vserver"]+")")
+	var results string
+	switch api {
+	case "vserver-get-iter":
+		if _, ok := s.aggregates[params["vserver-name"]]; ok {
+			results = fmt.Sprintf(`<results status="passed"><num-records>1</num-records><attributes-list><vserver-info><vserver-name>%s</vserver-name></vserver-info></attributes-list></results>`, params["vserver-name"])
+		} else {
+			results = `<results status="passed"><num-records>0</num-records></results>`
+		}
+	case "vserver-show-aggr-get-iter":
+		aggregates, ok := s.aggregates[params["vserver"]]
+		if !ok {
+			results = fmt.Sprintf(`<results status="failed" errno="15698" reason="Vserver %s does not exist."/>`, params["vserver"])
+			break
+		}
+		var names []string
+		for name := range aggregates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var b strings.Builder
+		for _, name := range names {
+			fmt.Fprintf(&b, "<show-aggregates><aggregate-name>%s</aggregate-name><available-size>%d</available-size><vserver-name>%s</vserver-name></show-aggregates>", name, aggregates[name], params["vserver"])
+		}
+		results = fmt.Sprintf(`<results status="passed"><num-records>%d</num-records><attributes-list>%s</attributes-list></results>`, len(names), b.String())
+	default:
+		results = fmt.Sprintf(`<results status="failed" errno="13005" reason="Unable to find API: %s"/>`, api)
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><netapp version="1.15" xmlns="http://www.netapp.com/filer/admin">%s</netapp>`, results)
+}
+
+func TestZapiGetAggregateMaxReplica(t *testing.T) {
+	primary := newZapiStub(map[string]map[string]int{"svm1": {"aggr1": 500 << 30, "aggr2": 800 << 30}})
+	defer primary.Close()
+	dr := newZapiStub(map[string]map[string]int{"svm1_dr": {"aggr_dr1": 900 << 30, "aggr_dr2": 300 << 30}})
+	defer dr.Close()
+	nodeConfig := &config.NodeConfig{}
+	nodeConfig.Storage.CdotCredentials = config.CdotCredentials{Credentials: config.Credentials{Host: primary.host(), User: "admin", Password: "secret"}, ApiMethod: "zapi"}
+	nodeConfig.Storage.SvmName = "svm1"
+	nodeConfig.Storage.BootLun.Size = 20
+	nodeConfig.Storage.DataLun.Size = 100
+	nodeConfig.Storage.Replication.CdotCredentials = config.CdotCredentials{Credentials: config.Credentials{Host: dr.host(), User: "admin", Password: "secret"}, ApiMethod: "zapi"}
+	nodeConfig.Storage.Replication.SvmName = "svm1_dr"
+
+	c, err := NewOntapClient(nodeConfig)
+	if err != nil {
+		t.Fatalf("NewOntapClient() failure: %s", err)
+	}
+	if aggregateName, err := c.GetAggregateMax(nodeConfig); err != nil || aggregateName != "aggr2" {
+		t.Fatalf("unexpected primary aggregate %q, error %v", aggregateName, err)
+	}
+	// DR cluster client is given primary node config, aggregates are of DR SVM
+	if c, err = NewOntapReplicaClient(nodeConfig); err != nil {
+		t.Fatalf("NewOntapReplicaClient() failure: %s", err)
+	}
+	if aggregateName, err := c.GetAggregateMax(nodeConfig); err != nil || aggregateName != "aggr_dr1" {
+		t.Fatalf("unexpected DR aggregate %q, error %v", aggregateName, err)
+	}
+	if calls := strings.Join(dr.calls, ","); calls != "vserver-get-iter(svm1_dr),vserver-show-aggr-get-iter(svm1_dr)" {
+		t.Fatalf("unexpected DR cluster calls %s", calls)
+	}
+	if nodeConfig.Storage.SvmName != "svm1" {
+		t.Fatalf("primary SVM is changed to %s", nodeConfig.Storage.SvmName)
+	}
+
+	// requested storage does not fit DR aggregates
+	nodeConfig.Storage.DataLun.Size = 500
+	if _, err = c.GetAggregateMax(nodeConfig); err == nil || !strings.Contains(err.Error(), "no aggregates found for requested storage size 1040GB") {
+		t.Fatalf("expected storage size failure, got %v", err)
+	}
+}
+
+func TestZapiLunUploadNotSupported(t *testing.T) {
+	stub := newZapiStub(map[string]map[string]int{"svm1": {"aggr1": 500 << 30}})
+	defer stub.Close()
+	nodeConfig := &config.NodeConfig{}
+	nodeConfig.Storage.CdotCredentials = config.CdotCredentials{Credentials: config.Credentials{Host: stub.host(), User: "admin", Password: "secret"}, ApiMethod: "zapi"}
+	nodeConfig.Storage.SvmName = "svm1"
+	c, err := NewOntapClient(nodeConfig)
+	if err != nil {
+		t.Fatalf("NewOntapClient() failure: %s", err)
+	}
+	err = c.LunUpload("/vol/node1_iboot/node1_iboot", strings.NewReader("ESX boot ISO"), 12, nil)
+	if err == nil || !strings.Contains(err.Error(), "upload to existing LUN /vol/node1_iboot/node1_iboot is not supported by ZAPI") {
+		t.Fatalf("expected not supported failure, got %v", err)
+	}
+}
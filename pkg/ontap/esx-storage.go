@@ -14,6 +14,7 @@ import (
 
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/util/artifact"
 )
 
 // CreateEsxStorage creates ESX node storage in cDOT
@@ -79,9 +80,26 @@ func CreateEsxStorage(nodeConfig *config.NodeConfig) (err error) {
 	}
 	defer os.RemoveAll(overlayTmpDir)
 	bootImagePath := tmpDir + "/" + nodeConfig.Storage.BootLun.OsImage.Name
+	osImagePath := nodeConfig.Storage.BootLun.OsImage.Location
+	if artifact.IsStoreLocation(osImagePath) {
+		// xorriso needs local copy of ESX installation ISO
+		osImagePath = tmpDir + "/source-" + nodeConfig.Storage.BootLun.OsImage.Name
+		if err = downloadArtifact(nodeConfig, nodeConfig.Storage.BootLun.OsImage.Location, osImagePath); err != nil {
+			err = fmt.Errorf("CreateEsxStorage(): %s", err)
+			return
+		}
+	}
 	var fileReader io.Reader
 	var file *os.File
-	if strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "http://") || strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "https://") {
+	if artifact.IsStoreLocation(nodeConfig.Storage.SeedLun.SeedTemplate.Location) {
+		var artifactReader io.ReadCloser
+		if artifactReader, _, err = artifact.Open(nodeConfig.Storage.SeedLun.SeedTemplate.Location, nodeConfig.Storage.ArtifactSources); err != nil {
+			err = fmt.Errorf("CreateEsxStorage(): failure to get kickstart template %s: %s", nodeConfig.Storage.SeedLun.SeedTemplate.Location, err)
+			return
+		}
+		fileReader = artifactReader
+		defer artifactReader.Close()
+	} else if strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "http://") || strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "https://") {
 		var httpResponse *http.Response
 		if httpResponse, err = http.Get(nodeConfig.Storage.SeedLun.SeedTemplate.Location); err == nil {
 			fileReader = httpResponse.Body
@@ -132,7 +150,7 @@ func CreateEsxStorage(nodeConfig *config.NodeConfig) (err error) {
 	}
 	os.Remove(filepath.Join(overlayTmpDir, "ks.cfg"))
 	var bootCfg []byte
-	if bootCfg, err = extractISOFile(nodeConfig.Storage.BootLun.OsImage.Location, "/BOOT.CFG"); err != nil {
+	if bootCfg, err = extractISOFile(osImagePath, "/BOOT.CFG"); err != nil {
 		err = fmt.Errorf("CreateEsxStorage(): failure to extract boot.cfg: %s", err)
 		return
 	}
@@ -160,7 +178,7 @@ func CreateEsxStorage(nodeConfig *config.NodeConfig) (err error) {
 		err = fmt.Errorf("CreateEsxStorage(): failute to write to EFI boot.cfg: %s", err)
 		return
 	}
-	if err = buildISO(nodeConfig.Storage.BootLun.OsImage.Location, bootImagePath, overlayTmpDir, nodeConfig.Compute.Firmware); err != nil {
+	if err = buildISO(osImagePath, bootImagePath, overlayTmpDir, nodeConfig.Compute.Firmware); err != nil {
 		err = fmt.Errorf("CreateEsxStorage(): buildISO(): %s", err)
 		return
 	}
@@ -174,7 +192,7 @@ func CreateEsxStorage(nodeConfig *config.NodeConfig) (err error) {
 		return
 	}
 	imageSize := int64(fileInfo.Size())
-	if err = c.LunUpload(bootLunPath, file, imageSize); err != nil {
+	if err = c.LunUpload(bootLunPath, file, imageSize, nil); err != nil {
 		err = fmt.Errorf(errorFormat, err)
 		return
         }
@@ -184,7 +202,7 @@ func CreateEsxStorage(nodeConfig *config.NodeConfig) (err error) {
 		return
 	}
 	if !igroupExists {
-		if c.IgroupCreate(nodeConfig.Storage.IgroupName, "vmware"); err != nil {
+		if c.IgroupCreate(nodeConfig.Storage.IgroupName, "iscsi", "vmware"); err != nil {
 			err = fmt.Errorf(errorFormat, err)
 			return
 		}
@@ -227,7 +245,17 @@ func CreateEsxStoragePreflight(nodeConfig *config.NodeConfig) (err error) {
 		err = fmt.Errorf("CreateEsxStoragePreflight(): isohybrid not found: %s ", err)
 	}
 	var imageFile, templateFile *os.File
-	if !(strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "http://") || strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "https://")) {
+	for _, location := range []string{nodeConfig.Storage.SeedLun.SeedTemplate.Location, nodeConfig.Storage.BootLun.OsImage.Location} {
+		if artifact.IsStoreLocation(location) {
+			var artifactReader io.ReadCloser
+			if artifactReader, _, err = artifact.Open(location, nodeConfig.Storage.ArtifactSources); err != nil {
+				err = fmt.Errorf("CreateEsxStoragePreflight(): failure to open %s: %s", location, err)
+				return
+			}
+			artifactReader.Close()
+		}
+	}
+	if !(strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "http://") || strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "https://") || artifact.IsStoreLocation(nodeConfig.Storage.SeedLun.SeedTemplate.Location)) {
 		if strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "file://") {
 			templateFile, err = os.Open(nodeConfig.Storage.SeedLun.SeedTemplate.Location[7:])
 		} else {
@@ -239,7 +267,7 @@ func CreateEsxStoragePreflight(nodeConfig *config.NodeConfig) (err error) {
 		}
 		defer templateFile.Close()
 	}
-	if !(strings.HasPrefix(nodeConfig.Storage.BootLun.OsImage.Location, "http://") || strings.HasPrefix(nodeConfig.Storage.BootLun.OsImage.Location, "https://")) {
+	if !(strings.HasPrefix(nodeConfig.Storage.BootLun.OsImage.Location, "http://") || strings.HasPrefix(nodeConfig.Storage.BootLun.OsImage.Location, "https://") || artifact.IsStoreLocation(nodeConfig.Storage.BootLun.OsImage.Location)) {
 		if strings.HasPrefix(nodeConfig.Storage.BootLun.OsImage.Location, "file://") {
 			imageFile, err = os.Open(nodeConfig.Storage.BootLun.OsImage.Location[7:])
 		} else {
@@ -373,6 +401,26 @@ func extractISOFile(isoPath, isoFilePath string) ([]byte, error) {
 	return os.ReadFile(tmp.Name())
 }
 
+// downloadArtifact downloads artifact from object storage or OCI registry into local file
+func downloadArtifact(nodeConfig *config.NodeConfig, location string, localPath string) (err error) {
+	var artifactReader io.ReadCloser
+	if artifactReader, _, err = artifact.Open(location, nodeConfig.Storage.ArtifactSources); err != nil {
+		err = fmt.Errorf("downloadArtifact(): %s", err)
+		return
+	}
+	defer artifactReader.Close()
+	var file *os.File
+	if file, err = os.Create(localPath); err != nil {
+		err = fmt.Errorf("downloadArtifact(): failure to create file %s: %s", localPath, err)
+		return
+	}
+	defer file.Close()
+	if _, err = io.Copy(file, artifactReader); err != nil {
+		err = fmt.Errorf("downloadArtifact(): failure to download %s: %s", location, err)
+	}
+	return
+}
+
 // buildISO creates a new ISO by overlaying modified files onto the source ISO.
 func buildISO(inputISO, outputISO, overlayDir string, firmware string) (err error) {
 	var args []string
@@ -0,0 +1,126 @@
+package ontap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client/fake"
+)
+
+const (
+	testCdotHost  = "cdot.test"
+	testSvm       = "svm1"
+	testImageName = "ubuntu-22.04"
+	testTemplate  = "#cloud-config\nhostname: {{.Compute.HostName}}\n"
+)
+
+// useFakeFactory makes pkg/ontap functions run against in-memory clusters for the duration of the test
+func useFakeFactory(t *testing.T) *fake.Factory {
+	f := fake.NewFactory()
+	client.SetClientFactory(f.NewClient)
+	settleTime := repoVolumeSettleTime
+	repoVolumeSettleTime = 0
+	t.Cleanup(func() {
+		client.SetClientFactory(nil)
+		repoVolumeSettleTime = settleTime
+	})
+	cluster := f.Cluster(testCdotHost, testSvm)
+	cluster.IscsiLIFs = []string{"192.168.10.11", "192.168.10.12", "192.168.20.11"}
+	return f
+}
+
+// testNodeConfig makes node configuration with boot, seed and data LUN's and cloud-init template in test directory
+func testNodeConfig(t *testing.T, hostName string) *config.NodeConfig {
+	nodeConfig := &config.NodeConfig{}
+	nodeConfig.Compute.HostName = hostName
+	nodeConfig.Storage.CdotCredentials.Host = testCdotHost
+	nodeConfig.Storage.SvmName = testSvm
+	nodeConfig.Storage.ImageRepoName = "image_repo"
+	nodeConfig.Storage.TemplateRepoName = "template_repo"
+	nodeConfig.Storage.VolumeName = hostName + "_iboot"
+	nodeConfig.Storage.IgroupName = hostName + "_iboot"
+	nodeConfig.Storage.BootLun.Name = hostName + "_iboot"
+	nodeConfig.Storage.BootLun.Id = 0
+	nodeConfig.Storage.BootLun.Size = 2
+	nodeConfig.Storage.BootLun.OsImage.Name = testImageName
+	nodeConfig.Storage.SeedLun.Name = hostName + "_seed"
+	nodeConfig.Storage.SeedLun.Id = 1
+	nodeConfig.Storage.DataLun.Name = hostName + "_data"
+	nodeConfig.Storage.DataLun.Id = 2
+	nodeConfig.Storage.DataLun.Size = 4
+	templatePath := filepath.Join(t.TempDir(), "cloud-init.yaml")
+	if err := ioutil.WriteFile(templatePath, []byte(testTemplate), 0644); err != nil {
+		t.Fatal(err)
+	}
+	nodeConfig.Storage.SeedLun.SeedTemplate.Location = templatePath
+	nodeConfig.Network.IscsiInitiator = []config.IscsiInitiator{
+		{NetworkInterface: config.NetworkInterface{Name: "iscsi0", Subnet: "192.168.10.0/24"}, InitiatorName: "iqn.2005-02.com.open-iscsi:" + hostName},
+	}
+	return nodeConfig
+}
+
+// testImageFile writes raw image and its SHA256SUMS file into test directory
+func testImageFile(t *testing.T, content []byte) (imagePath string, checksumPath string) {
+	dir := t.TempDir()
+	imagePath = filepath.Join(dir, testImageName+".raw")
+	checksumPath = filepath.Join(dir, "SHA256SUMS")
+	digest := sha256.Sum256(content)
+	if err := ioutil.WriteFile(imagePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(checksumPath, []byte(hex.EncodeToString(digest[:])+"  "+testImageName+".raw\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func testImageContent(seed byte, size int) []byte {
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = seed + byte(i%253)
+	}
+	return b
+}
+
+// testCalls counts calls of the method in cluster call history
+func testCalls(cluster *fake.Cluster, method string) (count int) {
+	for _, call := range cluster.Calls() {
+		if strings.HasPrefix(call, method+"(") {
+			count++
+		}
+	}
+	return
+}
+
+// testVerifyClean fails the test on storage discrepancies
+func testVerifyClean(t *testing.T, nodeConfig *config.NodeConfig) {
+	t.Helper()
+	discrepancies, err := VerifyBootStorage(nodeConfig)
+	if err != nil {
+		t.Fatalf("VerifyBootStorage() failure: %s", err)
+	}
+	if len(discrepancies) > 0 {
+		t.Fatalf("unexpected storage discrepancies: %v", discrepancies)
+	}
+}
+
+// testHasDiscrepancy checks if VerifyBootStorage reports discrepancy of object property
+func testHasDiscrepancy(t *testing.T, nodeConfig *config.NodeConfig, object string, property string) bool {
+	t.Helper()
+	discrepancies, err := VerifyBootStorage(nodeConfig)
+	if err != nil {
+		t.Fatalf("VerifyBootStorage() failure: %s", err)
+	}
+	for _, d := range discrepancies {
+		if d.Object == object && d.Property == property {
+			return true
+		}
+	}
+	return false
+}
@@ -40,7 +40,7 @@ func CreateHarvesterStorage(nodeConfig *config.NodeConfig) (err error) {
 		return
 	}
 	if !igroupExists {
-		if c.IgroupCreate(nodeConfig.Storage.IgroupName, "linux"); err != nil {
+		if c.IgroupCreate(nodeConfig.Storage.IgroupName, "iscsi", "linux"); err != nil {
 			err = fmt.Errorf(errorFormat, err)
 			return
 		}
@@ -71,6 +71,10 @@ func CreateHarvesterStorage(nodeConfig *config.NodeConfig) (err error) {
 		return
 	}
 	if !lunExists {
+		if err = verifyRepoImageSigned(c, nodeConfig, imageLunPath); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
 		if err = c.LunCopy(imageLunPath, bootstrapLunPath); err != nil {
 			err = fmt.Errorf(errorFormat, err)
 			return
@@ -308,7 +312,7 @@ func DiscoverHarvesterStorage(nodeConfig *config.NodeConfig) (storageExists bool
 		return
 	}
 	if lunInfo.Comment != "" {
-		nodeConfig.Storage.BootLun.OsImage.Name = lunInfo.Comment
+		nodeConfig.Storage.BootLun.OsImage.Name = ParseRepoImageComment(lunInfo.Comment).Name
 	}
 	nodeConfig.Storage.BootLun.Size = lunInfo.Size
 	var iscsiNodeName string
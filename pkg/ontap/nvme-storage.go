@@ -7,13 +7,9 @@ import (
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
 )
 
-const (
-	apiMethod = "rest"
-)
-
 // CreateNvmeStorage creates node NVME data storage in cDOT (REST API only)
 func CreateNvmeStorage(nodeConfig *config.NodeConfig) (err error) {
-        if nodeConfig.Storage.CdotCredentials.ApiMethod == apiMethod && len(nodeConfig.Network.NvmeHost) > 0 && nodeConfig.Storage.DataNvme.Size > 0 {
+        if client.RestFeature(nodeConfig, client.FeatureNvme) && len(nodeConfig.Network.NvmeHost) > 0 && nodeConfig.Storage.DataNvme.Size > 0 {
 	        var c client.OntapClient
 	        errorFormat := "CreateNvmeStorage(): %s"
 	        if c, err = client.NewOntapClient(nodeConfig); err != nil {
@@ -89,7 +85,7 @@ func CreateNvmeStorage(nodeConfig *config.NodeConfig) (err error) {
 
 // CreateNvmeStoragePreflight is sanity check before actual storage provisioning (REST API only)
 func CreateNvmeStoragePreflight(nodeConfig *config.NodeConfig) (err error) {
-        if nodeConfig.Storage.CdotCredentials.ApiMethod == apiMethod && len(nodeConfig.Network.NvmeHost) > 0 && nodeConfig.Storage.DataNvme.Size > 0 {
+        if client.RestFeature(nodeConfig, client.FeatureNvme) && len(nodeConfig.Network.NvmeHost) > 0 && nodeConfig.Storage.DataNvme.Size > 0 {
 	        var c client.OntapClient
 	        errorFormat := "CreateNvmeStoragePreflight(): %s"
 	        if c, err = client.NewOntapClient(nodeConfig); err != nil {
@@ -106,7 +102,7 @@ func CreateNvmeStoragePreflight(nodeConfig *config.NodeConfig) (err error) {
 
 // DeleteNvmeStorage deletes node NVME storage (REST API only)
 func DeleteNvmeStorage(nodeConfig *config.NodeConfig) (err error) {
-        if nodeConfig.Storage.CdotCredentials.ApiMethod == apiMethod && len(nodeConfig.Network.NvmeHost) > 0 {
+        if client.RestFeature(nodeConfig, client.FeatureNvme) && len(nodeConfig.Network.NvmeHost) > 0 {
 	        var c client.OntapClient
 	        errorFormat := "DeleteNvmeStorage(): %s"
 	        if c, err = client.NewOntapClient(nodeConfig); err != nil {
@@ -151,7 +147,7 @@ func DeleteNvmeStorage(nodeConfig *config.NodeConfig) (err error) {
 
 // DiscoverNvmeStorage discovers NVME storage in cDOT (REST API only)
 func DiscoverNvmeStorage(nodeConfig *config.NodeConfig) (err error) {
-        if nodeConfig.Storage.CdotCredentials.ApiMethod == apiMethod && len(nodeConfig.Network.NvmeHost) > 0 && nodeConfig.Storage.DataNvme.Size > 0 {
+        if client.RestFeature(nodeConfig, client.FeatureNvme) && len(nodeConfig.Network.NvmeHost) > 0 && nodeConfig.Storage.DataNvme.Size > 0 {
 	        var c client.OntapClient
 	        errorFormat := "DiscoverNvmeStorage(): %s"
 	        if c, err = client.NewOntapClient(nodeConfig); err != nil {
@@ -0,0 +1,209 @@
+package ontap
+
+import (
+	"fmt"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
+)
+
+// replicationPaths makes SnapMirror source and destination paths for node volume
+func replicationPaths(nodeConfig *config.NodeConfig) (sourcePath string, destinationPath string) {
+	sourcePath = nodeConfig.Storage.SvmName + ":" + nodeConfig.Storage.VolumeName
+	destinationPath = nodeConfig.Storage.Replication.SvmName + ":" + nodeConfig.Storage.VolumeName
+	return
+}
+
+// CreateReplication creates DR volume and SnapMirror relationship for node volume
+func CreateReplication(nodeConfig *config.NodeConfig) (err error) {
+	if nodeConfig.Storage.Replication.SvmName == "" || nodeConfig.Storage.Replication.FailedOver {
+		return
+	}
+	var c client.OntapClient
+	errorFormat := "CreateReplication(): %s"
+	if c, err = client.NewOntapReplicaClient(nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	sourcePath, destinationPath := replicationPaths(nodeConfig)
+	var volumeExists bool
+	if volumeExists, err = c.VolumeExists(nodeConfig.Storage.VolumeName); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	if !volumeExists {
+		var aggregateName string
+		if aggregateName, err = c.GetAggregateMax(nodeConfig); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+		if err = c.VolumeCreateDP(nodeConfig.Storage.VolumeName, aggregateName, (nodeConfig.Storage.BootLun.Size+nodeConfig.Storage.DataLun.Size+nodeConfig.Storage.DataNvme.Size)*2); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+	}
+	var snapmirrorInfo *client.SnapmirrorInfo
+	if snapmirrorInfo, err = c.SnapmirrorGet(destinationPath); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	if snapmirrorInfo == nil {
+		if err = c.SnapmirrorCreate(sourcePath, destinationPath, nodeConfig.Storage.Replication.Policy, nodeConfig.Storage.Replication.Schedule); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+		if snapmirrorInfo, err = c.SnapmirrorGet(destinationPath); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+	}
+	if snapmirrorInfo != nil {
+		nodeConfig.Storage.Replication.State = snapmirrorInfo.State
+	}
+	return
+}
+
+// UpdateReplication reconciles SnapMirror relationship with node config and starts transfer
+func UpdateReplication(nodeConfig *config.NodeConfig) (err error) {
+	if nodeConfig.Storage.Replication.SvmName == "" || nodeConfig.Storage.Replication.FailedOver {
+		return
+	}
+	var c client.OntapClient
+	errorFormat := "UpdateReplication(): %s"
+	if c, err = client.NewOntapReplicaClient(nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	_, destinationPath := replicationPaths(nodeConfig)
+	var snapmirrorInfo *client.SnapmirrorInfo
+	if snapmirrorInfo, err = c.SnapmirrorGet(destinationPath); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	if snapmirrorInfo == nil {
+		return CreateReplication(nodeConfig)
+	}
+	if snapmirrorInfo.Policy != nodeConfig.Storage.Replication.Policy || snapmirrorInfo.Schedule != nodeConfig.Storage.Replication.Schedule {
+		if err = c.SnapmirrorModify(destinationPath, nodeConfig.Storage.Replication.Policy, nodeConfig.Storage.Replication.Schedule); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+	}
+	if snapmirrorInfo.State == "snapmirrored" {
+		if err = c.SnapmirrorUpdate(destinationPath); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+	}
+	nodeConfig.Storage.Replication.State = snapmirrorInfo.State
+	return
+}
+
+// DeleteReplication breaks and deletes SnapMirror relationship and destroys DR volume,
+// DR volume of failed over node is the node volume, it is left for DeleteBootStorage
+func DeleteReplication(nodeConfig *config.NodeConfig) (err error) {
+	if nodeConfig.Storage.Replication.SvmName == "" {
+		return
+	}
+	var c client.OntapClient
+	errorFormat := "DeleteReplication(): %s"
+	if c, err = client.NewOntapReplicaClient(nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	_, destinationPath := replicationPaths(nodeConfig)
+	var snapmirrorInfo *client.SnapmirrorInfo
+	if snapmirrorInfo, err = c.SnapmirrorGet(destinationPath); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	if snapmirrorInfo != nil {
+		if snapmirrorInfo.State != "broken_off" {
+			if err = c.SnapmirrorBreak(destinationPath); err != nil {
+				err = fmt.Errorf(errorFormat, err)
+				return
+			}
+		}
+		if err = c.SnapmirrorDelete(destinationPath); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+	}
+	if nodeConfig.Storage.Replication.FailedOver {
+		nodeConfig.Storage.Replication.State = ""
+		return
+	}
+	var volumeExists bool
+	if volumeExists, err = c.VolumeExists(nodeConfig.Storage.VolumeName); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	if volumeExists {
+		if err = c.VolumeDestroy(nodeConfig.Storage.VolumeName); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+	}
+	nodeConfig.Storage.Replication.State = ""
+	return
+}
+
+// DeletePrimaryStorage deletes node LUN's, iGroup and volume left on original primary cluster of failed over node,
+// the primary cluster may be unavailable after failover, the error is expected to be reported as leftover
+func DeletePrimaryStorage(nodeConfig *config.NodeConfig) (err error) {
+	if nodeConfig.Storage.Replication.SvmName == "" || !nodeConfig.Storage.Replication.FailedOver {
+		return
+	}
+	primaryConfig := *nodeConfig
+	primaryConfig.Storage.Replication.FailedOver = false
+	if err = DeleteBootStorage(&primaryConfig); err != nil {
+		sourcePath, _ := replicationPaths(nodeConfig)
+		err = fmt.Errorf("DeletePrimaryStorage(): volume %s is left on primary cluster %s: %s", sourcePath, nodeConfig.Storage.CdotCredentials.Host, err)
+	}
+	return
+}
+
+// FailoverReplication breaks SnapMirror relationship and rebuilds node iGroup and LUN mappings in DR cluster,
+// node config is switched to DR cluster with discovered iSCSI/FC targets
+func FailoverReplication(nodeConfig *config.NodeConfig) (err error) {
+	if nodeConfig.Storage.Replication.SvmName == "" {
+		err = fmt.Errorf("FailoverReplication(): replication is not configured for node \"%s\"", nodeConfig.Compute.HostName)
+		return
+	}
+	var c client.OntapClient
+	errorFormat := "FailoverReplication(): %s"
+	if c, err = client.NewOntapReplicaClient(nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	if !nodeConfig.Storage.Replication.FailedOver {
+		_, destinationPath := replicationPaths(nodeConfig)
+		if err = c.SnapmirrorBreak(destinationPath); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+		nodeConfig.Storage.Replication.FailedOver = true
+		nodeConfig.Storage.Replication.State = "broken_off"
+	}
+	if err = createIgroup(c, nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	if err = setIscsiInitiatorsAuth(c, nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	if err = LunRestoreMapping(nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	if err = discoverIscsiTargets(c, nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	if err = discoverFcTargets(c, nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	return
+}
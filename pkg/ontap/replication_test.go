@@ -0,0 +1,62 @@
+package ontap
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const (
+	testDrCdotHost = "cdot-dr.test"
+	testDrSvm      = "svm1_dr"
+)
+
+func TestDeleteReplicationFailedOver(t *testing.T) {
+	f := useFakeFactory(t)
+	primary := f.Cluster(testCdotHost, testSvm)
+	dr := f.Cluster(testDrCdotHost, testDrSvm)
+	dr.IscsiLIFs = []string{"192.168.10.21"}
+	nodeConfig := testNodeConfig(t, "node1")
+	nodeConfig.Storage.Replication.CdotCredentials.Host = testDrCdotHost
+	nodeConfig.Storage.Replication.SvmName = testDrSvm
+	testCreateNodeStorage(t, nodeConfig, testImageContent(1, 16*1024))
+	if err := CreateReplication(nodeConfig); err != nil {
+		t.Fatalf("CreateReplication() failure: %s", err)
+	}
+	if err := FailoverReplication(nodeConfig); err != nil {
+		t.Fatalf("FailoverReplication() failure: %s", err)
+	}
+	testVerifyClean(t, nodeConfig)
+
+	// primary cluster is not available, the node storage is deleted in DR cluster and primary volume is reported
+	primary.InjectFault("NewClient", errors.New("connection refused"), 0, "")
+	if err := DeleteReplication(nodeConfig); err != nil {
+		t.Fatalf("DeleteReplication() failure: %s", err)
+	}
+	if err := DeleteBootStorage(nodeConfig); err != nil {
+		t.Fatalf("DeleteBootStorage() failure: %s", err)
+	}
+	if volumes := strings.Join(dr.Volumes(), ","); volumes != "" {
+		t.Fatalf("unexpected volumes in DR cluster: %s", volumes)
+	}
+	if testCalls(dr, "SnapmirrorDelete") != 1 || testCalls(dr, "SnapmirrorBreak") != 1 {
+		t.Fatalf("unexpected SnapMirror calls in DR cluster: %v", dr.Calls())
+	}
+	err := DeletePrimaryStorage(nodeConfig)
+	if err == nil || !strings.Contains(err.Error(), "volume svm1:node1_iboot is left on primary cluster cdot.test: ") || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("expected primary volume leftover, got %v", err)
+	}
+
+	// primary cluster is available again
+	primary.ClearFaults()
+	if err = DeletePrimaryStorage(nodeConfig); err != nil {
+		t.Fatalf("DeletePrimaryStorage() failure: %s", err)
+	}
+	if volumes := strings.Join(primary.Volumes(), ","); volumes != "image_repo" {
+		t.Fatalf("unexpected volumes in primary cluster: %s", volumes)
+	}
+	c, _ := f.NewClient(testNodeConfig(t, "node1"))
+	if igroupExists, err := c.IgroupExists("node1_iboot"); err != nil || igroupExists {
+		t.Fatalf("igroup is left on primary cluster: %v", err)
+	}
+}
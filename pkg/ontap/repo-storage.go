@@ -1,75 +1,272 @@
 package ontap
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/util/artifact"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/util/diskimage"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/util/signature"
 )
 
 const (
 	imageRepoVolSize    = 64
 	templateRepoVolSize = 1
+	// Image LUN comment is "<image name> sha256:<image digest>",
+	// boot LUN cloned from image inherits the comment
+	repoImageDigestPrefix = " sha256:"
+	// Image is uploaded into "<image name>.upload" LUN and renamed once verified
+	repoImageUploadSuffix = ".upload"
+	// Image signature is kept in image repository as "/_<image name>.sig" file
+	repoImageSignatureSuffix = ".sig"
+	// Template signature is kept in repository next to the template
+	repoTemplateSignatureSuffix = ".sig"
 )
 
-// CreateRepoImage creates cDOT storage and uploads image
-func CreateRepoImage(nodeConfig *config.NodeConfig, imageName string, imagePath string) (err error) {
-	var c client.OntapClient
-	if c, err = client.NewOntapClient(nodeConfig); err != nil {
-		err = fmt.Errorf("CreateRepoImage(): %s", err)
+// repoVolumeSettleTime is wait time after repository volume is created
+var repoVolumeSettleTime = 10 * time.Second
+
+// RepoImageInfo is image repository entry with SHA-256 digest of uploaded image
+// and identifier of trusted key the image signature is verified with
+type RepoImageInfo struct {
+	Name   string `yaml:"name" json:"name"`
+	Sha256 string `yaml:"sha256,omitempty" json:"sha256,omitempty"`
+	Signer string `yaml:"signer,omitempty" json:"signer,omitempty"`
+}
+
+// ParseRepoImageComment gets image name, image SHA-256 digest and signer from image or boot LUN comment
+func ParseRepoImageComment(lunComment string) (imageInfo RepoImageInfo) {
+	i := strings.Index(lunComment, repoImageDigestPrefix)
+	if i < 0 {
+		imageInfo.Name = lunComment
 		return
 	}
-	var volExists bool
-	if volExists, err = c.VolumeExists(nodeConfig.Storage.ImageRepoName); err != nil {
-		err = fmt.Errorf("CreateRepoImage(): %s", err)
+	imageInfo.Name = lunComment[:i]
+	for _, field := range strings.Fields(lunComment[i:]) {
+		if strings.HasPrefix(field, "sha256:") {
+			imageInfo.Sha256 = strings.TrimPrefix(field, "sha256:")
+		}
+		if strings.HasPrefix(field, "signer:") {
+			imageInfo.Signer = strings.TrimPrefix(field, "signer:")
+		}
+	}
+	return
+}
+
+// readArtifactSignature reads artifact detached signature if signatures are required or signature location is provided,
+// default signature location is artifact location with ".sig" suffix
+func readArtifactSignature(nodeConfig *config.NodeConfig, location string, signatureLocation string) (sig []byte, err error) {
+	if !nodeConfig.Storage.Signatures.Required && signatureLocation == "" {
 		return
 	}
-	if !volExists {
-		var aggregateName string
-		if aggregateName, err = c.GetAggregateMax(nodeConfig); err != nil {
-			err = fmt.Errorf("CreateRepoImage(): %s", err)
+	if signatureLocation == "" {
+		signatureLocation = location + ".sig"
+	}
+	if artifact.IsStoreLocation(signatureLocation) {
+		var b []byte
+		if b, err = artifact.ReadAll(signatureLocation, nodeConfig.Storage.ArtifactSources); err != nil {
 			return
 		}
-		if err = c.ExportPolicyCreate(nodeConfig.Storage.ImageRepoName); err != nil {
-			err = fmt.Errorf("CreateRepoImage(): %s", err)
+		sig, err = signature.DecodeSignature(b)
+		return
+	}
+	sig, err = signature.ReadSignature(signatureLocation)
+	return
+}
+
+// verifyArtifactSignature verifies artifact SHA-256 digest signature with trusted keys,
+// returns identifier of the key the signature is verified with
+func verifyArtifactSignature(nodeConfig *config.NodeConfig, artifactName string, digest []byte, sig []byte) (signer string, err error) {
+	if sig == nil {
+		if nodeConfig.Storage.Signatures.Required {
+			err = fmt.Errorf("artifact %s is not signed", artifactName)
+		}
+		return
+	}
+	if signer, err = signature.VerifyDigest(digest, sig, nodeConfig.Storage.Signatures.TrustedKeys); err != nil {
+		err = fmt.Errorf("artifact %s signature verification failure: %s", artifactName, err)
+	}
+	return
+}
+
+// verifyRepoImageSigned verifies repository image signature kept next to the image against image digest recorded at upload
+// and trusted keys, LUN data is not hashed again (qcow2 image is signed by source file digest), so unsigned or replaced
+// repository entries are rejected but data modified in place is not detected, signer in LUN comment is informational only
+// and is not trusted, the check is enforced only if signatures are required
+func verifyRepoImageSigned(c client.OntapClient, nodeConfig *config.NodeConfig, imageLunPath string) (err error) {
+	if !nodeConfig.Storage.Signatures.Required {
+		return
+	}
+	volumeName := strings.TrimPrefix(filepath.Dir(imageLunPath), "/vol/")
+	imageName := filepath.Base(imageLunPath)
+	var lunInfo *client.LunInfo
+	if lunInfo, err = c.LunGetInfo(imageLunPath); err != nil {
+		return
+	}
+	imageInfo := ParseRepoImageComment(lunInfo.Comment)
+	if !isSha256Digest(imageInfo.Sha256) {
+		err = fmt.Errorf("image %s has no SHA-256 digest", imageName)
+		return
+	}
+	var fileExists bool
+	if fileExists, err = c.FileExists(volumeName, "/_"+imageName+repoImageSignatureSuffix); err != nil {
+		return
+	}
+	if !fileExists {
+		err = fmt.Errorf("image %s is not signed", imageName)
+		return
+	}
+	var b, sig []byte
+	if b, err = c.FileDownload(volumeName, "/_"+imageName+repoImageSignatureSuffix); err != nil {
+		return
+	}
+	if sig, err = signature.DecodeSignature(b); err != nil {
+		err = fmt.Errorf("image %s signature: %s", imageName, err)
+		return
+	}
+	digest, _ := hex.DecodeString(imageInfo.Sha256)
+	if _, err = signature.VerifyDigest(digest, sig, nodeConfig.Storage.Signatures.TrustedKeys); err != nil {
+		err = fmt.Errorf("image %s signature verification failure: %s", imageName, err)
+	}
+	return
+}
+
+// isSha256Digest checks if value is hex encoded SHA-256 digest
+func isSha256Digest(value string) bool {
+	if len(value) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+// ResolveImageChecksum gets expected image SHA-256 digest from checksum value,
+// checksum is either "[sha256:]<digest>", or a path or URL to checksum file in sha256sum format
+func ResolveImageChecksum(imageChecksum string, imagePath string, sources config.ArtifactSources) (digest string, err error) {
+	checksum := strings.ToLower(strings.TrimPrefix(imageChecksum, "sha256:"))
+	if checksum == "" || isSha256Digest(checksum) {
+		digest = checksum
+		return
+	}
+	var reader io.Reader
+	if artifact.IsStoreLocation(imageChecksum) {
+		var artifactReader io.ReadCloser
+		if artifactReader, _, err = artifact.Open(imageChecksum, sources); err != nil {
+			err = fmt.Errorf("ResolveImageChecksum(): failure to open file %s: %s", imageChecksum, err)
 			return
 		}
-		if err = c.VolumeCreateNAS(nodeConfig.Storage.ImageRepoName, aggregateName, nodeConfig.Storage.ImageRepoName, imageRepoVolSize); err != nil {
-			err = fmt.Errorf("CreateRepoImage(): %s", err)
+		defer artifactReader.Close()
+		reader = artifactReader
+	} else if strings.HasPrefix(imageChecksum, "http://") || strings.HasPrefix(imageChecksum, "https://") {
+		var httpResponse *http.Response
+		if httpResponse, err = http.Get(imageChecksum); err != nil {
+			err = fmt.Errorf("ResolveImageChecksum(): failure to open file %s: %s", imageChecksum, err)
+			return
+		}
+		defer httpResponse.Body.Close()
+		if httpResponse.StatusCode != http.StatusOK {
+			err = fmt.Errorf("ResolveImageChecksum(): failure to open file %s: %s", imageChecksum, httpResponse.Status)
+			return
+		}
+		reader = httpResponse.Body
+	} else {
+		var file *os.File
+		if file, err = os.Open(strings.TrimPrefix(imageChecksum, "file://")); err != nil {
+			err = fmt.Errorf("ResolveImageChecksum(): failure to open file %s: %s", imageChecksum, err)
+			return
+		}
+		defer file.Close()
+		reader = file
+	}
+	var digests []string
+	imageFile := filepath.Base(strings.TrimPrefix(imagePath, "file://"))
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || !isSha256Digest(fields[0]) {
+			continue
+		}
+		if len(fields) > 1 && filepath.Base(strings.TrimPrefix(fields[1], "*")) == imageFile {
+			digest = strings.ToLower(fields[0])
 			return
 		}
-		time.Sleep(10 * time.Second)
+		digests = append(digests, strings.ToLower(fields[0]))
 	}
-	var lunExists bool
-	if lunExists, err = c.LunExists("/vol/" + nodeConfig.Storage.ImageRepoName + "/" + imageName); err != nil {
-		err = fmt.Errorf("CreateRepoImage(): %s", err)
+	if err = scanner.Err(); err != nil {
+		err = fmt.Errorf("ResolveImageChecksum(): failure to read file %s: %s", imageChecksum, err)
 		return
 	}
-	if lunExists {
-		if err = c.LunDestroy("/vol/" + nodeConfig.Storage.ImageRepoName + "/" + imageName); err != nil {
-			err = fmt.Errorf("CreateRepoImage(): %s", err)
-			return
-		}
+	if len(digests) == 1 {
+		digest = digests[0]
+	} else {
+		err = fmt.Errorf("ResolveImageChecksum(): no SHA-256 digest for image %s found in %s", imageFile, imageChecksum)
 	}
-	var fileReader io.Reader
-	var fileExists bool
-	if fileExists, err = c.FileExists(nodeConfig.Storage.ImageRepoName, "/_"+imageName); err != nil {
+	return
+}
+
+// createRepoVolume creates NAS volume for image or template repository if it does not exist
+func createRepoVolume(c client.OntapClient, nodeConfig *config.NodeConfig, volumeName string, volumeSize int) (err error) {
+	var volExists bool
+	if volExists, err = c.VolumeExists(volumeName); err != nil || volExists {
+		return
+	}
+	var aggregateName string
+	if aggregateName, err = c.GetAggregateMax(nodeConfig); err != nil {
+		return
+	}
+	if err = c.ExportPolicyCreate(volumeName); err != nil {
+		return
+	}
+	if err = c.VolumeCreateNAS(volumeName, aggregateName, volumeName, volumeSize); err != nil {
+		return
+	}
+	time.Sleep(repoVolumeSettleTime)
+	return
+}
+
+// CreateRepoImage creates cDOT storage and uploads image,
+// image SHA-256 digest is calculated while streaming, verified against expected checksum and signature (if any)
+// and kept in image LUN comment.
+// Signature is verified before upload if image digest is known upfront (checksum is provided or image is local file),
+// otherwise after upload. Image is uploaded into temporary LUN which replaces existing image only once verified
+func CreateRepoImage(nodeConfig *config.NodeConfig, imageName string, imagePath string, imageChecksum string, imageSignature string, progress client.UploadProgress) (err error) {
+	var c client.OntapClient
+	var expectedDigest string
+	if expectedDigest, err = ResolveImageChecksum(imageChecksum, imagePath, nodeConfig.Storage.ArtifactSources); err != nil {
 		err = fmt.Errorf("CreateRepoImage(): %s", err)
 		return
 	}
-	if fileExists {
-		if err = c.FileDelete(nodeConfig.Storage.ImageRepoName, "/_"+imageName); err != nil {
-			err = fmt.Errorf("CreateRepoImage(): %s", err)
-			return
-		}
+	var sig []byte
+	if sig, err = readArtifactSignature(nodeConfig, imagePath, imageSignature); err != nil {
+		err = fmt.Errorf("CreateRepoImage(): %s", err)
+		return
 	}
+	var fileReader io.Reader
 	var imageSize int64
-	if strings.HasPrefix(imagePath, "http://") || strings.HasPrefix(imagePath, "https://") {
+	var file *os.File
+	if artifact.IsStoreLocation(imagePath) {
+		var artifactReader io.ReadCloser
+		if artifactReader, imageSize, err = artifact.Open(imagePath, nodeConfig.Storage.ArtifactSources); err != nil {
+			err = fmt.Errorf("CreateRepoImage(): failure to open file %s: %s", imagePath, err)
+			return
+		}
+		fileReader = artifactReader
+		defer artifactReader.Close()
+	} else if strings.HasPrefix(imagePath, "http://") || strings.HasPrefix(imagePath, "https://") {
 		var httpResponse *http.Response
 		if httpResponse, err = http.Get(imagePath); err == nil {
 			fileReader = httpResponse.Body
@@ -80,7 +277,6 @@ func CreateRepoImage(nodeConfig *config.NodeConfig, imageName string, imagePath
 			return
 		}
 	} else {
-		var file *os.File
 		var fileInfo os.FileInfo
 		if strings.HasPrefix(imagePath, "file://") {
 			file, err = os.Open(imagePath[7:])
@@ -91,21 +287,277 @@ func CreateRepoImage(nodeConfig *config.NodeConfig, imageName string, imagePath
 			err = fmt.Errorf("CreateRepoImage(): failure to open file %s: %s", imagePath, err)
 			return
 		}
-		fileReader = file
+		defer file.Close()
 		if fileInfo, err = file.Stat(); err != nil {
 			err = fmt.Errorf("CreateRepoImage(): failure in Stat() for file %s: %s", imagePath, err)
 			return
 		}
 		imageSize = int64(fileInfo.Size())
-		defer file.Close()
+		// local image is hashed upfront, checksum mismatch fails before anything is uploaded
+		var fileDigest string
+		if fileDigest, err = fileSha256(file); err != nil {
+			err = fmt.Errorf("CreateRepoImage(): failure to read file %s: %s", imagePath, err)
+			return
+		}
+		if expectedDigest != "" && fileDigest != expectedDigest {
+			err = fmt.Errorf("CreateRepoImage(): image %s checksum mismatch: expected sha256:%s, image sha256:%s", imagePath, expectedDigest, fileDigest)
+			return
+		}
+		expectedDigest = fileDigest
+		fileReader = file
+	}
+	var signer string
+	if expectedDigest != "" {
+		// streamed image is verified against the same digest while uploading
+		digest, _ := hex.DecodeString(expectedDigest)
+		if signer, err = verifyArtifactSignature(nodeConfig, imagePath, digest, sig); err != nil {
+			err = fmt.Errorf("CreateRepoImage(): %s", err)
+			return
+		}
+	}
+	if c, err = client.NewOntapClient(nodeConfig); err != nil {
+		err = fmt.Errorf("CreateRepoImage(): %s", err)
+		return
+	}
+	if err = createRepoVolume(c, nodeConfig, nodeConfig.Storage.ImageRepoName, imageRepoVolSize); err != nil {
+		err = fmt.Errorf("CreateRepoImage(): %s", err)
+		return
+	}
+	uploadName := imageName + repoImageUploadSuffix
+	uploadLunPath := "/vol/" + nodeConfig.Storage.ImageRepoName + "/" + uploadName
+	// leftover of interrupted upload
+	if err = deleteRepoLun(c, nodeConfig.Storage.ImageRepoName, uploadName); err != nil {
+		err = fmt.Errorf("CreateRepoImage(): %s", err)
+		return
+	}
+	checksum := newChecksumReader(fileReader, imageSize, expectedDigest)
+	var image *rawImage
+	if image, err = openRawImage(checksum, file, imageSize); err != nil {
+		err = fmt.Errorf("CreateRepoImage(): image %s: %s", imagePath, err)
+		return
+	}
+	defer image.Close()
+	// upload is aborted by checksum reader as soon as the stream is read to the end and the digest does not match
+	if err = c.LunCreateAndUpload(nodeConfig.Storage.ImageRepoName, "/_"+uploadName, image.size, image.reader, uploadLunPath, imageName, "linux", progress); err != nil {
+		err = fmt.Errorf("CreateRepoImage(): image %s: %s", imagePath, err)
+		err = cleanupRepoImage(c, nodeConfig, uploadName, err)
+		return
+	}
+	if err = image.drain(); err != nil {
+		err = fmt.Errorf("CreateRepoImage(): failure to read image %s: %s", imagePath, err)
+		err = cleanupRepoImage(c, nodeConfig, uploadName, err)
+		return
+	}
+	if checksum.digest == nil {
+		// local qcow2 image is read by converter with random access rather than streamed, the digest is calculated upfront
+		checksum.digest, _ = hex.DecodeString(expectedDigest)
+	}
+	if expectedDigest == "" {
+		if signer, err = verifyArtifactSignature(nodeConfig, imagePath, checksum.digest, sig); err != nil {
+			err = fmt.Errorf("CreateRepoImage(): %s", err)
+			err = cleanupRepoImage(c, nodeConfig, uploadName, err)
+			return
+		}
+	}
+	lunComment := imageName + repoImageDigestPrefix + hex.EncodeToString(checksum.digest)
+	if signer != "" {
+		lunComment += " signer:" + signer
 	}
-	if err = c.LunCreateAndUpload(nodeConfig.Storage.ImageRepoName, "/_"+imageName, imageSize, fileReader, "/vol/"+nodeConfig.Storage.ImageRepoName+"/"+imageName, imageName, "linux"); err != nil {
+	if err = c.LunSetComment(uploadLunPath, lunComment); err != nil {
 		err = fmt.Errorf("CreateRepoImage(): %s", err)
+		err = cleanupRepoImage(c, nodeConfig, uploadName, err)
 		return
-        }
+	}
+	if err = swapRepoImage(c, nodeConfig, imageName, uploadName, sig); err != nil {
+		err = fmt.Errorf("CreateRepoImage(): %s", err)
+		err = cleanupRepoImage(c, nodeConfig, uploadName, err)
+	}
 	return
 }
 
+// swapRepoImage replaces image LUN with uploaded LUN, image signature (if any) is kept next to the image
+// to be verified again when the image is used
+func swapRepoImage(c client.OntapClient, nodeConfig *config.NodeConfig, imageName string, uploadName string, sig []byte) (err error) {
+	if err = deleteRepoLun(c, nodeConfig.Storage.ImageRepoName, imageName); err != nil {
+		return
+	}
+	if err = deleteRepoFile(c, nodeConfig.Storage.ImageRepoName, "/_"+imageName+repoImageSignatureSuffix); err != nil {
+		return
+	}
+	if err = c.LunRename("/vol/"+nodeConfig.Storage.ImageRepoName+"/"+uploadName, "/vol/"+nodeConfig.Storage.ImageRepoName+"/"+imageName); err != nil {
+		return
+	}
+	// upload staging file (if any) is not needed for renamed LUN
+	if err = deleteRepoFile(c, nodeConfig.Storage.ImageRepoName, "/_"+uploadName); err != nil {
+		return
+	}
+	if sig != nil {
+		err = c.FileUploadAPI(nodeConfig.Storage.ImageRepoName, "/_"+imageName+repoImageSignatureSuffix, strings.NewReader(base64.StdEncoding.EncodeToString(sig)))
+	}
+	return
+}
+
+// deleteRepoLun deletes repository LUN and its upload staging file
+func deleteRepoLun(c client.OntapClient, volumeName string, lunName string) (err error) {
+	var lunExists bool
+	if lunExists, err = c.LunExists("/vol/" + volumeName + "/" + lunName); err != nil {
+		return
+	}
+	if lunExists {
+		if err = c.LunDestroy("/vol/" + volumeName + "/" + lunName); err != nil {
+			return
+		}
+	}
+	err = deleteRepoFile(c, volumeName, "/_"+lunName)
+	return
+}
+
+// deleteRepoFile deletes file in repository volume if it exists
+func deleteRepoFile(c client.OntapClient, volumeName string, filePath string) (err error) {
+	var fileExists bool
+	if fileExists, err = c.FileExists(volumeName, filePath); err != nil || !fileExists {
+		return
+	}
+	err = c.FileDelete(volumeName, filePath)
+	return
+}
+
+// cleanupRepoImage removes partially uploaded image, cleanup failure is appended to the original error
+func cleanupRepoImage(c client.OntapClient, nodeConfig *config.NodeConfig, uploadName string, err error) error {
+	if cleanupErr := deleteRepoLun(c, nodeConfig.Storage.ImageRepoName, uploadName); cleanupErr != nil {
+		return fmt.Errorf("%s, cleanup failure: %s", err, cleanupErr)
+	}
+	return err
+}
+
+// fileSha256 calculates SHA-256 digest of the file and rewinds the file
+func fileSha256(file *os.File) (digest string, err error) {
+	hasher := sha256.New()
+	if _, err = io.Copy(hasher, file); err != nil {
+		return
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	digest = hex.EncodeToString(hasher.Sum(nil))
+	return
+}
+
+// checksumReader calculates SHA-256 digest of the stream, the digest is verified against expected digest (if any)
+// once size bytes are read (if size is known) or at the end of the stream, mismatch is returned as read error
+// so that the upload reading the stream is aborted
+type checksumReader struct {
+	reader   io.Reader
+	hash     hash.Hash
+	size     int64
+	read     int64
+	expected string
+	digest   []byte
+}
+
+func newChecksumReader(reader io.Reader, size int64, expectedDigest string) *checksumReader {
+	return &checksumReader{
+		reader:   reader,
+		hash:     sha256.New(),
+		size:     size,
+		expected: expectedDigest,
+	}
+}
+
+// Read reads the stream and verifies the digest at the end of the stream
+func (r *checksumReader) Read(p []byte) (n int, err error) {
+	if r.digest != nil {
+		return 0, io.EOF
+	}
+	n, err = r.reader.Read(p)
+	r.hash.Write(p[:n])
+	r.read += int64(n)
+	if err == io.EOF || (r.size > 0 && r.read >= r.size) {
+		r.digest = r.hash.Sum(nil)
+		if digest := hex.EncodeToString(r.digest); r.expected != "" && digest != r.expected {
+			err = fmt.Errorf("checksum mismatch: expected sha256:%s, image sha256:%s", r.expected, digest)
+		}
+	}
+	return
+}
+
+// rawImage is raw disk image stream converted on the fly from the source image format
+type rawImage struct {
+	format  string
+	reader  io.Reader
+	size    int64
+	source  io.Reader
+	tmpFile *os.File
+}
+
+// openRawImage detects source image format by magic bytes and opens raw image stream,
+// the source stream is the original artifact so that checksum and signature verify the original artifact
+func openRawImage(sourceReader io.Reader, sourceFile *os.File, sourceSize int64) (image *rawImage, err error) {
+	bufReader := bufio.NewReaderSize(sourceReader, diskimage.HeaderSize)
+	header, _ := bufReader.Peek(diskimage.HeaderSize)
+	image = &rawImage{
+		format: diskimage.DetectFormat(header),
+		source: bufReader,
+	}
+	switch image.format {
+	case diskimage.FormatQcow2:
+		// qcow2 clusters are not stored in guest order, random access to the source is required,
+		// local image file is hashed upfront
+		var readerAt io.ReaderAt
+		if sourceFile != nil {
+			readerAt = sourceFile
+		} else {
+			if image.tmpFile, err = ioutil.TempFile("", "flexbot-image-"); err != nil {
+				err = fmt.Errorf("openRawImage(): failure to create temporary file: %s", err)
+				return
+			}
+			if _, err = io.Copy(image.tmpFile, image.source); err != nil {
+				image.Close()
+				err = fmt.Errorf("openRawImage(): failure to download image: %s", err)
+				return
+			}
+			readerAt = image.tmpFile
+		}
+		image.source = nil
+		var qcow2Reader *diskimage.Qcow2Reader
+		if qcow2Reader, err = diskimage.NewQcow2Reader(readerAt); err != nil {
+			image.Close()
+			return
+		}
+		image.reader = qcow2Reader
+		image.size = qcow2Reader.VirtualSize()
+	case diskimage.FormatVmdk:
+		var vmdkReader *diskimage.VmdkStreamReader
+		if vmdkReader, err = diskimage.NewVmdkStreamReader(image.source); err != nil {
+			return
+		}
+		image.reader = vmdkReader
+		image.size = vmdkReader.VirtualSize()
+	default:
+		image.reader = image.source
+		image.size = sourceSize
+		image.source = nil
+	}
+	return
+}
+
+// drain reads the rest of the source image not consumed by converter (e.g. VMDK footer) to complete the checksum
+func (image *rawImage) drain() (err error) {
+	if image.source != nil {
+		_, err = io.Copy(ioutil.Discard, image.source)
+	}
+	return
+}
+
+// Close removes temporary file if any
+func (image *rawImage) Close() {
+	if image.tmpFile != nil {
+		image.tmpFile.Close()
+		os.Remove(image.tmpFile.Name())
+		image.tmpFile = nil
+	}
+}
+
 // DeleteRepoImage deletes all related to the image cDOT storage elements
 func DeleteRepoImage(nodeConfig *config.NodeConfig, imageName string) (err error) {
 	var c client.OntapClient
@@ -133,14 +585,10 @@ func DeleteRepoImage(nodeConfig *config.NodeConfig, imageName string) (err error
 			return
 		}
 	}
-	var fileExists bool
-	if fileExists, err = c.FileExists(nodeConfig.Storage.ImageRepoName, "/_"+imageName); err != nil {
-		err = fmt.Errorf("DeleteRepoImage(): %s", err)
-		return
-	}
-	if fileExists {
-		if err = c.FileDelete(nodeConfig.Storage.ImageRepoName, "/_"+imageName); err != nil {
+	for _, filePath := range []string{"/_" + imageName, "/_" + imageName + repoImageSignatureSuffix} {
+		if err = deleteRepoFile(c, nodeConfig.Storage.ImageRepoName, filePath); err != nil {
 			err = fmt.Errorf("DeleteRepoImage(): %s", err)
+			return
 		}
 	}
 	return
@@ -161,53 +609,82 @@ func GetRepoImages(nodeConfig *config.NodeConfig) (imagesList []string, err erro
 	if !volExists {
 		return
 	}
-	if imagesList, err = c.LunGetList(nodeConfig.Storage.ImageRepoName); err != nil {
+	var lunList []string
+	if lunList, err = c.LunGetList(nodeConfig.Storage.ImageRepoName); err != nil {
 		err = fmt.Errorf("GetRepoImages(): %s", err)
+		return
+	}
+	for _, lunName := range lunList {
+		if !strings.HasSuffix(lunName, repoImageUploadSuffix) {
+			imagesList = append(imagesList, lunName)
+		}
 	}
 	return
 }
 
-// CreateRepoTemplate creates cDOT storage and uploads cloud-init template
-func CreateRepoTemplate(nodeConfig *config.NodeConfig, templateName string, templatePath string) (err error) {
+// GetRepoImagesInfo gets list of images with SHA-256 digests
+func GetRepoImagesInfo(nodeConfig *config.NodeConfig) (imagesInfo []RepoImageInfo, err error) {
 	var c client.OntapClient
+	imagesInfo = []RepoImageInfo{}
 	if c, err = client.NewOntapClient(nodeConfig); err != nil {
-		err = fmt.Errorf("CreateRepoTemplate(): %s", err)
+		err = fmt.Errorf("GetRepoImagesInfo(): %s", err)
 		return
 	}
 	var volExists bool
-	if volExists, err = c.VolumeExists(nodeConfig.Storage.TemplateRepoName); err != nil {
-		err = fmt.Errorf("CreateRepoTemplate(): %s", err)
+	if volExists, err = c.VolumeExists(nodeConfig.Storage.ImageRepoName); err != nil {
+		err = fmt.Errorf("GetRepoImagesInfo(): %s", err)
 		return
 	}
 	if !volExists {
-		var aggregateName string
-		if aggregateName, err = c.GetAggregateMax(nodeConfig); err != nil {
-			err = fmt.Errorf("CreateRepoTemplate(): %s", err)
-			return
-		}
-		if err = c.ExportPolicyCreate(nodeConfig.Storage.TemplateRepoName); err != nil {
-			err = fmt.Errorf("CreateRepoTemplate(): %s", err)
-			return
+		return
+	}
+	var imagesList []string
+	if imagesList, err = c.LunGetList(nodeConfig.Storage.ImageRepoName); err != nil {
+		err = fmt.Errorf("GetRepoImagesInfo(): %s", err)
+		return
+	}
+	for _, imageName := range imagesList {
+		if strings.HasSuffix(imageName, repoImageUploadSuffix) {
+			continue
 		}
-		if err = c.VolumeCreateNAS(nodeConfig.Storage.TemplateRepoName, aggregateName, nodeConfig.Storage.TemplateRepoName, templateRepoVolSize); err != nil {
-			err = fmt.Errorf("CreateRepoTemplate(): %s", err)
+		var lunInfo *client.LunInfo
+		if lunInfo, err = c.LunGetInfo("/vol/" + nodeConfig.Storage.ImageRepoName + "/" + imageName); err != nil {
+			err = fmt.Errorf("GetRepoImagesInfo(): %s", err)
 			return
 		}
-		time.Sleep(10 * time.Second)
+		imageInfo := ParseRepoImageComment(lunInfo.Comment)
+		imageInfo.Name = imageName
+		imagesInfo = append(imagesInfo, imageInfo)
 	}
-	var fileReader io.Reader
-	var fileExists bool
-	if fileExists, err = c.FileExists(nodeConfig.Storage.TemplateRepoName, "/cloud-init/"+templateName); err != nil {
+	return
+}
+
+// CreateRepoTemplate creates cDOT storage and uploads cloud-init template
+func CreateRepoTemplate(nodeConfig *config.NodeConfig, templateName string, templatePath string, templateSignature string) (err error) {
+	var c client.OntapClient
+	var sig []byte
+	if sig, err = readArtifactSignature(nodeConfig, templatePath, templateSignature); err != nil {
 		err = fmt.Errorf("CreateRepoTemplate(): %s", err)
 		return
 	}
-	if fileExists {
-		if err = c.FileDelete(nodeConfig.Storage.TemplateRepoName, "/cloud-init/"+templateName); err != nil {
-			err = fmt.Errorf("CreateRepoTemplate(): %s", err)
+	if c, err = client.NewOntapClient(nodeConfig); err != nil {
+		err = fmt.Errorf("CreateRepoTemplate(): %s", err)
+		return
+	}
+	if err = createRepoVolume(c, nodeConfig, nodeConfig.Storage.TemplateRepoName, templateRepoVolSize); err != nil {
+		err = fmt.Errorf("CreateRepoTemplate(): %s", err)
+		return
+	}
+	var fileReader io.Reader
+	if artifact.IsStoreLocation(templatePath) {
+		var artifactReader io.ReadCloser
+		if artifactReader, _, err = artifact.Open(templatePath, nodeConfig.Storage.ArtifactSources); err != nil {
+			err = fmt.Errorf("CreateRepoTemplate(): failure to open file %s: %s", templatePath, err)
 			return
 		}
-	}
-	if strings.HasPrefix(templatePath, "http://") || strings.HasPrefix(templatePath, "https://") {
+		fileReader = artifactReader
+		defer artifactReader.Close()
+	} else if strings.HasPrefix(templatePath, "http://") || strings.HasPrefix(templatePath, "https://") {
 		var httpResponse *http.Response
 		if httpResponse, err = http.Get(templatePath); err == nil {
 			fileReader = httpResponse.Body
@@ -230,8 +707,38 @@ func CreateRepoTemplate(nodeConfig *config.NodeConfig, templateName string, temp
 		fileReader = file
 		defer file.Close()
 	}
-	if err = c.FileUploadAPI(nodeConfig.Storage.TemplateRepoName, "/cloud-init/"+templateName, fileReader); err != nil {
+	var b []byte
+	if b, err = ioutil.ReadAll(fileReader); err != nil {
+		err = fmt.Errorf("CreateRepoTemplate(): failure to read file %s: %s", templatePath, err)
+		return
+	}
+	digest := sha256.Sum256(b)
+	if _, err = verifyArtifactSignature(nodeConfig, templatePath, digest[:], sig); err != nil {
 		err = fmt.Errorf("CreateRepoTemplate(): %s", err)
+		return
+	}
+	for _, filePath := range []string{"/cloud-init/" + templateName, "/cloud-init/" + templateName + repoTemplateSignatureSuffix} {
+		var fileExists bool
+		if fileExists, err = c.FileExists(nodeConfig.Storage.TemplateRepoName, filePath); err != nil {
+			err = fmt.Errorf("CreateRepoTemplate(): %s", err)
+			return
+		}
+		if fileExists {
+			if err = c.FileDelete(nodeConfig.Storage.TemplateRepoName, filePath); err != nil {
+				err = fmt.Errorf("CreateRepoTemplate(): %s", err)
+				return
+			}
+		}
+	}
+	if err = c.FileUploadAPI(nodeConfig.Storage.TemplateRepoName, "/cloud-init/"+templateName, bytes.NewReader(b)); err != nil {
+		err = fmt.Errorf("CreateRepoTemplate(): %s", err)
+		return
+	}
+	if sig != nil {
+		// signature is kept next to the template for verification at seed storage creation
+		if err = c.FileUploadAPI(nodeConfig.Storage.TemplateRepoName, "/cloud-init/"+templateName+repoTemplateSignatureSuffix, strings.NewReader(base64.StdEncoding.EncodeToString(sig))); err != nil {
+			err = fmt.Errorf("CreateRepoTemplate(): %s", err)
+		}
 	}
 	return
 }
@@ -251,8 +758,16 @@ func GetRepoTemplates(nodeConfig *config.NodeConfig) (templatesList []string, er
 	if !volExists {
 		return
 	}
-	if templatesList, err = c.FileGetList(nodeConfig.Storage.TemplateRepoName, "/cloud-init"); err != nil {
+	var fileList []string
+	if fileList, err = c.FileGetList(nodeConfig.Storage.TemplateRepoName, "/cloud-init"); err != nil {
 		err = fmt.Errorf("GetRepoTemplates(): %s", err)
+		return
+	}
+	templatesList = []string{}
+	for _, fileName := range fileList {
+		if !strings.HasSuffix(fileName, repoTemplateSignatureSuffix) {
+			templatesList = append(templatesList, fileName)
+		}
 	}
 	return
 }
@@ -273,14 +788,17 @@ func DeleteRepoTemplate(nodeConfig *config.NodeConfig, templateName string) (err
 		err = fmt.Errorf("DeleteRepoTemplate(): repo volume \"%s\" does not exist", nodeConfig.Storage.TemplateRepoName)
 		return
 	}
-	var fileExists bool
-	if fileExists, err = c.FileExists(nodeConfig.Storage.TemplateRepoName, "/cloud-init/"+templateName); err != nil {
-		err = fmt.Errorf("DeleteRepoTemplate(): %s", err)
-		return
-	}
-	if fileExists {
-		if err = c.FileDelete(nodeConfig.Storage.TemplateRepoName, "/cloud-init/"+templateName); err != nil {
+	for _, filePath := range []string{"/cloud-init/" + templateName, "/cloud-init/" + templateName + repoTemplateSignatureSuffix} {
+		var fileExists bool
+		if fileExists, err = c.FileExists(nodeConfig.Storage.TemplateRepoName, filePath); err != nil {
 			err = fmt.Errorf("DeleteRepoTemplate(): %s", err)
+			return
+		}
+		if fileExists {
+			if err = c.FileDelete(nodeConfig.Storage.TemplateRepoName, filePath); err != nil {
+				err = fmt.Errorf("DeleteRepoTemplate(): %s", err)
+				return
+			}
 		}
 	}
 	return
@@ -330,3 +848,28 @@ func DownloadRepoTemplate(nodeConfig *config.NodeConfig, templateName string) (t
 	}
 	return
 }
+
+// DownloadRepoTemplateSignature downloads cloud-init template signature from cDOT storage, returns nil if template is not signed
+func DownloadRepoTemplateSignature(nodeConfig *config.NodeConfig, templateName string) (sig []byte, err error) {
+	var c client.OntapClient
+	if c, err = client.NewOntapClient(nodeConfig); err != nil {
+		err = fmt.Errorf("DownloadRepoTemplateSignature(): %s", err)
+		return
+	}
+	var fileExists bool
+	if fileExists, err = c.FileExists(nodeConfig.Storage.TemplateRepoName, "/cloud-init/"+templateName+repoTemplateSignatureSuffix); err != nil || !fileExists {
+		if err != nil {
+			err = fmt.Errorf("DownloadRepoTemplateSignature(): %s", err)
+		}
+		return
+	}
+	var b []byte
+	if b, err = c.FileDownload(nodeConfig.Storage.TemplateRepoName, "/cloud-init/"+templateName+repoTemplateSignatureSuffix); err != nil {
+		err = fmt.Errorf("DownloadRepoTemplateSignature(): %s", err)
+		return
+	}
+	if sig, err = signature.DecodeSignature(b); err != nil {
+		err = fmt.Errorf("DownloadRepoTemplateSignature(): %s", err)
+	}
+	return
+}
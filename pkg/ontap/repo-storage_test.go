@@ -0,0 +1,148 @@
+package ontap
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCreateRepoImage(t *testing.T) {
+	f := useFakeFactory(t)
+	cluster := f.Cluster(testCdotHost, testSvm)
+	nodeConfig := testNodeConfig(t, "node1")
+	content := testImageContent(1, 256*1024)
+	imagePath, checksumPath := testImageFile(t, content)
+	if err := CreateRepoImage(nodeConfig, testImageName, imagePath, checksumPath, "", nil); err != nil {
+		t.Fatalf("CreateRepoImage() failure: %s", err)
+	}
+	data, err := cluster.LunData("/vol/image_repo/" + testImageName)
+	if err != nil || string(data) != string(content) {
+		t.Fatalf("unexpected image LUN content: %v", err)
+	}
+	imagesInfo, err := GetRepoImagesInfo(nodeConfig)
+	if err != nil {
+		t.Fatalf("GetRepoImagesInfo() failure: %s", err)
+	}
+	digest := sha256.Sum256(content)
+	if len(imagesInfo) != 1 || imagesInfo[0].Name != testImageName || imagesInfo[0].Sha256 != hex.EncodeToString(digest[:]) {
+		t.Fatalf("unexpected images info %+v", imagesInfo)
+	}
+	if _, err = cluster.LunData("/vol/image_repo/" + testImageName + repoImageUploadSuffix); err == nil {
+		t.Fatalf("temporary upload LUN is not removed")
+	}
+
+	// image update replaces the image
+	update := testImageContent(2, 128*1024)
+	imagePath, checksumPath = testImageFile(t, update)
+	if err = CreateRepoImage(nodeConfig, testImageName, imagePath, checksumPath, "", nil); err != nil {
+		t.Fatalf("CreateRepoImage() update failure: %s", err)
+	}
+	if data, _ = cluster.LunData("/vol/image_repo/" + testImageName); string(data) != string(update) {
+		t.Fatalf("image is not updated")
+	}
+}
+
+func TestCreateRepoImageFailureKeepsImage(t *testing.T) {
+	f := useFakeFactory(t)
+	cluster := f.Cluster(testCdotHost, testSvm)
+	nodeConfig := testNodeConfig(t, "node1")
+	content := testImageContent(1, 64*1024)
+	imagePath, checksumPath := testImageFile(t, content)
+	if err := CreateRepoImage(nodeConfig, testImageName, imagePath, checksumPath, "", nil); err != nil {
+		t.Fatalf("CreateRepoImage() failure: %s", err)
+	}
+	updatePath, updateChecksumPath := testImageFile(t, testImageContent(2, 64*1024))
+	tests := []struct {
+		name     string
+		method   string
+		checksum string
+		err      string
+		uploads  int
+	}{
+		{name: "checksum mismatch", checksum: checksumPath, err: "checksum mismatch"},
+		{name: "upload failure", method: "LunCreateAndUpload", checksum: updateChecksumPath, err: "LunCreateAndUpload() failure: connection reset", uploads: 1},
+		{name: "comment failure", method: "LunSetComment", checksum: updateChecksumPath, err: "LunSetComment() failure: connection reset", uploads: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			uploads := testCalls(cluster, "LunCreateAndUpload")
+			if test.method != "" {
+				cluster.InjectFault(test.method, errors.New("connection reset"), 1, "")
+			}
+			defer cluster.ClearFaults()
+			err := CreateRepoImage(nodeConfig, testImageName, updatePath, test.checksum, "", nil)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error containing %q, got %v", test.err, err)
+			}
+			if n := testCalls(cluster, "LunCreateAndUpload") - uploads; n != test.uploads {
+				t.Errorf("expected %d uploads, got %d", test.uploads, n)
+			}
+			if data, _ := cluster.LunData("/vol/image_repo/" + testImageName); string(data) != string(content) {
+				t.Fatalf("existing image is changed")
+			}
+			luns, _ := GetRepoImages(nodeConfig)
+			if len(luns) != 1 {
+				t.Errorf("unexpected repository images %v", luns)
+			}
+			if _, err = cluster.LunData("/vol/image_repo/" + testImageName + repoImageUploadSuffix); err == nil {
+				t.Fatalf("temporary upload LUN is not removed")
+			}
+		})
+	}
+}
+
+func TestCreateRepoImageSignature(t *testing.T) {
+	f := useFakeFactory(t)
+	cluster := f.Cluster(testCdotHost, testSvm)
+	publicKey, privateKey, _ := ed25519.GenerateKey(nil)
+	nodeConfig := testNodeConfig(t, "node1")
+	nodeConfig.Storage.Signatures.Required = true
+	nodeConfig.Storage.Signatures.TrustedKeys = []string{base64.StdEncoding.EncodeToString(publicKey)}
+	content := testImageContent(3, 32*1024)
+	imagePath, checksumPath := testImageFile(t, content)
+	digest := sha256.Sum256(content)
+	if err := CreateRepoImage(nodeConfig, testImageName, imagePath, checksumPath, "", nil); err == nil || !strings.Contains(err.Error(), imagePath+".sig") {
+		t.Fatalf("expected missing signature failure, got %v", err)
+	}
+	if testCalls(cluster, "LunCreateAndUpload") != 0 {
+		t.Fatalf("unsigned image is uploaded")
+	}
+	// signature of other content is rejected before upload
+	otherDigest := sha256.Sum256([]byte("other"))
+	if err := ioutil.WriteFile(imagePath+".sig", ed25519.Sign(privateKey, otherDigest[:]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateRepoImage(nodeConfig, testImageName, imagePath, checksumPath, "", nil); err == nil || !strings.Contains(err.Error(), "signature verification failure") {
+		t.Fatalf("expected signature verification failure, got %v", err)
+	}
+	if testCalls(cluster, "LunCreateAndUpload") != 0 {
+		t.Fatalf("image with invalid signature is uploaded")
+	}
+	if err := ioutil.WriteFile(imagePath+".sig", ed25519.Sign(privateKey, digest[:]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateRepoImage(nodeConfig, testImageName, imagePath, checksumPath, "", nil); err != nil {
+		t.Fatalf("CreateRepoImage() failure: %s", err)
+	}
+	if err := CreateBootStorage(nodeConfig); err != nil {
+		t.Fatalf("CreateBootStorage() failure: %s", err)
+	}
+	// signature kept in repository is verified again when the image is used
+	c, _ := f.NewClient(nodeConfig)
+	if err := c.FileUploadAPI("image_repo", "/_"+testImageName+repoImageSignatureSuffix, strings.NewReader(base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, otherDigest[:])))); err != nil {
+		t.Fatal(err)
+	}
+	node2 := testNodeConfig(t, "node2")
+	node2.Storage.Signatures = nodeConfig.Storage.Signatures
+	if err := CreateBootStorage(node2); err == nil || !strings.Contains(err.Error(), "image "+testImageName+" signature verification failure") {
+		t.Fatalf("expected signature verification failure, got %v", err)
+	}
+	if testCalls(cluster, "LunCopy") != 1 {
+		t.Fatalf("boot LUN is cloned from image with invalid signature")
+	}
+}
@@ -0,0 +1,334 @@
+package ontap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/util/artifact"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	repoSyncParallelism = 2
+	// Repository sync actions
+	RepoSyncCreate    = "create"
+	RepoSyncUpdate    = "update"
+	RepoSyncDelete    = "delete"
+	RepoSyncUnchanged = "unchanged"
+	RepoSyncKeep      = "keep"
+	// Repository item kinds
+	RepoItemImage    = "image"
+	RepoItemTemplate = "template"
+)
+
+// RepoManifest is a list of images and templates published per OS release, YAML or JSON
+type RepoManifest struct {
+	Images    []RepoManifestItem `yaml:"images,omitempty" json:"images,omitempty"`
+	Templates []RepoManifestItem `yaml:"templates,omitempty" json:"templates,omitempty"`
+}
+
+// RepoManifestItem is image or template in manifest,
+// checksum is "[sha256:]<digest>" or a path or URL to checksum file in sha256sum format
+type RepoManifestItem struct {
+	Name      string            `yaml:"name" json:"name"`
+	Location  string            `yaml:"location" json:"location"`
+	Checksum  string            `yaml:"checksum,omitempty" json:"checksum,omitempty"`
+	Signature string            `yaml:"signature,omitempty" json:"signature,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+}
+
+// RepoSyncChange is planned change of repository item
+type RepoSyncChange struct {
+	Kind         string            `yaml:"kind" json:"kind"`
+	Name         string            `yaml:"name" json:"name"`
+	Action       string            `yaml:"action" json:"action"`
+	Reason       string            `yaml:"reason,omitempty" json:"reason,omitempty"`
+	Labels       map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	ErrorMessage string            `yaml:"errorMessage,omitempty" json:"errorMessage,omitempty"`
+}
+
+// ReadRepoManifest reads manifest from file, URL, S3-compatible object storage or OCI registry
+func ReadRepoManifest(nodeConfig *config.NodeConfig, manifestPath string) (manifest *RepoManifest, err error) {
+	var b []byte
+	if artifact.IsStoreLocation(manifestPath) {
+		b, err = artifact.ReadAll(manifestPath, nodeConfig.Storage.ArtifactSources)
+	} else if strings.HasPrefix(manifestPath, "http://") || strings.HasPrefix(manifestPath, "https://") {
+		var httpResponse *http.Response
+		if httpResponse, err = http.Get(manifestPath); err == nil {
+			defer httpResponse.Body.Close()
+			if httpResponse.StatusCode != http.StatusOK {
+				err = fmt.Errorf("%s", httpResponse.Status)
+			} else {
+				b, err = ioutil.ReadAll(httpResponse.Body)
+			}
+		}
+	} else {
+		b, err = ioutil.ReadFile(strings.TrimPrefix(manifestPath, "file://"))
+	}
+	if err != nil {
+		err = fmt.Errorf("ReadRepoManifest(): failure to read manifest %s: %s", manifestPath, err)
+		return
+	}
+	manifest = &RepoManifest{}
+	// JSON is a subset of YAML
+	if err = yaml.Unmarshal(b, manifest); err != nil {
+		err = fmt.Errorf("ReadRepoManifest(): failure to parse manifest %s: %s", manifestPath, err)
+		return
+	}
+	names := make(map[string]bool)
+	for kind, items := range map[string][]RepoManifestItem{RepoItemImage: manifest.Images, RepoItemTemplate: manifest.Templates} {
+		for _, item := range items {
+			if item.Name == "" || item.Location == "" {
+				err = fmt.Errorf("ReadRepoManifest(): %s in manifest %s must have name and location", kind, manifestPath)
+				return
+			}
+			if names[kind+"/"+item.Name] {
+				err = fmt.Errorf("ReadRepoManifest(): duplicate %s \"%s\" in manifest %s", kind, item.Name, manifestPath)
+				return
+			}
+			names[kind+"/"+item.Name] = true
+		}
+	}
+	return
+}
+
+// PlanRepoSync compares repository with manifest, items not in manifest and not in keepItems list are planned for deletion if prune is set,
+// images in use are never deleted or updated
+func PlanRepoSync(nodeConfig *config.NodeConfig, manifest *RepoManifest, prune bool, keepItems []string) (changes []RepoSyncChange, err error) {
+	changes = []RepoSyncChange{}
+	errorFormat := "PlanRepoSync(): %s"
+	var c client.OntapClient
+	if c, err = client.NewOntapClient(nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	var imagesUsage []RepoImageUsage
+	if imagesUsage, err = getRepoImagesUsage(c, nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	repoImages := make(map[string]RepoImageUsage)
+	for _, imageUsage := range imagesUsage {
+		repoImages[imageUsage.Name] = imageUsage
+	}
+	var templates []string
+	if templates, err = GetRepoTemplates(nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	repoTemplates := make(map[string]bool)
+	for _, templateName := range templates {
+		repoTemplates[templateName] = true
+	}
+	manifestItems := make(map[string]bool)
+	for _, image := range manifest.Images {
+		manifestItems[RepoItemImage+"/"+image.Name] = true
+		change := RepoSyncChange{Kind: RepoItemImage, Name: image.Name, Action: RepoSyncUnchanged, Labels: image.Labels}
+		if imageUsage, exists := repoImages[image.Name]; !exists {
+			change.Action = RepoSyncCreate
+		} else if image.Checksum != "" {
+			var digest string
+			if digest, err = ResolveImageChecksum(image.Checksum, image.Location, nodeConfig.Storage.ArtifactSources); err != nil {
+				err = fmt.Errorf(errorFormat, err)
+				return
+			}
+			if imageUsage.Sha256 != digest {
+				change.Action = RepoSyncUpdate
+				change.Reason = fmt.Sprintf("repository sha256:%s, manifest sha256:%s", imageUsage.Sha256, digest)
+				// image is not replaced under its consumers, update is deferred until the image is not in use
+				if len(imageUsage.Consumers) > 0 {
+					change.Action = RepoSyncKeep
+					change.Reason = fmt.Sprintf("update deferred, in use by %s, %s", strings.Join(imageUsage.Consumers, ","), change.Reason)
+				}
+			}
+		}
+		changes = append(changes, change)
+	}
+	for _, template := range manifest.Templates {
+		manifestItems[RepoItemTemplate+"/"+template.Name] = true
+		change := RepoSyncChange{Kind: RepoItemTemplate, Name: template.Name, Action: RepoSyncUnchanged, Labels: template.Labels}
+		if !repoTemplates[template.Name] {
+			change.Action = RepoSyncCreate
+		} else if template.Checksum != "" {
+			var digest string
+			if digest, err = ResolveImageChecksum(template.Checksum, template.Location, nodeConfig.Storage.ArtifactSources); err != nil {
+				err = fmt.Errorf(errorFormat, err)
+				return
+			}
+			var templateContent []byte
+			if templateContent, err = DownloadRepoTemplate(nodeConfig, template.Name); err != nil {
+				err = fmt.Errorf(errorFormat, err)
+				return
+			}
+			sum := sha256.Sum256(templateContent)
+			if repoDigest := hex.EncodeToString(sum[:]); repoDigest != digest {
+				change.Action = RepoSyncUpdate
+				change.Reason = fmt.Sprintf("repository sha256:%s, manifest sha256:%s", repoDigest, digest)
+			}
+		}
+		changes = append(changes, change)
+	}
+	if !prune {
+		return
+	}
+	keep := make(map[string]bool)
+	for _, name := range keepItems {
+		keep[name] = true
+	}
+	for _, imageUsage := range imagesUsage {
+		if manifestItems[RepoItemImage+"/"+imageUsage.Name] || keep[imageUsage.Name] {
+			continue
+		}
+		change := RepoSyncChange{Kind: RepoItemImage, Name: imageUsage.Name, Action: RepoSyncDelete}
+		if len(imageUsage.Consumers) > 0 {
+			change.Action = RepoSyncKeep
+			change.Reason = "in use by " + strings.Join(imageUsage.Consumers, ",")
+		}
+		changes = append(changes, change)
+	}
+	sort.Strings(templates)
+	for _, templateName := range templates {
+		if manifestItems[RepoItemTemplate+"/"+templateName] || keep[templateName] {
+			continue
+		}
+		changes = append(changes, RepoSyncChange{Kind: RepoItemTemplate, Name: templateName, Action: RepoSyncDelete})
+	}
+	return
+}
+
+// ApplyRepoSync applies planned changes by parallel workers,
+// images are checked again not to be in use right before update or delete,
+// progress (if set) makes upload progress callback per image
+func ApplyRepoSync(nodeConfig *config.NodeConfig, manifest *RepoManifest, changes []RepoSyncChange, parallelism int, progress func(imageName string) client.UploadProgress) (err error) {
+	errorFormat := "ApplyRepoSync(): %s"
+	if parallelism <= 0 {
+		parallelism = repoSyncParallelism
+	}
+	items := make(map[string]RepoManifestItem)
+	for _, image := range manifest.Images {
+		items[RepoItemImage+"/"+image.Name] = image
+	}
+	for _, template := range manifest.Templates {
+		items[RepoItemTemplate+"/"+template.Name] = template
+	}
+	var c client.OntapClient
+	if c, err = client.NewOntapClient(nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	// repository volumes are created upfront so that parallel uploads do not race for them
+	for _, change := range changes {
+		if change.Action == RepoSyncCreate || change.Action == RepoSyncUpdate {
+			if change.Kind == RepoItemImage {
+				err = createRepoVolume(c, nodeConfig, nodeConfig.Storage.ImageRepoName, imageRepoVolSize)
+			} else {
+				err = createRepoVolume(c, nodeConfig, nodeConfig.Storage.TemplateRepoName, templateRepoVolSize)
+			}
+			if err != nil {
+				err = fmt.Errorf(errorFormat, err)
+				return
+			}
+		}
+	}
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, parallelism)
+	for i := range changes {
+		if changes[i].Action != RepoSyncCreate && changes[i].Action != RepoSyncUpdate && changes[i].Action != RepoSyncDelete {
+			continue
+		}
+		wg.Add(1)
+		workers <- struct{}{}
+		// clients resolve and set SVM name in node config, every worker gets its own copy
+		workerConfig := *nodeConfig
+		go func(nodeConfig *config.NodeConfig, change *RepoSyncChange) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			var changeErr error
+			item := items[change.Kind+"/"+change.Name]
+			switch {
+			case change.Kind == RepoItemImage && change.Action == RepoSyncDelete:
+				if changeErr = CheckRepoImagesNotInUse(nodeConfig, []string{change.Name}); changeErr == nil {
+					changeErr = DeleteRepoImage(nodeConfig, change.Name)
+				}
+			case change.Kind == RepoItemImage:
+				var imageProgress client.UploadProgress
+				if progress != nil {
+					imageProgress = progress(change.Name)
+				}
+				if change.Action == RepoSyncUpdate {
+					changeErr = CheckRepoImagesNotInUse(nodeConfig, []string{change.Name})
+				}
+				if changeErr == nil {
+					changeErr = CreateRepoImage(nodeConfig, change.Name, item.Location, item.Checksum, item.Signature, imageProgress)
+				}
+			case change.Action == RepoSyncDelete:
+				changeErr = DeleteRepoTemplate(nodeConfig, change.Name)
+			default:
+				if changeErr = CreateRepoTemplate(nodeConfig, change.Name, item.Location, item.Signature); changeErr == nil && item.Checksum != "" {
+					changeErr = verifyRepoTemplateChecksum(nodeConfig, item)
+				}
+			}
+			if changeErr != nil {
+				change.ErrorMessage = changeErr.Error()
+			}
+		}(&workerConfig, &changes[i])
+	}
+	wg.Wait()
+	var failed []string
+	for _, change := range changes {
+		if change.ErrorMessage != "" {
+			failed = append(failed, fmt.Sprintf("%s %s \"%s\": %s", change.Action, change.Kind, change.Name, change.ErrorMessage))
+		}
+	}
+	if len(failed) > 0 {
+		err = fmt.Errorf("ApplyRepoSync(): %s", strings.Join(failed, "; "))
+	}
+	return
+}
+
+// verifyRepoTemplateChecksum verifies uploaded template against manifest checksum, template is deleted on mismatch
+func verifyRepoTemplateChecksum(nodeConfig *config.NodeConfig, item RepoManifestItem) (err error) {
+	var digest string
+	if digest, err = ResolveImageChecksum(item.Checksum, item.Location, nodeConfig.Storage.ArtifactSources); err != nil {
+		return
+	}
+	var templateContent []byte
+	if templateContent, err = DownloadRepoTemplate(nodeConfig, item.Name); err != nil {
+		return
+	}
+	sum := sha256.Sum256(templateContent)
+	if templateDigest := hex.EncodeToString(sum[:]); templateDigest != digest {
+		DeleteRepoTemplate(nodeConfig, item.Name)
+		err = fmt.Errorf("template %s checksum mismatch: expected sha256:%s, uploaded sha256:%s", item.Location, digest, templateDigest)
+	}
+	return
+}
+
+// RepoSyncSummary makes plan-style summary of repository changes
+func RepoSyncSummary(changes []RepoSyncChange) string {
+	counts := make(map[string]int)
+	for _, change := range changes {
+		counts[change.Action]++
+	}
+	return fmt.Sprintf("%d to create, %d to update, %d to delete, %d unchanged, %d kept in use", counts[RepoSyncCreate], counts[RepoSyncUpdate], counts[RepoSyncDelete], counts[RepoSyncUnchanged], counts[RepoSyncKeep])
+}
+
+// RepoSyncPending checks if there are changes to apply
+func RepoSyncPending(changes []RepoSyncChange) bool {
+	for _, change := range changes {
+		if change.Action == RepoSyncCreate || change.Action == RepoSyncUpdate || change.Action == RepoSyncDelete {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package ontap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
+)
+
+// RepoImageUsage is image repository entry with LUN's provisioned from the image
+type RepoImageUsage struct {
+	Name       string    `yaml:"name" json:"name"`
+	Sha256     string    `yaml:"sha256,omitempty" json:"sha256,omitempty"`
+	CreateTime time.Time `yaml:"createTime" json:"createTime"`
+	Consumers  []string  `yaml:"consumers,omitempty" json:"consumers,omitempty"`
+}
+
+// getRepoImagesUsage scans SVM LUN's outside of image repository volume,
+// LUN is a consumer of the image if its comment (inherited from image LUN) has the image name or it is copied from image LUN,
+// temporary LUN's of uploads in progress are not images
+func getRepoImagesUsage(c client.OntapClient, nodeConfig *config.NodeConfig) (imagesUsage []RepoImageUsage, err error) {
+	imagesUsage = []RepoImageUsage{}
+	var volExists bool
+	if volExists, err = c.VolumeExists(nodeConfig.Storage.ImageRepoName); err != nil || !volExists {
+		return
+	}
+	var images, luns []client.LunInfo
+	if images, err = c.LunGetInfoList(nodeConfig.Storage.ImageRepoName); err != nil {
+		return
+	}
+	if luns, err = c.LunGetInfoList(""); err != nil {
+		return
+	}
+	repoPrefix := "/vol/" + nodeConfig.Storage.ImageRepoName + "/"
+	for _, image := range images {
+		if strings.HasSuffix(image.Path, repoImageUploadSuffix) {
+			continue
+		}
+		imageUsage := RepoImageUsage{
+			Name:       strings.TrimPrefix(image.Path, repoPrefix),
+			Sha256:     ParseRepoImageComment(image.Comment).Sha256,
+			CreateTime: image.CreateTime,
+		}
+		for _, lun := range luns {
+			if strings.HasPrefix(lun.Path, repoPrefix) {
+				continue
+			}
+			if (lun.Comment != "" && ParseRepoImageComment(lun.Comment).Name == imageUsage.Name) || lun.Source == image.Path {
+				imageUsage.Consumers = append(imageUsage.Consumers, lun.Path)
+			}
+		}
+		sort.Strings(imageUsage.Consumers)
+		imagesUsage = append(imagesUsage, imageUsage)
+	}
+	sort.Slice(imagesUsage, func(i, j int) bool {
+		return imagesUsage[i].Name < imagesUsage[j].Name
+	})
+	return
+}
+
+// GetRepoImagesUsage gets list of images with LUN's provisioned from the images
+func GetRepoImagesUsage(nodeConfig *config.NodeConfig) (imagesUsage []RepoImageUsage, err error) {
+	var c client.OntapClient
+	if c, err = client.NewOntapClient(nodeConfig); err != nil {
+		err = fmt.Errorf("GetRepoImagesUsage(): %s", err)
+		return
+	}
+	if imagesUsage, err = getRepoImagesUsage(c, nodeConfig); err != nil {
+		err = fmt.Errorf("GetRepoImagesUsage(): %s", err)
+	}
+	return
+}
+
+// CheckRepoImagesNotInUse returns error if any of the images has consumers
+func CheckRepoImagesNotInUse(nodeConfig *config.NodeConfig, imageNames []string) (err error) {
+	if len(imageNames) == 0 {
+		return
+	}
+	var imagesUsage []RepoImageUsage
+	if imagesUsage, err = GetRepoImagesUsage(nodeConfig); err != nil {
+		err = fmt.Errorf("CheckRepoImagesNotInUse(): %s", err)
+		return
+	}
+	var inUse []string
+	for _, imageUsage := range imagesUsage {
+		for _, imageName := range imageNames {
+			if imageUsage.Name == imageName && len(imageUsage.Consumers) > 0 {
+				inUse = append(inUse, fmt.Sprintf("%s (%s)", imageName, strings.Join(imageUsage.Consumers, ",")))
+			}
+		}
+	}
+	if len(inUse) > 0 {
+		err = fmt.Errorf("CheckRepoImagesNotInUse(): images are in use: %s", strings.Join(inUse, ", "))
+	}
+	return
+}
+
+// GcRepoImages deletes images with no consumers older than max age (Go duration format plus days, i.e. "30d"),
+// images in keepImages list are preserved, nothing is deleted if max age is empty
+func GcRepoImages(nodeConfig *config.NodeConfig, maxAge string, keepImages []string) (imagesUsage []RepoImageUsage, pruned []string, err error) {
+	pruned = []string{}
+	errorFormat := "GcRepoImages(): %s"
+	var age time.Duration
+	if age, err = ParseSnapshotMaxAge(maxAge); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	var c client.OntapClient
+	if c, err = client.NewOntapClient(nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	if imagesUsage, err = getRepoImagesUsage(c, nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	if age == 0 {
+		return
+	}
+	keep := make(map[string]bool)
+	for _, name := range keepImages {
+		keep[name] = true
+	}
+	now := time.Now()
+	for _, imageUsage := range imagesUsage {
+		if keep[imageUsage.Name] || len(imageUsage.Consumers) > 0 || imageUsage.CreateTime.IsZero() || now.Sub(imageUsage.CreateTime) <= age {
+			continue
+		}
+		if err = DeleteRepoImage(nodeConfig, imageUsage.Name); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+		pruned = append(pruned, imageUsage.Name)
+	}
+	return
+}
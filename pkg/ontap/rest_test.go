@@ -0,0 +1,177 @@
+package ontap
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client/fake"
+)
+
+// useRestServer makes pkg/ontap functions run with REST API client against simulator of in-memory cluster
+func useRestServer(t *testing.T) (server *fake.RestServer) {
+	cluster := fake.NewCluster(testCdotHost, testSvm)
+	cluster.IscsiLIFs = []string{"192.168.10.11", "192.168.10.12", "192.168.20.11"}
+	server = fake.NewRestServer(cluster)
+	server.User = "admin"
+	server.Password = "secret"
+	settleTime := repoVolumeSettleTime
+	repoVolumeSettleTime = 0
+	lunSettleTime := client.SetLunCreateSettleTime(0)
+	t.Cleanup(func() {
+		server.Close()
+		repoVolumeSettleTime = settleTime
+		client.SetLunCreateSettleTime(lunSettleTime)
+	})
+	return
+}
+
+// testRestNodeConfig makes node configuration with REST API credentials of the simulator
+func testRestNodeConfig(t *testing.T, server *fake.RestServer, hostName string) (nodeConfig *config.NodeConfig) {
+	nodeConfig = testNodeConfig(t, hostName)
+	nodeConfig.Storage.CdotCredentials.Host = server.Host()
+	nodeConfig.Storage.CdotCredentials.ApiMethod = "rest"
+	nodeConfig.Storage.CdotCredentials.User = server.User
+	nodeConfig.Storage.CdotCredentials.Password = server.Password
+	return
+}
+
+func TestRestStorageFlow(t *testing.T) {
+	server := useRestServer(t)
+	cluster := server.Cluster()
+	nodeConfig := testRestNodeConfig(t, server, "node1")
+	content := testImageContent(1, 64*1024)
+	testCreateNodeStorage(t, nodeConfig, content)
+	testVerifyClean(t, nodeConfig)
+	if data, err := cluster.LunData("/vol/node1_iboot/node1_iboot"); err != nil || !bytes.Equal(data, content) {
+		t.Fatalf("boot LUN is not cloned from image: %v", err)
+	}
+	imagesInfo, err := GetRepoImagesInfo(nodeConfig)
+	if err != nil || len(imagesInfo) != 1 || imagesInfo[0].Name != testImageName {
+		t.Fatalf("unexpected images info %+v, error %v", imagesInfo, err)
+	}
+	target := nodeConfig.Network.IscsiInitiator[0].IscsiTarget
+	// REST API client selects one LIF in initiator subnet per node, the simulator has single node
+	if target == nil || target.NodeName != cluster.IscsiTargetName || strings.Join(target.Interfaces, ",") != "192.168.10.11" {
+		t.Fatalf("unexpected iSCSI target %+v", target)
+	}
+
+	if err = CreateSnapshot(nodeConfig, "snap1", "before upgrade"); err != nil {
+		t.Fatalf("CreateSnapshot() failure: %s", err)
+	}
+	if metadata, err := GetSnapshotMetadata(nodeConfig, "snap1"); err != nil || metadata == nil {
+		t.Fatalf("GetSnapshotMetadata() failure: %v", err)
+	}
+	c, err := client.NewOntapClient(nodeConfig)
+	if err != nil {
+		t.Fatalf("NewOntapClient() failure: %s", err)
+	}
+	changed := testImageContent(7, 32*1024)
+	if err = c.LunUpload("/vol/node1_iboot/node1_iboot", bytes.NewReader(changed), int64(len(changed)), nil); err != nil {
+		t.Fatalf("LunUpload() failure: %s", err)
+	}
+	if err = c.LunDestroy("/vol/node1_iboot/node1_data"); err != nil {
+		t.Fatalf("LunDestroy() failure: %s", err)
+	}
+	if !testHasDiscrepancy(t, nodeConfig, "lun /vol/node1_iboot/node1_data", "exists") {
+		t.Fatalf("missing data LUN is not reported")
+	}
+	if err = RestoreSnapshot(nodeConfig, "snap1"); err != nil {
+		t.Fatalf("RestoreSnapshot() failure: %s", err)
+	}
+	if err = LunRestoreMapping(nodeConfig); err != nil {
+		t.Fatalf("LunRestoreMapping() failure: %s", err)
+	}
+	testVerifyClean(t, nodeConfig)
+	if data, _ := cluster.LunData("/vol/node1_iboot/node1_iboot"); !bytes.Equal(data, content) {
+		t.Fatalf("boot LUN is not restored")
+	}
+
+	if err = DeleteBootStorage(nodeConfig); err != nil {
+		t.Fatalf("DeleteBootStorage() failure: %s", err)
+	}
+	if volumes := cluster.Volumes(); strings.Join(volumes, ",") != "image_repo" {
+		t.Fatalf("unexpected volumes %v after DeleteBootStorage()", volumes)
+	}
+}
+
+func TestRestStorageFaults(t *testing.T) {
+	server := useRestServer(t)
+	cluster := server.Cluster()
+	nodeConfig := testRestNodeConfig(t, server, "node1")
+	content := testImageContent(1, 64*1024)
+	imagePath, checksumPath := testImageFile(t, content)
+	if err := CreateRepoImage(nodeConfig, testImageName, imagePath, checksumPath, "", nil); err != nil {
+		t.Fatalf("CreateRepoImage() failure: %s", err)
+	}
+
+	// failed image update keeps existing image and removes uploaded LUN
+	updatePath, updateChecksumPath := testImageFile(t, testImageContent(2, 64*1024))
+	cluster.InjectFault("POST /api/storage/luns", &fake.RestError{Status: http.StatusInternalServerError, Code: "5374860", Message: "not enough space"}, 1, "")
+	err := CreateRepoImage(nodeConfig, testImageName, updatePath, updateChecksumPath, "", nil)
+	if err == nil || !strings.Contains(err.Error(), "not enough space") {
+		t.Fatalf("expected REST error, got %v", err)
+	}
+	if data, _ := cluster.LunData("/vol/image_repo/" + testImageName); !bytes.Equal(data, content) {
+		t.Fatalf("existing image is changed")
+	}
+	if _, err = cluster.LunData("/vol/image_repo/" + testImageName + repoImageUploadSuffix); err == nil {
+		t.Fatalf("temporary upload LUN is not removed")
+	}
+
+	// boot LUN mapping failure is reported by verify and completed by retry
+	cluster.InjectFault("POST /api/protocols/san/lun-maps", &fake.RestError{Status: http.StatusInternalServerError, Code: "5374922", Message: "igroup is busy"}, 1, "")
+	if err = CreateBootStorage(nodeConfig); err == nil || !strings.Contains(err.Error(), "igroup is busy") {
+		t.Fatalf("expected REST error, got %v", err)
+	}
+	if !testHasDiscrepancy(t, nodeConfig, "lun /vol/node1_iboot/node1_iboot", "mapping") {
+		t.Fatalf("unmapped boot LUN is not reported")
+	}
+	if err = CreateBootStorage(nodeConfig); err != nil {
+		t.Fatalf("CreateBootStorage() retry failure: %s", err)
+	}
+	if err = CreateSeedStorage(nodeConfig); err != nil {
+		t.Fatalf("CreateSeedStorage() failure: %s", err)
+	}
+	testVerifyClean(t, nodeConfig)
+
+	// failed snapshot restore leaves storage as is
+	if err = CreateSnapshot(nodeConfig, "snap1", ""); err != nil {
+		t.Fatalf("CreateSnapshot() failure: %s", err)
+	}
+	c, _ := client.NewOntapClient(nodeConfig)
+	if err = c.LunDestroy("/vol/node1_iboot/node1_data"); err != nil {
+		t.Fatalf("LunDestroy() failure: %s", err)
+	}
+	cluster.InjectFault("PATCH /api/storage/volumes/*", &fake.RestError{Status: http.StatusConflict, Code: "13107406", Message: "snapshot is busy"}, 1, "")
+	if err = RestoreSnapshot(nodeConfig, "snap1"); err == nil || !strings.Contains(err.Error(), "snapshot is busy") {
+		t.Fatalf("expected REST error, got %v", err)
+	}
+	if !testHasDiscrepancy(t, nodeConfig, "lun /vol/node1_iboot/node1_data", "exists") {
+		t.Fatalf("storage is changed by failed restore")
+	}
+	if err = RestoreSnapshot(nodeConfig, "snap1"); err != nil {
+		t.Fatalf("RestoreSnapshot() failure: %s", err)
+	}
+	if err = LunRestoreMapping(nodeConfig); err != nil {
+		t.Fatalf("LunRestoreMapping() failure: %s", err)
+	}
+	testVerifyClean(t, nodeConfig)
+}
+
+func TestRestAuthentication(t *testing.T) {
+	server := useRestServer(t)
+	nodeConfig := testRestNodeConfig(t, server, "node1")
+	nodeConfig.Storage.CdotCredentials.Password = "wrong"
+	if _, err := VerifyBootStorage(nodeConfig); err == nil || !strings.Contains(err.Error(), "User is not authorized") {
+		t.Fatalf("expected authorization failure, got %v", err)
+	}
+	nodeConfig = testRestNodeConfig(t, server, "node1")
+	nodeConfig.Storage.SvmName = "svm2"
+	if _, err := VerifyBootStorage(nodeConfig); err == nil || !strings.Contains(err.Error(), "svm \"svm2\" not found") {
+		t.Fatalf("expected missing SVM failure, got %v", err)
+	}
+}
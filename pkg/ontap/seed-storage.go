@@ -2,6 +2,7 @@ package ontap
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/util/artifact"
 	"github.com/kdomanski/iso9660"
 )
 
@@ -21,6 +23,7 @@ func CreateSeedStorage(nodeConfig *config.NodeConfig) (err error) {
 	var fileReader io.Reader
 	var file *os.File
 	var b []byte
+	var repoTemplate bool
 	var calcFuncMap = template.FuncMap {
 		"add": func(a, b int) int {
 			return a + b
@@ -29,7 +32,15 @@ func CreateSeedStorage(nodeConfig *config.NodeConfig) (err error) {
 			return a - b
 		},
 	}
-	if strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "http://") || strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "https://") {
+	if artifact.IsStoreLocation(nodeConfig.Storage.SeedLun.SeedTemplate.Location) {
+		var artifactReader io.ReadCloser
+		if artifactReader, _, err = artifact.Open(nodeConfig.Storage.SeedLun.SeedTemplate.Location, nodeConfig.Storage.ArtifactSources); err != nil {
+			err = fmt.Errorf("CreateSeedStorage(): failure to open cloud-init template %s: %s", nodeConfig.Storage.SeedLun.SeedTemplate.Location, err)
+			return
+		}
+		fileReader = artifactReader
+		defer artifactReader.Close()
+	} else if strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "http://") || strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "https://") {
 		var httpResponse *http.Response
 		if httpResponse, err = http.Get(nodeConfig.Storage.SeedLun.SeedTemplate.Location); err == nil {
 			fileReader = httpResponse.Body
@@ -48,6 +59,7 @@ func CreateSeedStorage(nodeConfig *config.NodeConfig) (err error) {
 			if os.IsNotExist(err) {
 				// Last resort to download template from storage repository
 				b, err = DownloadRepoTemplate(nodeConfig, filepath.Base(nodeConfig.Storage.SeedLun.SeedTemplate.Location))
+				repoTemplate = true
 			}
 		} else {
 			fileReader = file
@@ -64,6 +76,23 @@ func CreateSeedStorage(nodeConfig *config.NodeConfig) (err error) {
 			return
 		}
 	}
+	if nodeConfig.Storage.Signatures.Required {
+		var sig []byte
+		if repoTemplate {
+			sig, err = DownloadRepoTemplateSignature(nodeConfig, filepath.Base(nodeConfig.Storage.SeedLun.SeedTemplate.Location))
+		} else {
+			sig, err = readArtifactSignature(nodeConfig, nodeConfig.Storage.SeedLun.SeedTemplate.Location, "")
+		}
+		if err != nil {
+			err = fmt.Errorf("CreateSeedStorage(): %s", err)
+			return
+		}
+		digest := sha256.Sum256(b)
+		if _, err = verifyArtifactSignature(nodeConfig, nodeConfig.Storage.SeedLun.SeedTemplate.Location, digest[:], sig); err != nil {
+			err = fmt.Errorf("CreateSeedStorage(): %s", err)
+			return
+		}
+	}
 	var isoWriter *iso9660.ImageWriter
 	if isoWriter, err = iso9660.NewWriter(); err != nil {
 		err = fmt.Errorf("CreateSeedStorage(): failed to create ISO writer: %v", err)
@@ -118,7 +147,7 @@ func CreateSeedStorage(nodeConfig *config.NodeConfig) (err error) {
 			return
 		}
 	}
-	if err = c.LunCreateAndUpload(nodeConfig.Storage.VolumeName, "/seed", int64(isoBuffer.Len()), isoReader, seedLunPath, nodeConfig.Storage.SeedLun.SeedTemplate.Location, "linux"); err != nil {
+	if err = c.LunCreateAndUpload(nodeConfig.Storage.VolumeName, "/seed", int64(isoBuffer.Len()), isoReader, seedLunPath, nodeConfig.Storage.SeedLun.SeedTemplate.Location, "linux", nil); err != nil {
 		err = fmt.Errorf("CreateSeedStorage(): %s", err)
 		return
         }
@@ -130,7 +159,14 @@ func CreateSeedStorage(nodeConfig *config.NodeConfig) (err error) {
 
 // CreateSeedStoragePreflight is sanity check before actual storage is created
 func CreateSeedStoragePreflight(nodeConfig *config.NodeConfig) (err error) {
-	if strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "http://") || strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "https://") {
+	if artifact.IsStoreLocation(nodeConfig.Storage.SeedLun.SeedTemplate.Location) {
+		var artifactReader io.ReadCloser
+		if artifactReader, _, err = artifact.Open(nodeConfig.Storage.SeedLun.SeedTemplate.Location, nodeConfig.Storage.ArtifactSources); err == nil {
+			artifactReader.Close()
+		} else {
+			err = fmt.Errorf("CreateSeedStoragePreflight(): failure to open cloud-init template %s: %s", nodeConfig.Storage.SeedLun.SeedTemplate.Location, err)
+		}
+	} else if strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "http://") || strings.HasPrefix(nodeConfig.Storage.SeedLun.SeedTemplate.Location, "https://") {
 		var httpResponse *http.Response
 		if httpResponse, err = http.Get(nodeConfig.Storage.SeedLun.SeedTemplate.Location); err == nil {
 			httpResponse.Body.Close()
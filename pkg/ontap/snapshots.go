@@ -1,12 +1,122 @@
 package ontap
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
 )
 
+const (
+	// SnapshotCommentPrefix marks snapshots created by flexbot, other snapshots are never pruned
+	SnapshotCommentPrefix = "flexbot"
+	// SnapshotMetadataVersion is current version of snapshot metadata record
+	SnapshotMetadataVersion = 1
+	// ONTAP snapshot comment length limit
+	snapshotCommentMaxLen = 255
+)
+
+// SnapshotMetadata is node configuration captured at snapshot creation
+type SnapshotMetadata struct {
+	Version         int               `yaml:"version" json:"version"`
+	OsImage         string            `yaml:"osImage,omitempty" json:"osImage,omitempty"`
+	SeedTemplate    string            `yaml:"seedTemplate,omitempty" json:"seedTemplate,omitempty"`
+	BladeModel      string            `yaml:"bladeModel,omitempty" json:"bladeModel,omitempty"`
+	BootLunSize     int               `yaml:"bootLunSize,omitempty" json:"bootLunSize,omitempty"`
+	DataLunSize     int               `yaml:"dataLunSize,omitempty" json:"dataLunSize,omitempty"`
+	Labels          map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	LabelsTruncated bool              `yaml:"labelsTruncated,omitempty" json:"labelsTruncated,omitempty"`
+	Comment         string            `yaml:"comment,omitempty" json:"comment,omitempty"`
+}
+
+// snapshotMetadataRecord is compact encoding of SnapshotMetadata in snapshot comment
+type snapshotMetadataRecord struct {
+	Version         int               `json:"v"`
+	OsImage         string            `json:"i,omitempty"`
+	SeedTemplate    string            `json:"t,omitempty"`
+	BladeModel      string            `json:"b,omitempty"`
+	BootLunSize     int               `json:"bs,omitempty"`
+	DataLunSize     int               `json:"ds,omitempty"`
+	Labels          map[string]string `json:"l,omitempty"`
+	LabelsTruncated bool              `json:"lt,omitempty"`
+	Comment         string            `json:"c,omitempty"`
+}
+
+// SnapshotDetails is snapshot info with flexbot metadata
+type SnapshotDetails struct {
+	Name       string            `yaml:"name" json:"name"`
+	CreateTime string            `yaml:"createTime,omitempty" json:"createTime,omitempty"`
+	Flexbot    bool              `yaml:"flexbot" json:"flexbot"`
+	Metadata   *SnapshotMetadata `yaml:"metadata,omitempty" json:"metadata,omitempty"`
+}
+
+// encodeSnapshotMetadata makes snapshot comment with node metadata record,
+// labels are dropped if record does not fit into comment, metadata is dropped if it still does not fit
+func encodeSnapshotMetadata(nodeConfig *config.NodeConfig, snapshotComment string) (comment string, err error) {
+	record := snapshotMetadataRecord{
+		Version:      SnapshotMetadataVersion,
+		OsImage:      nodeConfig.Storage.BootLun.OsImage.Name,
+		SeedTemplate: nodeConfig.Storage.SeedLun.SeedTemplate.Name,
+		BladeModel:   nodeConfig.Compute.BladeAssigned.Model,
+		BootLunSize:  nodeConfig.Storage.BootLun.Size,
+		DataLunSize:  nodeConfig.Storage.DataLun.Size,
+		Labels:       nodeConfig.Labels,
+		Comment:      snapshotComment,
+	}
+	if record.BladeModel == "" {
+		record.BladeModel = nodeConfig.Compute.BladeSpec.Model
+	}
+	var b []byte
+	if b, err = json.Marshal(&record); err != nil {
+		return
+	}
+	if len(SnapshotCommentPrefix)+1+len(b) > snapshotCommentMaxLen && len(record.Labels) > 0 {
+		record.Labels = nil
+		record.LabelsTruncated = true
+		if b, err = json.Marshal(&record); err != nil {
+			return
+		}
+	}
+	if len(SnapshotCommentPrefix)+1+len(b) > snapshotCommentMaxLen {
+		// Metadata does not fit with user comment, comment is kept without metadata
+		comment = SnapshotCommentPrefix
+		if snapshotComment != "" {
+			comment = comment + ":" + snapshotComment
+		}
+		return
+	}
+	comment = SnapshotCommentPrefix + ":" + string(b)
+	return
+}
+
+// DecodeSnapshotMetadata gets node metadata from snapshot comment, returns nil if comment has no metadata record
+func DecodeSnapshotMetadata(snapshotComment string) (metadata *SnapshotMetadata) {
+	if !strings.HasPrefix(snapshotComment, SnapshotCommentPrefix+":{") {
+		return
+	}
+	var record snapshotMetadataRecord
+	if err := json.Unmarshal([]byte(snapshotComment[len(SnapshotCommentPrefix)+1:]), &record); err != nil {
+		return
+	}
+	metadata = &SnapshotMetadata{
+		Version:         record.Version,
+		OsImage:         record.OsImage,
+		SeedTemplate:    record.SeedTemplate,
+		BladeModel:      record.BladeModel,
+		BootLunSize:     record.BootLunSize,
+		DataLunSize:     record.DataLunSize,
+		Labels:          record.Labels,
+		LabelsTruncated: record.LabelsTruncated,
+		Comment:         record.Comment,
+	}
+	return
+}
+
 // SnapshotExists checks if snapshot exists
 func SnapshotExists(nodeConfig *config.NodeConfig, snapshotName string) (exists bool, err error) {
 	var c client.OntapClient
@@ -46,14 +156,19 @@ func GetSnapshots(nodeConfig *config.NodeConfig) (snapshotList []string, err err
 	return
 }
 
-// CreateSnapshot creates snapshot
+// CreateSnapshot creates snapshot with node metadata record in snapshot comment
 func CreateSnapshot(nodeConfig *config.NodeConfig, snapshotName string, snapshotComment string) (err error) {
 	var c client.OntapClient
 	if c, err = client.NewOntapClient(nodeConfig); err != nil {
 		err = fmt.Errorf("CreateSnapshot(): %s", err)
 		return
 	}
-	if err = c.SnapshotCreate(nodeConfig.Storage.VolumeName, snapshotName, snapshotComment); err != nil {
+	var comment string
+	if comment, err = encodeSnapshotMetadata(nodeConfig, snapshotComment); err != nil {
+		err = fmt.Errorf("CreateSnapshot(): %s", err)
+		return
+	}
+	if err = c.SnapshotCreate(nodeConfig.Storage.VolumeName, snapshotName, comment); err != nil {
 		err = fmt.Errorf("CreateSnapshot(): %s", err)
 	}
 	return
@@ -72,6 +187,46 @@ func DeleteSnapshot(nodeConfig *config.NodeConfig, snapshotName string) (err err
 	return
 }
 
+// GetSnapshotsDetails gets list of snapshots with flexbot metadata
+func GetSnapshotsDetails(nodeConfig *config.NodeConfig) (snapshotDetails []SnapshotDetails, err error) {
+	snapshotDetails = []SnapshotDetails{}
+	var c client.OntapClient
+	if c, err = client.NewOntapClient(nodeConfig); err != nil {
+		err = fmt.Errorf("GetSnapshotsDetails(): %s", err)
+		return
+	}
+	var snapshots []client.SnapshotInfo
+	if snapshots, err = c.SnapshotGetInfoList(nodeConfig.Storage.VolumeName); err != nil {
+		err = fmt.Errorf("GetSnapshotsDetails(): %s", err)
+		return
+	}
+	for _, snapshot := range snapshots {
+		snapshotDetails = append(snapshotDetails, SnapshotDetails{
+			Name:       snapshot.Name,
+			CreateTime: snapshot.CreateTime.Format(time.RFC3339),
+			Flexbot:    IsFlexbotSnapshot(snapshot.Comment),
+			Metadata:   DecodeSnapshotMetadata(snapshot.Comment),
+		})
+	}
+	return
+}
+
+// GetSnapshotMetadata gets node metadata captured in snapshot, returns nil if snapshot has no metadata
+func GetSnapshotMetadata(nodeConfig *config.NodeConfig, snapshotName string) (metadata *SnapshotMetadata, err error) {
+	var snapshotDetails []SnapshotDetails
+	if snapshotDetails, err = GetSnapshotsDetails(nodeConfig); err != nil {
+		return
+	}
+	for _, snapshot := range snapshotDetails {
+		if snapshot.Name == snapshotName {
+			metadata = snapshot.Metadata
+			return
+		}
+	}
+	err = fmt.Errorf("GetSnapshotMetadata(): snapshot \"%s\" not found", snapshotName)
+	return
+}
+
 // RestoreSnapshot restores node storage from snapshot
 func RestoreSnapshot(nodeConfig *config.NodeConfig, snapshotName string) (err error) {
 	var c client.OntapClient
@@ -84,3 +239,170 @@ func RestoreSnapshot(nodeConfig *config.NodeConfig, snapshotName string) (err er
 	}
 	return
 }
+
+// CreateGroupSnapshot creates consistency group snapshot across nodes volumes,
+// all nodes are expected to share the same storage cluster and SVM
+func CreateGroupSnapshot(nodeConfigs []*config.NodeConfig, snapshotName string, snapshotComment string) (err error) {
+	var c client.OntapClient
+	errorFormat := "CreateGroupSnapshot(): %s"
+	if len(nodeConfigs) == 0 {
+		err = fmt.Errorf(errorFormat, "expected at least one node")
+		return
+	}
+	if c, err = client.NewOntapClient(nodeConfigs[0]); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	var volumeNames []string
+	for _, nodeConfig := range nodeConfigs {
+		if nodeConfig.Storage.CdotCredentials.Host != nodeConfigs[0].Storage.CdotCredentials.Host || nodeConfig.Storage.SvmName != nodeConfigs[0].Storage.SvmName {
+			err = fmt.Errorf(errorFormat, fmt.Sprintf("node \"%s\" storage is not in SVM \"%s\" of cluster \"%s\"", nodeConfig.Compute.HostName, nodeConfigs[0].Storage.SvmName, nodeConfigs[0].Storage.CdotCredentials.Host))
+			return
+		}
+		volumeNames = append(volumeNames, nodeConfig.Storage.VolumeName)
+	}
+	if err = c.SnapshotCreateGroup(volumeNames, snapshotName); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	for _, nodeConfig := range nodeConfigs {
+		var comment string
+		if comment, err = encodeSnapshotMetadata(nodeConfig, snapshotComment); err == nil {
+			err = c.SnapshotSetComment(nodeConfig.Storage.VolumeName, snapshotName, comment)
+		}
+		if err != nil {
+			// group snapshot without metadata is not usable for restore, remove it from all volumes
+			for _, volumeName := range volumeNames {
+				if deleteErr := c.SnapshotDelete(volumeName, snapshotName); deleteErr != nil {
+					err = fmt.Errorf("%s, rollback of snapshot in volume \"%s\" failed: %s", err, volumeName, deleteErr)
+				}
+			}
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+	}
+	return
+}
+
+// RestoreGroupSnapshot restores nodes storage from group snapshot,
+// snapshot presence is verified in all volumes before any volume is restored
+func RestoreGroupSnapshot(nodeConfigs []*config.NodeConfig, snapshotName string) (err error) {
+	errorFormat := "RestoreGroupSnapshot(): %s"
+	for _, nodeConfig := range nodeConfigs {
+		var exists bool
+		if exists, err = SnapshotExists(nodeConfig, snapshotName); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+		if !exists {
+			err = fmt.Errorf(errorFormat, fmt.Sprintf("snapshot \"%s\" not found for node \"%s\"", snapshotName, nodeConfig.Compute.HostName))
+			return
+		}
+	}
+	for _, nodeConfig := range nodeConfigs {
+		if err = RestoreSnapshot(nodeConfig, snapshotName); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+		if err = LunRestoreMapping(nodeConfig); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+	}
+	return
+}
+
+// IsFlexbotSnapshot checks if snapshot comment has flexbot mark
+func IsFlexbotSnapshot(snapshotComment string) bool {
+	return snapshotComment == SnapshotCommentPrefix || strings.HasPrefix(snapshotComment, SnapshotCommentPrefix+":")
+}
+
+// ParseSnapshotMaxAge parses retention max age, Go duration format plus days (i.e. "30d")
+func ParseSnapshotMaxAge(maxAge string) (age time.Duration, err error) {
+	if maxAge == "" {
+		return
+	}
+	if strings.HasSuffix(maxAge, "d") {
+		var days int
+		if days, err = strconv.Atoi(strings.TrimSuffix(maxAge, "d")); err != nil {
+			err = fmt.Errorf("invalid max age \"%s\": %s", maxAge, err)
+			return
+		}
+		age = time.Duration(days) * 24 * time.Hour
+	} else {
+		if age, err = time.ParseDuration(maxAge); err != nil {
+			err = fmt.Errorf("invalid max age \"%s\": %s", maxAge, err)
+		}
+	}
+	return
+}
+
+// snapshotRetentionRule finds the longest prefix retention rule for snapshot
+func snapshotRetentionRule(rules []config.SnapshotRetention, snapshotName string) (ruleIndex int) {
+	ruleIndex = -1
+	for i, rule := range rules {
+		if strings.HasPrefix(snapshotName, rule.Prefix) {
+			if ruleIndex < 0 || len(rule.Prefix) > len(rules[ruleIndex].Prefix) {
+				ruleIndex = i
+			}
+		}
+	}
+	return
+}
+
+// PruneSnapshots deletes snapshots created by flexbot according to retention rules,
+// snapshots in keepSnapshots list are preserved
+func PruneSnapshots(nodeConfig *config.NodeConfig, keepSnapshots []string) (pruned []string, err error) {
+	pruned = []string{}
+	rules := nodeConfig.Storage.SnapshotRetention
+	if len(rules) == 0 {
+		return
+	}
+	errorFormat := "PruneSnapshots(): %s"
+	maxAges := make([]time.Duration, len(rules))
+	for i := range rules {
+		if maxAges[i], err = ParseSnapshotMaxAge(rules[i].MaxAge); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+	}
+	var c client.OntapClient
+	if c, err = client.NewOntapClient(nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	var snapshots []client.SnapshotInfo
+	if snapshots, err = c.SnapshotGetInfoList(nodeConfig.Storage.VolumeName); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	keep := make(map[string]bool)
+	for _, name := range keepSnapshots {
+		keep[name] = true
+	}
+	ruleSnapshots := make(map[int][]client.SnapshotInfo)
+	for _, snapshot := range snapshots {
+		if !IsFlexbotSnapshot(snapshot.Comment) || keep[snapshot.Name] {
+			continue
+		}
+		if i := snapshotRetentionRule(rules, snapshot.Name); i >= 0 {
+			ruleSnapshots[i] = append(ruleSnapshots[i], snapshot)
+		}
+	}
+	now := time.Now()
+	for i, snapshots := range ruleSnapshots {
+		sort.Slice(snapshots, func(j, k int) bool {
+			return snapshots[j].CreateTime.After(snapshots[k].CreateTime)
+		})
+		for j, snapshot := range snapshots {
+			if (rules[i].KeepLast > 0 && j >= rules[i].KeepLast) || (maxAges[i] > 0 && now.Sub(snapshot.CreateTime) > maxAges[i]) {
+				if err = c.SnapshotDelete(nodeConfig.Storage.VolumeName, snapshot.Name); err != nil {
+					err = fmt.Errorf(errorFormat, err)
+					return
+				}
+				pruned = append(pruned, snapshot.Name)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,101 @@
+package ontap
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+func TestRestoreSnapshot(t *testing.T) {
+	f := useFakeFactory(t)
+	cluster := f.Cluster(testCdotHost, testSvm)
+	nodeConfig := testNodeConfig(t, "node1")
+	content := testImageContent(1, 64*1024)
+	testCreateNodeStorage(t, nodeConfig, content)
+	if err := CreateSnapshot(nodeConfig, "snap1", "before upgrade"); err != nil {
+		t.Fatalf("CreateSnapshot() failure: %s", err)
+	}
+	metadata, err := GetSnapshotMetadata(nodeConfig, "snap1")
+	if err != nil || metadata == nil {
+		t.Fatalf("GetSnapshotMetadata() failure: %v", err)
+	}
+
+	// node storage is changed after snapshot
+	c, _ := f.NewClient(nodeConfig)
+	changed := testImageContent(7, 32*1024)
+	if err = c.LunUpload("/vol/node1_iboot/node1_iboot", bytes.NewReader(changed), int64(len(changed)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.LunDestroy("/vol/node1_iboot/node1_data"); err != nil {
+		t.Fatal(err)
+	}
+	if err = CreateSnapshot(nodeConfig, "snap2", ""); err != nil {
+		t.Fatalf("CreateSnapshot() failure: %s", err)
+	}
+	if !testHasDiscrepancy(t, nodeConfig, "lun /vol/node1_iboot/node1_data", "exists") {
+		t.Fatalf("missing data LUN is not reported")
+	}
+
+	// failed restore leaves storage as is
+	cluster.InjectFault("SnapshotRestore", errors.New("snapshot is busy"), 1, "")
+	if err = RestoreSnapshot(nodeConfig, "snap1"); err == nil || !strings.Contains(err.Error(), "SnapshotRestore() failure: snapshot is busy") {
+		t.Fatalf("expected SnapshotRestore() failure, got %v", err)
+	}
+	if data, _ := cluster.LunData("/vol/node1_iboot/node1_iboot"); !bytes.Equal(data, changed) {
+		t.Fatalf("boot LUN is changed by failed restore")
+	}
+	if snapshots, _ := GetSnapshots(nodeConfig); strings.Join(snapshots, ",") != "snap1,snap2" {
+		t.Fatalf("unexpected snapshots %v after failed restore", snapshots)
+	}
+
+	if err = RestoreSnapshot(nodeConfig, "snap1"); err != nil {
+		t.Fatalf("RestoreSnapshot() failure: %s", err)
+	}
+	if data, _ := cluster.LunData("/vol/node1_iboot/node1_iboot"); !bytes.Equal(data, content) {
+		t.Fatalf("boot LUN is not restored")
+	}
+	// restored LUN's are not mapped until mapping is restored
+	if !testHasDiscrepancy(t, nodeConfig, "lun /vol/node1_iboot/node1_data", "mapping") {
+		t.Fatalf("unmapped data LUN is not reported")
+	}
+	if err = LunRestoreMapping(nodeConfig); err != nil {
+		t.Fatalf("LunRestoreMapping() failure: %s", err)
+	}
+	testVerifyClean(t, nodeConfig)
+	if snapshots, _ := GetSnapshots(nodeConfig); strings.Join(snapshots, ",") != "snap1" {
+		t.Fatalf("unexpected snapshots %v after restore", snapshots)
+	}
+	if err = RestoreSnapshot(nodeConfig, "snap2"); err == nil {
+		t.Fatalf("expected failure to restore deleted snapshot")
+	}
+}
+
+func TestCreateGroupSnapshotRollback(t *testing.T) {
+	f := useFakeFactory(t)
+	cluster := f.Cluster(testCdotHost, testSvm)
+	node1 := testNodeConfig(t, "node1")
+	node2 := testNodeConfig(t, "node2")
+	testCreateNodeStorage(t, node1, testImageContent(1, 16*1024))
+	if err := CreateBootStorage(node2); err != nil {
+		t.Fatalf("CreateBootStorage() failure: %s", err)
+	}
+	cluster.InjectFault("SnapshotSetComment", errors.New("connection reset"), 1, "node2")
+	err := CreateGroupSnapshot([]*config.NodeConfig{node1, node2}, "group1", "")
+	if err == nil || !strings.Contains(err.Error(), "SnapshotSetComment() failure: connection reset") {
+		t.Fatalf("expected SnapshotSetComment() failure, got %v", err)
+	}
+	for _, nodeConfig := range []*config.NodeConfig{node1, node2} {
+		if exists, _ := SnapshotExists(nodeConfig, "group1"); exists {
+			t.Errorf("group snapshot is not removed from volume %s", nodeConfig.Storage.VolumeName)
+		}
+	}
+	if err = CreateGroupSnapshot([]*config.NodeConfig{node1, node2}, "group1", ""); err != nil {
+		t.Fatalf("CreateGroupSnapshot() failure: %s", err)
+	}
+	if err = RestoreGroupSnapshot([]*config.NodeConfig{node1, node2}, "group1"); err != nil {
+		t.Fatalf("RestoreGroupSnapshot() failure: %s", err)
+	}
+}
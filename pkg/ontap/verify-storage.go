@@ -0,0 +1,222 @@
+package ontap
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
+)
+
+// StorageDiscrepancy is a difference between node storage configuration and cDOT
+type StorageDiscrepancy struct {
+	Object   string `yaml:"object" json:"object"`
+	Property string `yaml:"property" json:"property"`
+	Expected string `yaml:"expected" json:"expected"`
+	Actual   string `yaml:"actual" json:"actual"`
+}
+
+// String formats discrepancy as a single line
+func (d StorageDiscrepancy) String() string {
+	return fmt.Sprintf("%s: %s expected \"%s\", actual \"%s\"", d.Object, d.Property, d.Expected, d.Actual)
+}
+
+// VerifyBootStorage deeply verifies node storage in cDOT against node configuration:
+// iGroup initiators, LUN's sizes and mappings, NVME namespace and subsystem hosts, seed LUN template
+func VerifyBootStorage(nodeConfig *config.NodeConfig) (discrepancies []StorageDiscrepancy, err error) {
+	discrepancies = []StorageDiscrepancy{}
+	var c client.OntapClient
+	errorFormat := "VerifyBootStorage(): %s"
+	if c, err = client.NewOntapClient(nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	var volumeExists bool
+	if volumeExists, err = c.VolumeExists(nodeConfig.Storage.VolumeName); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	if !volumeExists {
+		discrepancies = append(discrepancies, StorageDiscrepancy{Object: "volume " + nodeConfig.Storage.VolumeName, Property: "exists", Expected: "true", Actual: "false"})
+		return
+	}
+	if discrepancies, err = verifyIgroup(c, nodeConfig, discrepancies); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	luns := []struct {
+		name string
+		id   int
+		size int
+	}{
+		{nodeConfig.Storage.BootLun.Name, nodeConfig.Storage.BootLun.Id, nodeConfig.Storage.BootLun.Size},
+		{nodeConfig.Storage.SeedLun.Name, nodeConfig.Storage.SeedLun.Id, 0},
+	}
+	if nodeConfig.Storage.DataLun.Size > 0 {
+		luns = append(luns, struct {
+			name string
+			id   int
+			size int
+		}{nodeConfig.Storage.DataLun.Name, nodeConfig.Storage.DataLun.Id, nodeConfig.Storage.DataLun.Size})
+	}
+	for _, lun := range luns {
+		var lunInfo *client.LunInfo
+		if lunInfo, discrepancies, err = verifyLun(c, nodeConfig, "/vol/"+nodeConfig.Storage.VolumeName+"/"+lun.name, lun.id, lun.size, discrepancies); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+		if lunInfo != nil && lun.name == nodeConfig.Storage.SeedLun.Name && nodeConfig.Storage.SeedLun.SeedTemplate.Location != "" && lunInfo.Comment != nodeConfig.Storage.SeedLun.SeedTemplate.Location {
+			discrepancies = append(discrepancies, StorageDiscrepancy{Object: "lun " + lunInfo.Path, Property: "seedTemplate", Expected: nodeConfig.Storage.SeedLun.SeedTemplate.Location, Actual: lunInfo.Comment})
+		}
+	}
+	if discrepancies, err = verifyNvmeStorage(c, nodeConfig, discrepancies); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+	}
+	return
+}
+
+// verifyIgroup verifies that iGroup has exactly the node iSCSI and FC initiators
+func verifyIgroup(c client.OntapClient, nodeConfig *config.NodeConfig, in []StorageDiscrepancy) (discrepancies []StorageDiscrepancy, err error) {
+	discrepancies = in
+	object := "igroup " + nodeConfig.Storage.IgroupName
+	var igroupExists bool
+	if igroupExists, err = c.IgroupExists(nodeConfig.Storage.IgroupName); err != nil {
+		return
+	}
+	if !igroupExists {
+		discrepancies = append(discrepancies, StorageDiscrepancy{Object: object, Property: "exists", Expected: "true", Actual: "false"})
+		return
+	}
+	var expected []string
+	for i := range nodeConfig.Network.IscsiInitiator {
+		if nodeConfig.Network.IscsiInitiator[i].InitiatorName != "" {
+			expected = append(expected, strings.ToLower(nodeConfig.Network.IscsiInitiator[i].InitiatorName))
+		}
+	}
+	for i := range nodeConfig.Network.FcInitiator {
+		if nodeConfig.Network.FcInitiator[i].Wwpn != "" {
+			expected = append(expected, strings.ToLower(nodeConfig.Network.FcInitiator[i].Wwpn))
+		}
+	}
+	var initiators []string
+	if initiators, err = c.IgroupGetInitiators(nodeConfig.Storage.IgroupName); err != nil {
+		return
+	}
+	var actual []string
+	for _, initiator := range initiators {
+		actual = append(actual, strings.ToLower(initiator))
+	}
+	sort.Strings(expected)
+	sort.Strings(actual)
+	if strings.Join(expected, ",") != strings.Join(actual, ",") {
+		discrepancies = append(discrepancies, StorageDiscrepancy{Object: object, Property: "initiators", Expected: strings.Join(expected, ","), Actual: strings.Join(actual, ",")})
+	}
+	return
+}
+
+// verifyLun verifies LUN existence, size (if not zero) and the only mapping to node iGroup with expected LUN ID
+func verifyLun(c client.OntapClient, nodeConfig *config.NodeConfig, lunPath string, lunID int, lunSize int, in []StorageDiscrepancy) (lunInfo *client.LunInfo, discrepancies []StorageDiscrepancy, err error) {
+	discrepancies = in
+	object := "lun " + lunPath
+	var lunExists bool
+	if lunExists, err = c.LunExists(lunPath); err != nil {
+		return
+	}
+	if !lunExists {
+		discrepancies = append(discrepancies, StorageDiscrepancy{Object: object, Property: "exists", Expected: "true", Actual: "false"})
+		return
+	}
+	if lunInfo, err = c.LunGetInfo(lunPath); err != nil {
+		return
+	}
+	lunInfo.Path = lunPath
+	if lunSize > 0 && lunInfo.Size != lunSize {
+		discrepancies = append(discrepancies, StorageDiscrepancy{Object: object, Property: "size", Expected: strconv.Itoa(lunSize), Actual: strconv.Itoa(lunInfo.Size)})
+	}
+	var lunMaps []client.LunMapInfo
+	if lunMaps, err = c.LunGetMaps(lunPath); err != nil {
+		return
+	}
+	var actual []string
+	for _, lunMap := range lunMaps {
+		actual = append(actual, lunMap.IgroupName+":"+strconv.Itoa(lunMap.LunId))
+	}
+	sort.Strings(actual)
+	expected := nodeConfig.Storage.IgroupName + ":" + strconv.Itoa(lunID)
+	if len(actual) != 1 || actual[0] != expected {
+		discrepancies = append(discrepancies, StorageDiscrepancy{Object: object, Property: "mapping", Expected: expected, Actual: strings.Join(actual, ",")})
+	}
+	return
+}
+
+// verifyNvmeStorage verifies NVME namespace size and mapping, and subsystem hosts (REST API only)
+func verifyNvmeStorage(c client.OntapClient, nodeConfig *config.NodeConfig, in []StorageDiscrepancy) (discrepancies []StorageDiscrepancy, err error) {
+	discrepancies = in
+	if !(client.RestFeature(nodeConfig, client.FeatureNvme) && len(nodeConfig.Network.NvmeHost) > 0 && nodeConfig.Storage.DataNvme.Size > 0) {
+		return
+	}
+	namespacePath := "/vol/" + nodeConfig.Storage.VolumeName + "/" + nodeConfig.Storage.DataNvme.Namespace
+	object := "namespace " + namespacePath
+	var namespaceExists bool
+	if namespaceExists, err = c.NvmeNamespaceExists(namespacePath); err != nil {
+		return
+	}
+	if !namespaceExists {
+		discrepancies = append(discrepancies, StorageDiscrepancy{Object: object, Property: "exists", Expected: "true", Actual: "false"})
+		return
+	}
+	var namespaceInfo *client.NvmeNamespaceInfo
+	if namespaceInfo, err = c.NvmeNamespaceGetInfo(namespacePath); err != nil {
+		return
+	}
+	if namespaceInfo.Size != nodeConfig.Storage.DataNvme.Size {
+		discrepancies = append(discrepancies, StorageDiscrepancy{Object: object, Property: "size", Expected: strconv.Itoa(nodeConfig.Storage.DataNvme.Size), Actual: strconv.Itoa(namespaceInfo.Size)})
+	}
+	var namespaceMapped bool
+	if namespaceMapped, err = c.IsNvmeNamespaceMapped(namespacePath); err != nil {
+		return
+	}
+	if !namespaceMapped {
+		discrepancies = append(discrepancies, StorageDiscrepancy{Object: object, Property: "mapped", Expected: "true", Actual: "false"})
+	}
+	var subsystemExists bool
+	if subsystemExists, err = c.NvmeSubsystemExists(nodeConfig.Storage.DataNvme.Subsystem); err != nil {
+		return
+	}
+	object = "subsystem " + nodeConfig.Storage.DataNvme.Subsystem
+	if !subsystemExists {
+		discrepancies = append(discrepancies, StorageDiscrepancy{Object: object, Property: "exists", Expected: "true", Actual: "false"})
+		return
+	}
+	var hostNqns []string
+	if hostNqns, err = c.NvmeSubsystemGetHosts(nodeConfig.Storage.DataNvme.Subsystem); err != nil {
+		return
+	}
+	var expected []string
+	for i := range nodeConfig.Network.NvmeHost {
+		if nodeConfig.Network.NvmeHost[i].HostNqn != "" {
+			expected = append(expected, nodeConfig.Network.NvmeHost[i].HostNqn)
+		}
+	}
+	expected = uniqueSorted(expected)
+	actual := uniqueSorted(hostNqns)
+	if strings.Join(expected, ",") != strings.Join(actual, ",") {
+		discrepancies = append(discrepancies, StorageDiscrepancy{Object: object, Property: "hosts", Expected: strings.Join(expected, ","), Actual: strings.Join(actual, ",")})
+	}
+	return
+}
+
+// uniqueSorted returns sorted list without duplicates
+func uniqueSorted(list []string) (result []string) {
+	seen := make(map[string]bool)
+	for _, item := range list {
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	sort.Strings(result)
+	return
+}
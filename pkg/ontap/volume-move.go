@@ -0,0 +1,115 @@
+package ontap
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
+)
+
+const (
+	// VolumeMovePolicyMaxAvailable picks aggregate with maximum space available
+	VolumeMovePolicyMaxAvailable = "max_available"
+	volumeMovePollInterval       = 15
+)
+
+// volumeMoveFailed checks if volume move state (REST state or ZAPI phase) is final failure
+func volumeMoveFailed(state string) bool {
+	return state == "failed" || state == "aborted"
+}
+
+// volumeMoveDone checks if volume move state (REST state or ZAPI phase) is final success
+func volumeMoveDone(state string) bool {
+	return state == "success" || state == "completed"
+}
+
+// GetVolumeMoveAggregate picks destination aggregate for node volume move,
+// explicit aggregate name takes precedence over aggregate selection policy
+func GetVolumeMoveAggregate(nodeConfig *config.NodeConfig, aggregateName string, policy string) (destAggregate string, err error) {
+	if aggregateName != "" {
+		destAggregate = aggregateName
+		return
+	}
+	var c client.OntapClient
+	if c, err = client.NewOntapClient(nodeConfig); err != nil {
+		err = fmt.Errorf("GetVolumeMoveAggregate(): %s", err)
+		return
+	}
+	switch policy {
+	case VolumeMovePolicyMaxAvailable, "":
+		if destAggregate, err = c.GetAggregateMax(nodeConfig); err != nil {
+			err = fmt.Errorf("GetVolumeMoveAggregate(): %s", err)
+		}
+	default:
+		err = fmt.Errorf("GetVolumeMoveAggregate(): unsupported aggregate selection policy \"%s\"", policy)
+	}
+	return
+}
+
+// MoveBootStorage moves node volume to another aggregate online and waits for the move to complete,
+// progress callback is called on every status poll
+func MoveBootStorage(nodeConfig *config.NodeConfig, aggregateName string, timeout time.Duration, progress func(client.VolumeMoveInfo)) (err error) {
+	var c client.OntapClient
+	errorFormat := "MoveBootStorage(): %s"
+	if c, err = client.NewOntapClient(nodeConfig); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	var currentAggregate string
+	if currentAggregate, err = c.VolumeGetAggregate(nodeConfig.Storage.VolumeName); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	if currentAggregate == aggregateName {
+		return
+	}
+	var moveInfo *client.VolumeMoveInfo
+	if moveInfo, err = c.VolumeMoveGetStatus(nodeConfig.Storage.VolumeName); err != nil {
+		err = fmt.Errorf(errorFormat, err)
+		return
+	}
+	if moveInfo != nil && !volumeMoveDone(moveInfo.State) && !volumeMoveFailed(moveInfo.State) {
+		if moveInfo.DestinationAggregate != aggregateName {
+			err = fmt.Errorf("MoveBootStorage(): volume \"%s\" move to aggregate \"%s\" is already in progress", nodeConfig.Storage.VolumeName, moveInfo.DestinationAggregate)
+			return
+		}
+	} else {
+		if err = c.VolumeMoveStart(nodeConfig.Storage.VolumeName, aggregateName); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+	}
+	giveupTime := time.Now().Add(timeout)
+	for {
+		time.Sleep(volumeMovePollInterval * time.Second)
+		if moveInfo, err = c.VolumeMoveGetStatus(nodeConfig.Storage.VolumeName); err != nil {
+			err = fmt.Errorf(errorFormat, err)
+			return
+		}
+		// status of previous move may be reported until the new move is registered
+		if moveInfo != nil && moveInfo.DestinationAggregate == aggregateName {
+			if progress != nil {
+				progress(*moveInfo)
+			}
+			if volumeMoveFailed(moveInfo.State) {
+				err = fmt.Errorf("MoveBootStorage(): volume \"%s\" move to aggregate \"%s\" failed: %s", nodeConfig.Storage.VolumeName, aggregateName, moveInfo.Details)
+				return
+			}
+			if volumeMoveDone(moveInfo.State) {
+				if currentAggregate, err = c.VolumeGetAggregate(nodeConfig.Storage.VolumeName); err != nil {
+					err = fmt.Errorf(errorFormat, err)
+					return
+				}
+				if currentAggregate == aggregateName {
+					nodeConfig.Storage.Aggregate = currentAggregate
+					return
+				}
+			}
+		}
+		if time.Now().After(giveupTime) {
+			err = fmt.Errorf("MoveBootStorage(): timeout waiting for volume \"%s\" move to aggregate \"%s\"", nodeConfig.Storage.VolumeName, aggregateName)
+			return
+		}
+	}
+}
@@ -180,9 +180,10 @@ func (node *Rke2Node) RancherAPINodeSetAnnotationsLabelsTaints() (err error) {
 		annotations := make(map[string]string)
 		if len(node.NodeConfig.Compute.SpDn) > 0 && len(node.NodeConfig.Compute.BladeAssigned.Dn) > 0 {
 			computeAnnotations := config.ComputeAnnotations{
-				UcsmHost: node.NodeConfig.Compute.UcsmCredentials.Host,
-				SpDn:     node.NodeConfig.Compute.SpDn,
-				Blade:    node.NodeConfig.Compute.BladeAssigned,
+				UcsmHost:  node.NodeConfig.Compute.UcsmCredentials.Host,
+				SpDn:      node.NodeConfig.Compute.SpDn,
+				Blade:     node.NodeConfig.Compute.BladeAssigned,
+				Placement: node.NodeConfig.Compute.Placement,
 			}
 			if computeB, err = json.Marshal(computeAnnotations); err != nil {
 				err = fmt.Errorf("json.Marshal(computeAnnotations): %s", err)
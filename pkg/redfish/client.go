@@ -0,0 +1,267 @@
+package redfish
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+const (
+	httpTimeout = 120
+)
+
+// Client is Redfish service client with basic authentication
+type Client struct {
+	endPoint   string
+	user       string
+	password   string
+	httpClient *http.Client
+}
+
+// OdataId is Redfish resource reference
+type OdataId struct {
+	Id string `json:"@odata.id"`
+}
+
+// Collection is Redfish resource collection
+type Collection struct {
+	Members []OdataId `json:"Members"`
+}
+
+// Status is Redfish resource status
+type Status struct {
+	State  string `json:"State,omitempty"`
+	Health string `json:"Health,omitempty"`
+}
+
+// Boot is ComputerSystem boot override settings
+type Boot struct {
+	BootSourceOverrideEnabled    string   `json:"BootSourceOverrideEnabled,omitempty"`
+	BootSourceOverrideTarget     string   `json:"BootSourceOverrideTarget,omitempty"`
+	BootSourceOverrideMode       string   `json:"BootSourceOverrideMode,omitempty"`
+	UefiTargetBootSourceOverride string   `json:"UefiTargetBootSourceOverride,omitempty"`
+	BootOptions                  *OdataId `json:"BootOptions,omitempty"`
+}
+
+// ComputerSystem is Redfish ComputerSystem resource
+type ComputerSystem struct {
+	OdataId
+	Id               string `json:"Id"`
+	Name             string `json:"Name"`
+	Model            string `json:"Model"`
+	SerialNumber     string `json:"SerialNumber"`
+	UUID             string `json:"UUID"`
+	AssetTag         string `json:"AssetTag"`
+	PowerState       string `json:"PowerState"`
+	Status           Status `json:"Status"`
+	Boot             Boot   `json:"Boot"`
+	ProcessorSummary struct {
+		Count                 int `json:"Count"`
+		CoreCount             int `json:"CoreCount"`
+		LogicalProcessorCount int `json:"LogicalProcessorCount"`
+	} `json:"ProcessorSummary"`
+	MemorySummary struct {
+		TotalSystemMemoryGiB float64 `json:"TotalSystemMemoryGiB"`
+	} `json:"MemorySummary"`
+	EthernetInterfaces OdataId `json:"EthernetInterfaces"`
+	Actions            struct {
+		Reset struct {
+			Target string `json:"target"`
+		} `json:"#ComputerSystem.Reset"`
+	} `json:"Actions"`
+	Links struct {
+		Chassis []OdataId `json:"Chassis"`
+	} `json:"Links"`
+}
+
+// EthernetInterface is Redfish EthernetInterface resource
+type EthernetInterface struct {
+	OdataId
+	Id                  string `json:"Id"`
+	Name                string `json:"Name"`
+	MACAddress          string `json:"MACAddress"`
+	PermanentMACAddress string `json:"PermanentMACAddress"`
+}
+
+// Chassis is Redfish Chassis resource
+type Chassis struct {
+	OdataId
+	Id              string  `json:"Id"`
+	NetworkAdapters OdataId `json:"NetworkAdapters"`
+}
+
+// NetworkAdapter is Redfish NetworkAdapter resource
+type NetworkAdapter struct {
+	OdataId
+	Id                     string  `json:"Id"`
+	NetworkDeviceFunctions OdataId `json:"NetworkDeviceFunctions"`
+}
+
+// IscsiBoot is NetworkDeviceFunction iSCSI boot settings
+type IscsiBoot struct {
+	IPAddressType            string `json:"IPAddressType,omitempty"`
+	InitiatorIPAddress       string `json:"InitiatorIPAddress"`
+	InitiatorName            string `json:"InitiatorName"`
+	InitiatorDefaultGateway  string `json:"InitiatorDefaultGateway"`
+	InitiatorNetmask         string `json:"InitiatorNetmask"`
+	TargetInfoViaDHCP        bool   `json:"TargetInfoViaDHCP"`
+	PrimaryTargetName        string `json:"PrimaryTargetName"`
+	PrimaryTargetIPAddress   string `json:"PrimaryTargetIPAddress"`
+	PrimaryTargetTCPPort     int    `json:"PrimaryTargetTCPPort,omitempty"`
+	PrimaryLUN               int    `json:"PrimaryLUN"`
+	SecondaryTargetName      string `json:"SecondaryTargetName"`
+	SecondaryTargetIPAddress string `json:"SecondaryTargetIPAddress"`
+	SecondaryTargetTCPPort   int    `json:"SecondaryTargetTCPPort,omitempty"`
+	SecondaryLUN             int    `json:"SecondaryLUN"`
+	PrimaryDNS               string `json:"PrimaryDNS"`
+	SecondaryDNS             string `json:"SecondaryDNS"`
+	AuthenticationMethod     string `json:"AuthenticationMethod,omitempty"`
+	CHAPUsername             string `json:"CHAPUsername,omitempty"`
+	CHAPSecret               string `json:"CHAPSecret,omitempty"`
+	MutualCHAPUsername       string `json:"MutualCHAPUsername,omitempty"`
+	MutualCHAPSecret         string `json:"MutualCHAPSecret,omitempty"`
+}
+
+// NetworkDeviceFunction is Redfish NetworkDeviceFunction resource
+type NetworkDeviceFunction struct {
+	OdataId
+	Id             string `json:"Id"`
+	Name           string `json:"Name"`
+	NetDevFuncType string `json:"NetDevFuncType"`
+	BootMode       string `json:"BootMode"`
+	Ethernet       struct {
+		MACAddress string `json:"MACAddress"`
+	} `json:"Ethernet"`
+	IscsiBoot *IscsiBoot `json:"iSCSIBoot,omitempty"`
+}
+
+// BootOption is Redfish BootOption resource
+type BootOption struct {
+	OdataId
+	Id                  string `json:"Id"`
+	DisplayName         string `json:"DisplayName"`
+	Alias               string `json:"Alias"`
+	BootOptionReference string `json:"BootOptionReference"`
+	UefiDevicePath      string `json:"UefiDevicePath"`
+}
+
+// NewClient creates Redfish client, host may include scheme (defaults to https)
+func NewClient(credentials config.Credentials) (client *Client) {
+	endPoint := strings.TrimRight(credentials.Host, "/")
+	if !strings.HasPrefix(endPoint, "http://") && !strings.HasPrefix(endPoint, "https://") {
+		endPoint = "https://" + endPoint
+	}
+	client = &Client{
+		endPoint: endPoint,
+		user:     credentials.User,
+		password: credentials.Password,
+		httpClient: &http.Client{
+			Timeout: time.Duration(httpTimeout) * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+	}
+	return
+}
+
+// do sends request to Redfish service and decodes response into out if not nil
+func (c *Client) do(method string, path string, etag string, in interface{}, out interface{}) (respEtag string, err error) {
+	var body []byte
+	var req *http.Request
+	var resp *http.Response
+	if in != nil {
+		if body, err = json.Marshal(in); err != nil {
+			return
+		}
+	}
+	if req, err = http.NewRequest(method, c.endPoint+path, bytes.NewReader(body)); err != nil {
+		return
+	}
+	req.SetBasicAuth(c.user, c.password)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+	if resp, err = c.httpClient.Do(req); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if body, err = ioutil.ReadAll(resp.Body); err != nil {
+		return
+	}
+	if resp.StatusCode >= 300 {
+		err = fmt.Errorf("%s %s: HTTP status %d: %s", method, path, resp.StatusCode, extendedError(body))
+		return
+	}
+	respEtag = resp.Header.Get("ETag")
+	if out != nil && len(body) > 0 {
+		err = json.Unmarshal(body, out)
+	}
+	return
+}
+
+// extendedError extracts message from Redfish error response
+func extendedError(body []byte) string {
+	var redfishError struct {
+		Error struct {
+			Message      string `json:"message"`
+			ExtendedInfo []struct {
+				Message string `json:"Message"`
+			} `json:"@Message.ExtendedInfo"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &redfishError) == nil {
+		if len(redfishError.Error.ExtendedInfo) > 0 && redfishError.Error.ExtendedInfo[0].Message != "" {
+			return redfishError.Error.ExtendedInfo[0].Message
+		}
+		if redfishError.Error.Message != "" {
+			return redfishError.Error.Message
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// Get retrieves Redfish resource
+func (c *Client) Get(path string, out interface{}) (err error) {
+	_, err = c.do("GET", path, "", nil, out)
+	return
+}
+
+// Patch updates Redfish resource, ETag is passed in If-Match if service provides it
+func (c *Client) Patch(path string, in interface{}) (err error) {
+	var etag string
+	if etag, err = c.do("GET", path, "", nil, nil); err != nil {
+		return
+	}
+	_, err = c.do("PATCH", path, etag, in, nil)
+	return
+}
+
+// Post invokes Redfish action
+func (c *Client) Post(path string, in interface{}) (err error) {
+	_, err = c.do("POST", path, "", in, nil)
+	return
+}
+
+// GetCollection retrieves members of Redfish resource collection
+func (c *Client) GetCollection(path string) (members []string, err error) {
+	var collection Collection
+	if err = c.Get(path, &collection); err != nil {
+		return
+	}
+	for _, member := range collection.Members {
+		members = append(members, member.Id)
+	}
+	return
+}
@@ -0,0 +1,329 @@
+// Package fake is in-memory Redfish service stand-in for offline testing of pkg/redfish
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	SystemPath  = "/redfish/v1/Systems/1"
+	ChassisPath = "/redfish/v1/Chassis/1"
+	AdapterPath = ChassisPath + "/NetworkAdapters/1"
+	// FunctionsPath is collection of network device functions "iscsi0" and "iscsi1" of the system adapter
+	FunctionsPath = AdapterPath + "/NetworkDeviceFunctions"
+	// IscsiDevicePath is UEFI device path of iSCSI boot option
+	IscsiDevicePath = "PciRoot(0x0)/Pci(0x1,0x0)/Pci(0x0,0x0)/MAC(0025B5000A01,0x1)/IPv4(0.0.0.0)/iSCSI(iqn,0x1,0x0,None,None,None,TCP)"
+)
+
+// Server is Redfish BMC stand-in with single ComputerSystem, one chassis network adapter and boot options,
+// it serves endpoints used by pkg/redfish over TLS, use URL as redfishCredentials.host.
+// PATCH requires If-Match of the current resource ETag if the request has If-Match header
+type Server struct {
+	*httptest.Server
+	// User and Password enable basic authentication if set
+	User     string
+	Password string
+	// PowerTransitionPolls is number of system reads the system reports "PoweringOn" or "PoweringOff" after reset
+	PowerTransitionPolls int
+	mu                   sync.Mutex
+	resources            map[string]record
+	writable             map[string][]string
+	etags                map[string]int
+	faults               map[string]*fault
+	pendingPowerState    string
+	pendingPolls         int
+	calls                []string
+}
+
+type record map[string]interface{}
+
+type fault struct {
+	status  int
+	message string
+}
+
+// NewServer starts Redfish service stand-in with powered on system
+func NewServer() (s *Server) {
+	s = &Server{
+		resources: make(map[string]record),
+		writable:  make(map[string][]string),
+		etags:     make(map[string]int),
+		faults:    make(map[string]*fault),
+	}
+	s.add(SystemPath, record{
+		"Id":           "1",
+		"Name":         "System",
+		"Model":        "UCSC-C220-M5SX",
+		"SerialNumber": "WZP2248003A",
+		"UUID":         "4C4C4544-0042-4E10-8057-B7C04F4A3332",
+		"AssetTag":     "",
+		"PowerState":   "On",
+		"Status":       record{"State": "Enabled", "Health": "OK"},
+		"Boot": record{
+			"BootSourceOverrideEnabled": "Disabled",
+			"BootOptions":               ref(SystemPath + "/BootOptions"),
+		},
+		"ProcessorSummary":   record{"Count": 2, "CoreCount": 40, "LogicalProcessorCount": 80},
+		"MemorySummary":      record{"TotalSystemMemoryGiB": 384},
+		"EthernetInterfaces": ref(SystemPath + "/EthernetInterfaces"),
+		"Actions": record{
+			"#ComputerSystem.Reset": record{"target": SystemPath + "/Actions/ComputerSystem.Reset"},
+		},
+		"Links": record{"Chassis": []interface{}{ref(ChassisPath)}},
+	}, "AssetTag", "Boot")
+	s.add("/redfish/v1/Systems", collection(SystemPath))
+	s.add(SystemPath+"/EthernetInterfaces", collection(SystemPath+"/EthernetInterfaces/eth0", SystemPath+"/EthernetInterfaces/eth1"))
+	s.add(SystemPath+"/EthernetInterfaces/eth0", record{"Id": "eth0", "Name": "eth0", "MACAddress": "00:25:B5:00:0B:01", "PermanentMACAddress": "00:25:B5:00:0B:01"})
+	s.add(SystemPath+"/EthernetInterfaces/eth1", record{"Id": "eth1", "Name": "eth1", "MACAddress": "", "PermanentMACAddress": "00:25:B5:00:0B:02"})
+	s.add(ChassisPath, record{"Id": "1", "NetworkAdapters": ref(ChassisPath + "/NetworkAdapters")})
+	s.add(ChassisPath+"/NetworkAdapters", collection(AdapterPath))
+	s.add(AdapterPath, record{"Id": "1", "NetworkDeviceFunctions": ref(FunctionsPath)})
+	s.add(FunctionsPath, collection(FunctionsPath+"/1", FunctionsPath+"/2"))
+	s.add(FunctionsPath+"/1", record{"Id": "1", "Name": "iscsi0", "NetDevFuncType": "iSCSI", "BootMode": "Disabled", "Ethernet": record{"MACAddress": "00:25:B5:00:0A:01"}}, "BootMode", "iSCSIBoot")
+	s.add(FunctionsPath+"/2", record{"Id": "2", "Name": "iscsi1", "NetDevFuncType": "iSCSI", "BootMode": "Disabled", "Ethernet": record{"MACAddress": "00:25:B5:00:0A:02"}}, "BootMode", "iSCSIBoot")
+	s.add(SystemPath+"/BootOptions", collection(SystemPath+"/BootOptions/1", SystemPath+"/BootOptions/2"))
+	s.add(SystemPath+"/BootOptions/1", record{"Id": "1", "DisplayName": "UEFI PXE: IPv4 00:25:B5:00:0B:01", "BootOptionReference": "Boot0001", "UefiDevicePath": "PciRoot(0x0)/Pci(0x1,0x0)/MAC(0025B5000B01,0x1)/IPv4(0.0.0.0)"})
+	s.add(SystemPath+"/BootOptions/2", record{"Id": "2", "DisplayName": "UEFI iSCSI: iscsi0", "BootOptionReference": "Boot0002", "UefiDevicePath": IscsiDevicePath})
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serveHTTP))
+	return
+}
+
+// ref makes resource reference
+func ref(path string) record {
+	return record{"@odata.id": path}
+}
+
+// collection makes resource collection of members
+func collection(members ...string) record {
+	refs := []interface{}{}
+	for _, member := range members {
+		refs = append(refs, ref(member))
+	}
+	return record{"Members": refs, "Members@odata.count": len(refs)}
+}
+
+// add adds resource with writable properties
+func (s *Server) add(path string, res record, writable ...string) {
+	res["@odata.id"] = path
+	s.resources[path] = res
+	s.writable[path] = writable
+	s.etags[path] = 1
+}
+
+// Calls gets log of mutating calls in "<method> <path>" format
+func (s *Server) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.calls...)
+}
+
+// Resource decodes resource into out, i.e. redfish.ComputerSystem
+func (s *Server) Resource(path string, out interface{}) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res, ok := s.resources[path]
+	if !ok {
+		return fmt.Errorf("resource %s not found", path)
+	}
+	var b []byte
+	if b, err = json.Marshal(res); err == nil {
+		err = json.Unmarshal(b, out)
+	}
+	return
+}
+
+// Update sets resource properties bypassing writable check, nil value removes property
+func (s *Server) Update(path string, properties map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res, ok := s.resources[path]
+	if !ok {
+		res = record{"@odata.id": path}
+		s.resources[path] = res
+	}
+	for name, value := range properties {
+		if value == nil {
+			delete(res, name)
+		} else {
+			res[name] = value
+		}
+	}
+	s.etags[path]++
+}
+
+// AddSystem adds another system to systems collection
+func (s *Server) AddSystem(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := "/redfish/v1/Systems/" + id
+	s.add(path, record{"Id": id, "PowerState": "Off"}, "AssetTag", "Boot")
+	systems := s.resources["/redfish/v1/Systems"]
+	systems["Members"] = append(systems["Members"].([]interface{}), ref(path))
+}
+
+// Fail makes the next request of method to path fail with HTTP status and Redfish error message
+func (s *Server) Fail(method string, path string, status int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[method+" "+path] = &fault{status: status, message: message}
+}
+
+// reply writes JSON response
+func reply(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if body != nil {
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+// replyError writes Redfish error with extended info
+func replyError(w http.ResponseWriter, status int, code string, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	reply(w, status, record{"error": record{
+		"code":                  "Base.1.8." + code,
+		"message":               "See @Message.ExtendedInfo for more information.",
+		"@Message.ExtendedInfo": []interface{}{record{"MessageId": "Base.1.8." + code, "Message": message}},
+	}})
+}
+
+// merge merges patch into resource, nested objects are merged recursively
+func merge(res record, patch map[string]interface{}) {
+	for name, value := range patch {
+		if nested, ok := value.(map[string]interface{}); ok {
+			if current, ok := res[name].(record); ok {
+				merge(current, nested)
+				continue
+			}
+			if current, ok := res[name].(map[string]interface{}); ok {
+				merge(record(current), nested)
+				continue
+			}
+		}
+		res[name] = value
+	}
+}
+
+// etag formats resource ETag
+func (s *Server) etag(path string) string {
+	return "W/\"" + strconv.Itoa(s.etags[path]) + "\""
+}
+
+// advancePower completes pending power state transition once polls are exhausted
+func (s *Server) advancePower() {
+	if s.pendingPowerState == "" {
+		return
+	}
+	if s.pendingPolls > 0 {
+		s.pendingPolls--
+		return
+	}
+	s.resources[SystemPath]["PowerState"] = s.pendingPowerState
+	s.pendingPowerState = ""
+	s.etags[SystemPath]++
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.User != "" {
+		if user, password, ok := r.BasicAuth(); !ok || user != s.User || password != s.Password {
+			replyError(w, http.StatusUnauthorized, "InsufficientPrivilege", "There are insufficient privileges for the account or credentials associated with the current session to perform the requested operation.")
+			return
+		}
+	}
+	path := strings.TrimRight(r.URL.Path, "/")
+	if r.Method != "GET" {
+		s.calls = append(s.calls, r.Method+" "+path)
+	}
+	if f, ok := s.faults[r.Method+" "+path]; ok {
+		delete(s.faults, r.Method+" "+path)
+		replyError(w, f.status, "GeneralError", "%s", f.message)
+		return
+	}
+	var in map[string]interface{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &in); err != nil {
+			replyError(w, http.StatusBadRequest, "MalformedJSON", "The request body submitted was malformed JSON: %s", err)
+			return
+		}
+	}
+	if r.Method == "POST" && path == SystemPath+"/Actions/ComputerSystem.Reset" {
+		s.reset(w, in)
+		return
+	}
+	res, ok := s.resources[path]
+	if !ok {
+		replyError(w, http.StatusNotFound, "ResourceMissingAtURI", "The resource at the URI %s was not found.", path)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		if path == SystemPath {
+			s.advancePower()
+		}
+		w.Header().Set("ETag", s.etag(path))
+		reply(w, http.StatusOK, res)
+	case "PATCH":
+		if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != s.etag(path) {
+			replyError(w, http.StatusPreconditionFailed, "PreconditionFailed", "The ETag supplied did not match the ETag required to change this resource.")
+			return
+		}
+		var names []string
+		for name := range in {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			writable := false
+			for _, property := range s.writable[path] {
+				if property == name {
+					writable = true
+				}
+			}
+			if !writable {
+				replyError(w, http.StatusBadRequest, "PropertyNotWritable", "The property %s is a read only property and cannot be assigned a value.", name)
+				return
+			}
+		}
+		merge(res, in)
+		s.etags[path]++
+		w.Header().Set("ETag", s.etag(path))
+		reply(w, http.StatusOK, res)
+	default:
+		replyError(w, http.StatusMethodNotAllowed, "OperationNotAllowed", "The HTTP method %s is not allowed on %s.", r.Method, path)
+	}
+}
+
+// reset handles ComputerSystem.Reset action
+func (s *Server) reset(w http.ResponseWriter, in map[string]interface{}) {
+	system := s.resources[SystemPath]
+	var state, transition string
+	switch in["ResetType"] {
+	case "On", "ForceRestart", "GracefulRestart":
+		state, transition = "On", "PoweringOn"
+	case "ForceOff", "GracefulShutdown":
+		state, transition = "Off", "PoweringOff"
+	default:
+		replyError(w, http.StatusBadRequest, "ActionParameterValueNotInList", "The value %v for the parameter ResetType is not in the list of acceptable values.", in["ResetType"])
+		return
+	}
+	if s.PowerTransitionPolls > 0 {
+		system["PowerState"] = transition
+		s.pendingPowerState = state
+		s.pendingPolls = s.PowerTransitionPolls - 1
+	} else {
+		system["PowerState"] = state
+	}
+	s.etags[SystemPath]++
+	reply(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,14 @@
+package redfish
+
+import (
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+const (
+	planBackend = "redfish"
+)
+
+// planRecord records Redfish mutation in node configuration plan
+func planRecord(nodeConfig *config.NodeConfig, operation string, format string, args ...interface{}) {
+	nodeConfig.Plan.Record(planBackend, nodeConfig.Compute.RedfishCredentials.Host, operation, format, args...)
+}
@@ -0,0 +1,596 @@
+package redfish
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/igor-feoktistov/go-ucsm-sdk/util"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+const (
+	systemsPath  = "/redfish/v1/Systems"
+	powerWaitMax = 300
+)
+
+// powerPollInterval is wait time between system power state checks after reset
+var powerPollInterval = 5 * time.Second
+
+// getSystem retrieves ComputerSystem per systemId, the only system of BMC is used if systemId is not set
+func getSystem(client *Client, nodeConfig *config.NodeConfig) (system *ComputerSystem, err error) {
+	var systemPath string
+	if nodeConfig.Compute.SystemId == "" {
+		var members []string
+		if members, err = client.GetCollection(systemsPath); err != nil {
+			err = fmt.Errorf("getSystem(): %s", err)
+			return
+		}
+		if len(members) != 1 {
+			err = fmt.Errorf("getSystem(): expected one system in %s, found %d, systemId should be specified", systemsPath, len(members))
+			return
+		}
+		systemPath = members[0]
+	} else if strings.HasPrefix(nodeConfig.Compute.SystemId, "/") {
+		systemPath = nodeConfig.Compute.SystemId
+	} else {
+		systemPath = systemsPath + "/" + nodeConfig.Compute.SystemId
+	}
+	system = &ComputerSystem{}
+	if err = client.Get(systemPath, system); err != nil {
+		err = fmt.Errorf("getSystem(): %s", err)
+		return
+	}
+	if system.OdataId.Id == "" {
+		system.OdataId.Id = systemPath
+	}
+	return
+}
+
+// getEthernetInterfaces retrieves system ethernet interfaces
+func getEthernetInterfaces(client *Client, system *ComputerSystem) (ethernetInterfaces []EthernetInterface, err error) {
+	var members []string
+	if system.EthernetInterfaces.Id == "" {
+		return
+	}
+	if members, err = client.GetCollection(system.EthernetInterfaces.Id); err != nil {
+		err = fmt.Errorf("getEthernetInterfaces(): %s", err)
+		return
+	}
+	for _, member := range members {
+		var ethernetInterface EthernetInterface
+		if err = client.Get(member, &ethernetInterface); err != nil {
+			err = fmt.Errorf("getEthernetInterfaces(): %s", err)
+			return
+		}
+		ethernetInterfaces = append(ethernetInterfaces, ethernetInterface)
+	}
+	return
+}
+
+// getNetworkDeviceFunctions retrieves network device functions of system chassis adapters
+func getNetworkDeviceFunctions(client *Client, system *ComputerSystem) (functions []NetworkDeviceFunction, err error) {
+	for _, chassisRef := range system.Links.Chassis {
+		var chassis Chassis
+		var adapters []string
+		if err = client.Get(chassisRef.Id, &chassis); err != nil {
+			err = fmt.Errorf("getNetworkDeviceFunctions(): %s", err)
+			return
+		}
+		if chassis.NetworkAdapters.Id == "" {
+			continue
+		}
+		if adapters, err = client.GetCollection(chassis.NetworkAdapters.Id); err != nil {
+			err = fmt.Errorf("getNetworkDeviceFunctions(): %s", err)
+			return
+		}
+		for _, adapterPath := range adapters {
+			var adapter NetworkAdapter
+			var members []string
+			if err = client.Get(adapterPath, &adapter); err != nil {
+				err = fmt.Errorf("getNetworkDeviceFunctions(): %s", err)
+				return
+			}
+			if adapter.NetworkDeviceFunctions.Id == "" {
+				continue
+			}
+			if members, err = client.GetCollection(adapter.NetworkDeviceFunctions.Id); err != nil {
+				err = fmt.Errorf("getNetworkDeviceFunctions(): %s", err)
+				return
+			}
+			for _, member := range members {
+				var function NetworkDeviceFunction
+				if err = client.Get(member, &function); err != nil {
+					err = fmt.Errorf("getNetworkDeviceFunctions(): %s", err)
+					return
+				}
+				functions = append(functions, function)
+			}
+		}
+	}
+	return
+}
+
+// findNetworkDeviceFunction finds network device function by Id or Name
+func findNetworkDeviceFunction(functions []NetworkDeviceFunction, name string) *NetworkDeviceFunction {
+	for i := range functions {
+		if functions[i].Id == name || functions[i].Name == name {
+			return &functions[i]
+		}
+	}
+	return nil
+}
+
+// setInventory sets assigned server attributes from ComputerSystem
+func setInventory(system *ComputerSystem, nodeConfig *config.NodeConfig) {
+	nodeConfig.Compute.SpDn = system.OdataId.Id
+	nodeConfig.Compute.BladeSpec.Dn = system.OdataId.Id
+	nodeConfig.Compute.BladeAssigned = util.BladeSpec{
+		Dn:           system.OdataId.Id,
+		Model:        system.Model,
+		Serial:       system.SerialNumber,
+		NumOfCpus:    strconv.Itoa(system.ProcessorSummary.Count),
+		NumOfCores:   strconv.Itoa(system.ProcessorSummary.CoreCount),
+		NumOfThreads: strconv.Itoa(system.ProcessorSummary.LogicalProcessorCount),
+		TotalMemory:  strconv.Itoa(int(system.MemorySummary.TotalSystemMemoryGiB * 1024)),
+	}
+	if len(system.Links.Chassis) > 0 {
+		nodeConfig.Compute.ChassisId = system.Links.Chassis[0].Id
+	}
+	nodeConfig.Compute.Powerstate = powerState(system.PowerState)
+	for i := range nodeConfig.Network.NvmeHost {
+		nodeConfig.Network.NvmeHost[i].HostNqn = "nqn.2014-08.org.nvmexpress:uuid:" + strings.ToLower(system.UUID)
+	}
+}
+
+// setMacAddresses discovers MAC addresses of node and iSCSI interfaces
+func setMacAddresses(client *Client, system *ComputerSystem, nodeConfig *config.NodeConfig) (functions []NetworkDeviceFunction, err error) {
+	var ethernetInterfaces []EthernetInterface
+	if ethernetInterfaces, err = getEthernetInterfaces(client, system); err != nil {
+		return
+	}
+	for i := range nodeConfig.Network.Node {
+		var found int = 0
+		for _, ethernetInterface := range ethernetInterfaces {
+			if ethernetInterface.Id == nodeConfig.Network.Node[i].Name || ethernetInterface.Name == nodeConfig.Network.Node[i].Name {
+				nodeConfig.Network.Node[i].Macaddr = ethernetInterface.MACAddress
+				if nodeConfig.Network.Node[i].Macaddr == "" {
+					nodeConfig.Network.Node[i].Macaddr = ethernetInterface.PermanentMACAddress
+				}
+				found++
+			}
+		}
+		if found == 0 {
+			err = fmt.Errorf("setMacAddresses(): no ethernet interfaces found in system \"%s\" that match node interface \"%s\"", system.OdataId.Id, nodeConfig.Network.Node[i].Name)
+			return
+		}
+	}
+	if len(nodeConfig.Network.IscsiInitiator) == 0 {
+		return
+	}
+	if functions, err = getNetworkDeviceFunctions(client, system); err != nil {
+		return
+	}
+	for i := range nodeConfig.Network.IscsiInitiator {
+		if i > 1 {
+			break
+		}
+		function := findNetworkDeviceFunction(functions, nodeConfig.Network.IscsiInitiator[i].Name)
+		if function == nil {
+			err = fmt.Errorf("setMacAddresses(): no network device functions found in system \"%s\" that match iSCSI interface \"%s\"", system.OdataId.Id, nodeConfig.Network.IscsiInitiator[i].Name)
+			return
+		}
+		nodeConfig.Network.IscsiInitiator[i].Macaddr = function.Ethernet.MACAddress
+	}
+	return
+}
+
+// setIscsiBoot programs network device functions with iSCSI boot targets
+func setIscsiBoot(client *Client, system *ComputerSystem, functions []NetworkDeviceFunction, nodeConfig *config.NodeConfig) (err error) {
+	var ipv4Net *net.IPNet
+	for i := range nodeConfig.Network.IscsiInitiator {
+		if i > 1 {
+			break
+		}
+		initiator := nodeConfig.Network.IscsiInitiator[i]
+		function := findNetworkDeviceFunction(functions, initiator.Name)
+		if function == nil {
+			err = fmt.Errorf("setIscsiBoot(): no network device functions found in system \"%s\" that match iSCSI interface \"%s\"", system.OdataId.Id, initiator.Name)
+			return
+		}
+		if _, ipv4Net, err = net.ParseCIDR(initiator.Subnet); err != nil {
+			err = fmt.Errorf("setIscsiBoot(): ParseCIDR() failure for subnet %s: %s", initiator.Subnet, err)
+			return
+		}
+		iscsiBoot := IscsiBoot{
+			IPAddressType:           "IPv4",
+			InitiatorIPAddress:      initiator.Ip,
+			InitiatorName:           initiator.InitiatorName,
+			InitiatorDefaultGateway: initiator.Gateway,
+			InitiatorNetmask:        net.IPv4(ipv4Net.Mask[0], ipv4Net.Mask[1], ipv4Net.Mask[2], ipv4Net.Mask[3]).String(),
+			PrimaryDNS:              initiator.DnsServer1,
+			SecondaryDNS:            initiator.DnsServer2,
+			AuthenticationMethod:    "None",
+		}
+		if initiator.IscsiTarget != nil {
+			for j, targetInterface := range initiator.IscsiTarget.Interfaces {
+				if j == 0 {
+					iscsiBoot.PrimaryTargetName = initiator.IscsiTarget.NodeName
+					iscsiBoot.PrimaryTargetIPAddress = targetInterface
+					iscsiBoot.PrimaryTargetTCPPort = 3260
+				}
+				if j == 1 {
+					iscsiBoot.SecondaryTargetName = initiator.IscsiTarget.NodeName
+					iscsiBoot.SecondaryTargetIPAddress = targetInterface
+					iscsiBoot.SecondaryTargetTCPPort = 3260
+				}
+			}
+		}
+		if nodeConfig.Network.IscsiChap.User != "" {
+			iscsiBoot.AuthenticationMethod = "CHAP"
+			iscsiBoot.CHAPUsername = nodeConfig.Network.IscsiChap.User
+			iscsiBoot.CHAPSecret = nodeConfig.Network.IscsiChap.Password
+			if nodeConfig.Network.IscsiChap.TargetUser != "" {
+				iscsiBoot.AuthenticationMethod = "MutualCHAP"
+				iscsiBoot.MutualCHAPUsername = nodeConfig.Network.IscsiChap.TargetUser
+				iscsiBoot.MutualCHAPSecret = nodeConfig.Network.IscsiChap.TargetPassword
+			}
+		}
+		if nodeConfig.Plan != nil {
+			planRecord(nodeConfig, "SetIscsiBoot", "set system %s iSCSI boot on %s, initiator %s, IP %s, target %s via %s,%s", system.OdataId.Id, function.OdataId.Id, iscsiBoot.InitiatorName, iscsiBoot.InitiatorIPAddress, iscsiBoot.PrimaryTargetName, iscsiBoot.PrimaryTargetIPAddress, iscsiBoot.SecondaryTargetIPAddress)
+			continue
+		}
+		if err = client.Patch(function.OdataId.Id, map[string]interface{}{"BootMode": "iSCSI", "iSCSIBoot": iscsiBoot}); err != nil {
+			err = fmt.Errorf("setIscsiBoot(): failure for iSCSI interface %s: %s", initiator.Name, err)
+			return
+		}
+	}
+	return
+}
+
+// setBootOverride sets continuous boot override to iSCSI boot option if BMC exposes it
+func setBootOverride(client *Client, system *ComputerSystem, nodeConfig *config.NodeConfig) (err error) {
+	var members []string
+	if len(nodeConfig.Network.IscsiInitiator) == 0 || system.Boot.BootOptions == nil || system.Boot.BootOptions.Id == "" {
+		return
+	}
+	if members, err = client.GetCollection(system.Boot.BootOptions.Id); err != nil {
+		err = fmt.Errorf("setBootOverride(): %s", err)
+		return
+	}
+	for _, member := range members {
+		var bootOption BootOption
+		if err = client.Get(member, &bootOption); err != nil {
+			err = fmt.Errorf("setBootOverride(): %s", err)
+			return
+		}
+		if bootOption.UefiDevicePath == "" || !(strings.Contains(strings.ToLower(bootOption.DisplayName), "iscsi") || strings.Contains(strings.ToLower(bootOption.UefiDevicePath), "iscsi")) {
+			continue
+		}
+		if nodeConfig.Plan != nil {
+			planRecord(nodeConfig, "SetBootOverride", "set system %s continuous boot override to %s", system.OdataId.Id, bootOption.DisplayName)
+			return
+		}
+		if err = client.Patch(system.OdataId.Id, map[string]interface{}{
+			"Boot": Boot{
+				BootSourceOverrideEnabled:    "Continuous",
+				BootSourceOverrideMode:       "UEFI",
+				BootSourceOverrideTarget:     "UefiTarget",
+				UefiTargetBootSourceOverride: bootOption.UefiDevicePath,
+			},
+		}); err != nil {
+			err = fmt.Errorf("setBootOverride(): %s", err)
+		}
+		return
+	}
+	return
+}
+
+// setAssetTag claims or releases system by asset tag
+func setAssetTag(client *Client, system *ComputerSystem, nodeConfig *config.NodeConfig, assetTag string) (err error) {
+	if nodeConfig.Plan != nil {
+		planRecord(nodeConfig, "SetAssetTag", "set system %s asset tag \"%s\"", system.OdataId.Id, assetTag)
+		return
+	}
+	if err = client.Patch(system.OdataId.Id, map[string]interface{}{"AssetTag": assetTag}); err != nil {
+		err = fmt.Errorf("setAssetTag(): %s", err)
+	}
+	return
+}
+
+// setPowerState resets system to "On" or "ForceOff" and waits for the power state
+func setPowerState(client *Client, system *ComputerSystem, nodeConfig *config.NodeConfig, resetType string) (err error) {
+	state := "Off"
+	if resetType == "On" {
+		state = "On"
+	}
+	if system.PowerState == state {
+		nodeConfig.Compute.Powerstate = powerState(state)
+		return
+	}
+	if nodeConfig.Plan != nil {
+		planRecord(nodeConfig, "Reset", "reset system %s with type \"%s\"", system.OdataId.Id, resetType)
+		nodeConfig.Compute.Powerstate = powerState(state)
+		return
+	}
+	target := system.Actions.Reset.Target
+	if target == "" {
+		target = system.OdataId.Id + "/Actions/ComputerSystem.Reset"
+	}
+	if err = client.Post(target, map[string]interface{}{"ResetType": resetType}); err != nil {
+		err = fmt.Errorf("setPowerState(): %s", err)
+		return
+	}
+	giveupTime := time.Now().Add(time.Second * time.Duration(powerWaitMax))
+	for time.Now().Before(giveupTime) {
+		var current ComputerSystem
+		if err = client.Get(system.OdataId.Id, &current); err != nil {
+			err = fmt.Errorf("setPowerState(): %s", err)
+			return
+		}
+		if current.PowerState == state {
+			nodeConfig.Compute.Powerstate = powerState(state)
+			return
+		}
+		time.Sleep(powerPollInterval)
+	}
+	err = fmt.Errorf("setPowerState(): timeout waiting for system %s power state \"%s\"", system.OdataId.Id, state)
+	return
+}
+
+// powerState translates Redfish power state to compute power state
+func powerState(state string) string {
+	switch state {
+	case "On", "PoweringOff":
+		return "up"
+	case "Off", "PoweringOn":
+		return "down"
+	}
+	return strings.ToLower(state)
+}
+
+// CreateServerPreflight does sanity check before claiming system
+func CreateServerPreflight(nodeConfig *config.NodeConfig) (err error) {
+	var system *ComputerSystem
+	client := NewClient(nodeConfig.Compute.RedfishCredentials)
+	if len(nodeConfig.Network.FcInitiator) > 0 {
+		err = fmt.Errorf("CreateServerPreflight: FC boot is not supported by Redfish compute")
+		return
+	}
+	if system, err = getSystem(client, nodeConfig); err != nil {
+		err = fmt.Errorf("CreateServerPreflight: %s", err)
+		return
+	}
+	if system.AssetTag != "" && system.AssetTag != nodeConfig.Compute.HostName {
+		err = fmt.Errorf("CreateServerPreflight: system \"%s\" is claimed by \"%s\"", system.OdataId.Id, system.AssetTag)
+		return
+	}
+	if _, err = setMacAddresses(client, system, nodeConfig); err != nil {
+		err = fmt.Errorf("CreateServerPreflight: %s", err)
+	}
+	return
+}
+
+// CreateServer claims system, programs iSCSI boot and powers it off for the first boot
+func CreateServer(nodeConfig *config.NodeConfig) (err error) {
+	var system *ComputerSystem
+	var functions []NetworkDeviceFunction
+	client := NewClient(nodeConfig.Compute.RedfishCredentials)
+	if system, err = getSystem(client, nodeConfig); err != nil {
+		err = fmt.Errorf("CreateServer: %s", err)
+		return
+	}
+	if system.AssetTag != "" && system.AssetTag != nodeConfig.Compute.HostName {
+		err = fmt.Errorf("CreateServer: system \"%s\" is claimed by \"%s\"", system.OdataId.Id, system.AssetTag)
+		return
+	}
+	if err = setAssetTag(client, system, nodeConfig, nodeConfig.Compute.HostName); err != nil {
+		err = fmt.Errorf("CreateServer: %s", err)
+		return
+	}
+	setInventory(system, nodeConfig)
+	if functions, err = setMacAddresses(client, system, nodeConfig); err != nil {
+		err = fmt.Errorf("CreateServer: %s", err)
+		return
+	}
+	if err = setIscsiBoot(client, system, functions, nodeConfig); err != nil {
+		err = fmt.Errorf("CreateServer: %s", err)
+		return
+	}
+	if err = setBootOverride(client, system, nodeConfig); err != nil {
+		err = fmt.Errorf("CreateServer: %s", err)
+		return
+	}
+	if err = setPowerState(client, system, nodeConfig, "ForceOff"); err != nil {
+		err = fmt.Errorf("CreateServer: %s", err)
+	}
+	return
+}
+
+// SetServerBootTargets re-programs iSCSI boot targets (usually after storage failover)
+func SetServerBootTargets(nodeConfig *config.NodeConfig) (err error) {
+	var system *ComputerSystem
+	var functions []NetworkDeviceFunction
+	client := NewClient(nodeConfig.Compute.RedfishCredentials)
+	if system, err = getSystem(client, nodeConfig); err != nil {
+		err = fmt.Errorf("SetServerBootTargets: %s", err)
+		return
+	}
+	if functions, err = getNetworkDeviceFunctions(client, system); err != nil {
+		err = fmt.Errorf("SetServerBootTargets: %s", err)
+		return
+	}
+	if err = setIscsiBoot(client, system, functions, nodeConfig); err != nil {
+		err = fmt.Errorf("SetServerBootTargets: %s", err)
+	}
+	return
+}
+
+// DiscoverServer finds system claimed by host name and retrives it's attributes
+func DiscoverServer(nodeConfig *config.NodeConfig) (serverExists bool, err error) {
+	var system *ComputerSystem
+	var functions []NetworkDeviceFunction
+	client := NewClient(nodeConfig.Compute.RedfishCredentials)
+	if system, err = getSystem(client, nodeConfig); err != nil {
+		err = fmt.Errorf("DiscoverServer: %s", err)
+		return
+	}
+	if system.AssetTag != nodeConfig.Compute.HostName {
+		serverExists = false
+		return
+	}
+	serverExists = true
+	setInventory(system, nodeConfig)
+	if functions, err = setMacAddresses(client, system, nodeConfig); err != nil {
+		err = fmt.Errorf("DiscoverServer: %s", err)
+		return
+	}
+	for i := range nodeConfig.Network.IscsiInitiator {
+		if i > 1 {
+			break
+		}
+		function := findNetworkDeviceFunction(functions, nodeConfig.Network.IscsiInitiator[i].Name)
+		if function.IscsiBoot == nil || function.IscsiBoot.PrimaryTargetIPAddress == "" {
+			err = fmt.Errorf("DiscoverServer: iSCSI targets are not configured for interface \"%s\" in system \"%s\"", nodeConfig.Network.IscsiInitiator[i].Name, system.OdataId.Id)
+			return
+		}
+		nodeConfig.Network.IscsiInitiator[i].IscsiTarget = &config.IscsiTarget{
+			NodeName:   function.IscsiBoot.PrimaryTargetName,
+			Interfaces: []string{function.IscsiBoot.PrimaryTargetIPAddress},
+		}
+		if function.IscsiBoot.SecondaryTargetIPAddress != "" {
+			nodeConfig.Network.IscsiInitiator[i].IscsiTarget.Interfaces = append(nodeConfig.Network.IscsiInitiator[i].IscsiTarget.Interfaces, function.IscsiBoot.SecondaryTargetIPAddress)
+		}
+		nodeConfig.Network.IscsiInitiator[i].Ip = function.IscsiBoot.InitiatorIPAddress
+		nodeConfig.Network.IscsiInitiator[i].Gateway = function.IscsiBoot.InitiatorDefaultGateway
+		nodeConfig.Network.IscsiInitiator[i].DnsServer1 = function.IscsiBoot.PrimaryDNS
+		nodeConfig.Network.IscsiInitiator[i].DnsServer2 = function.IscsiBoot.SecondaryDNS
+	}
+	return
+}
+
+// UpdateServerPreflight rejects re-assigning, rack server is fixed by systemId
+func UpdateServerPreflight(nodeConfig *config.NodeConfig) (err error) {
+	err = fmt.Errorf("UpdateServerPreflight: re-assigning server is not supported by Redfish compute")
+	return
+}
+
+// UpdateServer rejects re-assigning, rack server is fixed by systemId
+func UpdateServer(nodeConfig *config.NodeConfig) (err error) {
+	err = fmt.Errorf("UpdateServer: re-assigning server is not supported by Redfish compute")
+	return
+}
+
+// UpdateServerAttributes is no-op, Redfish systems do not keep description and label
+func UpdateServerAttributes(nodeConfig *config.NodeConfig) (err error) {
+	return
+}
+
+// DeleteServer disables iSCSI boot and releases system claim
+func DeleteServer(nodeConfig *config.NodeConfig) (err error) {
+	var system *ComputerSystem
+	var functions []NetworkDeviceFunction
+	client := NewClient(nodeConfig.Compute.RedfishCredentials)
+	if system, err = getSystem(client, nodeConfig); err != nil {
+		err = fmt.Errorf("DeleteServer: %s", err)
+		return
+	}
+	if system.AssetTag != nodeConfig.Compute.HostName {
+		return
+	}
+	if system.PowerState != "Off" {
+		err = fmt.Errorf("DeleteServer: server \"%s\" has power state \"%s\"", system.OdataId.Id, powerState(system.PowerState))
+		return
+	}
+	if len(nodeConfig.Network.IscsiInitiator) > 0 {
+		if functions, err = getNetworkDeviceFunctions(client, system); err != nil {
+			err = fmt.Errorf("DeleteServer: %s", err)
+			return
+		}
+	}
+	for i := range nodeConfig.Network.IscsiInitiator {
+		if function := findNetworkDeviceFunction(functions, nodeConfig.Network.IscsiInitiator[i].Name); function != nil && function.BootMode == "iSCSI" {
+			if nodeConfig.Plan != nil {
+				planRecord(nodeConfig, "SetBootMode", "disable iSCSI boot on %s", function.OdataId.Id)
+				continue
+			}
+			if err = client.Patch(function.OdataId.Id, map[string]interface{}{"BootMode": "Disabled"}); err != nil {
+				err = fmt.Errorf("DeleteServer: %s", err)
+				return
+			}
+		}
+	}
+	if system.Boot.BootSourceOverrideEnabled != "" && system.Boot.BootSourceOverrideEnabled != "Disabled" {
+		if nodeConfig.Plan != nil {
+			planRecord(nodeConfig, "SetBootOverride", "disable system %s boot override", system.OdataId.Id)
+		} else if err = client.Patch(system.OdataId.Id, map[string]interface{}{"Boot": Boot{BootSourceOverrideEnabled: "Disabled"}}); err != nil {
+			err = fmt.Errorf("DeleteServer: %s", err)
+			return
+		}
+	}
+	if err = setAssetTag(client, system, nodeConfig, ""); err != nil {
+		err = fmt.Errorf("DeleteServer: %s", err)
+	}
+	return
+}
+
+// StartServer powers system on
+func StartServer(nodeConfig *config.NodeConfig) (err error) {
+	var system *ComputerSystem
+	client := NewClient(nodeConfig.Compute.RedfishCredentials)
+	if system, err = getSystem(client, nodeConfig); err != nil {
+		err = fmt.Errorf("StartServer: %s", err)
+		return
+	}
+	if err = setPowerState(client, system, nodeConfig, "On"); err != nil {
+		err = fmt.Errorf("StartServer: %s", err)
+	}
+	return
+}
+
+// StopServer powers system off
+func StopServer(nodeConfig *config.NodeConfig) (err error) {
+	var system *ComputerSystem
+	client := NewClient(nodeConfig.Compute.RedfishCredentials)
+	if system, err = getSystem(client, nodeConfig); err != nil {
+		err = fmt.Errorf("StopServer: %s", err)
+		return
+	}
+	if err = setPowerState(client, system, nodeConfig, "ForceOff"); err != nil {
+		err = fmt.Errorf("StopServer: %s", err)
+	}
+	return
+}
+
+// GetServerPowerState retrievs system powerstate
+func GetServerPowerState(nodeConfig *config.NodeConfig) (state string, err error) {
+	var system *ComputerSystem
+	client := NewClient(nodeConfig.Compute.RedfishCredentials)
+	if system, err = getSystem(client, nodeConfig); err != nil {
+		err = fmt.Errorf("GetServerPowerState: %s", err)
+		return
+	}
+	state = powerState(system.PowerState)
+	return
+}
+
+// GetServerOperationalState retrievs system operational state
+func GetServerOperationalState(nodeConfig *config.NodeConfig) (operationalState string, err error) {
+	var system *ComputerSystem
+	client := NewClient(nodeConfig.Compute.RedfishCredentials)
+	if system, err = getSystem(client, nodeConfig); err != nil {
+		err = fmt.Errorf("GetServerOperationalState: %s", err)
+		return
+	}
+	switch {
+	case system.PowerState == "Off":
+		operationalState = "power-off"
+	case system.Status.State == "" || system.Status.State == "Enabled":
+		operationalState = "ok"
+	default:
+		operationalState = strings.ToLower(system.Status.State)
+	}
+	return
+}
@@ -0,0 +1,413 @@
+package redfish
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/redfish/fake"
+)
+
+const (
+	testIscsiTargetName = "iqn.1992-08.com.netapp:sn.0123456789"
+)
+
+// useFakeServer starts Redfish service stand-in and makes power state polling immediate
+func useFakeServer(t *testing.T) (server *fake.Server) {
+	server = fake.NewServer()
+	server.User = "admin"
+	server.Password = "secret"
+	pollInterval := powerPollInterval
+	powerPollInterval = 0
+	t.Cleanup(func() {
+		server.Close()
+		powerPollInterval = pollInterval
+	})
+	return
+}
+
+// testNodeConfig makes node configuration with node interface and two iSCSI initiators
+func testNodeConfig(server *fake.Server, hostName string) (nodeConfig *config.NodeConfig) {
+	nodeConfig = &config.NodeConfig{}
+	nodeConfig.Compute.Provider = "redfish"
+	nodeConfig.Compute.HostName = hostName
+	nodeConfig.Compute.RedfishCredentials = config.Credentials{Host: server.URL, User: server.User, Password: server.Password}
+	nodeConfig.Network.Node = []config.NetworkInterface{{Name: "eth0"}, {Name: "eth1"}}
+	for i, name := range []string{"iscsi0", "iscsi1"} {
+		nodeConfig.Network.IscsiInitiator = append(nodeConfig.Network.IscsiInitiator, config.IscsiInitiator{
+			NetworkInterface: config.NetworkInterface{
+				Name:       name,
+				Ip:         []string{"192.168.10.101", "192.168.20.101"}[i],
+				Subnet:     []string{"192.168.10.0/24", "192.168.20.0/23"}[i],
+				Gateway:    []string{"192.168.10.1", "192.168.20.1"}[i],
+				DnsServer1: "10.0.0.53",
+				DnsServer2: "10.0.1.53",
+			},
+			InitiatorName: "iqn.2005-02.com.open-iscsi:" + hostName,
+			IscsiTarget: &config.IscsiTarget{
+				NodeName:   testIscsiTargetName,
+				Interfaces: []string{[]string{"192.168.10.11", "192.168.20.11"}[i], []string{"192.168.10.12", "192.168.20.12"}[i]},
+			},
+		})
+	}
+	return
+}
+
+// testFunction gets network device function from the server
+func testFunction(t *testing.T, server *fake.Server, id string) (function NetworkDeviceFunction) {
+	t.Helper()
+	if err := server.Resource(fake.FunctionsPath+"/"+id, &function); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+// testSystem gets the system from the server
+func testSystem(t *testing.T, server *fake.Server) (system ComputerSystem) {
+	t.Helper()
+	if err := server.Resource(fake.SystemPath, &system); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestCreateServer(t *testing.T) {
+	server := useFakeServer(t)
+	nodeConfig := testNodeConfig(server, "node1")
+	nodeConfig.Network.IscsiChap = config.IscsiChap{User: "chap", Password: "chapsecret", TargetUser: "target", TargetPassword: "targetsecret"}
+	if err := CreateServerPreflight(nodeConfig); err != nil {
+		t.Fatalf("CreateServerPreflight() failure: %s", err)
+	}
+	if calls := server.Calls(); len(calls) != 0 {
+		t.Fatalf("unexpected calls %v in preflight", calls)
+	}
+	if err := CreateServer(nodeConfig); err != nil {
+		t.Fatalf("CreateServer() failure: %s", err)
+	}
+	system := testSystem(t, server)
+	if system.AssetTag != "node1" || system.PowerState != "Off" || nodeConfig.Compute.Powerstate != "down" {
+		t.Fatalf("unexpected system asset tag %q, power state %q", system.AssetTag, system.PowerState)
+	}
+	if system.Boot.BootSourceOverrideEnabled != "Continuous" || system.Boot.BootSourceOverrideMode != "UEFI" || system.Boot.UefiTargetBootSourceOverride != fake.IscsiDevicePath {
+		t.Fatalf("unexpected boot override %+v", system.Boot)
+	}
+	assigned := nodeConfig.Compute.BladeAssigned
+	if assigned.Dn != fake.SystemPath || assigned.Serial != "WZP2248003A" || assigned.NumOfCpus != "2" || assigned.NumOfThreads != "80" || assigned.TotalMemory != "393216" {
+		t.Fatalf("unexpected inventory %+v", assigned)
+	}
+	// MAC of interface without current address is its permanent address
+	if nodeConfig.Network.Node[0].Macaddr != "00:25:B5:00:0B:01" || nodeConfig.Network.Node[1].Macaddr != "00:25:B5:00:0B:02" {
+		t.Fatalf("unexpected node MAC addresses %+v", nodeConfig.Network.Node)
+	}
+	for i, id := range []string{"1", "2"} {
+		initiator := nodeConfig.Network.IscsiInitiator[i]
+		function := testFunction(t, server, id)
+		if initiator.Macaddr != function.Ethernet.MACAddress {
+			t.Errorf("unexpected iSCSI interface %s MAC %s", initiator.Name, initiator.Macaddr)
+		}
+		iscsiBoot := function.IscsiBoot
+		if function.BootMode != "iSCSI" || iscsiBoot == nil {
+			t.Fatalf("iSCSI boot is not set on %s", initiator.Name)
+		}
+		if iscsiBoot.InitiatorIPAddress != initiator.Ip || iscsiBoot.InitiatorName != initiator.InitiatorName || iscsiBoot.InitiatorDefaultGateway != initiator.Gateway {
+			t.Errorf("unexpected iSCSI initiator %+v", iscsiBoot)
+		}
+		if netmask := []string{"255.255.255.0", "255.255.254.0"}[i]; iscsiBoot.InitiatorNetmask != netmask {
+			t.Errorf("expected netmask %s, got %s", netmask, iscsiBoot.InitiatorNetmask)
+		}
+		if iscsiBoot.PrimaryTargetName != testIscsiTargetName || iscsiBoot.PrimaryTargetIPAddress != initiator.IscsiTarget.Interfaces[0] || iscsiBoot.PrimaryTargetTCPPort != 3260 ||
+			iscsiBoot.SecondaryTargetName != testIscsiTargetName || iscsiBoot.SecondaryTargetIPAddress != initiator.IscsiTarget.Interfaces[1] || iscsiBoot.SecondaryTargetTCPPort != 3260 {
+			t.Errorf("unexpected iSCSI targets %+v", iscsiBoot)
+		}
+		if iscsiBoot.AuthenticationMethod != "MutualCHAP" || iscsiBoot.CHAPUsername != "chap" || iscsiBoot.MutualCHAPSecret != "targetsecret" {
+			t.Errorf("unexpected iSCSI authentication %+v", iscsiBoot)
+		}
+	}
+	expected := []string{
+		"PATCH " + fake.SystemPath,
+		"PATCH " + fake.FunctionsPath + "/1",
+		"PATCH " + fake.FunctionsPath + "/2",
+		"PATCH " + fake.SystemPath,
+		"POST " + fake.SystemPath + "/Actions/ComputerSystem.Reset",
+	}
+	if calls := server.Calls(); strings.Join(calls, ",") != strings.Join(expected, ",") {
+		t.Fatalf("unexpected calls %v", calls)
+	}
+
+	// repeated call on claimed and powered off system does not reset it
+	if err := CreateServer(nodeConfig); err != nil {
+		t.Fatalf("CreateServer() failure: %s", err)
+	}
+	if calls := server.Calls(); calls[len(calls)-1] == "POST "+fake.SystemPath+"/Actions/ComputerSystem.Reset" {
+		t.Fatalf("powered off system is reset")
+	}
+}
+
+func TestCreateServerClaimed(t *testing.T) {
+	server := useFakeServer(t)
+	server.Update(fake.SystemPath, map[string]interface{}{"AssetTag": "node2"})
+	nodeConfig := testNodeConfig(server, "node1")
+	for _, f := range []func(*config.NodeConfig) error{CreateServerPreflight, CreateServer} {
+		if err := f(nodeConfig); err == nil || !strings.Contains(err.Error(), "is claimed by \"node2\"") {
+			t.Fatalf("expected claimed system failure, got %v", err)
+		}
+	}
+	if calls := server.Calls(); len(calls) != 0 {
+		t.Fatalf("unexpected calls %v on claimed system", calls)
+	}
+	nodeConfig.Network.FcInitiator = []config.FcInitiator{{Name: "fc0"}}
+	if err := CreateServerPreflight(nodeConfig); err == nil || !strings.Contains(err.Error(), "FC boot is not supported") {
+		t.Fatalf("expected FC boot failure, got %v", err)
+	}
+}
+
+func TestCreateServerFault(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		status   int
+		err      string
+		bootMode string
+	}{
+		{name: "asset tag", method: "PATCH", path: fake.SystemPath, status: http.StatusForbidden, err: "setAssetTag(): PATCH " + fake.SystemPath + ": HTTP status 403: license required", bootMode: "Disabled"},
+		{name: "iSCSI boot", method: "PATCH", path: fake.FunctionsPath + "/2", status: http.StatusBadRequest, err: "setIscsiBoot(): failure for iSCSI interface iscsi1: PATCH " + fake.FunctionsPath + "/2: HTTP status 400: license required", bootMode: "iSCSI"},
+		{name: "reset", method: "POST", path: fake.SystemPath + "/Actions/ComputerSystem.Reset", status: http.StatusServiceUnavailable, err: "setPowerState(): POST " + fake.SystemPath + "/Actions/ComputerSystem.Reset: HTTP status 503: license required", bootMode: "iSCSI"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := useFakeServer(t)
+			nodeConfig := testNodeConfig(server, "node1")
+			server.Fail(test.method, test.path, test.status, "license required")
+			if err := CreateServer(nodeConfig); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error containing %q, got %v", test.err, err)
+			}
+			if function := testFunction(t, server, "1"); function.BootMode != test.bootMode {
+				t.Errorf("expected boot mode %s, got %s", test.bootMode, function.BootMode)
+			}
+			// retry completes server configuration
+			if err := CreateServer(nodeConfig); err != nil {
+				t.Fatalf("CreateServer() retry failure: %s", err)
+			}
+			if system := testSystem(t, server); system.AssetTag != "node1" || system.PowerState != "Off" || system.Boot.BootSourceOverrideEnabled != "Continuous" {
+				t.Fatalf("unexpected system after retry %+v", system)
+			}
+		})
+	}
+}
+
+func TestSetMacAddressesMissing(t *testing.T) {
+	server := useFakeServer(t)
+	nodeConfig := testNodeConfig(server, "node1")
+	nodeConfig.Network.Node[1].Name = "eth9"
+	if err := CreateServerPreflight(nodeConfig); err == nil || !strings.Contains(err.Error(), "no ethernet interfaces found in system \""+fake.SystemPath+"\" that match node interface \"eth9\"") {
+		t.Fatalf("expected missing node interface failure, got %v", err)
+	}
+	nodeConfig = testNodeConfig(server, "node1")
+	nodeConfig.Network.IscsiInitiator[1].Name = "iscsi9"
+	if err := CreateServer(nodeConfig); err == nil || !strings.Contains(err.Error(), "no network device functions found in system \""+fake.SystemPath+"\" that match iSCSI interface \"iscsi9\"") {
+		t.Fatalf("expected missing iSCSI interface failure, got %v", err)
+	}
+	if function := testFunction(t, server, "1"); function.BootMode != "Disabled" {
+		t.Fatalf("iSCSI boot is set with missing iSCSI interface")
+	}
+	// functions are matched by Id as well as by Name
+	nodeConfig = testNodeConfig(server, "node1")
+	nodeConfig.Network.IscsiInitiator[1].Name = "2"
+	if err := CreateServerPreflight(nodeConfig); err != nil || nodeConfig.Network.IscsiInitiator[1].Macaddr != "00:25:B5:00:0A:02" {
+		t.Fatalf("unexpected MAC %s, error %v", nodeConfig.Network.IscsiInitiator[1].Macaddr, err)
+	}
+}
+
+func TestDiscoverServer(t *testing.T) {
+	server := useFakeServer(t)
+	if err := CreateServer(testNodeConfig(server, "node1")); err != nil {
+		t.Fatalf("CreateServer() failure: %s", err)
+	}
+	nodeConfig := testNodeConfig(server, "node1")
+	for i := range nodeConfig.Network.IscsiInitiator {
+		nodeConfig.Network.IscsiInitiator[i].IscsiTarget = nil
+		nodeConfig.Network.IscsiInitiator[i].Ip = ""
+		nodeConfig.Network.IscsiInitiator[i].Gateway = ""
+	}
+	serverExists, err := DiscoverServer(nodeConfig)
+	if err != nil || !serverExists {
+		t.Fatalf("DiscoverServer() failure: %v, exists %v", err, serverExists)
+	}
+	initiator := nodeConfig.Network.IscsiInitiator[1]
+	if initiator.IscsiTarget == nil || initiator.IscsiTarget.NodeName != testIscsiTargetName || strings.Join(initiator.IscsiTarget.Interfaces, ",") != "192.168.20.11,192.168.20.12" {
+		t.Fatalf("unexpected iSCSI target %+v", initiator.IscsiTarget)
+	}
+	if initiator.Ip != "192.168.20.101" || initiator.Gateway != "192.168.20.1" || initiator.DnsServer1 != "10.0.0.53" || initiator.Macaddr != "00:25:B5:00:0A:02" {
+		t.Fatalf("unexpected iSCSI initiator %+v", initiator)
+	}
+	if nodeConfig.Compute.SpDn != fake.SystemPath || nodeConfig.Compute.ChassisId != fake.ChassisPath || nodeConfig.Compute.Powerstate != "down" {
+		t.Fatalf("unexpected compute %+v", nodeConfig.Compute)
+	}
+
+	if serverExists, err = DiscoverServer(testNodeConfig(server, "node2")); err != nil || serverExists {
+		t.Fatalf("system claimed by other host is discovered: %v", err)
+	}
+	// claimed system without iSCSI targets is reported
+	server.Update(fake.FunctionsPath+"/1", map[string]interface{}{"iSCSIBoot": nil})
+	if _, err = DiscoverServer(testNodeConfig(server, "node1")); err == nil || !strings.Contains(err.Error(), "iSCSI targets are not configured for interface \"iscsi0\"") {
+		t.Fatalf("expected missing iSCSI targets failure, got %v", err)
+	}
+}
+
+func TestSetServerBootTargets(t *testing.T) {
+	server := useFakeServer(t)
+	nodeConfig := testNodeConfig(server, "node1")
+	if err := CreateServer(nodeConfig); err != nil {
+		t.Fatalf("CreateServer() failure: %s", err)
+	}
+	nodeConfig.Network.IscsiInitiator[0].IscsiTarget.Interfaces = []string{"192.168.10.13"}
+	if err := SetServerBootTargets(nodeConfig); err != nil {
+		t.Fatalf("SetServerBootTargets() failure: %s", err)
+	}
+	iscsiBoot := testFunction(t, server, "1").IscsiBoot
+	if iscsiBoot.PrimaryTargetIPAddress != "192.168.10.13" || iscsiBoot.SecondaryTargetIPAddress != "" {
+		t.Fatalf("unexpected iSCSI targets %+v", iscsiBoot)
+	}
+	// stale ETag is rejected by the service
+	server.Fail("PATCH", fake.FunctionsPath+"/2", http.StatusPreconditionFailed, "The ETag supplied did not match the ETag required to change this resource.")
+	if err := SetServerBootTargets(nodeConfig); err == nil || !strings.Contains(err.Error(), "HTTP status 412: The ETag supplied did not match") {
+		t.Fatalf("expected precondition failure, got %v", err)
+	}
+}
+
+func TestPowerState(t *testing.T) {
+	server := useFakeServer(t)
+	server.PowerTransitionPolls = 3
+	nodeConfig := testNodeConfig(server, "node1")
+	if err := StopServer(nodeConfig); err != nil {
+		t.Fatalf("StopServer() failure: %s", err)
+	}
+	if system := testSystem(t, server); system.PowerState != "Off" || nodeConfig.Compute.Powerstate != "down" {
+		t.Fatalf("system is not powered off: %s", system.PowerState)
+	}
+	if state, err := GetServerOperationalState(nodeConfig); err != nil || state != "power-off" {
+		t.Fatalf("unexpected operational state %q, error %v", state, err)
+	}
+	if err := StartServer(nodeConfig); err != nil {
+		t.Fatalf("StartServer() failure: %s", err)
+	}
+	if state, err := GetServerPowerState(nodeConfig); err != nil || state != "up" || nodeConfig.Compute.Powerstate != "up" {
+		t.Fatalf("unexpected power state %q, error %v", state, err)
+	}
+	if state, err := GetServerOperationalState(nodeConfig); err != nil || state != "ok" {
+		t.Fatalf("unexpected operational state %q, error %v", state, err)
+	}
+	// powered on system is not reset again
+	calls := len(server.Calls())
+	if err := StartServer(nodeConfig); err != nil || len(server.Calls()) != calls {
+		t.Fatalf("powered on system is reset: %v", err)
+	}
+
+	// system in transition reports power state it is going to leave
+	server.PowerTransitionPolls = 10
+	server.Update(fake.SystemPath, map[string]interface{}{"PowerState": "PoweringOff", "Status": map[string]interface{}{"State": "Quiesced"}})
+	if state, err := GetServerPowerState(nodeConfig); err != nil || state != "up" {
+		t.Fatalf("unexpected power state %q, error %v", state, err)
+	}
+	if state, err := GetServerOperationalState(nodeConfig); err != nil || state != "quiesced" {
+		t.Fatalf("unexpected operational state %q, error %v", state, err)
+	}
+
+	server.Update(fake.SystemPath, map[string]interface{}{"PowerState": "Off"})
+	server.Fail("POST", fake.SystemPath+"/Actions/ComputerSystem.Reset", http.StatusConflict, "The system is in a transient power state")
+	if err := StartServer(nodeConfig); err == nil || !strings.Contains(err.Error(), "StartServer: setPowerState(): POST "+fake.SystemPath+"/Actions/ComputerSystem.Reset: HTTP status 409: The system is in a transient power state") {
+		t.Fatalf("expected reset failure, got %v", err)
+	}
+}
+
+func TestGetSystem(t *testing.T) {
+	server := useFakeServer(t)
+	server.AddSystem("2")
+	nodeConfig := testNodeConfig(server, "node1")
+	if _, err := GetServerPowerState(nodeConfig); err == nil || !strings.Contains(err.Error(), "expected one system in /redfish/v1/Systems, found 2, systemId should be specified") {
+		t.Fatalf("expected multiple systems failure, got %v", err)
+	}
+	for _, systemId := range []string{"2", "/redfish/v1/Systems/2"} {
+		nodeConfig.Compute.SystemId = systemId
+		if state, err := GetServerPowerState(nodeConfig); err != nil || state != "down" {
+			t.Fatalf("unexpected power state %q of system %s, error %v", state, systemId, err)
+		}
+	}
+	nodeConfig.Compute.SystemId = "3"
+	if _, err := GetServerPowerState(nodeConfig); err == nil || !strings.Contains(err.Error(), "GET /redfish/v1/Systems/3: HTTP status 404: The resource at the URI /redfish/v1/Systems/3 was not found.") {
+		t.Fatalf("expected missing system failure, got %v", err)
+	}
+	nodeConfig = testNodeConfig(server, "node1")
+	nodeConfig.Compute.SystemId = "1"
+	nodeConfig.Compute.RedfishCredentials.Password = "wrong"
+	if _, err := GetServerPowerState(nodeConfig); err == nil || !strings.Contains(err.Error(), "HTTP status 401: There are insufficient privileges") {
+		t.Fatalf("expected authentication failure, got %v", err)
+	}
+}
+
+func TestDeleteServer(t *testing.T) {
+	server := useFakeServer(t)
+	nodeConfig := testNodeConfig(server, "node1")
+	if err := CreateServer(nodeConfig); err != nil {
+		t.Fatalf("CreateServer() failure: %s", err)
+	}
+	if err := StartServer(nodeConfig); err != nil {
+		t.Fatalf("StartServer() failure: %s", err)
+	}
+	if err := DeleteServer(nodeConfig); err == nil || !strings.Contains(err.Error(), "has power state \"up\"") {
+		t.Fatalf("expected powered on system failure, got %v", err)
+	}
+	if system := testSystem(t, server); system.AssetTag != "node1" {
+		t.Fatalf("powered on system is released")
+	}
+	if err := StopServer(nodeConfig); err != nil {
+		t.Fatalf("StopServer() failure: %s", err)
+	}
+	// system claimed by other host is left as is
+	calls := len(server.Calls())
+	if err := DeleteServer(testNodeConfig(server, "node2")); err != nil || len(server.Calls()) != calls {
+		t.Fatalf("system claimed by other host is changed: %v", err)
+	}
+	if err := DeleteServer(nodeConfig); err != nil {
+		t.Fatalf("DeleteServer() failure: %s", err)
+	}
+	system := testSystem(t, server)
+	if system.AssetTag != "" || system.Boot.BootSourceOverrideEnabled != "Disabled" {
+		t.Fatalf("system is not released: %+v", system)
+	}
+	for _, id := range []string{"1", "2"} {
+		if function := testFunction(t, server, id); function.BootMode != "Disabled" {
+			t.Errorf("iSCSI boot is not disabled on function %s", id)
+		}
+	}
+	if serverExists, err := DiscoverServer(nodeConfig); err != nil || serverExists {
+		t.Fatalf("released system is discovered: %v", err)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	server := useFakeServer(t)
+	nodeConfig := testNodeConfig(server, "node1")
+	nodeConfig.Plan = config.NewPlan()
+	if err := CreateServer(nodeConfig); err != nil {
+		t.Fatalf("CreateServer() failure: %s", err)
+	}
+	if calls := server.Calls(); len(calls) != 0 {
+		t.Fatalf("unexpected calls %v in plan mode", calls)
+	}
+	var operations []string
+	for _, op := range nodeConfig.Plan.Operations() {
+		if op.Backend != "redfish" || op.Host != server.URL {
+			t.Errorf("unexpected planned operation %+v", op)
+		}
+		operations = append(operations, op.Operation)
+	}
+	if strings.Join(operations, ",") != "SetAssetTag,SetIscsiBoot,SetIscsiBoot,SetBootOverride,Reset" {
+		t.Fatalf("unexpected planned operations %v", operations)
+	}
+	if nodeConfig.Compute.Powerstate != "down" || nodeConfig.Network.IscsiInitiator[0].Macaddr != "00:25:B5:00:0A:01" {
+		t.Fatalf("planned server attributes are not discovered")
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/intersight"
+)
+
+// IntersightProvider is Intersight compute provider
+type IntersightProvider struct {
+}
+
+// NewIntersightProvider creates Intersight compute provider
+func NewIntersightProvider() *IntersightProvider {
+	return &IntersightProvider{}
+}
+
+// CreateServerPreflight implements ComputeProvider
+func (p *IntersightProvider) CreateServerPreflight(nodeConfig *config.NodeConfig) error {
+	return intersight.CreateServerPreflight(nodeConfig)
+}
+
+// CreateServer implements ComputeProvider
+func (p *IntersightProvider) CreateServer(nodeConfig *config.NodeConfig) error {
+	return intersight.CreateServer(nodeConfig)
+}
+
+// DiscoverServer implements ComputeProvider
+func (p *IntersightProvider) DiscoverServer(nodeConfig *config.NodeConfig) (bool, error) {
+	return intersight.DiscoverServer(nodeConfig)
+}
+
+// UpdateServerPreflight implements ComputeProvider
+func (p *IntersightProvider) UpdateServerPreflight(nodeConfig *config.NodeConfig) error {
+	return intersight.UpdateServerPreflight(nodeConfig)
+}
+
+// UpdateServer implements ComputeProvider
+func (p *IntersightProvider) UpdateServer(nodeConfig *config.NodeConfig) error {
+	return intersight.UpdateServer(nodeConfig)
+}
+
+// UpdateServerAttributes implements ComputeProvider
+func (p *IntersightProvider) UpdateServerAttributes(nodeConfig *config.NodeConfig) error {
+	return intersight.UpdateServerAttributes(nodeConfig)
+}
+
+// DeleteServer implements ComputeProvider
+func (p *IntersightProvider) DeleteServer(nodeConfig *config.NodeConfig) error {
+	return intersight.DeleteServer(nodeConfig)
+}
+
+// StartServer implements ComputeProvider
+func (p *IntersightProvider) StartServer(nodeConfig *config.NodeConfig) error {
+	return intersight.StartServer(nodeConfig)
+}
+
+// StopServer implements ComputeProvider
+func (p *IntersightProvider) StopServer(nodeConfig *config.NodeConfig) error {
+	return intersight.StopServer(nodeConfig)
+}
+
+// GetServerPowerState implements ComputeProvider
+func (p *IntersightProvider) GetServerPowerState(nodeConfig *config.NodeConfig) (string, error) {
+	return intersight.GetServerPowerState(nodeConfig)
+}
+
+// GetServerOperationalState implements ComputeProvider
+func (p *IntersightProvider) GetServerOperationalState(nodeConfig *config.NodeConfig) (string, error) {
+	return intersight.GetServerOperationalState(nodeConfig)
+}
+
+// SetServerBootTargets implements ComputeProvider
+func (p *IntersightProvider) SetServerBootTargets(nodeConfig *config.NodeConfig) error {
+	return intersight.SetServerBootTargets(nodeConfig)
+}
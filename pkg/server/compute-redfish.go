@@ -0,0 +1,75 @@
+package server
+
+import (
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/redfish"
+)
+
+// RedfishProvider is Redfish compute provider
+type RedfishProvider struct {
+}
+
+// NewRedfishProvider creates Redfish compute provider
+func NewRedfishProvider() *RedfishProvider {
+	return &RedfishProvider{}
+}
+
+// CreateServerPreflight implements ComputeProvider
+func (p *RedfishProvider) CreateServerPreflight(nodeConfig *config.NodeConfig) error {
+	return redfish.CreateServerPreflight(nodeConfig)
+}
+
+// CreateServer implements ComputeProvider
+func (p *RedfishProvider) CreateServer(nodeConfig *config.NodeConfig) error {
+	return redfish.CreateServer(nodeConfig)
+}
+
+// DiscoverServer implements ComputeProvider
+func (p *RedfishProvider) DiscoverServer(nodeConfig *config.NodeConfig) (bool, error) {
+	return redfish.DiscoverServer(nodeConfig)
+}
+
+// UpdateServerPreflight implements ComputeProvider
+func (p *RedfishProvider) UpdateServerPreflight(nodeConfig *config.NodeConfig) error {
+	return redfish.UpdateServerPreflight(nodeConfig)
+}
+
+// UpdateServer implements ComputeProvider
+func (p *RedfishProvider) UpdateServer(nodeConfig *config.NodeConfig) error {
+	return redfish.UpdateServer(nodeConfig)
+}
+
+// UpdateServerAttributes implements ComputeProvider
+func (p *RedfishProvider) UpdateServerAttributes(nodeConfig *config.NodeConfig) error {
+	return redfish.UpdateServerAttributes(nodeConfig)
+}
+
+// DeleteServer implements ComputeProvider
+func (p *RedfishProvider) DeleteServer(nodeConfig *config.NodeConfig) error {
+	return redfish.DeleteServer(nodeConfig)
+}
+
+// StartServer implements ComputeProvider
+func (p *RedfishProvider) StartServer(nodeConfig *config.NodeConfig) error {
+	return redfish.StartServer(nodeConfig)
+}
+
+// StopServer implements ComputeProvider
+func (p *RedfishProvider) StopServer(nodeConfig *config.NodeConfig) error {
+	return redfish.StopServer(nodeConfig)
+}
+
+// GetServerPowerState implements ComputeProvider
+func (p *RedfishProvider) GetServerPowerState(nodeConfig *config.NodeConfig) (string, error) {
+	return redfish.GetServerPowerState(nodeConfig)
+}
+
+// GetServerOperationalState implements ComputeProvider
+func (p *RedfishProvider) GetServerOperationalState(nodeConfig *config.NodeConfig) (string, error) {
+	return redfish.GetServerOperationalState(nodeConfig)
+}
+
+// SetServerBootTargets implements ComputeProvider
+func (p *RedfishProvider) SetServerBootTargets(nodeConfig *config.NodeConfig) error {
+	return redfish.SetServerBootTargets(nodeConfig)
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ucsm"
+)
+
+// UcsmProvider is UCS Manager compute provider
+type UcsmProvider struct {
+}
+
+// NewUcsmProvider creates UCS Manager compute provider
+func NewUcsmProvider() *UcsmProvider {
+	return &UcsmProvider{}
+}
+
+// CreateServerPreflight implements ComputeProvider
+func (p *UcsmProvider) CreateServerPreflight(nodeConfig *config.NodeConfig) error {
+	return ucsm.CreateServerPreflight(nodeConfig)
+}
+
+// CreateServer implements ComputeProvider
+func (p *UcsmProvider) CreateServer(nodeConfig *config.NodeConfig) error {
+	_, err := ucsm.CreateServer(nodeConfig)
+	return err
+}
+
+// DiscoverServer implements ComputeProvider
+func (p *UcsmProvider) DiscoverServer(nodeConfig *config.NodeConfig) (bool, error) {
+	return ucsm.DiscoverServer(nodeConfig)
+}
+
+// UpdateServerPreflight implements ComputeProvider
+func (p *UcsmProvider) UpdateServerPreflight(nodeConfig *config.NodeConfig) error {
+	return ucsm.UpdateServerPreflight(nodeConfig)
+}
+
+// UpdateServer implements ComputeProvider
+func (p *UcsmProvider) UpdateServer(nodeConfig *config.NodeConfig) error {
+	return ucsm.UpdateServer(nodeConfig)
+}
+
+// UpdateServerAttributes implements ComputeProvider
+func (p *UcsmProvider) UpdateServerAttributes(nodeConfig *config.NodeConfig) error {
+	return ucsm.UpdateServerAttributes(nodeConfig)
+}
+
+// DeleteServer implements ComputeProvider
+func (p *UcsmProvider) DeleteServer(nodeConfig *config.NodeConfig) error {
+	return ucsm.DeleteServer(nodeConfig)
+}
+
+// StartServer implements ComputeProvider
+func (p *UcsmProvider) StartServer(nodeConfig *config.NodeConfig) error {
+	return ucsm.StartServer(nodeConfig)
+}
+
+// StopServer implements ComputeProvider
+func (p *UcsmProvider) StopServer(nodeConfig *config.NodeConfig) error {
+	return ucsm.StopServer(nodeConfig)
+}
+
+// GetServerPowerState implements ComputeProvider
+func (p *UcsmProvider) GetServerPowerState(nodeConfig *config.NodeConfig) (string, error) {
+	return ucsm.GetServerPowerState(nodeConfig)
+}
+
+// GetServerOperationalState implements ComputeProvider
+func (p *UcsmProvider) GetServerOperationalState(nodeConfig *config.NodeConfig) (string, error) {
+	return ucsm.GetServerOperationalState(nodeConfig)
+}
+
+// SetServerBootTargets implements ComputeProvider
+func (p *UcsmProvider) SetServerBootTargets(nodeConfig *config.NodeConfig) error {
+	return ucsm.SetServerBootTargets(nodeConfig)
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+// ComputeProvider is generic compute provider interface
+type ComputeProvider interface {
+	CreateServerPreflight(nodeConfig *config.NodeConfig) error
+	CreateServer(nodeConfig *config.NodeConfig) error
+	DiscoverServer(nodeConfig *config.NodeConfig) (bool, error)
+	UpdateServerPreflight(nodeConfig *config.NodeConfig) error
+	UpdateServer(nodeConfig *config.NodeConfig) error
+	UpdateServerAttributes(nodeConfig *config.NodeConfig) error
+	DeleteServer(nodeConfig *config.NodeConfig) error
+	StartServer(nodeConfig *config.NodeConfig) error
+	StopServer(nodeConfig *config.NodeConfig) error
+	GetServerPowerState(nodeConfig *config.NodeConfig) (string, error)
+	GetServerOperationalState(nodeConfig *config.NodeConfig) (string, error)
+	SetServerBootTargets(nodeConfig *config.NodeConfig) error
+}
+
+// NewProvider initializes compute provider, UCSM is default
+func NewProvider(compute *config.Compute) (provider ComputeProvider, err error) {
+	switch compute.Provider {
+	case "", "ucsm":
+		provider = NewUcsmProvider()
+	case "intersight":
+		provider = NewIntersightProvider()
+	case "redfish":
+		provider = NewRedfishProvider()
+	default:
+		err = fmt.Errorf("NewProvider(): compute provider %s is not implemented", compute.Provider)
+	}
+	return
+}
+
+// CreateServerPreflight does sanity check before server creation
+func CreateServerPreflight(nodeConfig *config.NodeConfig) (err error) {
+	var provider ComputeProvider
+	if provider, err = NewProvider(&nodeConfig.Compute); err == nil {
+		err = provider.CreateServerPreflight(nodeConfig)
+	}
+	return
+}
+
+// CreateServer creates server
+func CreateServer(nodeConfig *config.NodeConfig) (err error) {
+	var provider ComputeProvider
+	if provider, err = NewProvider(&nodeConfig.Compute); err == nil {
+		err = provider.CreateServer(nodeConfig)
+	}
+	return
+}
+
+// DiscoverServer finds server and retrives it's attributes
+func DiscoverServer(nodeConfig *config.NodeConfig) (serverExists bool, err error) {
+	var provider ComputeProvider
+	if provider, err = NewProvider(&nodeConfig.Compute); err == nil {
+		serverExists, err = provider.DiscoverServer(nodeConfig)
+	}
+	return
+}
+
+// UpdateServerPreflight does sanity check before server update
+func UpdateServerPreflight(nodeConfig *config.NodeConfig) (err error) {
+	var provider ComputeProvider
+	if provider, err = NewProvider(&nodeConfig.Compute); err == nil {
+		err = provider.UpdateServerPreflight(nodeConfig)
+	}
+	return
+}
+
+// UpdateServer re-assigns physical server
+func UpdateServer(nodeConfig *config.NodeConfig) (err error) {
+	var provider ComputeProvider
+	if provider, err = NewProvider(&nodeConfig.Compute); err == nil {
+		err = provider.UpdateServer(nodeConfig)
+	}
+	return
+}
+
+// UpdateServerAttributes updates server description and label
+func UpdateServerAttributes(nodeConfig *config.NodeConfig) (err error) {
+	var provider ComputeProvider
+	if provider, err = NewProvider(&nodeConfig.Compute); err == nil {
+		err = provider.UpdateServerAttributes(nodeConfig)
+	}
+	return
+}
+
+// DeleteServer deletes server
+func DeleteServer(nodeConfig *config.NodeConfig) (err error) {
+	var provider ComputeProvider
+	if provider, err = NewProvider(&nodeConfig.Compute); err == nil {
+		err = provider.DeleteServer(nodeConfig)
+	}
+	return
+}
+
+// StartServer sets server powerstate to "up"
+func StartServer(nodeConfig *config.NodeConfig) (err error) {
+	var provider ComputeProvider
+	if provider, err = NewProvider(&nodeConfig.Compute); err == nil {
+		err = provider.StartServer(nodeConfig)
+	}
+	return
+}
+
+// StopServer sets server powerstate to "down"
+func StopServer(nodeConfig *config.NodeConfig) (err error) {
+	var provider ComputeProvider
+	if provider, err = NewProvider(&nodeConfig.Compute); err == nil {
+		err = provider.StopServer(nodeConfig)
+	}
+	return
+}
+
+// GetServerPowerState retrievs server powerstate
+func GetServerPowerState(nodeConfig *config.NodeConfig) (powerState string, err error) {
+	var provider ComputeProvider
+	if provider, err = NewProvider(&nodeConfig.Compute); err == nil {
+		powerState, err = provider.GetServerPowerState(nodeConfig)
+	}
+	return
+}
+
+// GetServerOperationalState retrievs server operational state
+func GetServerOperationalState(nodeConfig *config.NodeConfig) (operationalState string, err error) {
+	var provider ComputeProvider
+	if provider, err = NewProvider(&nodeConfig.Compute); err == nil {
+		operationalState, err = provider.GetServerOperationalState(nodeConfig)
+	}
+	return
+}
+
+// SetServerBootTargets re-programs server boot targets
+func SetServerBootTargets(nodeConfig *config.NodeConfig) (err error) {
+	var provider ComputeProvider
+	if provider, err = NewProvider(&nodeConfig.Compute); err == nil {
+		err = provider.SetServerBootTargets(nodeConfig)
+	}
+	return
+}
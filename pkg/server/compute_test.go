@@ -0,0 +1,81 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/redfish"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/redfish/fake"
+)
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		provider string
+		expected interface{}
+	}{
+		{provider: "", expected: &UcsmProvider{}},
+		{provider: "ucsm", expected: &UcsmProvider{}},
+		{provider: "intersight", expected: &IntersightProvider{}},
+		{provider: "redfish", expected: &RedfishProvider{}},
+	}
+	for _, test := range tests {
+		provider, err := NewProvider(&config.Compute{Provider: test.provider})
+		if err != nil {
+			t.Fatalf("NewProvider(%q) failure: %s", test.provider, err)
+		}
+		if got, expected := fmt.Sprintf("%T", provider), fmt.Sprintf("%T", test.expected); got != expected {
+			t.Errorf("NewProvider(%q) expected %s, got %s", test.provider, expected, got)
+		}
+	}
+	nodeConfig := &config.NodeConfig{}
+	nodeConfig.Compute.Provider = "ipmi"
+	if err := StartServer(nodeConfig); err == nil || err.Error() != "NewProvider(): compute provider ipmi is not implemented" {
+		t.Fatalf("expected not implemented provider failure, got %v", err)
+	}
+}
+
+func TestRedfishProvider(t *testing.T) {
+	bmc := fake.NewServer()
+	defer bmc.Close()
+	nodeConfig := &config.NodeConfig{}
+	nodeConfig.Compute.Provider = "redfish"
+	nodeConfig.Compute.HostName = "node1"
+	nodeConfig.Compute.RedfishCredentials = config.Credentials{Host: bmc.URL}
+	nodeConfig.Network.Node = []config.NetworkInterface{{Name: "eth0"}}
+	nodeConfig.Network.IscsiInitiator = []config.IscsiInitiator{{
+		NetworkInterface: config.NetworkInterface{Name: "iscsi0", Ip: "192.168.10.101", Subnet: "192.168.10.0/24"},
+		InitiatorName:    "iqn.2005-02.com.open-iscsi:node1",
+		IscsiTarget:      &config.IscsiTarget{NodeName: "iqn.1992-08.com.netapp:sn.0123456789", Interfaces: []string{"192.168.10.11"}},
+	}}
+	if err := CreateServerPreflight(nodeConfig); err != nil {
+		t.Fatalf("CreateServerPreflight() failure: %s", err)
+	}
+	if err := CreateServer(nodeConfig); err != nil {
+		t.Fatalf("CreateServer() failure: %s", err)
+	}
+	if state, err := GetServerPowerState(nodeConfig); err != nil || state != "down" {
+		t.Fatalf("unexpected power state %q, error %v", state, err)
+	}
+	nodeConfig.Network.IscsiInitiator[0].IscsiTarget.Interfaces = []string{"192.168.10.12"}
+	if err := SetServerBootTargets(nodeConfig); err != nil {
+		t.Fatalf("SetServerBootTargets() failure: %s", err)
+	}
+	var function redfish.NetworkDeviceFunction
+	if err := bmc.Resource(fake.FunctionsPath+"/1", &function); err != nil || function.IscsiBoot == nil || function.IscsiBoot.PrimaryTargetIPAddress != "192.168.10.12" {
+		t.Fatalf("iSCSI boot targets are not updated: %v", err)
+	}
+	if serverExists, err := DiscoverServer(nodeConfig); err != nil || !serverExists {
+		t.Fatalf("DiscoverServer() failure: %v, exists %v", err, serverExists)
+	}
+	if err := UpdateServer(nodeConfig); err == nil || !strings.Contains(err.Error(), "not supported by Redfish compute") {
+		t.Fatalf("expected not supported failure, got %v", err)
+	}
+	if err := DeleteServer(nodeConfig); err != nil {
+		t.Fatalf("DeleteServer() failure: %s", err)
+	}
+	if serverExists, err := DiscoverServer(nodeConfig); err != nil || serverExists {
+		t.Fatalf("deleted server is discovered: %v", err)
+	}
+}
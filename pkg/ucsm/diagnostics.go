@@ -0,0 +1,219 @@
+package ucsm
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/igor-feoktistov/go-ucsm-sdk/api"
+	"github.com/igor-feoktistov/go-ucsm-sdk/mo"
+	"github.com/igor-feoktistov/go-ucsm-sdk/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// Fault is UCSM fault raised on SP or blade
+type Fault struct {
+	Dn       string `xml:"dn,attr,omitempty"`
+	Id       string `xml:"id,attr,omitempty"`
+	Code     string `xml:"code,attr,omitempty"`
+	Severity string `xml:"severity,attr,omitempty"`
+	Cause    string `xml:"cause,attr,omitempty"`
+	Descr    string `xml:"descr,attr,omitempty"`
+	Created  string `xml:"created,attr,omitempty"`
+}
+
+// FaultInsts is list of faults returned by configResolveClass
+type FaultInsts struct {
+	XMLName xml.Name
+	Faults  []Fault `xml:"faultInst"`
+}
+
+// SpDiagnostics is SP FSM progress and faults raised on SP or its blade
+type SpDiagnostics struct {
+	SpDn        string
+	BladeDn     string
+	AssocState  string
+	FsmDescr    string
+	FsmStage    string
+	FsmStatus   string
+	FsmProgress string
+	FsmError    string
+	Faults      []Fault
+}
+
+// String formats diagnostics to be appended to error message
+func (d *SpDiagnostics) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "service profile %s", d.SpDn)
+	if d.BladeDn != "" {
+		fmt.Fprintf(&b, ", blade %s", d.BladeDn)
+	}
+	if d.AssocState != "" {
+		fmt.Fprintf(&b, ", association state \"%s\"", d.AssocState)
+	}
+	if d.FsmDescr != "" || d.FsmStage != "" {
+		fmt.Fprintf(&b, "\n  FSM %s: stage \"%s\", status \"%s\", progress %s%%", d.FsmDescr, d.FsmStage, d.FsmStatus, d.FsmProgress)
+	}
+	if d.FsmError != "" {
+		fmt.Fprintf(&b, "\n  FSM remote error: %s", d.FsmError)
+	}
+	for _, fault := range d.Faults {
+		fmt.Fprintf(&b, "\n  fault %s [%s] on %s: %s", fault.Code, fault.Severity, faultParentDn(fault.Dn), fault.Descr)
+	}
+	return b.String()
+}
+
+// faultParentDn gets DN of object fault is raised on, i.e. "org-root/ls-node1" for "org-root/ls-node1/fault-F0327"
+func faultParentDn(faultDn string) string {
+	if ndx := strings.LastIndex(faultDn, "/fault-"); ndx != -1 {
+		return faultDn[:ndx]
+	}
+	return faultDn
+}
+
+// GetFaults gets active faults raised on objects or their children
+func GetFaults(client *api.Client, dns ...string) (faults []Fault, err error) {
+	var out FaultInsts
+	var filters []api.FilterAny
+	for _, dn := range dns {
+		if dn == "" {
+			continue
+		}
+		filters = append(filters, api.FilterWildcard{
+			FilterProperty: api.FilterProperty{
+				Class:    "faultInst",
+				Property: "dn",
+				Value:    "^" + regexp.QuoteMeta(dn) + "/",
+			},
+		})
+	}
+	if len(filters) == 0 {
+		return
+	}
+	req := api.ConfigResolveClassRequest{
+		Cookie:         client.Cookie,
+		ClassId:        "faultInst",
+		InHierarchical: "false",
+		InFilter:       api.FilterOr{Filters: filters},
+	}
+	if err = client.ConfigResolveClass(req, &out); err != nil {
+		err = fmt.Errorf("GetFaults: ConfigResolveClass() failure: %s", err)
+		return
+	}
+	for _, fault := range out.Faults {
+		if fault.Severity != "cleared" {
+			faults = append(faults, fault)
+		}
+	}
+	sort.SliceStable(faults, func(i, j int) bool {
+		return faults[i].Created < faults[j].Created
+	})
+	return
+}
+
+// GetSpDiagnostics gets SP FSM progress and faults raised on SP or blade,
+// blade is SP physical node unless bladeDn is set
+func GetSpDiagnostics(client *api.Client, spDn string, bladeDn string) (diagnostics *SpDiagnostics, err error) {
+	var lsServers []*mo.LsServer
+	diagnostics = &SpDiagnostics{SpDn: spDn, BladeDn: bladeDn}
+	if lsServers, err = util.ServerGet(client, spDn, "instance"); err != nil {
+		err = fmt.Errorf("GetSpDiagnostics: ServerGet() failure: %s", err)
+		return
+	}
+	if len(lsServers) > 0 {
+		setFsm(diagnostics, lsServers[0])
+		if diagnostics.BladeDn == "" {
+			diagnostics.BladeDn = lsServers[0].PnDn
+		}
+	}
+	if diagnostics.Faults, err = GetFaults(client, spDn, diagnostics.BladeDn); err != nil {
+		err = fmt.Errorf("GetSpDiagnostics: %s", err)
+	}
+	return
+}
+
+// setFsm sets SP FSM attributes in diagnostics
+func setFsm(diagnostics *SpDiagnostics, sp *mo.LsServer) {
+	diagnostics.AssocState = sp.AssocState
+	diagnostics.FsmDescr = sp.FsmDescr
+	diagnostics.FsmStage = sp.FsmStageDescr
+	diagnostics.FsmStatus = sp.FsmStatus
+	diagnostics.FsmProgress = sp.FsmProgr
+	diagnostics.FsmError = sp.FsmRmtInvErrDescr
+}
+
+// spDiagnosticsError appends SP diagnostics to error message, diagnostics are logged
+func spDiagnosticsError(client *api.Client, spDn string, bladeDn string, err error) error {
+	diagnostics, diagErr := GetSpDiagnostics(client, spDn, bladeDn)
+	if diagErr != nil {
+		log.Warnf("%s", diagErr)
+		return err
+	}
+	log.Warnf("%s: %s", err, diagnostics)
+	return fmt.Errorf("%s\n%s", err, diagnostics)
+}
+
+// spWaitForAssociation waits for SP association like util.SpWaitForAssociation,
+// FSM stage changes and new faults on SP or blade are streamed to log while waiting,
+// faults are checked on FSM change only to keep UCSM polling light
+func spWaitForAssociation(client *api.Client, spDn string, bladeDn string, waitMax int) (assocState string, err error) {
+	var lsServers []*mo.LsServer
+	var lastFsm string
+	seenFaults := make(map[string]bool)
+	waitUntil := time.Now().Add(time.Second * time.Duration(waitMax))
+	poll := func() (err error) {
+		var faults []Fault
+		if lsServers, err = util.ServerGet(client, spDn, "instance"); err != nil {
+			return
+		}
+		if len(lsServers) == 0 {
+			err = fmt.Errorf("ServerGet: no server %s found", spDn)
+			return
+		}
+		sp := lsServers[0]
+		assocState = sp.AssocState
+		fsm := fmt.Sprintf("%s/%s/%s/%s", sp.FsmDescr, sp.FsmStageDescr, sp.FsmStatus, sp.FsmProgr)
+		if fsm == lastFsm && assocState == "associating" {
+			return
+		}
+		if fsm != lastFsm {
+			lastFsm = fsm
+			log.Infof("spWaitForAssociation: service profile %s association state \"%s\", FSM %s: stage \"%s\", status \"%s\", progress %s%%", spDn, assocState, sp.FsmDescr, sp.FsmStageDescr, sp.FsmStatus, sp.FsmProgr)
+		}
+		if faults, err = GetFaults(client, spDn, bladeDn); err != nil {
+			log.Warnf("spWaitForAssociation: %s", err)
+			err = nil
+			return
+		}
+		for _, fault := range faults {
+			if !seenFaults[fault.Dn+"/"+fault.Id] {
+				seenFaults[fault.Dn+"/"+fault.Id] = true
+				log.Warnf("spWaitForAssociation: fault %s [%s] on %s: %s", fault.Code, fault.Severity, faultParentDn(fault.Dn), fault.Descr)
+			}
+		}
+		return
+	}
+	for time.Now().Before(waitUntil) {
+		if err = poll(); err != nil {
+			return
+		}
+		if assocState == "associating" {
+			time.Sleep(2 * time.Second)
+			continue
+		}
+		// Let to settle down on the state different from "associating"
+		// to make sure it's not turning to "associating" again from
+		// "failure" or "associated" (known issue)
+		for n := 0; n < 5 && assocState != "associating" && err == nil; n++ {
+			time.Sleep(1 * time.Second)
+			err = poll()
+		}
+		if err != nil || assocState != "associating" {
+			return
+		}
+	}
+	return
+}
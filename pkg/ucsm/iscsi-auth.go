@@ -0,0 +1,151 @@
+package ucsm
+
+import (
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/igor-feoktistov/go-ucsm-sdk/api"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+// go-ucsm-sdk does not implement iSCSI authentication profiles,
+// re-defining just enough of iscsiAuthProfile class
+
+// IscsiAuthProfile is iSCSI CHAP authentication profile
+type IscsiAuthProfile struct {
+	XMLName  xml.Name `xml:"iscsiAuthProfile"`
+	Dn       string   `xml:"dn,attr,omitempty"`
+	Name     string   `xml:"name,attr,omitempty"`
+	UserId   string   `xml:"userId,attr,omitempty"`
+	Password string   `xml:"password,attr,omitempty"`
+	Status   string   `xml:"status,attr,omitempty"`
+}
+
+// IscsiAuthProfileMo is configConfMo/configResolveDn container for IscsiAuthProfile
+type IscsiAuthProfileMo struct {
+	XMLName          xml.Name
+	IscsiAuthProfile IscsiAuthProfile `xml:"iscsiAuthProfile"`
+}
+
+// Re-defining mo.VnicIScsi to set just authProfileName value
+type vnicIScsiAuth struct {
+	AuthProfileName string `xml:"authProfileName,attr"`
+}
+
+type vnicIScsiAuthMo struct {
+	XMLName   xml.Name
+	VnicIScsi vnicIScsiAuth `xml:"vnicIScsi"`
+}
+
+// iscsiAuthProfileNames makes UCSM compliant (16 chars max) profile names for node initiator and target CHAP
+func iscsiAuthProfileNames(hostName string) (initiatorProfile string, targetProfile string) {
+	h := fnv.New32a()
+	h.Write([]byte(hostName))
+	initiatorProfile = fmt.Sprintf("chap-%08x", h.Sum32())
+	targetProfile = fmt.Sprintf("chapt-%08x", h.Sum32())
+	return
+}
+
+// SetIscsiAuthProfile creates or modifies iSCSI authentication profile in organization
+func SetIscsiAuthProfile(client *api.Client, orgDn string, profileName string, userId string, password string) (err error) {
+	var out IscsiAuthProfileMo
+	req := api.ConfigConfMoRequest{
+		Cookie:         client.Cookie,
+		Dn:             orgDn + "/iscsi-auth-profile-" + profileName,
+		InHierarchical: "false",
+		InConfig: IscsiAuthProfileMo{
+			IscsiAuthProfile: IscsiAuthProfile{
+				Dn:       orgDn + "/iscsi-auth-profile-" + profileName,
+				Name:     profileName,
+				UserId:   userId,
+				Password: password,
+			},
+		},
+	}
+	if err = client.ConfigConfMo(req, &out); err != nil {
+		err = fmt.Errorf("SetIscsiAuthProfile: ConfigConfMo() failure: %s", err)
+	}
+	return
+}
+
+// DeleteIscsiAuthProfile deletes iSCSI authentication profile in organization
+func DeleteIscsiAuthProfile(client *api.Client, orgDn string, profileName string) (err error) {
+	var out IscsiAuthProfileMo
+	resolveReq := api.ConfigResolveDnRequest{
+		Cookie:         client.Cookie,
+		Dn:             orgDn + "/iscsi-auth-profile-" + profileName,
+		InHierarchical: "false",
+	}
+	if err = client.ConfigResolveDn(resolveReq, &out); err != nil {
+		err = fmt.Errorf("DeleteIscsiAuthProfile: ConfigResolveDn() failure: %s", err)
+		return
+	}
+	if out.IscsiAuthProfile.Dn == "" {
+		return
+	}
+	req := api.ConfigConfMoRequest{
+		Cookie:         client.Cookie,
+		Dn:             orgDn + "/iscsi-auth-profile-" + profileName,
+		InHierarchical: "false",
+		InConfig: IscsiAuthProfileMo{
+			IscsiAuthProfile: IscsiAuthProfile{
+				Dn:     orgDn + "/iscsi-auth-profile-" + profileName,
+				Status: "deleted",
+			},
+		},
+	}
+	if err = client.ConfigConfMo(req, &out); err != nil {
+		err = fmt.Errorf("DeleteIscsiAuthProfile: ConfigConfMo() failure: %s", err)
+	}
+	return
+}
+
+// SpSetIscsiAuth sets iSCSI vNIC initiator authentication profile
+func SpSetIscsiAuth(client *api.Client, spDn string, iscsiVnicName string, profileName string) (err error) {
+	var out vnicIScsiAuthMo
+	req := api.ConfigConfMoRequest{
+		Cookie:         client.Cookie,
+		Dn:             spDn + "/iscsi-" + iscsiVnicName,
+		InHierarchical: "false",
+		InConfig: vnicIScsiAuthMo{
+			VnicIScsi: vnicIScsiAuth{
+				AuthProfileName: profileName,
+			},
+		},
+	}
+	if err = client.ConfigConfMo(req, &out); err != nil {
+		err = fmt.Errorf("SpSetIscsiAuth: ConfigConfMo() failure: %s", err)
+	}
+	return
+}
+
+// setIscsiAuthProfiles creates node iSCSI authentication profiles for initiator and mutual CHAP
+func setIscsiAuthProfiles(client *api.Client, nodeConfig *config.NodeConfig) (initiatorProfile string, targetProfile string, err error) {
+	if nodeConfig.Network.IscsiChap.User == "" {
+		return
+	}
+	initiatorProfileName, targetProfileName := iscsiAuthProfileNames(nodeConfig.Compute.HostName)
+	if err = SetIscsiAuthProfile(client, nodeConfig.Compute.SpOrg, initiatorProfileName, nodeConfig.Network.IscsiChap.User, nodeConfig.Network.IscsiChap.Password); err != nil {
+		return
+	}
+	initiatorProfile = initiatorProfileName
+	if nodeConfig.Network.IscsiChap.TargetUser != "" {
+		if err = SetIscsiAuthProfile(client, nodeConfig.Compute.SpOrg, targetProfileName, nodeConfig.Network.IscsiChap.TargetUser, nodeConfig.Network.IscsiChap.TargetPassword); err != nil {
+			return
+		}
+		targetProfile = targetProfileName
+	}
+	return
+}
+
+// deleteIscsiAuthProfiles deletes node iSCSI authentication profiles
+func deleteIscsiAuthProfiles(client *api.Client, nodeConfig *config.NodeConfig) (err error) {
+	initiatorProfileName, targetProfileName := iscsiAuthProfileNames(nodeConfig.Compute.HostName)
+	for _, profileName := range []string{initiatorProfileName, targetProfileName} {
+		if err = DeleteIscsiAuthProfile(client, nodeConfig.Compute.SpOrg, profileName); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,238 @@
+package ucsm
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/igor-feoktistov/go-ucsm-sdk/api"
+	"github.com/igor-feoktistov/go-ucsm-sdk/mo"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Blade placement policies
+const (
+	PlacementRandom     = "random"
+	PlacementSpread     = "spread"
+	PlacementPack       = "pack"
+	PlacementLowestSlot = "lowest-slot"
+	PlacementFirmware   = "firmware"
+)
+
+var chassisRegexp = regexp.MustCompile(`chassis-([0-9]+)`)
+
+// Placement lookups used by SelectBlade, replaceable in tests
+var (
+	placementPeers = GetPlacementPeers
+	bladeFirmware  = GetBladeFirmware
+)
+
+// LsServers is list of service profiles returned by configResolveClass
+type LsServers struct {
+	XMLName   xml.Name
+	LsServers []mo.LsServer `xml:"lsServer"`
+}
+
+// FirmwareStatusMo is blade firmware status returned by configResolveDn
+type FirmwareStatusMo struct {
+	XMLName        xml.Name
+	FirmwareStatus mo.FirmwareStatus `xml:"firmwareStatus"`
+}
+
+// placementCandidate is available blade with placement attributes
+type placementCandidate struct {
+	blade     *mo.ComputeBlade
+	chassisId int
+	peers     int
+	available int
+	firmware  string
+}
+
+// chassisId gets chassis id from blade DN, i.e. "sys/chassis-1/blade-2"
+func chassisId(bladeDn string) int {
+	if m := chassisRegexp.FindStringSubmatch(bladeDn); m != nil {
+		id, _ := strconv.Atoi(m[1])
+		return id
+	}
+	return 0
+}
+
+// checkPlacement validates blade placement policy settings
+func checkPlacement(nodeConfig *config.NodeConfig) (err error) {
+	switch nodeConfig.Compute.BladePlacement.Policy {
+	case "", PlacementRandom, PlacementLowestSlot:
+	case PlacementSpread, PlacementPack:
+		if nodeConfig.Compute.Label == "" {
+			err = fmt.Errorf("checkPlacement(): \"%s\" placement groups nodes by compute label, label is required", nodeConfig.Compute.BladePlacement.Policy)
+		}
+	case PlacementFirmware:
+		if nodeConfig.Compute.BladePlacement.Firmware == "" {
+			err = fmt.Errorf("checkPlacement(): \"firmware\" placement requires preferred firmware version")
+		}
+	default:
+		err = fmt.Errorf("checkPlacement(): unknown placement policy \"%s\"", nodeConfig.Compute.BladePlacement.Policy)
+	}
+	return
+}
+
+// GetPlacementPeers gets number of blades per chassis associated with service profiles labeled as node,
+// node service profile is excluded
+func GetPlacementPeers(client *api.Client, nodeConfig *config.NodeConfig) (peers map[int]int, err error) {
+	var out LsServers
+	req := api.ConfigResolveClassRequest{
+		Cookie:         client.Cookie,
+		ClassId:        "lsServer",
+		InHierarchical: "false",
+		InFilter: api.FilterAnd{
+			Filters: []api.FilterAny{
+				api.FilterEq{
+					FilterProperty: api.FilterProperty{
+						Class:    "lsServer",
+						Property: "usrLbl",
+						Value:    nodeConfig.Compute.Label,
+					},
+				},
+				api.FilterEq{
+					FilterProperty: api.FilterProperty{
+						Class:    "lsServer",
+						Property: "type",
+						Value:    "instance",
+					},
+				},
+			},
+		},
+	}
+	if err = client.ConfigResolveClass(req, &out); err != nil {
+		err = fmt.Errorf("GetPlacementPeers: ConfigResolveClass() failure: %s", err)
+		return
+	}
+	peers = make(map[int]int)
+	for _, sp := range out.LsServers {
+		if sp.Dn != nodeConfig.Compute.SpDn && sp.PnDn != "" {
+			peers[chassisId(sp.PnDn)]++
+		}
+	}
+	return
+}
+
+// GetBladeFirmware gets host firmware package version running on blade
+func GetBladeFirmware(client *api.Client, bladeDn string) (version string, err error) {
+	var out FirmwareStatusMo
+	req := api.ConfigResolveDnRequest{
+		Cookie:         client.Cookie,
+		Dn:             bladeDn + "/fw-status",
+		InHierarchical: "false",
+	}
+	if err = client.ConfigResolveDn(req, &out); err == nil {
+		version = out.FirmwareStatus.PackageVersion
+	}
+	return
+}
+
+// SelectBlade selects blade out of available blades per placement policy,
+// all policies but "random" are deterministic, rationale explains the choice
+func SelectBlade(client *api.Client, computeBlades []mo.ComputeBlade, nodeConfig *config.NodeConfig) (blade *mo.ComputeBlade, rationale string, err error) {
+	var candidates []*placementCandidate
+	var peers map[int]int
+	var peersTotal int
+	if len(computeBlades) == 0 {
+		err = fmt.Errorf("SelectBlade: no blades available")
+		return
+	}
+	if err = checkPlacement(nodeConfig); err != nil {
+		err = fmt.Errorf("SelectBlade: %s", err)
+		return
+	}
+	policy := nodeConfig.Compute.BladePlacement.Policy
+	if policy == "" || policy == PlacementRandom {
+		rand.Seed(time.Now().UnixNano())
+		blade = &computeBlades[rand.Intn(len(computeBlades))]
+		rationale = fmt.Sprintf("random: picked %s out of %d available blades", blade.Dn, len(computeBlades))
+		return
+	}
+	if policy == PlacementSpread || policy == PlacementPack {
+		if peers, err = placementPeers(client, nodeConfig); err != nil {
+			err = fmt.Errorf("SelectBlade: %s", err)
+			return
+		}
+		for _, n := range peers {
+			peersTotal += n
+		}
+	}
+	available := make(map[int]int)
+	for i := range computeBlades {
+		candidate := &placementCandidate{
+			blade:     &computeBlades[i],
+			chassisId: chassisId(computeBlades[i].Dn),
+		}
+		candidate.peers = peers[candidate.chassisId]
+		available[candidate.chassisId]++
+		candidates = append(candidates, candidate)
+	}
+	for _, candidate := range candidates {
+		candidate.available = available[candidate.chassisId]
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].chassisId != candidates[j].chassisId {
+			return candidates[i].chassisId < candidates[j].chassisId
+		}
+		if candidates[i].blade.SlotId != candidates[j].blade.SlotId {
+			return candidates[i].blade.SlotId < candidates[j].blade.SlotId
+		}
+		return candidates[i].blade.Dn < candidates[j].blade.Dn
+	})
+	switch policy {
+	case PlacementLowestSlot:
+		blade = candidates[0].blade
+		rationale = fmt.Sprintf("lowest-slot: %s has the lowest chassis and slot out of %d available blades", blade.Dn, len(candidates))
+	case PlacementSpread:
+		selected := candidates[0]
+		for _, candidate := range candidates[1:] {
+			if candidate.peers < selected.peers {
+				selected = candidate
+			}
+		}
+		if peersTotal > 0 && selected.peers == peersTotal {
+			err = fmt.Errorf("SelectBlade: spread placement: all %d nodes labeled \"%s\" would share chassis-%d, no blades available in other chassis", peersTotal+1, nodeConfig.Compute.Label, selected.chassisId)
+			return
+		}
+		blade = selected.blade
+		rationale = fmt.Sprintf("spread: chassis-%d runs %d of %d other nodes labeled \"%s\" (fewest among chassis with available blades), %s has the lowest slot", selected.chassisId, selected.peers, peersTotal, nodeConfig.Compute.Label, blade.Dn)
+		// chassis with the fewest peers is selected, so the majority cannot be avoided with available blades
+		if peersTotal > 0 && selected.peers+1 > (peersTotal+1)/2 {
+			warning := fmt.Sprintf("chassis-%d would run majority (%d of %d) of nodes labeled \"%s\", no blades available in less loaded chassis", selected.chassisId, selected.peers+1, peersTotal+1, nodeConfig.Compute.Label)
+			log.Warnf("SelectBlade: spread placement: %s", warning)
+			rationale += ", warning: " + warning
+		}
+	case PlacementPack:
+		selected := candidates[0]
+		for _, candidate := range candidates[1:] {
+			if candidate.peers > selected.peers || (candidate.peers == selected.peers && candidate.available < selected.available) {
+				selected = candidate
+			}
+		}
+		blade = selected.blade
+		rationale = fmt.Sprintf("pack: chassis-%d runs %d of %d other nodes labeled \"%s\" and has %d available blades, %s has the lowest slot", selected.chassisId, selected.peers, peersTotal, nodeConfig.Compute.Label, selected.available, blade.Dn)
+	case PlacementFirmware:
+		preferred := nodeConfig.Compute.BladePlacement.Firmware
+		for _, candidate := range candidates {
+			if candidate.firmware, err = bladeFirmware(client, candidate.blade.Dn); err != nil {
+				err = fmt.Errorf("SelectBlade: GetBladeFirmware() failure for blade %s: %s", candidate.blade.Dn, err)
+				return
+			}
+			if candidate.firmware == preferred {
+				blade = candidate.blade
+				rationale = fmt.Sprintf("firmware: %s runs preferred firmware \"%s\" and has the lowest slot among such blades", blade.Dn, preferred)
+				return
+			}
+		}
+		blade = candidates[0].blade
+		rationale = fmt.Sprintf("firmware: none of %d available blades runs preferred firmware \"%s\", %s has the lowest chassis and slot and runs \"%s\"", len(candidates), preferred, blade.Dn, candidates[0].firmware)
+	}
+	return
+}
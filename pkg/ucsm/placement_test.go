@@ -0,0 +1,254 @@
+package ucsm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/igor-feoktistov/go-ucsm-sdk/api"
+	"github.com/igor-feoktistov/go-ucsm-sdk/mo"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+func testBlades(dns ...string) (blades []mo.ComputeBlade) {
+	for _, dn := range dns {
+		var slot int
+		fmt.Sscanf(dn[strings.LastIndex(dn, "-")+1:], "%d", &slot)
+		var blade mo.ComputeBlade
+		blade.Dn = dn
+		blade.SlotId = slot
+		blades = append(blades, blade)
+	}
+	return
+}
+
+func testPlacementConfig(policy string, label string, firmware string) *config.NodeConfig {
+	nodeConfig := &config.NodeConfig{}
+	nodeConfig.Compute.SpDn = "org-root/ls-node1"
+	nodeConfig.Compute.Label = label
+	nodeConfig.Compute.BladePlacement.Policy = policy
+	nodeConfig.Compute.BladePlacement.Firmware = firmware
+	return nodeConfig
+}
+
+func TestSelectBlade(t *testing.T) {
+	defer func() {
+		placementPeers = GetPlacementPeers
+		bladeFirmware = GetBladeFirmware
+	}()
+	tests := []struct {
+		name      string
+		policy    string
+		label     string
+		firmware  string
+		blades    []mo.ComputeBlade
+		peers     map[int]int
+		peersErr  error
+		firmwares map[string]string
+		blade     string
+		rationale string
+		err       string
+	}{
+		{
+			name:   "no blades",
+			policy: PlacementLowestSlot,
+			err:    "no blades available",
+		},
+		{
+			name:   "unknown policy",
+			policy: "best",
+			blades: testBlades("sys/chassis-1/blade-1"),
+			err:    "unknown placement policy \"best\"",
+		},
+		{
+			name:   "spread without label",
+			policy: PlacementSpread,
+			blades: testBlades("sys/chassis-1/blade-1"),
+			err:    "label is required",
+		},
+		{
+			name:   "pack without label",
+			policy: PlacementPack,
+			blades: testBlades("sys/chassis-1/blade-1"),
+			err:    "label is required",
+		},
+		{
+			name:   "firmware without version",
+			policy: PlacementFirmware,
+			blades: testBlades("sys/chassis-1/blade-1"),
+			err:    "requires preferred firmware version",
+		},
+		{
+			name:      "random",
+			policy:    PlacementRandom,
+			blades:    testBlades("sys/chassis-1/blade-1"),
+			blade:     "sys/chassis-1/blade-1",
+			rationale: "random: picked sys/chassis-1/blade-1 out of 1 available blades",
+		},
+		{
+			name:      "default policy is random",
+			blades:    testBlades("sys/chassis-2/blade-3"),
+			blade:     "sys/chassis-2/blade-3",
+			rationale: "random:",
+		},
+		{
+			name:      "lowest-slot",
+			policy:    PlacementLowestSlot,
+			blades:    testBlades("sys/chassis-2/blade-1", "sys/chassis-1/blade-10", "sys/chassis-1/blade-2"),
+			blade:     "sys/chassis-1/blade-2",
+			rationale: "lowest-slot: sys/chassis-1/blade-2",
+		},
+		{
+			name:      "spread to chassis with fewest peers",
+			policy:    PlacementSpread,
+			label:     "k8s",
+			blades:    testBlades("sys/chassis-1/blade-1", "sys/chassis-2/blade-4", "sys/chassis-2/blade-2", "sys/chassis-3/blade-1"),
+			peers:     map[int]int{1: 2, 2: 1, 3: 2},
+			blade:     "sys/chassis-2/blade-2",
+			rationale: "spread: chassis-2 runs 1 of 5 other nodes",
+		},
+		{
+			name:      "spread first node",
+			policy:    PlacementSpread,
+			label:     "k8s",
+			blades:    testBlades("sys/chassis-2/blade-1", "sys/chassis-1/blade-3"),
+			peers:     map[int]int{},
+			blade:     "sys/chassis-1/blade-3",
+			rationale: "spread: chassis-1 runs 0 of 0 other nodes",
+		},
+		{
+			name:      "spread to empty chassis",
+			policy:    PlacementSpread,
+			label:     "k8s",
+			blades:    testBlades("sys/chassis-1/blade-1", "sys/chassis-2/blade-1"),
+			peers:     map[int]int{1: 1},
+			blade:     "sys/chassis-2/blade-1",
+			rationale: "spread: chassis-2 runs 0 of 1 other nodes",
+		},
+		{
+			name:      "spread does not warn on half",
+			policy:    PlacementSpread,
+			label:     "k8s",
+			blades:    testBlades("sys/chassis-1/blade-5", "sys/chassis-2/blade-5"),
+			peers:     map[int]int{1: 2, 2: 1},
+			blade:     "sys/chassis-2/blade-5",
+			rationale: "spread: chassis-2 runs 1 of 3 other nodes",
+		},
+		{
+			name:      "spread warns on majority",
+			policy:    PlacementSpread,
+			label:     "k8s",
+			blades:    testBlades("sys/chassis-2/blade-5", "sys/chassis-1/blade-5"),
+			peers:     map[int]int{1: 2, 2: 2},
+			blade:     "sys/chassis-1/blade-5",
+			rationale: "warning: chassis-1 would run majority (3 of 5) of nodes labeled \"k8s\"",
+		},
+		{
+			name:      "spread with majority unavoidable in two chassis",
+			policy:    PlacementSpread,
+			label:     "k8s",
+			blades:    testBlades("sys/chassis-1/blade-5", "sys/chassis-2/blade-5"),
+			peers:     map[int]int{1: 1, 2: 1},
+			blade:     "sys/chassis-1/blade-5",
+			rationale: "warning: chassis-1 would run majority (2 of 3)",
+		},
+		{
+			name:   "spread fails when all nodes share chassis",
+			policy: PlacementSpread,
+			label:  "k8s",
+			blades: testBlades("sys/chassis-1/blade-5", "sys/chassis-1/blade-6"),
+			peers:  map[int]int{1: 2},
+			err:    "all 3 nodes labeled \"k8s\" would share chassis-1",
+		},
+		{
+			name:     "spread peers lookup failure",
+			policy:   PlacementSpread,
+			label:    "k8s",
+			blades:   testBlades("sys/chassis-1/blade-1"),
+			peersErr: fmt.Errorf("connection refused"),
+			err:      "connection refused",
+		},
+		{
+			name:      "pack to chassis with most peers",
+			policy:    PlacementPack,
+			label:     "k8s",
+			blades:    testBlades("sys/chassis-1/blade-1", "sys/chassis-2/blade-3", "sys/chassis-2/blade-2"),
+			peers:     map[int]int{1: 1, 2: 3},
+			blade:     "sys/chassis-2/blade-2",
+			rationale: "pack: chassis-2 runs 3 of 4 other nodes labeled \"k8s\" and has 2 available blades",
+		},
+		{
+			name:      "pack ties to chassis with fewest available blades",
+			policy:    PlacementPack,
+			label:     "k8s",
+			blades:    testBlades("sys/chassis-1/blade-1", "sys/chassis-1/blade-2", "sys/chassis-2/blade-7"),
+			peers:     map[int]int{},
+			blade:     "sys/chassis-2/blade-7",
+			rationale: "pack: chassis-2 runs 0 of 0 other nodes labeled \"k8s\" and has 1 available blades",
+		},
+		{
+			name:      "firmware match",
+			policy:    PlacementFirmware,
+			firmware:  "4.2(1f)",
+			blades:    testBlades("sys/chassis-1/blade-1", "sys/chassis-1/blade-2", "sys/chassis-2/blade-1"),
+			firmwares: map[string]string{"sys/chassis-1/blade-1": "4.1(3b)", "sys/chassis-1/blade-2": "4.2(1f)", "sys/chassis-2/blade-1": "4.2(1f)"},
+			blade:     "sys/chassis-1/blade-2",
+			rationale: "firmware: sys/chassis-1/blade-2 runs preferred firmware \"4.2(1f)\"",
+		},
+		{
+			name:      "firmware fallback",
+			policy:    PlacementFirmware,
+			firmware:  "4.2(1f)",
+			blades:    testBlades("sys/chassis-2/blade-1", "sys/chassis-1/blade-4"),
+			firmwares: map[string]string{"sys/chassis-1/blade-4": "4.1(3b)", "sys/chassis-2/blade-1": "4.1(3c)"},
+			blade:     "sys/chassis-1/blade-4",
+			rationale: "firmware: none of 2 available blades runs preferred firmware \"4.2(1f)\", sys/chassis-1/blade-4 has the lowest chassis and slot and runs \"4.1(3b)\"",
+		},
+		{
+			name:      "firmware lookup failure",
+			policy:    PlacementFirmware,
+			firmware:  "4.2(1f)",
+			blades:    testBlades("sys/chassis-1/blade-1"),
+			firmwares: map[string]string{},
+			err:       "GetBladeFirmware() failure for blade sys/chassis-1/blade-1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			placementPeers = func(client *api.Client, nodeConfig *config.NodeConfig) (map[int]int, error) {
+				if test.peers == nil && test.peersErr == nil {
+					t.Fatalf("unexpected placement peers lookup")
+				}
+				return test.peers, test.peersErr
+			}
+			bladeFirmware = func(client *api.Client, bladeDn string) (string, error) {
+				if test.firmwares == nil {
+					t.Fatalf("unexpected blade firmware lookup")
+				}
+				if version, ok := test.firmwares[bladeDn]; ok {
+					return version, nil
+				}
+				return "", fmt.Errorf("no fw-status for %s", bladeDn)
+			}
+			blade, rationale, err := SelectBlade(&api.Client{}, test.blades, testPlacementConfig(test.policy, test.label, test.firmware))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if blade.Dn != test.blade {
+				t.Errorf("expected blade %s, got %s", test.blade, blade.Dn)
+			}
+			if !strings.Contains(rationale, test.rationale) {
+				t.Errorf("expected rationale containing %q, got %q", test.rationale, rationale)
+			}
+			if strings.Contains(rationale, "warning:") != strings.Contains(test.rationale, "warning:") {
+				t.Errorf("unexpected majority warning in rationale %q", rationale)
+			}
+		})
+	}
+}
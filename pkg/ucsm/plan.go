@@ -0,0 +1,128 @@
+package ucsm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/igor-feoktistov/go-ucsm-sdk/api"
+	"github.com/igor-feoktistov/go-ucsm-sdk/mo"
+	"github.com/igor-feoktistov/go-ucsm-sdk/util"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+const (
+	planBackend = "ucsm"
+)
+
+// planRecord records UCSM mutation in node configuration plan
+func planRecord(nodeConfig *config.NodeConfig, operation string, format string, args ...interface{}) {
+	nodeConfig.Plan.Record(planBackend, nodeConfig.Compute.UcsmCredentials.Host, operation, format, args...)
+}
+
+// planCreateServer records SP creation from SPT, blade assignment is planned per available blades
+func planCreateServer(client *api.Client, nodeConfig *config.NodeConfig) (sp *mo.LsServer, err error) {
+	spDn := nodeConfig.Compute.SpOrg + "/ls-" + nodeConfig.Compute.HostName
+	planRecord(nodeConfig, "SptInstantiate", "instantiate service profile %s in org %s from template %s", nodeConfig.Compute.HostName, nodeConfig.Compute.SpOrg, nodeConfig.Compute.SpTemplate)
+	sp = &mo.LsServer{Dn: spDn}
+	nodeConfig.Compute.SpDn = spDn
+	if len(nodeConfig.Compute.Description) > 0 || len(nodeConfig.Compute.Label) > 0 {
+		planRecord(nodeConfig, "SpSetAttributes", "set service profile %s description \"%s\", label \"%s\"", spDn, nodeConfig.Compute.Description, nodeConfig.Compute.Label)
+	}
+	planRecord(nodeConfig, "SpUnbindFromSpt", "unbind service profile %s from template", spDn)
+	for i := range nodeConfig.Network.FcInitiator {
+		if nodeConfig.Network.FcInitiator[i].Wwpn == "" {
+			nodeConfig.Network.FcInitiator[i].Wwpn = "<WWPN of planned vHBA " + nodeConfig.Network.FcInitiator[i].Name + ">"
+		}
+	}
+	planServerBoot(nodeConfig, spDn)
+	err = AssignBlade(client, nodeConfig)
+	return
+}
+
+// planServerBoot records programming of SP SAN and iSCSI boot targets
+func planServerBoot(nodeConfig *config.NodeConfig, spDn string) {
+	if len(nodeConfig.Network.FcInitiator) > 0 {
+		var vhbas []string
+		for _, initiator := range nodeConfig.Network.FcInitiator {
+			vhbas = append(vhbas, initiator.Name)
+		}
+		planRecord(nodeConfig, "SpSetSanBoot", "set service profile %s SAN boot from LUN id %d via vHBA %s", spDn, nodeConfig.Storage.BootLun.Id, strings.Join(vhbas, ","))
+	}
+	var initiatorProfile string
+	if len(nodeConfig.Network.IscsiInitiator) > 0 && nodeConfig.Network.IscsiChap.User != "" {
+		var targetProfile string
+		initiatorProfile, targetProfile = iscsiAuthProfileNames(nodeConfig.Compute.HostName)
+		planRecord(nodeConfig, "SetIscsiAuthProfile", "create iSCSI auth profile %s in org %s, user %s", initiatorProfile, nodeConfig.Compute.SpOrg, nodeConfig.Network.IscsiChap.User)
+		if nodeConfig.Network.IscsiChap.TargetUser != "" {
+			planRecord(nodeConfig, "SetIscsiAuthProfile", "create iSCSI auth profile %s in org %s, user %s", targetProfile, nodeConfig.Compute.SpOrg, nodeConfig.Network.IscsiChap.TargetUser)
+		}
+	}
+	for i := range nodeConfig.Network.IscsiInitiator {
+		if i > 1 {
+			break
+		}
+		initiator := nodeConfig.Network.IscsiInitiator[i]
+		var targetName string
+		var targets []string
+		if initiator.IscsiTarget != nil {
+			targetName = initiator.IscsiTarget.NodeName
+			targets = initiator.IscsiTarget.Interfaces
+			if len(targets) > 2 {
+				targets = targets[:2]
+			}
+		}
+		planRecord(nodeConfig, "SpSetIscsiBoot", "set service profile %s iSCSI boot on vNIC %s, initiator %s, IP %s, target %s via %s", spDn, initiator.Name, initiator.InitiatorName, initiator.Ip, targetName, strings.Join(targets, ","))
+		if initiatorProfile != "" {
+			planRecord(nodeConfig, "SpSetIscsiAuth", "set service profile %s iSCSI vNIC %s auth profile %s", spDn, initiator.Name, initiatorProfile)
+		}
+	}
+}
+
+// planAssignBlade records blade assignment, available blades are queried per BladeSpec
+func planAssignBlade(client *api.Client, nodeConfig *config.NodeConfig) (err error) {
+	var computeBlades *[]mo.ComputeBlade
+	bladeSpec := nodeConfig.Compute.BladeSpec
+	if computeBlades, err = util.ComputeBladeGetAvailable(client, &bladeSpec); err != nil {
+		err = fmt.Errorf("AssignBlade: ComputeBladeGetAvailable() failure: %s", err)
+		return
+	}
+	if len(*computeBlades) == 0 {
+		err = fmt.Errorf("AssignBlade: ComputeBladeGetAvailable(): no blades found per BladeSpec")
+		return
+	}
+	spDn := nodeConfig.Compute.SpOrg + "/ls-" + nodeConfig.Compute.HostName
+	if policy := nodeConfig.Compute.BladePlacement.Policy; policy != "" && policy != PlacementRandom {
+		var blade *mo.ComputeBlade
+		var rationale string
+		if blade, rationale, err = SelectBlade(client, *computeBlades, nodeConfig); err != nil {
+			err = fmt.Errorf("AssignBlade: %s", err)
+			return
+		}
+		nodeConfig.Compute.Placement = rationale
+		planRecord(nodeConfig, "SpAssignBlade", "assign service profile %s to blade %s (%s) and wait for association", spDn, blade.Dn, rationale)
+		return
+	}
+	var blades []string
+	for _, blade := range *computeBlades {
+		blades = append(blades, blade.Dn)
+	}
+	planRecord(nodeConfig, "SpAssignBlade", "assign service profile %s to one of %d available blades (%s) and wait for association", spDn, len(blades), strings.Join(blades, ","))
+	return
+}
+
+// planDeleteServer records SP deletion
+func planDeleteServer(nodeConfig *config.NodeConfig, spDn string) {
+	planRecord(nodeConfig, "SpDelete", "delete service profile %s", spDn)
+	if nodeConfig.Network.IscsiChap.User != "" {
+		initiatorProfile, targetProfile := iscsiAuthProfileNames(nodeConfig.Compute.HostName)
+		for _, profileName := range []string{initiatorProfile, targetProfile} {
+			planRecord(nodeConfig, "DeleteIscsiAuthProfile", "delete iSCSI auth profile %s in org %s", profileName, nodeConfig.Compute.SpOrg)
+		}
+	}
+}
+
+// planPowerState records SP power state change
+func planPowerState(nodeConfig *config.NodeConfig, spDn string, powerState string) {
+	planRecord(nodeConfig, "SpSetPowerState", "set service profile %s power state \"%s\"", spDn, powerState)
+	nodeConfig.Compute.Powerstate = powerState
+}
@@ -0,0 +1,231 @@
+package ucsm
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+
+	"github.com/igor-feoktistov/go-ucsm-sdk/api"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+// go-ucsm-sdk does not implement vHBA and SAN boot managed objects,
+// re-defining just enough of vnicFc, vnicFcNode and lsbootDef classes
+
+// VnicFc is SP vHBA
+type VnicFc struct {
+	XMLName  xml.Name `xml:"vnicFc"`
+	Dn       string   `xml:"dn,attr,omitempty"`
+	Name     string   `xml:"name,attr,omitempty"`
+	Addr     string   `xml:"addr,attr,omitempty"`
+	SwitchId string   `xml:"switchId,attr,omitempty"`
+}
+
+// VnicsFc is the list of SP vHBA's
+type VnicsFc struct {
+	XMLName xml.Name
+	Vnics   []VnicFc `xml:"vnicFc"`
+}
+
+// VnicFcNode is SP FC node (WWNN)
+type VnicFcNode struct {
+	XMLName xml.Name `xml:"vnicFcNode"`
+	Dn      string   `xml:"dn,attr,omitempty"`
+	Addr    string   `xml:"addr,attr,omitempty"`
+}
+
+// VnicFcNodeMo is configResolveDn container for VnicFcNode
+type VnicFcNodeMo struct {
+	XMLName    xml.Name
+	VnicFcNode VnicFcNode `xml:"vnicFcNode"`
+}
+
+// LsbootSanCatSanImagePath is SAN boot target
+type LsbootSanCatSanImagePath struct {
+	XMLName xml.Name `xml:"lsbootSanCatSanImagePath"`
+	Rn      string   `xml:"rn,attr,omitempty"`
+	Type    string   `xml:"type,attr,omitempty"`
+	Wwn     string   `xml:"wwn,attr,omitempty"`
+	Lun     string   `xml:"lun,attr,omitempty"`
+}
+
+// LsbootSanCatSanImage is SAN boot vHBA
+type LsbootSanCatSanImage struct {
+	XMLName  xml.Name                   `xml:"lsbootSanCatSanImage"`
+	Rn       string                     `xml:"rn,attr,omitempty"`
+	Type     string                     `xml:"type,attr,omitempty"`
+	VnicName string                     `xml:"vnicName,attr,omitempty"`
+	Paths    []LsbootSanCatSanImagePath `xml:"lsbootSanCatSanImagePath"`
+}
+
+// LsbootSan is SAN boot device
+type LsbootSan struct {
+	XMLName xml.Name               `xml:"lsbootSan"`
+	Rn      string                 `xml:"rn,attr,omitempty"`
+	Order   string                 `xml:"order,attr,omitempty"`
+	Images  []LsbootSanCatSanImage `xml:"lsbootSanCatSanImage"`
+}
+
+// LsbootDef is SP specific boot policy
+type LsbootDef struct {
+	XMLName         xml.Name   `xml:"lsbootDef"`
+	Dn              string     `xml:"dn,attr,omitempty"`
+	RebootOnUpdate  string     `xml:"rebootOnUpdate,attr,omitempty"`
+	EnforceVnicName string     `xml:"enforceVnicName,attr,omitempty"`
+	LsbootSan       *LsbootSan `xml:"lsbootSan,omitempty"`
+}
+
+// LsbootDefMo is configConfMo/configResolveDn container for LsbootDef
+type LsbootDefMo struct {
+	XMLName   xml.Name
+	LsbootDef LsbootDef `xml:"lsbootDef"`
+}
+
+// Re-defining mo.LsServer to allow empty BootPolicyName value
+type lsServerBootPolicy struct {
+	BootPolicyName string `xml:"bootPolicyName,attr"`
+}
+
+type lsServerBootPolicyMo struct {
+	XMLName  xml.Name
+	LsServer lsServerBootPolicy `xml:"lsServer"`
+}
+
+// SpGetVnicsFc retrieves SP or SPT vHBA's
+func SpGetVnicsFc(client *api.Client, spDn string) (vnicsFc *[]VnicFc, err error) {
+	var out VnicsFc
+	req := api.ConfigResolveChildrenRequest{
+		Cookie:         client.Cookie,
+		InDn:           spDn,
+		ClassId:        "vnicFc",
+		InHierarchical: "false",
+	}
+	if err = client.ConfigResolveChildren(req, &out); err == nil {
+		vnicsFc = &out.Vnics
+	}
+	return
+}
+
+// SpGetFcNode retrieves SP FC node WWNN
+func SpGetFcNode(client *api.Client, spDn string) (wwnn string, err error) {
+	var out VnicFcNodeMo
+	req := api.ConfigResolveDnRequest{
+		Cookie:         client.Cookie,
+		Dn:             spDn + "/fc-node",
+		InHierarchical: "false",
+	}
+	if err = client.ConfigResolveDn(req, &out); err == nil {
+		wwnn = out.VnicFcNode.Addr
+	}
+	return
+}
+
+// SpGetSanBoot retrieves SP specific SAN boot policy
+func SpGetSanBoot(client *api.Client, spDn string) (lsbootSan *LsbootSan, err error) {
+	var out LsbootDefMo
+	req := api.ConfigResolveDnRequest{
+		Cookie:         client.Cookie,
+		Dn:             spDn + "/boot-policy",
+		InHierarchical: "true",
+	}
+	if err = client.ConfigResolveDn(req, &out); err == nil {
+		lsbootSan = out.LsbootDef.LsbootSan
+	}
+	return
+}
+
+// SpSetSanBoot programs SP specific boot policy with SAN boot targets
+func SpSetSanBoot(client *api.Client, spDn string, lunId int, fcInitiators []config.FcInitiator) (err error) {
+	var lsServerOut lsServerBootPolicyMo
+	lsServerReq := api.ConfigConfMoRequest{
+		Cookie:         client.Cookie,
+		Dn:             spDn,
+		InHierarchical: "false",
+		InConfig: lsServerBootPolicyMo{
+			LsServer: lsServerBootPolicy{
+				BootPolicyName: "",
+			},
+		},
+	}
+	if err = client.ConfigConfMo(lsServerReq, &lsServerOut); err != nil {
+		err = fmt.Errorf("SpSetSanBoot: ConfigConfMo(lsServer) failure: %s", err)
+		return
+	}
+	lsbootSan := &LsbootSan{
+		Rn:    "san",
+		Order: "1",
+	}
+	for i, initiator := range fcInitiators {
+		if i > 1 {
+			break
+		}
+		imageType := []string{"primary", "secondary"}[i]
+		image := LsbootSanCatSanImage{
+			Rn:       "sanimg-" + imageType,
+			Type:     imageType,
+			VnicName: initiator.Name,
+		}
+		if initiator.FcTarget != nil {
+			for j, wwpn := range initiator.FcTarget.Interfaces {
+				if j > 1 {
+					break
+				}
+				pathType := []string{"primary", "secondary"}[j]
+				image.Paths = append(image.Paths, LsbootSanCatSanImagePath{
+					Rn:   "path-" + pathType,
+					Type: pathType,
+					Wwn:  wwpn,
+					Lun:  strconv.Itoa(lunId),
+				})
+			}
+		}
+		lsbootSan.Images = append(lsbootSan.Images, image)
+	}
+	var lsbootOut LsbootDefMo
+	lsbootReq := api.ConfigConfMoRequest{
+		Cookie:         client.Cookie,
+		Dn:             spDn + "/boot-policy",
+		InHierarchical: "true",
+		InConfig: LsbootDefMo{
+			LsbootDef: LsbootDef{
+				Dn:              spDn + "/boot-policy",
+				RebootOnUpdate:  "no",
+				EnforceVnicName: "yes",
+				LsbootSan:       lsbootSan,
+			},
+		},
+	}
+	if err = client.ConfigConfMo(lsbootReq, &lsbootOut); err != nil {
+		err = fmt.Errorf("SpSetSanBoot: ConfigConfMo(lsbootDef) failure: %s", err)
+	}
+	return
+}
+
+// setFcInitiators discovers vHBA's WWPN's and WWNN for configured FC initiators
+func setFcInitiators(client *api.Client, spDn string, nodeConfig *config.NodeConfig) (err error) {
+	var vnicsFc *[]VnicFc
+	if vnicsFc, err = SpGetVnicsFc(client, spDn); err != nil {
+		err = fmt.Errorf("SpGetVnicsFc() failure: %s", err)
+		return
+	}
+	var wwnn string
+	if wwnn, err = SpGetFcNode(client, spDn); err != nil {
+		err = fmt.Errorf("SpGetFcNode() failure: %s", err)
+		return
+	}
+	for i := range nodeConfig.Network.FcInitiator {
+		var found int = 0
+		for _, vnicFc := range *vnicsFc {
+			if vnicFc.Name == nodeConfig.Network.FcInitiator[i].Name {
+				nodeConfig.Network.FcInitiator[i].Wwpn = vnicFc.Addr
+				nodeConfig.Network.FcInitiator[i].Wwnn = wwnn
+				found++
+			}
+		}
+		if found == 0 {
+			err = fmt.Errorf("no vHBAs found in SP \"%s\" that match fcInitiator \"%s\"", spDn, nodeConfig.Network.FcInitiator[i].Name)
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,153 @@
+package ucsm
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/igor-feoktistov/go-ucsm-sdk/api"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	sessionErrorRegexp = regexp.MustCompile(`errorCode="552"`)
+	aaaRequestRegexp   = regexp.MustCompile(`^\s*<aaa`)
+)
+
+// sessionTransport keeps UCSM session cookie current for api.Client,
+// api.Client is logged in once and keeps its cookie (alias) for the life of the session,
+// the alias is substituted with the current cookie in every request, so that the cookie is refreshed
+// and the session is re-established without changing api.Client shared by concurrent callers.
+// Request failed on session error is retried once with the cookie of re-established session
+type sessionTransport struct {
+	mu       sync.Mutex
+	base     http.RoundTripper
+	endPoint string
+	username string
+	password string
+	alias    string
+	cookie   string
+}
+
+// newSessionTransport creates session transport, alias is set once logged in
+func newSessionTransport(endPoint string, username string, password string) *sessionTransport {
+	return &sessionTransport{
+		base: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		endPoint: endPoint,
+		username: username,
+		password: password,
+	}
+}
+
+// setAlias sets cookie of logged in api.Client
+func (t *sessionTransport) setAlias(cookie string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.alias = cookie
+	t.cookie = cookie
+}
+
+// current gets current session cookie
+func (t *sessionTransport) current() (alias string, cookie string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.alias, t.cookie
+}
+
+// newAaaClient creates client for aaa requests with the current cookie, requests are sent with base transport
+func (t *sessionTransport) newAaaClient(cookie string) (client *api.Client, err error) {
+	if client, err = api.NewClient(api.Config{Endpoint: t.endPoint, Username: t.username, Password: t.password, HttpClient: &http.Client{Transport: t.base}}); err == nil {
+		client.Cookie = cookie
+	}
+	return
+}
+
+// refresh requests new session cookie with aaaRefresh, returns session refresh period
+func (t *sessionTransport) refresh() (refreshPeriod time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var client *api.Client
+	if client, err = t.newAaaClient(t.cookie); err != nil {
+		return
+	}
+	var resp *api.AaaRefreshResponse
+	if resp, err = client.AaaRefresh(); err != nil {
+		err = fmt.Errorf("AaaRefresh() failure: %s", err)
+		return
+	}
+	t.cookie = resp.OutCookie
+	refreshPeriod = time.Duration(resp.OutRefreshPeriod) * time.Second
+	return
+}
+
+// renew logs in again unless the stale cookie is already replaced by concurrent request
+func (t *sessionTransport) renew(staleCookie string) (cookie string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cookie != staleCookie {
+		cookie = t.cookie
+		return
+	}
+	var client *api.Client
+	if client, err = t.newAaaClient(""); err != nil {
+		return
+	}
+	if _, err = client.AaaLogin(); err != nil {
+		err = fmt.Errorf("AaaLogin() failure: %s", err)
+		return
+	}
+	t.cookie = client.Cookie
+	cookie = t.cookie
+	return
+}
+
+// send sends request with alias substituted with the cookie
+func (t *sessionTransport) send(req *http.Request, body []byte, alias string, cookie string) (resp *http.Response, err error) {
+	if alias != "" && cookie != alias {
+		body = bytes.ReplaceAll(body, []byte(`"`+alias+`"`), []byte(`"`+cookie+`"`))
+	}
+	r := req.Clone(req.Context())
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	return t.base.RoundTrip(r)
+}
+
+// RoundTrip sends request with the current cookie, request failed on session error is retried once
+// with the cookie of re-established session, aaa requests are not retried
+func (t *sessionTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	var body []byte
+	if req.Body != nil {
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return
+		}
+	}
+	alias, cookie := t.current()
+	if resp, err = t.send(req, body, alias, cookie); err != nil || alias == "" || aaaRequestRegexp.Match(body) {
+		return
+	}
+	var respBody []byte
+	respBody, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return
+	}
+	if sessionErrorRegexp.Match(respBody) {
+		if cookie, err = t.renew(cookie); err == nil {
+			log.Warnf("UcsmLogin: session to %s is re-established, request is retried", t.endPoint)
+			return t.send(req, body, alias, cookie)
+		}
+		log.Warnf("UcsmLogin: failure to re-establish session to %s: %s", t.endPoint, err)
+		err = nil
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	return
+}
@@ -0,0 +1,298 @@
+package ucsm
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/igor-feoktistov/go-ucsm-sdk/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	loginBackoffMin = 5
+	loginBackoffMax = 60
+)
+
+// UCSM error codes returned for bad credentials or locked account
+var authErrorCodes = map[string]bool{
+	"551": true,
+	"553": true,
+	"554": true,
+}
+
+var errorCodeRegexp = regexp.MustCompile(`\(code ([0-9]+)\)`)
+
+// SessionCache keeps one UCSM session per domain and credentials, so that concurrent
+// resources share the session rather than login and logout per call,
+// cached sessions are kept alive with aaaKeepAlive, session cookie is refreshed with aaaRefresh
+// every half of session refresh period, and sessions are re-established on failure
+type SessionCache struct {
+	mu                sync.Mutex
+	sessions          map[string]*session
+	keepAliveInterval time.Duration
+	closed            bool
+}
+
+type session struct {
+	client        *api.Client
+	transport     *sessionTransport
+	refs          int
+	refreshPeriod time.Duration
+	err           error
+	ready         chan struct{}
+	done          chan struct{}
+	stale         bool
+}
+
+// sessionCache is used by UcsmLogin if set
+var sessionCache *SessionCache
+
+// SetSessionCache makes UcsmLogin reuse sessions from cache,
+// nil cache restores a new session per call
+func SetSessionCache(cache *SessionCache) {
+	sessionCache = cache
+}
+
+// NewSessionCache creates session cache, cached sessions are kept alive every keepAliveInterval
+// or half of session refresh period reported by UCSM, whichever is shorter,
+// session cookie is refreshed every half of session refresh period
+func NewSessionCache(keepAliveInterval time.Duration) *SessionCache {
+	return &SessionCache{
+		sessions:          make(map[string]*session),
+		keepAliveInterval: keepAliveInterval,
+	}
+}
+
+// sessionKey makes cache key, password is hashed to keep it out of the key
+func sessionKey(endPoint string, username string, password string) string {
+	return fmt.Sprintf("%s/%s/%x", endPoint, username, sha256.Sum256([]byte(password)))
+}
+
+// IsAuthError returns true for UCSM errors which are not resolved by retry, i.e. bad credentials
+func IsAuthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if m := errorCodeRegexp.FindStringSubmatch(err.Error()); m != nil && authErrorCodes[m[1]] {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "authentication failed") || strings.Contains(msg, "authorization failed")
+}
+
+// isSessionError returns true for UCSM errors caused by expired or invalid session cookie
+func isSessionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if m := errorCodeRegexp.FindStringSubmatch(err.Error()); m != nil && m[1] == "552" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "authorization required")
+}
+
+// aaaLogin logs in to UCSM, returns client, its session transport and session refresh period
+func aaaLogin(endPoint string, username string, password string) (client *api.Client, transport *sessionTransport, refreshPeriod time.Duration, err error) {
+	var resp *api.AaaLoginResponse
+	transport = newSessionTransport(endPoint, username, password)
+	if client, err = api.NewClient(api.Config{Endpoint: endPoint, Username: username, Password: password, HttpClient: &http.Client{Transport: transport}}); err != nil {
+		return
+	}
+	if resp, err = client.AaaLogin(); err == nil {
+		transport.setAlias(client.Cookie)
+		refreshPeriod = time.Duration(resp.OutRefreshPeriod) * time.Second
+	}
+	return
+}
+
+// loginWithRetry logs in to UCSM, authentication errors fail fast,
+// transient errors are retried with exponential backoff up to transientWaitMax
+func loginWithRetry(endPoint string, username string, password string) (client *api.Client, transport *sessionTransport, refreshPeriod time.Duration, err error) {
+	giveupTime := time.Now().Add(time.Second * time.Duration(transientWaitMax))
+	backoff := time.Duration(loginBackoffMin) * time.Second
+	for {
+		if client, transport, refreshPeriod, err = aaaLogin(endPoint, username, password); err == nil {
+			return
+		}
+		if IsAuthError(err) {
+			err = fmt.Errorf("UcsmLogin: authentication failure for user \"%s\": %s", username, err)
+			return
+		}
+		if time.Now().Add(backoff).After(giveupTime) {
+			err = fmt.Errorf("UcsmLogin: login failure after %d seconds of retries: %s", transientWaitMax, err)
+			return
+		}
+		log.Warnf("UcsmLogin: login to %s failed, retry in %s: %s", endPoint, backoff, err)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > time.Duration(loginBackoffMax)*time.Second {
+			backoff = time.Duration(loginBackoffMax) * time.Second
+		}
+	}
+}
+
+// Get gets cached session for UCSM domain and credentials or logs in,
+// concurrent calls for the same key wait for a single login
+func (cache *SessionCache) Get(endPoint string, username string, password string) (client *api.Client, err error) {
+	key := sessionKey(endPoint, username, password)
+	cache.mu.Lock()
+	if cache.closed {
+		cache.mu.Unlock()
+		client, _, _, err = loginWithRetry(endPoint, username, password)
+		return
+	}
+	s, ok := cache.sessions[key]
+	if ok && s.stale {
+		delete(cache.sessions, key)
+		ok = false
+	}
+	if !ok {
+		s = &session{ready: make(chan struct{}), done: make(chan struct{})}
+		cache.sessions[key] = s
+	}
+	s.refs++
+	cache.mu.Unlock()
+	if !ok {
+		c, transport, refreshPeriod, loginErr := loginWithRetry(endPoint, username, password)
+		cache.mu.Lock()
+		s.client, s.transport, s.refreshPeriod, s.err = c, transport, refreshPeriod, loginErr
+		if s.err != nil {
+			if cache.sessions[key] == s {
+				delete(cache.sessions, key)
+			}
+		} else if cache.closed {
+			s.stale = true
+			close(s.done)
+		} else {
+			go cache.keepAlive(key, s)
+		}
+		cache.mu.Unlock()
+		close(s.ready)
+	}
+	<-s.ready
+	if err = s.err; err != nil {
+		cache.mu.Lock()
+		s.refs--
+		cache.mu.Unlock()
+		return
+	}
+	client = s.client
+	return
+}
+
+// Release releases session obtained with Get, session is logged out only if it is not cached anymore
+// and not in use, client not known to cache is logged out
+func (cache *SessionCache) Release(client *api.Client) {
+	cache.mu.Lock()
+	for key, s := range cache.sessions {
+		if s.client == client {
+			s.refs--
+			if s.refs <= 0 && s.stale {
+				delete(cache.sessions, key)
+				cache.mu.Unlock()
+				client.AaaLogout()
+				return
+			}
+			cache.mu.Unlock()
+			return
+		}
+	}
+	cache.mu.Unlock()
+	client.AaaLogout()
+}
+
+// Invalidate marks session stale, i.e. after session error, next Get logs in again
+func (cache *SessionCache) Invalidate(client *api.Client) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	for _, s := range cache.sessions {
+		if s.client == client && !s.stale {
+			s.stale = true
+			close(s.done)
+		}
+	}
+}
+
+// keepAlive sends aaaKeepAlive for cached session until session is stale or cache is closed,
+// session cookie is refreshed with aaaRefresh once half of session refresh period passed since login or last refresh,
+// failed session is marked stale to be replaced on next Get
+func (cache *SessionCache) keepAlive(key string, s *session) {
+	refreshInterval := s.refreshPeriod / 2
+	interval := cache.keepAliveInterval
+	if refreshInterval > 0 && (interval == 0 || refreshInterval < interval) {
+		interval = refreshInterval
+	}
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	refreshed := time.Now()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			var err error
+			if refreshInterval > 0 && time.Since(refreshed) >= refreshInterval {
+				var refreshPeriod time.Duration
+				if refreshPeriod, err = s.transport.refresh(); err == nil {
+					refreshed = time.Now()
+					if refreshPeriod > 0 && refreshPeriod/2 != refreshInterval {
+						refreshInterval = refreshPeriod / 2
+					}
+				}
+			} else {
+				_, err = s.client.AaaKeepAlive()
+			}
+			if err != nil {
+				log.Warnf("UcsmLogin: keepalive failure, session will be re-established: %s", err)
+				cache.mu.Lock()
+				if !s.stale {
+					s.stale = true
+					close(s.done)
+				}
+				idle := s.refs <= 0
+				if idle && cache.sessions[key] == s {
+					delete(cache.sessions, key)
+				}
+				cache.mu.Unlock()
+				return
+			}
+		}
+	}
+}
+
+// Close stops keepalives and logs out idle sessions, sessions in use are logged out on release,
+// sessions are not cached after Close
+func (cache *SessionCache) Close() {
+	var idle []*api.Client
+	cache.mu.Lock()
+	cache.closed = true
+	for key, s := range cache.sessions {
+		select {
+		case <-s.ready:
+		default:
+			continue
+		}
+		if s.err != nil {
+			continue
+		}
+		if !s.stale {
+			s.stale = true
+			close(s.done)
+		}
+		if s.refs <= 0 {
+			idle = append(idle, s.client)
+			delete(cache.sessions, key)
+		}
+	}
+	cache.mu.Unlock()
+	for _, client := range idle {
+		client.AaaLogout()
+	}
+}
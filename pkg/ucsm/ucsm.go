@@ -2,7 +2,6 @@ package ucsm
 
 import (
 	"fmt"
-	"math/rand"
 	"net"
 	"strconv"
 	"time"
@@ -11,6 +10,7 @@ import (
 	"github.com/igor-feoktistov/go-ucsm-sdk/mo"
 	"github.com/igor-feoktistov/go-ucsm-sdk/util"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -19,40 +19,68 @@ const (
 	transientWaitMax = 600
 )
 
-// UcsmLogin return client with the authentication cookie
+// UcsmLogin return client with the authentication cookie,
+// session is shared per UCSM domain if session cache is set
 func UcsmLogin(endPoint string, username string, password string) (client *api.Client, err error) {
-	giveupTime := time.Now().Add(time.Second * time.Duration(transientWaitMax))
-        for time.Now().Before(giveupTime) {
-		if client, err = util.AaaLogin(endPoint, username, password); err == nil {
-			break
-		}
-		time.Sleep(time.Duration(30 * time.Second))
+	if sessionCache != nil {
+		return sessionCache.Get(endPoint, username, password)
 	}
+	client, _, _, err = loginWithRetry(endPoint, username, password)
 	return
 }
 
+// UcsmLogout releases client obtained with UcsmLogin, cached session is invalidated on session error
+func UcsmLogout(client *api.Client, err *error) {
+	if sessionCache == nil {
+		client.AaaLogout()
+		return
+	}
+	if err != nil && isSessionError(*err) {
+		sessionCache.Invalidate(client)
+	}
+	sessionCache.Release(client)
+}
+
 // AssignBlade assigns physical blade to SP
 func AssignBlade(client *api.Client, nodeConfig *config.NodeConfig) (err error) {
 	var computeBlades *[]mo.ComputeBlade
 	var assignErr error
+	if nodeConfig.Plan != nil {
+		return planAssignBlade(client, nodeConfig)
+	}
+	failedBlades := make(map[string]bool)
 	for i := 0; i < assignTryMax; i++ {
-		var pnDn string
+		var pnDn, rationale string
+		var blades []mo.ComputeBlade
+		var blade *mo.ComputeBlade
 		bladeSpec := nodeConfig.Compute.BladeSpec
 		if computeBlades, err = util.ComputeBladeGetAvailable(client, &bladeSpec); err != nil {
 			err = fmt.Errorf("AssignBlade: ComputeBladeGetAvailable() failure: %s", err)
 			return
 		}
-		if len(*computeBlades) > 0 {
-			rand.Seed(time.Now().UnixNano())
-			pnDn = (*computeBlades)[rand.Intn(len(*computeBlades))].Dn
-		} else {
+		for _, computeBlade := range *computeBlades {
+			if !failedBlades[computeBlade.Dn] {
+				blades = append(blades, computeBlade)
+			}
+		}
+		if len(blades) == 0 {
 			err = fmt.Errorf("AssignBlade: ComputeBladeGetAvailable(): no blades found per BladeSpec")
+			if assignErr != nil {
+				err = assignErr
+			}
+			return
+		}
+		if blade, rationale, err = SelectBlade(client, blades, nodeConfig); err != nil {
+			err = fmt.Errorf("AssignBlade: %s", err)
 			return
 		}
+		pnDn = blade.Dn
+		log.Infof("AssignBlade: service profile %s placement %s", nodeConfig.Compute.SpDn, rationale)
 		if _, assignErr = util.SpAssignBlade(client, nodeConfig.Compute.SpDn, pnDn); assignErr == nil {
+			nodeConfig.Compute.Placement = rationale
 			var assocState string
-			if assocState, assignErr = util.SpWaitForAssociation(client, nodeConfig.Compute.SpDn, assignWaitMax); assignErr != nil {
-				err = fmt.Errorf("AssignBlade: SpWaitForAssociation() failure: %s", assignErr)
+			if assocState, assignErr = spWaitForAssociation(client, nodeConfig.Compute.SpDn, pnDn, assignWaitMax); assignErr != nil {
+				err = spDiagnosticsError(client, nodeConfig.Compute.SpDn, pnDn, fmt.Errorf("AssignBlade: SpWaitForAssociation() failure: %s", assignErr))
 				return
 			}
 			if assocState == "associated" {
@@ -84,6 +112,9 @@ func AssignBlade(client *api.Client, nodeConfig *config.NodeConfig) (err error)
 						}
 					}
 				}
+				if len(nodeConfig.Network.IscsiInitiator) == 0 {
+					return
+				}
 				var vnicsIScsi *[]mo.VnicIScsi
 				if vnicsIScsi, err = util.SpGetVnicsIScsi(client, nodeConfig.Compute.SpDn); err != nil {
 					err = fmt.Errorf("AssignBlade: SpGetVnicsIScsi(): %s", err)
@@ -112,8 +143,10 @@ func AssignBlade(client *api.Client, nodeConfig *config.NodeConfig) (err error)
 				}
 				return
 			}
-			assignErr = fmt.Errorf("AssignBlade: SpWaitForAssociation(): association state is %s", assocState)
+			failedBlades[pnDn] = true
+			assignErr = spDiagnosticsError(client, nodeConfig.Compute.SpDn, pnDn, fmt.Errorf("AssignBlade: SpWaitForAssociation(): association state is %s", assocState))
 		} else {
+			failedBlades[pnDn] = true
 			assignErr = fmt.Errorf("AssignBlade: SpAssignBlade() failure: %s", assignErr)
 		}
 		time.Sleep(2 * time.Second)
@@ -131,7 +164,10 @@ func CreateServer(nodeConfig *config.NodeConfig) (sp *mo.LsServer, err error) {
 		err = fmt.Errorf("CreateServer: AaaLogin() failure: %s", err)
 		return
 	}
-	defer client.AaaLogout()
+	defer UcsmLogout(client, &err)
+	if nodeConfig.Plan != nil {
+		return planCreateServer(client, nodeConfig)
+	}
 	if sp, err = util.SptInstantiate(client, nodeConfig.Compute.SpTemplate, nodeConfig.Compute.SpOrg, nodeConfig.Compute.HostName); err != nil {
 		err = fmt.Errorf("CreateServer: SptInstantiate() failure: %s", err)
 		return
@@ -147,11 +183,49 @@ func CreateServer(nodeConfig *config.NodeConfig) (sp *mo.LsServer, err error) {
 		err = fmt.Errorf("CreateServer: SpUnbindFromSpt() failure: %s", err)
 		return
 	}
+	if len(nodeConfig.Network.FcInitiator) > 0 {
+		if err = setFcInitiators(client, sp.Dn, nodeConfig); err != nil {
+			err = fmt.Errorf("CreateServer: %s", err)
+			return
+		}
+		if err = SpSetSanBoot(client, sp.Dn, nodeConfig.Storage.BootLun.Id, nodeConfig.Network.FcInitiator); err != nil {
+			err = fmt.Errorf("CreateServer: %s", err)
+			return
+		}
+	}
+	if err = setIscsiBoot(client, sp.Dn, nodeConfig); err != nil {
+		err = fmt.Errorf("CreateServer: %s", err)
+		return
+	}
+	if len(nodeConfig.Network.NvmeHost) > 0 {
+	        if lsServers, err = util.ServerGet(client, nodeConfig.Compute.SpDn, "instance"); err != nil {
+		        err = fmt.Errorf("CreateServer: ServerGet() failure: %s", err)
+		        return
+	        }
+	        for i := range nodeConfig.Network.NvmeHost {
+	                nodeConfig.Network.NvmeHost[i].HostNqn = "nqn.2014-08.org.nvmexpress:uuid:" + lsServers[0].Uuid
+	        }
+	}
+	err = AssignBlade(client, nodeConfig)
+	return
+}
+
+// setIscsiBoot programs SP iSCSI vNIC's with boot targets
+func setIscsiBoot(client *api.Client, spDn string, nodeConfig *config.NodeConfig) (err error) {
+	var initiatorAuthProfile, targetAuthProfile string
+	if len(nodeConfig.Network.IscsiInitiator) > 0 {
+		if initiatorAuthProfile, targetAuthProfile, err = setIscsiAuthProfiles(client, nodeConfig); err != nil {
+			return
+		}
+	}
 	var iscsiVnicAddr mo.VnicIPv4IscsiAddr
 	var ipv4Net *net.IPNet
-	for i := range nodeConfig.Network.IscsiInitiator[:2] {
+	for i := range nodeConfig.Network.IscsiInitiator {
+		if i > 1 {
+			break
+		}
 		if _, ipv4Net, err = net.ParseCIDR(nodeConfig.Network.IscsiInitiator[i].Subnet); err != nil {
-			err = fmt.Errorf("CreateServer: ParseCIDR() failure for subnet %s: %s", nodeConfig.Network.IscsiInitiator[i].Subnet, err)
+			err = fmt.Errorf("ParseCIDR() failure for subnet %s: %s", nodeConfig.Network.IscsiInitiator[i].Subnet, err)
 			return
 		}
 		iscsiVnicAddr = mo.VnicIPv4IscsiAddr{
@@ -165,41 +239,70 @@ func CreateServer(nodeConfig *config.NodeConfig) (sp *mo.LsServer, err error) {
 		for j := range nodeConfig.Network.IscsiInitiator[i].IscsiTarget.Interfaces {
 			if j < 2 {
 				iscsiTargets = append(iscsiTargets, mo.VnicIScsiStaticTargetIf{
-					IpAddress: nodeConfig.Network.IscsiInitiator[i].IscsiTarget.Interfaces[j],
-					Name:      nodeConfig.Network.IscsiInitiator[i].IscsiTarget.NodeName,
-					Port:      "3260",
-					Priority:  strconv.Itoa(j + 1),
-					VnicLuns:  []mo.VnicLun{{Bootable: "yes", Id: "0"}},
+					IpAddress:       nodeConfig.Network.IscsiInitiator[i].IscsiTarget.Interfaces[j],
+					Name:            nodeConfig.Network.IscsiInitiator[i].IscsiTarget.NodeName,
+					Port:            "3260",
+					Priority:        strconv.Itoa(j + 1),
+					AuthProfileName: targetAuthProfile,
+					VnicLuns:        []mo.VnicLun{{Bootable: "yes", Id: "0"}},
 				})
 			}
 		}
-		if _, err = util.SpSetIscsiBoot(client, sp.Dn, nodeConfig.Network.IscsiInitiator[i].Name, nodeConfig.Network.IscsiInitiator[i].InitiatorName, iscsiVnicAddr, iscsiTargets); err != nil {
-			err = fmt.Errorf("CreateServer: SpSetIscsiBoot() failure for iSCSI interface %s: %s", nodeConfig.Network.IscsiInitiator[i].Name, err)
+		if _, err = util.SpSetIscsiBoot(client, spDn, nodeConfig.Network.IscsiInitiator[i].Name, nodeConfig.Network.IscsiInitiator[i].InitiatorName, iscsiVnicAddr, iscsiTargets); err != nil {
+			err = fmt.Errorf("SpSetIscsiBoot() failure for iSCSI interface %s: %s", nodeConfig.Network.IscsiInitiator[i].Name, err)
 			return
 		}
+		if initiatorAuthProfile != "" {
+			if err = SpSetIscsiAuth(client, spDn, nodeConfig.Network.IscsiInitiator[i].Name, initiatorAuthProfile); err != nil {
+				return
+			}
+		}
 	}
-	if len(nodeConfig.Network.NvmeHost) > 0 {
-	        if lsServers, err = util.ServerGet(client, nodeConfig.Compute.SpDn, "instance"); err != nil {
-		        err = fmt.Errorf("CreateServer: ServerGet() failure: %s", err)
-		        return
-	        }
-	        for i := range nodeConfig.Network.NvmeHost {
-	                nodeConfig.Network.NvmeHost[i].HostNqn = "nqn.2014-08.org.nvmexpress:uuid:" + lsServers[0].Uuid
-	        }
+	return
+}
+
+// SetServerBootTargets re-programs SP boot targets (usually after storage failover)
+func SetServerBootTargets(nodeConfig *config.NodeConfig) (err error) {
+	var client *api.Client
+	spDn := nodeConfig.Compute.SpOrg + "/ls-" + nodeConfig.Compute.HostName
+	if nodeConfig.Plan != nil {
+		planServerBoot(nodeConfig, spDn)
+		return
+	}
+	if client, err = UcsmLogin("https://"+nodeConfig.Compute.UcsmCredentials.Host+"/", nodeConfig.Compute.UcsmCredentials.User, nodeConfig.Compute.UcsmCredentials.Password); err != nil {
+		err = fmt.Errorf("SetServerBootTargets: AaaLogin() failure: %s", err)
+		return
+	}
+	defer UcsmLogout(client, &err)
+	if len(nodeConfig.Network.FcInitiator) > 0 {
+		if err = SpSetSanBoot(client, spDn, nodeConfig.Storage.BootLun.Id, nodeConfig.Network.FcInitiator); err != nil {
+			err = fmt.Errorf("SetServerBootTargets: %s", err)
+			return
+		}
+	}
+	if err = setIscsiBoot(client, spDn, nodeConfig); err != nil {
+		err = fmt.Errorf("SetServerBootTargets: %s", err)
 	}
-	err = AssignBlade(client, nodeConfig)
 	return
 }
 
 // CreateServerPreflight does sanity check before SP creation
 func CreateServerPreflight(nodeConfig *config.NodeConfig) (err error) {
 	var client *api.Client
+	if nodeConfig.Compute.SpOrg == "" || nodeConfig.Compute.SpTemplate == "" {
+		err = fmt.Errorf("CreateServerPreflight: spOrg and spTemplate are required for UCSM compute")
+		return
+	}
+	if err = checkPlacement(nodeConfig); err != nil {
+		err = fmt.Errorf("CreateServerPreflight: %s", err)
+		return
+	}
 	client, err = UcsmLogin("https://"+nodeConfig.Compute.UcsmCredentials.Host+"/", nodeConfig.Compute.UcsmCredentials.User, nodeConfig.Compute.UcsmCredentials.Password)
 	if err != nil {
 		err = fmt.Errorf("CreateServerPreflight: AaaLogin() failure: %s", err)
 		return
 	}
-	defer client.AaaLogout()
+	defer UcsmLogout(client, &err)
 	var computeBlades *[]mo.ComputeBlade
 	if computeBlades, err = util.ComputeBladeGetAvailable(client, &nodeConfig.Compute.BladeSpec); err != nil {
 		err = fmt.Errorf("CreateServerPreflight: ComputeBladeGetAvailable() failure: %s", err)
@@ -214,10 +317,29 @@ func CreateServerPreflight(nodeConfig *config.NodeConfig) (err error) {
 		err = fmt.Errorf("CreateServerPreflight: SpGetVnicsIScsi(): %s", err)
 		return
 	}
-	if len(*vnicsIScsi) == 0 {
+	if len(*vnicsIScsi) == 0 && len(nodeConfig.Network.IscsiInitiator) > 0 {
 		err = fmt.Errorf("CreateServerPreflight: SpGetVnicsIScsi(): SPT \"%s\" is not configured for iSCSI boot", nodeConfig.Compute.SpTemplate)
 		return
 	}
+	if len(nodeConfig.Network.FcInitiator) > 0 {
+		var vnicsFc *[]VnicFc
+		if vnicsFc, err = SpGetVnicsFc(client, nodeConfig.Compute.SpTemplate); err != nil {
+			err = fmt.Errorf("CreateServerPreflight: SpGetVnicsFc(): %s", err)
+			return
+		}
+		for _, initiator := range nodeConfig.Network.FcInitiator {
+			var found int = 0
+			for _, vnic := range *vnicsFc {
+				if vnic.Name == initiator.Name {
+					found++
+				}
+			}
+			if found == 0 {
+				err = fmt.Errorf("CreateServerPreflight: no vHBAs found in SPT \"%s\" that match fcInitiator \"%s\"", nodeConfig.Compute.SpTemplate, initiator.Name)
+				return
+			}
+		}
+	}
 	var vnicsEther *[]mo.VnicEther
 	if vnicsEther, err = util.SpGetVnicsEther(client, nodeConfig.Compute.SpTemplate); err != nil {
 		err = fmt.Errorf("CreateServerPreflight: SpGetVnicsEther(): %s", err)
@@ -227,7 +349,10 @@ func CreateServerPreflight(nodeConfig *config.NodeConfig) (err error) {
 		err = fmt.Errorf("CreateServerPreflight: SpGetVnicsEther(): no ethernet vNICs found in SPT \"%s\"", nodeConfig.Compute.SpTemplate)
 		return
 	}
-	for i := range nodeConfig.Network.IscsiInitiator[:2] {
+	for i := range nodeConfig.Network.IscsiInitiator {
+		if i > 1 {
+			break
+		}
 		if _, _, err = net.ParseCIDR(nodeConfig.Network.IscsiInitiator[i].Subnet); err != nil {
 			err = fmt.Errorf("CreateServerPreflight: ParseCIDR(): failure for subnet %s: %s", nodeConfig.Network.IscsiInitiator[i].Subnet, err)
 			return
@@ -268,7 +393,7 @@ func DiscoverServer(nodeConfig *config.NodeConfig) (serverExists bool, err error
 		err = fmt.Errorf("DiscoverServer: AaaLogin() failure: %s", err)
 		return
 	}
-	defer client.AaaLogout()
+	defer UcsmLogout(client, &err)
 	var lsServers []*mo.LsServer
 	nodeConfig.Compute.SpDn = nodeConfig.Compute.SpOrg + "/ls-" + nodeConfig.Compute.HostName
 	if lsServers, err = util.ServerGet(client, nodeConfig.Compute.SpDn, "instance"); err != nil {
@@ -321,16 +446,42 @@ func DiscoverServer(nodeConfig *config.NodeConfig) (serverExists bool, err error
 			return
 		}
 	}
+	if len(nodeConfig.Network.FcInitiator) > 0 {
+		if err = setFcInitiators(client, nodeConfig.Compute.SpDn, nodeConfig); err != nil {
+			err = fmt.Errorf("DiscoverServer: %s", err)
+			return
+		}
+		var lsbootSan *LsbootSan
+		if lsbootSan, err = SpGetSanBoot(client, nodeConfig.Compute.SpDn); err != nil {
+			err = fmt.Errorf("DiscoverServer: SpGetSanBoot(): %s", err)
+			return
+		}
+		if lsbootSan != nil {
+			for i := range nodeConfig.Network.FcInitiator {
+				for _, image := range lsbootSan.Images {
+					if image.VnicName == nodeConfig.Network.FcInitiator[i].Name {
+						nodeConfig.Network.FcInitiator[i].FcTarget = &config.FcTarget{}
+						for _, path := range image.Paths {
+							nodeConfig.Network.FcInitiator[i].FcTarget.Interfaces = append(nodeConfig.Network.FcInitiator[i].FcTarget.Interfaces, path.Wwn)
+						}
+					}
+				}
+			}
+		}
+	}
 	var vnicsIScsi *[]mo.VnicIScsi
 	if vnicsIScsi, err = util.SpGetVnicsIScsi(client, nodeConfig.Compute.SpDn); err != nil {
 		err = fmt.Errorf("DiscoverServer: SpGetVnicsIScsi(): %s", err)
 		return
 	}
-	if len(*vnicsIScsi) == 0 {
+	if len(*vnicsIScsi) == 0 && len(nodeConfig.Network.IscsiInitiator) > 0 {
 		err = fmt.Errorf("DiscoverServer: SpGetVnicsIScsi(): SP \"%s\" is not configured for iSCSI boot", nodeConfig.Compute.SpDn)
 		return
 	}
-	for i := range nodeConfig.Network.IscsiInitiator[:2] {
+	for i := range nodeConfig.Network.IscsiInitiator {
+		if i > 1 {
+			break
+		}
 		var found int = 0
 		for _, vnicIScsi := range *vnicsIScsi {
 			if vnicIScsi.Name == nodeConfig.Network.IscsiInitiator[i].Name {
@@ -382,12 +533,16 @@ func DiscoverServer(nodeConfig *config.NodeConfig) (serverExists bool, err error
 // UpdateServerPreflight check blade availability before re-assigning
 func UpdateServerPreflight(nodeConfig *config.NodeConfig) (err error) {
 	var client *api.Client
+	if err = checkPlacement(nodeConfig); err != nil {
+		err = fmt.Errorf("UpdateServerPreflight: %s", err)
+		return
+	}
 	client, err = UcsmLogin("https://"+nodeConfig.Compute.UcsmCredentials.Host+"/", nodeConfig.Compute.UcsmCredentials.User, nodeConfig.Compute.UcsmCredentials.Password)
 	if err != nil {
 		err = fmt.Errorf("UpdateServerPreflight: AaaLogin() failure: %s", err)
 		return
 	}
-	defer client.AaaLogout()
+	defer UcsmLogout(client, &err)
 	var computeBlades *[]mo.ComputeBlade
 	if computeBlades, err = util.ComputeBladeGetAvailable(client, &nodeConfig.Compute.BladeSpec); err != nil {
 		err = fmt.Errorf("UpdateServerPreflight: ComputeBladeGetAvailable() failure: %s", err)
@@ -408,7 +563,7 @@ func UpdateServer(nodeConfig *config.NodeConfig) (err error) {
 		err = fmt.Errorf("UpdateServer: AaaLogin() failure: %s", err)
 		return
 	}
-	defer client.AaaLogout()
+	defer UcsmLogout(client, &err)
 	var lsServers []*mo.LsServer
 	nodeConfig.Compute.SpDn = nodeConfig.Compute.SpOrg + "/ls-" + nodeConfig.Compute.HostName
 	if lsServers, err = util.ServerGet(client, nodeConfig.Compute.SpDn, "instance"); err != nil {
@@ -440,7 +595,7 @@ func UpdateServerAttributes(nodeConfig *config.NodeConfig) (err error) {
 		err = fmt.Errorf("UpdateServerAttributes: AaaLogin() failure: %s", err)
 		return
 	}
-	defer client.AaaLogout()
+	defer UcsmLogout(client, &err)
 	var lsServers []*mo.LsServer
 	nodeConfig.Compute.SpDn = nodeConfig.Compute.SpOrg + "/ls-" + nodeConfig.Compute.HostName
 	if lsServers, err = util.ServerGet(client, nodeConfig.Compute.SpDn, "instance"); err != nil {
@@ -452,6 +607,10 @@ func UpdateServerAttributes(nodeConfig *config.NodeConfig) (err error) {
 		return
 	}
 	if lsServers[0].Descr != nodeConfig.Compute.Description || lsServers[0].UsrLbl != nodeConfig.Compute.Label {
+		if nodeConfig.Plan != nil {
+			planRecord(nodeConfig, "SpSetAttributes", "set service profile %s description \"%s\", label \"%s\"", nodeConfig.Compute.SpDn, nodeConfig.Compute.Description, nodeConfig.Compute.Label)
+			return
+		}
 		if _, err = util.SpSetAttributes(client, nodeConfig.Compute.SpDn, nodeConfig.Compute.Description, nodeConfig.Compute.Label); err != nil {
 			err = fmt.Errorf("UpdateServerAttributes: SpSetAttributes() failure: %s", err)
 		}
@@ -468,7 +627,7 @@ func DeleteServer(nodeConfig *config.NodeConfig) (err error) {
 		err = fmt.Errorf("DeleteServer: AaaLogin() failure: %s", err)
 		return
 	}
-	defer client.AaaLogout()
+	defer UcsmLogout(client, &err)
 	var lsServers []*mo.LsServer
 	if lsServers, err = util.ServerGet(client, spDn, "instance"); err != nil {
 		err = fmt.Errorf("DeleteServer: ServerGet() failure: %s", err)
@@ -480,8 +639,18 @@ func DeleteServer(nodeConfig *config.NodeConfig) (err error) {
 			return
 		}
 		if powerState == "down" {
+			if nodeConfig.Plan != nil {
+				planDeleteServer(nodeConfig, spDn)
+				return
+			}
 			if err = util.SpDelete(client, spDn); err != nil {
 				err = fmt.Errorf("DeleteServer: SpDelete() failure: %s", err)
+				return
+			}
+			if nodeConfig.Network.IscsiChap.User != "" {
+				if err = deleteIscsiAuthProfiles(client, nodeConfig); err != nil {
+					err = fmt.Errorf("DeleteServer: %s", err)
+				}
 			}
 		} else {
 			if powerState == "" {
@@ -499,15 +668,22 @@ func StartServer(nodeConfig *config.NodeConfig) (err error) {
 	var client *api.Client
 	var lsPower *mo.LsPower
 	spDn := nodeConfig.Compute.SpOrg + "/ls-" + nodeConfig.Compute.HostName
+	if nodeConfig.Plan != nil {
+		planPowerState(nodeConfig, spDn, "up")
+		return
+	}
 	if client, err = UcsmLogin("https://"+nodeConfig.Compute.UcsmCredentials.Host+"/", nodeConfig.Compute.UcsmCredentials.User, nodeConfig.Compute.UcsmCredentials.Password); err != nil {
 		err = fmt.Errorf("StartServer: AaaLogin() failure: %s", err)
 		return
 	}
-	defer client.AaaLogout()
+	defer UcsmLogout(client, &err)
 	if lsPower, err = util.SpSetPowerState(client, spDn, "up"); err != nil {
-		err = fmt.Errorf("StartServer: SpSetPowerState() failure: %s", err)
+		err = spDiagnosticsError(client, spDn, "", fmt.Errorf("StartServer: SpSetPowerState() failure: %s", err))
 	} else {
 		nodeConfig.Compute.Powerstate = lsPower.State
+		if diagnostics, diagErr := GetSpDiagnostics(client, spDn, ""); diagErr == nil && (len(diagnostics.Faults) > 0 || diagnostics.FsmError != "") {
+			log.Warnf("StartServer: %s", diagnostics)
+		}
 	}
 	return
 }
@@ -517,11 +693,15 @@ func StopServer(nodeConfig *config.NodeConfig) (err error) {
 	var client *api.Client
 	var lsPower *mo.LsPower
 	spDn := nodeConfig.Compute.SpOrg + "/ls-" + nodeConfig.Compute.HostName
+	if nodeConfig.Plan != nil {
+		planPowerState(nodeConfig, spDn, "down")
+		return
+	}
 	if client, err = UcsmLogin("https://"+nodeConfig.Compute.UcsmCredentials.Host+"/", nodeConfig.Compute.UcsmCredentials.User, nodeConfig.Compute.UcsmCredentials.Password); err != nil {
 		err = fmt.Errorf("StopServer: AaaLogin() failure: %s", err)
 		return
 	}
-	defer client.AaaLogout()
+	defer UcsmLogout(client, &err)
 	if lsPower, err = util.SpSetPowerState(client, spDn, "down"); err != nil {
 		err = fmt.Errorf("StopServer: SpSetPowerState() failure: %s", err)
 	} else {
@@ -538,7 +718,7 @@ func GetServerPowerState(nodeConfig *config.NodeConfig) (powerState string, err
 		err = fmt.Errorf("GetServerPowerState: AaaLogin() failure: %s", err)
 		return
 	}
-	defer client.AaaLogout()
+	defer UcsmLogout(client, &err)
 	powerState, err = util.SpGetPowerState(client, spDn)
 	return
 }
@@ -551,7 +731,7 @@ func GetServerOperationalState(nodeConfig *config.NodeConfig) (operationalState
 		err = fmt.Errorf("GetServerPowerState: AaaLogin() failure: %s", err)
 		return
 	}
-	defer client.AaaLogout()
+	defer UcsmLogout(client, &err)
 	spDn := nodeConfig.Compute.SpOrg + "/ls-" + nodeConfig.Compute.HostName
 	if lsServers, err = util.ServerGet(client, spDn, "instance"); err != nil {
 		err = fmt.Errorf("GetServerOperationalState(): ServerGet() failure: %s", err)
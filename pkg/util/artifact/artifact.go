@@ -0,0 +1,57 @@
+package artifact
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+const (
+	s3Prefix  = "s3://"
+	ociPrefix = "oci://"
+)
+
+// IsStoreLocation checks if location is in S3-compatible object storage or OCI registry
+func IsStoreLocation(location string) bool {
+	return strings.HasPrefix(location, s3Prefix) || strings.HasPrefix(location, ociPrefix)
+}
+
+// Open opens artifact in S3-compatible object storage (s3://bucket/key) or OCI registry (oci://registry/repository[:tag|@digest][#file]),
+// size is zero if unknown
+func Open(location string, sources config.ArtifactSources) (reader io.ReadCloser, size int64, err error) {
+	switch {
+	case strings.HasPrefix(location, s3Prefix):
+		reader, size, err = openS3(location, sources.S3)
+	case strings.HasPrefix(location, ociPrefix):
+		reader, size, err = openOci(location, sources.Oci)
+	default:
+		err = fmt.Errorf("Open(): unsupported artifact location %s", location)
+	}
+	return
+}
+
+// ReadAll reads artifact content
+func ReadAll(location string, sources config.ArtifactSources) (b []byte, err error) {
+	var reader io.ReadCloser
+	if reader, _, err = Open(location, sources); err != nil {
+		return
+	}
+	defer reader.Close()
+	if b, err = ioutil.ReadAll(reader); err != nil {
+		err = fmt.Errorf("ReadAll(): failure to read %s: %s", location, err)
+	}
+	return
+}
+
+// responseError makes error from unexpected HTTP response
+func responseError(httpResponse *http.Response) error {
+	b, _ := ioutil.ReadAll(io.LimitReader(httpResponse.Body, 1024))
+	if len(b) > 0 {
+		return fmt.Errorf("%s: %s", httpResponse.Status, strings.TrimSpace(string(b)))
+	}
+	return fmt.Errorf("%s", httpResponse.Status)
+}
@@ -0,0 +1,261 @@
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+const (
+	ociManifestMediaTypes = "application/vnd.oci.image.manifest.v1+json, application/vnd.docker.distribution.manifest.v2+json"
+	ociTitleAnnotation    = "org.opencontainers.image.title"
+	ociDefaultTag         = "latest"
+)
+
+var ociChallengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// ociDescriptor is OCI content descriptor
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ociManifest is OCI image (artifact) manifest
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+// ociReference is parsed oci://registry/repository[:tag|@digest][#file] location
+type ociReference struct {
+	registry   string
+	repository string
+	reference  string
+	file       string
+}
+
+// ociClient is OCI distribution API client with token authentication
+type ociClient struct {
+	source config.OciSource
+	ref    ociReference
+	scheme string
+	token  string
+	basic  bool
+}
+
+// digestReader verifies SHA-256 digest of the content at EOF
+type digestReader struct {
+	r        io.ReadCloser
+	h        hash.Hash
+	expected string
+}
+
+func (d *digestReader) Read(p []byte) (n int, err error) {
+	n, err = d.r.Read(p)
+	d.h.Write(p[:n])
+	if err == io.EOF {
+		if digest := "sha256:" + hex.EncodeToString(d.h.Sum(nil)); digest != d.expected {
+			err = fmt.Errorf("blob digest mismatch: expected %s, got %s", d.expected, digest)
+		}
+	}
+	return
+}
+
+func (d *digestReader) Close() error {
+	return d.r.Close()
+}
+
+// parseOciReference parses OCI artifact location
+func parseOciReference(location string) (ref ociReference, err error) {
+	path := strings.TrimPrefix(location, ociPrefix)
+	if i := strings.LastIndex(path, "#"); i >= 0 {
+		ref.file = path[i+1:]
+		path = path[:i]
+	}
+	i := strings.Index(path, "/")
+	if i <= 0 || i == len(path)-1 {
+		err = fmt.Errorf("parseOciReference(): invalid location %s, expected oci://registry/repository[:tag|@digest][#file]", location)
+		return
+	}
+	ref.registry = path[:i]
+	path = path[i+1:]
+	if j := strings.LastIndex(path, "@"); j >= 0 {
+		ref.repository, ref.reference = path[:j], path[j+1:]
+	} else if j := strings.LastIndex(path, ":"); j > strings.LastIndex(path, "/") {
+		ref.repository, ref.reference = path[:j], path[j+1:]
+	} else {
+		ref.repository, ref.reference = path, ociDefaultTag
+	}
+	if ref.repository == "" || ref.reference == "" {
+		err = fmt.Errorf("parseOciReference(): invalid location %s, expected oci://registry/repository[:tag|@digest][#file]", location)
+	}
+	return
+}
+
+// credentials gets registry credentials from configuration with fallback to environment variables
+func (c *ociClient) credentials() (user string, password string) {
+	if user, password = c.source.User, c.source.Password; user == "" && password == "" {
+		user, password = os.Getenv("OCI_USERNAME"), os.Getenv("OCI_PASSWORD")
+	}
+	return
+}
+
+// authenticate gets bearer token per registry authentication challenge
+func (c *ociClient) authenticate(challenge string) (err error) {
+	if strings.HasPrefix(strings.ToLower(challenge), "basic") {
+		c.basic = true
+		return
+	}
+	params := make(map[string]string)
+	for _, match := range ociChallengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(match[1])] = match[2]
+	}
+	if params["realm"] == "" {
+		err = fmt.Errorf("unsupported authentication challenge: %s", challenge)
+		return
+	}
+	if params["scope"] == "" {
+		params["scope"] = "repository:" + c.ref.repository + ":pull"
+	}
+	query := url.Values{}
+	query.Set("scope", params["scope"])
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	var req *http.Request
+	if req, err = http.NewRequest("GET", params["realm"]+"?"+query.Encode(), nil); err != nil {
+		return
+	}
+	if user, password := c.credentials(); user != "" {
+		req.SetBasicAuth(user, password)
+	}
+	var httpResponse *http.Response
+	if httpResponse, err = http.DefaultClient.Do(req); err != nil {
+		err = fmt.Errorf("failure to get token: %s", err)
+		return
+	}
+	defer httpResponse.Body.Close()
+	if httpResponse.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failure to get token: %s", responseError(httpResponse))
+		return
+	}
+	var tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(httpResponse.Body).Decode(&tokenResponse); err != nil {
+		err = fmt.Errorf("failure to decode token: %s", err)
+		return
+	}
+	if c.token = tokenResponse.Token; c.token == "" {
+		c.token = tokenResponse.AccessToken
+	}
+	if c.token == "" {
+		err = fmt.Errorf("empty token from %s", params["realm"])
+	}
+	return
+}
+
+// get sends distribution API request, authenticates on the first unauthorized response
+func (c *ociClient) get(path string, accept string) (httpResponse *http.Response, err error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		var req *http.Request
+		if req, err = http.NewRequest("GET", c.scheme+"://"+c.ref.registry+"/v2/"+c.ref.repository+path, nil); err != nil {
+			return
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		} else if user, password := c.credentials(); c.basic && user != "" {
+			req.SetBasicAuth(user, password)
+		}
+		if httpResponse, err = http.DefaultClient.Do(req); err != nil {
+			return
+		}
+		if httpResponse.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			break
+		}
+		httpResponse.Body.Close()
+		if err = c.authenticate(httpResponse.Header.Get("WWW-Authenticate")); err != nil {
+			return
+		}
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		err = responseError(httpResponse)
+		httpResponse.Body.Close()
+		httpResponse = nil
+	}
+	return
+}
+
+// openOci opens artifact layer in OCI registry, layer is selected by file name (title annotation) or the only layer is used
+func openOci(location string, source config.OciSource) (reader io.ReadCloser, size int64, err error) {
+	c := &ociClient{
+		source: source,
+		scheme: "https",
+	}
+	if source.PlainHttp {
+		c.scheme = "http"
+	}
+	if c.ref, err = parseOciReference(location); err != nil {
+		err = fmt.Errorf("openOci(): %s", err)
+		return
+	}
+	var httpResponse *http.Response
+	if httpResponse, err = c.get("/manifests/"+c.ref.reference, ociManifestMediaTypes); err != nil {
+		err = fmt.Errorf("openOci(): failure to get manifest %s: %s", location, err)
+		return
+	}
+	var manifest ociManifest
+	err = json.NewDecoder(httpResponse.Body).Decode(&manifest)
+	httpResponse.Body.Close()
+	if err != nil {
+		err = fmt.Errorf("openOci(): failure to decode manifest %s: %s", location, err)
+		return
+	}
+	var layer *ociDescriptor
+	var files []string
+	for i := range manifest.Layers {
+		title := manifest.Layers[i].Annotations[ociTitleAnnotation]
+		files = append(files, title)
+		if c.ref.file != "" && title == c.ref.file {
+			layer = &manifest.Layers[i]
+			break
+		}
+	}
+	if c.ref.file == "" && len(manifest.Layers) == 1 {
+		layer = &manifest.Layers[0]
+	}
+	if layer == nil {
+		if c.ref.file == "" {
+			err = fmt.Errorf("openOci(): artifact %s has %d layers, select file with #<file> suffix: %s", location, len(manifest.Layers), strings.Join(files, ","))
+		} else {
+			err = fmt.Errorf("openOci(): file %s not found in artifact %s", c.ref.file, location)
+		}
+		return
+	}
+	if httpResponse, err = c.get("/blobs/"+layer.Digest, ""); err != nil {
+		err = fmt.Errorf("openOci(): failure to get blob %s of %s: %s", layer.Digest, location, err)
+		return
+	}
+	reader = httpResponse.Body
+	if strings.HasPrefix(layer.Digest, "sha256:") {
+		reader = &digestReader{r: httpResponse.Body, h: sha256.New(), expected: layer.Digest}
+	}
+	size = layer.Size
+	return
+}
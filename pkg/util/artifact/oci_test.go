@@ -0,0 +1,297 @@
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+const (
+	testOciUser     = "flexbot"
+	testOciPassword = "s3cr3t"
+	testOciToken    = "registry-token"
+	testOciService  = "registry.test"
+)
+
+// testOciRegistry is OCI registry with token authentication serving one repository
+type testOciRegistry struct {
+	*httptest.Server
+	t          *testing.T
+	repository string
+	auth       string
+	manifests  map[string]ociManifest
+	blobs      map[string][]byte
+	mu         sync.Mutex
+	tokens     int
+	requests   []string
+}
+
+func newTestOciRegistry(t *testing.T, repository string, auth string) *testOciRegistry {
+	r := &testOciRegistry{
+		t:          t,
+		repository: repository,
+		auth:       auth,
+		manifests:  make(map[string]ociManifest),
+		blobs:      make(map[string][]byte),
+	}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.handle))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func testDigest(b []byte) string {
+	h := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(h[:])
+}
+
+// push adds artifact with layers keyed by title, manifest is tagged and addressable by digest
+func (r *testOciRegistry) push(tag string, files map[string][]byte, order ...string) (manifestDigest string) {
+	manifest := ociManifest{MediaType: "application/vnd.oci.image.manifest.v1+json"}
+	for _, title := range order {
+		digest := testDigest(files[title])
+		r.blobs[digest] = files[title]
+		layer := ociDescriptor{MediaType: "application/octet-stream", Digest: digest, Size: int64(len(files[title]))}
+		if title != "" {
+			layer.Annotations = map[string]string{ociTitleAnnotation: title}
+		}
+		manifest.Layers = append(manifest.Layers, layer)
+	}
+	b, _ := json.Marshal(manifest)
+	manifestDigest = testDigest(b)
+	r.manifests[tag] = manifest
+	r.manifests[manifestDigest] = manifest
+	return
+}
+
+func (r *testOciRegistry) handle(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	r.requests = append(r.requests, req.URL.Path)
+	r.mu.Unlock()
+	if req.URL.Path == "/token" {
+		if user, password, ok := req.BasicAuth(); !ok || user != testOciUser || password != testOciPassword {
+			http.Error(w, `{"errors":[{"code":"UNAUTHORIZED"}]}`, http.StatusUnauthorized)
+			return
+		}
+		if scope := req.URL.Query().Get("scope"); scope != "repository:"+r.repository+":pull" {
+			r.t.Errorf("unexpected token scope %q", scope)
+		}
+		if service := req.URL.Query().Get("service"); service != testOciService {
+			r.t.Errorf("unexpected token service %q", service)
+		}
+		r.mu.Lock()
+		r.tokens++
+		r.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]string{"access_token": testOciToken})
+		return
+	}
+	switch r.auth {
+	case "bearer":
+		if req.Header.Get("Authorization") != "Bearer "+testOciToken {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+r.URL+`/token",service="`+testOciService+`"`)
+			http.Error(w, `{"errors":[{"code":"UNAUTHORIZED"}]}`, http.StatusUnauthorized)
+			return
+		}
+	case "basic":
+		if user, password, ok := req.BasicAuth(); !ok || user != testOciUser || password != testOciPassword {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			http.Error(w, `{"errors":[{"code":"UNAUTHORIZED"}]}`, http.StatusUnauthorized)
+			return
+		}
+	}
+	prefix := "/v2/" + r.repository + "/"
+	if !strings.HasPrefix(req.URL.Path, prefix) {
+		http.Error(w, `{"errors":[{"code":"NAME_UNKNOWN"}]}`, http.StatusNotFound)
+		return
+	}
+	path := strings.TrimPrefix(req.URL.Path, prefix)
+	switch {
+	case strings.HasPrefix(path, "manifests/"):
+		if !strings.Contains(req.Header.Get("Accept"), "application/vnd.oci.image.manifest.v1+json") {
+			r.t.Errorf("unexpected manifest Accept header %q", req.Header.Get("Accept"))
+		}
+		manifest, ok := r.manifests[strings.TrimPrefix(path, "manifests/")]
+		if !ok {
+			http.Error(w, `{"errors":[{"code":"MANIFEST_UNKNOWN"}]}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", manifest.MediaType)
+		json.NewEncoder(w).Encode(manifest)
+	case strings.HasPrefix(path, "blobs/"):
+		blob, ok := r.blobs[strings.TrimPrefix(path, "blobs/")]
+		if !ok {
+			http.Error(w, `{"errors":[{"code":"BLOB_UNKNOWN"}]}`, http.StatusNotFound)
+			return
+		}
+		w.Write(blob)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func testOciEnvironment(t *testing.T) {
+	t.Setenv("OCI_USERNAME", "")
+	t.Setenv("OCI_PASSWORD", "")
+}
+
+func testOciSource() config.ArtifactSources {
+	return config.ArtifactSources{Oci: config.OciSource{User: testOciUser, Password: testOciPassword, PlainHttp: true}}
+}
+
+func TestOpenOci(t *testing.T) {
+	testOciEnvironment(t)
+	image := testContent(100 * 1024)
+	checksums := []byte(testDigest(image)[7:] + "  image.qcow2\n")
+	registry := newTestOciRegistry(t, "flexbot/images", "bearer")
+	manifestDigest := registry.push("v1", map[string][]byte{"image.qcow2": image, "SHA256SUMS": checksums}, "SHA256SUMS", "image.qcow2")
+	host := strings.TrimPrefix(registry.URL, "http://")
+	reader, size, err := Open("oci://"+host+"/flexbot/images:v1#image.qcow2", testOciSource())
+	if err != nil {
+		t.Fatalf("Open() failure: %s", err)
+	}
+	if size != int64(len(image)) {
+		t.Errorf("expected size %d, got %d", len(image), size)
+	}
+	b, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil || string(b) != string(image) {
+		t.Fatalf("read failure: %v", err)
+	}
+	// token is requested once and reused for blob
+	expected := []string{"/v2/flexbot/images/manifests/v1", "/token", "/v2/flexbot/images/manifests/v1", "/v2/flexbot/images/blobs/" + testDigest(image)}
+	if strings.Join(registry.requests, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected requests %q, got %q", expected, registry.requests)
+	}
+	// manifest by digest, credentials from environment
+	t.Setenv("OCI_USERNAME", testOciUser)
+	t.Setenv("OCI_PASSWORD", testOciPassword)
+	if b, err = ReadAll("oci://"+host+"/flexbot/images@"+manifestDigest+"#SHA256SUMS", config.ArtifactSources{Oci: config.OciSource{PlainHttp: true}}); err != nil || string(b) != string(checksums) {
+		t.Fatalf("ReadAll() failure: %v, content %q", err, b)
+	}
+}
+
+func TestOpenOciLayerSelection(t *testing.T) {
+	testOciEnvironment(t)
+	registry := newTestOciRegistry(t, "flexbot/templates", "")
+	registry.push("single", map[string][]byte{"": []byte("untitled")}, "")
+	registry.push("multi", map[string][]byte{"a.yaml": []byte("a"), "b.yaml": []byte("b")}, "a.yaml", "b.yaml")
+	registry.push("empty", nil)
+	host := strings.TrimPrefix(registry.URL, "http://")
+	tests := []struct {
+		location string
+		content  string
+		err      string
+	}{
+		{location: "/flexbot/templates:single", content: "untitled"},
+		{location: "/flexbot/templates:multi#b.yaml", content: "b"},
+		{location: "/flexbot/templates:multi", err: "has 2 layers, select file with #<file> suffix: a.yaml,b.yaml"},
+		{location: "/flexbot/templates:multi#c.yaml", err: "file c.yaml not found in artifact"},
+		{location: "/flexbot/templates:single#untitled", err: "file untitled not found in artifact"},
+		{location: "/flexbot/templates:empty", err: "has 0 layers"},
+		{location: "/flexbot/templates:missing", err: "404 Not Found: {\"errors\":[{\"code\":\"MANIFEST_UNKNOWN\"}]}"},
+		{location: "/flexbot/other", err: "NAME_UNKNOWN"},
+	}
+	for _, test := range tests {
+		t.Run(test.location, func(t *testing.T) {
+			b, err := ReadAll("oci://"+host+test.location, testOciSource())
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil || string(b) != test.content {
+				t.Fatalf("ReadAll() failure: %v, content %q", err, b)
+			}
+		})
+	}
+}
+
+func TestOpenOciAuthentication(t *testing.T) {
+	testOciEnvironment(t)
+	t.Run("basic", func(t *testing.T) {
+		registry := newTestOciRegistry(t, "flexbot/images", "basic")
+		registry.push("latest", map[string][]byte{"image.raw": []byte("raw")}, "image.raw")
+		b, err := ReadAll("oci://"+strings.TrimPrefix(registry.URL, "http://")+"/flexbot/images", testOciSource())
+		if err != nil || string(b) != "raw" {
+			t.Fatalf("ReadAll() failure: %v, content %q", err, b)
+		}
+		if registry.tokens != 0 {
+			t.Errorf("unexpected token requests")
+		}
+	})
+	t.Run("invalid credentials", func(t *testing.T) {
+		registry := newTestOciRegistry(t, "flexbot/images", "bearer")
+		registry.push("latest", map[string][]byte{"image.raw": []byte("raw")}, "image.raw")
+		sources := testOciSource()
+		sources.Oci.Password = "invalid"
+		if _, err := ReadAll("oci://"+strings.TrimPrefix(registry.URL, "http://")+"/flexbot/images", sources); err == nil || !strings.Contains(err.Error(), "failure to get token: 401 Unauthorized") {
+			t.Fatalf("expected token failure, got %v", err)
+		}
+	})
+	t.Run("unsupported challenge", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("WWW-Authenticate", `Bearer service="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+		if _, err := ReadAll("oci://"+strings.TrimPrefix(server.URL, "http://")+"/flexbot/images", testOciSource()); err == nil || !strings.Contains(err.Error(), "unsupported authentication challenge") {
+			t.Fatalf("expected challenge failure, got %v", err)
+		}
+	})
+}
+
+func TestOpenOciDigestMismatch(t *testing.T) {
+	testOciEnvironment(t)
+	registry := newTestOciRegistry(t, "flexbot/images", "bearer")
+	registry.push("v1", map[string][]byte{"image.raw": testContent(70 * 1024)}, "image.raw")
+	// blob is corrupted in the registry
+	for digest, blob := range registry.blobs {
+		tampered := append([]byte(nil), blob...)
+		tampered[len(tampered)/2] ^= 0xff
+		registry.blobs[digest] = tampered
+	}
+	reader, _, err := Open("oci://"+strings.TrimPrefix(registry.URL, "http://")+"/flexbot/images:v1", testOciSource())
+	if err != nil {
+		t.Fatalf("Open() failure: %s", err)
+	}
+	defer reader.Close()
+	if _, err = ioutil.ReadAll(reader); err == nil || !strings.Contains(err.Error(), "blob digest mismatch") {
+		t.Fatalf("expected digest mismatch, got %v", err)
+	}
+}
+
+func TestParseOciReference(t *testing.T) {
+	tests := []struct {
+		location string
+		ref      ociReference
+		err      bool
+	}{
+		{location: "oci://ghcr.io/org/images", ref: ociReference{registry: "ghcr.io", repository: "org/images", reference: "latest"}},
+		{location: "oci://registry:5000/images:v1.2#image.qcow2", ref: ociReference{registry: "registry:5000", repository: "images", reference: "v1.2", file: "image.qcow2"}},
+		{location: "oci://registry/org/images@sha256:abcd", ref: ociReference{registry: "registry", repository: "org/images", reference: "sha256:abcd"}},
+		{location: "oci://registry", err: true},
+		{location: "oci://registry/", err: true},
+		{location: "oci:///images", err: true},
+		{location: "oci://registry/images:", err: true},
+	}
+	for _, test := range tests {
+		ref, err := parseOciReference(test.location)
+		if test.err {
+			if err == nil {
+				t.Errorf("expected error for %s", test.location)
+			}
+			continue
+		}
+		if err != nil || ref != test.ref {
+			t.Errorf("unexpected reference %+v, error %v for %s", ref, err, test.location)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package artifact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+const (
+	s3DefaultRegion    = "us-east-1"
+	s3EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	s3TimeFormat       = "20060102T150405Z"
+	// Max number of ranged GET requests to resume interrupted download
+	s3MaxResume = 5
+)
+
+// s3Reader reads object, interrupted download is resumed with ranged GET of the same object version (ETag)
+type s3Reader struct {
+	source   config.S3Source
+	url      string
+	location string
+	body     io.ReadCloser
+	offset   int64
+	etag     string
+	resumed  int
+}
+
+// s3Credentials makes S3 endpoint and credentials from configuration with fallback to environment variables
+func s3Credentials(source config.S3Source) (s config.S3Source) {
+	s = source
+	if s.Region == "" {
+		if s.Region = os.Getenv("AWS_REGION"); s.Region == "" {
+			if s.Region = os.Getenv("AWS_DEFAULT_REGION"); s.Region == "" {
+				s.Region = s3DefaultRegion
+			}
+		}
+	}
+	if s.Endpoint == "" {
+		if s.Endpoint = os.Getenv("AWS_ENDPOINT_URL_S3"); s.Endpoint == "" {
+			s.Endpoint = os.Getenv("AWS_ENDPOINT_URL")
+		}
+	}
+	if s.AccessKey == "" && s.SecretKey == "" {
+		s.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		s.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if s.SessionToken == "" {
+			s.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		}
+	}
+	return
+}
+
+// s3EncodePath URI-encodes object key as required by AWS signature V4
+func s3EncodePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		ch := path[i]
+		if (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9') || ch == '-' || ch == '_' || ch == '.' || ch == '~' || ch == '/' {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+// s3ObjectURL makes object URL, path-style addressing is used for custom endpoints and virtual-hosted style for AWS
+func s3ObjectURL(s config.S3Source, bucket string, key string) (objectURL *url.URL, err error) {
+	var rawURL string
+	if s.Endpoint == "" {
+		rawURL = "https://" + bucket + ".s3." + s.Region + ".amazonaws.com/" + s3EncodePath(key)
+	} else {
+		endpoint := strings.TrimSuffix(s.Endpoint, "/")
+		if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+			endpoint = "https://" + endpoint
+		}
+		rawURL = endpoint + "/" + s3EncodePath(bucket) + "/" + s3EncodePath(key)
+	}
+	objectURL, err = url.Parse(rawURL)
+	return
+}
+
+// s3SignRequest signs request with AWS signature V4
+func s3SignRequest(req *http.Request, s config.S3Source, now time.Time) {
+	amzDate := now.UTC().Format(s3TimeFormat)
+	date := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", s3EmptyPayloadHash)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" + "x-amz-content-sha256:" + s3EmptyPayloadHash + "\n" + "x-amz-date:" + amzDate + "\n"
+	if s.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", s.SessionToken)
+		signedHeaders += ";x-amz-security-token"
+		canonicalHeaders += "x-amz-security-token:" + s.SessionToken + "\n"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		s3EmptyPayloadHash,
+	}, "\n")
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+	signingKey := hmacSha256([]byte("AWS4"+s.SecretKey), date)
+	signingKey = hmacSha256(signingKey, s.Region)
+	signingKey = hmacSha256(signingKey, "s3")
+	signingKey = hmacSha256(signingKey, "aws4_request")
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.AccessKey, scope, signedHeaders, hex.EncodeToString(hmacSha256(signingKey, stringToSign))))
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Get sends object GET request, ranged request starts at offset of the object version with ETag
+func s3Get(s config.S3Source, objectURL string, offset int64, etag string) (httpResponse *http.Response, err error) {
+	var req *http.Request
+	if req, err = http.NewRequest("GET", objectURL, nil); err != nil {
+		return
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if etag != "" {
+			req.Header.Set("If-Match", etag)
+		}
+	}
+	if s.AccessKey != "" {
+		s3SignRequest(req, s, time.Now())
+	}
+	if httpResponse, err = http.DefaultClient.Do(req); err != nil {
+		return
+	}
+	expectedStatus := http.StatusOK
+	if offset > 0 {
+		expectedStatus = http.StatusPartialContent
+	}
+	if httpResponse.StatusCode != expectedStatus {
+		err = responseError(httpResponse)
+		httpResponse.Body.Close()
+		httpResponse = nil
+	}
+	return
+}
+
+func (r *s3Reader) Read(p []byte) (n int, err error) {
+	n, err = r.body.Read(p)
+	r.offset += int64(n)
+	if err == nil || err == io.EOF || r.resumed >= s3MaxResume {
+		return
+	}
+	readErr := err
+	r.body.Close()
+	r.resumed++
+	var httpResponse *http.Response
+	if httpResponse, err = s3Get(r.source, r.url, r.offset, r.etag); err != nil {
+		r.body = ioutil.NopCloser(strings.NewReader(""))
+		err = fmt.Errorf("failure to resume download of %s at offset %d after read error \"%s\": %s", r.location, r.offset, readErr, err)
+		return
+	}
+	r.body = httpResponse.Body
+	return
+}
+
+func (r *s3Reader) Close() error {
+	return r.body.Close()
+}
+
+// openS3 opens object in S3-compatible object storage, request is anonymous if no credentials configured
+func openS3(location string, source config.S3Source) (reader io.ReadCloser, size int64, err error) {
+	path := strings.TrimPrefix(location, s3Prefix)
+	i := strings.Index(path, "/")
+	if i <= 0 || i == len(path)-1 {
+		err = fmt.Errorf("openS3(): invalid location %s, expected s3://bucket/key", location)
+		return
+	}
+	s := s3Credentials(source)
+	var objectURL *url.URL
+	if objectURL, err = s3ObjectURL(s, path[:i], path[i+1:]); err != nil {
+		err = fmt.Errorf("openS3(): invalid endpoint: %s", err)
+		return
+	}
+	var httpResponse *http.Response
+	if httpResponse, err = s3Get(s, objectURL.String(), 0, ""); err != nil {
+		err = fmt.Errorf("openS3(): failure to get %s: %s", location, err)
+		return
+	}
+	reader = &s3Reader{
+		source:   s,
+		url:      objectURL.String(),
+		location: location,
+		body:     httpResponse.Body,
+		etag:     httpResponse.Header.Get("ETag"),
+	}
+	if httpResponse.ContentLength > 0 {
+		size = httpResponse.ContentLength
+	}
+	return
+}
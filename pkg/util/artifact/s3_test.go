@@ -0,0 +1,300 @@
+package artifact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+)
+
+var testAuthorizationRegexp = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=([^/]+)/(\d{8})/([^/]+)/s3/aws4_request, SignedHeaders=([^,]+), Signature=([0-9a-f]{64})$`)
+
+// testS3Server serves one object, response is aborted once it reaches any of cut offsets
+type testS3Server struct {
+	*httptest.Server
+	t           *testing.T
+	source      config.S3Source
+	path        string
+	content     []byte
+	etag        string
+	cuts        []int
+	ignoreRange bool
+	mu          sync.Mutex
+	ranges      []string
+}
+
+func newTestS3Server(t *testing.T, source config.S3Source, path string, content []byte) *testS3Server {
+	s := &testS3Server{t: t, source: source, path: path, content: content, etag: `"0123456789abcdef"`}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// verifySignature verifies AWS signature V4 of request signed with empty payload
+func (s *testS3Server) verifySignature(r *http.Request) error {
+	m := testAuthorizationRegexp.FindStringSubmatch(r.Header.Get("Authorization"))
+	if m == nil {
+		return fmt.Errorf("malformed Authorization header %q", r.Header.Get("Authorization"))
+	}
+	accessKey, date, region, signedHeaders, signature := m[1], m[2], m[3], m[4], m[5]
+	if accessKey != s.source.AccessKey || region != s.source.Region {
+		return fmt.Errorf("unexpected credential %s/%s", accessKey, region)
+	}
+	amzDate := r.Header.Get("x-amz-date")
+	requestTime, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil || !strings.HasPrefix(amzDate, date) || time.Since(requestTime) > time.Minute {
+		return fmt.Errorf("invalid x-amz-date %q", amzDate)
+	}
+	payloadHash := sha256.Sum256(nil)
+	if r.Header.Get("x-amz-content-sha256") != hex.EncodeToString(payloadHash[:]) {
+		return fmt.Errorf("unexpected x-amz-content-sha256 %q", r.Header.Get("x-amz-content-sha256"))
+	}
+	var canonicalHeaders string
+	for _, header := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(header)
+		if header == "host" {
+			value = r.Host
+		}
+		canonicalHeaders += header + ":" + value + "\n"
+	}
+	if !strings.Contains(signedHeaders, "x-amz-date") || (r.Header.Get("x-amz-security-token") != "" && !strings.Contains(signedHeaders, "x-amz-security-token")) {
+		return fmt.Errorf("required headers are not signed: %s", signedHeaders)
+	}
+	canonicalRequest := r.Method + "\n" + r.URL.EscapedPath() + "\n" + r.URL.RawQuery + "\n" + canonicalHeaders + "\n" + signedHeaders + "\n" + hex.EncodeToString(payloadHash[:])
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	key := []byte("AWS4" + s.source.SecretKey)
+	for _, data := range []string{date, region, "s3", "aws4_request"} {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(data))
+		key = h.Sum(nil)
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte("AWS4-HMAC-SHA256\n" + amzDate + "\n" + date + "/" + region + "/s3/aws4_request\n" + hex.EncodeToString(requestHash[:])))
+	if expected := hex.EncodeToString(h.Sum(nil)); signature != expected {
+		return fmt.Errorf("signature mismatch: expected %s, got %s", expected, signature)
+	}
+	return nil
+}
+
+func (s *testS3Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.ranges = append(s.ranges, r.Header.Get("Range"))
+	etag := s.etag
+	s.mu.Unlock()
+	if s.source.AccessKey == "" {
+		if r.Header.Get("Authorization") != "" {
+			http.Error(w, "unexpected Authorization header", http.StatusBadRequest)
+			return
+		}
+	} else if err := s.verifySignature(r); err != nil {
+		s.t.Errorf("%s %s: %s", r.Method, r.URL, err)
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>", http.StatusForbidden)
+		return
+	}
+	if r.Header.Get("x-amz-security-token") != s.source.SessionToken {
+		http.Error(w, "<Error><Code>InvalidToken</Code></Error>", http.StatusBadRequest)
+		return
+	}
+	if r.URL.EscapedPath() != s.path {
+		http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+		return
+	}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != etag {
+		http.Error(w, "<Error><Code>PreconditionFailed</Code></Error>", http.StatusPreconditionFailed)
+		return
+	}
+	start := 0
+	status := http.StatusOK
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && !s.ignoreRange {
+		var err error
+		if start, err = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rangeHeader, "bytes="), "-")); err != nil || start >= len(s.content) {
+			http.Error(w, "<Error><Code>InvalidRange</Code></Error>", http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(s.content)-1, len(s.content)))
+		status = http.StatusPartialContent
+	}
+	end := len(s.content)
+	for _, cut := range s.cuts {
+		if cut > start {
+			end = cut
+			break
+		}
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Length", strconv.Itoa(len(s.content)-start))
+	w.WriteHeader(status)
+	w.Write(s.content[start:end])
+	if end < len(s.content) {
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func testS3Environment(t *testing.T) {
+	for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION", "AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"} {
+		t.Setenv(name, "")
+	}
+}
+
+func testContent(size int) []byte {
+	b := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(b)
+	return b
+}
+
+func TestOpenS3(t *testing.T) {
+	testS3Environment(t)
+	content := testContent(64 * 1024)
+	source := config.S3Source{Region: "eu-west-1", AccessKey: "AKIDEXAMPLE", SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	server := newTestS3Server(t, source, "/images/ubuntu%2022.04/image%2Bboot.qcow2", content)
+	source.Endpoint = server.URL + "/"
+	reader, size, err := Open("s3://images/ubuntu 22.04/image+boot.qcow2", config.ArtifactSources{S3: source})
+	if err != nil {
+		t.Fatalf("Open() failure: %s", err)
+	}
+	defer reader.Close()
+	if size != int64(len(content)) {
+		t.Errorf("expected size %d, got %d", len(content), size)
+	}
+	b, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read failure: %s", err)
+	}
+	if string(b) != string(content) {
+		t.Fatalf("content mismatch")
+	}
+	if len(server.ranges) != 1 || server.ranges[0] != "" {
+		t.Fatalf("expected single GET without range, got %q", server.ranges)
+	}
+}
+
+func TestOpenS3Credentials(t *testing.T) {
+	content := []byte("#cloud-config\n")
+	t.Run("environment with session token", func(t *testing.T) {
+		testS3Environment(t)
+		source := config.S3Source{Region: "us-west-2", AccessKey: "ASIAEXAMPLE", SecretKey: "secret", SessionToken: "session/token=="}
+		server := newTestS3Server(t, source, "/templates/cloud-init.yaml", content)
+		t.Setenv("AWS_ENDPOINT_URL_S3", server.URL)
+		t.Setenv("AWS_DEFAULT_REGION", source.Region)
+		t.Setenv("AWS_ACCESS_KEY_ID", source.AccessKey)
+		t.Setenv("AWS_SECRET_ACCESS_KEY", source.SecretKey)
+		t.Setenv("AWS_SESSION_TOKEN", source.SessionToken)
+		b, err := ReadAll("s3://templates/cloud-init.yaml", config.ArtifactSources{})
+		if err != nil || string(b) != string(content) {
+			t.Fatalf("ReadAll() failure: %v, content %q", err, b)
+		}
+	})
+	t.Run("anonymous", func(t *testing.T) {
+		testS3Environment(t)
+		server := newTestS3Server(t, config.S3Source{}, "/templates/cloud-init.yaml", content)
+		b, err := ReadAll("s3://templates/cloud-init.yaml", config.ArtifactSources{S3: config.S3Source{Endpoint: server.URL}})
+		if err != nil || string(b) != string(content) {
+			t.Fatalf("ReadAll() failure: %v, content %q", err, b)
+		}
+	})
+}
+
+func TestOpenS3Errors(t *testing.T) {
+	testS3Environment(t)
+	source := config.S3Source{Region: "eu-west-1", AccessKey: "AKIDEXAMPLE", SecretKey: "secret"}
+	server := newTestS3Server(t, source, "/images/image.qcow2", []byte("image"))
+	source.Endpoint = server.URL
+	if _, _, err := Open("s3://images/missing.qcow2", config.ArtifactSources{S3: source}); err == nil || !strings.Contains(err.Error(), "404 Not Found: <Error><Code>NoSuchKey</Code></Error>") {
+		t.Errorf("expected NoSuchKey error, got %v", err)
+	}
+	for _, location := range []string{"s3://images", "s3://images/", "s3:///image.qcow2"} {
+		if _, _, err := Open(location, config.ArtifactSources{S3: source}); err == nil || !strings.Contains(err.Error(), "expected s3://bucket/key") {
+			t.Errorf("expected invalid location error for %s, got %v", location, err)
+		}
+	}
+	if _, _, err := Open("ftp://images/image.qcow2", config.ArtifactSources{S3: source}); err == nil || !strings.Contains(err.Error(), "unsupported artifact location") {
+		t.Errorf("expected unsupported location error, got %v", err)
+	}
+}
+
+func TestOpenS3RangedGet(t *testing.T) {
+	testS3Environment(t)
+	content := testContent(256 * 1024)
+	source := config.S3Source{Region: "eu-west-1", AccessKey: "AKIDEXAMPLE", SecretKey: "secret"}
+	tests := []struct {
+		name        string
+		cuts        []int
+		ignoreRange bool
+		changeEtag  bool
+		ranges      []string
+		err         string
+	}{
+		{
+			name:   "resumed twice",
+			cuts:   []int{50000, 150001},
+			ranges: []string{"", "bytes=50000-", "bytes=150001-"},
+		},
+		{
+			name:   "too many interruptions",
+			cuts:   []int{1000, 2000, 3000, 4000, 5000, 6000, 7000},
+			ranges: []string{"", "bytes=1000-", "bytes=2000-", "bytes=3000-", "bytes=4000-", "bytes=5000-"},
+			err:    "unexpected EOF",
+		},
+		{
+			name:        "range is not supported",
+			cuts:        []int{50000},
+			ignoreRange: true,
+			ranges:      []string{"", "bytes=50000-"},
+			err:         "failure to resume download of s3://images/image.raw at offset 50000",
+		},
+		{
+			name:       "object is replaced",
+			cuts:       []int{50000},
+			changeEtag: true,
+			ranges:     []string{"", "bytes=50000-"},
+			err:        "412 Precondition Failed",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newTestS3Server(t, source, "/images/image.raw", content)
+			server.cuts = test.cuts
+			server.ignoreRange = test.ignoreRange
+			s := source
+			s.Endpoint = server.URL
+			reader, _, err := Open("s3://images/image.raw", config.ArtifactSources{S3: s})
+			if err != nil {
+				t.Fatalf("Open() failure: %s", err)
+			}
+			defer reader.Close()
+			if test.changeEtag {
+				server.mu.Lock()
+				server.etag = `"fedcba9876543210"`
+				server.mu.Unlock()
+			}
+			b, err := ioutil.ReadAll(reader)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+			} else if err != nil {
+				t.Fatalf("read failure: %s", err)
+			} else if string(b) != string(content) {
+				t.Fatalf("content mismatch after resume")
+			}
+			server.mu.Lock()
+			defer server.mu.Unlock()
+			if strings.Join(server.ranges, ",") != strings.Join(test.ranges, ",") {
+				t.Errorf("expected ranges %q, got %q", test.ranges, server.ranges)
+			}
+		})
+	}
+}
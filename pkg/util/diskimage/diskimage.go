@@ -0,0 +1,34 @@
+package diskimage
+
+import (
+	"bytes"
+)
+
+// Supported disk image formats
+const (
+	FormatRaw   = "raw"
+	FormatQcow2 = "qcow2"
+	FormatVmdk  = "vmdk"
+)
+
+const (
+	// HeaderSize is number of bytes enough to detect disk image format
+	HeaderSize = 512
+	sectorSize = 512
+)
+
+var (
+	qcow2Magic = []byte{'Q', 'F', 'I', 0xfb}
+	vmdkMagic  = []byte{'K', 'D', 'M', 'V'}
+)
+
+// DetectFormat detects disk image format by magic bytes in image header
+func DetectFormat(header []byte) string {
+	if bytes.HasPrefix(header, qcow2Magic) {
+		return FormatQcow2
+	}
+	if bytes.HasPrefix(header, vmdkMagic) {
+		return FormatVmdk
+	}
+	return FormatRaw
+}
@@ -0,0 +1,24 @@
+package diskimage
+
+import (
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	qcow2, _ := testQcow2Image(t, 3, 512, nil)
+	tests := []struct {
+		header []byte
+		format string
+	}{
+		{qcow2[:HeaderSize], FormatQcow2},
+		{newTestVmdkStream(8, 1).Bytes()[:HeaderSize], FormatVmdk},
+		{make([]byte, HeaderSize), FormatRaw},
+		{[]byte("QFI"), FormatRaw},
+		{nil, FormatRaw},
+	}
+	for _, test := range tests {
+		if format := DetectFormat(test.header); format != test.format {
+			t.Errorf("expected format %s for header of %d bytes, got %s", test.format, len(test.header), format)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package diskimage
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	qcow2HeaderSize          = 112
+	qcow2HeaderV2Size        = 72
+	qcow2L1OffsetMask        = 0x00fffffffffffe00
+	qcow2L2OffsetMask        = 0x00fffffffffffe00
+	qcow2L2Compressed        = uint64(1) << 62
+	qcow2L2ZeroCluster       = uint64(1)
+	qcow2IncompatDirty       = uint64(1) << 0
+	qcow2IncompatCompression = uint64(1) << 3
+	// Max L1 table size to read (32 MB is enough for 4 PB image with 64 KB clusters)
+	qcow2MaxL1Size = 32 * 1024 * 1024
+)
+
+// Qcow2Reader converts qcow2 image into raw image stream,
+// backing files, encryption, external data files, extended L2 entries and zstd compression are not supported
+type Qcow2Reader struct {
+	r           io.ReaderAt
+	version     uint32
+	clusterBits uint32
+	clusterSize int64
+	size        int64
+	l1Table     []uint64
+	l2Table     []uint64
+	l2Index     int64
+	offset      int64
+	cluster     []byte
+	clusterPos  int
+	clusterLen  int
+}
+
+// NewQcow2Reader creates qcow2 to raw image converter
+func NewQcow2Reader(r io.ReaderAt) (q *Qcow2Reader, err error) {
+	header := make([]byte, qcow2HeaderSize)
+	var n int
+	if n, err = r.ReadAt(header, 0); err != nil {
+		if err != io.EOF || n < qcow2HeaderV2Size {
+			err = fmt.Errorf("NewQcow2Reader(): failure to read header: %s", err)
+			return
+		}
+		err = nil
+	}
+	if !bytes.HasPrefix(header, qcow2Magic) {
+		err = fmt.Errorf("NewQcow2Reader(): not a qcow2 image")
+		return
+	}
+	q = &Qcow2Reader{
+		r:           r,
+		version:     binary.BigEndian.Uint32(header[4:8]),
+		clusterBits: binary.BigEndian.Uint32(header[20:24]),
+		size:        int64(binary.BigEndian.Uint64(header[24:32])),
+		l2Index:     -1,
+	}
+	if q.version != 2 && q.version != 3 {
+		err = fmt.Errorf("NewQcow2Reader(): qcow2 version %d is not supported", q.version)
+		return
+	}
+	if binary.BigEndian.Uint64(header[8:16]) != 0 {
+		err = fmt.Errorf("NewQcow2Reader(): qcow2 images with backing file are not supported")
+		return
+	}
+	if binary.BigEndian.Uint32(header[32:36]) != 0 {
+		err = fmt.Errorf("NewQcow2Reader(): encrypted qcow2 images are not supported")
+		return
+	}
+	if q.clusterBits < 9 || q.clusterBits > 21 {
+		err = fmt.Errorf("NewQcow2Reader(): invalid cluster bits %d", q.clusterBits)
+		return
+	}
+	if q.version == 3 {
+		incompatibleFeatures := binary.BigEndian.Uint64(header[72:80])
+		if incompatibleFeatures&qcow2IncompatCompression != 0 && header[104] != 0 {
+			err = fmt.Errorf("NewQcow2Reader(): qcow2 compression type %d is not supported", header[104])
+			return
+		}
+		// dirty bit affects refcounts only
+		if incompatibleFeatures&^(qcow2IncompatDirty|qcow2IncompatCompression) != 0 {
+			err = fmt.Errorf("NewQcow2Reader(): qcow2 incompatible features 0x%x are not supported", incompatibleFeatures)
+			return
+		}
+	}
+	q.clusterSize = int64(1) << q.clusterBits
+	q.cluster = make([]byte, q.clusterSize)
+	l1Size := int64(binary.BigEndian.Uint32(header[36:40]))
+	l1Offset := int64(binary.BigEndian.Uint64(header[40:48]))
+	if l1Size*8 > qcow2MaxL1Size {
+		err = fmt.Errorf("NewQcow2Reader(): L1 table size %d is too large", l1Size)
+		return
+	}
+	if q.l1Table, err = q.readTable(l1Offset, l1Size); err != nil {
+		err = fmt.Errorf("NewQcow2Reader(): failure to read L1 table: %s", err)
+	}
+	return
+}
+
+// VirtualSize is raw image size
+func (q *Qcow2Reader) VirtualSize() int64 {
+	return q.size
+}
+
+// Read reads raw image stream
+func (q *Qcow2Reader) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		if q.clusterPos >= q.clusterLen {
+			if q.offset >= q.size {
+				if n == 0 {
+					err = io.EOF
+				}
+				return
+			}
+			if err = q.readCluster(); err != nil {
+				return
+			}
+		}
+		c := copy(p[n:], q.cluster[q.clusterPos:q.clusterLen])
+		n += c
+		q.clusterPos += c
+	}
+	return
+}
+
+// readTable reads big-endian table of 64-bit entries
+func (q *Qcow2Reader) readTable(offset int64, entries int64) (table []uint64, err error) {
+	b := make([]byte, entries*8)
+	var n int
+	if n, err = q.r.ReadAt(b, offset); err != nil {
+		if err != io.EOF || n < len(b) {
+			return
+		}
+		err = nil
+	}
+	table = make([]uint64, entries)
+	for i := range table {
+		table[i] = binary.BigEndian.Uint64(b[i*8:])
+	}
+	return
+}
+
+// readCluster reads next guest cluster into cluster buffer
+func (q *Qcow2Reader) readCluster() (err error) {
+	q.clusterPos = 0
+	q.clusterLen = int(q.clusterSize)
+	if q.size-q.offset < q.clusterSize {
+		q.clusterLen = int(q.size - q.offset)
+	}
+	buf := q.cluster[:q.clusterLen]
+	clusterIndex := q.offset >> q.clusterBits
+	q.offset += int64(q.clusterLen)
+	l2Entries := q.clusterSize / 8
+	l1Index := clusterIndex / l2Entries
+	if l1Index >= int64(len(q.l1Table)) || q.l1Table[l1Index]&qcow2L1OffsetMask == 0 {
+		zeroFill(buf)
+		return
+	}
+	if q.l2Index != l1Index {
+		if q.l2Table, err = q.readTable(int64(q.l1Table[l1Index]&qcow2L1OffsetMask), l2Entries); err != nil {
+			err = fmt.Errorf("Qcow2Reader: failure to read L2 table: %s", err)
+			return
+		}
+		q.l2Index = l1Index
+	}
+	entry := q.l2Table[clusterIndex%l2Entries]
+	if entry&qcow2L2Compressed != 0 {
+		offsetBits := 62 - (q.clusterBits - 8)
+		hostOffset := int64(entry & (uint64(1)<<offsetBits - 1))
+		sectors := int64((entry >> offsetBits) & (uint64(1)<<(62-offsetBits) - 1))
+		compressed := make([]byte, (sectors+1)*sectorSize-(hostOffset&(sectorSize-1)))
+		var n int
+		if n, err = q.r.ReadAt(compressed, hostOffset); err != nil {
+			if err != io.EOF {
+				err = fmt.Errorf("Qcow2Reader: failure to read compressed cluster: %s", err)
+				return
+			}
+			err = nil
+		}
+		fr := flate.NewReader(bytes.NewReader(compressed[:n]))
+		defer fr.Close()
+		if _, err = io.ReadFull(fr, q.cluster); err != nil {
+			err = fmt.Errorf("Qcow2Reader: failure to decompress cluster: %s", err)
+		}
+		return
+	}
+	hostOffset := int64(entry & qcow2L2OffsetMask)
+	if hostOffset == 0 || (q.version == 3 && entry&qcow2L2ZeroCluster != 0) {
+		zeroFill(buf)
+		return
+	}
+	var n int
+	if n, err = q.r.ReadAt(buf, hostOffset); err != nil {
+		if err != io.EOF {
+			err = fmt.Errorf("Qcow2Reader: failure to read cluster: %s", err)
+			return
+		}
+		// cluster at the end of image file may be truncated
+		zeroFill(buf[n:])
+		err = nil
+	}
+	return
+}
+
+func zeroFill(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,192 @@
+package diskimage
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const testClusterBits = 9
+
+// testQcow2Cluster is guest cluster of test qcow2 image
+type testQcow2Cluster struct {
+	kind string
+	data []byte
+}
+
+// testQcow2Image builds qcow2 image with 512 bytes clusters: header, L1 table, L2 table, then data clusters
+func testQcow2Image(t *testing.T, version uint32, size int64, clusters []testQcow2Cluster) (image []byte, raw []byte) {
+	clusterSize := int64(1) << testClusterBits
+	image = make([]byte, 3*clusterSize)
+	raw = make([]byte, size)
+	copy(image, qcow2Magic)
+	binary.BigEndian.PutUint32(image[4:8], version)
+	binary.BigEndian.PutUint32(image[20:24], testClusterBits)
+	binary.BigEndian.PutUint64(image[24:32], uint64(size))
+	binary.BigEndian.PutUint32(image[36:40], 1)
+	binary.BigEndian.PutUint64(image[40:48], uint64(clusterSize))
+	if version == 3 {
+		binary.BigEndian.PutUint32(image[100:104], qcow2HeaderSize)
+	}
+	binary.BigEndian.PutUint64(image[clusterSize:], uint64(2*clusterSize)|uint64(1)<<63)
+	for i, cluster := range clusters {
+		var entry uint64
+		// host clusters are cluster aligned
+		image = append(image, make([]byte, (clusterSize-int64(len(image))%clusterSize)%clusterSize)...)
+		hostOffset := uint64(len(image))
+		switch cluster.kind {
+		case "allocated":
+			entry = hostOffset | uint64(1)<<63
+			image = append(image, cluster.data...)
+			image = append(image, make([]byte, clusterSize-int64(len(cluster.data)))...)
+			copy(raw[int64(i)*clusterSize:], cluster.data)
+		case "zero":
+			// zero flag set on allocated cluster, host data must be ignored
+			entry = hostOffset | qcow2L2ZeroCluster
+			image = append(image, bytes.Repeat([]byte{0xff}, int(clusterSize))...)
+		case "compressed":
+			var b bytes.Buffer
+			fw, _ := flate.NewWriter(&b, flate.BestCompression)
+			fw.Write(cluster.data)
+			fw.Close()
+			// compressed data does not start on sector boundary
+			hostOffset += 100
+			image = append(image, make([]byte, 100)...)
+			image = append(image, b.Bytes()...)
+			offsetBits := uint(62 - (testClusterBits - 8))
+			sectors := (uint64(100+b.Len()) + sectorSize - 1) / sectorSize
+			entry = qcow2L2Compressed | (sectors-1)<<offsetBits | hostOffset
+			copy(raw[int64(i)*clusterSize:], cluster.data)
+		case "unallocated":
+		default:
+			t.Fatalf("unknown cluster kind %s", cluster.kind)
+		}
+		binary.BigEndian.PutUint64(image[2*clusterSize+int64(i)*8:], entry)
+	}
+	return
+}
+
+func testPattern(seed byte, size int) []byte {
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = seed + byte(i%251)
+	}
+	return b
+}
+
+func TestQcow2Reader(t *testing.T) {
+	clusters := []testQcow2Cluster{
+		{kind: "allocated", data: testPattern(1, 512)},
+		{kind: "unallocated"},
+		{kind: "zero"},
+		{kind: "compressed", data: bytes.Repeat([]byte("flexbot"), 74)[:512]},
+		{kind: "compressed", data: testPattern(7, 512)},
+		{kind: "allocated", data: testPattern(3, 300)},
+	}
+	image, raw := testQcow2Image(t, 3, 5*512+300, clusters)
+	q, err := NewQcow2Reader(bytes.NewReader(image))
+	if err != nil {
+		t.Fatalf("NewQcow2Reader() failure: %s", err)
+	}
+	if q.VirtualSize() != int64(len(raw)) {
+		t.Fatalf("expected virtual size %d, got %d", len(raw), q.VirtualSize())
+	}
+	if err = iotest.TestReader(q, raw); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQcow2ReaderVersion2(t *testing.T) {
+	// zero flag is not defined in version 2, unallocated clusters beyond L1 table read as zeros
+	image, raw := testQcow2Image(t, 2, 70*512, []testQcow2Cluster{{kind: "allocated", data: testPattern(5, 512)}})
+	q, err := NewQcow2Reader(bytes.NewReader(image))
+	if err != nil {
+		t.Fatalf("NewQcow2Reader() failure: %s", err)
+	}
+	out, err := ioutil.ReadAll(q)
+	if err != nil {
+		t.Fatalf("Read() failure: %s", err)
+	}
+	if !bytes.Equal(out, raw) {
+		t.Fatalf("raw image mismatch")
+	}
+}
+
+func TestQcow2ReaderTruncated(t *testing.T) {
+	image, raw := testQcow2Image(t, 3, 2*512, []testQcow2Cluster{
+		{kind: "allocated", data: testPattern(1, 512)},
+		{kind: "allocated", data: testPattern(2, 512)},
+	})
+	// last cluster at the end of image file may be truncated, missing bytes are zeros
+	image = image[:len(image)-200]
+	zeroFill(raw[len(raw)-200:])
+	q, err := NewQcow2Reader(bytes.NewReader(image))
+	if err != nil {
+		t.Fatalf("NewQcow2Reader() failure: %s", err)
+	}
+	out, err := ioutil.ReadAll(q)
+	if err != nil {
+		t.Fatalf("Read() failure: %s", err)
+	}
+	if !bytes.Equal(out, raw) {
+		t.Fatalf("raw image mismatch")
+	}
+
+	compressed, _ := testQcow2Image(t, 3, 512, []testQcow2Cluster{{kind: "compressed", data: testPattern(9, 512)}})
+	if q, err = NewQcow2Reader(bytes.NewReader(compressed[:len(compressed)-10])); err != nil {
+		t.Fatalf("NewQcow2Reader() failure: %s", err)
+	}
+	if _, err = ioutil.ReadAll(q); err == nil || !strings.Contains(err.Error(), "failure to decompress cluster") {
+		t.Fatalf("expected decompress failure, got %v", err)
+	}
+
+	// L2 table is cut off
+	if q, err = NewQcow2Reader(bytes.NewReader(image[:2*512+8])); err != nil {
+		t.Fatalf("NewQcow2Reader() failure: %s", err)
+	}
+	if _, err = ioutil.ReadAll(q); err == nil || !strings.Contains(err.Error(), "failure to read L2 table") {
+		t.Fatalf("expected L2 table failure, got %v", err)
+	}
+}
+
+func TestNewQcow2ReaderErrors(t *testing.T) {
+	valid, _ := testQcow2Image(t, 3, 512, nil)
+	tests := []struct {
+		name   string
+		modify func(header []byte) []byte
+		err    string
+	}{
+		{"truncated header", func(h []byte) []byte { return h[:qcow2HeaderV2Size-1] }, "failure to read header"},
+		{"truncated L1 table", func(h []byte) []byte { return h[:512+4] }, "failure to read L1 table"},
+		{"not qcow2", func(h []byte) []byte { h[0] = 'X'; return h }, "not a qcow2 image"},
+		{"version", func(h []byte) []byte { binary.BigEndian.PutUint32(h[4:8], 1); return h }, "version 1 is not supported"},
+		{"backing file", func(h []byte) []byte { binary.BigEndian.PutUint64(h[8:16], 1024); return h }, "backing file"},
+		{"encryption", func(h []byte) []byte { binary.BigEndian.PutUint32(h[32:36], 2); return h }, "encrypted"},
+		{"cluster bits", func(h []byte) []byte { binary.BigEndian.PutUint32(h[20:24], 22); return h }, "invalid cluster bits 22"},
+		{"zstd", func(h []byte) []byte {
+			binary.BigEndian.PutUint64(h[72:80], qcow2IncompatCompression)
+			h[104] = 1
+			return h
+		}, "compression type 1"},
+		{"external data file", func(h []byte) []byte { binary.BigEndian.PutUint64(h[72:80], 1<<2); return h }, "incompatible features 0x4"},
+		{"L1 table size", func(h []byte) []byte { binary.BigEndian.PutUint32(h[36:40], qcow2MaxL1Size/8+1); return h }, "is too large"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			image := test.modify(append([]byte(nil), valid...))
+			if _, err := NewQcow2Reader(bytes.NewReader(image)); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+	// dirty bit affects refcounts only
+	image := append([]byte(nil), valid...)
+	binary.BigEndian.PutUint64(image[72:80], qcow2IncompatDirty)
+	if _, err := NewQcow2Reader(bytes.NewReader(image)); err != nil {
+		t.Fatalf("NewQcow2Reader() failure on dirty image: %s", err)
+	}
+}
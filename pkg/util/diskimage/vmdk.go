@@ -0,0 +1,166 @@
+package diskimage
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	vmdkFlagCompressed     = uint32(1) << 16
+	vmdkFlagMarkers        = uint32(1) << 17
+	vmdkCompressionDeflate = 1
+	vmdkMarkerEndOfStream  = 0
+	vmdkMarkerHeaderSize   = 12
+	// Max grain size (1 MB) to protect from corrupted headers
+	vmdkMaxGrainSize = 1024 * 1024
+)
+
+// VmdkStreamReader converts stream-optimized VMDK image into raw image stream,
+// grains are expected in ascending order as written by VMware and qemu-img
+type VmdkStreamReader struct {
+	r         io.Reader
+	capacity  int64
+	grainSize int64
+	outOffset int64
+	zeros     int64
+	grain     []byte
+	grainPos  int
+	eos       bool
+}
+
+// NewVmdkStreamReader creates stream-optimized VMDK to raw image converter
+func NewVmdkStreamReader(r io.Reader) (v *VmdkStreamReader, err error) {
+	header := make([]byte, sectorSize)
+	if _, err = io.ReadFull(r, header); err != nil {
+		err = fmt.Errorf("NewVmdkStreamReader(): failure to read header: %s", err)
+		return
+	}
+	if !bytes.HasPrefix(header, vmdkMagic) {
+		err = fmt.Errorf("NewVmdkStreamReader(): not a VMDK sparse extent")
+		return
+	}
+	flags := binary.LittleEndian.Uint32(header[8:12])
+	compressAlgorithm := binary.LittleEndian.Uint16(header[77:79])
+	if flags&vmdkFlagCompressed == 0 || flags&vmdkFlagMarkers == 0 || compressAlgorithm != vmdkCompressionDeflate {
+		err = fmt.Errorf("NewVmdkStreamReader(): only stream-optimized VMDK images are supported")
+		return
+	}
+	v = &VmdkStreamReader{
+		r:         r,
+		capacity:  int64(binary.LittleEndian.Uint64(header[12:20])) * sectorSize,
+		grainSize: int64(binary.LittleEndian.Uint64(header[20:28])) * sectorSize,
+	}
+	if v.grainSize <= 0 || v.grainSize > vmdkMaxGrainSize {
+		err = fmt.Errorf("NewVmdkStreamReader(): invalid grain size %d", v.grainSize)
+		return
+	}
+	overHead := int64(binary.LittleEndian.Uint64(header[64:72])) * sectorSize
+	if overHead > sectorSize {
+		if _, err = io.CopyN(ioutil.Discard, r, overHead-sectorSize); err != nil {
+			err = fmt.Errorf("NewVmdkStreamReader(): failure to read metadata: %s", err)
+		}
+	}
+	return
+}
+
+// VirtualSize is raw image size
+func (v *VmdkStreamReader) VirtualSize() int64 {
+	return v.capacity
+}
+
+// Read reads raw image stream
+func (v *VmdkStreamReader) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		if v.zeros > 0 {
+			c := int64(len(p) - n)
+			if c > v.zeros {
+				c = v.zeros
+			}
+			zeroFill(p[n : n+int(c)])
+			n += int(c)
+			v.zeros -= c
+			continue
+		}
+		if v.grainPos < len(v.grain) {
+			c := copy(p[n:], v.grain[v.grainPos:])
+			n += c
+			v.grainPos += c
+			continue
+		}
+		if v.eos {
+			if n == 0 {
+				err = io.EOF
+			}
+			return
+		}
+		if err = v.readMarker(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// readMarker reads next stream marker, grain data is decompressed into grain buffer
+func (v *VmdkStreamReader) readMarker() (err error) {
+	marker := make([]byte, sectorSize)
+	if _, err = io.ReadFull(v.r, marker); err != nil {
+		err = fmt.Errorf("VmdkStreamReader: failure to read marker: %s", err)
+		return
+	}
+	val := int64(binary.LittleEndian.Uint64(marker[0:8]))
+	size := int64(binary.LittleEndian.Uint32(marker[8:12]))
+	if size == 0 {
+		if binary.LittleEndian.Uint32(marker[12:16]) == vmdkMarkerEndOfStream {
+			if v.capacity > v.outOffset {
+				v.zeros = v.capacity - v.outOffset
+				v.outOffset = v.capacity
+			}
+			v.eos = true
+			return
+		}
+		// grain table, grain directory or footer metadata follows the marker
+		if _, err = io.CopyN(ioutil.Discard, v.r, val*sectorSize); err != nil {
+			err = fmt.Errorf("VmdkStreamReader: failure to read metadata: %s", err)
+		}
+		return
+	}
+	recordSize := (vmdkMarkerHeaderSize + size + sectorSize - 1) / sectorSize * sectorSize
+	record := make([]byte, recordSize)
+	copy(record, marker)
+	if recordSize > sectorSize {
+		if _, err = io.ReadFull(v.r, record[sectorSize:]); err != nil {
+			err = fmt.Errorf("VmdkStreamReader: failure to read grain: %s", err)
+			return
+		}
+	}
+	var zr io.ReadCloser
+	if zr, err = zlib.NewReader(bytes.NewReader(record[vmdkMarkerHeaderSize : vmdkMarkerHeaderSize+size])); err != nil {
+		err = fmt.Errorf("VmdkStreamReader: failure to decompress grain: %s", err)
+		return
+	}
+	defer zr.Close()
+	if v.grain, err = ioutil.ReadAll(io.LimitReader(zr, v.grainSize)); err != nil {
+		err = fmt.Errorf("VmdkStreamReader: failure to decompress grain: %s", err)
+		return
+	}
+	v.grainPos = 0
+	grainOffset := val * sectorSize
+	if grainOffset < v.outOffset {
+		err = fmt.Errorf("VmdkStreamReader: out of order grain at sector %d is not supported", val)
+		return
+	}
+	if grainOffset+int64(len(v.grain)) > v.capacity {
+		if grainOffset >= v.capacity {
+			v.grain = nil
+			return
+		}
+		v.grain = v.grain[:v.capacity-grainOffset]
+	}
+	v.zeros = grainOffset - v.outOffset
+	v.outOffset = grainOffset + int64(len(v.grain))
+	return
+}
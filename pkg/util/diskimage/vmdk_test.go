@@ -0,0 +1,215 @@
+package diskimage
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const (
+	testGrainSectors = 2
+	testGrainSize    = testGrainSectors * sectorSize
+)
+
+// testVmdkStream builds stream-optimized VMDK image, grains are addressed by starting sector
+type testVmdkStream struct {
+	bytes.Buffer
+}
+
+func newTestVmdkStream(capacitySectors uint64, overHeadSectors uint64) *testVmdkStream {
+	s := &testVmdkStream{}
+	header := make([]byte, sectorSize)
+	copy(header, vmdkMagic)
+	binary.LittleEndian.PutUint32(header[4:8], 3)
+	binary.LittleEndian.PutUint32(header[8:12], 1|vmdkFlagCompressed|vmdkFlagMarkers)
+	binary.LittleEndian.PutUint64(header[12:20], capacitySectors)
+	binary.LittleEndian.PutUint64(header[20:28], testGrainSectors)
+	binary.LittleEndian.PutUint64(header[64:72], overHeadSectors)
+	binary.LittleEndian.PutUint16(header[77:79], vmdkCompressionDeflate)
+	s.Write(header)
+	// embedded descriptor
+	if overHeadSectors > 1 {
+		s.Write(bytes.Repeat([]byte{'#'}, int(overHeadSectors-1)*sectorSize))
+	}
+	return s
+}
+
+func (s *testVmdkStream) pad() {
+	if r := s.Len() % sectorSize; r != 0 {
+		s.Write(make([]byte, sectorSize-r))
+	}
+}
+
+func (s *testVmdkStream) grain(lba uint64, data []byte) {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write(data)
+	zw.Close()
+	marker := make([]byte, vmdkMarkerHeaderSize)
+	binary.LittleEndian.PutUint64(marker[0:8], lba)
+	binary.LittleEndian.PutUint32(marker[8:12], uint32(b.Len()))
+	s.Write(marker)
+	s.Write(b.Bytes())
+	s.pad()
+}
+
+func (s *testVmdkStream) metadata(markerType uint32, sectors uint64) {
+	marker := make([]byte, sectorSize)
+	binary.LittleEndian.PutUint64(marker[0:8], sectors)
+	binary.LittleEndian.PutUint32(marker[12:16], markerType)
+	s.Write(marker)
+	s.Write(bytes.Repeat([]byte{0xee}, int(sectors)*sectorSize))
+}
+
+func (s *testVmdkStream) eos() {
+	s.Write(make([]byte, sectorSize))
+}
+
+func TestVmdkStreamReader(t *testing.T) {
+	raw := make([]byte, 11*sectorSize)
+	s := newTestVmdkStream(11, 3)
+	grain0 := testPattern(1, testGrainSize)
+	s.grain(0, grain0)
+	copy(raw, grain0)
+	// grain at sector 2 is sparse, grain at sector 4 is partial
+	grain4 := bytes.Repeat([]byte("flexbot"), 100)
+	s.grain(4, grain4)
+	copy(raw[4*sectorSize:], grain4)
+	// grain table, grain directory and footer
+	s.metadata(1, 1)
+	s.metadata(2, 1)
+	s.metadata(3, 1)
+	// last grain is cut by capacity
+	grain10 := testPattern(5, testGrainSize)
+	s.grain(10, grain10)
+	copy(raw[10*sectorSize:], grain10)
+	s.eos()
+	v, err := NewVmdkStreamReader(bytes.NewReader(s.Bytes()))
+	if err != nil {
+		t.Fatalf("NewVmdkStreamReader() failure: %s", err)
+	}
+	if v.VirtualSize() != int64(len(raw)) {
+		t.Fatalf("expected virtual size %d, got %d", len(raw), v.VirtualSize())
+	}
+	if err = iotest.TestReader(v, raw); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVmdkStreamReaderEndOfStreamPadding(t *testing.T) {
+	// sectors after the last grain up to capacity are zeros
+	raw := make([]byte, 64*sectorSize)
+	s := newTestVmdkStream(64, 1)
+	grain := testPattern(3, testGrainSize)
+	s.grain(6, grain)
+	copy(raw[6*sectorSize:], grain)
+	s.eos()
+	// data after end-of-stream marker is ignored
+	s.Write(bytes.Repeat([]byte{0xff}, sectorSize))
+	v, err := NewVmdkStreamReader(bytes.NewReader(s.Bytes()))
+	if err != nil {
+		t.Fatalf("NewVmdkStreamReader() failure: %s", err)
+	}
+	out, err := ioutil.ReadAll(v)
+	if err != nil {
+		t.Fatalf("Read() failure: %s", err)
+	}
+	if !bytes.Equal(out, raw) {
+		t.Fatalf("raw image mismatch")
+	}
+
+	// empty image
+	s = newTestVmdkStream(4, 1)
+	s.eos()
+	if v, err = NewVmdkStreamReader(bytes.NewReader(s.Bytes())); err != nil {
+		t.Fatalf("NewVmdkStreamReader() failure: %s", err)
+	}
+	if out, err = ioutil.ReadAll(v); err != nil || !bytes.Equal(out, make([]byte, 4*sectorSize)) {
+		t.Fatalf("expected %d zero bytes, got %d bytes, error %v", 4*sectorSize, len(out), err)
+	}
+}
+
+func TestVmdkStreamReaderErrors(t *testing.T) {
+	stream := func(build func(s *testVmdkStream)) []byte {
+		s := newTestVmdkStream(8, 1)
+		build(s)
+		return s.Bytes()
+	}
+	outOfOrder := stream(func(s *testVmdkStream) {
+		s.grain(4, testPattern(1, testGrainSize))
+		s.grain(2, testPattern(2, testGrainSize))
+		s.eos()
+	})
+	noEndOfStream := stream(func(s *testVmdkStream) {
+		s.grain(0, testPattern(1, testGrainSize))
+	})
+	// incompressible grain spans several sectors
+	cutGrain := stream(func(s *testVmdkStream) {
+		b := make([]byte, testGrainSize)
+		rand.New(rand.NewSource(1)).Read(b)
+		s.grain(0, b)
+		s.eos()
+	})
+	cutGrain = cutGrain[:sectorSize+sectorSize+100]
+	corruptGrain := stream(func(s *testVmdkStream) {
+		s.grain(0, testPattern(1, testGrainSize))
+		s.eos()
+	})
+	corruptGrain[sectorSize+vmdkMarkerHeaderSize] = 0
+	cutMetadata := stream(func(s *testVmdkStream) {
+		s.metadata(1, 4)
+	})
+	cutMetadata = cutMetadata[:len(cutMetadata)-sectorSize]
+	tests := []struct {
+		name  string
+		image []byte
+		err   string
+	}{
+		{"out of order grain", outOfOrder, "out of order grain at sector 2"},
+		{"missing end of stream", noEndOfStream, "failure to read marker"},
+		{"truncated grain", cutGrain, "failure to read grain"},
+		{"corrupted grain", corruptGrain, "failure to decompress grain"},
+		{"truncated metadata", cutMetadata, "failure to read metadata"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := NewVmdkStreamReader(bytes.NewReader(test.image))
+			if err != nil {
+				t.Fatalf("NewVmdkStreamReader() failure: %s", err)
+			}
+			if _, err = ioutil.ReadAll(v); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestNewVmdkStreamReaderErrors(t *testing.T) {
+	valid := newTestVmdkStream(8, 4).Bytes()
+	tests := []struct {
+		name   string
+		modify func(image []byte) []byte
+		err    string
+	}{
+		{"truncated header", func(b []byte) []byte { return b[:sectorSize-1] }, "failure to read header"},
+		{"truncated descriptor", func(b []byte) []byte { return b[:2*sectorSize] }, "failure to read metadata"},
+		{"not vmdk", func(b []byte) []byte { b[0] = 'X'; return b }, "not a VMDK sparse extent"},
+		{"monolithic sparse", func(b []byte) []byte { binary.LittleEndian.PutUint32(b[8:12], 1); return b }, "only stream-optimized"},
+		{"compression", func(b []byte) []byte { binary.LittleEndian.PutUint16(b[77:79], 2); return b }, "only stream-optimized"},
+		{"zero grain size", func(b []byte) []byte { binary.LittleEndian.PutUint64(b[20:28], 0); return b }, "invalid grain size 0"},
+		{"large grain size", func(b []byte) []byte { binary.LittleEndian.PutUint64(b[20:28], 4096); return b }, "invalid grain size"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			image := test.modify(append([]byte(nil), valid...))
+			if _, err := NewVmdkStreamReader(bytes.NewReader(image)); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// ParsePublicKey parses ed25519 public key either in PEM (PKIX) or base64 (raw 32 bytes key) encoding
+func ParsePublicKey(key string) (publicKey ed25519.PublicKey, err error) {
+	if block, _ := pem.Decode([]byte(key)); block != nil {
+		var pub interface{}
+		if pub, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			err = fmt.Errorf("ParsePublicKey(): failure to parse PEM public key: %s", err)
+			return
+		}
+		var ok bool
+		if publicKey, ok = pub.(ed25519.PublicKey); !ok {
+			err = fmt.Errorf("ParsePublicKey(): expected ed25519 public key")
+		}
+		return
+	}
+	var b []byte
+	if b, err = base64.StdEncoding.DecodeString(strings.TrimSpace(key)); err != nil {
+		err = fmt.Errorf("ParsePublicKey(): failure to decode base64 public key: %s", err)
+		return
+	}
+	if len(b) != ed25519.PublicKeySize {
+		err = fmt.Errorf("ParsePublicKey(): expected %d bytes ed25519 public key, got %d bytes", ed25519.PublicKeySize, len(b))
+		return
+	}
+	publicKey = ed25519.PublicKey(b)
+	return
+}
+
+// KeyId makes short identifier of public key
+func KeyId(publicKey ed25519.PublicKey) string {
+	h := sha256.Sum256(publicKey)
+	return hex.EncodeToString(h[:8])
+}
+
+// TrustedKeyIds makes identifiers of trusted public keys
+func TrustedKeyIds(trustedKeys []string) (keyIds []string, err error) {
+	for _, key := range trustedKeys {
+		var publicKey ed25519.PublicKey
+		if publicKey, err = ParsePublicKey(key); err != nil {
+			return
+		}
+		keyIds = append(keyIds, KeyId(publicKey))
+	}
+	return
+}
+
+// ReadSignature reads detached signature from file or URL, signature is either raw or base64 encoded
+func ReadSignature(location string) (signature []byte, err error) {
+	var b []byte
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		var httpResponse *http.Response
+		if httpResponse, err = http.Get(location); err != nil {
+			err = fmt.Errorf("ReadSignature(): failure to open signature %s: %s", location, err)
+			return
+		}
+		defer httpResponse.Body.Close()
+		if httpResponse.StatusCode != http.StatusOK {
+			err = fmt.Errorf("ReadSignature(): failure to open signature %s: %s", location, httpResponse.Status)
+			return
+		}
+		if b, err = ioutil.ReadAll(httpResponse.Body); err != nil {
+			err = fmt.Errorf("ReadSignature(): failure to read signature %s: %s", location, err)
+			return
+		}
+	} else {
+		if b, err = ioutil.ReadFile(strings.TrimPrefix(location, "file://")); err != nil {
+			err = fmt.Errorf("ReadSignature(): failure to read signature %s: %s", location, err)
+			return
+		}
+	}
+	signature, err = DecodeSignature(b)
+	return
+}
+
+// DecodeSignature decodes raw or base64 encoded signature
+func DecodeSignature(b []byte) (signature []byte, err error) {
+	if len(b) == ed25519.SignatureSize {
+		signature = b
+		return
+	}
+	if signature, err = base64.StdEncoding.DecodeString(string(bytes.TrimSpace(b))); err != nil {
+		err = fmt.Errorf("DecodeSignature(): failure to decode base64 signature: %s", err)
+		return
+	}
+	if len(signature) != ed25519.SignatureSize {
+		err = fmt.Errorf("DecodeSignature(): expected %d bytes ed25519 signature, got %d bytes", ed25519.SignatureSize, len(signature))
+	}
+	return
+}
+
+// VerifyDigest verifies ed25519 signature of artifact SHA-256 digest with trusted keys,
+// returns identifier of the key the signature is verified with
+func VerifyDigest(digest []byte, signature []byte, trustedKeys []string) (keyId string, err error) {
+	if len(trustedKeys) == 0 {
+		err = fmt.Errorf("VerifyDigest(): no trusted keys configured")
+		return
+	}
+	for _, key := range trustedKeys {
+		var publicKey ed25519.PublicKey
+		if publicKey, err = ParsePublicKey(key); err != nil {
+			return
+		}
+		if ed25519.Verify(publicKey, digest, signature) {
+			keyId = KeyId(publicKey)
+			return
+		}
+	}
+	err = fmt.Errorf("VerifyDigest(): signature is not verified by any of trusted keys")
+	return
+}
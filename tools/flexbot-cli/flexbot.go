@@ -7,13 +7,15 @@ import (
 	"fmt"
 	"io/ioutil"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/denisbrodbeck/machineid"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ipam"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap"
-	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ucsm"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap/client"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/server"
 	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/util/crypt"
 	"gopkg.in/yaml.v3"
 )
@@ -36,13 +38,35 @@ type BaseResult struct {
 // NodeResult type
 type NodeResult struct {
 	BaseResult `yaml:",inline" json:",inline"`
-	Node       *config.NodeConfig `yaml:"server,omitempty" json:"server,omitempty"`
+	Node       *config.NodeConfig        `yaml:"server,omitempty" json:"server,omitempty"`
+	Plan       []config.PlannedOperation `yaml:"plan,omitempty" json:"plan,omitempty"`
 }
 
 // ImageResult type
 type ImageResult struct {
 	BaseResult `yaml:",inline" json:",inline"`
-	Images     []string `yaml:"images,omitempty" json:"images,omitempty"`
+	Images     []string              `yaml:"images,omitempty" json:"images,omitempty"`
+	Details    []ontap.RepoImageInfo `yaml:"details,omitempty" json:"details,omitempty"`
+}
+
+// ImageGcResult type
+type ImageGcResult struct {
+	BaseResult `yaml:",inline" json:",inline"`
+	Images     []ontap.RepoImageUsage `yaml:"images,omitempty" json:"images,omitempty"`
+	Pruned     []string               `yaml:"pruned,omitempty" json:"pruned,omitempty"`
+}
+
+// RepoSyncResult type
+type RepoSyncResult struct {
+	BaseResult `yaml:",inline" json:",inline"`
+	Summary    string                 `yaml:"summary,omitempty" json:"summary,omitempty"`
+	Changes    []ontap.RepoSyncChange `yaml:"changes,omitempty" json:"changes,omitempty"`
+}
+
+// VerifyResult type
+type VerifyResult struct {
+	BaseResult    `yaml:",inline" json:",inline"`
+	Discrepancies []ontap.StorageDiscrepancy `yaml:"discrepancies,omitempty" json:"discrepancies,omitempty"`
 }
 
 // TemplateResult type
@@ -54,7 +78,21 @@ type TemplateResult struct {
 // SnapshotResult type
 type SnapshotResult struct {
 	BaseResult `yaml:",inline" json:",inline"`
-	Snapshots  []string `yaml:"snapshots,omitempty" json:"snapshots,omitempty"`
+	Snapshots  []string                `yaml:"snapshots,omitempty" json:"snapshots,omitempty"`
+	Details    []ontap.SnapshotDetails `yaml:"details,omitempty" json:"details,omitempty"`
+}
+
+// HostPruneResult type
+type HostPruneResult struct {
+	Host         string   `yaml:"host" json:"host"`
+	Pruned       []string `yaml:"pruned,omitempty" json:"pruned,omitempty"`
+	ErrorMessage string   `yaml:"errorMessage,omitempty" json:"errorMessage,omitempty"`
+}
+
+// PruneResult type
+type PruneResult struct {
+	BaseResult `yaml:",inline" json:",inline"`
+	Hosts      []HostPruneResult `yaml:"hosts,omitempty" json:"hosts,omitempty"`
 }
 
 func usage() {
@@ -63,14 +101,17 @@ func usage() {
 	fmt.Printf("flexbot version %s %s/%s\n\n", version, goOS, goARCH)
 	flag.Usage()
 	fmt.Println("")
-	fmt.Printf("flexbot --config=<config file path> --op=provisionServer --host=<host name> --image=<image name> --templatePath=<cloud-init template path>\n\n")
+	fmt.Printf("flexbot --config=<config file path> --op=provisionServer --host=<host name> --image=<image name> --templatePath=<cloud-init template path> [--dryRun]\n\n")
 	fmt.Printf("flexbot --config=<config file path> --op=stopServer --host=<host name>\n\n")
 	fmt.Printf("flexbot --config=<config file path> --op=startServer --host=<host name>\n\n")
-	fmt.Printf("flexbot --config=<config file path> --op=deprovisionServer --host=<host name>\n\n")
-	fmt.Printf("flexbot --config=<config file path> --op=uploadImage --image=<image name> --imagePath=<image path>\n\n")
+	fmt.Printf("flexbot --config=<config file path> --op=deprovisionServer --host=<host name> [--dryRun]\n\n")
+	fmt.Printf("flexbot --config=<config file path> --op=verifyServer --host=<host name> [--template=<cloud-init template name or path>]\n\n")
+	fmt.Printf("flexbot --config=<config file path> --op=uploadImage --image=<image name> --imagePath=<image path> [--imageChecksum=<image checksum>] [--signature=<signature path>]\n\n")
 	fmt.Printf("flexbot --config=<config file path> --op=deleteImage --image=<image name>\n\n")
 	fmt.Printf("flexbot --config=<config file path> --op=listImages\n\n")
-	fmt.Printf("flexbot --config=<config file path> --op=uploadTemplate --template=<template name> --templatePath=<template path>\n\n")
+	fmt.Printf("flexbot --config=<config file path> --op=gcImages [--maxAge=<max age of unused images to delete>] [--image=<image name to keep>[,<image name>...]]\n\n")
+	fmt.Printf("flexbot --config=<config file path> --op=syncRepo --manifest=<manifest path> [--prune] [--dryRun] [--parallelism=<number of parallel uploads>]\n\n")
+	fmt.Printf("flexbot --config=<config file path> --op=uploadTemplate --template=<template name> --templatePath=<template path> [--signature=<signature path>]\n\n")
 	fmt.Printf("flexbot --config=<config file path> --op=downloadTemplate --template=<template name>\n\n")
 	fmt.Printf("flexbot --config=<config file path> --op=deleteTemplate --template=<template name>\n\n")
 	fmt.Printf("flexbot --config=<config file path> --op=listTemplates\n\n")
@@ -78,6 +119,9 @@ func usage() {
 	fmt.Printf("flexbot --config=<config file path> --op=deleteSnapshot --host=<host name> --snapshot=<snapshot name>\n\n")
 	fmt.Printf("flexbot --config=<config file path> --op=restoreSnapshot --host=<host name> --snapshot=<snapshot name>\n\n")
 	fmt.Printf("flexbot --config=<config file path> --op=listSnapshots --host=<host name>\n\n")
+	fmt.Printf("flexbot --config=<config file path> --op=pruneSnapshots --host=<host name>[,<host name>...]\n\n")
+	fmt.Printf("flexbot --config=<config file path> --op=createGroupSnapshot --host=<host name>,<host name>[,<host name>...] --snapshot=<snapshot name> [--sshUser=<user name> --sshKey=<private key path>]\n\n")
+	fmt.Printf("flexbot --config=<config file path> --op=restoreGroupSnapshot --host=<host name>,<host name>[,<host name>...] --snapshot=<snapshot name>\n\n")
 	fmt.Printf("flexbot --config=<config file path> --op=decryptConfig [--passphrase=<password phrase>]\n\n")
 	fmt.Printf("flexbot --config=<config file path> --op=encryptConfig [--passphrase=<password phrase>]\n\n")
 	fmt.Printf("flexbot --op=encryptString --sourceString <string to encrypt> [--passphrase=<password phrase>]\n\n")
@@ -133,8 +177,14 @@ func (result *BaseResult) DumpResult(r interface{}, resultDest string, resultFor
 func (result *NodeResult) DumpResult(r interface{}, resultDest string, resultFormat string, resultErr error) {
 	result.Node.Ipam.IbCredentials = config.InfobloxCredentials{}
 	result.Node.Storage.CdotCredentials = config.CdotCredentials{}
+	result.Node.Storage.Replication.CdotCredentials = config.CdotCredentials{}
 	result.Node.Compute.UcsmCredentials = config.Credentials{}
+	result.Node.Compute.RedfishCredentials = config.Credentials{}
+	result.Node.Compute.IntersightCredentials = config.Credentials{}
 	result.Node.CloudArgs = map[string]string{}
+	if result.Node.Plan != nil {
+		result.Plan = result.Node.Plan.Operations()
+	}
 	result.BaseResult.DumpResult(r, resultDest, resultFormat, resultErr)
 }
 
@@ -149,20 +199,20 @@ func provisionServer(nodeConfig *config.NodeConfig) (err error) {
 	if err = ontap.CreateBootStorage(nodeConfig); err != nil {
 		return
 	}
-	if _, err = ucsm.CreateServer(nodeConfig); err != nil {
+	if err = server.CreateServer(nodeConfig); err != nil {
 		return
 	}
 	if err = ontap.CreateSeedStorage(nodeConfig); err != nil {
 		return
 	}
-	if err = ucsm.StartServer(nodeConfig); err != nil {
+	if err = server.StartServer(nodeConfig); err != nil {
 		return
 	}
 	return
 }
 
 func discoverServer(nodeConfig *config.NodeConfig) (serverExists bool, err error) {
-	if serverExists, err = ucsm.DiscoverServer(nodeConfig); err != nil {
+	if serverExists, err = server.DiscoverServer(nodeConfig); err != nil {
 		return
 	}
 	if serverExists {
@@ -176,6 +226,24 @@ func discoverServer(nodeConfig *config.NodeConfig) (serverExists bool, err error
 	return
 }
 
+func verifyServer(nodeConfig *config.NodeConfig) (discrepancies []ontap.StorageDiscrepancy, err error) {
+	var serverExists bool
+	if serverExists, err = server.DiscoverServer(nodeConfig); err != nil {
+		return
+	}
+	if !serverExists {
+		err = fmt.Errorf("verifyServer(): server %s does not exist", nodeConfig.Compute.HostName)
+		return
+	}
+	if discrepancies, err = ontap.VerifyBootStorage(nodeConfig); err != nil {
+		return
+	}
+	if len(discrepancies) > 0 {
+		err = fmt.Errorf("verifyServer(): found %d storage discrepancies", len(discrepancies))
+	}
+	return
+}
+
 func provisionServerPreflight(nodeConfig *config.NodeConfig) (err error) {
 	var stepErr error
 	var ipamProvider ipam.IpamProvider
@@ -196,7 +264,7 @@ func provisionServerPreflight(nodeConfig *config.NodeConfig) (err error) {
 			err = fmt.Errorf("%s\n%s", err, stepErr)
 		}
 	}
-	if stepErr = ucsm.CreateServerPreflight(nodeConfig); stepErr != nil {
+	if stepErr = server.CreateServerPreflight(nodeConfig); stepErr != nil {
 		if err == nil {
 			err = stepErr
 		} else {
@@ -220,14 +288,14 @@ func deprovisionServer(nodeConfig *config.NodeConfig) (err error) {
 	if ipamProvider, err = ipam.NewProvider(&nodeConfig.Ipam); err != nil {
 		return
 	}
-	if powerState, err = ucsm.GetServerPowerState(nodeConfig); err != nil {
+	if powerState, err = server.GetServerPowerState(nodeConfig); err != nil {
 		return
 	}
 	if powerState == "up" {
 		err = fmt.Errorf("DeprovisionServer: server \"%s\" has power state \"%s\"", nodeConfig.Compute.HostName, powerState)
 		return
 	}
-	if stepErr = ucsm.DeleteServer(nodeConfig); stepErr != nil {
+	if stepErr = server.DeleteServer(nodeConfig); stepErr != nil {
 		if err == nil {
 			err = stepErr
 		} else {
@@ -253,7 +321,7 @@ func deprovisionServer(nodeConfig *config.NodeConfig) (err error) {
 
 func restoreSnapshot(nodeConfig *config.NodeConfig, snapshotName string) (err error) {
 	var powerState string
-	if powerState, err = ucsm.GetServerPowerState(nodeConfig); err != nil {
+	if powerState, err = server.GetServerPowerState(nodeConfig); err != nil {
 		return
 	}
 	if powerState == "up" {
@@ -264,26 +332,79 @@ func restoreSnapshot(nodeConfig *config.NodeConfig, snapshotName string) (err er
 	return
 }
 
-func uploadImage(nodeConfig *config.NodeConfig, imageName string, imagePath string) (err error) {
-	outcome := make(chan bool)
-	defer close(outcome)
+func pruneSnapshots(nodeConfig *config.NodeConfig, hostNames []string, passPhrase string) (hosts []HostPruneResult, err error) {
+	var b []byte
+	var failed []string
+	if b, err = yaml.Marshal(nodeConfig); err != nil {
+		err = fmt.Errorf("PruneSnapshots: Marshal() failure: %s", err)
+		return
+	}
+	for _, hostName := range hostNames {
+		var hostConfig config.NodeConfig
+		var stepErr error
+		hostResult := HostPruneResult{Host: hostName}
+		if stepErr = yaml.Unmarshal(b, &hostConfig); stepErr == nil {
+			if stepErr = config.SetDefaults(&hostConfig, hostName, "", "", passPhrase); stepErr == nil {
+				hostResult.Pruned, stepErr = ontap.PruneSnapshots(&hostConfig, nil)
+			}
+		}
+		if stepErr != nil {
+			hostResult.ErrorMessage = stepErr.Error()
+			failed = append(failed, hostName)
+		}
+		hosts = append(hosts, hostResult)
+	}
+	if len(failed) > 0 {
+		err = fmt.Errorf("PruneSnapshots: failure for hosts: %s", strings.Join(failed, ","))
+	}
+	return
+}
+
+func syncRepo(nodeConfig *config.NodeConfig, manifestPath string, prune bool, dryRun bool, parallelism int) (changes []ontap.RepoSyncChange, err error) {
+	var manifest *ontap.RepoManifest
+	if manifest, err = ontap.ReadRepoManifest(nodeConfig, manifestPath); err != nil {
+		return
+	}
+	if changes, err = ontap.PlanRepoSync(nodeConfig, manifest, prune, nil); err != nil || dryRun || !ontap.RepoSyncPending(changes) {
+		return
+	}
+	err = ontap.ApplyRepoSync(nodeConfig, manifest, changes, parallelism, nil)
+	return
+}
+
+// uploadProgress makes upload progress callback which prints percent and throughput at most once per second
+func uploadProgress(startTime time.Time) client.UploadProgress {
+	var lastPrint time.Time
+	return func(bytesUploaded int64, bytesTotal int64) {
+		if time.Since(lastPrint) < time.Second && bytesUploaded < bytesTotal {
+			return
+		}
+		lastPrint = time.Now()
+		throughput := float64(bytesUploaded) / 1048576 / time.Since(startTime).Seconds()
+		if bytesTotal > 0 {
+			fmt.Printf("\rUploading image: %3d%% (%d of %d MB, %.1f MB/s)", bytesUploaded*100/bytesTotal, bytesUploaded>>20, bytesTotal>>20, throughput)
+		} else {
+			fmt.Printf("\rUploading image: %d MB (%.1f MB/s)", bytesUploaded>>20, throughput)
+		}
+	}
+}
+
+func uploadImage(nodeConfig *config.NodeConfig, imageName string, imagePath string, imageChecksum string, imageSignature string) (err error) {
 	fmt.Printf("Uploading image..")
-	go printProgess(outcome)
-	if err = ontap.CreateRepoImage(nodeConfig, imageName, imagePath); err != nil {
-		outcome <- false
+	if err = ontap.CreateRepoImage(nodeConfig, imageName, imagePath, imageChecksum, imageSignature, uploadProgress(time.Now())); err != nil {
+		fmt.Println(" failure")
 	} else {
-		outcome <- true
+		fmt.Println(" success")
 	}
-	time.Sleep(1 * time.Second)
 	return
 }
 
-func uploadTemplate(nodeConfig *config.NodeConfig, templateName string, templatePath string) (err error) {
+func uploadTemplate(nodeConfig *config.NodeConfig, templateName string, templatePath string, templateSignature string) (err error) {
 	outcome := make(chan bool)
 	defer close(outcome)
 	fmt.Printf("Uploading template..")
 	go printProgess(outcome)
-	if err = ontap.CreateRepoTemplate(nodeConfig, templateName, templatePath); err != nil {
+	if err = ontap.CreateRepoTemplate(nodeConfig, templateName, templatePath, templateSignature); err != nil {
 		outcome <- false
 	} else {
 		outcome <- true
@@ -329,14 +450,23 @@ func main() {
 	var passPhrase string
 	optHostName := flag.String("host", "", "compute node name")
 	optImageName := flag.String("image", "", "boot image name")
-	optImagePath := flag.String("imagePath", "", "a path to boot image (prefix can be either file:// or http(s)://)")
+	optImagePath := flag.String("imagePath", "", "a path to boot image (prefix can be either file://, http(s)://, s3:// or oci://)")
+	optImageChecksum := flag.String("imageChecksum", "", "boot image SHA-256 checksum: [sha256:]<digest> or a path to checksum file (prefix can be either file:// or http(s)://)")
 	optTemplateName := flag.String("template", "", "cloud-init template name or path (prefix can be either file:// or http(s)://)")
-	optTemplatePath := flag.String("templatePath", "", "cloud-init template path (prefix can be either file:// or http(s)://)")
+	optTemplatePath := flag.String("templatePath", "", "cloud-init template path (prefix can be either file://, http(s)://, s3:// or oci://)")
+	optSignature := flag.String("signature", "", "a path to image or template ed25519 signature (prefix can be either file:// or http(s)://, default is image or template path with .sig suffix)")
 	optSnapshotName := flag.String("snapshot", "", "volume snapshot name")
+	optManifest := flag.String("manifest", "", "syncRepo: a path to images and templates manifest in YAML or JSON (prefix can be either file://, http(s)://, s3:// or oci://)")
+	optPrune := flag.Bool("prune", false, "syncRepo: delete images and templates not in manifest (images in use are kept)")
+	optDryRun := flag.Bool("dryRun", false, "syncRepo, provisionServer, deprovisionServer: report planned changes without applying them")
+	optParallelism := flag.Int("parallelism", 2, "syncRepo: number of parallel uploads")
+	optMaxAge := flag.String("maxAge", "", "gcImages: delete images not in use and older than max age (i.e. 720h or 30d), report only if not set")
 	optPassPhrase := flag.String("passphrase", "", "passphrase to encrypt/decrypt passwords in configuration (default is machineid)")
 	optSourceString := flag.String("sourceString", "", "source string to encrypt")
+	optSshUser := flag.String("sshUser", "", "SSH user name to freeze node filesystems while taking group snapshot")
+	optSshKey := flag.String("sshKey", "", "a path to SSH private key to freeze node filesystems while taking group snapshot")
 	optNodeConfig := flag.String("config", "STDIN", "a path to configuration file, STDIN, or argument value in JSON")
-	optOp := flag.String("op", "", "operation: \n\tprovisionServer\n\tdeprovisionServer\n\tstopServer\n\tstartServer\n\tuploadImage\n\tdeleteImage\n\tlistImages\n\tuploadTemplate\n\tdownloadTemplate\n\tdeleteTemplate\n\tlistTemplates\n\tcreateSnapshot\n\tdeleteSnapshot\n\trestoreSnapshot\n\tlistSnapshots\n\tencryptConfig\n\tdecryptConfig\n\tencryptString")
+	optOp := flag.String("op", "", "operation: \n\tprovisionServer\n\tdeprovisionServer\n\tverifyServer\n\tstopServer\n\tstartServer\n\tuploadImage\n\tdeleteImage\n\tlistImages\n\tgcImages\n\tsyncRepo\n\tuploadTemplate\n\tdownloadTemplate\n\tdeleteTemplate\n\tlistTemplates\n\tcreateSnapshot\n\tdeleteSnapshot\n\trestoreSnapshot\n\tlistSnapshots\n\tpruneSnapshots\n\tcreateGroupSnapshot\n\trestoreGroupSnapshot\n\tencryptConfig\n\tdecryptConfig\n\tencryptString")
 	optDumpResult := flag.String("dumpResult", "STDOUT", "dump result: file path or STDOUT")
 	optEncodingFormat := flag.String("encodingFormat", "yaml", "supported encoding formats: json, yaml")
 	optVersion := flag.Bool("version", false, "flexbot version")
@@ -360,7 +490,12 @@ func main() {
 			err = fmt.Errorf("ParseNodeConfig() failure: %s", err)
 			panic(err.Error())
 		}
-		if err = config.SetDefaults(&nodeConfig, *optHostName, *optImageName, *optTemplateName, passPhrase); err != nil {
+		hostName := *optHostName
+		if *optOp == "pruneSnapshots" || *optOp == "createGroupSnapshot" || *optOp == "restoreGroupSnapshot" {
+			// host specific defaults are set per every host in the list
+			hostName = ""
+		}
+		if err = config.SetDefaults(&nodeConfig, hostName, *optImageName, *optTemplateName, passPhrase); err != nil {
 			err = fmt.Errorf("SetDefaults() failure: %s", err)
 			panic(err.Error())
 		}
@@ -371,11 +506,14 @@ func main() {
 		if nodeConfig.Compute.HostName == "" || nodeConfig.Storage.BootLun.OsImage.Name == "" || nodeConfig.Storage.SeedLun.SeedTemplate.Location == "" {
 			err = fmt.Errorf("main() failure: expected compute.hostName, storage.bootLun.osImage.name, and storage.seedLun.seedTemplate.location")
 		} else {
+			if *optDryRun {
+				nodeConfig.Plan = config.NewPlan()
+			}
 			var serverExists bool
 			if serverExists, err = discoverServer(&nodeConfig); err == nil {
 				if !serverExists {
 					if err = provisionServerPreflight(&nodeConfig); err == nil {
-						if err = provisionServer(&nodeConfig); err != nil {
+						if err = provisionServer(&nodeConfig); err != nil && nodeConfig.Plan == nil {
 							deprovisionServer(&nodeConfig)
 						}
 					}
@@ -388,15 +526,26 @@ func main() {
 		if nodeConfig.Compute.HostName == "" {
 			err = fmt.Errorf("main() failure: expected compute.hostName")
 		} else {
+			if *optDryRun {
+				nodeConfig.Plan = config.NewPlan()
+			}
 			err = deprovisionServer(&nodeConfig)
 		}
 		nodeResult.DumpResult(nodeResult, *optDumpResult, *optEncodingFormat, err)
+	case "verifyServer":
+		var verifyResult OperationResult = &VerifyResult{}
+		if nodeConfig.Compute.HostName == "" {
+			err = fmt.Errorf("main() failure: expected compute.hostName")
+		} else {
+			verifyResult.(*VerifyResult).Discrepancies, err = verifyServer(&nodeConfig)
+		}
+		verifyResult.DumpResult(verifyResult, *optDumpResult, *optEncodingFormat, err)
 	case "stopServer":
 		var nodeResult OperationResult = &NodeResult{Node: &nodeConfig}
 		if nodeConfig.Compute.HostName == "" {
 			err = fmt.Errorf("main() failure: expected compute.hostName")
 		} else {
-			err = ucsm.StopServer(&nodeConfig)
+			err = server.StopServer(&nodeConfig)
 		}
 		nodeResult.DumpResult(nodeResult, *optDumpResult, *optEncodingFormat, err)
 	case "startServer":
@@ -404,7 +553,7 @@ func main() {
 		if nodeConfig.Compute.HostName == "" {
 			err = fmt.Errorf("main() failure: expected image name and image path")
 		} else {
-			err = ucsm.StartServer(&nodeConfig)
+			err = server.StartServer(&nodeConfig)
 		}
 		nodeResult.DumpResult(nodeResult, *optDumpResult, *optEncodingFormat, err)
 	case "uploadImage":
@@ -413,7 +562,7 @@ func main() {
 			err = fmt.Errorf("main() failure: expected image name and image path")
 			baseResult.DumpResult(baseResult, *optDumpResult, *optEncodingFormat, err)
 		} else {
-			if err = uploadImage(&nodeConfig, *optImageName, *optImagePath); err != nil {
+			if err = uploadImage(&nodeConfig, *optImageName, *optImagePath, *optImageChecksum, *optSignature); err != nil {
 				baseResult.DumpResult(baseResult, *optDumpResult, *optEncodingFormat, err)
 			}
 		}
@@ -423,7 +572,7 @@ func main() {
 			err = fmt.Errorf("main() failure: expected template name and template path")
 			baseResult.DumpResult(baseResult, *optDumpResult, *optEncodingFormat, err)
 		} else {
-			if err = uploadTemplate(&nodeConfig, *optTemplateName, *optTemplatePath); err != nil {
+			if err = uploadTemplate(&nodeConfig, *optTemplateName, *optTemplatePath, *optSignature); err != nil {
 				baseResult.DumpResult(baseResult, *optDumpResult, *optEncodingFormat, err)
 			}
 		}
@@ -461,7 +610,9 @@ func main() {
 		if nodeConfig.Compute.HostName == "" || *optSnapshotName == "" {
 			err = fmt.Errorf("main() failure: expected compute.hostName and snapshot name")
 		} else {
-			err = ontap.CreateSnapshot(&nodeConfig, *optSnapshotName, "")
+			if err = ontap.CreateSnapshot(&nodeConfig, *optSnapshotName, ""); err == nil {
+				_, err = ontap.PruneSnapshots(&nodeConfig, nil)
+			}
 		}
 		baseResult.DumpResult(baseResult, *optDumpResult, *optEncodingFormat, err)
 	case "deleteSnapshot":
@@ -482,8 +633,29 @@ func main() {
 		baseResult.DumpResult(baseResult, *optDumpResult, *optEncodingFormat, err)
 	case "listImages":
 		var imageResult OperationResult = &ImageResult{}
-		imageResult.(*ImageResult).Images, err = ontap.GetRepoImages(&nodeConfig)
+		if imageResult.(*ImageResult).Details, err = ontap.GetRepoImagesInfo(&nodeConfig); err == nil {
+			for _, image := range imageResult.(*ImageResult).Details {
+				imageResult.(*ImageResult).Images = append(imageResult.(*ImageResult).Images, image.Name)
+			}
+		}
 		imageResult.DumpResult(imageResult, *optDumpResult, *optEncodingFormat, err)
+	case "gcImages":
+		var gcResult OperationResult = &ImageGcResult{}
+		var keepImages []string
+		if *optImageName != "" {
+			keepImages = strings.Split(*optImageName, ",")
+		}
+		gcResult.(*ImageGcResult).Images, gcResult.(*ImageGcResult).Pruned, err = ontap.GcRepoImages(&nodeConfig, *optMaxAge, keepImages)
+		gcResult.DumpResult(gcResult, *optDumpResult, *optEncodingFormat, err)
+	case "syncRepo":
+		var syncResult OperationResult = &RepoSyncResult{}
+		if *optManifest == "" {
+			err = fmt.Errorf("main() failure: expected manifest path")
+		} else {
+			syncResult.(*RepoSyncResult).Changes, err = syncRepo(&nodeConfig, *optManifest, *optPrune, *optDryRun, *optParallelism)
+			syncResult.(*RepoSyncResult).Summary = ontap.RepoSyncSummary(syncResult.(*RepoSyncResult).Changes)
+		}
+		syncResult.DumpResult(syncResult, *optDumpResult, *optEncodingFormat, err)
 	case "listTemplates":
 		var templateResult OperationResult = &TemplateResult{}
 		templateResult.(*TemplateResult).Templates, err = ontap.GetRepoTemplates(&nodeConfig)
@@ -493,9 +665,39 @@ func main() {
 		if nodeConfig.Compute.HostName == "" {
 			err = fmt.Errorf("main() failure: expected compute.hostName")
 		} else {
-			snapshotResult.(*SnapshotResult).Snapshots, err = ontap.GetSnapshots(&nodeConfig)
+			if snapshotResult.(*SnapshotResult).Details, err = ontap.GetSnapshotsDetails(&nodeConfig); err == nil {
+				for _, snapshot := range snapshotResult.(*SnapshotResult).Details {
+					snapshotResult.(*SnapshotResult).Snapshots = append(snapshotResult.(*SnapshotResult).Snapshots, snapshot.Name)
+				}
+			}
 		}
 		snapshotResult.DumpResult(snapshotResult, *optDumpResult, *optEncodingFormat, err)
+	case "pruneSnapshots":
+		var pruneResult OperationResult = &PruneResult{}
+		if *optHostName == "" {
+			err = fmt.Errorf("main() failure: expected host name(s)")
+		} else {
+			pruneResult.(*PruneResult).Hosts, err = pruneSnapshots(&nodeConfig, strings.Split(*optHostName, ","), passPhrase)
+		}
+		pruneResult.DumpResult(pruneResult, *optDumpResult, *optEncodingFormat, err)
+	case "createGroupSnapshot":
+		var baseResult OperationResult = &BaseResult{}
+		if *optHostName == "" || *optSnapshotName == "" {
+			err = fmt.Errorf("main() failure: expected host names and snapshot name")
+		} else if *optSshUser != "" && *optSshKey == "" {
+			err = fmt.Errorf("main() failure: expected SSH private key path for SSH user")
+		} else {
+			err = createGroupSnapshot(&nodeConfig, strings.Split(*optHostName, ","), *optSnapshotName, *optSshUser, *optSshKey, passPhrase)
+		}
+		baseResult.DumpResult(baseResult, *optDumpResult, *optEncodingFormat, err)
+	case "restoreGroupSnapshot":
+		var baseResult OperationResult = &BaseResult{}
+		if *optHostName == "" || *optSnapshotName == "" {
+			err = fmt.Errorf("main() failure: expected host names and snapshot name")
+		} else {
+			err = restoreGroupSnapshot(&nodeConfig, strings.Split(*optHostName, ","), *optSnapshotName, passPhrase)
+		}
+		baseResult.DumpResult(baseResult, *optDumpResult, *optEncodingFormat, err)
 	case "encryptConfig":
 		var baseResult OperationResult = &BaseResult{}
 		if err = config.EncryptNodeConfig(&nodeConfig, passPhrase); err == nil {
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/config"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/ontap"
+	"github.com/igor-feoktistov/terraform-provider-flexbot/pkg/server"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Max time to wait for node filesystems to freeze
+	nodeFreezeTimeout = 60
+	// Filesystems are thawed by node itself if thaw is not requested in time
+	nodeThawTimeout = 120
+)
+
+// nodeFreeze is fsfreeze session on a node, filesystems stay frozen until thaw is requested
+type nodeFreeze struct {
+	hostName string
+	conn     *ssh.Client
+	sess     *ssh.Session
+	stdin    io.WriteCloser
+	stdout   bytes.Buffer
+	stderr   bytes.Buffer
+}
+
+// groupNodeConfigs makes node configuration per every host in the list
+func groupNodeConfigs(nodeConfig *config.NodeConfig, hostNames []string, passPhrase string) (nodeConfigs []*config.NodeConfig, err error) {
+	var b []byte
+	if b, err = yaml.Marshal(nodeConfig); err != nil {
+		err = fmt.Errorf("groupNodeConfigs: Marshal() failure: %s", err)
+		return
+	}
+	for _, hostName := range hostNames {
+		hostConfig := &config.NodeConfig{}
+		if err = yaml.Unmarshal(b, hostConfig); err != nil {
+			err = fmt.Errorf("groupNodeConfigs: Unmarshal() failure: %s", err)
+			return
+		}
+		if err = config.SetDefaults(hostConfig, hostName, "", "", passPhrase); err != nil {
+			err = fmt.Errorf("groupNodeConfigs: SetDefaults() failure for host \"%s\": %s", hostName, err)
+			return
+		}
+		nodeConfigs = append(nodeConfigs, hostConfig)
+	}
+	return
+}
+
+// freezeNode freezes node filesystems
+func freezeNode(nodeConfig *config.NodeConfig, sshUser string, signer ssh.Signer) (f *nodeFreeze, err error) {
+	var filesystems, freezeCmds, unfreezeCmds []string
+	var sess *ssh.Session
+	var bStdout, bStderr bytes.Buffer
+	f = &nodeFreeze{hostName: nodeConfig.Compute.HostName}
+	sshConfig := &ssh.ClientConfig{
+		User: sshUser,
+		Auth: []ssh.AuthMethod{
+			ssh.PublicKeys(signer),
+		},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+	if f.conn, err = ssh.Dial("tcp", nodeConfig.Network.Node[0].Ip+":22", sshConfig); err != nil {
+		err = fmt.Errorf("freezeNode: failed to connect to host %s: %s", nodeConfig.Network.Node[0].Ip, err)
+		return
+	}
+	if sess, err = f.conn.NewSession(); err != nil {
+		f.conn.Close()
+		err = fmt.Errorf("freezeNode: failed to create SSH session: %s", err)
+		return
+	}
+	sess.Stdout = &bStdout
+	sess.Stderr = &bStderr
+	err = sess.Run(`cat /proc/mounts | sed -n 's/^\/dev\/mapper\/[^ ]\+[ ]\+\(\/[^ \/]\{1,64\}\).*/\1/p' | uniq`)
+	sess.Close()
+	if err != nil {
+		f.conn.Close()
+		err = fmt.Errorf("freezeNode: failed to run command: %s: %s", err, bStderr.String())
+		return
+	}
+	if bStdout.Len() > 0 {
+		filesystems = strings.Split(strings.Trim(bStdout.String(), "\n"), "\n")
+	}
+	unfreezeCmds = append(unfreezeCmds, "fsfreeze -u /")
+	for _, fs := range filesystems {
+		freezeCmds = append(freezeCmds, "fsfreeze -f "+fs)
+		unfreezeCmds = append(unfreezeCmds, "fsfreeze -u "+fs)
+	}
+	freezeCmds = append(freezeCmds, "fsfreeze -f /")
+	cmd := fmt.Sprintf(`sudo -n sh -c 'sync && sleep 5 && sync && %s && (echo -n frozen && timeout %d head -n 1 >/dev/null); %s'`, strings.Join(freezeCmds, " && "), nodeThawTimeout, strings.Join(unfreezeCmds, "; "))
+	if f.sess, err = f.conn.NewSession(); err != nil {
+		f.conn.Close()
+		err = fmt.Errorf("freezeNode: failed to create SSH session: %s", err)
+		return
+	}
+	f.sess.Stdout = &f.stdout
+	f.sess.Stderr = &f.stderr
+	if f.stdin, err = f.sess.StdinPipe(); err != nil {
+		f.sess.Close()
+		f.conn.Close()
+		err = fmt.Errorf("freezeNode: failed to get SSH session stdin: %s", err)
+		return
+	}
+	if err = f.sess.Start(cmd); err != nil {
+		f.sess.Close()
+		f.conn.Close()
+		err = fmt.Errorf("freezeNode: failed to start SSH command: %s", err)
+		return
+	}
+	for i := 0; i < nodeFreezeTimeout*2; i++ {
+		if f.stdout.Len() > 0 {
+			break
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	if f.stdout.String() != "frozen" {
+		thawNode(f)
+		err = fmt.Errorf("freezeNode: fsfreeze did not complete on host \"%s\"", f.hostName)
+	}
+	return
+}
+
+// thawNode requests node filesystems thaw and waits for fsfreeze session to complete
+func thawNode(f *nodeFreeze) (err error) {
+	defer f.conn.Close()
+	defer f.sess.Close()
+	f.stdin.Write([]byte("thaw\n"))
+	f.stdin.Close()
+	if err = f.sess.Wait(); err != nil {
+		err = fmt.Errorf("thawNode: failed to run SSH command on host \"%s\": %s: %s", f.hostName, err, f.stderr.String())
+	}
+	return
+}
+
+// freezeNodes freezes filesystems on all nodes concurrently, nodes are thawed back if any node fails to freeze
+func freezeNodes(nodeConfigs []*config.NodeConfig, sshUser string, signer ssh.Signer) (freezes []*nodeFreeze, err error) {
+	var wg sync.WaitGroup
+	var errs []string
+	nodeFreezes := make([]*nodeFreeze, len(nodeConfigs))
+	nodeErrs := make([]error, len(nodeConfigs))
+	for i := range nodeConfigs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			nodeFreezes[i], nodeErrs[i] = freezeNode(nodeConfigs[i], sshUser, signer)
+		}(i)
+	}
+	wg.Wait()
+	for i := range nodeConfigs {
+		if nodeErrs[i] == nil {
+			freezes = append(freezes, nodeFreezes[i])
+		} else {
+			errs = append(errs, nodeErrs[i].Error())
+		}
+	}
+	if len(errs) > 0 {
+		thawNodes(freezes)
+		freezes = nil
+		err = fmt.Errorf("freezeNodes: %s", strings.Join(errs, " , "))
+	}
+	return
+}
+
+// thawNodes thaws filesystems on all frozen nodes
+func thawNodes(freezes []*nodeFreeze) (err error) {
+	var errs []string
+	for _, f := range freezes {
+		if stepErr := thawNode(f); stepErr != nil {
+			errs = append(errs, stepErr.Error())
+		}
+	}
+	if len(errs) > 0 {
+		err = fmt.Errorf("thawNodes: %s", strings.Join(errs, " , "))
+	}
+	return
+}
+
+func createGroupSnapshot(nodeConfig *config.NodeConfig, hostNames []string, snapshotName string, sshUser string, sshKeyPath string, passPhrase string) (err error) {
+	var nodeConfigs []*config.NodeConfig
+	if nodeConfigs, err = groupNodeConfigs(nodeConfig, hostNames, passPhrase); err != nil {
+		return
+	}
+	for _, hostConfig := range nodeConfigs {
+		var exists bool
+		if exists, err = ontap.SnapshotExists(hostConfig, snapshotName); err != nil {
+			return
+		}
+		if exists {
+			err = fmt.Errorf("CreateGroupSnapshot: snapshot \"%s\" already exists for host \"%s\"", snapshotName, hostConfig.Compute.HostName)
+			return
+		}
+	}
+	if sshUser == "" {
+		err = ontap.CreateGroupSnapshot(nodeConfigs, snapshotName, "")
+		return
+	}
+	var b []byte
+	var signer ssh.Signer
+	if b, err = ioutil.ReadFile(sshKeyPath); err != nil {
+		err = fmt.Errorf("CreateGroupSnapshot: failed to read SSH private key: %s", err)
+		return
+	}
+	if signer, err = ssh.ParsePrivateKey(b); err != nil {
+		err = fmt.Errorf("CreateGroupSnapshot: failed to parse SSH private key: %s", err)
+		return
+	}
+	for _, hostConfig := range nodeConfigs {
+		var serverExists bool
+		if serverExists, err = discoverServer(hostConfig); err != nil {
+			return
+		}
+		if !serverExists || len(hostConfig.Network.Node) == 0 || hostConfig.Network.Node[0].Ip == "" {
+			err = fmt.Errorf("CreateGroupSnapshot: failed to discover IP address of host \"%s\"", hostConfig.Compute.HostName)
+			return
+		}
+	}
+	var freezes []*nodeFreeze
+	if freezes, err = freezeNodes(nodeConfigs, sshUser, signer); err != nil {
+		err = fmt.Errorf("CreateGroupSnapshot: snapshot is not created: %s", err)
+		return
+	}
+	snapshotErr := ontap.CreateGroupSnapshot(nodeConfigs, snapshotName, "")
+	thawErr := thawNodes(freezes)
+	if snapshotErr != nil && thawErr != nil {
+		err = fmt.Errorf("CreateGroupSnapshot: %s , %s", snapshotErr, thawErr)
+	} else if snapshotErr != nil {
+		err = snapshotErr
+	} else {
+		err = thawErr
+	}
+	return
+}
+
+func restoreGroupSnapshot(nodeConfig *config.NodeConfig, hostNames []string, snapshotName string, passPhrase string) (err error) {
+	var nodeConfigs []*config.NodeConfig
+	if nodeConfigs, err = groupNodeConfigs(nodeConfig, hostNames, passPhrase); err != nil {
+		return
+	}
+	for _, hostConfig := range nodeConfigs {
+		var powerState string
+		if powerState, err = server.GetServerPowerState(hostConfig); err != nil {
+			return
+		}
+		if powerState == "up" {
+			err = fmt.Errorf("RestoreGroupSnapshot: cannot restore LUNs from snapshot, server \"%s\" has power state \"%s\"", hostConfig.Compute.HostName, powerState)
+			return
+		}
+	}
+	err = ontap.RestoreGroupSnapshot(nodeConfigs, snapshotName)
+	return
+}